@@ -33,6 +33,10 @@ func (m *MockDoctorProvider) GetPartialResponse() string {
 	return ""
 }
 
+func (m *MockDoctorProvider) Capabilities() ai.Capabilities {
+	return ai.Capabilities{}
+}
+
 func (m *MockDoctorProvider) StreamResponse(ctx context.Context, prompt string) (<-chan ai.StreamResponse, error) {
 	ch := make(chan ai.StreamResponse, 1)
 	ch <- ai.StreamResponse{Content: m.response, Done: true}
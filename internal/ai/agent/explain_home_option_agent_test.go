@@ -28,6 +28,10 @@ func (m *MockProviderForHomeOptions) GetPartialResponse() string {
 	return ""
 }
 
+func (m *MockProviderForHomeOptions) Capabilities() ai.Capabilities {
+	return ai.Capabilities{}
+}
+
 func (m *MockProviderForHomeOptions) StreamResponse(ctx context.Context, prompt string) (<-chan ai.StreamResponse, error) {
 	ch := make(chan ai.StreamResponse, 1)
 	ch <- ai.StreamResponse{Content: m.response, Done: true}
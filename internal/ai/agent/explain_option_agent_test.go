@@ -26,6 +26,10 @@ func (m *MockProviderForOptions) GetPartialResponse() string {
 	return ""
 }
 
+func (m *MockProviderForOptions) Capabilities() ai.Capabilities {
+	return ai.Capabilities{}
+}
+
 func (m *MockProviderForOptions) Query(prompt string) (string, error) {
 	args := m.Called(prompt)
 	return args.String(0), args.Error(1)
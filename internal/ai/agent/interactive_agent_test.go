@@ -30,6 +30,10 @@ func (m *MockProviderForInteractive) GetPartialResponse() string {
 	return ""
 }
 
+func (m *MockProviderForInteractive) Capabilities() ai.Capabilities {
+	return ai.Capabilities{}
+}
+
 func (m *MockProviderForInteractive) StreamResponse(ctx context.Context, prompt string) (<-chan ai.StreamResponse, error) {
 	ch := make(chan ai.StreamResponse, 1)
 	ch <- ai.StreamResponse{Content: "mock stream response", Done: true}
@@ -26,6 +26,10 @@ func (m *MockMachinesProvider) GetPartialResponse() string {
 	return ""
 }
 
+func (m *MockMachinesProvider) Capabilities() ai.Capabilities {
+	return ai.Capabilities{}
+}
+
 func (m *MockMachinesProvider) StreamResponse(ctx context.Context, prompt string) (<-chan ai.StreamResponse, error) {
 	ch := make(chan ai.StreamResponse, 1)
 	ch <- ai.StreamResponse{Content: "mock stream response", Done: true}
@@ -28,6 +28,10 @@ func (m *MockStoreProvider) GetPartialResponse() string {
 	return ""
 }
 
+func (m *MockStoreProvider) Capabilities() ai.Capabilities {
+	return ai.Capabilities{}
+}
+
 func (m *MockStoreProvider) StreamResponse(ctx context.Context, prompt string) (<-chan ai.StreamResponse, error) {
 	ch := make(chan ai.StreamResponse, 1)
 	ch <- ai.StreamResponse{Content: "mock stream response", Done: true}
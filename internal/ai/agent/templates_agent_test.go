@@ -41,6 +41,10 @@ func (m *mockTemplatesProvider) GetPartialResponse() string {
 	return ""
 }
 
+func (m *mockTemplatesProvider) Capabilities() ai.Capabilities {
+	return ai.Capabilities{}
+}
+
 func (m *mockTemplatesProvider) StreamResponse(ctx context.Context, prompt string) (<-chan ai.StreamResponse, error) {
 	ch := make(chan ai.StreamResponse, 1)
 	ch <- ai.StreamResponse{Content: "mock stream response", Done: true}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/utils"
 )
 
 // ClaudeClient implements the AIProvider interface for Anthropic's Claude API.
@@ -20,6 +21,7 @@ type ClaudeClient struct {
 	APIURL     string
 	Model      string
 	HTTPClient *http.Client
+	Sampling   Sampling
 }
 
 // NewClaudeClient creates a new Claude client with the provided API key.
@@ -28,7 +30,7 @@ func NewClaudeClient(apiKey string) *ClaudeClient {
 		APIKey:     apiKey,
 		APIURL:     "https://api.anthropic.com/v1/messages",
 		Model:      "claude-sonnet-4-20250514", // default model
-		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		HTTPClient: utils.NewHTTPClient(30 * time.Second),
 	}
 }
 
@@ -63,15 +65,17 @@ func NewClaudeProviderWithModel(providerConfig *config.AIProviderConfig, modelNa
 		APIKey:     apiKey,
 		APIURL:     baseURL,
 		Model:      model.Name,
-		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		HTTPClient: utils.NewHTTPClient(30 * time.Second),
 	}, nil
 }
 
 // ClaudeRequest represents a request to the Claude API.
 type ClaudeRequest struct {
-	Model     string          `json:"model"`
-	MaxTokens int             `json:"max_tokens"`
-	Messages  []ClaudeMessage `json:"messages"`
+	Model       string          `json:"model"`
+	MaxTokens   int             `json:"max_tokens"`
+	Messages    []ClaudeMessage `json:"messages"`
+	Temperature *float64        `json:"temperature,omitempty"`
+	TopP        *float64        `json:"top_p,omitempty"`
 }
 
 // ClaudeMessage represents a message in the Claude API.
@@ -100,10 +104,12 @@ type ClaudeError struct {
 
 // ClaudeStreamRequest represents a streaming request to the Claude API.
 type ClaudeStreamRequest struct {
-	Model     string          `json:"model"`
-	MaxTokens int             `json:"max_tokens"`
-	Messages  []ClaudeMessage `json:"messages"`
-	Stream    bool            `json:"stream"`
+	Model       string          `json:"model"`
+	MaxTokens   int             `json:"max_tokens"`
+	Messages    []ClaudeMessage `json:"messages"`
+	Stream      bool            `json:"stream"`
+	Temperature *float64        `json:"temperature,omitempty"`
+	TopP        *float64        `json:"top_p,omitempty"`
 }
 
 // ClaudeStreamResponse represents a streaming response from Claude API.
@@ -121,8 +127,10 @@ type ClaudeStreamDelta struct {
 // Query implements the Provider interface for ClaudeClient.
 func (client *ClaudeClient) Query(ctx context.Context, prompt string) (string, error) {
 	request := ClaudeRequest{
-		Model:     client.Model,
-		MaxTokens: 4096,
+		Model:       client.Model,
+		MaxTokens:   4096,
+		Temperature: client.Sampling.Temperature,
+		TopP:        client.Sampling.TopP,
 		Messages: []ClaudeMessage{
 			{Role: "user", Content: prompt},
 		},
@@ -181,8 +189,10 @@ func (client *ClaudeClient) StreamResponse(ctx context.Context, prompt string) (
 		defer close(responseChan)
 
 		request := ClaudeStreamRequest{
-			Model:     client.Model,
-			MaxTokens: 4096,
+			Model:       client.Model,
+			MaxTokens:   4096,
+			Temperature: client.Sampling.Temperature,
+			TopP:        client.Sampling.TopP,
 			Messages: []ClaudeMessage{
 				{Role: "user", Content: prompt},
 			},
@@ -279,6 +289,11 @@ func (client *ClaudeClient) GetPartialResponse() string {
 	return ""
 }
 
+// Capabilities reports what Claude supports
+func (client *ClaudeClient) Capabilities() Capabilities {
+	return Capabilities{SupportsStreaming: true, SupportsSystemPrompt: true, MaxContext: 200000}
+}
+
 // CheckHealth checks if the Claude API is accessible and responding.
 func (client *ClaudeClient) CheckHealth() error {
 	// Simple health check by making a minimal request
@@ -0,0 +1,41 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DefaultOllamaMaxConcurrency is how many queries nixai sends to one Ollama server at a time.
+// A single-GPU Ollama serializes requests anyway, and parallel ones can run it out of memory.
+const DefaultOllamaMaxConcurrency = 1
+
+var (
+	limitersMu sync.Mutex
+	// limiters holds the in-flight slots of each server, shared by all providers using it
+	limiters = make(map[string]chan struct{})
+)
+
+// sharedLimiter returns the slots bounding concurrent queries to server at limit
+func sharedLimiter(server string, limit int) chan struct{} {
+	key := fmt.Sprintf("%s#%d", server, limit)
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	slots, ok := limiters[key]
+	if !ok {
+		slots = make(chan struct{}, limit)
+		limiters[key] = slots
+	}
+	return slots
+}
+
+// acquireSlot waits for a free slot, or until ctx is done, and returns the function
+// releasing it
+func acquireSlot(ctx context.Context, slots chan struct{}) (func(), error) {
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for a free query slot: %w", ctx.Err())
+	}
+}
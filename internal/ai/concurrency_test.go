@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingOllama is a fake Ollama server recording how many requests it handles at once
+type countingOllama struct {
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (c *countingOllama) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	c.running++
+	if c.running > c.peak {
+		c.peak = c.running
+	}
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.running--
+		c.mu.Unlock()
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	_ = json.NewEncoder(w).Encode(ollamaResponse{Response: "ok", Done: true})
+}
+
+// queryConcurrently sends n queries at once through separate providers for the same server
+func queryConcurrently(t *testing.T, endpoint string, maxConcurrency, n int) {
+	t.Helper()
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			provider := NewOllamaProvider("llama3")
+			provider.Endpoint = endpoint
+			provider.MaxConcurrency = maxConcurrency
+			if _, err := provider.Query(context.Background(), "prompt"); err != nil {
+				t.Errorf("query failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestOllamaQueriesAreSerializedByDefault(t *testing.T) {
+	server := &countingOllama{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	queryConcurrently(t, ts.URL, 0, 5)
+	if server.peak != DefaultOllamaMaxConcurrency {
+		t.Errorf("peak concurrency %d, want %d", server.peak, DefaultOllamaMaxConcurrency)
+	}
+}
+
+func TestOllamaMaxConcurrencyBoundsQueries(t *testing.T) {
+	server := &countingOllama{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	queryConcurrently(t, ts.URL, 2, 8)
+	if server.peak != 2 {
+		t.Errorf("peak concurrency %d, want 2", server.peak)
+	}
+}
+
+func TestAcquireSlotHonorsContext(t *testing.T) {
+	slots := sharedLimiter("test-acquire", 1)
+	release, err := acquireSlot(context.Background(), slots)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := acquireSlot(ctx, slots); err == nil {
+		t.Fatal("expected waiting for a busy slot to time out")
+	}
+
+	release()
+	release, err = acquireSlot(context.Background(), slots)
+	if err != nil {
+		t.Fatalf("the released slot was not reusable: %v", err)
+	}
+	release()
+}
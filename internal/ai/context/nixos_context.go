@@ -31,6 +31,7 @@ func (cb *NixOSContextBuilder) buildDetectedContextPrompt(context *config.NixOSC
 
 	prompt.WriteString("=== USER'S NIXOS CONTEXT ===\n")
 	prompt.WriteString(fmt.Sprintf("System Type: %s\n", context.SystemType))
+	prompt.WriteString(environmentGuidance(context))
 
 	// System configuration approach
 	if context.UsesFlakes {
@@ -67,6 +68,9 @@ func (cb *NixOSContextBuilder) buildDetectedContextPrompt(context *config.NixOSC
 	if context.NixOSVersion != "" {
 		prompt.WriteString(fmt.Sprintf("NixOS Version: %s\n", context.NixOSVersion))
 	}
+	if context.NixpkgsRelease != "" {
+		prompt.WriteString(fmt.Sprintf("Nixpkgs Release: %s (suggest only options available in this release)\n", context.NixpkgsRelease))
+	}
 	if context.NixVersion != "" {
 		prompt.WriteString(fmt.Sprintf("Nix Version: %s\n", context.NixVersion))
 	}
@@ -101,6 +105,29 @@ func (cb *NixOSContextBuilder) buildDetectedContextPrompt(context *config.NixOSC
 	return prompt.String()
 }
 
+// environmentGuidance describes where Nix runs and the advice that does not apply there, or
+// returns "" on a standard machine
+func environmentGuidance(context *config.NixOSContext) string {
+	switch context.Environment {
+	case "container":
+		runtime := context.ContainerRuntime
+		if runtime == "" {
+			runtime = "unknown runtime"
+		}
+		return fmt.Sprintf("Environment: container (%s), e.g. a nixos-container\n", runtime) +
+			"❌ NEVER suggest bootloader, kernel, kernel module, firmware or hardware changes - the host manages them\n" +
+			"Networking, filesystems and resource limits are configured on the host side (containers.<name> options)\n"
+	case "wsl":
+		return "Environment: WSL (NixOS-WSL or Nix on a WSL distribution)\n" +
+			"❌ NEVER suggest bootloader, kernel, display manager or GPU driver changes - Windows provides the kernel\n" +
+			"Use the wsl.* options of NixOS-WSL for WSL integration; graphical apps run through WSLg\n"
+	case "darwin":
+		return "Environment: macOS\n" +
+			"❌ NEVER suggest NixOS modules, systemd services or nixos-rebuild - use nix-darwin (launchd) or Home Manager\n"
+	}
+	return ""
+}
+
 // buildGenericNixOSPrompt creates a generic prompt when context detection fails
 func (cb *NixOSContextBuilder) buildGenericNixOSPrompt() string {
 	return `=== NIXOS CONFIGURATION GUIDANCE ===
@@ -249,6 +276,9 @@ func (cb *NixOSContextBuilder) GetContextSummary(context *config.NixOSContext) s
 	var parts []string
 
 	parts = append(parts, fmt.Sprintf("System: %s", context.SystemType))
+	if context.Environment != "" && context.Environment != "standard" {
+		parts = append(parts, fmt.Sprintf("Environment: %s", context.Environment))
+	}
 
 	if context.UsesFlakes {
 		parts = append(parts, "Flakes: Yes")
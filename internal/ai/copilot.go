@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"nix-ai-help/pkg/utils"
 )
 
 // CopilotClient represents a client for interacting with GitHub Copilot's OpenAI-compatible API.
@@ -25,7 +27,7 @@ func NewCopilotClient(apiKey string) *CopilotClient {
 		APIKey:     apiKey,
 		APIURL:     "https://api.githubcopilot.com/chat/completions",
 		Model:      "gpt-4", // Default model for GitHub Copilot
-		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		HTTPClient: utils.NewHTTPClient(30 * time.Second),
 	}
 }
 
@@ -284,6 +286,11 @@ func (client *CopilotClient) GetPartialResponse() string {
 	return ""
 }
 
+// Capabilities reports what Copilot supports
+func (client *CopilotClient) Capabilities() Capabilities {
+	return Capabilities{SupportsStreaming: true, SupportsSystemPrompt: true, MaxContext: 128000}
+}
+
 // SetTimeout sets the HTTP client timeout.
 func (client *CopilotClient) SetTimeout(timeout time.Duration) {
 	client.HTTPClient.Timeout = timeout
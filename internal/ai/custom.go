@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/utils"
 )
 
 // CustomProvider implements the Provider interface for user-defined HTTP API AI providers.
@@ -33,9 +34,7 @@ func NewCustomProvider(baseURL string, headers map[string]string) *CustomProvide
 		BaseURL: baseURL,
 		Headers: headers,
 		Model:   "",
-		client: &http.Client{
-			Timeout: 60 * time.Second,
-		},
+		client:  utils.NewHTTPClient(60 * time.Second),
 	}
 }
 
@@ -63,9 +62,7 @@ func NewCustomProviderWithModel(providerConfig *config.AIProviderConfig, modelNa
 		BaseURL: providerConfig.BaseURL,
 		Headers: headers,
 		Model:   model.Name,
-		client: &http.Client{
-			Timeout: 60 * time.Second,
-		},
+		client:  utils.NewHTTPClient(60 * time.Second),
 	}, nil
 }
 
@@ -82,7 +79,7 @@ func (c *CustomProvider) CheckHealth() error {
 		req.Header.Set(key, value)
 	}
 
-	client := &http.Client{Timeout: 5 * time.Second}
+	client := utils.NewHTTPClient(5 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("custom provider server not accessible: %w", err)
@@ -261,3 +258,9 @@ func (c *CustomProvider) StreamResponse(ctx context.Context, prompt string) (<-c
 func (c *CustomProvider) GetPartialResponse() string {
 	return ""
 }
+
+// Capabilities reports what a custom endpoint supports. Its streaming is simulated and its
+// context window is unknown.
+func (c *CustomProvider) Capabilities() Capabilities {
+	return Capabilities{SupportsStreaming: false, SupportsSystemPrompt: false, MaxContext: 0}
+}
@@ -1,12 +1,12 @@
 package flakes
 
 import (
-"context"
-"testing"
-"time"
+	"context"
+	"testing"
+	"time"
 
-"nix-ai-help/internal/ai/functionbase"
-"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/assert"
+	"nix-ai-help/internal/ai/functionbase"
 )
 
 func TestNewFlakesFunction(t *testing.T) {
@@ -14,7 +14,7 @@ func TestNewFlakesFunction(t *testing.T) {
 	assert.NotNil(t, fn)
 	assert.Equal(t, "flakes", fn.Name())
 	assert.NotEmpty(t, fn.Description())
-	
+
 	schema := fn.Schema()
 	assert.Equal(t, "flakes", schema.Name)
 	assert.NotEmpty(t, schema.Parameters)
@@ -62,13 +62,13 @@ func TestFlakesFunction_ValidateParameters(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-err := fn.ValidateParameters(tt.input)
-if tt.expectError {
-assert.Error(t, err)
-} else {
-assert.NoError(t, err)
-}
-})
+			err := fn.ValidateParameters(tt.input)
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
 	}
 }
 
@@ -91,7 +91,7 @@ func TestFlakesFunction_Execute_BasicOperations(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-options := &functionbase.FunctionOptions{}
+			options := &functionbase.FunctionOptions{}
 			result, err := fn.Execute(ctx, tt.input, options)
 			assert.NoError(t, err)
 			assert.NotNil(t, result)
@@ -27,6 +27,10 @@ func (m *MockProvider) GetPartialResponse() string {
 	return ""
 }
 
+func (m *MockProvider) Capabilities() ai.Capabilities {
+	return ai.Capabilities{}
+}
+
 func (m *MockProvider) StreamResponse(ctx context.Context, prompt string) (<-chan ai.StreamResponse, error) {
 	ch := make(chan ai.StreamResponse, 1)
 	ch <- ai.StreamResponse{Content: "Mock neovim configuration response", Done: true}
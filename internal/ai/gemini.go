@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"nix-ai-help/pkg/utils"
 )
 
 // GeminiClient is a struct that holds the configuration for the Gemini AI provider.
@@ -54,7 +56,7 @@ func (g *GeminiClient) CheckHealth() error {
 
 	req.Header.Set("X-Goog-Api-Key", g.APIKey)
 
-	client := &http.Client{}
+	client := utils.NewHTTPClient(0)
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("gemini API not accessible: %w", err)
@@ -181,7 +183,7 @@ func (c *GeminiClient) QueryLegacy(prompt string) (string, error) {
 
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := utils.NewHTTPClient(0)
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to send request: %w", err)
@@ -267,7 +269,7 @@ func (c *GeminiClient) QueryContext(ctx context.Context, prompt string) (string,
 
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := utils.NewHTTPClient(0)
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to send request: %w", err)
@@ -354,6 +356,12 @@ func (c *GeminiClient) GetPartialResponse() string {
 	return ""
 }
 
+// Capabilities reports what Gemini supports. StreamResponse only simulates streaming by
+// chunking the complete answer.
+func (c *GeminiClient) Capabilities() Capabilities {
+	return Capabilities{SupportsStreaming: false, SupportsSystemPrompt: true, MaxContext: 1048576}
+}
+
 // min returns the smaller of two ints
 func min(a, b int) int {
 	if a < b {
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/utils"
 )
 
 // GroqClient implements the AIProvider interface for Groq's API.
@@ -20,6 +21,7 @@ type GroqClient struct {
 	APIURL     string
 	Model      string
 	HTTPClient *http.Client
+	Sampling   Sampling
 }
 
 // NewGroqClient creates a new Groq client with the provided API key.
@@ -28,7 +30,7 @@ func NewGroqClient(apiKey string) *GroqClient {
 		APIKey:     apiKey,
 		APIURL:     "https://api.groq.com/openai/v1/chat/completions",
 		Model:      "llama-3.3-70b-versatile", // default model
-		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		HTTPClient: utils.NewHTTPClient(30 * time.Second),
 	}
 }
 
@@ -63,15 +65,17 @@ func NewGroqProviderWithModel(providerConfig *config.AIProviderConfig, modelName
 		APIKey:     apiKey,
 		APIURL:     baseURL,
 		Model:      model.Name,
-		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		HTTPClient: utils.NewHTTPClient(30 * time.Second),
 	}, nil
 }
 
 // GroqRequest represents a request to the Groq API (OpenAI-compatible format).
 type GroqRequest struct {
-	Model    string        `json:"model"`
-	Messages []GroqMessage `json:"messages"`
-	Stream   bool          `json:"stream"`
+	Model       string        `json:"model"`
+	Messages    []GroqMessage `json:"messages"`
+	Stream      bool          `json:"stream"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	TopP        *float64      `json:"top_p,omitempty"`
 }
 
 // GroqMessage represents a message in the Groq API.
@@ -121,7 +125,9 @@ func (client *GroqClient) Query(prompt string) (string, error) {
 // QueryWithContext implements the Provider interface with context support for GroqClient.
 func (client *GroqClient) QueryWithContext(ctx context.Context, prompt string) (string, error) {
 	request := GroqRequest{
-		Model: client.Model,
+		Model:       client.Model,
+		Temperature: client.Sampling.Temperature,
+		TopP:        client.Sampling.TopP,
 		Messages: []GroqMessage{
 			{Role: "user", Content: prompt},
 		},
@@ -180,7 +186,9 @@ func (client *GroqClient) StreamResponse(ctx context.Context, prompt string) (<-
 		defer close(responseChan)
 
 		request := GroqRequest{
-			Model: client.Model,
+			Model:       client.Model,
+			Temperature: client.Sampling.Temperature,
+			TopP:        client.Sampling.TopP,
 			Messages: []GroqMessage{
 				{Role: "user", Content: prompt},
 			},
@@ -271,6 +279,11 @@ func (client *GroqClient) GetPartialResponse() string {
 	return ""
 }
 
+// Capabilities reports what Groq supports
+func (client *GroqClient) Capabilities() Capabilities {
+	return Capabilities{SupportsStreaming: true, SupportsSystemPrompt: true, MaxContext: 8192}
+}
+
 // CheckHealth checks if the Groq API is accessible and responding.
 func (client *GroqClient) CheckHealth() error {
 	// Simple health check by making a minimal request
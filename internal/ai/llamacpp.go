@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/utils"
 )
 
 // LlamaCppProvider implements the AIProvider interface for llamacpp.
@@ -33,7 +34,7 @@ func NewLlamaCppProvider(model string) *LlamaCppProvider {
 	return &LlamaCppProvider{
 		Endpoint: endpoint,
 		Model:    model,
-		Client:   &http.Client{Timeout: timeout},
+		Client:   utils.NewHTTPClient(timeout),
 	}
 }
 
@@ -59,7 +60,7 @@ func NewLlamaCppProviderWithModel(providerConfig *config.AIProviderConfig, model
 	return &LlamaCppProvider{
 		Endpoint: endpoint,
 		Model:    model.Name,
-		Client:   &http.Client{Timeout: timeout},
+		Client:   utils.NewHTTPClient(timeout),
 	}, nil
 }
 
@@ -71,7 +72,7 @@ func (l *LlamaCppProvider) CheckHealth() error {
 		return fmt.Errorf("failed to create health check request: %w", err)
 	}
 
-	client := &http.Client{Timeout: 5 * time.Second}
+	client := utils.NewHTTPClient(5 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("llamacpp server not accessible: %w", err)
@@ -225,6 +226,12 @@ func (l *LlamaCppProvider) GetPartialResponse() string {
 	return l.lastPartial
 }
 
+// Capabilities reports what llama.cpp supports. StreamResponse only simulates streaming by
+// chunking the complete answer.
+func (l *LlamaCppProvider) Capabilities() Capabilities {
+	return Capabilities{SupportsStreaming: false, SupportsSystemPrompt: false, MaxContext: 4096}
+}
+
 // queryLlamaCpp is the legacy implementation
 func (l *LlamaCppProvider) queryLlamaCpp(prompt string, streaming bool) (string, error) {
 	reqBody, _ := json.Marshal(llamacppRequest{Prompt: prompt, Model: l.Model})
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 
 	"nix-ai-help/internal/config"
 	"nix-ai-help/pkg/logger"
@@ -15,6 +16,7 @@ type ProviderManager struct {
 	registry  *config.ModelRegistry
 	config    *config.UserConfig
 	providers map[string]Provider // Cache of initialized providers
+	mu        sync.Mutex          // Guards providers; a manager may be shared across commands
 	logger    *logger.Logger
 }
 
@@ -36,6 +38,9 @@ func NewProviderManager(cfg *config.UserConfig, log *logger.Logger) *ProviderMan
 
 // GetProvider retrieves or initializes a provider by name.
 func (pm *ProviderManager) GetProvider(providerName string) (Provider, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
 	// Check cache first
 	if provider, exists := pm.providers[providerName]; exists {
 		return provider, nil
@@ -53,6 +58,14 @@ func (pm *ProviderManager) GetProvider(providerName string) (Provider, error) {
 		return nil, fmt.Errorf("failed to initialize provider '%s': %w", providerName, err)
 	}
 
+	// Report the context window of the configured model rather than the provider's default
+	if wrapper, ok := provider.(*ProviderWrapper); ok {
+		defaultModel := pm.config.AIModels.SelectionPreferences.DefaultModels[providerName]
+		if model, err := pm.registry.GetModel(providerName, defaultModel); err == nil && model.ContextWindow > 0 {
+			wrapper.maxContext = model.ContextWindow
+		}
+	}
+
 	// Cache the provider
 	pm.providers[providerName] = provider
 	pm.logger.Info(fmt.Sprintf("Initialized AI provider: %s", providerName))
@@ -146,10 +159,18 @@ func (pm *ProviderManager) ValidateConfiguration() error {
 
 // RefreshProviders clears the provider cache, forcing reinitialization.
 func (pm *ProviderManager) RefreshProviders() {
+	pm.mu.Lock()
 	pm.providers = make(map[string]Provider)
+	pm.mu.Unlock()
 	pm.logger.Info("Provider cache cleared")
 }
 
+// sampling returns the configured temperature and top_p; the gemini, copilot, llamacpp and
+// custom providers ignore them
+func (pm *ProviderManager) sampling() Sampling {
+	return Sampling{Temperature: pm.config.AISampling.Temperature, TopP: pm.config.AISampling.TopP}
+}
+
 // initializeProvider creates a new provider instance based on configuration.
 func (pm *ProviderManager) initializeProvider(providerName string) (Provider, error) {
 	providerConfig, err := pm.registry.GetProvider(providerName)
@@ -194,9 +215,11 @@ func (pm *ProviderManager) initializeOllamaProvider(config *config.AIProviderCon
 
 	ollamaProvider := NewOllamaProvider(defaultModel)
 
-	// Apply configured timeout
+	// Apply configured timeout and concurrency limit
 	timeout := pm.config.GetAITimeout("ollama")
 	ollamaProvider.SetTimeout(timeout)
+	ollamaProvider.MaxConcurrency = config.MaxConcurrency
+	ollamaProvider.Sampling = pm.sampling()
 
 	pm.logger.Debug(fmt.Sprintf("Ollama provider initialized with %v timeout", timeout))
 
@@ -241,6 +264,7 @@ func (pm *ProviderManager) initializeOpenAIProvider(config *config.AIProviderCon
 	}
 
 	openaiClient := NewOpenAIClientWithModel(apiKey, defaultModel)
+	openaiClient.Sampling = pm.sampling()
 	return NewProviderWrapper(openaiClient), nil
 }
 
@@ -347,6 +371,7 @@ func (pm *ProviderManager) initializeClaudeProvider(config *config.AIProviderCon
 	// Apply configured timeout
 	timeout := pm.config.GetAITimeout("claude")
 	claudeClient.SetTimeout(timeout)
+	claudeClient.Sampling = pm.sampling()
 
 	pm.logger.Debug(fmt.Sprintf("Claude provider initialized with %v timeout", timeout))
 
@@ -378,6 +403,7 @@ func (pm *ProviderManager) initializeGroqProvider(config *config.AIProviderConfi
 	// Apply configured timeout
 	timeout := pm.config.GetAITimeout("groq")
 	groqClient.SetTimeout(timeout)
+	groqClient.Sampling = pm.sampling()
 
 	pm.logger.Debug(fmt.Sprintf("Groq provider initialized with %v timeout", timeout))
 
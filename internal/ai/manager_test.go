@@ -317,3 +317,31 @@ func TestLegacyCompatibility(t *testing.T) {
 		}
 	})
 }
+
+// streamingLegacy is a legacy provider that reports its capabilities
+type streamingLegacy struct{}
+
+func (streamingLegacy) Query(prompt string) (string, error) { return "", nil }
+
+func (streamingLegacy) Capabilities() Capabilities {
+	return Capabilities{SupportsStreaming: true, SupportsSystemPrompt: true, MaxContext: 8192}
+}
+
+// plainLegacy is a legacy provider that reports nothing
+type plainLegacy struct{}
+
+func (plainLegacy) Query(prompt string) (string, error) { return "", nil }
+
+func TestProviderWrapperCapabilities(t *testing.T) {
+	if got := NewProviderWrapper(plainLegacy{}).Capabilities(); got != (Capabilities{}) {
+		t.Errorf("expected no capabilities for a plain legacy provider, got %+v", got)
+	}
+	if got := NewLegacyProviderAdapter(streamingLegacy{}).Capabilities(); !got.SupportsStreaming || got.MaxContext != 8192 {
+		t.Errorf("expected the adapter to report the legacy capabilities, got %+v", got)
+	}
+
+	wrapper := &ProviderWrapper{legacy: streamingLegacy{}, maxContext: 128000}
+	if got := wrapper.Capabilities(); !got.SupportsStreaming || !got.SupportsSystemPrompt || got.MaxContext != 128000 {
+		t.Errorf("expected the configured context window to override the reported one, got %+v", got)
+	}
+}
@@ -0,0 +1,121 @@
+package ai
+
+import "fmt"
+
+// ModelTier is the class of model a task needs
+type ModelTier string
+
+const (
+	// TierDefault uses the provider's default model
+	TierDefault ModelTier = "default"
+	// TierFast is a cheap, quick model for simple lookups
+	TierFast ModelTier = "fast"
+	// TierStrong is a capable model for complex diagnosis and large inputs
+	TierStrong ModelTier = "strong"
+)
+
+const (
+	// strongTaskPromptSize is the prompt length, in characters, from which diagnosis commands
+	// need the strong model, e.g. a log of a few hundred lines
+	strongTaskPromptSize = 4000
+	// largePromptSize is the prompt length, in characters (about 4000 tokens), from which any
+	// command needs the strong model
+	largePromptSize = 16000
+)
+
+// fastTaskCommands are lookups whose answers a small model gets right
+var fastTaskCommands = map[string]bool{
+	"explain-option":      true,
+	"explain-home-option": true,
+	"search":              true,
+	"snippets":            true,
+}
+
+// strongTaskCommands analyse logs and build output, where a small model misses the cause
+var strongTaskCommands = map[string]bool{
+	"diagnose": true,
+	"logs":     true,
+	"build":    true,
+	"doctor":   true,
+	"flake":    true,
+}
+
+// SelectModelForTask picks the model tier for a command and the size of its prompt, and
+// returns it with the configured model of that tier. The model is empty when the tier has no
+// model configured, in which case the provider's default model is used. A tier pinned for the
+// command in ai_models.tiers.commands wins over the heuristic.
+func (pm *ProviderManager) SelectModelForTask(command string, promptSize int) (ModelTier, string) {
+	tiers := pm.config.AIModels.Tiers
+	tier := taskTier(command, promptSize)
+	if pinned, ok := tiers.Commands[command]; ok {
+		switch ModelTier(pinned) {
+		case TierFast, TierStrong, TierDefault:
+			tier = ModelTier(pinned)
+		default:
+			pm.logger.Warn(fmt.Sprintf("Ignoring unknown model tier %q for %s; use fast, strong or default", pinned, command))
+		}
+	}
+
+	switch tier {
+	case TierFast:
+		return tier, tiers.Fast
+	case TierStrong:
+		return tier, tiers.Strong
+	default:
+		return TierDefault, ""
+	}
+}
+
+// taskTier is the heuristic behind SelectModelForTask: large prompts and diagnosis of sizable
+// logs need the strong model, short lookups the fast one, and everything else the default
+func taskTier(command string, promptSize int) ModelTier {
+	switch {
+	case promptSize >= largePromptSize:
+		return TierStrong
+	case strongTaskCommands[command] && promptSize >= strongTaskPromptSize:
+		return TierStrong
+	case fastTaskCommands[command]:
+		return TierFast
+	default:
+		return TierDefault
+	}
+}
+
+// GetProviderForModel returns a provider that queries a specific model instead of the
+// provider's default model. An empty model returns the default provider of GetProvider.
+func (pm *ProviderManager) GetProviderForModel(providerName, modelName string) (Provider, error) {
+	if modelName == "" {
+		return pm.GetProvider(providerName)
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	key := providerName + "@" + modelName
+	if provider, exists := pm.providers[key]; exists {
+		return provider, nil
+	}
+	if _, err := pm.registry.GetProvider(providerName); err != nil {
+		return nil, fmt.Errorf("provider '%s' is not configured: %w", providerName, err)
+	}
+
+	// The providers read their model from the default models, so initialize one with a
+	// configuration whose default model for the provider is modelName
+	cfg := *pm.config
+	defaults := make(map[string]string, len(cfg.AIModels.SelectionPreferences.DefaultModels)+1)
+	for name, model := range cfg.AIModels.SelectionPreferences.DefaultModels {
+		defaults[name] = model
+	}
+	defaults[providerName] = modelName
+	cfg.AIModels.SelectionPreferences.DefaultModels = defaults
+	modelManager := &ProviderManager{registry: pm.registry, config: &cfg, providers: make(map[string]Provider), logger: pm.logger}
+
+	provider, err := modelManager.initializeProvider(providerName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize provider '%s' with model '%s': %w", providerName, modelName, err)
+	}
+
+	pm.providers[key] = provider
+	pm.logger.Info(fmt.Sprintf("Initialized AI provider: %s with model %s", providerName, modelName))
+	return provider, nil
+}
@@ -0,0 +1,99 @@
+package ai
+
+import (
+	"io"
+	"testing"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/logger"
+)
+
+// tieredTestManager returns a manager for ollama with fast and strong models
+func tieredTestManager(pinned map[string]string) *ProviderManager {
+	cfg := &config.UserConfig{
+		AIModels: config.AIModelsConfig{
+			Providers: map[string]config.AIProviderConfig{
+				"ollama": {Available: true, BaseURL: "http://localhost:11434"},
+			},
+			SelectionPreferences: config.AISelectionPreferences{
+				DefaultProvider: "ollama",
+				DefaultModels:   map[string]string{"ollama": "llama3"},
+			},
+			Tiers: config.AIModelTiers{Fast: "llama3.2:1b", Strong: "llama3.1:70b", Commands: pinned},
+		},
+	}
+	return NewProviderManager(cfg, logger.NewLoggerWithWriter(io.Discard))
+}
+
+func TestSelectModelForTask(t *testing.T) {
+	pm := tieredTestManager(nil)
+	tests := []struct {
+		command    string
+		promptSize int
+		wantTier   ModelTier
+		wantModel  string
+	}{
+		{"explain-option", 1500, TierFast, "llama3.2:1b"},
+		{"search", 800, TierFast, "llama3.2:1b"},
+		{"diagnose", 1500, TierDefault, ""},
+		{"diagnose", 25000, TierStrong, "llama3.1:70b"},
+		{"logs", strongTaskPromptSize, TierStrong, "llama3.1:70b"},
+		{"ask", 3000, TierDefault, ""},
+		// Any prompt too large for a small model needs the strong one
+		{"ask", largePromptSize, TierStrong, "llama3.1:70b"},
+		{"explain-option", largePromptSize + 1, TierStrong, "llama3.1:70b"},
+	}
+	for _, tt := range tests {
+		tier, model := pm.SelectModelForTask(tt.command, tt.promptSize)
+		if tier != tt.wantTier || model != tt.wantModel {
+			t.Errorf("SelectModelForTask(%q, %d) = %s, %q; want %s, %q", tt.command, tt.promptSize, tier, model, tt.wantTier, tt.wantModel)
+		}
+	}
+}
+
+func TestSelectModelForTask_PinnedTier(t *testing.T) {
+	pm := tieredTestManager(map[string]string{"explain-option": "strong", "diagnose": "default", "ask": "huge"})
+
+	if tier, model := pm.SelectModelForTask("explain-option", 100); tier != TierStrong || model != "llama3.1:70b" {
+		t.Errorf("pinned explain-option = %s, %q; want strong", tier, model)
+	}
+	if tier, model := pm.SelectModelForTask("diagnose", 50000); tier != TierDefault || model != "" {
+		t.Errorf("pinned diagnose = %s, %q; want default", tier, model)
+	}
+	// An unknown pinned tier is ignored
+	if tier, _ := pm.SelectModelForTask("ask", 100); tier != TierDefault {
+		t.Errorf("ask with an unknown pinned tier = %s, want default", tier)
+	}
+}
+
+func TestSelectModelForTask_UnsetTierModel(t *testing.T) {
+	pm := tieredTestManager(nil)
+	pm.config.AIModels.Tiers.Strong = ""
+
+	if tier, model := pm.SelectModelForTask("diagnose", 20000); tier != TierStrong || model != "" {
+		t.Errorf("got %s, %q; want the strong tier without a model", tier, model)
+	}
+}
+
+func TestGetProviderForModel(t *testing.T) {
+	pm := tieredTestManager(nil)
+
+	fast, err := pm.GetProviderForModel("ollama", "llama3.2:1b")
+	if err != nil {
+		t.Fatalf("GetProviderForModel failed: %v", err)
+	}
+	again, _ := pm.GetProviderForModel("ollama", "llama3.2:1b")
+	if fast != again {
+		t.Error("expected the provider for a model to be cached")
+	}
+	defaultProvider, _ := pm.GetProviderForModel("ollama", "")
+	if defaultProvider == fast {
+		t.Error("expected the default model to use a separate provider")
+	}
+	if got := pm.config.AIModels.SelectionPreferences.DefaultModels["ollama"]; got != "llama3" {
+		t.Errorf("the configured default model changed to %q", got)
+	}
+	if _, err := pm.GetProviderForModel("unknown", "model"); err == nil {
+		t.Error("expected an error for an unconfigured provider")
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"nix-ai-help/pkg/utils"
 )
 
 // OllamaProvider implements the new Provider interface for Ollama.
@@ -18,6 +20,10 @@ type OllamaProvider struct {
 	Model       string
 	Client      *http.Client
 	lastPartial string // Store partial response for token limit cases
+	// MaxConcurrency bounds the queries in flight to Endpoint across all providers;
+	// zero means DefaultOllamaMaxConcurrency
+	MaxConcurrency int
+	Sampling       Sampling
 }
 
 // NewOllamaProvider creates a new OllamaProvider.
@@ -34,17 +40,16 @@ func NewOllamaProvider(model string) *OllamaProvider {
 	return &OllamaProvider{
 		Endpoint: endpoint,
 		Model:    model,
-		Client: &http.Client{
-			Timeout: 60 * time.Second,
-		},
+		Client:   utils.NewHTTPClient(60 * time.Second),
 	}
 }
 
 // ollamaRequest is the request format for Ollama's API.
 type ollamaRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	Stream bool   `json:"stream"`
+	Model   string         `json:"model"`
+	Prompt  string         `json:"prompt"`
+	Stream  bool           `json:"stream"`
+	Options *ollamaOptions `json:"options,omitempty"`
 }
 
 // ollamaResponse is the response format from Ollama's API.
@@ -66,12 +71,28 @@ func (o *OllamaProvider) GenerateResponse(ctx context.Context, prompt string) (s
 	return o.queryWithContext(ctx, prompt)
 }
 
+// slots returns the limiter bounding the queries in flight to the Ollama endpoint
+func (o *OllamaProvider) slots() chan struct{} {
+	limit := o.MaxConcurrency
+	if limit <= 0 {
+		limit = DefaultOllamaMaxConcurrency
+	}
+	return sharedLimiter(o.Endpoint, limit)
+}
+
 // queryWithContext is the internal implementation that handles the actual API call.
 func (o *OllamaProvider) queryWithContext(ctx context.Context, prompt string) (string, error) {
+	release, err := acquireSlot(ctx, o.slots())
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
 	reqBody := ollamaRequest{
-		Model:  o.Model,
-		Prompt: prompt,
-		Stream: false,
+		Model:   o.Model,
+		Prompt:  prompt,
+		Stream:  false,
+		Options: o.Sampling.ollamaOptions(),
 	}
 
 	body, err := json.Marshal(reqBody)
@@ -115,10 +136,19 @@ func (o *OllamaProvider) StreamResponse(ctx context.Context, prompt string) (<-c
 	go func() {
 		defer close(responseChan)
 
+		// Hold the slot until the stream ends
+		release, err := acquireSlot(ctx, o.slots())
+		if err != nil {
+			responseChan <- StreamResponse{Error: err, Done: true}
+			return
+		}
+		defer release()
+
 		reqBody := ollamaRequest{
-			Model:  o.Model,
-			Prompt: prompt,
-			Stream: true,
+			Model:   o.Model,
+			Prompt:  prompt,
+			Stream:  true,
+			Options: o.Sampling.ollamaOptions(),
 		}
 
 		body, err := json.Marshal(reqBody)
@@ -200,6 +230,11 @@ func (o *OllamaProvider) GetPartialResponse() string {
 	return o.lastPartial
 }
 
+// Capabilities reports what Ollama supports
+func (o *OllamaProvider) Capabilities() Capabilities {
+	return Capabilities{SupportsStreaming: true, SupportsSystemPrompt: true, MaxContext: 8192}
+}
+
 // HealthCheck checks if the Ollama server is running and accessible
 func (o *OllamaProvider) HealthCheck() error {
 	// Create a simple health check request
@@ -223,6 +258,41 @@ func (o *OllamaProvider) HealthCheck() error {
 	return nil
 }
 
+// ListModels returns the names of the models installed on the Ollama server
+func (o *OllamaProvider) ListModels(ctx context.Context) ([]string, error) {
+	tagsURL := strings.Replace(o.Endpoint, "/api/generate", "/api/tags", 1)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", tagsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tags request: %w", err)
+	}
+
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ollama server not accessible: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ollama server returned status %d", resp.StatusCode)
+	}
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("failed to decode ollama tags: %w", err)
+	}
+
+	models := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		models = append(models, m.Name)
+	}
+	return models, nil
+}
+
 // SetModel allows changing the model after creation
 func (o *OllamaProvider) SetModel(model string) {
 	if model != "" {
@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"nix-ai-help/pkg/utils"
 )
 
 // OpenAIClient represents a client for interacting with the OpenAI API.
@@ -17,6 +19,7 @@ type OpenAIClient struct {
 	APIURL     string
 	Model      string // Added model support
 	HTTPClient *http.Client
+	Sampling   Sampling
 }
 
 // NewOpenAIClient creates a new OpenAI client with the provided API key.
@@ -25,7 +28,7 @@ func NewOpenAIClient(apiKey string) *OpenAIClient {
 		APIKey:     apiKey,
 		APIURL:     "https://api.openai.com/v1/chat/completions",
 		Model:      "gpt-3.5-turbo", // default model
-		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		HTTPClient: utils.NewHTTPClient(10 * time.Second),
 	}
 }
 
@@ -40,8 +43,10 @@ func NewOpenAIClientWithModel(apiKey, model string) *OpenAIClient {
 
 // Request represents a request to the OpenAI API.
 type Request struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        *float64  `json:"top_p,omitempty"`
 }
 
 // Message represents a message in the chat.
@@ -63,9 +68,11 @@ type Choice struct {
 
 // StreamRequest represents a streaming request to the OpenAI API.
 type StreamRequest struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
-	Stream   bool      `json:"stream"`
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Stream      bool      `json:"stream"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        *float64  `json:"top_p,omitempty"`
 }
 
 // StreamResponse represents a streaming response from OpenAI API.
@@ -86,8 +93,10 @@ type StreamDelta struct {
 // GenerateResponseFromMessages generates a response from the OpenAI API based on the provided messages.
 func (client *OpenAIClient) GenerateResponseFromMessages(messages []Message) (string, error) {
 	request := Request{
-		Model:    client.Model, // Use the configured model
-		Messages: messages,
+		Model:       client.Model, // Use the configured model
+		Messages:    messages,
+		Temperature: client.Sampling.Temperature,
+		TopP:        client.Sampling.TopP,
 	}
 
 	body, err := json.Marshal(request)
@@ -165,8 +174,10 @@ func (client *OpenAIClient) GenerateResponseLegacy(prompt string) (string, error
 // GenerateResponseFromMessagesContext generates a response from the OpenAI API with context support.
 func (client *OpenAIClient) GenerateResponseFromMessagesContext(ctx context.Context, messages []Message) (string, error) {
 	request := Request{
-		Model:    client.Model,
-		Messages: messages,
+		Model:       client.Model,
+		Messages:    messages,
+		Temperature: client.Sampling.Temperature,
+		TopP:        client.Sampling.TopP,
 	}
 
 	body, err := json.Marshal(request)
@@ -220,9 +231,11 @@ func (client *OpenAIClient) StreamResponse(ctx context.Context, prompt string) (
 
 		messages := []Message{{Role: "user", Content: prompt}}
 		request := StreamRequest{
-			Model:    client.Model,
-			Messages: messages,
-			Stream:   true,
+			Model:       client.Model,
+			Messages:    messages,
+			Stream:      true,
+			Temperature: client.Sampling.Temperature,
+			TopP:        client.Sampling.TopP,
 		}
 
 		body, err := json.Marshal(request)
@@ -309,6 +322,11 @@ func (client *OpenAIClient) GetPartialResponse() string {
 	return ""
 }
 
+// Capabilities reports what OpenAI supports
+func (client *OpenAIClient) Capabilities() Capabilities {
+	return Capabilities{SupportsStreaming: true, SupportsSystemPrompt: true, MaxContext: 128000}
+}
+
 // CheckHealth checks if the OpenAI API is accessible and responding.
 func (client *OpenAIClient) CheckHealth() error {
 	// For OpenAI, we can check by making a simple request to the models endpoint
@@ -319,7 +337,7 @@ func (client *OpenAIClient) CheckHealth() error {
 
 	req.Header.Set("Authorization", "Bearer "+client.APIKey)
 
-	httpClient := &http.Client{Timeout: 5 * time.Second}
+	httpClient := utils.NewHTTPClient(5 * time.Second)
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("openAI API not accessible: %w", err)
@@ -11,6 +11,22 @@ type StreamResponse struct {
 	PartialSaved bool // Indicates if partial response was saved for recovery
 }
 
+// Capabilities describes what a provider supports, so that commands can adapt to it
+type Capabilities struct {
+	// SupportsStreaming is true when StreamResponse delivers the answer as it is generated
+	// rather than in one chunk at the end
+	SupportsStreaming bool `json:"supports_streaming"`
+	// SupportsSystemPrompt is true when the API accepts a separate system prompt
+	SupportsSystemPrompt bool `json:"supports_system_prompt"`
+	// MaxContext is the context window in tokens, 0 when unknown
+	MaxContext int `json:"max_context"`
+}
+
+// capabilityReporter is implemented by providers that report their capabilities
+type capabilityReporter interface {
+	Capabilities() Capabilities
+}
+
 // Provider defines the interface that all AI providers must implement.
 // This interface supports both simple queries and context-aware operations.
 type Provider interface {
@@ -22,6 +38,8 @@ type Provider interface {
 	StreamResponse(ctx context.Context, prompt string) (<-chan StreamResponse, error)
 	// Method to get partial response on token limit or other failures
 	GetPartialResponse() string
+	// Capabilities reports what the provider supports
+	Capabilities() Capabilities
 }
 
 // AIProvider is the legacy interface for backward compatibility.
@@ -100,6 +118,15 @@ func (a *LegacyProviderAdapter) GetPartialResponse() string {
 	return a.lastPartial
 }
 
+// Capabilities returns the capabilities of the legacy provider when it reports them. Otherwise
+// nothing is assumed: StreamResponse sends the whole answer as one chunk.
+func (a *LegacyProviderAdapter) Capabilities() Capabilities {
+	if reporter, ok := a.legacy.(capabilityReporter); ok {
+		return reporter.Capabilities()
+	}
+	return Capabilities{}
+}
+
 // ProviderFactory manages registration and retrieval of AI providers.
 type ProviderFactory struct {
 	providers map[string]Provider
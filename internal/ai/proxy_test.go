@@ -0,0 +1,45 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nix-ai-help/pkg/utils"
+)
+
+func TestProvidersUseConfiguredProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.Host)
+		_, _ = w.Write([]byte(`{"response":"ok","done":true}`))
+	}))
+	defer proxy.Close()
+	if err := utils.SetHTTPProxy(proxy.URL); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = utils.SetHTTPProxy("") }()
+
+	ollama := NewOllamaProvider("llama3")
+	ollama.Endpoint = "http://ollama.example.test:11434/api/generate"
+	answer, err := ollama.Query(context.Background(), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != "ok" || len(proxied) != 1 || proxied[0] != "ollama.example.test:11434" {
+		t.Errorf("expected the query to go through the proxy, got %q via %v", answer, proxied)
+	}
+
+	for name, client := range map[string]*http.Client{
+		"claude":  NewClaudeClient("key").HTTPClient,
+		"groq":    NewGroqClient("key").HTTPClient,
+		"openai":  NewOpenAIClient("key").HTTPClient,
+		"copilot": NewCopilotClient("key").HTTPClient,
+	} {
+		transport, ok := client.Transport.(*http.Transport)
+		if !ok || transport.Proxy == nil {
+			t.Errorf("%s client does not use the proxy-aware transport", name)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package roles
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultForbiddenPatterns are commands that NixOS advice must never recommend.
+var DefaultForbiddenPatterns = []string{"nix-env"}
+
+// AskAccuracyRules are the NixOS accuracy rules shared by every ask mode.
+const AskAccuracyRules = "❌ NEVER recommend manual installation\n" +
+	"❌ NEVER use incorrect flake syntax like 'nixpkgs.nix = {...}'\n" +
+	"❌ NEVER suggest outdated or deprecated options\n\n" +
+	"✅ BLUETOOTH SPECIFIC RULES:\n" +
+	"✅ ALWAYS use 'hardware.bluetooth.enable = true;' for Bluetooth (NOT services.bluetooth.enable)\n" +
+	"✅ Use 'services.blueman.enable = true;' ONLY if user needs a GUI manager\n" +
+	"✅ Mention that both hardware.bluetooth.enable AND services.blueman.enable may be needed\n\n" +
+	"✅ ALWAYS USE configuration.nix for system packages\n" +
+	"✅ ALWAYS USE services.* options for services\n" +
+	"✅ ALWAYS use correct flake syntax: inputs.nixpkgs.url = \"github:...\" and outputs = { self, nixpkgs }: {...}\n" +
+	"✅ ALWAYS verify package names and option paths with provided search results\n" +
+	"✅ ALWAYS end with 'sudo nixos-rebuild switch' for configuration changes\n" +
+	"✅ ALWAYS use examples from the provided real-world GitHub configurations when available\n\n"
+
+// ForbiddenPatterns returns the default forbidden patterns followed by the configured extras, without duplicates.
+func ForbiddenPatterns(configured []string) []string {
+	seen := make(map[string]bool)
+	var patterns []string
+	for _, p := range append(append([]string{}, DefaultForbiddenPatterns...), configured...) {
+		p = strings.TrimSpace(p)
+		key := strings.ToLower(p)
+		if p == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		patterns = append(patterns, p)
+	}
+	return patterns
+}
+
+// AskGuidelines builds the guideline block prepended to ask prompts from the forbidden patterns.
+func AskGuidelines(forbidden []string) string {
+	var b strings.Builder
+	b.WriteString("ATTENTION: You are a NixOS expert with access to multiple verified sources.")
+	if len(forbidden) > 0 {
+		fmt.Fprintf(&b, " NEVER EVER suggest %s commands!", forbidden[0])
+	}
+	b.WriteString("\n\nCRITICAL ACCURACY RULES:\n")
+	for _, p := range forbidden {
+		fmt.Fprintf(&b, "❌ NEVER suggest '%s' or anything using it\n", p)
+	}
+	b.WriteString(AskAccuracyRules)
+	return b.String()
+}
+
+// FindForbiddenPatterns returns the forbidden patterns that appear in a response (case-insensitive).
+// A pattern only counts as a whole word or command: nix-env matches "nix-env -iA hello" but not
+// "nix-environment" or "my-nix-env".
+func FindForbiddenPatterns(response string, forbidden []string) []string {
+	lower := strings.ToLower(response)
+	var found []string
+	for _, p := range forbidden {
+		if containsWord(lower, strings.ToLower(p)) {
+			found = append(found, p)
+		}
+	}
+	return found
+}
+
+// containsWord reports whether pattern occurs in s without a word character directly before or
+// after it. Edges of the pattern that are not word characters, as in "curl | sh", need no boundary.
+func containsWord(s, pattern string) bool {
+	if pattern == "" {
+		return false
+	}
+	for start := 0; ; {
+		i := strings.Index(s[start:], pattern)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(pattern)
+		before := i == 0 || !isWordByte(pattern[0]) || !isWordByte(s[i-1])
+		after := end == len(s) || !isWordByte(pattern[len(pattern)-1]) || !isWordByte(s[end])
+		if before && after {
+			return true
+		}
+		start = i + 1
+	}
+}
+
+// isWordByte reports whether b belongs to a command or word; dashes and underscores do, so that
+// nix-env is not found inside nix-environment
+func isWordByte(b byte) bool {
+	return b == '-' || b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= 0x80
+}
@@ -0,0 +1,25 @@
+package ai
+
+// Sampling holds the sampling parameters sent with each query; nil values keep the provider's
+// default. Providers whose API has no such parameters ignore them.
+type Sampling struct {
+	// Temperature controls randomness: low values give repeatable answers for generated
+	// configuration, higher values more varied ones for brainstorming
+	Temperature *float64
+	// TopP limits sampling to the most likely tokens whose probabilities add up to TopP
+	TopP *float64
+}
+
+// ollamaOptions is the options object of an Ollama request
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+}
+
+// ollamaOptions returns the Ollama request options for the sampling, nil when none are set
+func (s Sampling) ollamaOptions() *ollamaOptions {
+	if s.Temperature == nil && s.TopP == nil {
+		return nil
+	}
+	return &ollamaOptions{Temperature: s.Temperature, TopP: s.TopP}
+}
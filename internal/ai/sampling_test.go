@@ -0,0 +1,98 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/logger"
+)
+
+// recordRequests starts a server that records each JSON request body and answers with reply
+func recordRequests(t *testing.T, reply string) (*httptest.Server, *[]map[string]interface{}) {
+	t.Helper()
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		bodies = append(bodies, body)
+		_, _ = w.Write([]byte(reply))
+	}))
+	t.Cleanup(server.Close)
+	return server, &bodies
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+func TestOpenAISendsSampling(t *testing.T) {
+	server, bodies := recordRequests(t, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+	client := NewOpenAIClient("key")
+	client.APIURL = server.URL
+
+	if _, err := client.Query("hello"); err != nil {
+		t.Fatal(err)
+	}
+	client.Sampling = Sampling{Temperature: float64Ptr(0), TopP: float64Ptr(0.9)}
+	if _, err := client.QueryWithContext(context.Background(), "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*bodies) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(*bodies))
+	}
+	if _, ok := (*bodies)[0]["temperature"]; ok {
+		t.Errorf("unset temperature was sent: %v", (*bodies)[0])
+	}
+	if (*bodies)[1]["temperature"] != 0.0 || (*bodies)[1]["top_p"] != 0.9 {
+		t.Errorf("sampling missing from request: %v", (*bodies)[1])
+	}
+}
+
+func TestOllamaSendsSamplingFromConfig(t *testing.T) {
+	server, bodies := recordRequests(t, `{"response":"ok","done":true}`)
+	t.Setenv("OLLAMA_ENDPOINT", "")
+	cfg := &config.UserConfig{
+		AIModels: config.AIModelsConfig{
+			Providers: map[string]config.AIProviderConfig{
+				"ollama": {Available: true, BaseURL: server.URL},
+			},
+		},
+		AISampling: config.AISamplingConfig{Temperature: float64Ptr(0.2), TopP: float64Ptr(0.5)},
+	}
+
+	provider, err := NewProviderManager(cfg, logger.NewLogger()).GetProvider("ollama")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Query("hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*bodies) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(*bodies))
+	}
+	options, _ := (*bodies)[0]["options"].(map[string]interface{})
+	if options["temperature"] != 0.2 || options["top_p"] != 0.5 {
+		t.Errorf("sampling missing from ollama options: %v", (*bodies)[0])
+	}
+}
+
+func TestOllamaOmitsUnsetSampling(t *testing.T) {
+	server, bodies := recordRequests(t, `{"response":"ok","done":true}`)
+	provider := NewOllamaProvider("llama3")
+	provider.Endpoint = server.URL
+
+	if _, err := provider.Query(context.Background(), "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := (*bodies)[0]["options"]; ok {
+		t.Errorf("options sent without sampling: %v", (*bodies)[0])
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"nix-ai-help/pkg/logger"
+	"nix-ai-help/pkg/utils"
 )
 
 // AutomatedQualityScorer uses local Nix commands to provide comprehensive quality scoring
@@ -162,7 +163,7 @@ func (aqs *AutomatedQualityScorer) validateSyntax(ctx context.Context, answer st
 		cmd.Stdin = strings.NewReader(expr)
 		score.CommandsRun = append(score.CommandsRun, "nix-instantiate --parse")
 
-		output, err := cmd.CombinedOutput()
+		output, err := utils.TraceCombinedOutput(cmd)
 		if err == nil {
 			validExpressions++
 			score.ValidationResults.SyntaxValid = true
@@ -211,7 +212,7 @@ func (aqs *AutomatedQualityScorer) validatePackages(ctx context.Context, answer
 		cmd := exec.CommandContext(ctx, "nix", "search", "nixpkgs", pkg, "--json")
 		score.CommandsRun = append(score.CommandsRun, fmt.Sprintf("nix search nixpkgs %s --json", pkg))
 
-		output, err := cmd.Output()
+		output, err := utils.TraceOutput(cmd)
 		if err == nil && len(output) > 2 {
 			result.Exists = true
 
@@ -237,7 +238,7 @@ func (aqs *AutomatedQualityScorer) validatePackages(ctx context.Context, answer
 			cmd = exec.CommandContext(ctx, "nix-env", "-qaP", pkg)
 			score.CommandsRun = append(score.CommandsRun, fmt.Sprintf("nix-env -qaP %s", pkg))
 
-			if output, err := cmd.Output(); err == nil && len(output) > 0 {
+			if output, err := utils.TraceOutput(cmd); err == nil && len(output) > 0 {
 				result.Exists = true
 				result.AttrPath = strings.TrimSpace(string(output))
 				validPackages++
@@ -280,7 +281,7 @@ func (aqs *AutomatedQualityScorer) validateOptions(ctx context.Context, answer s
 		cmd := exec.CommandContext(ctx, "nixos-option", opt)
 		score.CommandsRun = append(score.CommandsRun, fmt.Sprintf("nixos-option %s", opt))
 
-		output, err := cmd.Output()
+		output, err := utils.TraceOutput(cmd)
 		if err == nil && len(output) > 0 {
 			result.Valid = true
 			validOptions++
@@ -336,7 +337,7 @@ func (aqs *AutomatedQualityScorer) validateCommands(ctx context.Context, answer
 			whichCmd := exec.CommandContext(ctx, "which", binary)
 			score.CommandsRun = append(score.CommandsRun, fmt.Sprintf("which %s", binary))
 
-			if err := whichCmd.Run(); err == nil {
+			if err := utils.TraceRun(whichCmd); err == nil {
 				result.Available = true
 				result.Valid = true
 				validCommands++
@@ -416,7 +417,7 @@ func (aqs *AutomatedQualityScorer) validateFlakeStructure(ctx context.Context, a
 	cmd := exec.CommandContext(ctx, "nix", "flake", "check", tmpDir, "--no-build")
 	score.CommandsRun = append(score.CommandsRun, "nix flake check --no-build")
 
-	return cmd.Run() == nil
+	return utils.TraceRun(cmd) == nil
 }
 
 // Helper methods for extraction
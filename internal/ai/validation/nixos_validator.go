@@ -3,6 +3,8 @@ package validation
 import (
 	"regexp"
 	"strings"
+
+	"nix-ai-help/internal/ai/roles"
 )
 
 // NixOSValidationResult represents the result of NixOS configuration validation
@@ -38,6 +40,8 @@ type NixOSValidator struct {
 	optionCorrections map[string]string
 	// Common incorrect patterns
 	incorrectPatterns []NixOSIncorrectPattern
+	// Commands that advice must never recommend, see roles.ForbiddenPatterns
+	forbiddenPatterns []string
 }
 
 // NixOSIncorrectPattern represents a pattern that indicates incorrect NixOS configuration
@@ -142,9 +146,16 @@ func NewNixOSValidator() *NixOSValidator {
 	return &NixOSValidator{
 		optionCorrections: optionCorrections,
 		incorrectPatterns: incorrectPatterns,
+		forbiddenPatterns: roles.ForbiddenPatterns(nil),
 	}
 }
 
+// SetForbiddenPatterns replaces the forbidden-advice patterns checked, e.g. with those
+// configured under ai_models.forbidden_patterns
+func (nv *NixOSValidator) SetForbiddenPatterns(patterns []string) {
+	nv.forbiddenPatterns = patterns
+}
+
 // ValidateNixOSContent validates NixOS configuration content
 func (nv *NixOSValidator) ValidateNixOSContent(content string) *NixOSValidationResult {
 	result := &NixOSValidationResult{
@@ -220,9 +231,41 @@ func (nv *NixOSValidator) ValidateNixOSContent(content string) *NixOSValidationR
 		}
 	}
 
+	// Check for forbidden advice not already reported by an incorrect pattern
+	for _, forbidden := range roles.FindForbiddenPatterns(content, nv.forbiddenPatterns) {
+		if reportedPattern(result, forbidden) {
+			continue
+		}
+		result.Warnings = append(result.Warnings, NixOSValidationWarning{
+			Type:        "forbidden_pattern",
+			Message:     "Recommending forbidden advice '" + forbidden + "'",
+			Suggestion:  "Use declarative configuration in configuration.nix or flake.nix instead",
+			LinePattern: forbidden,
+		})
+		if result.Severity == "low" || result.Severity == "medium" {
+			result.Severity = "high"
+		}
+	}
+
 	return result
 }
 
+// reportedPattern reports whether an error or warning of result already quotes pattern
+func reportedPattern(result *NixOSValidationResult, pattern string) bool {
+	pattern = strings.ToLower(pattern)
+	for _, err := range result.Errors {
+		if strings.Contains(strings.ToLower(err.LinePattern), pattern) {
+			return true
+		}
+	}
+	for _, warning := range result.Warnings {
+		if strings.Contains(strings.ToLower(warning.LinePattern), pattern) {
+			return true
+		}
+	}
+	return false
+}
+
 // FormatNixOSValidationResult formats the validation result for display
 func (nv *NixOSValidator) FormatNixOSValidationResult(result *NixOSValidationResult) string {
 	if result.IsValid && len(result.Warnings) == 0 {
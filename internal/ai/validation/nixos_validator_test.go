@@ -0,0 +1,24 @@
+package validation
+
+import "testing"
+
+func TestNixOSValidatorForbiddenPatterns(t *testing.T) {
+	validator := NewNixOSValidator()
+	validator.SetForbiddenPatterns([]string{"nix-env", "curl | sh"})
+
+	result := validator.ValidateNixOSContent("Install it with curl | sh, then add hello to environment.systemPackages")
+	if len(result.Warnings) != 1 || result.Warnings[0].Type != "forbidden_pattern" || result.Severity != "high" {
+		t.Errorf("expected one forbidden_pattern warning, got %+v (severity %s)", result.Warnings, result.Severity)
+	}
+
+	// nix-env -i is already reported as a deprecated command, and nix-environment is not nix-env
+	result = validator.ValidateNixOSContent("Run nix-env -iA nixos.hello in your nix-environment")
+	for _, warning := range result.Warnings {
+		if warning.Type == "forbidden_pattern" {
+			t.Errorf("nix-env reported twice: %+v", result.Warnings)
+		}
+	}
+	if len(result.Warnings) != 1 {
+		t.Errorf("expected the deprecated command warning, got %+v", result.Warnings)
+	}
+}
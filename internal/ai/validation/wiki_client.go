@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"nix-ai-help/pkg/logger"
+	"nix-ai-help/pkg/utils"
 )
 
 // WikiClient handles interactions with the NixOS Wiki
@@ -69,9 +70,7 @@ type MediaWikiAPIResponse struct {
 // NewWikiClient creates a new wiki client
 func NewWikiClient() *WikiClient {
 	return &WikiClient{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:  utils.NewHTTPClient(30 * time.Second),
 		baseURL: "https://wiki.nixos.org",
 		logger:  logger.NewLogger(),
 	}
@@ -5,6 +5,9 @@ import "context"
 // ProviderWrapper wraps a legacy AIProvider to implement the new Provider interface
 type ProviderWrapper struct {
 	legacy AIProvider
+	// maxContext overrides the context window reported by the legacy provider, e.g. with the
+	// context_window of the configured model
+	maxContext int
 }
 
 // NewProviderWrapper creates a new provider wrapper
@@ -76,3 +79,16 @@ func (w *ProviderWrapper) GetPartialResponse() string {
 	}
 	return ""
 }
+
+// Capabilities returns the capabilities of the legacy provider when it reports them, with the
+// configured context window if one is set
+func (w *ProviderWrapper) Capabilities() Capabilities {
+	var capabilities Capabilities
+	if reporter, ok := w.legacy.(capabilityReporter); ok {
+		capabilities = reporter.Capabilities()
+	}
+	if w.maxContext > 0 {
+		capabilities.MaxContext = w.maxContext
+	}
+	return capabilities
+}
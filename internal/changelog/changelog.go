@@ -0,0 +1,141 @@
+// Package changelog reads the release notes embedded in nixai and tracks which release the
+// user has last seen, so the TUI can show what changed since their last upgrade.
+package changelog
+
+import (
+	_ "embed"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed CHANGELOG.md
+var embedded string
+
+// releaseHeading matches a release heading such as "## [1.0.7] - 2025-06-14"
+var releaseHeading = regexp.MustCompile(`^##\s+\[?v?([0-9][^\]\s]*)\]?(?:\s+-\s+(\S+))?\s*$`)
+
+// Entry is the notes of one release
+type Entry struct {
+	Version string
+	Date    string
+	// Notes is the Markdown below the release heading
+	Notes string
+}
+
+// Entries returns the releases of the embedded changelog, newest first
+func Entries() []Entry {
+	return SortNewestFirst(Parse(embedded))
+}
+
+// SortNewestFirst sorts entries by version number, highest first, and returns them. Entries of
+// the same version keep their order.
+func SortNewestFirst(entries []Entry) []Entry {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return CompareVersions(entries[i].Version, entries[j].Version) > 0
+	})
+	return entries
+}
+
+// Parse splits a Markdown changelog into its releases, in the order they appear. Text before
+// the first release heading is skipped.
+func Parse(markdown string) []Entry {
+	var entries []Entry
+	var notes strings.Builder
+	flush := func() {
+		if len(entries) > 0 {
+			entries[len(entries)-1].Notes = strings.TrimSpace(notes.String())
+		}
+		notes.Reset()
+	}
+	for _, line := range strings.Split(markdown, "\n") {
+		if m := releaseHeading.FindStringSubmatch(strings.TrimRight(line, "\r")); m != nil {
+			flush()
+			entries = append(entries, Entry{Version: m[1], Date: m[2]})
+			continue
+		}
+		notes.WriteString(line + "\n")
+	}
+	flush()
+	return entries
+}
+
+// Since returns the entries with a version number above lastSeen and, when current is set, not
+// above current, keeping their order. An empty lastSeen means nothing was seen yet. The upper
+// bound keeps notes numbered above the running version, such as those of an older numbering
+// scheme, from being shown as new.
+func Since(entries []Entry, lastSeen, current string) []Entry {
+	var shown []Entry
+	for _, entry := range entries {
+		if lastSeen != "" && CompareVersions(entry.Version, lastSeen) <= 0 {
+			continue
+		}
+		if current != "" && CompareVersions(entry.Version, current) > 0 {
+			continue
+		}
+		shown = append(shown, entry)
+	}
+	return shown
+}
+
+// CompareVersions compares two dotted version numbers, returning -1, 0 or 1. Missing parts
+// count as zero and pre-release suffixes such as -rc1 are ignored.
+func CompareVersions(a, b string) int {
+	as := versionParts(a)
+	bs := versionParts(b)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionParts returns the numeric parts of a version such as v1.2.3-rc1
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(version, "v")
+	version, _, _ = strings.Cut(version, "-")
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		n, _ := strconv.Atoi(part)
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// LastSeenPath is the file recording the last release the user has seen; replaced in tests
+var LastSeenPath = func() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".config", "nixai", "last-seen-version")
+}
+
+// LastSeen returns the last release the user has seen, or "" if none was recorded
+func LastSeen() string {
+	data, err := os.ReadFile(LastSeenPath())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// MarkSeen records version as the last release the user has seen
+func MarkSeen(version string) error {
+	path := LastSeenPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(version+"\n"), 0o600)
+}
@@ -0,0 +1,134 @@
+package changelog
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nix-ai-help/pkg/version"
+)
+
+const testChangelog = `# Changelog
+
+Intro text.
+
+## [1.2.0] - 2025-07-01
+
+### Features
+
+- **Flake dry run**: preview lock changes
+
+## [1.1.0] - 2025-06-20
+
+- Faster search
+
+## [1.0.9] - 2025-06-15
+
+- Bug fixes
+`
+
+func versions(entries []Entry) string {
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Version)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestParse(t *testing.T) {
+	entries := Parse(testChangelog)
+	if got := versions(entries); got != "1.2.0,1.1.0,1.0.9" {
+		t.Fatalf("versions = %q", got)
+	}
+	if entries[0].Date != "2025-07-01" || !strings.Contains(entries[0].Notes, "**Flake dry run**") {
+		t.Errorf("unexpected first entry %+v", entries[0])
+	}
+	if strings.Contains(entries[0].Notes, "Faster search") {
+		t.Error("notes run into the next release")
+	}
+}
+
+func TestSince(t *testing.T) {
+	entries := Parse(testChangelog)
+	tests := []struct {
+		lastSeen, current string
+		want              string
+	}{
+		{"1.0.9", "", "1.2.0,1.1.0"},
+		{"v1.1.0", "", "1.2.0"},
+		{"1.2.0", "", ""},
+		{"", "", "1.2.0,1.1.0,1.0.9"},
+		// Versions missing from the changelog are compared by number
+		{"1.0.10", "", "1.2.0,1.1.0"},
+		{"2.0.0", "", ""},
+		// Nothing above the running version is new
+		{"1.0.9", "1.1.0", "1.1.0"},
+		{"", "v1.1.0", "1.1.0,1.0.9"},
+	}
+	for _, tt := range tests {
+		if got := versions(Since(entries, tt.lastSeen, tt.current)); got != tt.want {
+			t.Errorf("Since(%q, %q) = %q, want %q", tt.lastSeen, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestSortNewestFirst(t *testing.T) {
+	entries := SortNewestFirst(Parse("## [1.0.9]\n## [1.2.0]\n## [1.0.10]\n## [v1.1.0] - 2025-06-20\n"))
+	if got := versions(entries); got != "1.2.0,1.1.0,1.0.10,1.0.9" {
+		t.Errorf("versions = %q, want them by version number", got)
+	}
+	// Releases out of file order must not be reported as new after an upgrade
+	if got := versions(Since(entries, "1.0.9", "1.1.0")); got != "1.1.0,1.0.10" {
+		t.Errorf("Since = %q, want 1.1.0,1.0.10", got)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.10", "1.0.9", 1},
+		{"1.0", "1.0.0", 0},
+		{"v1.2.3", "1.2.3-rc1", 0},
+		{"0.9.9", "1.0.0", -1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLastSeenRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nixai", "last-seen-version")
+	original := LastSeenPath
+	LastSeenPath = func() string { return path }
+	t.Cleanup(func() { LastSeenPath = original })
+
+	if got := LastSeen(); got != "" {
+		t.Errorf("expected nothing seen yet, got %q", got)
+	}
+	if err := MarkSeen("1.1.0"); err != nil {
+		t.Fatal(err)
+	}
+	if got := LastSeen(); got != "1.1.0" {
+		t.Errorf("LastSeen() = %q, want 1.1.0", got)
+	}
+}
+
+func TestEmbeddedChangelog(t *testing.T) {
+	entries := Entries()
+	if len(entries) == 0 || entries[0].Version == "" || entries[0].Notes == "" {
+		t.Fatalf("the embedded changelog has no releases: %+v", entries)
+	}
+	for i := 1; i < len(entries); i++ {
+		if CompareVersions(entries[i-1].Version, entries[i].Version) < 0 {
+			t.Errorf("release %s is listed before the higher %s", entries[i-1].Version, entries[i].Version)
+		}
+	}
+	// The newest release notes up to the running version are those of the version being built
+	if released := Since(entries, "", version.Version); len(released) == 0 || released[0].Version != version.Version {
+		t.Errorf("the changelog has no release notes for nixai %s", version.Version)
+	}
+}
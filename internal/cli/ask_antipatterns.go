@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"nix-ai-help/pkg/utils"
+)
+
+// questionAntipattern is a likely mistaken premise in an ask question, with the correction to
+// give the user
+type questionAntipattern struct {
+	pattern    *regexp.Regexp
+	correction string
+}
+
+// questionAntipatterns are checked locally before a question is sent to the AI provider
+var questionAntipatterns = []questionAntipattern{
+	{
+		pattern: regexp.MustCompile(`(?i)\bnix-env\b`),
+		correction: "nix-env installs packages imperatively into a user profile; on NixOS add them to " +
+			"environment.systemPackages (or home.packages with Home Manager), or try them with 'nix-shell -p'",
+	},
+	{
+		pattern: regexp.MustCompile(`(?i)\b(apt|apt-get|aptitude|yum|dnf|pacman)\s+(-\S+\s+)*(install|remove|purge|update|upgrade|search)\b|\b(apt-get|dpkg)\b`),
+		correction: "NixOS has no apt, dpkg or other distribution package managers; packages come from nixpkgs " +
+			"and are declared in configuration.nix",
+	},
+	{
+		pattern:    regexp.MustCompile(`(?i)/etc/apt\b`),
+		correction: "/etc/apt does not exist on NixOS; package sources are nixpkgs channels or flake inputs",
+	},
+}
+
+// DetectQuestionAntipatterns returns corrections for obviously wrong premises in a question,
+// such as installing packages with nix-env or apt, in the order of questionAntipatterns
+func DetectQuestionAntipatterns(q string) []string {
+	var corrections []string
+	for _, antipattern := range questionAntipatterns {
+		if antipattern.pattern.MatchString(q) {
+			corrections = append(corrections, antipattern.correction)
+		}
+	}
+	return corrections
+}
+
+// antipatternContext notes the corrections for a question on out and returns them as prompt
+// context asking the AI to correct the premise gently; empty when the question looks fine
+func antipatternContext(out io.Writer, question string) string {
+	corrections := DetectQuestionAntipatterns(question)
+	if len(corrections) == 0 {
+		return ""
+	}
+	for _, correction := range corrections {
+		_, _ = fmt.Fprintln(out, utils.FormatNote(correction))
+	}
+	return "\n\nPREMISE CHECK: The question likely rests on a mistaken premise. Gently correct it before " +
+		"answering, then give the NixOS way:\n- " + strings.Join(corrections, "\n- ")
+}
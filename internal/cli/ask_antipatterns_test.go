@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDetectQuestionAntipatterns(t *testing.T) {
+	tests := []struct {
+		question string
+		expected []string // substrings of the expected corrections, in order
+	}{
+		{"How do I nix-env install firefox?", []string{"nix-env"}},
+		{"sudo apt install vim doesn't work", []string{"no apt"}},
+		{"apt-get update fails on my NixOS box", []string{"no apt"}},
+		{"Where is /etc/apt/sources.list on NixOS?", []string{"/etc/apt"}},
+		{"nix-env -iA nixos.firefox vs pacman -S install?", []string{"nix-env", "no apt"}},
+		{"How do I add firefox to environment.systemPackages?", nil},
+		{"I'm apt to forget, how do I enable openssh?", nil},
+		{"What does the nix-environment variable NIX_PATH do?", nil},
+	}
+
+	for _, tt := range tests {
+		corrections := DetectQuestionAntipatterns(tt.question)
+		if len(corrections) != len(tt.expected) {
+			t.Errorf("DetectQuestionAntipatterns(%q) = %v, expected %d correction(s)", tt.question, corrections, len(tt.expected))
+			continue
+		}
+		for i, want := range tt.expected {
+			if !strings.Contains(corrections[i], want) {
+				t.Errorf("DetectQuestionAntipatterns(%q)[%d] = %q, expected it to mention %q", tt.question, i, corrections[i], want)
+			}
+		}
+	}
+}
+
+func TestAntipatternContext(t *testing.T) {
+	var out bytes.Buffer
+	if context := antipatternContext(&out, "How do I enable nginx?"); context != "" || out.Len() != 0 {
+		t.Errorf("expected no context and no note, got %q and %q", context, out.String())
+	}
+
+	context := antipatternContext(&out, "nix-env -i htop")
+	if !strings.Contains(context, "PREMISE CHECK") || !strings.Contains(context, "environment.systemPackages") {
+		t.Errorf("expected a premise correction in the prompt, got %q", context)
+	}
+	if !strings.Contains(out.String(), "nix-env installs packages imperatively") {
+		t.Errorf("expected a note for the user, got %q", out.String())
+	}
+}
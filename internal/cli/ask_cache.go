@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"nix-ai-help/internal/ai"
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/utils"
+)
+
+// askCacheTTL is how long a cached ask answer is reused
+const askCacheTTL = 24 * time.Hour
+
+// askCacheStatsFile holds the hit and miss counters in the ask cache directory
+const askCacheStatsFile = "stats.json"
+
+// Flags of ask controlling the response cache
+var (
+	askFresh         bool
+	askCacheStats    bool
+	askExplainCached bool
+)
+
+// askCacheDir returns the location of cached ask answers; replaced in tests
+var askCacheDir = func() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".cache", "nixai", "ask-cache")
+}
+
+// askCacheEntry is a cached ask answer
+type askCacheEntry struct {
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model"`
+	Question  string    `json:"question"`
+	Response  string    `json:"response"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// askCacheCounters counts the lookups of the ask cache
+type askCacheCounters struct {
+	Hits   int `json:"hits"`
+	Misses int `json:"misses"`
+}
+
+// askCacheKey identifies an answer by provider, model, sampling and the complete prompt, so
+// that changed documentation, search results, follow-up context or a different temperature
+// never reuse an old answer
+func askCacheKey(provider, model string, sampling config.AISamplingConfig, prompt string) string {
+	parts := []string{provider, model, samplingKey(sampling.Temperature), samplingKey(sampling.TopP), prompt}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(parts, "\x00"))))
+}
+
+// samplingKey writes a sampling setting in the cache key; unset stays empty
+func samplingKey(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'g', -1, 64)
+}
+
+// askCacheModel returns the model answering for provider: the --model flag or the configured default
+func askCacheModel(cfg *config.UserConfig, provider, modelParam string) string {
+	if modelParam != "" {
+		return modelParam
+	}
+	return cfg.AIModels.SelectionPreferences.DefaultModels[provider]
+}
+
+// loadAskCacheEntry returns the entry stored under key if it is younger than askCacheTTL
+func loadAskCacheEntry(dir, key string, now time.Time) (*askCacheEntry, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, key+".json"))
+	if err != nil {
+		return nil, false
+	}
+	var entry askCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Response == "" {
+		return nil, false
+	}
+	if now.Sub(entry.CreatedAt) > askCacheTTL {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// saveAskCacheEntry stores an answer under key
+func saveAskCacheEntry(dir, key string, entry askCacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, key+".json"), data, 0600)
+}
+
+// loadAskCacheCounters reads the hit and miss counters; a missing file counts as zero
+func loadAskCacheCounters(dir string) askCacheCounters {
+	var counters askCacheCounters
+	if data, err := os.ReadFile(filepath.Join(dir, askCacheStatsFile)); err == nil {
+		_ = json.Unmarshal(data, &counters)
+	}
+	return counters
+}
+
+// recordAskCacheLookup counts a cache hit or miss
+func recordAskCacheLookup(dir string, hit bool) {
+	counters := loadAskCacheCounters(dir)
+	if hit {
+		counters.Hits++
+	} else {
+		counters.Misses++
+	}
+	data, err := json.Marshal(counters)
+	if err != nil || os.MkdirAll(dir, 0755) != nil {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(dir, askCacheStatsFile), data, 0600)
+}
+
+// cachedAskQuery answers prompt from the ask cache when it holds a recent answer and --fresh is
+// not set; otherwise it queries the provider and caches the answer. The returned entry is set
+// only for answers served from the cache. Cache failures never fail the question.
+func cachedAskQuery(ctx context.Context, provider ai.Provider, providerName, model string, sampling config.AISamplingConfig, question, prompt string) (string, *askCacheEntry, error) {
+	dir := askCacheDir()
+	key := askCacheKey(providerName, model, sampling, prompt)
+	if !askFresh {
+		if entry, ok := loadAskCacheEntry(dir, key, time.Now()); ok {
+			recordAskCacheLookup(dir, true)
+			return entry.Response, entry, nil
+		}
+	}
+
+	response, err := queryAskProvider(ctx, provider, prompt)
+	if err != nil {
+		return "", nil, err
+	}
+	recordAskCacheLookup(dir, false)
+	_ = saveAskCacheEntry(dir, key, askCacheEntry{
+		Provider:  providerName,
+		Model:     model,
+		Question:  question,
+		Response:  response,
+		CreatedAt: time.Now(),
+	})
+	return response, nil, nil
+}
+
+// cacheAge describes how long ago an answer was cached, such as "2h ago"
+func cacheAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
+// writeCacheMarker marks an answer served from the cache and, with --explain-why-cached, explains
+// why it was reused and how to regenerate it
+func writeCacheMarker(out io.Writer, entry *askCacheEntry, now time.Time) {
+	if entry == nil {
+		if askExplainCached {
+			_, _ = fmt.Fprintln(out, utils.FormatNote(fmt.Sprintf("Fresh answer: no answer to the same question and context was cached in the last %.0f hours", askCacheTTL.Hours())))
+		}
+		return
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatNote("(cached "+cacheAge(now.Sub(entry.CreatedAt))+")"))
+	if !askExplainCached {
+		return
+	}
+	model := entry.Provider
+	if entry.Model != "" {
+		model += "/" + entry.Model
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Why cached", "the same question, sources and context were sent to "+model+" before"))
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Answered", entry.CreatedAt.Format("2006-01-02 15:04")))
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Expires", entry.CreatedAt.Add(askCacheTTL).Format("2006-01-02 15:04")))
+	_, _ = fmt.Fprintln(out, utils.FormatTip("Run the question again with --fresh to regenerate the answer"))
+}
+
+// showAskCacheStats prints the hit and miss counts and the size of the ask cache
+func showAskCacheStats(out io.Writer) {
+	dir := askCacheDir()
+	counters := loadAskCacheCounters(dir)
+	entries, size := 0, int64(0)
+	files, _ := os.ReadDir(dir)
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") || file.Name() == askCacheStatsFile {
+			continue
+		}
+		if info, err := file.Info(); err == nil {
+			entries++
+			size += info.Size()
+		}
+	}
+
+	hitRate := "n/a"
+	if lookups := counters.Hits + counters.Misses; lookups > 0 {
+		hitRate = fmt.Sprintf("%.0f%%", float64(counters.Hits)*100/float64(lookups))
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatHeader("📦 Ask Response Cache"))
+	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Hits", fmt.Sprint(counters.Hits)))
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Misses", fmt.Sprint(counters.Misses)))
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Hit rate", hitRate))
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Cached answers", fmt.Sprint(entries)))
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Size", formatBytes(size)))
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Expiry", fmt.Sprintf("%.0f hours", askCacheTTL.Hours())))
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Location", dir))
+}
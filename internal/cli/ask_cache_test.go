@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"nix-ai-help/internal/config"
+)
+
+// useTempAskCache points the ask cache at a temporary directory and resets the cache flags
+func useTempAskCache(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	origDir := askCacheDir
+	askCacheDir = func() string { return dir }
+	t.Cleanup(func() {
+		askCacheDir = origDir
+		askFresh, askExplainCached = false, false
+	})
+	return dir
+}
+
+func TestCachedAskQueryServesCachedAnswer(t *testing.T) {
+	dir := useTempAskCache(t)
+	provider := &scriptedProvider{responses: []string{"Enable services.nginx.", "Regenerated answer."}}
+
+	response, cached, err := cachedAskQuery(context.Background(), provider, "ollama", "llama3", config.AISamplingConfig{}, "nginx?", "prompt")
+	if err != nil || response != "Enable services.nginx." || cached != nil {
+		t.Fatalf("first query = %q, %+v, %v; want a fresh answer", response, cached, err)
+	}
+
+	response, cached, err = cachedAskQuery(context.Background(), provider, "ollama", "llama3", config.AISamplingConfig{}, "nginx?", "prompt")
+	if err != nil || response != "Enable services.nginx." || cached == nil {
+		t.Fatalf("second query = %q, %+v, %v; want the cached answer", response, cached, err)
+	}
+	if provider.calls != 1 {
+		t.Errorf("the cached answer should not query the provider, got %d calls", provider.calls)
+	}
+
+	// Another model or prompt is a different question
+	if _, cached, _ := cachedAskQuery(context.Background(), provider, "ollama", "mistral", config.AISamplingConfig{}, "nginx?", "prompt"); cached != nil {
+		t.Error("an answer of another model was reused")
+	}
+
+	counters := loadAskCacheCounters(dir)
+	if counters.Hits != 1 || counters.Misses != 2 {
+		t.Errorf("counters = %+v, want 1 hit and 2 misses", counters)
+	}
+}
+
+func TestCachedAskQueryFreshBypassesCache(t *testing.T) {
+	useTempAskCache(t)
+	provider := &scriptedProvider{responses: []string{"Old answer.", "New answer.", "Unused."}}
+
+	if _, _, err := cachedAskQuery(context.Background(), provider, "ollama", "llama3", config.AISamplingConfig{}, "q", "prompt"); err != nil {
+		t.Fatal(err)
+	}
+	askFresh = true
+	response, cached, err := cachedAskQuery(context.Background(), provider, "ollama", "llama3", config.AISamplingConfig{}, "q", "prompt")
+	if err != nil || response != "New answer." || cached != nil || provider.calls != 2 {
+		t.Fatalf("--fresh query = %q, %+v, %v after %d calls; want a regenerated answer", response, cached, err, provider.calls)
+	}
+
+	// The regenerated answer replaces the cached one
+	askFresh = false
+	response, cached, _ = cachedAskQuery(context.Background(), provider, "ollama", "llama3", config.AISamplingConfig{}, "q", "prompt")
+	if response != "New answer." || cached == nil {
+		t.Errorf("expected the regenerated answer from the cache, got %q", response)
+	}
+}
+
+func TestAskCacheKeyIncludesSampling(t *testing.T) {
+	low, high := 0.2, 0.9
+	unset := askCacheKey("ollama", "llama3", config.AISamplingConfig{}, "prompt")
+	cool := askCacheKey("ollama", "llama3", config.AISamplingConfig{Temperature: &low}, "prompt")
+	hot := askCacheKey("ollama", "llama3", config.AISamplingConfig{Temperature: &high}, "prompt")
+	topP := askCacheKey("ollama", "llama3", config.AISamplingConfig{TopP: &low}, "prompt")
+	if unset == cool || cool == hot || cool == topP {
+		t.Error("answers under different sampling share a cache key")
+	}
+	again := 0.2
+	if cool != askCacheKey("ollama", "llama3", config.AISamplingConfig{Temperature: &again}, "prompt") {
+		t.Error("the same sampling gives different cache keys")
+	}
+}
+
+func TestCachedAskQueryExpires(t *testing.T) {
+	dir := useTempAskCache(t)
+	key := askCacheKey("ollama", "llama3", config.AISamplingConfig{}, "prompt")
+	if err := saveAskCacheEntry(dir, key, askCacheEntry{Response: "Stale.", CreatedAt: time.Now().Add(-askCacheTTL - time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	provider := &scriptedProvider{responses: []string{"Current."}}
+	response, cached, _ := cachedAskQuery(context.Background(), provider, "ollama", "llama3", config.AISamplingConfig{}, "q", "prompt")
+	if response != "Current." || cached != nil {
+		t.Errorf("an expired answer was served: %q", response)
+	}
+}
+
+func TestWriteCacheMarker(t *testing.T) {
+	useTempAskCache(t)
+	now := time.Date(2025, 6, 14, 12, 0, 0, 0, time.UTC)
+	entry := &askCacheEntry{Provider: "openai", Model: "gpt-4", CreatedAt: now.Add(-2*time.Hour - 10*time.Minute)}
+
+	var out bytes.Buffer
+	writeCacheMarker(&out, entry, now)
+	if !strings.Contains(out.String(), "(cached 2h ago)") || strings.Contains(out.String(), "Why cached") {
+		t.Errorf("unexpected marker:\n%s", out.String())
+	}
+
+	out.Reset()
+	askExplainCached = true
+	writeCacheMarker(&out, entry, now)
+	for _, want := range []string{"(cached 2h ago)", "openai/gpt-4", "--fresh"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("explanation missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	askExplainCached = false
+	writeCacheMarker(&out, nil, now)
+	if out.Len() != 0 {
+		t.Errorf("fresh answers should not be marked: %q", out.String())
+	}
+}
+
+func TestShowAskCacheStats(t *testing.T) {
+	useTempAskCache(t)
+	provider := &scriptedProvider{responses: []string{"Answer."}}
+	for i := 0; i < 3; i++ {
+		if _, _, err := cachedAskQuery(context.Background(), provider, "ollama", "llama3", config.AISamplingConfig{}, "q", "prompt"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	showAskCacheStats(&out)
+	for _, want := range []string{"Hits", "2", "Misses", "67%", "Cached answers"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("stats missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestCacheAge(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		20 * time.Second:  "just now",
+		5 * time.Minute:   "5m ago",
+		150 * time.Minute: "2h ago",
+		50 * time.Hour:    "2d ago",
+	} {
+		if got := cacheAge(d); got != want {
+			t.Errorf("cacheAge(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
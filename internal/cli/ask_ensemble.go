@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"nix-ai-help/internal/ai"
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/utils"
+)
+
+// askEnsemble lists the providers ask --ensemble queries side by side, each as a provider
+// name or provider:model
+var askEnsemble []string
+
+// ensembleAnswer is the answer of one ensemble member
+type ensembleAnswer struct {
+	Member string
+	Answer string
+	Err    error
+}
+
+// normalizeAskEnsemble trims the --ensemble members and drops repeated ones, keeping the first,
+// and checks that at least two distinct members remain
+func normalizeAskEnsemble(members []string) ([]string, error) {
+	seen := map[string]bool{}
+	var normalized []string
+	for _, member := range members {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			return nil, fmt.Errorf("--ensemble contains an empty provider name")
+		}
+		if seen[member] {
+			continue
+		}
+		seen[member] = true
+		normalized = append(normalized, member)
+	}
+	if len(normalized) < 2 {
+		return nil, fmt.Errorf("--ensemble needs at least two different providers, e.g. --ensemble ollama,openai")
+	}
+	return normalized, nil
+}
+
+// queryEnsemble asks every member the prompt concurrently and returns their answers in the
+// order of members
+func queryEnsemble(ctx context.Context, members []string, getProvider func(string) (ai.Provider, error), prompt string) []ensembleAnswer {
+	answers := make([]ensembleAnswer, len(members))
+	var wg sync.WaitGroup
+	for i, member := range members {
+		answers[i].Member = member
+		provider, err := getProvider(member)
+		if err != nil {
+			answers[i].Err = err
+			continue
+		}
+		wg.Add(1)
+		go func(answer *ensembleAnswer, provider ai.Provider) {
+			defer wg.Done()
+			answer.Answer, answer.Err = queryAskProvider(ctx, provider, prompt)
+		}(&answers[i], provider)
+	}
+	wg.Wait()
+	return answers
+}
+
+// ensembleSynthesisPrompt asks for one answer reconciling the answers of the members, with the
+// points they disagree on called out
+func ensembleSynthesisPrompt(question string, answers []ensembleAnswer) string {
+	var b strings.Builder
+	b.WriteString("You are a NixOS expert reviewing answers that different AI assistants gave to the same question. " +
+		"Write one answer that keeps what they agree on and what is correct for NixOS. " +
+		"Where they disagree, decide which answer is right and why; drop options, packages or commands that only one " +
+		"assistant mentions unless you are sure they exist. End with a section titled \"Disagreements\" that lists each " +
+		"point the answers disagreed on and which answer you followed, or says that they agreed.\n\n")
+	b.WriteString("QUESTION:\n" + question + "\n")
+	for _, answer := range answers {
+		fmt.Fprintf(&b, "\nANSWER FROM %s:\n%s\n", answer.Member, strings.TrimSpace(answer.Answer))
+	}
+	return b.String()
+}
+
+// ensembleAskQuery answers prompt with ask --ensemble: the members are queried concurrently and
+// synthesizer reconciles their answers. Members that fail are reported on warn; when only one
+// answers, its answer is returned without a synthesis pass.
+func ensembleAskQuery(ctx context.Context, warn io.Writer, members []string, getProvider func(string) (ai.Provider, error), synthesizer ai.Provider, question, prompt string) (string, error) {
+	var answered []ensembleAnswer
+	for _, answer := range queryEnsemble(ctx, members, getProvider, prompt) {
+		if answer.Err != nil {
+			_, _ = fmt.Fprintln(warn, utils.FormatWarning(fmt.Sprintf("Ensemble provider %s failed: %v", answer.Member, answer.Err)))
+			continue
+		}
+		answered = append(answered, answer)
+	}
+
+	switch len(answered) {
+	case 0:
+		return "", fmt.Errorf("no ensemble provider answered")
+	case 1:
+		_, _ = fmt.Fprintln(warn, utils.FormatWarning("Only "+answered[0].Member+" answered; showing its answer without cross-checking"))
+		return answered[0].Answer, nil
+	}
+	return queryAskProvider(ctx, synthesizer, ensembleSynthesisPrompt(question, answered))
+}
+
+// answerAsk queries the ask answer: from the ensemble with --ensemble, otherwise from provider
+// through the answer cache, under the sampling the provider was set up with. Ensemble answers
+// are not cached.
+func answerAsk(ctx context.Context, manager *ai.ProviderManager, provider ai.Provider, providerName, model string, sampling config.AISamplingConfig, question, prompt string) (string, *askCacheEntry, error) {
+	if len(askEnsemble) == 0 {
+		return cachedAskQuery(ctx, provider, providerName, model, sampling, question, prompt)
+	}
+	getProvider := func(member string) (ai.Provider, error) {
+		// GetProviderForModel falls back to the default model of a member without one
+		name, memberModel, _ := strings.Cut(member, ":")
+		return manager.GetProviderForModel(name, memberModel)
+	}
+	response, err := ensembleAskQuery(ctx, os.Stderr, askEnsemble, getProvider, provider, question, prompt)
+	return response, nil, err
+}
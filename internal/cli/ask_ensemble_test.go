@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/ai"
+)
+
+// ensembleProviders returns a getProvider serving the given providers by name
+func ensembleProviders(providers map[string]*scriptedProvider) func(string) (ai.Provider, error) {
+	return func(name string) (ai.Provider, error) {
+		provider, ok := providers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown provider %s", name)
+		}
+		return provider, nil
+	}
+}
+
+func TestEnsembleAskQuerySynthesizesDifferingAnswers(t *testing.T) {
+	ollama := &scriptedProvider{responses: []string{"Set boot.loader.systemd-boot.enable = true;"}}
+	openai := &scriptedProvider{responses: []string{"Set boot.loader.grub.efiSupport = true;"}}
+	synthesizer := &scriptedProvider{responses: []string{"Use systemd-boot.\n\n## Disagreements\n- grub vs systemd-boot"}}
+
+	var warn bytes.Buffer
+	response, err := ensembleAskQuery(context.Background(), &warn, []string{"ollama", "openai"},
+		ensembleProviders(map[string]*scriptedProvider{"ollama": ollama, "openai": openai}), synthesizer, "Which bootloader?", "PROMPT")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(response, "Disagreements") {
+		t.Errorf("expected the synthesized answer, got %q", response)
+	}
+	if ollama.calls != 1 || openai.calls != 1 || ollama.prompts[0] != "PROMPT" || openai.prompts[0] != "PROMPT" {
+		t.Errorf("expected each member to be asked the prompt once, got %v and %v", ollama.prompts, openai.prompts)
+	}
+	if synthesizer.calls != 1 {
+		t.Fatalf("expected one synthesis pass, got %d", synthesizer.calls)
+	}
+	synthesis := synthesizer.prompts[0]
+	for _, want := range []string{"Which bootloader?", "ANSWER FROM ollama", "systemd-boot.enable", "ANSWER FROM openai", "grub.efiSupport", "Disagreements"} {
+		if !strings.Contains(synthesis, want) {
+			t.Errorf("expected the synthesis prompt to contain %q:\n%s", want, synthesis)
+		}
+	}
+	if warn.Len() != 0 {
+		t.Errorf("unexpected warnings: %q", warn.String())
+	}
+}
+
+func TestEnsembleAskQueryWithFailingMembers(t *testing.T) {
+	ollama := &scriptedProvider{responses: []string{"Enable services.openssh."}}
+	openai := &scriptedProvider{err: errors.New("401 unauthorized")}
+	synthesizer := &scriptedProvider{responses: []string{"merged"}}
+	providers := ensembleProviders(map[string]*scriptedProvider{"ollama": ollama, "openai": openai})
+
+	// With one answer left there is nothing to reconcile
+	var warn bytes.Buffer
+	response, err := ensembleAskQuery(context.Background(), &warn, []string{"ollama", "openai", "gemini"}, providers, synthesizer, "q", "p")
+	if err != nil || response != "Enable services.openssh." {
+		t.Fatalf("got %q, %v", response, err)
+	}
+	if synthesizer.calls != 0 {
+		t.Error("expected no synthesis pass with a single answer")
+	}
+	for _, want := range []string{"openai failed: 401 unauthorized", "gemini failed: unknown provider", "Only ollama answered"} {
+		if !strings.Contains(warn.String(), want) {
+			t.Errorf("expected warning %q, got %q", want, warn.String())
+		}
+	}
+
+	if _, err := ensembleAskQuery(context.Background(), &bytes.Buffer{}, []string{"openai", "gemini"}, providers, synthesizer, "q", "p"); err == nil {
+		t.Error("expected an error when no member answers")
+	}
+}
+
+func TestNormalizeAskEnsemble(t *testing.T) {
+	// --ensemble "ollama, openai:gpt-4o" leaves a space before the second member
+	members, err := normalizeAskEnsemble([]string{"ollama", " openai:gpt-4o", "ollama "})
+	if err != nil || !reflect.DeepEqual(members, []string{"ollama", "openai:gpt-4o"}) {
+		t.Errorf("normalizeAskEnsemble = %q, %v", members, err)
+	}
+	for _, members := range [][]string{{"ollama"}, {"ollama", "ollama"}, {"ollama", " ollama"}, {"ollama", " "}} {
+		if _, err := normalizeAskEnsemble(members); err == nil {
+			t.Errorf("expected %q to be rejected", members)
+		}
+	}
+}
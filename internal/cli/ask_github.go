@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+
+	"nix-ai-help/internal/community"
+)
+
+// maxExamplesPerTerm limits how many GitHub configurations are used per search term
+const maxExamplesPerTerm = 2
+
+// askOffline skips network lookups such as the GitHub example search in ask
+var askOffline bool
+
+// configurationSearcher is the part of the GitHub client used to gather ask examples
+type configurationSearcher interface {
+	SearchNixOSConfigurations(topic string) ([]community.Configuration, error)
+}
+
+// githubExampleSearch is the outcome of gathering real-world examples for a question
+type githubExampleSearch struct {
+	Examples []string
+	Err      error // first search error, kept so failures can be told apart from empty results
+	Skipped  bool  // true when the search was not run because of --offline
+}
+
+// Failed reports whether no examples were found because GitHub search failed
+func (s githubExampleSearch) Failed() bool {
+	return s.Err != nil && len(s.Examples) == 0
+}
+
+// searchGitHubExamples searches GitHub for NixOS configurations matching the search terms.
+// Short terms are ignored; errors are recorded rather than aborting the remaining terms.
+func searchGitHubExamples(searcher configurationSearcher, terms []string) githubExampleSearch {
+	var result githubExampleSearch
+	if askOffline {
+		result.Skipped = true
+		return result
+	}
+
+	for _, term := range terms {
+		if len(term) <= 3 {
+			continue
+		}
+		configs, err := searcher.SearchNixOSConfigurations(term)
+		if err != nil {
+			if result.Err == nil {
+				result.Err = err
+			}
+			continue
+		}
+		for i, config := range configs {
+			if i >= maxExamplesPerTerm {
+				break
+			}
+			result.Examples = append(result.Examples,
+				fmt.Sprintf("Real-world NixOS configuration example (%s):\nRepo: %s\nDescription: %s\nAuthor: %s\nStars: %d\nURL: %s",
+					term, config.Name, config.Description, config.Author, config.Views, config.URL))
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+
+	"nix-ai-help/internal/community"
+)
+
+type fakeConfigurationSearcher struct {
+	results map[string][]community.Configuration
+	errs    map[string]error
+}
+
+func (f fakeConfigurationSearcher) SearchNixOSConfigurations(topic string) ([]community.Configuration, error) {
+	return f.results[topic], f.errs[topic]
+}
+
+func TestSearchGitHubExamples_FailureVersusEmpty(t *testing.T) {
+	failing := fakeConfigurationSearcher{errs: map[string]error{"nginx": errors.New("GitHub API error: 502")}}
+	result := searchGitHubExamples(failing, []string{"nginx"})
+	if !result.Failed() {
+		t.Error("expected a failed search when GitHub returns an error")
+	}
+
+	empty := fakeConfigurationSearcher{}
+	result = searchGitHubExamples(empty, []string{"nginx"})
+	if result.Failed() || len(result.Examples) != 0 {
+		t.Errorf("expected an empty, successful search, got %+v", result)
+	}
+
+	partial := fakeConfigurationSearcher{
+		errs:    map[string]error{"nginx": errors.New("GitHub API error: 503")},
+		results: map[string][]community.Configuration{"flake": {{Name: "dotfiles"}, {Name: "nixos"}, {Name: "extra"}}},
+	}
+	result = searchGitHubExamples(partial, []string{"nginx", "flake", "ssh"})
+	if result.Failed() {
+		t.Error("a search with results should not be reported as failed")
+	}
+	if len(result.Examples) != maxExamplesPerTerm {
+		t.Errorf("expected %d examples, got %d", maxExamplesPerTerm, len(result.Examples))
+	}
+}
+
+func TestSearchGitHubExamples_Offline(t *testing.T) {
+	askOffline = true
+	defer func() { askOffline = false }()
+
+	searcher := fakeConfigurationSearcher{results: map[string][]community.Configuration{"nginx": {{Name: "web"}}}}
+	result := searchGitHubExamples(searcher, []string{"nginx"})
+	if !result.Skipped || len(result.Examples) != 0 {
+		t.Errorf("expected the search to be skipped offline, got %+v", result)
+	}
+}
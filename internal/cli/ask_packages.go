@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"fmt"
+	"sync"
+)
+
+// maxConcurrentPackageSearches bounds how many nix searches ask runs at once
+const maxConcurrentPackageSearches = 4
+
+// packageSearcher is the part of the NixOS executor used to search packages for ask
+type packageSearcher interface {
+	SearchNixPackages(query string) (string, error)
+}
+
+// searchPackagesForTerms searches packages for each term concurrently, at most
+// maxConcurrentPackageSearches at a time, and returns the results in term order so that prompts
+// stay deterministic. Terms without results or whose search failed are left out.
+func searchPackagesForTerms(searcher packageSearcher, terms []string) []string {
+	results := make([]string, len(terms))
+	slots := make(chan struct{}, maxConcurrentPackageSearches)
+	var wg sync.WaitGroup
+	for i, term := range terms {
+		wg.Add(1)
+		go func(i int, term string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			if packageInfo, err := searcher.SearchNixPackages(term); err == nil && packageInfo != "" {
+				results[i] = fmt.Sprintf("Package Search for '%s':\n%s", term, packageInfo)
+			}
+		}(i, term)
+	}
+	wg.Wait()
+
+	found := results[:0]
+	for _, result := range results {
+		if result != "" {
+			found = append(found, result)
+		}
+	}
+	return found
+}
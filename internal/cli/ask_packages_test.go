@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowSearcher answers package searches after a delay and records how many ran at once
+type slowSearcher struct {
+	delay   time.Duration
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (s *slowSearcher) SearchNixPackages(query string) (string, error) {
+	s.mu.Lock()
+	s.running++
+	if s.running > s.peak {
+		s.peak = s.running
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running--
+		s.mu.Unlock()
+	}()
+
+	time.Sleep(s.delay)
+	switch query {
+	case "missing":
+		return "", nil
+	case "broken":
+		return "", errors.New("nix search failed")
+	}
+	return "pkgs." + query, nil
+}
+
+func TestSearchPackagesForTermsRunsConcurrentlyInOrder(t *testing.T) {
+	searcher := &slowSearcher{delay: 30 * time.Millisecond}
+	terms := []string{"nginx", "missing", "postgresql", "broken", "redis", "caddy", "git", "vim"}
+
+	start := time.Now()
+	results := searchPackagesForTerms(searcher, terms)
+	elapsed := time.Since(start)
+
+	want := []string{"nginx", "postgresql", "redis", "caddy", "git", "vim"}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d: %v", len(results), len(want), results)
+	}
+	for i, term := range want {
+		if expected := fmt.Sprintf("Package Search for '%s':\npkgs.%s", term, term); results[i] != expected {
+			t.Errorf("result %d = %q, want %q", i, results[i], expected)
+		}
+	}
+
+	if searcher.peak < 2 {
+		t.Errorf("searches ran sequentially (peak concurrency %d)", searcher.peak)
+	}
+	if searcher.peak > maxConcurrentPackageSearches {
+		t.Errorf("peak concurrency %d exceeds the limit of %d", searcher.peak, maxConcurrentPackageSearches)
+	}
+	if sequential := time.Duration(len(terms)) * searcher.delay; elapsed >= sequential {
+		t.Errorf("searches took %v, no faster than running them one by one (%v)", elapsed, sequential)
+	}
+}
+
+func TestSearchPackagesForTermsNoTerms(t *testing.T) {
+	if results := searchPackagesForTerms(&slowSearcher{}, nil); len(results) != 0 {
+		t.Errorf("expected no results, got %v", results)
+	}
+}
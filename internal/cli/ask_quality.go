@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/utils"
+)
+
+const (
+	// maxAskContextScore is the highest context score reachable before the AI answers
+	maxAskContextScore = 4
+	// maxBroadenedTerms limits the extra search terms used when broadening
+	maxBroadenedTerms = 5
+)
+
+// questionWords are common question words that make poor search terms
+var questionWords = map[string]bool{
+	"how": true, "what": true, "why": true, "where": true, "when": true, "which": true,
+	"who": true, "my": true, "your": true, "this": true, "that": true, "use": true,
+}
+
+// askMinQuality is the --min-quality threshold for the context gathered by ask
+var askMinQuality int
+
+// validateAskMinQuality checks that --min-quality is a reachable context score
+func validateAskMinQuality(minQuality int) error {
+	if minQuality < 0 || minQuality > maxAskContextScore {
+		return fmt.Errorf("--min-quality must be between 0 and %d, got %d", maxAskContextScore, minQuality)
+	}
+	return nil
+}
+
+// askContextScore scores the gathered context before the AI call, one point per source
+// that produced results. It matches the context part of the response quality indicators.
+func askContextScore(docExcerpts, searchContext, githubExamples []string, systemContext bool) int {
+	score := 0
+	for _, found := range []bool{len(docExcerpts) > 0, len(searchContext) > 0, len(githubExamples) > 0, systemContext} {
+		if found {
+			score++
+		}
+	}
+	return score
+}
+
+// broadenSearchTerms returns additional search terms taken from every meaningful word of
+// the question, skipping terms that were already searched
+func broadenSearchTerms(question string, existing []string) []string {
+	seen := make(map[string]bool)
+	for _, term := range existing {
+		seen[term] = true
+	}
+
+	var terms []string
+	for _, word := range strings.Fields(strings.ToLower(question)) {
+		cleaned := strings.Trim(word, ".,!?;:\"'()")
+		if len(cleaned) <= 2 || isStopWord(cleaned) || questionWords[cleaned] || seen[cleaned] {
+			continue
+		}
+		seen[cleaned] = true
+		terms = append(terms, cleaned)
+		if len(terms) >= maxBroadenedTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// ensureAskQuality gathers the broader sources when the context score is below minQuality
+// and reports whether the threshold was met. A threshold of zero disables the check.
+func ensureAskQuality(out io.Writer, minQuality int, score func() int, broader []askSource) bool {
+	if minQuality <= 0 {
+		return true
+	}
+	current := score()
+	if current >= minQuality {
+		return true
+	}
+
+	_, _ = fmt.Fprintln(out, utils.FormatWarning(fmt.Sprintf("Context quality %d/%d is below --min-quality %d, broadening search", current, maxAskContextScore, minQuality)))
+	if len(broader) > 0 {
+		gatherAskSources(out, broader)
+	}
+
+	current = score()
+	if current >= minQuality {
+		_, _ = fmt.Fprintln(out, utils.FormatSuccess(fmt.Sprintf("Context quality raised to %d/%d", current, maxAskContextScore)))
+		return true
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatWarning(fmt.Sprintf("Quality threshold %d not met: context quality is %d/%d", minQuality, current, maxAskContextScore)))
+	return false
+}
+
+// broadenAskContextQuietly is ensureAskQuality for the concise and quiet ask layouts: the broader
+// sources are gathered without progress output and the outcome is returned as a single note, ""
+// when the context already met the threshold
+func broadenAskContextQuietly(minQuality int, score func() int, broader []askSource) string {
+	if minQuality <= 0 || score() >= minQuality {
+		return ""
+	}
+	if len(broader) > 0 {
+		gatherAskSources(io.Discard, broader)
+	}
+	if current := score(); current < minQuality {
+		return utils.FormatWarning(fmt.Sprintf("Context quality %d/%d is below --min-quality %d, even after a broader search", current, maxAskContextScore, minQuality))
+	}
+	return utils.FormatNote(fmt.Sprintf("Searched more broadly to reach --min-quality %d", minQuality))
+}
+
+// broaderAskSources returns the sources ask --min-quality adds for the broader terms of a
+// question: more documentation, packages and real-world configurations, each only when the
+// first search found none. githubSearch receives the result of the configuration search.
+func broaderAskSources(cfg *config.UserConfig, nixosCtx *config.NixOSContext, broaderTerms []string,
+	docExcerpts, searchContext, githubExamples *[]string, githubSearch *githubExampleSearch) []askSource {
+	if len(broaderTerms) == 0 {
+		return nil
+	}
+	var sources []askSource
+	if cfg.MCPServer.Host != "" && len(*docExcerpts) == 0 {
+		sources = append(sources, askSource{label: "Querying additional documentation", gather: func() string {
+			mcpClient := newDocsClient(cfg, nixosCtx)
+			for _, term := range broaderTerms {
+				if doc, err := mcpClient.QueryDocumentation("NixOS option " + term); err == nil && len(doc) > 20 && len(doc) < 3000 {
+					*docExcerpts = append(*docExcerpts, fmt.Sprintf("NixOS Documentation Context for '%s':\n%s", term, doc))
+				}
+			}
+			if len(*docExcerpts) > 0 {
+				return utils.FormatSuccess(fmt.Sprintf("found %d documentation results", len(*docExcerpts)))
+			}
+			return utils.FormatWarning("no documentation found")
+		}})
+	}
+	if len(*searchContext) == 0 {
+		sources = append(sources, askSource{label: "Searching more packages", gather: func() string {
+			*searchContext = append(*searchContext, searchPackagesForTerms(newAskPackageSearcher(cfg), broaderTerms)...)
+			if len(*searchContext) > 0 {
+				return utils.FormatSuccess(fmt.Sprintf("found %d package results", len(*searchContext)))
+			}
+			return utils.FormatWarning("no packages found")
+		}})
+	}
+	if len(*githubExamples) == 0 && !askOffline {
+		sources = append(sources, askSource{label: "Searching more real-world configurations", gather: func() string {
+			*githubSearch = searchGitHubExamples(newAskConfigurationSearcher(), broaderTerms)
+			*githubExamples = githubSearch.Examples
+			if len(*githubExamples) > 0 {
+				return utils.FormatSuccess(fmt.Sprintf("found %d configuration examples", len(*githubExamples)))
+			}
+			return utils.FormatWarning("no configuration examples found")
+		}})
+	}
+	return sources
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBroadenSearchTerms(t *testing.T) {
+	got := broadenSearchTerms("How do I enable tailscale on my laptop?", []string{"tailscale"})
+	if !reflect.DeepEqual(got, []string{"enable", "laptop"}) {
+		t.Errorf("unexpected broadened terms: %v", got)
+	}
+}
+
+func TestEnsureAskQuality_GathersMoreWhenSparse(t *testing.T) {
+	var docs, packages, examples []string
+	score := func() int { return askContextScore(docs, packages, examples, false) }
+
+	broader := []askSource{
+		{label: "Querying additional documentation", gather: func() string {
+			docs = append(docs, "services.tailscale.enable")
+			return "found docs"
+		}},
+		{label: "Searching more packages", gather: func() string {
+			packages = append(packages, "tailscale")
+			return "found packages"
+		}},
+	}
+
+	var out bytes.Buffer
+	if !ensureAskQuality(&out, 2, score, broader) {
+		t.Fatalf("expected the threshold to be met after broadening, output:\n%s", out.String())
+	}
+	if len(docs) != 1 || len(packages) != 1 {
+		t.Errorf("expected broader sources to be gathered, got docs=%v packages=%v", docs, packages)
+	}
+	if !strings.Contains(out.String(), "broadening search") {
+		t.Errorf("expected a broadening notice, got:\n%s", out.String())
+	}
+}
+
+func TestEnsureAskQuality_ReportsUnmetThreshold(t *testing.T) {
+	score := func() int { return askContextScore(nil, nil, nil, false) }
+	broader := []askSource{{label: "Searching more packages", gather: func() string { return "no packages found" }}}
+
+	var out bytes.Buffer
+	if ensureAskQuality(&out, 3, score, broader) {
+		t.Fatal("expected the threshold not to be met")
+	}
+	if !strings.Contains(out.String(), "Quality threshold 3 not met") {
+		t.Errorf("expected an unmet threshold report, got:\n%s", out.String())
+	}
+}
+
+func TestEnsureAskQuality_SkipsWhenSufficient(t *testing.T) {
+	score := func() int { return askContextScore([]string{"doc"}, []string{"pkg"}, nil, true) }
+	gathered := false
+	broader := []askSource{{label: "Searching more packages", gather: func() string { gathered = true; return "" }}}
+
+	var out bytes.Buffer
+	if !ensureAskQuality(&out, 3, score, broader) || gathered {
+		t.Error("expected no broader gathering when the context already meets the threshold")
+	}
+	if !ensureAskQuality(&out, 0, func() int { return 0 }, broader) || gathered {
+		t.Error("expected a zero threshold to disable the check")
+	}
+}
+
+func TestBroadenAskContextQuietly(t *testing.T) {
+	var docs []string
+	score := func() int { return askContextScore(docs, nil, nil, false) }
+	broader := []askSource{{label: "Querying additional documentation", gather: func() string {
+		docs = append(docs, "services.tailscale.enable")
+		return "found docs"
+	}}}
+
+	if note := broadenAskContextQuietly(0, score, broader); note != "" || len(docs) != 0 {
+		t.Errorf("expected a zero threshold to do nothing, got note %q and docs %v", note, docs)
+	}
+	note := broadenAskContextQuietly(1, score, broader)
+	if len(docs) != 1 || !strings.Contains(note, "--min-quality 1") || strings.Contains(note, "Querying") {
+		t.Errorf("expected only a note after broadening, got %q with docs %v", note, docs)
+	}
+	if note := broadenAskContextQuietly(1, score, broader); note != "" || len(docs) != 1 {
+		t.Errorf("expected no note when the context already meets the threshold, got %q", note)
+	}
+	if note := broadenAskContextQuietly(3, score, nil); !strings.Contains(note, "below --min-quality 3") {
+		t.Errorf("expected an unmet threshold note, got %q", note)
+	}
+}
+
+func TestValidateAskMinQuality(t *testing.T) {
+	for minQuality, valid := range map[int]bool{0: true, 3: true, maxAskContextScore: true, -1: false, 5: false, 10: false} {
+		if err := validateAskMinQuality(minQuality); (err == nil) != valid {
+			t.Errorf("validateAskMinQuality(%d) = %v, want valid %v", minQuality, err, valid)
+		}
+	}
+}
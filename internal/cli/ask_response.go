@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"nix-ai-help/internal/ai"
+)
+
+// errEmptyResponse is returned when the provider answers with nothing, even after a retry
+var errEmptyResponse = errors.New("provider returned an empty response, try another model (--model) or provider (--provider)")
+
+// queryProvider sends a prompt through QueryWithContext when the provider supports it
+func queryProvider(ctx context.Context, provider ai.Provider, prompt string) (string, error) {
+	if p, ok := provider.(interface {
+		QueryWithContext(context.Context, string) (string, error)
+	}); ok {
+		return p.QueryWithContext(ctx, prompt)
+	}
+	return provider.Query(prompt)
+}
+
+// queryAskProvider queries the provider for an ask answer. Some models return an empty answer
+// instead of an error, e.g. when the prompt fills their context window, so an empty or
+// whitespace-only response is retried once and then reported as errEmptyResponse.
+func queryAskProvider(ctx context.Context, provider ai.Provider, prompt string) (string, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		response, err := queryProvider(ctx, provider, prompt)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(response) != "" {
+			return response, nil
+		}
+	}
+	return "", errEmptyResponse
+}
+
+// askOutputFormat is the output format ask prints its answer in; empty means markdown
+var askOutputFormat string
+
+// askAnswerJSON is the answer of ask in the JSON output format
+type askAnswerJSON struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+	Provider string `json:"provider,omitempty"`
+}
+
+// writeAskAnswer prints an ask answer in askOutputFormat
+func writeAskAnswer(out io.Writer, question, answer, provider string) {
+	if askOutputFormat == outputJSON {
+		_ = writeJSONOutput(out, askAnswerJSON{Question: question, Answer: answer, Provider: provider})
+		return
+	}
+	writePaged(out, renderAnswer(askOutputFormat, answer)+"\n")
+}
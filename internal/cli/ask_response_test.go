@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"nix-ai-help/internal/ai"
+)
+
+// scriptedProvider returns its responses in order and records the prompts
+type scriptedProvider struct {
+	responses []string
+	err       error
+	calls     int
+	prompts   []string
+}
+
+func (p *scriptedProvider) Query(prompt string) (string, error) {
+	return p.GenerateResponse(context.Background(), prompt)
+}
+
+func (p *scriptedProvider) GenerateResponse(ctx context.Context, prompt string) (string, error) {
+	p.calls++
+	p.prompts = append(p.prompts, prompt)
+	if p.err != nil {
+		return "", p.err
+	}
+	if len(p.responses) == 0 {
+		return "", nil
+	}
+	response := p.responses[0]
+	p.responses = p.responses[1:]
+	return response, nil
+}
+
+func (p *scriptedProvider) StreamResponse(ctx context.Context, prompt string) (<-chan ai.StreamResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *scriptedProvider) GetPartialResponse() string {
+	return ""
+}
+
+func (p *scriptedProvider) Capabilities() ai.Capabilities {
+	return ai.Capabilities{}
+}
+
+func TestQueryAskProvider_RetriesEmptyResponseOnce(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{"  \n\t", "Set services.nginx.enable = true;"}}
+	response, err := queryAskProvider(context.Background(), provider, "prompt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response != "Set services.nginx.enable = true;" || provider.calls != 2 {
+		t.Errorf("got %q after %d calls", response, provider.calls)
+	}
+}
+
+func TestQueryAskProvider_ReportsRepeatedEmptyResponse(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{"", " "}}
+	if _, err := queryAskProvider(context.Background(), provider, "prompt"); !errors.Is(err, errEmptyResponse) {
+		t.Errorf("expected errEmptyResponse, got %v", err)
+	}
+	if provider.calls != 2 {
+		t.Errorf("expected one retry, got %d calls", provider.calls)
+	}
+}
+
+func TestQueryAskProvider_ErrorsAreNotRetried(t *testing.T) {
+	provider := &scriptedProvider{err: errors.New("connection refused")}
+	if _, err := queryAskProvider(context.Background(), provider, "prompt"); err == nil || errors.Is(err, errEmptyResponse) {
+		t.Errorf("expected the provider error, got %v", err)
+	}
+	if provider.calls != 1 {
+		t.Errorf("expected a single call, got %d", provider.calls)
+	}
+}
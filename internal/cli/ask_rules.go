@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"strings"
+
+	nixoscontext "nix-ai-help/internal/ai/context"
+	"nix-ai-help/internal/ai/roles"
+	"nix-ai-help/internal/config"
+)
+
+// askForbiddenPatterns returns the forbidden-advice patterns for ask, including those
+// configured under ai_models.forbidden_patterns
+func askForbiddenPatterns(cfg *config.UserConfig) []string {
+	if cfg == nil {
+		return roles.ForbiddenPatterns(nil)
+	}
+	return roles.ForbiddenPatterns(cfg.AIModels.ForbiddenPatterns)
+}
+
+// buildAskBasePrompt returns the context-aware prompt shared by the concise, quiet and
+// verbose ask modes: the ask role template followed by the forbidden-advice rules and, for
+// Home Manager users, where user-level configuration belongs
+func buildAskBasePrompt(cfg *config.UserConfig, nixosCtx *config.NixOSContext) string {
+	basePrompt := ""
+	if template, exists := roles.RolePromptTemplate[roles.RoleAsk]; exists {
+		basePrompt = template
+	}
+
+	guidelines := roles.AskGuidelines(askForbiddenPatterns(cfg))
+	if guidance := homeManagerAskGuidance(nixosCtx); guidance != "" {
+		guidelines += "\n\n" + guidance
+	}
+	return nixoscontext.NewNixOSContextBuilder().BuildContextualPrompt(basePrompt+"\n\n"+guidelines, nixosCtx)
+}
+
+// homeManagerAskGuidance steers answers for Home Manager users toward their Home Manager
+// configuration for user-level settings; it is empty when Home Manager was not detected
+func homeManagerAskGuidance(nixosCtx *config.NixOSContext) string {
+	if nixosCtx == nil || !nixosCtx.HasHomeManager {
+		return ""
+	}
+
+	var lines []string
+	switch nixosCtx.HomeManagerType {
+	case "standalone":
+		location := "home.nix"
+		if nixosCtx.HomeManagerConfigPath != "" {
+			location = nixosCtx.HomeManagerConfigPath
+		}
+		lines = []string{
+			"The user runs Home Manager standalone, configured in " + location + ".",
+			"- For user-level programs, dotfiles and user services (shells, editors, git, browsers, desktop apps), answer with Home Manager options in " + location + ": prefer programs.<name> and services.<name> modules, and home.packages for plain packages",
+			"- Apply user-level changes with 'home-manager switch' (with flakes: 'home-manager switch --flake .#<user>')",
+			"- Use configuration.nix and nixos-rebuild only for system-level settings such as boot, hardware, networking and system services",
+		}
+	case "module":
+		lines = []string{
+			"The user runs Home Manager as a NixOS module.",
+			"- For user-level programs, dotfiles and user services (shells, editors, git, browsers, desktop apps), answer with Home Manager options under home-manager.users.<username>: prefer programs.<name> and services.<name> modules, and home.packages for plain packages",
+			"- These changes are applied by nixos-rebuild switch; do not suggest a separate home-manager switch",
+			"- Keep system-level settings such as boot, hardware, networking and system services in the NixOS configuration itself",
+		}
+	default:
+		lines = []string{
+			"The user has Home Manager.",
+			"- For user-level programs, dotfiles and user services, prefer Home Manager options (programs.<name>, services.<name>, home.packages) over system-wide configuration",
+			"- Use configuration.nix only for system-level settings such as boot, hardware, networking and system services",
+		}
+	}
+	return "=== HOME MANAGER GUIDANCE ===\n" + strings.Join(lines, "\n") + "\n=== END HOME MANAGER GUIDANCE ==="
+}
+
+// forbiddenAdviceIn returns the forbidden patterns recommended by an ask response
+func forbiddenAdviceIn(response string, cfg *config.UserConfig) []string {
+	return roles.FindForbiddenPatterns(response, askForbiddenPatterns(cfg))
+}
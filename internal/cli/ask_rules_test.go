@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/ai/validation"
+	"nix-ai-help/internal/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestBuildAskBasePrompt_IncludesForbiddenPatterns(t *testing.T) {
+	var cfg config.UserConfig
+	data := "ai_models:\n  forbidden_patterns:\n    - curl | sh\n    - nix-env\n"
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+
+	patterns := askForbiddenPatterns(&cfg)
+	if len(patterns) != 2 || patterns[0] != "nix-env" || patterns[1] != "curl | sh" {
+		t.Fatalf("expected default and configured patterns without duplicates, got %v", patterns)
+	}
+
+	prompt := buildAskBasePrompt(&cfg, nil)
+	for _, want := range []string{"NEVER suggest 'nix-env'", "NEVER suggest 'curl | sh'", "hardware.bluetooth.enable"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("expected ask prompt to contain %q", want)
+		}
+	}
+
+	if prompt := buildAskBasePrompt(nil, nil); !strings.Contains(prompt, "NEVER suggest 'nix-env'") {
+		t.Error("expected default forbidden patterns without a config")
+	}
+}
+
+func TestForbiddenAdviceIn(t *testing.T) {
+	cfg := &config.UserConfig{AIModels: config.AIModelsConfig{ForbiddenPatterns: []string{"curl | sh"}}}
+
+	found := forbiddenAdviceIn("Run CURL | SH then nix-env -iA nixpkgs.hello", cfg)
+	if len(found) != 2 {
+		t.Errorf("expected both forbidden patterns to be detected, got %v", found)
+	}
+	if found := forbiddenAdviceIn("Add it to configuration.nix", cfg); len(found) != 0 {
+		t.Errorf("expected no forbidden advice, got %v", found)
+	}
+	// Only whole commands count, not text that merely contains a pattern
+	for _, harmless := range []string{"Set up a nix-environment for Python", "The my-nix-env wrapper", "curl | shasum"} {
+		if found := forbiddenAdviceIn(harmless, cfg); len(found) != 0 {
+			t.Errorf("%q: expected no forbidden advice, got %v", harmless, found)
+		}
+	}
+	if found := forbiddenAdviceIn("`nix-env -q` lists installed packages", cfg); len(found) != 1 {
+		t.Errorf("expected nix-env in backquotes to be detected, got %v", found)
+	}
+}
+
+// The validator checks answers against the same rules the ask prompt forbids
+func TestForbiddenPatterns_PromptAndValidatorAgree(t *testing.T) {
+	cfg := &config.UserConfig{AIModels: config.AIModelsConfig{ForbiddenPatterns: []string{"curl | sh"}}}
+	patterns := askForbiddenPatterns(cfg)
+	prompt := buildAskBasePrompt(cfg, nil)
+
+	validator := validation.NewNixOSValidator()
+	validator.SetForbiddenPatterns(patterns)
+
+	for _, pattern := range patterns {
+		if !strings.Contains(prompt, "NEVER suggest '"+pattern+"'") {
+			t.Errorf("prompt does not forbid %q", pattern)
+		}
+	}
+	response := "Install the tool with curl | sh"
+	if found := forbiddenAdviceIn(response, cfg); len(found) != 1 || found[0] != "curl | sh" {
+		t.Errorf("forbiddenAdviceIn = %v, want [curl | sh]", found)
+	}
+	var flagged []string
+	for _, warning := range validator.ValidateNixOSContent(response).Warnings {
+		if warning.Type == "forbidden_pattern" {
+			flagged = append(flagged, warning.LinePattern)
+		}
+	}
+	if len(flagged) != 1 || flagged[0] != "curl | sh" {
+		t.Errorf("validator flagged %v, want [curl | sh]", flagged)
+	}
+	for _, harmless := range []string{"The my-nix-env wrapper", "curl | shasum"} {
+		if result := validator.ValidateNixOSContent(harmless); len(result.Warnings) != 0 || len(forbiddenAdviceIn(harmless, cfg)) != 0 {
+			t.Errorf("%q: expected neither check to flag it, validator warned %+v", harmless, result.Warnings)
+		}
+	}
+}
+
+func TestBuildAskBasePrompt_HomeManagerGuidance(t *testing.T) {
+	const marker = "=== HOME MANAGER GUIDANCE ==="
+
+	for _, ctx := range []*config.NixOSContext{nil, {CacheValid: true, SystemType: "nixos"}, {HomeManagerType: "standalone"}} {
+		if prompt := buildAskBasePrompt(nil, ctx); strings.Contains(prompt, marker) {
+			t.Errorf("unexpected Home Manager guidance without Home Manager detected (%+v)", ctx)
+		}
+	}
+
+	standalone := &config.NixOSContext{HasHomeManager: true, HomeManagerType: "standalone", HomeManagerConfigPath: "/home/alice/.config/home-manager/home.nix"}
+	prompt := buildAskBasePrompt(nil, standalone)
+	for _, want := range []string{marker, "/home/alice/.config/home-manager/home.nix", "programs.<name>", "'home-manager switch'"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("standalone prompt missing %q", want)
+		}
+	}
+
+	module := &config.NixOSContext{HasHomeManager: true, HomeManagerType: "module"}
+	prompt = buildAskBasePrompt(nil, module)
+	for _, want := range []string{marker, "home-manager.users.<username>", "nixos-rebuild switch"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("module prompt missing %q", want)
+		}
+	}
+	if strings.Contains(homeManagerAskGuidance(module), "home.nix") {
+		t.Error("module guidance should not point at a standalone home.nix")
+	}
+}
+
+func TestBuildAskBasePrompt_EnvironmentGuidance(t *testing.T) {
+	standard := buildAskBasePrompt(nil, &config.NixOSContext{CacheValid: true, SystemType: "nixos", Environment: "standard"})
+	if strings.Contains(standard, "Environment:") {
+		t.Errorf("unexpected environment guidance on a standard machine:\n%s", standard)
+	}
+
+	container := buildAskBasePrompt(nil, &config.NixOSContext{CacheValid: true, SystemType: "nixos", Environment: "container", ContainerRuntime: "systemd-nspawn"})
+	for _, want := range []string{"Environment: container (systemd-nspawn)", "NEVER suggest bootloader"} {
+		if !strings.Contains(container, want) {
+			t.Errorf("container prompt missing %q", want)
+		}
+	}
+
+	wsl := buildAskBasePrompt(nil, &config.NixOSContext{CacheValid: true, SystemType: "nixos", Environment: "wsl"})
+	for _, want := range []string{"Environment: WSL", "wsl.* options"} {
+		if !strings.Contains(wsl, want) {
+			t.Errorf("WSL prompt missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"nix-ai-help/pkg/utils"
+)
+
+// maxPreviousAnswer limits how much of the previous answer is sent along with a follow-up
+const maxPreviousAnswer = 4000
+
+// askContinue is the --continue flag of ask
+var askContinue bool
+
+// askTurn is the last question and answer of ask, kept so that ask --continue can follow up on it
+type askTurn struct {
+	Question string    `json:"question"`
+	Answer   string    `json:"answer"`
+	AskedAt  time.Time `json:"asked_at"`
+}
+
+// askSessionPath returns the location of the saved ask turn; replaced in tests
+var askSessionPath = func() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".cache", "nixai", "ask-session.json")
+}
+
+// saveAskTurn stores a question and its answer as the last ask turn
+func saveAskTurn(path, question, answer string) error {
+	data, err := json.Marshal(askTurn{Question: question, Answer: answer, AskedAt: time.Now()})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// loadAskTurn reads the last saved ask turn
+func loadAskTurn(path string) (*askTurn, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var turn askTurn
+	if err := json.Unmarshal(data, &turn); err != nil {
+		return nil, fmt.Errorf("invalid ask session %s: %w", path, err)
+	}
+	if turn.Question == "" {
+		return nil, fmt.Errorf("ask session %s is empty", path)
+	}
+	return &turn, nil
+}
+
+// previousTurnPrompt renders a previous turn as prompt context for a follow-up question
+func previousTurnPrompt(turn *askTurn) string {
+	answer := strings.TrimSpace(turn.Answer)
+	if runes := []rune(answer); len(runes) > maxPreviousAnswer {
+		answer = string(runes[:maxPreviousAnswer]) + "\n[previous answer truncated]"
+	}
+	return "\n\nPREVIOUS CONVERSATION:\nUser: " + turn.Question + "\nAssistant: " + answer +
+		"\n\nThe user question below is a follow-up to this conversation; answer it in that context."
+}
+
+// followUpContext returns the previous turn as prompt context when --continue is set, and
+// notes on out which question is being continued
+func followUpContext(out io.Writer) string {
+	if !askContinue {
+		return ""
+	}
+	turn, err := loadAskTurn(askSessionPath())
+	if err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatWarning("No previous ask session to continue; answering as a new question"))
+		return ""
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatNote("Continuing from: "+turn.Question))
+	return previousTurnPrompt(turn)
+}
+
+// rememberAskTurn saves the answered question for a later ask --continue
+func rememberAskTurn(question, answer string) {
+	// A missing session only disables --continue, so errors are not reported
+	_ = saveAskTurn(askSessionPath(), question, answer)
+}
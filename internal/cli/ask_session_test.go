@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stubAskSession points the ask session at a temporary file and sets --continue
+func stubAskSession(t *testing.T, continueFlag bool) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ask-session.json")
+	originalPath, originalContinue := askSessionPath, askContinue
+	askSessionPath = func() string { return path }
+	askContinue = continueFlag
+	t.Cleanup(func() {
+		askSessionPath, askContinue = originalPath, originalContinue
+	})
+	return path
+}
+
+func TestSaveAndLoadAskTurn(t *testing.T) {
+	path := stubAskSession(t, false)
+	if err := saveAskTurn(path, "How do I enable nginx?", "Set services.nginx.enable = true;"); err != nil {
+		t.Fatal(err)
+	}
+	turn, err := loadAskTurn(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if turn.Question != "How do I enable nginx?" || turn.Answer != "Set services.nginx.enable = true;" || turn.AskedAt.IsZero() {
+		t.Errorf("unexpected turn: %+v", turn)
+	}
+}
+
+func TestFollowUpContextIncludesPreviousTurn(t *testing.T) {
+	stubAskSession(t, true)
+	rememberAskTurn("How do I enable nginx?", "Set services.nginx.enable = true;")
+
+	var out bytes.Buffer
+	context := followUpContext(&out)
+	for _, want := range []string{"PREVIOUS CONVERSATION", "User: How do I enable nginx?", "Assistant: Set services.nginx.enable = true;"} {
+		if !strings.Contains(context, want) {
+			t.Errorf("follow-up context missing %q:\n%s", want, context)
+		}
+	}
+	if !strings.Contains(out.String(), "How do I enable nginx?") {
+		t.Errorf("expected the continued question to be shown, got %q", out.String())
+	}
+}
+
+func TestFollowUpContextWithoutContinue(t *testing.T) {
+	stubAskSession(t, false)
+	rememberAskTurn("How do I enable nginx?", "Set services.nginx.enable = true;")
+
+	var out bytes.Buffer
+	if context := followUpContext(&out); context != "" || out.Len() != 0 {
+		t.Errorf("expected no follow-up context, got %q / %q", context, out.String())
+	}
+}
+
+func TestFollowUpContextWithoutSession(t *testing.T) {
+	stubAskSession(t, true)
+
+	var out bytes.Buffer
+	if context := followUpContext(&out); context != "" {
+		t.Errorf("expected no context without a saved session, got %q", context)
+	}
+	if !strings.Contains(out.String(), "No previous ask session") {
+		t.Errorf("expected a warning, got %q", out.String())
+	}
+}
+
+func TestLoadAskTurnInvalid(t *testing.T) {
+	path := stubAskSession(t, true)
+	if err := os.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadAskTurn(path); err == nil {
+		t.Error("expected an error for an invalid session file")
+	}
+}
+
+func TestPreviousTurnPromptTruncatesLongAnswers(t *testing.T) {
+	prompt := previousTurnPrompt(&askTurn{Question: "q", Answer: strings.Repeat("a", maxPreviousAnswer+100)})
+	if !strings.Contains(prompt, "[previous answer truncated]") || strings.Count(prompt, "a") > maxPreviousAnswer+20 {
+		t.Errorf("long answer not truncated")
+	}
+}
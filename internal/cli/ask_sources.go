@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"nix-ai-help/internal/community"
+	"nix-ai-help/internal/config"
+	"nix-ai-help/internal/mcp"
+	"nix-ai-help/internal/nixos"
+	"nix-ai-help/pkg/utils"
+)
+
+// newAskPackageSearcher and newAskConfigurationSearcher create the package and GitHub
+// searchers of the ask sources; tests replace them to keep nix and GitHub out of the run
+var (
+	newAskPackageSearcher = func(cfg *config.UserConfig) packageSearcher {
+		return nixos.NewExecutor(cfg.NixosFolder)
+	}
+	newAskConfigurationSearcher = func() configurationSearcher {
+		return community.NewGitHubClient(os.Getenv("GITHUB_TOKEN"))
+	}
+)
+
+// askSource is one independent information source gathered for an ask question
+type askSource struct {
+	label  string        // progress label, e.g. "Querying official documentation"
+	gather func() string // collects the source's results and returns a formatted status
+}
+
+// askSources returns the sources gathered for an ask question: the configured documentation
+// sources, queried through the MCP server when one is configured, packages and options, and
+// real-world configurations for questions about flakes, configurations, services or enabling
+// something. Each source appends its results to docExcerpts, searchContext or githubExamples;
+// githubSearch receives the result of the configuration search.
+func askSources(cfg *config.UserConfig, nixosCtx *config.NixOSContext, question string, searchTerms []string,
+	docExcerpts, searchContext, githubExamples *[]string, githubSearch *githubExampleSearch) []askSource {
+	var sources []askSource
+
+	// 1. MCP server documentation queries
+	if cfg.MCPServer.Host != "" {
+		sources = append(sources, askSource{label: "Querying official documentation", gather: func() string {
+			mcpClient := newDocsClient(cfg, nixosCtx)
+
+			// Primary documentation query
+			status := utils.FormatWarning("no documentation found")
+			doc, mcpErr := mcpClient.QueryDocumentation(question, config.SourceURLs(cfg.MCPServer.DocumentationSources)...)
+			if mcpErr == nil && doc != "" {
+				opt, fallbackDoc := parseMCPOptionDoc(doc)
+				if opt.Name != "" {
+					context := fmt.Sprintf("NixOS Option Documentation:\nOption: %s\nType: %s\nDefault: %s\nExample: %s\nDescription: %s\nSource: %s\nVersion: %s\nRelated: %v\nLinks: %v",
+						opt.Name, opt.Type, opt.Default, opt.Example, opt.Description, opt.Source, opt.Version, opt.Related, opt.Links)
+					*docExcerpts = append(*docExcerpts, context)
+					status = utils.FormatSuccess("found option documentation")
+				} else if len(fallbackDoc) > 10 && len(fallbackDoc) < 3000 {
+					*docExcerpts = append(*docExcerpts, "NixOS Documentation Context:\n"+fallbackDoc)
+					status = utils.FormatSuccess("found general documentation")
+				} else {
+					status = utils.FormatWarning("limited documentation found")
+				}
+			}
+
+			// Query for service examples if applicable
+			for _, term := range searchTerms {
+				if strings.Contains(question, "service") || strings.Contains(question, "enable") {
+					if serviceDoc, err := mcpClient.QueryDocumentation("service examples for " + term); err == nil && serviceDoc != "" {
+						if len(serviceDoc) > 20 && len(serviceDoc) < 2000 {
+							*docExcerpts = append(*docExcerpts, fmt.Sprintf("Service Configuration Examples for '%s':\n%s", term, serviceDoc))
+						}
+					}
+				}
+			}
+			return status
+		}})
+	}
+
+	// 2. Package and options search
+	sources = append(sources, askSource{label: "Searching packages and options", gather: func() string {
+		packageResults := searchPackagesForTerms(newAskPackageSearcher(cfg), searchTerms)
+		*searchContext = append(*searchContext, packageResults...)
+		if len(packageResults) > 0 {
+			return utils.FormatSuccess(fmt.Sprintf("found %d package results", len(packageResults)))
+		}
+		return utils.FormatWarning("no packages found")
+	}})
+
+	// 3. GitHub code search
+	if strings.Contains(question, "flake") || strings.Contains(question, "configuration") ||
+		strings.Contains(question, "service") || strings.Contains(question, "enable") {
+		sources = append(sources, askSource{label: "Searching real-world configurations", gather: func() string {
+			*githubSearch = searchGitHubExamples(newAskConfigurationSearcher(), searchTerms)
+			*githubExamples = githubSearch.Examples
+			switch {
+			case githubSearch.Skipped:
+				return utils.FormatWarning("skipped (offline)")
+			case len(*githubExamples) > 0:
+				return utils.FormatSuccess(fmt.Sprintf("found %d configuration examples", len(*githubExamples)))
+			case githubSearch.Failed():
+				return utils.FormatWarning("GitHub search failed: " + githubSearch.Err.Error())
+			default:
+				return utils.FormatWarning("no configuration examples found")
+			}
+		}})
+	}
+	return sources
+}
+
+// gatherAskSources runs all sources concurrently. Each source's progress line is
+// written as a whole once it finishes, so output stays readable regardless of order.
+func gatherAskSources(out io.Writer, sources []askSource) {
+	for _, source := range sources {
+		_, _ = fmt.Fprintln(out, utils.FormatProgress(source.label+"..."))
+	}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for _, source := range sources {
+		wg.Add(1)
+		go func(source askSource) {
+			defer wg.Done()
+			status := source.gather()
+
+			mu.Lock()
+			defer mu.Unlock()
+			_, _ = fmt.Fprintln(out, utils.FormatInfo(source.label+"... ")+status)
+		}(source)
+	}
+	wg.Wait()
+}
+
+// dedupeAskExcerpts drops the paragraphs that repeat across the documentation excerpts of a
+// question. The excerpts come from separate MCP queries, e.g. the service examples of each
+// term and the broader-term searches, which often return the same pages. The label line of
+// an excerpt, e.g. "Service Configuration Examples for 'nginx':", is kept out of the
+// comparison, and an excerpt left with nothing but its label is dropped.
+func dedupeAskExcerpts(excerpts []string) []string {
+	labels := make([]string, len(excerpts))
+	bodies := make([]string, len(excerpts))
+	for i, excerpt := range excerpts {
+		bodies[i] = excerpt
+		if label, body, found := strings.Cut(excerpt, "\n"); found && strings.HasSuffix(label, ":") {
+			labels[i], bodies[i] = label, body
+		}
+	}
+
+	var deduped []string
+	for i, body := range mcp.DedupeTexts(bodies) {
+		switch {
+		case body == "":
+			continue
+		case labels[i] != "":
+			deduped = append(deduped, labels[i]+"\n"+body)
+		default:
+			deduped = append(deduped, body)
+		}
+	}
+	return deduped
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nix-ai-help/internal/community"
+	"nix-ai-help/internal/config"
+)
+
+// barrierPackageSearcher finds one package per term once every ask source has started
+type barrierPackageSearcher struct{ wait func() bool }
+
+func (s barrierPackageSearcher) SearchNixPackages(query string) (string, error) {
+	if !s.wait() {
+		return "", nil
+	}
+	return "nixpkgs." + query, nil
+}
+
+// barrierConfigurationSearcher finds one configuration per term once every ask source has
+// started
+type barrierConfigurationSearcher struct{ wait func() bool }
+
+func (s barrierConfigurationSearcher) SearchNixOSConfigurations(topic string) ([]community.Configuration, error) {
+	if !s.wait() {
+		return nil, nil
+	}
+	return []community.Configuration{{Name: topic + "-config", URL: "https://github.com/example/" + topic}}, nil
+}
+
+func TestAskSources_GatherConfiguredSourcesConcurrently(t *testing.T) {
+	// Every source waits until all three have started, so a sequential run would time out
+	var started sync.WaitGroup
+	started.Add(3)
+	allStarted := make(chan struct{})
+	go func() { started.Wait(); close(allStarted) }()
+	waitOnce := func() func() bool {
+		var once sync.Once
+		return func() bool {
+			once.Do(started.Done)
+			select {
+			case <-allStarted:
+				return true
+			case <-time.After(2 * time.Second):
+				return false
+			}
+		}
+	}
+
+	var (
+		mu           sync.Mutex
+		queriedFirst []string
+	)
+	waitDocs := waitOnce()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/query" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Query   string   `json:"query"`
+			Sources []string `json:"sources"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		result := "services.nginx.enable: Whether to enable the nginx web server."
+		if !strings.HasPrefix(req.Query, "service examples") {
+			mu.Lock()
+			queriedFirst = req.Sources
+			mu.Unlock()
+			if !waitDocs() {
+				result = ""
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"result": result})
+	}))
+	defer server.Close()
+
+	origPackages, origConfigurations := newAskPackageSearcher, newAskConfigurationSearcher
+	waitPackages, waitConfigurations := waitOnce(), waitOnce()
+	newAskPackageSearcher = func(*config.UserConfig) packageSearcher { return barrierPackageSearcher{waitPackages} }
+	newAskConfigurationSearcher = func() configurationSearcher { return barrierConfigurationSearcher{waitConfigurations} }
+	defer func() { newAskPackageSearcher, newAskConfigurationSearcher = origPackages, origConfigurations }()
+
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultUserConfig()
+	cfg.MCPServer.Host = host
+	cfg.MCPServer.Port, _ = strconv.Atoi(port)
+	cfg.MCPServer.DocumentationSources = config.DocumentationSourcesFromURLs("https://wiki.nixos.org/wiki/NixOS_Wiki", "https://nix.dev/")
+
+	question := "How do I enable nginx?"
+	var docExcerpts, searchContext, githubExamples []string
+	var githubSearch githubExampleSearch
+	sources := askSources(cfg, nil, question, extractSearchTerms(question), &docExcerpts, &searchContext, &githubExamples, &githubSearch)
+	if len(sources) != 3 {
+		t.Fatalf("expected documentation, package and configuration sources, got %d", len(sources))
+	}
+
+	var out bytes.Buffer
+	gatherAskSources(&out, sources)
+
+	if len(docExcerpts) == 0 || len(searchContext) == 0 || len(githubExamples) == 0 {
+		t.Fatalf("sources did not run concurrently or were not merged: docs=%v packages=%v examples=%v\n%s",
+			docExcerpts, searchContext, githubExamples, out.String())
+	}
+	if want := config.SourceURLs(cfg.MCPServer.DocumentationSources); !reflect.DeepEqual(queriedFirst, want) {
+		t.Errorf("documentation queried with sources %v, want the configured %v", queriedFirst, want)
+	}
+	for _, want := range []string{"found general documentation", "package results", "configuration examples"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected progress output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
@@ -57,6 +57,11 @@ Enhanced monitoring:
   nixai build background firefox     # Start firefox build in background
   nixai build status                 # Show all active builds
   nixai build queue pkg1 pkg2 pkg3   # Build packages sequentially with AI optimization`,
+	Example: `  # Run a basic build with AI assistance
+  nixai build
+
+  # Analyze a failing package build
+  nixai build debug firefox`,
 	Args: cobra.ArbitraryArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Check if this is a subcommand call first
@@ -123,7 +128,7 @@ Enhanced monitoring:
 		if nixosConfigPathGlobal != "" {
 			command.Dir = nixosConfigPathGlobal
 		}
-		out, err := command.CombinedOutput()
+		out, err := utils.TraceCombinedOutput(command)
 
 		if err == nil {
 			fmt.Println(utils.FormatSuccess("✅ Build completed successfully!"))
@@ -632,7 +637,7 @@ func attemptBuild(packageName string, verbose bool) (string, error) {
 		cmd.Args = append(cmd.Args, "--verbose")
 	}
 
-	output, err := cmd.CombinedOutput()
+	output, err := utils.TraceCombinedOutput(cmd)
 	return string(output), err
 }
 
@@ -724,7 +729,7 @@ func getLastBuildFailure() string {
 	}
 
 	// Check nix-store for recent failures
-	if out, err := exec.Command("nix-store", "--query", "--failed").CombinedOutput(); err == nil {
+	if out, err := utils.TraceCombinedOutput(exec.Command("nix-store", "--query", "--failed")); err == nil {
 		failures := strings.TrimSpace(string(out))
 		if failures != "" {
 			lines := strings.Split(failures, "\n")
@@ -743,7 +748,7 @@ func applyFixesAndRetry(packageName string) bool {
 
 	// Try garbage collection first
 	fmt.Println(utils.FormatProgress("Running garbage collection..."))
-	if _, err := exec.Command("nix-collect-garbage").CombinedOutput(); err != nil {
+	if _, err := utils.TraceCombinedOutput(exec.Command("nix-collect-garbage")); err != nil {
 		fmt.Println(utils.FormatWarning("Garbage collection failed: " + err.Error()))
 	} else {
 		fmt.Println(utils.FormatInfo("Garbage collection completed"))
@@ -751,7 +756,7 @@ func applyFixesAndRetry(packageName string) bool {
 
 	// Try updating the channel
 	fmt.Println(utils.FormatProgress("Updating nix channels..."))
-	if _, err := exec.Command("nix-channel", "--update").CombinedOutput(); err != nil {
+	if _, err := utils.TraceCombinedOutput(exec.Command("nix-channel", "--update")); err != nil {
 		fmt.Println(utils.FormatWarning("Channel update failed: " + err.Error()))
 	} else {
 		fmt.Println(utils.FormatInfo("Channels updated"))
@@ -759,7 +764,7 @@ func applyFixesAndRetry(packageName string) bool {
 
 	// Clear failed builds
 	fmt.Println(utils.FormatProgress("Clearing failed builds..."))
-	if _, err := exec.Command("nix-store", "--clear-failed-paths").CombinedOutput(); err != nil {
+	if _, err := utils.TraceCombinedOutput(exec.Command("nix-store", "--clear-failed-paths")); err != nil {
 		fmt.Println(utils.FormatWarning("Failed to clear failed paths: " + err.Error()))
 	}
 
@@ -772,7 +777,7 @@ func applyFixesAndRetry(packageName string) bool {
 		cmd = exec.Command("nix", "build", packageName)
 	}
 
-	output, err := cmd.CombinedOutput()
+	output, err := utils.TraceCombinedOutput(cmd)
 	if err == nil {
 		fmt.Println(utils.FormatSuccess("Retry successful!"))
 		return true
@@ -788,7 +793,7 @@ func analyzeCachePerformance() map[string]interface{} {
 	stats := make(map[string]interface{})
 
 	// Get cache size
-	if out, err := exec.Command("nix-store", "--query", "--size", "--all").CombinedOutput(); err == nil {
+	if out, err := utils.TraceCombinedOutput(exec.Command("nix-store", "--query", "--size", "--all")); err == nil {
 		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
 		if len(lines) > 0 {
 			stats["cache_entries"] = len(lines)
@@ -798,7 +803,7 @@ func analyzeCachePerformance() map[string]interface{} {
 	}
 
 	// Check for binary cache configuration
-	if out, err := exec.Command("nix", "show-config").CombinedOutput(); err == nil {
+	if out, err := utils.TraceCombinedOutput(exec.Command("nix", "show-config")); err == nil {
 		config := string(out)
 		if strings.Contains(config, "substituters") {
 			// Count configured substituters
@@ -813,7 +818,7 @@ func analyzeCachePerformance() map[string]interface{} {
 	}
 
 	// Get some recent build info from nix-store
-	if out, err := exec.Command("nix-store", "--verify", "--check-contents").CombinedOutput(); err == nil {
+	if out, err := utils.TraceCombinedOutput(exec.Command("nix-store", "--verify", "--check-contents")); err == nil {
 		if strings.Contains(string(out), "checking") {
 			stats["store_integrity"] = "verified"
 		}
@@ -839,7 +844,7 @@ func analyzeSandboxEnvironment() map[string]interface{} {
 	info := make(map[string]interface{})
 
 	// Check nix configuration for sandbox settings
-	if out, err := exec.Command("nix", "show-config").CombinedOutput(); err == nil {
+	if out, err := utils.TraceCombinedOutput(exec.Command("nix", "show-config")); err == nil {
 		config := string(out)
 		lines := strings.Split(config, "\n")
 
@@ -866,7 +871,7 @@ func analyzeSandboxEnvironment() map[string]interface{} {
 	}
 
 	// Check build users
-	if out, err := exec.Command("getent", "group", "nixbld").CombinedOutput(); err == nil {
+	if out, err := utils.TraceCombinedOutput(exec.Command("getent", "group", "nixbld")); err == nil {
 		info["build_users_configured"] = true
 		// Count build users
 		groupInfo := string(out)
@@ -916,7 +921,7 @@ func profileBuild(packageName string) map[string]interface{} {
 	}
 
 	// Capture output and timing
-	output, err := cmd.CombinedOutput()
+	output, err := utils.TraceCombinedOutput(cmd)
 	duration := time.Since(startTime)
 
 	data["dry_run_time"] = duration.String()
@@ -942,12 +947,12 @@ func profileBuild(packageName string) map[string]interface{} {
 	data["dependencies_to_download"] = downloadCount
 
 	// Get system information for context
-	if out, err := exec.Command("nproc").CombinedOutput(); err == nil {
+	if out, err := utils.TraceCombinedOutput(exec.Command("nproc")); err == nil {
 		data["cpu_cores"] = strings.TrimSpace(string(out))
 	}
 
 	// Get memory info
-	if out, err := exec.Command("free", "-h").CombinedOutput(); err == nil {
+	if out, err := utils.TraceCombinedOutput(exec.Command("free", "-h")); err == nil {
 		lines := strings.Split(string(out), "\n")
 		if len(lines) > 1 {
 			fields := strings.Fields(lines[1])
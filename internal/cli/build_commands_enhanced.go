@@ -440,7 +440,7 @@ func optimizeBuildOrder(packages []string) []string {
 // initializeModernAIProvider creates an AI provider that implements the ai.Provider interface
 func initializeModernAIProvider(cfg *config.UserConfig) ai.Provider {
 	// Use the new ProviderManager system
-	manager := ai.NewProviderManager(cfg, logger.NewLogger())
+	manager := GetAIProviderManager(cfg, logger.NewLogger())
 
 	// Get the configured default provider or fall back to ollama
 	defaultProvider := cfg.AIModels.SelectionPreferences.DefaultProvider
@@ -2,15 +2,18 @@ package cli
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"nix-ai-help/internal/ai"
@@ -27,6 +30,7 @@ import (
 	"nix-ai-help/pkg/version"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var rootCmd = &cobra.Command{
@@ -43,6 +47,22 @@ Usage:
 	SilenceUsage: true,
 	Version:      version.Get().Version,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		mcp.SetStrictVersionCheck(strictMCPVersion)
+		mcp.SetVersionWarningHandler(func(err error) {
+			fmt.Fprintln(os.Stderr, utils.FormatWarning(err.Error()))
+		})
+		samplingCommand = topLevelCommandName(cmd)
+
+		// Returning ErrHelp makes cobra call the help func, which prints only examples
+		if showExamples {
+			return pflag.ErrHelp
+		}
+
+		if err := checkNixAvailable(cmd, nixos.NewExecutor("")); err != nil {
+			fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+			os.Exit(1)
+		}
+
 		// Check for global TUI flag and handle it for any command except interactive
 		if globalTUI && cmd.Name() != "interactive" {
 			// For non-interactive commands, launch TUI with the command pre-selected
@@ -101,6 +121,10 @@ var aiProvider string
 var aiModel string
 var contextFile string
 var globalTUI bool
+var strictMCPVersion bool
+var showExamples bool
+var traceCommands bool
+var noContext bool
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&askQuestion, "ask", "a", "", "Ask a question about NixOS configuration")
@@ -109,21 +133,60 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&agentType, "agent", "", "Specify the agent type (ask, build, diagnose, flake, etc.)")
 	rootCmd.PersistentFlags().StringVar(&aiProvider, "provider", "", "Specify the AI provider (ollama, openai, gemini, etc.)")
 	rootCmd.PersistentFlags().StringVar(&aiModel, "model", "", "Specify the AI model (llama3, gpt-4, gemini-1.5-pro, etc.)")
+	rootCmd.PersistentFlags().Var(&temperatureFlag, "temperature", "AI sampling temperature from 0 to 2; lower gives more deterministic answers")
+	rootCmd.PersistentFlags().Var(&topPFlag, "top-p", "AI nucleus sampling (top_p) from 0 to 1")
 	rootCmd.PersistentFlags().StringVar(&contextFile, "context-file", "", "Path to a file containing context information (JSON or text)")
 	rootCmd.PersistentFlags().BoolVar(&globalTUI, "tui", false, "Launch TUI mode for any command")
+	rootCmd.PersistentFlags().BoolVar(&strictMCPVersion, "strict-mcp-version", false, "Refuse to use an MCP server whose version is incompatible with this client")
+	rootCmd.PersistentFlags().BoolVar(&showExamples, "examples", false, "Show runnable examples for the command and exit")
+	rootCmd.PersistentFlags().StringVar(&outputLanguage, "lang", "", "Language for AI responses, e.g. de or fr (Nix code stays in English)")
+	rootCmd.PersistentFlags().BoolVar(&noAI, "no-ai", false, "Skip the AI provider and show only local results; commands that need AI refuse to run")
+	rootCmd.PersistentFlags().BoolVar(&noPager, "no-pager", false, "Do not page long output through $PAGER (default less -R)")
+	rootCmd.PersistentFlags().BoolVar(&traceCommands, "trace", false, "Print every external command run, with its exit code and duration, to stderr")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Use this config file instead of ~/.config/nixai/config.yaml")
+	rootCmd.PersistentFlags().BoolVar(&noContext, "no-context", false, "Skip NixOS context detection and build prompts without system context")
 	mcpServerCmd.Flags().BoolVarP(&daemonMode, "daemon", "d", false, "Run MCP server in background/daemon mode")
+	mcpServerCmd.AddCommand(newMCPQueryCmd())
+	searchCmd.Flags().String("format", "text", "Package result format: text or table")
+	searchCmd.Flags().Bool("service", false, "Search NixOS service options (services.<name>.*) instead of packages")
+	searchCmd.Flags().IntVar(&searchLimit, "limit", defaultSearchLimit, "Maximum number of packages to show (0 shows all)")
+	searchCmd.Flags().IntVar(&searchOffset, "offset", 0, "Number of packages to skip, for the next pages of results")
+	searchCmd.Flags().BoolVar(&searchExplain, "explain", false, "Tell whether the query is a package to install, a service to enable or both")
+	addOutputFormatFlags(searchCmd)
+	completionCmd.Flags().Bool("model-list", false, "List all known provider:model pairs used for --model completion")
 	doctorCmd.Flags().BoolP("verbose", "v", false, "Show detailed output and progress information")
+	doctorCmd.Flags().Bool("fix", false, "Step through the suggested commands and run the ones you confirm")
+	doctorCmd.Flags().StringSlice("only", nil, "Run only these check categories (comma-separated or repeated)")
+	doctorCmd.Flags().StringSlice("skip", nil, "Skip these check categories (comma-separated or repeated)")
+	doctorCmd.Flags().Bool("summary", false, "Print only a one-line verdict; exits 1 on warnings and 2 on failures")
+	addOutputFormatFlags(doctorCmd)
 
 	// Add ask command flags
 	askCmd.Flags().BoolP("quiet", "q", false, "Suppress validation output and show only the AI response")
 	askCmd.Flags().BoolP("verbose", "v", false, "Show detailed validation output with multi-section layout")
 	askCmd.Flags().BoolP("stream", "s", false, "Stream the response in real-time")
+	askCmd.Flags().BoolVar(&askOffline, "offline", false, "Skip network lookups such as the GitHub example search")
+	askCmd.Flags().BoolVar(&askContinue, "continue", false, "Follow up on the previous ask question and answer")
+	askCmd.Flags().Var(&responseLength, "length", "Answer length: short, normal or detailed")
+	askCmd.Flags().BoolVar(&askFresh, "fresh", false, "Ignore cached answers and ask the AI provider again")
+	askCmd.Flags().BoolVar(&askCacheStats, "cache-stats", false, "Show hit and miss counts and the size of the answer cache")
+	askCmd.Flags().BoolVar(&askExplainCached, "explain-why-cached", false, "Explain why an answer was served from the cache")
+	askCmd.Flags().StringSliceVar(&askEnsemble, "ensemble", nil, "Ask these providers (provider or provider:model, comma-separated) and merge their answers, flagging disagreements")
+	askCmd.Flags().IntVar(&askMinQuality, "min-quality", 0, "Broaden source gathering before answering when the context quality score (0-4) is below this value")
+	addOutputFormatFlags(askCmd)
+	addRawFlag(askCmd)
 
 	// Add package-repo command flags
 	packageRepoCmd.Flags().String("local", "", "Analyze local repository path instead of cloning")
 	packageRepoCmd.Flags().String("output", "", "Output file path for generated derivation")
 	packageRepoCmd.Flags().String("name", "", "Override package name for the derivation")
 	packageRepoCmd.Flags().Bool("analyze-only", false, "Only analyze repository without generating derivation")
+	packageRepoCmd.Flags().Bool("json", false, "Output the analysis and derivation as JSON")
+	packageRepoCmd.Flags().String("subdir", "", "Package this subdirectory of the repository instead of the detected package root")
+	packageRepoCmd.Flags().Bool("interactive", false, "Refine the generated derivation with feedback before saving it")
+	packageRepoCmd.Flags().Bool("open", false, "Open the written derivation in $EDITOR (or $VISUAL) when running in a terminal")
+	packageRepoCmd.Flags().Bool("keep-temp", false, "Keep the cloned repository and temporary files for debugging")
+	addYesFlag(packageRepoCmd)
 
 	// Add logs subcommands
 	logsCmd.AddCommand(logsSystemCmd)
@@ -132,6 +195,7 @@ func init() {
 	logsCmd.AddCommand(logsErrorsCmd)
 	logsCmd.AddCommand(logsBuildCmd)
 	logsCmd.AddCommand(logsAnalyzeCmd)
+	logsAnalyzeCmd.Flags().StringVar(&logsAnalyzeFormat, "format", logsFormatText, "Output format: text or jsonl (one JSON finding per line, streamed as the log is read)")
 }
 
 // Helper functions for agent/role/context handling
@@ -286,12 +350,21 @@ func showConfig() {
 	fmt.Println()
 	fmt.Println(utils.FormatKeyValue("AI Provider", cfg.AIProvider))
 	fmt.Println(utils.FormatKeyValue("AI Model", cfg.AIModel))
+	if cfg.Language != "" {
+		fmt.Println(utils.FormatKeyValue("Response Language", cfg.Language))
+	}
+	if cfg.Output.DefaultFormat != "" {
+		fmt.Println(utils.FormatKeyValue("Default Output Format", cfg.Output.DefaultFormat))
+	}
+	if cfg.Network.Proxy != "" {
+		fmt.Println(utils.FormatKeyValue("Proxy", config.RedactProxy(cfg.Network.Proxy)))
+	}
 	fmt.Println(utils.FormatKeyValue("Log Level", cfg.LogLevel))
 	fmt.Println(utils.FormatKeyValue("NixOS Folder", cfg.NixosFolder))
 	fmt.Println(utils.FormatKeyValue("MCP Server Host", cfg.MCPServer.Host))
 	fmt.Println(utils.FormatKeyValue("MCP Server Port", fmt.Sprintf("%d", cfg.MCPServer.Port)))
 	if len(cfg.MCPServer.DocumentationSources) > 0 {
-		fmt.Println(utils.FormatKeyValue("Documentation Sources", strings.Join(cfg.MCPServer.DocumentationSources, ", ")))
+		fmt.Println(utils.FormatKeyValue("Documentation Sources", strings.Join(config.SourceURLs(cfg.MCPServer.DocumentationSources), ", ")))
 	}
 }
 
@@ -304,6 +377,7 @@ func setConfig(key, value string) {
 	if nixosPath != "" {
 		cfg.NixosFolder = nixosPath
 	}
+	oldMCPServer := cfg.MCPServer
 
 	switch key {
 	case "ai_provider":
@@ -325,6 +399,22 @@ func setConfig(key, value string) {
 		cfg.AIProvider = value
 	case "ai_model":
 		cfg.AIModel = value
+	case "language":
+		cfg.Language = value
+	case "output.default_format":
+		if !validOutputFormat(value) {
+			fmt.Println(utils.FormatError("Invalid output format. Valid options: " + strings.Join(outputFormats, ", ")))
+			os.Exit(1)
+		}
+		cfg.Output.DefaultFormat = value
+	case "network.proxy":
+		if value != "" {
+			if _, err := utils.ParseProxyURL(value); err != nil {
+				fmt.Println(utils.FormatError("Invalid proxy: " + err.Error()))
+				os.Exit(1)
+			}
+		}
+		cfg.Network.Proxy = value
 	case "log_level":
 		if value != "debug" && value != "info" && value != "warn" && value != "error" {
 			fmt.Println(utils.FormatError("Invalid log level. Valid options: debug, info, warn, error"))
@@ -343,7 +433,7 @@ func setConfig(key, value string) {
 		}
 	default:
 		fmt.Println(utils.FormatError("Unknown configuration key: " + key))
-		fmt.Println(utils.FormatTip("Available keys: ai_provider, ai_model, log_level, nixos_folder, mcp_host, mcp_port"))
+		fmt.Println(utils.FormatTip("Available keys: ai_provider, ai_model, language, output.default_format, network.proxy, log_level, nixos_folder, mcp_host, mcp_port"))
 		os.Exit(1)
 	}
 
@@ -352,9 +442,14 @@ func setConfig(key, value string) {
 		fmt.Fprintln(os.Stderr, utils.FormatError("Failed to save config: "+err.Error()))
 		os.Exit(1)
 	}
+	ResetProviderManager()
 
 	fmt.Println(utils.FormatSuccess("✅ Configuration updated successfully"))
 	fmt.Println(utils.FormatKeyValue(key, value))
+	if warning := cfg.MissingAPIKeyWarning(); key == "ai_provider" && warning != "" {
+		fmt.Println(utils.FormatWarning(warning))
+	}
+	checkMCPAddressChange(mcpAddressPromptReader(), os.Stdout, oldMCPServer, cfg)
 }
 
 func getConfig(key string) {
@@ -373,6 +468,12 @@ func getConfig(key string) {
 		value = cfg.AIProvider
 	case "ai_model":
 		value = cfg.AIModel
+	case "language":
+		value = cfg.Language
+	case "output.default_format":
+		value = cfg.Output.DefaultFormat
+	case "network.proxy":
+		value = cfg.Network.Proxy
 	case "log_level":
 		value = cfg.LogLevel
 	case "nixos_folder":
@@ -383,7 +484,7 @@ func getConfig(key string) {
 		value = fmt.Sprintf("%d", cfg.MCPServer.Port)
 	default:
 		fmt.Println(utils.FormatError("Unknown configuration key: " + key))
-		fmt.Println(utils.FormatTip("Available keys: ai_provider, ai_model, log_level, nixos_folder, mcp_host, mcp_port"))
+		fmt.Println(utils.FormatTip("Available keys: ai_provider, ai_model, language, output.default_format, network.proxy, log_level, nixos_folder, mcp_host, mcp_port"))
 		os.Exit(1)
 	}
 
@@ -408,13 +509,13 @@ func resetConfig() {
 		MCPServer: config.MCPServerConfig{
 			Host: "localhost",
 			Port: 8081,
-			DocumentationSources: []string{
+			DocumentationSources: config.DocumentationSourcesFromURLs(
 				"https://wiki.nixos.org/wiki/NixOS_Wiki",
 				"https://nix.dev/manual/nix",
 				"https://nixos.org/manual/nixpkgs/stable/",
 				"https://nix.dev/manual/nix/2.28/language/",
 				"https://nix-community.github.io/home-manager/",
-			},
+			),
 		},
 	}
 
@@ -423,6 +524,7 @@ func resetConfig() {
 		fmt.Fprintln(os.Stderr, utils.FormatError("Failed to reset config: "+err.Error()))
 		os.Exit(1)
 	}
+	ResetProviderManager()
 
 	fmt.Println(utils.FormatSuccess("✅ Configuration reset to defaults successfully"))
 }
@@ -431,28 +533,132 @@ func resetConfig() {
 // Only fields we care about
 
 type mcpOptionDoc struct {
-	Name        string   `json:"option_name"`
-	Type        string   `json:"option_type"`
-	Default     string   `json:"option_default"`
-	Example     string   `json:"option_example"`
-	Description string   `json:"option_description"`
-	Source      string   `json:"option_source"`
-	Version     string   `json:"nixos_version"`
-	Related     []string `json:"related_options"`
-	Links       []string `json:"links"`
-}
-
-// Parse MCP doc JSON, fallback to plain doc string if not JSON
+	Name        string      `json:"option_name"`
+	Type        string      `json:"option_type"`
+	Default     optionValue `json:"option_default"`
+	Example     optionValue `json:"option_example"`
+	Description string      `json:"option_description"`
+	Source      string      `json:"option_source"`
+	Version     string      `json:"nixos_version"`
+	Related     []string    `json:"related_options"`
+	Links       []string    `json:"links"`
+}
+
+// optionValue is the default or example of an option as text. Option indexes send them as
+// strings, but option JSON may hold the value itself, e.g. false, [ ] or a literalExpression
+// object, which is rendered rather than dropped.
+type optionValue string
+
+func (v *optionValue) UnmarshalJSON(data []byte) error {
+	var raw json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*v = optionValue(renderOptionValue(raw))
+	return nil
+}
+
+// renderOptionValue renders a JSON option value: strings as they are, literal expressions as
+// their Nix text and anything else as compact JSON
+func renderOptionValue(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var literal struct {
+		Type string `json:"_type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &literal); err == nil && strings.HasPrefix(literal.Type, "literal") {
+		return literal.Text
+	}
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return ""
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return string(raw)
+	}
+	return compact.String()
+}
+
+// Parse MCP doc JSON, fallback to plain doc string if not JSON. The first option documented in
+// the doc is returned; see parseMCPOptionDocs.
 func parseMCPOptionDoc(doc string) (mcpOptionDoc, string) {
-	var opt mcpOptionDoc
-	if err := json.Unmarshal([]byte(doc), &opt); err == nil && opt.Name != "" {
-		return opt, ""
+	if opts := parseMCPOptionDocs(doc); len(opts) > 0 {
+		return opts[0], ""
+	}
+	return mcpOptionDoc{}, doc
+}
+
+// parseMCPOptionDocNamed is parseMCPOptionDoc preferring the option called name when the doc
+// documents several
+func parseMCPOptionDocNamed(doc, name string) (mcpOptionDoc, string) {
+	opts := parseMCPOptionDocs(doc)
+	for _, opt := range opts {
+		if opt.Name == name {
+			return opt, ""
+		}
+	}
+	if len(opts) > 0 {
+		return opts[0], ""
 	}
 	return mcpOptionDoc{}, doc
 }
 
+// parseMCPOptionDocs returns the options documented in an MCP response, in order. The JSON may
+// be wrapped in prose or a code fence, and may hold several objects or arrays of options; JSON
+// values without an option_name are searched for options nested inside them.
+func parseMCPOptionDocs(doc string) []mcpOptionDoc {
+	var opts []mcpOptionDoc
+	for i := 0; i < len(doc); i++ {
+		if doc[i] != '{' && doc[i] != '[' {
+			continue
+		}
+		decoder := json.NewDecoder(strings.NewReader(doc[i:]))
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			continue
+		}
+		if found := decodeMCPOptions(value); len(found) > 0 {
+			opts = append(opts, found...)
+			// Continue after the value rather than inside it
+			i += int(decoder.InputOffset()) - 1
+		}
+	}
+	return opts
+}
+
+// decodeMCPOptions decodes a JSON option object or array of them, keeping the options with a
+// name. A field of an unexpected type, such as a boolean option_default, is left empty rather
+// than discarding the option.
+func decodeMCPOptions(value json.RawMessage) []mcpOptionDoc {
+	usable := func(err error) bool {
+		var typeErr *json.UnmarshalTypeError
+		return err == nil || errors.As(err, &typeErr)
+	}
+	if bytes.HasPrefix(value, []byte("[")) {
+		var list []mcpOptionDoc
+		if !usable(json.Unmarshal(value, &list)) {
+			return nil
+		}
+		var opts []mcpOptionDoc
+		for _, opt := range list {
+			if opt.Name != "" {
+				opts = append(opts, opt)
+			}
+		}
+		return opts
+	}
+	var opt mcpOptionDoc
+	if !usable(json.Unmarshal(value, &opt)) || opt.Name == "" {
+		return nil
+	}
+	return []mcpOptionDoc{opt}
+}
+
 func buildEnhancedExplainOptionPrompt(option, documentation, format, source, version string) string {
-	opt, fallbackDoc := parseMCPOptionDoc(documentation)
+	opt, fallbackDoc := parseMCPOptionDocNamed(documentation, option)
 	if opt.Name == "" {
 		// fallback to old prompt if not JSON
 		sourceInfo := ""
@@ -462,7 +668,24 @@ func buildEnhancedExplainOptionPrompt(option, documentation, format, source, ver
 		if version != "" {
 			sourceInfo += fmt.Sprintf("\n**NixOS Version:** %s", version)
 		}
-		return fmt.Sprintf(`You are a NixOS expert helping users understand configuration options. Please explain the following NixOS option in a clear, practical manner.\n\n**Option:** %s%s\n\n**Official Documentation:**\n%s\n\n**Please provide:**\n\n1. **Purpose & Overview**: What this option does and why you'd use it\n2. **Type & Default**: The data type and default value (if any)\n3. **Usage Examples**: Show 2-3 practical configuration examples\n4. **Best Practices**: How to use this option effectively\n5. **Related Options**: List and briefly describe other options commonly used with this one\n6. **Troubleshooting Tips**: Common issues and how to resolve them\n7. **Links**: If possible, include links to relevant official documentation\n8. **Summary Table**: Provide a summary table of key attributes (name, type, default, description)\n\nFormat your response using %s with section headings and code blocks for examples.`, option, sourceInfo, fallbackDoc, format)
+		return fmt.Sprintf("You are a NixOS expert helping users understand configuration options. "+
+			"Please explain the following NixOS option in a clear, practical manner.\n\n"+
+			"**Option:** %s%s\n\n"+
+			"**Official Documentation:**\n%s\n\n"+
+			"**Please provide:**\n\n"+
+			"1. **Purpose & Overview**: What this option does and why you'd use it\n"+
+			"2. **Type & Default**: The data type and default value (if any)\n"+
+			"3. **Usage Examples**: Show 2-3 practical configuration examples\n"+
+			"4. **Best Practices**: How to use this option effectively\n"+
+			"5. **Related Options**: List and briefly describe other options commonly used with this one\n"+
+			"6. **Troubleshooting Tips**: Common issues and how to resolve them\n"+
+			"7. **Links**: If possible, include links to relevant official documentation\n"+
+			"8. **Summary Table**: Provide a summary table of key attributes (name, type, default, description)\n\n"+
+			"Format your response using %s with section headings and code blocks for examples.",
+			option, sourceInfo, fallbackDoc, format)
+	}
+	if opt.Version == "" {
+		opt.Version = version
 	}
 	// Compose a rich prompt using all available fields
 	related := ""
@@ -473,7 +696,18 @@ func buildEnhancedExplainOptionPrompt(option, documentation, format, source, ver
 	if len(opt.Links) > 0 {
 		links = "- " + strings.Join(opt.Links, "\n- ")
 	}
-	return fmt.Sprintf(`You are a NixOS expert. Explain the following option in detail for a Linux user.\n\n**Option:** %s\n**Type:** %s\n**Default:** %s\n**Example:** %s\n**Description:** %s\n**Source:** %s\n**NixOS Version:** %s\n\n**Related Options:**\n%s\n\n**Links:**\n%s\n\n**Please provide:**\n1. Purpose & Overview\n2. Usage Examples (with code)\n3. Best Practices\n4. Troubleshooting Tips\n5. Summary Table (name, type, default, description)\n\nFormat your response using %s.`,
+	return fmt.Sprintf("You are a NixOS expert. Explain the following option in detail for a Linux user.\n\n"+
+		"**Option:** %s\n**Type:** %s\n**Default:** %s\n**Example:** %s\n**Description:** %s\n"+
+		"**Source:** %s\n**NixOS Version:** %s\n\n"+
+		"**Related Options:**\n%s\n\n"+
+		"**Links:**\n%s\n\n"+
+		"**Please provide:**\n"+
+		"1. Purpose & Overview\n"+
+		"2. Usage Examples (with code)\n"+
+		"3. Best Practices\n"+
+		"4. Troubleshooting Tips\n"+
+		"5. Summary Table (name, type, default, description)\n\n"+
+		"Format your response using %s.",
 		opt.Name, opt.Type, opt.Default, opt.Example, opt.Description, opt.Source, opt.Version, related, links, format)
 }
 
@@ -485,14 +719,38 @@ func buildExamplesOnlyPrompt(option, documentation, format, source, version stri
 	if version != "" {
 		sourceInfo += fmt.Sprintf("\n**NixOS Version:** %s", version)
 	}
-	return fmt.Sprintf(`You are a NixOS expert. Show only 2-3 practical configuration examples for the following option.\n\n**Option:** %s%s\n\n**Official Documentation:**\n%s\n\nFormat your response using %s and code blocks.`, option, sourceInfo, documentation, format)
+	return fmt.Sprintf("You are a NixOS expert. Show only 2-3 practical configuration examples for the following option.\n\n"+
+		"**Option:** %s%s\n\n"+
+		"**Official Documentation:**\n%s\n\n"+
+		"Format your response using %s and code blocks.",
+		option, sourceInfo, documentation, format)
 }
 
 // searchCmd implements the enhanced search logic
 var searchCmd = &cobra.Command{
 	Use:   "search [package]",
 	Short: "Search for NixOS packages/services and get config/AI tips",
-	Args:  conditionalArgsValidator(1),
+	Example: `  # Search for a package
+  nixai search firefox
+
+  # Search with a multi-word query
+  nixai search "web server"
+
+  # Show package results as a table
+  nixai search firefox --format table
+
+  # Show the second page of 20 results
+  nixai search python --offset 20
+
+  # Find service options (services.nginx.*) instead of packages
+  nixai search nginx --service
+
+  # Find out whether to install the nginx package or enable the nginx service
+  nixai search nginx --explain
+
+  # Print the results as JSON
+  nixai search firefox --json`,
+	Args: conditionalArgsValidator(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		query := strings.Join(args, " ")
 		cfg, err := config.LoadUserConfig()
@@ -500,12 +758,23 @@ var searchCmd = &cobra.Command{
 			fmt.Fprintln(os.Stderr, utils.FormatError("Failed to load config: "+err.Error()))
 			os.Exit(1)
 		}
+		outputFormat, err := resolveOutputFormat(cmd.Flags(), cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+			os.Exit(1)
+		}
+		// Progress goes to stderr so that JSON results can be piped
+		status := io.Writer(os.Stdout)
+		if outputFormat == outputJSON {
+			status = os.Stderr
+			utils.DisableSpinners(true)
+		}
 
 		// Initialize context detector and get NixOS context
 		contextDetector := nixos.NewContextDetector(logger.NewLogger())
 		nixosCtx, err := contextDetector.GetContext(cfg)
 		if err != nil {
-			fmt.Println(utils.FormatWarning("Context detection failed: " + err.Error()))
+			_, _ = fmt.Fprintln(status, utils.FormatWarning("Context detection failed: "+err.Error()))
 			nixosCtx = nil
 		}
 
@@ -513,20 +782,76 @@ var searchCmd = &cobra.Command{
 		if nixosCtx != nil && nixosCtx.CacheValid {
 			contextBuilder := nixoscontext.NewNixOSContextBuilder()
 			contextSummary := contextBuilder.GetContextSummary(nixosCtx)
-			fmt.Println(utils.FormatNote("📋 " + contextSummary))
-			fmt.Println()
+			_, _ = fmt.Fprintln(status, utils.FormatNote("📋 "+contextSummary))
+			_, _ = fmt.Fprintln(status)
 		}
 
 		if nixosPath != "" {
 			cfg.NixosFolder = nixosPath
 		}
 		exec := nixos.NewExecutor(cfg.NixosFolder)
-		fmt.Println(utils.FormatHeader("🔍 NixOS Search Results for: " + query))
-		fmt.Println()
-		// Package search
-		pkgOut, pkgErr := exec.SearchNixPackages(query)
-		if pkgErr == nil && pkgOut != "" {
-			fmt.Println(pkgOut)
+		result := searchResultJSON{Query: query}
+		if outputFormat != outputJSON {
+			fmt.Println(utils.FormatHeader("🔍 NixOS Search Results for: " + query))
+			fmt.Println()
+		}
+		// Service option search
+		if service, _ := cmd.Flags().GetBool("service"); service {
+			options, indexed := loadOptionNames(optionIndexPath(), newOptionIndex, serviceOptionQuery(query, nixpkgsRelease(nixosCtx)))
+			if outputFormat == outputJSON {
+				if !indexed {
+					_, _ = fmt.Fprintln(status, utils.FormatWarning(noOptionIndexWarning))
+				}
+				result.Services = searchServiceOptions(options, query, mcpOptionDescriber(cfg, nixosCtx))
+				_ = writeJSONOutput(os.Stdout, result)
+				return
+			}
+			runServiceSearch(os.Stdout, query, options, indexed, mcpOptionDescriber(cfg, nixosCtx))
+			return
+		}
+		// Package search, one page at a time
+		if err := validateSearchPage(searchLimit, searchOffset); err != nil {
+			fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+			os.Exit(1)
+		}
+		var pkgs []nixos.NixPackage
+		pkgErr := exec.CheckNix()
+		if pkgErr != nil {
+			// Without nix there are no package results, but the AI tips below still work
+			_, _ = fmt.Fprintln(status, utils.FormatWarning(pkgErr.Error()+"; skipping the package search"))
+		} else {
+			pkgs, pkgErr = exec.SearchPackages(query)
+		}
+		page := paginatePackages(pkgs, searchLimit, searchOffset)
+		if outputFormat == outputJSON {
+			result.Packages, result.Total = page, len(pkgs)
+		} else if pkgErr == nil && len(pkgs) > 0 {
+			if format, _ := cmd.Flags().GetString("format"); format == "table" {
+				fmt.Println(formatPackageTable(page))
+			} else {
+				fmt.Println(nixos.FormatPackageList(page))
+			}
+			fmt.Println(utils.FormatNote(packagePageFooter(searchOffset, len(page), len(pkgs))))
+			fmt.Println()
+		}
+		// With --explain, tell whether to install the package or enable the service
+		var installOrEnable *InstallOrEnable
+		if searchExplain {
+			options, indexed := loadOptionNames(optionIndexPath(), newOptionIndex, serviceOptionQuery(query, nixpkgsRelease(nixosCtx)))
+			match := detectInstallOrEnable(query, pkgs, options)
+			match.ServiceUnknown = !indexed
+			installOrEnable = &match
+			if outputFormat == outputJSON {
+				result.InstallOrEnable = installOrEnable
+			} else {
+				printInstallOrEnable(os.Stdout, query, match)
+			}
+		}
+		if noAI {
+			if outputFormat == outputJSON {
+				_ = writeJSONOutput(os.Stdout, result)
+			}
+			return
 		}
 		// Query MCP for documentation context (with progress indicator)
 		aiProvider, err := GetLegacyAIProvider(cfg, logger.NewLogger())
@@ -541,15 +866,15 @@ var searchCmd = &cobra.Command{
 			providerName = "ollama"
 		}
 		var docExcerpts []string
-		fmt.Print(utils.FormatInfo("Querying documentation... "))
+		_, _ = fmt.Fprint(status, utils.FormatInfo("Querying documentation... "))
 		mcpBase := cfg.MCPServer.Host
 		mcpContextAdded := false
 		if mcpBase != "" {
 			mcpClient := mcp.NewMCPClient(mcpBase)
 			doc, err := mcpClient.QueryDocumentation(query)
-			fmt.Println(utils.FormatSuccess("done"))
+			_, _ = fmt.Fprintln(status, utils.FormatSuccess("done"))
 			if err == nil && doc != "" {
-				opt, fallbackDoc := parseMCPOptionDoc(doc)
+				opt, fallbackDoc := parseMCPOptionDocNamed(doc, query)
 				if opt.Name != "" {
 					context := fmt.Sprintf("Option: %s\nType: %s\nDefault: %s\nExample: %s\nDescription: %s\nSource: %s\nNixOS Version: %s\nRelated: %v\nLinks: %v", opt.Name, opt.Type, opt.Default, opt.Example, opt.Description, opt.Source, opt.Version, opt.Related, opt.Links)
 					docExcerpts = append(docExcerpts, context)
@@ -560,10 +885,15 @@ var searchCmd = &cobra.Command{
 				}
 			}
 		} else {
-			fmt.Println(utils.FormatWarning("skipped (no MCP host configured)"))
+			_, _ = fmt.Fprintln(status, utils.FormatWarning("skipped (no MCP host configured)"))
 		}
 		// Always add a strong NixOS-specific instruction to the prompt
 		promptInstruction := "You are a NixOS expert. Always provide NixOS-specific configuration.nix examples, use the NixOS module system, and avoid generic Linux or upstream package advice. Show how to enable and configure this package/service in NixOS."
+		if installOrEnable != nil {
+			if steer := installOrEnablePrompt(*installOrEnable); steer != "" {
+				promptInstruction += " " + steer
+			}
+		}
 		if !mcpContextAdded {
 			docExcerpts = append(docExcerpts, promptInstruction)
 		} else {
@@ -588,21 +918,41 @@ var searchCmd = &cobra.Command{
 			fmt.Fprintln(os.Stderr, utils.FormatError("Prompt build error: "+err.Error()))
 			os.Exit(1)
 		}
-		fmt.Print(utils.FormatInfo("Querying AI provider... "))
+		spinner := utils.StartSpinner("Querying AI provider...")
 		aiAnswer, aiErr := aiProvider.Query(prompt)
-		fmt.Println(utils.FormatSuccess("done"))
+		spinner.Stop()
+		if outputFormat == outputJSON {
+			if aiErr == nil {
+				result.Answer = aiAnswer
+			}
+			_ = writeJSONOutput(os.Stdout, result)
+			return
+		}
 		if aiErr == nil && aiAnswer != "" {
 			fmt.Println(utils.FormatHeader("🤖 AI Best Practices & Tips"))
-			fmt.Println(utils.RenderMarkdown(aiAnswer))
+			fmt.Println(renderAnswer(outputFormat, aiAnswer))
 		}
 	},
 }
 
+// searchResultJSON is the search result in the JSON output format
+type searchResultJSON struct {
+	Query           string             `json:"query"`
+	Packages        []nixos.NixPackage `json:"packages,omitempty"`
+	Total           int                `json:"total,omitempty"`
+	Services        []ServiceMatch     `json:"services,omitempty"`
+	Answer          string             `json:"answer,omitempty"`
+	InstallOrEnable *InstallOrEnable   `json:"install_or_enable,omitempty"`
+}
+
 // explainHomeOptionCmd implements the explain-home-option command
 var explainHomeOptionCmd = &cobra.Command{
 	Use:   "explain-home-option <option>",
 	Short: "Explain a Home Manager option using AI and documentation",
-	Args:  conditionalExactArgsValidator(1),
+	Example: `  # Explain a Home Manager option
+  nixai explain-home-option programs.git.enable`,
+	Args:              conditionalExactArgsValidator(1),
+	ValidArgsFunction: completeOptionArg,
 	Run: func(cmd *cobra.Command, args []string) {
 		option := args[0]
 		fmt.Println(utils.FormatHeader("🏠 Home Manager Option: " + option))
@@ -631,8 +981,8 @@ var explainHomeOptionCmd = &cobra.Command{
 			fmt.Println()
 		}
 
-		aiProvider, err := GetLegacyAIProvider(cfg, logger.NewLogger())
-		if err != nil {
+		aiProvider, err := optionalAIProvider(cfg, logger.NewLogger())
+		if err != nil && !errors.Is(err, errAIDisabled) {
 			fmt.Fprintln(os.Stderr, utils.FormatError("Failed to initialize AI provider: "+err.Error()))
 			os.Exit(1)
 		}
@@ -645,6 +995,7 @@ var explainHomeOptionCmd = &cobra.Command{
 
 		// Query MCP for documentation context (with progress indicator)
 		var docExcerpts []string
+		var optionDoc string
 		fmt.Print(utils.FormatInfo("Querying documentation... "))
 		mcpBase := cfg.MCPServer.Host
 		if mcpBase != "" {
@@ -652,7 +1003,8 @@ var explainHomeOptionCmd = &cobra.Command{
 			doc, err := mcpClient.QueryDocumentation(option)
 			fmt.Println(utils.FormatSuccess("done"))
 			if err == nil && doc != "" {
-				opt, fallbackDoc := parseMCPOptionDoc(doc)
+				optionDoc = doc
+				opt, fallbackDoc := parseMCPOptionDocNamed(doc, option)
 				if opt.Name != "" {
 					context := fmt.Sprintf("Option: %s\nType: %s\nDefault: %s\nExample: %s\nDescription: %s\nSource: %s\nNixOS Version: %s\nRelated: %v\nLinks: %v", opt.Name, opt.Type, opt.Default, opt.Example, opt.Description, opt.Source, opt.Version, opt.Related, opt.Links)
 					docExcerpts = append(docExcerpts, context)
@@ -664,6 +1016,15 @@ var explainHomeOptionCmd = &cobra.Command{
 			fmt.Println(utils.FormatWarning("skipped (no MCP host configured)"))
 		}
 
+		if aiProvider == nil {
+			if optionDoc == "" {
+				fmt.Fprintln(os.Stderr, utils.FormatError("No documentation found for option: "+option))
+				os.Exit(1)
+			}
+			writeOptionDocumentation(os.Stdout, option, optionDoc)
+			return
+		}
+
 		promptCtx := ai.PromptContext{
 			Question:     option,
 			DocExcerpts:  docExcerpts,
@@ -680,11 +1041,11 @@ var explainHomeOptionCmd = &cobra.Command{
 
 		// Build context-aware prompt using the context builder
 		contextBuilder := nixoscontext.NewNixOSContextBuilder()
-		contextualPrompt := contextBuilder.BuildContextualPrompt(basePrompt, nixosCtx)
+		contextualPrompt := withLanguageInstruction(contextBuilder.BuildContextualPrompt(basePrompt, nixosCtx), cfg)
 
-		fmt.Print(utils.FormatInfo("Querying AI provider... "))
+		spinner := utils.StartSpinner("Querying AI provider...")
 		aiResp, aiErr := aiProvider.Query(contextualPrompt)
-		fmt.Println(utils.FormatSuccess("done"))
+		spinner.Stop()
 		if aiErr != nil {
 			fmt.Fprintln(os.Stderr, utils.FormatError("AI error: "+aiErr.Error()))
 			os.Exit(1)
@@ -701,12 +1062,32 @@ func NewExplainOptionCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "explain-option <option>",
 		Short: "Explain a NixOS option using AI and documentation",
-		Args:  conditionalExactArgsValidator(1),
+		Example: `  # Explain a NixOS option
+  nixai explain-option services.nginx.enable
+
+  # Show only usage examples
+  nixai explain-option networking.firewall.enable --examples-only
+
+  # Propose a value for a specific situation
+  nixai explain-option services.nginx.appendHttpConfig --suggest "for a high-traffic reverse proxy"`,
+		Args:              conditionalExactArgsValidator(1),
+		ValidArgsFunction: completeOptionArg,
 		Run: func(cmd *cobra.Command, args []string) {
 			option := args[0]
 			format, _ := cmd.Flags().GetString("format")
 			providerFlag, _ := cmd.Flags().GetString("provider")
 			examplesOnly, _ := cmd.Flags().GetBool("examples-only")
+			scenario, _ := cmd.Flags().GetString("suggest")
+			if scenario != "" && examplesOnly {
+				fmt.Fprintln(os.Stderr, utils.FormatError("--suggest cannot be combined with --examples-only"))
+				os.Exit(1)
+			}
+			if scenario != "" || examplesOnly {
+				if err := requireAI("explain-option --suggest and --examples-only"); err != nil {
+					fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+					os.Exit(1)
+				}
+			}
 
 			// Load configuration first
 			cfg, err := config.LoadUserConfig()
@@ -731,8 +1112,8 @@ func NewExplainOptionCommand() *cobra.Command {
 				fmt.Println()
 			}
 
-			mcpURL := fmt.Sprintf("http://%s:%d", cfg.MCPServer.Host, cfg.MCPServer.Port)
-			mcpClient := mcp.NewMCPClient(mcpURL)
+			ensureMCPServer(os.Stdout, cfg)
+			mcpClient := newDocsClient(cfg, nixosCtx)
 			fmt.Print(utils.FormatInfo("Querying documentation... "))
 			doc, docErr := mcpClient.QueryDocumentation(option)
 			fmt.Println(utils.FormatSuccess("done"))
@@ -740,17 +1121,14 @@ func NewExplainOptionCommand() *cobra.Command {
 				fmt.Fprintln(os.Stderr, utils.FormatError("No documentation found for option: "+option))
 				return
 			}
-			var source, version string
+			var source string
 			if strings.Contains(doc, "option_source") {
 				parts := strings.Split(doc, "option_source")
 				if len(parts) > 1 {
 					source = strings.Split(parts[1], "\"")[1]
 				}
 			}
-			if strings.Contains(doc, "nixos-") {
-				idx := strings.Index(doc, "nixos-")
-				version = doc[idx : idx+12]
-			}
+			version := nixpkgsRelease(nixosCtx)
 			aiProviderName := providerFlag
 			if aiProviderName == "" {
 				aiProviderName = cfg.AIProvider
@@ -760,35 +1138,68 @@ func NewExplainOptionCommand() *cobra.Command {
 			tempCfg := *cfg
 			tempCfg.AIProvider = aiProviderName
 
-			aiProvider, err := GetLegacyAIProvider(&tempCfg, logger.NewLogger())
+			dep, deprecated := parseOptionDeprecation(doc)
+			if deprecated {
+				fmt.Println(utils.FormatWarning(deprecationBanner(dep)))
+				fmt.Println()
+			}
+
+			aiProvider, err := optionalAIProvider(&tempCfg, logger.NewLogger())
+			if errors.Is(err, errAIDisabled) {
+				writeOptionDocumentation(os.Stdout, option, doc)
+				return
+			}
 			if err != nil {
 				fmt.Fprintln(os.Stderr, utils.FormatError("Failed to initialize AI provider: "+err.Error()))
 				os.Exit(1)
 			}
 
+			// Render the option attributes as a real table; the AI explanation stays markdown
+			if format == "table" {
+				if opt, _ := parseMCPOptionDoc(doc); opt.Name != "" {
+					fmt.Println(formatOptionTable(opt))
+					fmt.Println()
+				}
+				format = "markdown"
+			}
+
 			// Build context-aware prompt using the context builder
 			var basePrompt string
-			if examplesOnly {
+			optionType := optionTypeFromDoc(doc)
+			if scenario != "" {
+				basePrompt = buildOptionSuggestPrompt(option, doc, optionType, scenario, version)
+			} else if examplesOnly {
 				basePrompt = buildExamplesOnlyPrompt(option, doc, format, source, version)
 			} else {
 				basePrompt = buildEnhancedExplainOptionPrompt(option, doc, format, source, version)
 			}
+			if deprecated {
+				basePrompt += deprecationInstruction(option, dep)
+			}
 			contextBuilder := nixoscontext.NewNixOSContextBuilder()
-			contextualPrompt := contextBuilder.BuildContextualPrompt(basePrompt, nixosCtx)
+			contextualPrompt := withLanguageInstruction(withLengthInstruction(contextBuilder.BuildContextualPrompt(basePrompt, nixosCtx)), cfg)
 
-			fmt.Print(utils.FormatInfo("Querying AI provider... "))
+			spinner := utils.StartSpinner("Querying AI provider...")
 			aiResp, aiErr := aiProvider.Query(contextualPrompt)
-			fmt.Println(utils.FormatSuccess("done"))
+			spinner.Stop()
 			if aiErr != nil {
 				fmt.Fprintln(os.Stderr, utils.FormatError("AI error: "+aiErr.Error()))
 				os.Exit(1)
 			}
-			fmt.Println(utils.RenderMarkdown(aiResp))
+			fmt.Println(renderAIResponse(aiResp))
+			if scenario != "" {
+				if warning := checkOptionSuggestion(option, optionType, aiResp); warning != "" {
+					fmt.Println(utils.FormatWarning(warning))
+				}
+			}
 		},
 	}
 	cmd.Flags().String("format", "markdown", "Output format: markdown, plain, or table")
 	cmd.Flags().String("provider", "", "AI provider to use for this query (ollama, openai, gemini)")
 	cmd.Flags().Bool("examples-only", false, "Show only usage examples for the option")
+	cmd.Flags().String("suggest", "", "Propose a value for the option suited to this scenario, checked against the option type")
+	cmd.Flags().Var(&responseLength, "length", "Answer length: short, normal or detailed")
+	addRawFlag(cmd)
 	return cmd
 }
 
@@ -838,14 +1249,39 @@ migration from legacy configurations, and troubleshooting.`,
   # Validate an existing flake
   nixai flake validate
 
+  # Explain why flake check fails
+  nixai flake check --explain
+
   # Migrate from legacy NixOS configuration
   nixai flake migrate --from /etc/nixos
 
   # Analyze flake for issues
-  nixai flake analyze`,
+  nixai flake analyze
+
+  # Preview input updates without writing flake.lock
+  nixai flake update --dry-run
+
+  # Show how the options your configuration sets changed between releases
+  nixai flake diff-options --from 23.11 --to 24.05
+
+  # Show the outputs of a remote flake
+  nixai flake show github:owner/repo`,
 	Run: handleFlakeCommand,
 }
 
+func init() {
+	flakeCmd.Flags().BoolVar(&flakeDryRun, "dry-run", false, "Show the input revisions update or lock would change without writing flake.lock")
+	flakeCmd.Flags().BoolVar(&flakeExplain, "explain", false, "When check or validate fails, have the AI explain what failed and how to fix it")
+	flakeCmd.Flags().StringVar(&flakeDiffFrom, "from", "", "Release diff-options compares from, e.g. 23.11 (also --since)")
+	flakeCmd.Flags().StringVar(&flakeDiffTo, "to", "", "Release diff-options compares to (default: the detected release)")
+	flakeCmd.Flags().SetNormalizeFunc(func(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "since" {
+			name = "from"
+		}
+		return pflag.NormalizedName(name)
+	})
+}
+
 // Learning system command implementation
 var learnCmd = &cobra.Command{
 	Use:   "learn",
@@ -857,6 +1293,12 @@ from beginners to advanced NixOS users. Progress is tracked and saved locally.`,
 	Example: `  # List available learning modules
   nixai learn list
 
+  # Recommend modules based on your system (flakes, Home Manager, services)
+  nixai learn path
+
+  # Review quiz questions of studied topics that are due (spaced repetition)
+  nixai learn review
+
   # Start a specific learning module
   nixai learn start basics
 
@@ -930,7 +1372,12 @@ var logsAnalyzeCmd = &cobra.Command{
 	Use:   "analyze [file]",
 	Short: "Analyze specific log file",
 	Long:  "Analyze a specific log file with AI-powered diagnostics.",
-	Run:   handleLogsAnalyze,
+	Example: `  # Analyze a log file with AI
+  nixai logs analyze /var/log/nixos-rebuild.log
+
+  # Stream findings as JSON Lines
+  journalctl -f | nixai logs analyze --format jsonl | jq .issue`,
+	Run: handleLogsAnalyze,
 }
 
 // Neovim setup command implementation
@@ -1068,7 +1515,19 @@ and generates appropriate Nix derivations with proper dependencies and build ins
   nixai package-repo https://github.com/user/repo --name my-package
 
   # Output to specific file
-  nixai package-repo https://github.com/user/repo --output ./result.nix`,
+  nixai package-repo https://github.com/user/repo --output ./result.nix
+
+  # Package a subdirectory of a monorepo
+  nixai package-repo https://github.com/user/monorepo --subdir tools/cli
+
+  # Emit the analysis and derivation as JSON for CI pipelines
+  nixai package-repo https://github.com/user/repo --json
+
+  # Fix up the generated derivation with feedback until it is saved
+  nixai package-repo --local ./my-project --interactive
+
+  # Keep the clone to inspect what was analyzed
+  nixai package-repo https://github.com/user/repo --keep-temp`,
 	Run: handlePackageRepoCommand,
 }
 
@@ -1085,7 +1544,8 @@ Examples:
   nixai mcp-server start -d     # Start the MCP server in daemon mode
   nixai mcp-server stop         # Stop the MCP server  
   nixai mcp-server status       # Check server status
-  nixai mcp-server restart      # Restart the MCP server`,
+  nixai mcp-server restart      # Restart the MCP server
+  nixai mcp-server query flakes --source https://nix.dev/manual/nix --json`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return handleMCPServerCommand(args)
 	},
@@ -1095,8 +1555,8 @@ Examples:
 // and bypasses argument validation if so
 func conditionalArgsValidator(minArgs int) cobra.PositionalArgs {
 	return func(cmd *cobra.Command, args []string) error {
-		// If TUI mode is requested, don't validate args
-		if globalTUI {
+		// If TUI mode or examples are requested, don't validate args
+		if globalTUI || showExamples {
 			return nil
 		}
 		// Otherwise, apply the minimum args validation
@@ -1108,8 +1568,8 @@ func conditionalArgsValidator(minArgs int) cobra.PositionalArgs {
 // and bypasses exact argument validation if so
 func conditionalExactArgsValidator(exactArgs int) cobra.PositionalArgs {
 	return func(cmd *cobra.Command, args []string) error {
-		// If TUI mode is requested, don't validate args
-		if globalTUI {
+		// If TUI mode or examples are requested, don't validate args
+		if globalTUI || showExamples {
 			return nil
 		}
 		// Otherwise, apply the exact args validation
@@ -1121,8 +1581,8 @@ func conditionalExactArgsValidator(exactArgs int) cobra.PositionalArgs {
 // and bypasses range argument validation if so
 func conditionalRangeArgsValidator(min, max int) cobra.PositionalArgs {
 	return func(cmd *cobra.Command, args []string) error {
-		// If TUI mode is requested, don't validate args
-		if globalTUI {
+		// If TUI mode or examples are requested, don't validate args
+		if globalTUI || showExamples {
 			return nil
 		}
 		// Otherwise, apply the range args validation
@@ -1134,8 +1594,8 @@ func conditionalRangeArgsValidator(min, max int) cobra.PositionalArgs {
 // and bypasses maximum argument validation if so
 func conditionalMaximumArgsValidator(maxArgs int) cobra.PositionalArgs {
 	return func(cmd *cobra.Command, args []string) error {
-		// If TUI mode is requested, don't validate args
-		if globalTUI {
+		// If TUI mode or examples are requested, don't validate args
+		if globalTUI || showExamples {
 			return nil
 		}
 		// Otherwise, apply the maximum args validation
@@ -1160,6 +1620,15 @@ Output modes:
 - --quiet: Show only the AI response without any validation output
 - --verbose: Show detailed validation output with multi-section layout
 - --stream: Stream the response in real-time (great for LlamaCpp with Vulkan support)
+- --offline: Skip the GitHub example search
+- --continue: Follow up on the previous question, sending its answer as context
+- --length short|normal|detailed: Ask for a terse answer or a full walkthrough
+- --raw: Print the answer's markdown exactly as the AI returned it, without rendering
+- --min-quality N: Search more sources before answering when fewer than N sources have results (in the concise and quiet layouts only a note reports the outcome)
+- --fresh: Ignore answers cached in the last 24 hours, marked "(cached 2h ago)", and ask again
+- --explain-why-cached: Explain why an answer came from the cache
+- --cache-stats: Show cache hits, misses and size
+- --ensemble P1,P2: Ask several providers at once; --provider then reconciles their answers and lists where they disagree
 
 Examples:
   nixai ask "How do I configure nginx?"
@@ -1167,12 +1636,63 @@ Examples:
   nixai ask "How do I set up a development environment with Python?" --provider gemini
   nixai ask "How do I enable SSH?" --quiet
   nixai ask "How do I enable nginx?" --verbose
-  nixai ask "Help me troubleshoot my build" --stream`,
-	Args: conditionalArgsValidator(1), Run: func(cmd *cobra.Command, args []string) {
+  nixai ask "How do I enable nginx?" --min-quality 3
+  nixai ask "How do I enable nginx?" --raw > answer.md
+  nixai ask --continue "and for a flake?"
+  nixai ask "Help me troubleshoot my build" --stream
+  nixai ask "How do I enable nginx?" --fresh
+  nixai ask "How do I enable nginx?" --json
+  nixai ask "How do I migrate to systemd-boot?" --ensemble ollama,openai
+  nixai ask --cache-stats`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if askCacheStats {
+			return nil
+		}
+		return conditionalArgsValidator(1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if askCacheStats {
+			showAskCacheStats(cmd.OutOrStdout())
+			return
+		}
+		if err := requireAI("ask"); err != nil {
+			fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+			os.Exit(1)
+		}
 		// Get the quiet, verbose, and stream flag values
 		quiet, _ := cmd.Flags().GetBool("quiet")
 		verbose, _ := cmd.Flags().GetBool("verbose")
 		stream, _ := cmd.Flags().GetBool("stream")
+		if err := validateAskMinQuality(askMinQuality); err != nil {
+			fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+			os.Exit(1)
+		}
+		if len(askEnsemble) > 0 {
+			members, err := normalizeAskEnsemble(askEnsemble)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+				os.Exit(1)
+			}
+			askEnsemble = members
+			if stream {
+				fmt.Fprintln(os.Stderr, utils.FormatError("--ensemble cannot be combined with --stream"))
+				os.Exit(1)
+			}
+		}
+
+		// Plain and JSON answers skip the validation layout of the other modes
+		cfg, _ := config.LoadUserConfig()
+		format, err := resolveOutputFormat(cmd.Flags(), cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+			os.Exit(1)
+		}
+		askOutputFormat = format
+		if format == outputJSON || (format == outputPlain && !stream) {
+			quiet = true
+			stream = false
+		}
+		utils.DisableSpinners(quiet)
 
 		// Get current provider and model flag values - check both command and persistent flags
 		currentProvider, _ := cmd.Root().PersistentFlags().GetString("provider")
@@ -1231,12 +1751,24 @@ Available subcommands:
   set <key> <value>       - Set a configuration value
   get <key>               - Get a configuration value
   reset                   - Reset to default configuration
+  history                 - List backups of previous configurations
+  restore <timestamp>     - Restore a previous configuration
+  export                  - Print the configuration as YAML to share with teammates
+  import <file>           - Merge an exported configuration into yours
+
+Every change keeps the previous configuration as a backup (the last 10 are kept).
+Exports redact API keys, credential headers and proxy credentials unless
+--include-secrets is given; importing a redacted value keeps your own.
 
 Examples:
   nixai config show
   nixai config set ai_provider ollama
   nixai config set ai_model llama3
-  nixai config get ai_provider`,
+  nixai config get ai_provider
+  nixai config history
+  nixai config restore 20250614-093012
+  nixai config export > nixai-config.yaml
+  nixai config import nixai-config.yaml`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) == 0 {
 			_ = cmd.Help()
@@ -1260,6 +1792,34 @@ Examples:
 			getConfig(args[1])
 		case "reset":
 			resetConfig()
+		case "history":
+			if err := showConfigHistory(os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+				os.Exit(1)
+			}
+		case "restore":
+			if len(args) < 2 {
+				fmt.Println(utils.FormatError("Usage: nixai config restore <timestamp>"))
+				os.Exit(1)
+			}
+			if err := restoreConfig(os.Stdout, args[1]); err != nil {
+				fmt.Fprintln(os.Stderr, utils.FormatError("Failed to restore config: "+err.Error()))
+				os.Exit(1)
+			}
+		case "export":
+			if err := exportConfig(cmd.OutOrStdout(), configIncludeSecrets); err != nil {
+				fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+				os.Exit(1)
+			}
+		case "import":
+			if len(args) < 2 {
+				fmt.Println(utils.FormatError("Usage: nixai config import <file>"))
+				os.Exit(1)
+			}
+			if err := importConfig(cmd.OutOrStdout(), args[1]); err != nil {
+				fmt.Fprintln(os.Stderr, utils.FormatError("Failed to import config: "+err.Error()))
+				os.Exit(1)
+			}
 		default:
 			fmt.Println(utils.FormatError("Unknown config command: " + args[0]))
 			_ = cmd.Help()
@@ -1267,6 +1827,11 @@ Examples:
 		}
 	},
 }
+
+func init() {
+	configCmd.Flags().BoolVar(&configIncludeSecrets, "include-secrets", false, "With export, keep API keys, credential headers and proxy credentials instead of redacting them")
+}
+
 var configureCmd = &cobra.Command{
 	Use:   "configure",
 	Short: "Configure NixOS interactively",
@@ -1278,8 +1843,18 @@ Examples:
   nixai configure --output my-config.nix
   nixai configure --advanced --home --output home-config.nix
   nixai configure --search "desktop" --advanced --output desktop-config.nix
+  nixai configure --search "desktop" --output configuration.nix --validate
+  nixai configure --output new.nix --since-generation=/etc/nixos/configuration.nix
+  nixai configure explain
+
+With --validate or --since-generation, the generated configuration is compared with the
+currently active one (or the given file) and options that would be lost are listed before saving.
 `,
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := requireAI("configure"); err != nil {
+			fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+			os.Exit(1)
+		}
 		fmt.Println(utils.FormatHeader("🛠️  Interactive NixOS Configuration"))
 		fmt.Println()
 
@@ -1288,6 +1863,23 @@ Examples:
 		outputFile, _ := cmd.Flags().GetString("output")
 		isAdvanced, _ := cmd.Flags().GetBool("advanced")
 		isHome, _ := cmd.Flags().GetBool("home")
+		validate, _ := cmd.Flags().GetBool("validate")
+		sinceGeneration, _ := cmd.Flags().GetString("since-generation")
+		if validate && sinceGeneration == "" {
+			sinceGeneration = "current"
+		}
+		profile, _ := cmd.Flags().GetString("profile")
+		openFile, _ := cmd.Flags().GetBool("open")
+		if openFile && outputFile == "" {
+			fmt.Fprintln(os.Stderr, utils.FormatError("--open needs --output"))
+			os.Exit(1)
+		}
+		if profile != "" {
+			if err := nixos.ValidateProfileName(profile); err != nil {
+				fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+				os.Exit(1)
+			}
+		}
 
 		cfg, err := config.LoadUserConfig()
 		if err != nil {
@@ -1351,23 +1943,55 @@ Examples:
 			prompt = contextualPrompt
 		}
 
-		fmt.Print(utils.FormatInfo("Querying AI provider... "))
+		spinner := utils.StartSpinner("Querying AI provider...")
 		resp, err := aiProvider.Query(prompt)
-		fmt.Println(utils.FormatSuccess("done"))
+		spinner.Stop()
 		if err != nil {
 			fmt.Fprintln(os.Stderr, utils.FormatError("AI error: "+err.Error()))
 			os.Exit(1)
 		}
 
+		// Compare with the active configuration so settings are not lost silently
+		var dropped []string
+		if sinceGeneration != "" {
+			previousPath := activeConfigurationPath(sinceGeneration, nixosCtx)
+			dropped, err = reportDroppedOptions(os.Stdout, previousPath, extractNixConfiguration(resp))
+			if err != nil {
+				fmt.Println(utils.FormatWarning("Could not compare with " + previousPath + ": " + err.Error()))
+			}
+			fmt.Println()
+		}
+
 		// Display or save the output
 		if outputFile != "" {
-			err := saveConfigurationToFile(resp, outputFile)
+			if len(dropped) > 0 {
+				fmt.Print(utils.FormatWarning("Save anyway? (y/N): "))
+				var response string
+				_, _ = fmt.Scanln(&response)
+				if response != "y" && response != "Y" {
+					fmt.Println(utils.FormatInfo("Configuration not saved"))
+					fmt.Println(utils.RenderMarkdown(resp))
+					return
+				}
+			}
+			savedPath, err := saveConfigurationToFile(resp, outputFile, writeConfirm(cmd))
+			if errors.Is(err, utils.ErrOverwriteDeclined) {
+				fmt.Println(utils.FormatInfo("Configuration not saved"))
+				fmt.Println(utils.RenderMarkdown(resp))
+				return
+			}
 			if err != nil {
 				fmt.Fprintln(os.Stderr, utils.FormatError("Failed to save to file: "+err.Error()))
 				os.Exit(1)
 			}
-			fmt.Println(utils.FormatSuccess("✅ Configuration saved to: " + outputFile))
+			fmt.Println(utils.FormatSuccess("✅ Configuration saved to: " + savedPath))
 			fmt.Println(utils.FormatTip("Review the generated configuration and customize as needed"))
+			if !isHome {
+				fmt.Println(utils.FormatTip("Once merged into your configuration, apply it with: " + nixos.RebuildCommandLineWithOptions(nixosCtx, "switch", nixos.RebuildOptions{Profile: profile})))
+			}
+			if openFile {
+				openInEditor(os.Stdout, savedPath)
+			}
 		} else {
 			fmt.Println(utils.RenderMarkdown(resp))
 		}
@@ -1428,8 +2052,22 @@ func buildConfigurePrompt(input string, isHome bool, isAdvanced bool) string {
 	return prompt.String()
 }
 
-// saveConfigurationToFile saves the generated configuration to a file
-func saveConfigurationToFile(content, filename string) error {
+// saveConfigurationToFile saves the generated configuration to a file and returns its path,
+// which gets a .nix extension when filename has none. An existing file is only overwritten
+// as confirm allows.
+func saveConfigurationToFile(content, filename string, confirm utils.WriteConfirm) (string, error) {
+	finalContent := extractNixConfiguration(content)
+
+	// Ensure the file has a .nix extension
+	if !strings.HasSuffix(filename, ".nix") {
+		filename += ".nix"
+	}
+
+	return filename, utils.WriteFileWithConfirm(filename, []byte(finalContent), 0644, confirm)
+}
+
+// extractNixConfiguration extracts the Nix configuration from an AI response
+func extractNixConfiguration(content string) string {
 	// Clean the content to extract just the configuration
 	lines := strings.Split(content, "\n")
 	var configLines []string
@@ -1456,14 +2094,7 @@ func saveConfigurationToFile(content, filename string) error {
 		configLines = lines
 	}
 
-	finalContent := strings.Join(configLines, "\n")
-
-	// Ensure the file has a .nix extension
-	if !strings.HasSuffix(filename, ".nix") {
-		filename += ".nix"
-	}
-
-	return os.WriteFile(filename, []byte(finalContent), 0644)
+	return strings.Join(configLines, "\n")
 }
 
 func init() {
@@ -1472,6 +2103,13 @@ func init() {
 	configureCmd.Flags().StringP("output", "o", "", "Output file path for generated configuration (will add .nix extension)")
 	configureCmd.Flags().Bool("advanced", false, "Generate advanced configuration with detailed options and optimizations")
 	configureCmd.Flags().Bool("home", false, "Generate Home Manager configuration instead of NixOS system configuration")
+	configureCmd.Flags().Bool("validate", false, "Warn about options of the active configuration missing from the generated one before saving")
+	configureCmd.Flags().String("since-generation", "", "Diff the generated configuration against this file ('current' for the active configuration)")
+	configureCmd.Flags().Lookup("since-generation").NoOptDefVal = "current"
+	configureCmd.Flags().String("profile", "", "System profile used in the suggested nixos-rebuild command (--profile-name)")
+	configureCmd.Flags().Bool("open", false, "Open the saved configuration in $EDITOR (or $VISUAL) when running in a terminal")
+	addYesFlag(configureCmd)
+	configureCmd.AddCommand(newConfigureExplainCmd())
 }
 
 var diagnoseCmd = &cobra.Command{
@@ -1482,44 +2120,122 @@ var diagnoseCmd = &cobra.Command{
 Examples:
   nixai diagnose /var/log/messages
   journalctl -xe | nixai diagnose
+  nixos-rebuild switch 2>&1 | nixai diagnose --build
   nixai diagnose --file /var/log/nixos-rebuild.log
   nixai diagnose --type system
+  nixai diagnose --services
   nixai diagnose --context "build failed with dependency error"
+  journalctl -b -p err | nixai diagnose --summary
+  nixai diagnose /var/log/messages --raw
+  nixai diagnose --regression
+  journalctl -b -1 -p err | nixai diagnose --regression
+  nixai diagnose --watch /etc/nixos/configuration.nix
 `,
 	Args: conditionalMaximumArgsValidator(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println(utils.FormatHeader("🩺 NixOS Diagnostics"))
-		fmt.Println()
+		// Load configuration first
+		cfg, err := config.LoadUserConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, utils.FormatError("Failed to load config: "+err.Error()))
+			os.Exit(1)
+		}
 
 		// Parse command flags
 		inputFile, _ := cmd.Flags().GetString("file")
 		diagType, _ := cmd.Flags().GetString("type")
-		outputFormat, _ := cmd.Flags().GetString("output")
 		additionalContext, _ := cmd.Flags().GetString("context")
-
-		// Load configuration first
-		cfg, err := config.LoadUserConfig()
+		buildOutput, _ := cmd.Flags().GetBool("build")
+		servicesDiagnosis, _ := cmd.Flags().GetBool("services")
+		regression, _ := cmd.Flags().GetBool("regression")
+		watchFile, _ := cmd.Flags().GetString("watch")
+		outputFormat, err := resolveOutputFormat(cmd.Flags(), cfg)
 		if err != nil {
-			fmt.Fprintln(os.Stderr, utils.FormatError("Failed to load config: "+err.Error()))
+			fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
 			os.Exit(1)
 		}
+		if watchFile != "" {
+			if outputFormat, err = validateDiagnoseWatch(cmd, args, outputFormat); err != nil {
+				fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+				os.Exit(1)
+			}
+			inputFile = watchFile
+		}
+		summary, _ := cmd.Flags().GetBool("summary")
+		if summary {
+			if outputFormat, err = textOutputFormat(cmd.Flags(), outputFormat, "--summary"); err != nil {
+				fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+				os.Exit(1)
+			}
+		}
+		if summary && regression {
+			fmt.Fprintln(os.Stderr, utils.FormatError("--summary cannot be combined with --regression"))
+			os.Exit(1)
+		}
+		// Keep JSON output free of progress animation and decoration
+		utils.DisableSpinners(outputFormat == outputJSON)
+		if outputFormat != outputJSON && !summary {
+			fmt.Println(utils.FormatHeader("🩺 NixOS Diagnostics"))
+			fmt.Println()
+		}
 
 		// Initialize context detector and get NixOS context
-		contextDetector := nixos.NewContextDetector(logger.NewLogger())
+		contextLog := logger.NewLogger()
+		if summary {
+			contextLog = logger.NewLoggerWithWriter(io.Discard)
+		}
+		contextDetector := nixos.NewContextDetector(contextLog)
 		nixosCtx, err := contextDetector.GetContext(cfg)
 		if err != nil {
-			fmt.Println(utils.FormatWarning("Context detection failed: " + err.Error()))
+			if !summary {
+				fmt.Println(utils.FormatWarning("Context detection failed: " + err.Error()))
+			}
 			nixosCtx = nil
 		}
 
 		// Display detected context summary if available
-		if nixosCtx != nil && nixosCtx.CacheValid {
+		if nixosCtx != nil && nixosCtx.CacheValid && outputFormat != outputJSON && !summary {
 			contextBuilder := nixoscontext.NewNixOSContextBuilder()
 			contextSummary := contextBuilder.GetContextSummary(nixosCtx)
 			fmt.Println(utils.FormatNote("📋 " + contextSummary))
 			fmt.Println()
 		}
 
+		// Without a log to read, services diagnostics analyze the failed units themselves
+		if servicesDiagnosis || diagType == "services" {
+			stat, _ := os.Stdin.Stat()
+			if inputFile == "" && len(args) == 0 && (stat.Mode()&os.ModeCharDevice) != 0 {
+				if summary {
+					units, err := listFailedUnits(runDoctorCheckCommand)
+					if err != nil {
+						fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+						os.Exit(1)
+					}
+					line, code := failedServicesSummary(units)
+					fmt.Println(line)
+					os.Exit(code)
+				}
+				// Without AI the failed units are listed without an analysis
+				var query func(string) (string, error)
+				aiProvider, err := optionalAIProvider(cfg, logger.NewLogger())
+				switch {
+				case err == nil:
+					contextBuilder := nixoscontext.NewNixOSContextBuilder()
+					query = func(prompt string) (string, error) {
+						return aiProvider.Query(withLanguageInstruction(withLengthInstruction(contextBuilder.BuildContextualPrompt(prompt, nixosCtx)), cfg))
+					}
+				case !errors.Is(err, errAIDisabled):
+					fmt.Fprintln(os.Stderr, utils.FormatError("Failed to initialize AI provider: "+err.Error()))
+					os.Exit(1)
+				}
+				if err := diagnoseFailedServices(os.Stdout, outputFormat, runDoctorCheckCommand, query, additionalContext); err != nil {
+					fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+					os.Exit(1)
+				}
+				return
+			}
+			diagType = "services"
+		}
+
 		var logData string
 
 		// Determine input source based on flags and arguments
@@ -1548,10 +2264,14 @@ Examples:
 				logData = string(input)
 			} else {
 				// No input provided, offer diagnostic options based on type flag
+				if summary {
+					fmt.Fprintln(os.Stderr, utils.FormatError("--summary needs a log file, piped input or --services"))
+					os.Exit(1)
+				}
 				if diagType != "" {
 					fmt.Printf("Running %s diagnostics...\n", diagType)
 					logData = fmt.Sprintf("Perform %s diagnostics for NixOS system", diagType)
-				} else {
+				} else if !regression {
 					fmt.Println(utils.FormatWarning("No log file, piped input, or diagnostic type provided."))
 					fmt.Println(utils.FormatTip("Usage: nixai diagnose [logfile] or nixai diagnose --type system"))
 					return
@@ -1559,46 +2279,122 @@ Examples:
 			}
 		}
 
-		aiProvider, err := GetLegacyAIProvider(cfg, logger.NewLogger())
-		if err != nil {
+		// The summary comes from the known error patterns, without waiting for an AI diagnosis
+		if summary {
+			line, code := diagnoseSummary(nixos.Diagnose(logData, "", nil))
+			fmt.Println(line)
+			os.Exit(code)
+		}
+
+		// Without AI the log is checked against the known error patterns only
+		aiProvider, err := optionalAIProvider(cfg, logger.NewLogger())
+		if err != nil && !errors.Is(err, errAIDisabled) {
 			fmt.Fprintln(os.Stderr, utils.FormatError("Failed to initialize AI provider: "+err.Error()))
 			os.Exit(1)
 		}
 
-		// Build context-aware prompt using the context builder
-		basePrompt := "You are a NixOS expert. Analyze the following log or error output and provide a diagnosis, root cause, and step-by-step fix instructions.\n\n"
-
-		if diagType != "" {
-			basePrompt += fmt.Sprintf("Focus on %s-related issues. ", diagType)
+		// With --regression, compare the failing generation with the last good one
+		var regressionDiff *RegressionDiff
+		if regression {
+			generations, err := listGenerations()
+			if err == nil {
+				regressionDiff, err = collectRegressionDiff(generations, runDoctorCheckCommand, filepath.EvalSymlinks, readGenerationState)
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+				os.Exit(1)
+			}
+			if outputFormat != outputJSON {
+				printRegressionDiff(os.Stdout, regressionDiff)
+			}
 		}
 
-		if additionalContext != "" {
-			basePrompt += fmt.Sprintf("Additional context: %s\n\n", additionalContext)
-		}
+		// diagnoseLog asks the AI about logData and prints the diagnosis
+		diagnoseLog := func(logData string) error {
+			if aiProvider == nil {
+				diags := nixos.Diagnose(logData, "", nil)
+				if outputFormat == outputJSON {
+					return writeJSONOutput(os.Stdout, struct {
+						Diagnoses  []nixos.Diagnostic `json:"diagnoses"`
+						Regression *RegressionDiff    `json:"regression,omitempty"`
+					}{diags, regressionDiff})
+				}
+				writePatternDiagnoses(os.Stdout, diags)
+				return nil
+			}
+			// Build context-aware prompt using the context builder
+			var basePrompt string
+			if regressionDiff != nil {
+				basePrompt = regressionPrompt(regressionDiff, logData, additionalContext)
+			} else if isBuildDiagnosis(buildOutput, logData) {
+				// The detected build failures are progress output, kept out of JSON results
+				progress := io.Writer(os.Stdout)
+				if outputFormat == outputJSON {
+					progress = os.Stderr
+				}
+				basePrompt = buildDiagnosisPrompt(progress, logData, additionalContext)
+			} else {
+				basePrompt = "You are a NixOS expert. Analyze the following log or error output and provide a diagnosis, root cause, and step-by-step fix instructions.\n\n"
 
-		basePrompt += "Log or error:\n" + logData
+				if diagType != "" {
+					basePrompt += fmt.Sprintf("Focus on %s-related issues. ", diagType)
+				}
 
-		contextBuilder := nixoscontext.NewNixOSContextBuilder()
-		contextualPrompt := contextBuilder.BuildContextualPrompt(basePrompt, nixosCtx)
+				if additionalContext != "" {
+					basePrompt += fmt.Sprintf("Additional context: %s\n\n", additionalContext)
+				}
 
-		fmt.Print(utils.FormatInfo("Querying AI provider... "))
-		resp, err := aiProvider.Query(contextualPrompt)
-		fmt.Println(utils.FormatSuccess("done"))
-		if err != nil {
-			fmt.Fprintln(os.Stderr, utils.FormatError("AI error: "+err.Error()))
-			os.Exit(1)
+				basePrompt += "Log or error:\n" + logData
+			}
+
+			contextBuilder := nixoscontext.NewNixOSContextBuilder()
+			contextualPrompt := withLanguageInstruction(withLengthInstruction(contextBuilder.BuildContextualPrompt(basePrompt, nixosCtx)), cfg)
+
+			spinner := utils.StartSpinner("Querying AI provider...")
+			resp, err := aiProvider.Query(contextualPrompt)
+			spinner.Stop()
+			if err != nil {
+				return fmt.Errorf("AI error: %w", err)
+			}
+
+			// Format output based on the output format
+			if outputFormat == outputJSON {
+				return writeJSONOutput(os.Stdout, struct {
+					Diagnosis  string          `json:"diagnosis"`
+					Regression *RegressionDiff `json:"regression,omitempty"`
+				}{resp, regressionDiff})
+			}
+			fmt.Println(renderAnswer(outputFormat, resp))
+			return nil
 		}
 
-		// Format output based on output format flag
-		switch outputFormat {
-		case "plain":
-			fmt.Println(resp)
-		case "json":
-			// Simple JSON wrapper
-			fmt.Printf(`{"diagnosis": %q}`, resp)
+		if err := diagnoseLog(logData); err != nil {
+			fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+			if watchFile == "" {
+				os.Exit(1)
+			}
+		}
+		if watchFile == "" {
+			return
+		}
+
+		// With --watch, diagnose again each time the file is saved; a failed run is reported
+		// and the watch goes on
+		rerun := func() {
+			data, err := os.ReadFile(watchFile)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, utils.FormatError("Failed to read file: "+err.Error()))
+				return
+			}
 			fmt.Println()
-		default: // markdown
-			fmt.Println(utils.RenderMarkdown(resp))
+			fmt.Println(utils.FormatSubsection(fmt.Sprintf("🔄 %s changed at %s", watchFile, time.Now().Format("15:04:05")), ""))
+			if err := diagnoseLog(string(data)); err != nil {
+				fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+			}
+		}
+		if err := watchDiagnose(os.Stdout, watchFile, rerun); err != nil {
+			fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+			os.Exit(1)
 		}
 	},
 }
@@ -1607,8 +2403,15 @@ func init() {
 	// Add flags to diagnose command
 	diagnoseCmd.Flags().StringP("file", "f", "", "Specify log file path to analyze")
 	diagnoseCmd.Flags().StringP("type", "t", "", "Diagnostic type (system, config, services, network, hardware, performance)")
-	diagnoseCmd.Flags().StringP("output", "o", "markdown", "Output format (markdown, plain, json)")
+	addOutputFormatFlags(diagnoseCmd)
+	addRawFlag(diagnoseCmd)
 	diagnoseCmd.Flags().StringP("context", "c", "", "Additional context information to include in analysis")
+	diagnoseCmd.Flags().Var(&responseLength, "length", "Answer length: short, normal or detailed")
+	diagnoseCmd.Flags().Bool("services", false, "Analyze every failed systemd unit from its journal (same as --type services)")
+	diagnoseCmd.Flags().Bool("build", false, "Treat the input as nixos-rebuild output (detected automatically from build markers)")
+	diagnoseCmd.Flags().Bool("regression", false, "Compare the failing system generation with the last good one to find the change that broke it")
+	diagnoseCmd.Flags().Bool("summary", false, "Print only a one-sentence verdict; exits 1 on minor and 2 on serious problems")
+	diagnoseCmd.Flags().String("watch", "", "Diagnose the given log or config file and re-run each time it changes, until Ctrl-C")
 }
 
 var doctorCmd = &cobra.Command{
@@ -1631,6 +2434,12 @@ Examples:
   nixai doctor system        # Run only system checks
   nixai doctor packages      # Check package integrity
   nixai doctor --verbose     # Detailed output
+  nixai doctor --fix         # Review and run suggested commands one by one
+  nixai doctor --no-ai       # Health checks only, without AI analysis
+  nixai doctor --only network,storage   # Run a subset of the categories
+  nixai doctor --skip security          # Run all categories except security
+  nixai doctor --json                   # Print the results as JSON
+  nixai doctor --summary                # One line for scripts, e.g. HEALTH: warnings (2 warn, 0 fail)
 `,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
@@ -1640,9 +2449,6 @@ Examples:
 
 // runDoctorCommand executes the comprehensive doctor health checks
 func runDoctorCommand(cmd *cobra.Command, args []string) {
-	fmt.Println(utils.FormatHeader("🩻 NixOS Doctor: Comprehensive Health Check"))
-	fmt.Println()
-
 	// Load configuration first
 	cfg, err := config.LoadUserConfig()
 	if err != nil {
@@ -1650,11 +2456,46 @@ func runDoctorCommand(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	outputFormat, err := resolveOutputFormat(cmd.Flags(), cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+		os.Exit(1)
+	}
+	fix, _ := cmd.Flags().GetBool("fix")
+	if fix {
+		if outputFormat, err = textOutputFormat(cmd.Flags(), outputFormat, "--fix"); err != nil {
+			fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+			os.Exit(1)
+		}
+	}
+	summary, _ := cmd.Flags().GetBool("summary")
+	if summary && fix {
+		fmt.Fprintln(os.Stderr, utils.FormatError("--summary cannot be combined with --fix"))
+		os.Exit(1)
+	}
+	if summary {
+		if outputFormat, err = textOutputFormat(cmd.Flags(), outputFormat, "--summary"); err != nil {
+			fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+			os.Exit(1)
+		}
+	}
+	// Progress goes to stderr so that JSON results can be piped
+	status := io.Writer(os.Stdout)
+	if outputFormat == outputJSON {
+		status = os.Stderr
+	}
+	if summary {
+		status = io.Discard
+	}
+
+	_, _ = fmt.Fprintln(status, utils.FormatHeader("🩻 NixOS Doctor: Comprehensive Health Check"))
+	_, _ = fmt.Fprintln(status)
+
 	// Initialize context detector and get NixOS context
-	contextDetector := nixos.NewContextDetector(logger.NewLogger())
+	contextDetector := nixos.NewContextDetector(logger.NewLoggerWithWriter(status))
 	nixosCtx, err := contextDetector.GetContext(cfg)
 	if err != nil {
-		fmt.Println(utils.FormatWarning("Context detection failed: " + err.Error()))
+		_, _ = fmt.Fprintln(status, utils.FormatWarning("Context detection failed: "+err.Error()))
 		nixosCtx = nil
 	}
 
@@ -1662,109 +2503,153 @@ func runDoctorCommand(cmd *cobra.Command, args []string) {
 	if nixosCtx != nil && nixosCtx.CacheValid {
 		contextBuilder := nixoscontext.NewNixOSContextBuilder()
 		contextSummary := contextBuilder.GetContextSummary(nixosCtx)
-		fmt.Println(utils.FormatNote("📋 " + contextSummary))
-		fmt.Println()
+		_, _ = fmt.Fprintln(status, utils.FormatNote("📋 "+contextSummary))
+		_, _ = fmt.Fprintln(status)
 	}
 
-	// Determine check type
+	// Determine the check categories
 	checkType := "all"
 	if len(args) > 0 {
 		checkType = args[0]
 	}
+	only, _ := cmd.Flags().GetStringSlice("only")
+	skip, _ := cmd.Flags().GetStringSlice("skip")
+	checkTypes, err := selectCheckTypes(checkType, only, skip)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+		os.Exit(1)
+	}
 
 	// Get verbose flag
 	verbose, _ := cmd.Flags().GetBool("verbose")
 
-	fmt.Println(utils.FormatInfo("🔍 Performing health checks..."))
-	fmt.Println()
+	_, _ = fmt.Fprintln(status, utils.FormatInfo("🔍 Performing health checks..."))
+	_, _ = fmt.Fprintln(status)
 
 	// Show what checks are being performed
-	showChecksBeingPerformed(checkType, verbose)
+	showChecksBeingPerformed(status, checkTypes, verbose)
 
-	// Initialize AI provider for analysis
-	aiProvider, err := GetLegacyAIProvider(cfg, logger.NewLogger())
-	if err != nil {
+	// The summary is derived from the checks alone, without waiting for an AI analysis
+	if summary {
+		line, code := doctorSummary(performHealthChecks(status, checkTypes, cfg, verbose))
+		fmt.Println(line)
+		os.Exit(code)
+	}
+
+	// Initialize AI provider for analysis unless --no-ai is set
+	aiProvider, err := optionalAIProvider(cfg, logger.NewLogger())
+	if err != nil && !errors.Is(err, errAIDisabled) {
 		fmt.Fprintln(os.Stderr, utils.FormatError("Failed to initialize AI provider: "+err.Error()))
 		os.Exit(1)
 	}
 
 	// Perform actual health checks
-	healthResults := performHealthChecks(checkType, cfg, verbose)
+	healthResults := performHealthChecks(status, checkTypes, cfg, verbose)
 
-	// Display results
-	displayHealthResults(healthResults, verbose)
+	// Show the check results right away rather than after the AI analysis
+	if outputFormat != outputJSON {
+		fmt.Println()
+		displayHealthResults(os.Stdout, healthResults, verbose)
+	}
 
 	// Get AI analysis if provider is available
+	var analysis string
+	var analysisErr error
 	if aiProvider != nil {
-		fmt.Println()
-		fmt.Println(utils.FormatHeader("🤖 AI-Powered Analysis"))
-		fmt.Print(utils.FormatInfo("Analyzing results with AI... "))
+		_, _ = fmt.Fprintln(status)
+		_, _ = fmt.Fprint(status, utils.FormatInfo("Analyzing results with AI... "))
 
 		// Build context-aware prompt using the context builder
 		baseAnalysisPrompt := buildAnalysisPrompt(healthResults, checkType)
 		contextBuilder := nixoscontext.NewNixOSContextBuilder()
 		contextualPrompt := contextBuilder.BuildContextualPrompt(baseAnalysisPrompt, nixosCtx)
 
-		analysis, err := aiProvider.Query(contextualPrompt)
+		analysis, analysisErr = aiProvider.Query(contextualPrompt)
 
-		fmt.Println(utils.FormatSuccess("done"))
-		if err != nil {
-			fmt.Println(utils.FormatWarning("AI analysis unavailable: " + err.Error()))
+		_, _ = fmt.Fprintln(status, utils.FormatSuccess("done"))
+	}
+
+	if outputFormat == outputJSON {
+		report := doctorReport{Categories: checkTypes, Results: healthResults}
+		if analysisErr == nil {
+			report.Analysis = analysis
+		}
+		_ = writeJSONOutput(os.Stdout, report)
+		return
+	}
+
+	// Page only the AI analysis, the check results are already shown
+	if aiProvider != nil {
+		var report bytes.Buffer
+		_, _ = fmt.Fprintln(&report, utils.FormatHeader("🤖 AI-Powered Analysis"))
+		if analysisErr != nil {
+			_, _ = fmt.Fprintln(&report, utils.FormatWarning("AI analysis unavailable: "+analysisErr.Error()))
 		} else {
-			fmt.Println()
-			fmt.Println(utils.RenderMarkdown(analysis))
+			_, _ = fmt.Fprintln(&report)
+			_, _ = fmt.Fprintln(&report, renderAnswer(outputFormat, analysis))
 		}
+		fmt.Println()
+		writePaged(os.Stdout, report.String())
+	}
+
+	if fix {
+		fmt.Println()
+		offerFixes(os.Stdout, healthResults)
 	}
 }
 
-// showChecksBeingPerformed displays what checks are being performed
-func showChecksBeingPerformed(checkType string, verbose bool) {
-	checkTypes := getCheckTypes(checkType)
+// doctorReport is the doctor result in the JSON output format
+type doctorReport struct {
+	Categories []string            `json:"categories"`
+	Results    []HealthCheckResult `json:"results"`
+	Analysis   string              `json:"analysis,omitempty"`
+}
 
-	fmt.Println(utils.FormatSubsection("Health Check Categories", ""))
+// showChecksBeingPerformed displays what checks are being performed
+func showChecksBeingPerformed(out io.Writer, checkTypes []string, verbose bool) {
+	_, _ = fmt.Fprintln(out, utils.FormatSubsection("Health Check Categories", ""))
 	for _, ct := range checkTypes {
 		switch ct {
 		case "system":
-			fmt.Println("  🖥️  System Health - Core system components and boot status")
+			_, _ = fmt.Fprintln(out, "  🖥️  System Health - Core system components and boot status")
 		case "nixos":
-			fmt.Println("  🐧 NixOS Configuration - Config syntax and rebuild status")
+			_, _ = fmt.Fprintln(out, "  🐧 NixOS Configuration - Config syntax and rebuild status")
 		case "packages":
-			fmt.Println("  📦 Package Integrity - Nix store and package health")
+			_, _ = fmt.Fprintln(out, "  📦 Package Integrity - Nix store and package health")
 		case "services":
-			fmt.Println("  🔧 System Services - Service status and failed units")
+			_, _ = fmt.Fprintln(out, "  🔧 System Services - Service status and failed units")
 		case "storage":
-			fmt.Println("  💾 Storage Health - Filesystem and disk usage")
+			_, _ = fmt.Fprintln(out, "  💾 Storage Health - Filesystem and disk usage")
 		case "network":
-			fmt.Println("  🌐 Network Status - Connectivity and DNS resolution")
+			_, _ = fmt.Fprintln(out, "  🌐 Network Status - Connectivity and DNS resolution")
 		case "security":
-			fmt.Println("  🔒 Security Audit - Permissions and security settings")
+			_, _ = fmt.Fprintln(out, "  🔒 Security Audit - Permissions and security settings")
 		}
 	}
-	fmt.Println()
+	_, _ = fmt.Fprintln(out)
 }
 
 // getCheckTypes returns the list of check types to perform
 func getCheckTypes(checkType string) []string {
 	if checkType == "all" {
-		return []string{"system", "nixos", "packages", "services", "storage", "network", "security"}
+		return append([]string(nil), doctorCategories...)
 	}
 	return []string{checkType}
 }
 
 // HealthCheckResult represents the result of a health check
 type HealthCheckResult struct {
-	Category    string
-	Name        string
-	Status      string // "pass", "warn", "fail", "info"
-	Description string
-	Details     string
-	Command     string // Optional command suggestion
+	Category    string `json:"category"`
+	Name        string `json:"name"`
+	Status      string `json:"status"` // "pass", "warn", "fail", "info"
+	Description string `json:"description"`
+	Details     string `json:"details,omitempty"`
+	Command     string `json:"command,omitempty"` // Optional command suggestion
 }
 
 // performHealthChecks executes the actual health checks
-func performHealthChecks(checkType string, cfg *config.UserConfig, verbose bool) []HealthCheckResult {
+func performHealthChecks(out io.Writer, checkTypes []string, cfg *config.UserConfig, verbose bool) []HealthCheckResult {
 	var results []HealthCheckResult
-	checkTypes := getCheckTypes(checkType)
 
 	// Determine config path
 	configPath := cfg.NixosFolder
@@ -1776,7 +2661,7 @@ func performHealthChecks(checkType string, cfg *config.UserConfig, verbose bool)
 	}
 
 	for _, ct := range checkTypes {
-		fmt.Print(utils.FormatProgress("  Checking " + ct + "... "))
+		_, _ = fmt.Fprint(out, utils.FormatProgress("  Checking "+ct+"... "))
 
 		switch ct {
 		case "system":
@@ -1795,7 +2680,7 @@ func performHealthChecks(checkType string, cfg *config.UserConfig, verbose bool)
 			results = append(results, performSecurityChecks(verbose)...)
 		}
 
-		fmt.Println(utils.FormatSuccess("done"))
+		_, _ = fmt.Fprintln(out, utils.FormatSuccess("done"))
 	}
 
 	return results
@@ -1821,7 +2706,7 @@ func performSystemChecks(configPath string, verbose bool) []HealthCheckResult {
 			Status:      "fail",
 			Description: "NixOS system may not be properly initialized",
 			Details:     "/run/current-system not found",
-			Command:     "sudo nixos-rebuild switch",
+			Command:     nixos.RebuildCommandLine(nixos.ContextForConfigPath(configPath), "switch"),
 		})
 	}
 
@@ -1839,7 +2724,7 @@ func performSystemChecks(configPath string, verbose bool) []HealthCheckResult {
 
 	// Try to use bootctl to get boot loader information if available
 	if isEFISystem {
-		if output, err := exec.Command("bootctl", "status").CombinedOutput(); err == nil {
+		if output, err := utils.TraceCombinedOutput(exec.Command("bootctl", "status")); err == nil {
 			outputStr := string(output)
 			bootLoaderDetails = append(bootLoaderDetails, "bootctl command available")
 
@@ -1902,7 +2787,7 @@ func performSystemChecks(configPath string, verbose bool) []HealthCheckResult {
 
 	// Check via efibootmgr if available and EFI system
 	if isEFISystem && !bootLoaderDetected {
-		if output, err := exec.Command("efibootmgr").CombinedOutput(); err == nil {
+		if output, err := utils.TraceCombinedOutput(exec.Command("efibootmgr")); err == nil {
 			outputStr := string(output)
 			if strings.Contains(outputStr, "nixos") || strings.Contains(outputStr, "systemd-boot") || strings.Contains(outputStr, "GRUB") {
 				bootLoaderDetected = true
@@ -2066,58 +2951,17 @@ func performPackageChecks(verbose bool) []HealthCheckResult {
 		})
 	}
 
+	// Check the nix daemon and trusted users
+	_, err := exec.LookPath("systemctl")
+	results = append(results, daemonChecks(systemDaemonCheckEnv(err == nil))...)
+
 	return results
 }
 
 // performServiceChecks checks system services
 func performServiceChecks(verbose bool) []HealthCheckResult {
-	var results []HealthCheckResult
-
-	// Check systemctl availability
-	if _, err := exec.LookPath("systemctl"); err == nil {
-		results = append(results, HealthCheckResult{
-			Category:    "services",
-			Name:        "Service Manager",
-			Status:      "pass",
-			Description: "systemd is available",
-			Details:     "System service management is functional",
-			Command:     "systemctl status",
-		})
-
-		// Check for failed services
-		cmd := exec.Command("systemctl", "--failed", "--no-legend", "--no-pager")
-		if output, err := cmd.Output(); err == nil {
-			failedServices := strings.TrimSpace(string(output))
-			if failedServices == "" {
-				results = append(results, HealthCheckResult{
-					Category:    "services",
-					Name:        "Failed Services",
-					Status:      "pass",
-					Description: "No failed services detected",
-					Details:     "All system services are running properly",
-				})
-			} else {
-				results = append(results, HealthCheckResult{
-					Category:    "services",
-					Name:        "Failed Services",
-					Status:      "warn",
-					Description: "Some services have failed",
-					Details:     "Failed services detected",
-					Command:     "systemctl --failed",
-				})
-			}
-		}
-	} else {
-		results = append(results, HealthCheckResult{
-			Category:    "services",
-			Name:        "Service Manager",
-			Status:      "warn",
-			Description: "systemctl not available",
-			Details:     "Cannot check service status",
-		})
-	}
-
-	return results
+	_, err := exec.LookPath("systemctl")
+	return serviceChecks(runDoctorCheckCommand, err == nil, nixos.IsWSL())
 }
 
 // performStorageChecks checks storage and filesystem health
@@ -2127,7 +2971,7 @@ func performStorageChecks(verbose bool) []HealthCheckResult {
 	// Check disk usage of root filesystem
 	if _, err := exec.LookPath("df"); err == nil {
 		cmd := exec.Command("df", "-h", "/")
-		if output, err := cmd.Output(); err == nil {
+		if output, err := utils.TraceOutput(cmd); err == nil {
 			lines := strings.Split(string(output), "\n")
 			if len(lines) >= 2 {
 				fields := strings.Fields(lines[1])
@@ -2148,7 +2992,7 @@ func performStorageChecks(verbose bool) []HealthCheckResult {
 
 	// Check for Nix store disk usage
 	cmd := exec.Command("du", "-sh", "/nix/store")
-	if output, err := cmd.Output(); err == nil {
+	if output, err := utils.TraceOutput(cmd); err == nil {
 		storeSize := strings.Fields(string(output))[0]
 		results = append(results, HealthCheckResult{
 			Category:    "storage",
@@ -2169,7 +3013,7 @@ func performNetworkChecks(verbose bool) []HealthCheckResult {
 
 	// Check internet connectivity
 	cmd := exec.Command("ping", "-c", "1", "-W", "3", "8.8.8.8")
-	if err := cmd.Run(); err == nil {
+	if err := utils.TraceRun(cmd); err == nil {
 		results = append(results, HealthCheckResult{
 			Category:    "network",
 			Name:        "Internet Connectivity",
@@ -2184,13 +3028,13 @@ func performNetworkChecks(verbose bool) []HealthCheckResult {
 			Status:      "warn",
 			Description: "Internet connection issue",
 			Details:     "Cannot reach external servers",
-			Command:     "ping 8.8.8.8",
+			Command:     "ping -c 4 8.8.8.8",
 		})
 	}
 
 	// Check DNS resolution
 	cmd = exec.Command("nslookup", "nixos.org")
-	if err := cmd.Run(); err == nil {
+	if err := utils.TraceRun(cmd); err == nil {
 		results = append(results, HealthCheckResult{
 			Category:    "network",
 			Name:        "DNS Resolution",
@@ -2250,7 +3094,7 @@ func performSecurityChecks(verbose bool) []HealthCheckResult {
 	// Check firewall status if available
 	if _, err := exec.LookPath("iptables"); err == nil {
 		cmd := exec.Command("iptables", "-L", "-n")
-		if err := cmd.Run(); err == nil {
+		if err := utils.TraceRun(cmd); err == nil {
 			results = append(results, HealthCheckResult{
 				Category:    "security",
 				Name:        "Firewall Rules",
@@ -2266,9 +3110,9 @@ func performSecurityChecks(verbose bool) []HealthCheckResult {
 }
 
 // displayHealthResults shows the health check results in a formatted way
-func displayHealthResults(results []HealthCheckResult, verbose bool) {
-	fmt.Println(utils.FormatHeader("📊 Health Check Results"))
-	fmt.Println()
+func displayHealthResults(out io.Writer, results []HealthCheckResult, verbose bool) {
+	_, _ = fmt.Fprintln(out, utils.FormatHeader("📊 Health Check Results"))
+	_, _ = fmt.Fprintln(out)
 
 	categories := make(map[string][]HealthCheckResult)
 	var passCount, warnCount, failCount, infoCount int
@@ -2293,35 +3137,35 @@ func displayHealthResults(results []HealthCheckResult, verbose bool) {
 	categoryOrder := []string{"system", "nixos", "packages", "services", "storage", "network", "security"}
 	for _, category := range categoryOrder {
 		if results, exists := categories[category]; exists {
-			fmt.Println(utils.FormatSubsection(getCategoryTitle(category), ""))
+			_, _ = fmt.Fprintln(out, utils.FormatSubsection(getCategoryTitle(category), ""))
 
 			for _, result := range results {
 				status := getStatusIcon(result.Status)
-				fmt.Printf("  %s %s\n", status, result.Description)
+				_, _ = fmt.Fprintf(out, "  %s %s\n", status, result.Description)
 
 				if verbose && result.Details != "" {
-					fmt.Printf("      %s\n", utils.FormatKeyValue("Details", result.Details))
+					_, _ = fmt.Fprintf(out, "      %s\n", utils.FormatKeyValue("Details", result.Details))
 				}
 
 				if result.Command != "" {
-					fmt.Printf("      %s\n", utils.FormatKeyValue("Suggested command", result.Command))
+					_, _ = fmt.Fprintf(out, "      %s\n", utils.FormatKeyValue("Suggested command", result.Command))
 				}
 			}
-			fmt.Println()
+			_, _ = fmt.Fprintln(out)
 		}
 	}
 
 	// Display summary
-	fmt.Println(utils.FormatHeader("📈 Health Summary"))
-	fmt.Printf("  %s %d checks passed\n", getStatusIcon("pass"), passCount)
+	_, _ = fmt.Fprintln(out, utils.FormatHeader("📈 Health Summary"))
+	_, _ = fmt.Fprintf(out, "  %s %d checks passed\n", getStatusIcon("pass"), passCount)
 	if infoCount > 0 {
-		fmt.Printf("  %s %d informational\n", getStatusIcon("info"), infoCount)
+		_, _ = fmt.Fprintf(out, "  %s %d informational\n", getStatusIcon("info"), infoCount)
 	}
 	if warnCount > 0 {
-		fmt.Printf("  %s %d warnings\n", getStatusIcon("warn"), warnCount)
+		_, _ = fmt.Fprintf(out, "  %s %d warnings\n", getStatusIcon("warn"), warnCount)
 	}
 	if failCount > 0 {
-		fmt.Printf("  %s %d failures\n", getStatusIcon("fail"), failCount)
+		_, _ = fmt.Fprintf(out, "  %s %d failures\n", getStatusIcon("fail"), failCount)
 	}
 
 	overallStatus := "healthy"
@@ -2331,7 +3175,7 @@ func displayHealthResults(results []HealthCheckResult, verbose bool) {
 		overallStatus = "warnings detected"
 	}
 
-	fmt.Printf("\n  Overall Status: %s\n", utils.FormatKeyValue("", overallStatus))
+	_, _ = fmt.Fprintf(out, "\n  Overall Status: %s\n", utils.FormatKeyValue("", overallStatus))
 }
 
 // getCategoryTitle returns a formatted title for each category
@@ -2405,12 +3249,31 @@ var completionCmd = &cobra.Command{
 	Short: "Generate the autocompletion script for the specified shell",
 	Long: `Generate shell completion scripts for bash, zsh, fish, or powershell.
 
+The generated scripts complete --provider from the configured providers and
+--model from the selected provider's models, including models installed in Ollama.
+
 Examples:
   nixai completion bash > /etc/bash_completion.d/nixai
   nixai completion zsh > ~/.zshrc
+  nixai completion --model-list     # List all known provider:model pairs
 `,
-	Args: conditionalExactArgsValidator(1),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if modelList, _ := cmd.Flags().GetBool("model-list"); modelList {
+			return cobra.NoArgs(cmd, args)
+		}
+		return conditionalExactArgsValidator(1)(cmd, args)
+	},
+	ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
 	Run: func(cmd *cobra.Command, args []string) {
+		if modelList, _ := cmd.Flags().GetBool("model-list"); modelList {
+			cfg, err := config.LoadUserConfig()
+			if err != nil {
+				fmt.Println(utils.FormatError("Failed to load config: " + err.Error()))
+				return
+			}
+			printModelList(cmd.OutOrStdout(), cfg)
+			return
+		}
 		switch args[0] {
 		case "bash":
 			_ = rootCmd.GenBashCompletion(os.Stdout)
@@ -2460,30 +3323,6 @@ func handleMCPServerCommand(args []string) error {
 		return handleMCPServerStatus(cfg)
 	case "restart":
 		return handleMCPServerRestart(cfg)
-	case "query":
-		if len(args) < 2 {
-			return fmt.Errorf("query command requires a query string")
-		}
-
-		var query string
-		var sources []string
-		var inSourcesMode bool
-
-		for i := 1; i < len(args); i++ {
-			if args[i] == "--source" || args[i] == "-s" {
-				inSourcesMode = true
-			} else if inSourcesMode {
-				sources = append(sources, args[i])
-				inSourcesMode = false
-			} else {
-				if query != "" {
-					query += " "
-				}
-				query += args[i]
-			}
-		}
-
-		return handleMCPServerQuery(cfg, query, sources...)
 	default:
 		return fmt.Errorf("unknown subcommand: %s. Available: start, stop, status, restart, query", subcommand)
 	}
@@ -2497,7 +3336,7 @@ func handleMCPServerStart(cfg *config.UserConfig, daemon bool) error {
 	// If daemon mode is requested, fork the process
 	if daemon {
 		// Create a command to start the server without daemon flag
-		cmd := exec.Command(os.Args[0], "mcp-server", "start")
+		cmd := exec.Command(os.Args[0], append([]string{"mcp-server", "start"}, configFileArgs()...)...)
 
 		// Start the background process without complex process group management
 		err := cmd.Start()
@@ -2564,7 +3403,7 @@ func handleMCPServerStop(cfg *config.UserConfig) error {
 
 	fmt.Print(utils.FormatInfo("Sending shutdown signal... "))
 
-	resp, err := http.Get(url)
+	resp, err := utils.NewHTTPClient(10 * time.Second).Get(url)
 	if err != nil {
 		fmt.Println(utils.FormatError("failed"))
 		return fmt.Errorf("failed to connect to server: %v", err)
@@ -2583,18 +3422,33 @@ func handleMCPServerStatus(cfg *config.UserConfig) error {
 	fmt.Println()
 
 	// Check HTTP endpoint
-	url := fmt.Sprintf("http://%s:%d/healthz", cfg.MCPServer.Host, cfg.MCPServer.Port)
+	mcpURL := fmt.Sprintf("http://%s:%d", cfg.MCPServer.Host, cfg.MCPServer.Port)
+	client := mcp.NewMCPClient(mcpURL)
 
 	fmt.Print(utils.FormatInfo("Checking HTTP endpoint... "))
 
-	resp, err := http.Get(url)
-	if err != nil {
+	info, err := client.ServerInfo()
+	switch {
+	case errors.Is(err, mcp.ErrServerInfoUnavailable) && client.Healthy() == nil:
+		// Servers that predate /info still answer /healthz
+		fmt.Println(utils.FormatSuccess("healthy"))
+		fmt.Println(utils.FormatKeyValue("HTTP Status", "✅ Running"))
+		fmt.Println(utils.FormatKeyValue("Server Version", "unknown"))
+		fmt.Println(utils.FormatWarning(fmt.Sprintf("Server predates version reporting (client %s); run 'nixai mcp-server restart'", version.Version)))
+	case err != nil:
 		fmt.Println(utils.FormatError("unreachable"))
 		fmt.Println(utils.FormatKeyValue("HTTP Status", "❌ Not running"))
-	} else {
-		defer resp.Body.Close()
+	default:
 		fmt.Println(utils.FormatSuccess("healthy"))
 		fmt.Println(utils.FormatKeyValue("HTTP Status", "✅ Running"))
+		fmt.Println(utils.FormatKeyValue("Server Version", info.Version))
+		if !mcp.IsCompatibleVersion(version.Version, info.Version) {
+			fmt.Println(utils.FormatWarning(fmt.Sprintf("Server version %s is incompatible with client %s; run 'nixai mcp-server restart'", info.Version, version.Version)))
+		}
+		fmt.Println(utils.FormatKeyValue("Uptime", (time.Duration(info.UptimeSeconds) * time.Second).String()))
+		fmt.Println(utils.FormatKeyValue("Server Sources", fmt.Sprintf("%d sources", info.DocumentationSources)))
+		fmt.Println(utils.FormatKeyValue("Cached Queries", fmt.Sprintf("%d entries", info.CacheEntries)))
+		fmt.Println(utils.FormatKeyValue("Authentication", map[bool]string{true: "enabled", false: "disabled"}[info.AuthEnabled]))
 	}
 
 	// Check Unix socket
@@ -2641,36 +3495,6 @@ func handleMCPServerRestart(cfg *config.UserConfig) error {
 	return handleMCPServerStart(cfg, false)
 }
 
-// handleMCPServerQuery queries the MCP server directly
-func handleMCPServerQuery(cfg *config.UserConfig, query string, sources ...string) error {
-	fmt.Println(utils.FormatHeader("🔍 MCP Server Query"))
-	fmt.Println()
-	fmt.Println(utils.FormatKeyValue("Query", query))
-	if len(sources) > 0 {
-		fmt.Println(utils.FormatKeyValue("Sources", strings.Join(sources, ", ")))
-	}
-	fmt.Println()
-
-	// Create MCP client
-	mcpURL := fmt.Sprintf("http://%s:%d", cfg.MCPServer.Host, cfg.MCPServer.Port)
-	client := mcp.NewMCPClient(mcpURL)
-
-	fmt.Print(utils.FormatInfo("Querying documentation... "))
-
-	result, err := client.QueryDocumentation(query, sources...)
-	if err != nil {
-		fmt.Println(utils.FormatError("failed"))
-		return fmt.Errorf("query failed: %v", err)
-	}
-
-	fmt.Println(utils.FormatSuccess("done"))
-	fmt.Println()
-	fmt.Println(utils.FormatSubsection("📖 Documentation Results", ""))
-	fmt.Println(utils.RenderMarkdown(result))
-
-	return nil
-}
-
 // Missing command handlers
 
 // handleFlakeCommand handles the flake command
@@ -2680,8 +3504,7 @@ func handleFlakeCommand(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	// TODO: Implement flake command functionality
-	fmt.Println("Flake command functionality is coming soon!")
+	runFlakeCmd(args, cmd.OutOrStdout())
 }
 
 // handleLearnCommand handles the learn command
@@ -2746,7 +3569,7 @@ func handleLogsErrors(cmd *cobra.Command, args []string) {
 	// Initialize logs agent
 	logsAgent, err := initializeLogsAgent()
 	if err != nil {
-		fmt.Println(utils.FormatWarning("Failed to initialize AI agent, using basic analysis: " + err.Error()))
+		fmt.Println(basicAnalysisNotice(err))
 		displayBasicLogSummary(logData, "errors")
 		return
 	}
@@ -2775,6 +3598,18 @@ func handleLogsAnalyze(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	if err := checkLogsFormat(logsAnalyzeFormat); err != nil {
+		fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+		os.Exit(1)
+	}
+	if logsAnalyzeFormat == logsFormatJSONL {
+		if err := runLogsAnalyzeJSONL(args, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+
 	fmt.Println(utils.FormatHeader("🔍 Log File Analysis"))
 	fmt.Println()
 
@@ -2817,7 +3652,7 @@ func handleLogsAnalyze(cmd *cobra.Command, args []string) {
 	// Initialize logs agent
 	logsAgent, err := initializeLogsAgent()
 	if err != nil {
-		fmt.Println(utils.FormatWarning("Failed to initialize AI agent, using basic analysis: " + err.Error()))
+		fmt.Println(basicAnalysisNotice(err))
 		displayBasicLogSummary(logData, "file")
 		return
 	}
@@ -2974,6 +3809,11 @@ func handlePackageRepoCommand(cmd *cobra.Command, args []string) {
 	outputPath, _ := cmd.Flags().GetString("output")
 	packageName, _ := cmd.Flags().GetString("name")
 	analyzeOnly, _ := cmd.Flags().GetBool("analyze-only")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	interactive, _ := cmd.Flags().GetBool("interactive")
+	subdir, _ := cmd.Flags().GetString("subdir")
+	keepTemp, _ := cmd.Flags().GetBool("keep-temp")
+	openFile, _ := cmd.Flags().GetBool("open")
 
 	// Determine repository URL or local path
 	var repoURL string
@@ -2988,6 +3828,15 @@ func handlePackageRepoCommand(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	if interactive && (jsonOutput || analyzeOnly) {
+		fmt.Fprintln(os.Stderr, utils.FormatError("--interactive cannot be combined with --json or --analyze-only"))
+		return
+	}
+	if interactive && !isInteractiveSession() {
+		fmt.Fprintln(os.Stderr, utils.FormatError("--interactive needs an interactive terminal"))
+		return
+	}
+
 	// Load configuration
 	cfg, err := config.LoadUserConfig()
 	if err != nil {
@@ -3006,22 +3855,70 @@ func handlePackageRepoCommand(cmd *cobra.Command, args []string) {
 	mcpURL := fmt.Sprintf("http://%s:%d", cfg.MCPServer.Host, cfg.MCPServer.Port)
 	mcpClient := mcp.NewMCPClient(mcpURL)
 
-	// Create packaging service
-	tempDir := "/tmp/nixai-packaging"
+	// Create packaging service; in JSON mode its log goes to stderr to keep stdout parseable
+	packagingLog := logger.NewLogger()
+	if jsonOutput {
+		packagingLog = logger.NewLoggerWithLevelAndWriter(cfg.LogLevel, os.Stderr)
+	}
+	tempDir, cleanupTemp, err := packagingTempDir(keepTemp)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+		return
+	}
+	defer cleanupTemp()
+	if keepTemp {
+		fmt.Fprintln(os.Stderr, utils.FormatNote("Keeping the clone and temporary files in "+tempDir))
+	}
 	packagingService := packaging.NewPackagingService(
 		legacyAIProvider, // Use legacy AI provider directly
 		mcpClient,
 		tempDir,
-		logger.NewLogger(),
+		packagingLog,
 	)
 
-	// Create package request
+	// Create package request. The service only generates the derivation; it is written to
+	// --output below, asking before an existing file is replaced.
 	request := &packaging.PackageRequest{
 		RepoURL:     repoURL,
 		LocalPath:   localPath,
-		OutputPath:  outputPath,
 		PackageName: packageName,
-		Quiet:       false,
+		Subdir:      subdir,
+		Quiet:       jsonOutput,
+		KeepClone:   keepTemp,
+		AnalyzeOnly: analyzeOnly,
+	}
+
+	// Bound cloning, analysis and generation so that a huge repository cannot hang forever
+	timeout := cfg.PackageRepoTimeout()
+	packageCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if jsonOutput {
+		result, err := packagingService.PackageRepository(packageCtx, request)
+		cleanupTemp() // os.Exit below skips the deferred cleanup
+		if err != nil {
+			fmt.Fprintln(os.Stderr, utils.FormatError(packageRepoError(err, timeout)))
+			os.Exit(1)
+		}
+		if outputPath != "" && result.Derivation != "" {
+			// stdout carries the JSON, so any overwrite preview goes to stderr
+			confirm := writeConfirm(cmd)
+			confirm.Out = os.Stderr
+			err := utils.WriteFileWithConfirm(outputPath, []byte(result.Derivation), 0644, confirm)
+			switch {
+			case errors.Is(err, utils.ErrOverwriteDeclined):
+				fmt.Fprintln(os.Stderr, utils.FormatInfo("Derivation not saved"))
+			case err != nil:
+				fmt.Fprintln(os.Stderr, utils.FormatError("Failed to write derivation to file: "+err.Error()))
+			default:
+				result.OutputFile = outputPath
+			}
+		}
+		if err := writePackageResultJSON(cmd.OutOrStdout(), result); err != nil {
+			fmt.Fprintln(os.Stderr, utils.FormatError("Failed to encode result: "+err.Error()))
+			os.Exit(1)
+		}
+		return
 	}
 
 	// Display header
@@ -3033,20 +3930,30 @@ func handlePackageRepoCommand(cmd *cobra.Command, args []string) {
 	fmt.Println()
 
 	// Execute packaging
-	ctx := context.Background()
-	result, err := packagingService.PackageRepository(ctx, request)
+	result, err := packagingService.PackageRepository(packageCtx, request)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, utils.FormatError("Failed to package repository: "+err.Error()))
+		fmt.Fprintln(os.Stderr, utils.FormatError(packageRepoError(err, timeout)))
+		if errors.Is(err, context.DeadlineExceeded) {
+			fmt.Fprintln(os.Stderr, utils.FormatTip("Raise package_repo.timeout in your config, or use --subdir to package a smaller part of the repository"))
+		}
 		return
 	}
 
 	// Display analysis results
 	fmt.Println(utils.FormatHeader("🔍 Repository Analysis"))
+	if result.PackageRoot != "" && result.PackageRoot != "." {
+		fmt.Println(utils.FormatKeyValue("Package Root", result.PackageRoot))
+	}
 	fmt.Println(utils.FormatKeyValue("Project Name", result.Analysis.ProjectName))
 	fmt.Println(utils.FormatKeyValue("Language", result.Analysis.Language))
 	fmt.Println(utils.FormatKeyValue("Build System", string(result.Analysis.BuildSystem))) // Convert BuildSystem to string
 	fmt.Println(utils.FormatKeyValue("Dependencies", fmt.Sprintf("%d found", len(result.Analysis.Dependencies))))
 
+	if len(result.OtherPackageRoots) > 0 {
+		fmt.Println(utils.FormatNote("Other package roots found: " + strings.Join(result.OtherPackageRoots, ", ")))
+		fmt.Println(utils.FormatTip("Use --subdir <path> to package one of them instead"))
+	}
+
 	if len(result.Analysis.Dependencies) > 0 {
 		fmt.Println()
 		fmt.Println(utils.FormatHeader("📋 Dependencies"))
@@ -3070,14 +3977,19 @@ func handlePackageRepoCommand(cmd *cobra.Command, args []string) {
 		fmt.Println(utils.FormatHeader("📜 Generated Nix Derivation"))
 		fmt.Println(utils.RenderMarkdown("```nix\n" + result.Derivation + "\n```"))
 
-		// Save to file if output path specified
-		if outputPath != "" {
-			err := os.WriteFile(outputPath, []byte(result.Derivation), 0644)
-			if err != nil {
+		// Save to file if output path specified; interactive sessions save when the user is done
+		if outputPath != "" && !interactive {
+			err := utils.WriteFileWithConfirm(outputPath, []byte(result.Derivation), 0644, writeConfirm(cmd))
+			if errors.Is(err, utils.ErrOverwriteDeclined) {
+				fmt.Println(utils.FormatInfo("Derivation not saved"))
+			} else if err != nil {
 				fmt.Fprintln(os.Stderr, utils.FormatError("Failed to write derivation to file: "+err.Error()))
 			} else {
 				fmt.Println()
 				fmt.Println(utils.FormatSuccess("✅ Derivation written to: " + outputPath))
+				if openFile {
+					openInEditor(os.Stdout, outputPath)
+				}
 			}
 		}
 	}
@@ -3086,8 +3998,28 @@ func handlePackageRepoCommand(cmd *cobra.Command, args []string) {
 	if len(result.NixpkgsMappings) > 0 {
 		fmt.Println()
 		fmt.Println(utils.FormatHeader("🗂️  Nixpkgs Mappings"))
-		for dep, nixpkg := range result.NixpkgsMappings {
-			fmt.Println(utils.FormatKeyValue(dep, nixpkg))
+		deps := make([]string, 0, len(result.NixpkgsMappings))
+		for dep := range result.NixpkgsMappings {
+			deps = append(deps, dep)
+		}
+		sort.Strings(deps)
+		for _, dep := range deps {
+			mapping := result.NixpkgsMappings[dep]
+			if confidence, ok := result.MappingConfidence[dep]; ok {
+				mapping += " (" + string(confidence) + ")"
+			}
+			fmt.Println(utils.FormatKeyValue(dep, mapping))
+		}
+	}
+
+	if interactive && result.Derivation != "" {
+		// The service never writes the derivation, so the refined one is saved only here
+		savePath := derivationOutputPath(outputPath, result.Analysis.ProjectName)
+		// The refinement waits for the user, so the packaging timeout does not apply to it
+		assumeYes, _ := cmd.Flags().GetBool("yes")
+		saved := refineDerivationInteractively(context.Background(), bufio.NewReader(os.Stdin), cmd.OutOrStdout(), packagingService, result, savePath, assumeYes)
+		if saved && openFile {
+			openInEditor(os.Stdout, savePath)
 		}
 	}
 
@@ -3095,8 +4027,42 @@ func handlePackageRepoCommand(cmd *cobra.Command, args []string) {
 	fmt.Println(utils.FormatSuccess("✅ Repository analysis complete!"))
 }
 
+// derivationOutputPath returns the file the derivation of project is saved to: outputPath, or
+// <project>.nix inside it when it is a directory, e.g. one created by an older nixai, and
+// <project>.nix in the working directory without one
+func derivationOutputPath(outputPath, project string) string {
+	if outputPath == "" {
+		return project + ".nix"
+	}
+	if info, err := os.Stat(outputPath); err == nil && info.IsDir() {
+		return filepath.Join(outputPath, project+".nix")
+	}
+	return outputPath
+}
+
+// packageRepoError describes a packaging failure, naming the timeout when it was reached
+func packageRepoError(err error, timeout time.Duration) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Sprintf("Packaging the repository took longer than %s and was aborted", timeout)
+	}
+	return "Failed to package repository: " + err.Error()
+}
+
+// writePackageResultJSON writes the package-repo result as indented JSON
+func writePackageResultJSON(out io.Writer, result *packaging.PackageResult) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
 // initializeLogsAgent creates a logs agent with AI provider
 func initializeLogsAgent() (*agent.LogsAgent, error) {
+	if noAI {
+		return nil, errAIDisabled
+	}
 	cfg, err := config.LoadUserConfig()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
@@ -3148,7 +4114,7 @@ func analyzeSystemLogs(out io.Writer) {
 	// Initialize logs agent
 	logsAgent, err := initializeLogsAgent()
 	if err != nil {
-		fmt.Fprintln(out, utils.FormatWarning("Failed to initialize AI agent, using basic analysis: "+err.Error()))
+		fmt.Fprintln(out, basicAnalysisNotice(err))
 		displayBasicLogSummaryToWriter(out, logData, "system")
 		return
 	}
@@ -3217,7 +4183,7 @@ func handleLogsBoot(cmd *cobra.Command, args []string) {
 	// Initialize logs agent
 	logsAgent, err := initializeLogsAgent()
 	if err != nil {
-		fmt.Println(utils.FormatWarning("Failed to initialize AI agent, using basic analysis: " + err.Error()))
+		fmt.Println(basicAnalysisNotice(err))
 		displayBasicLogSummary(logData, "boot")
 		return
 	}
@@ -3294,7 +4260,7 @@ func handleLogsService(cmd *cobra.Command, args []string) {
 	// Initialize logs agent
 	logsAgent, err := initializeLogsAgent()
 	if err != nil {
-		fmt.Println(utils.FormatWarning("Failed to initialize AI agent, using basic analysis: " + err.Error()))
+		fmt.Println(basicAnalysisNotice(err))
 		displayBasicLogSummary(logData, "service")
 		return
 	}
@@ -3369,7 +4335,7 @@ func handleLogsBuild(cmd *cobra.Command, args []string) {
 	// Initialize logs agent
 	logsAgent, err := initializeLogsAgent()
 	if err != nil {
-		fmt.Println(utils.FormatWarning("Failed to initialize AI agent, using basic analysis: " + err.Error()))
+		fmt.Println(basicAnalysisNotice(err))
 		displayBasicLogSummary(logData, "build")
 		return
 	}
@@ -3481,8 +4447,27 @@ func displayBasicLogSummaryToWriter(out io.Writer, logData, logType string) {
 	}
 }
 
+// commandsOnce ensures commands are only registered once, since several entry points initialize them
+var commandsOnce sync.Once
+
 // initializeCommands adds all commands to the root command
 func initializeCommands() {
+	commandsOnce.Do(registerCommands)
+}
+
+// registerCommands registers the help command and all subcommands on the root command
+func registerCommands() {
+	rootCmd.SetHelpCommand(helpCmd)
+	defaultHelp := rootCmd.HelpFunc()
+	rootCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if showExamples {
+			printCommandExamples(cmd.OutOrStdout(), cmd)
+			return
+		}
+		defaultHelp(cmd, args)
+	})
+	registerFlagCompletions(rootCmd)
+	rootCmd.AddCommand(examplesCmd)
 	rootCmd.AddCommand(askCmd)
 	rootCmd.AddCommand(searchCmd)
 	rootCmd.AddCommand(explainOptionCmd)
@@ -3498,7 +4483,8 @@ func initializeCommands() {
 	rootCmd.AddCommand(snippetsCmd)
 	rootCmd.AddCommand(enhancedBuildCmd)
 	rootCmd.AddCommand(devenvCmd)
-	rootCmd.AddCommand(NewDepsCommand())
+	depsCmd := NewDepsCommand()
+	rootCmd.AddCommand(depsCmd)
 	rootCmd.AddCommand(contextCmd)
 	// Register stub commands for missing features
 	rootCmd.AddCommand(communityCmd)
@@ -3512,18 +4498,40 @@ func initializeCommands() {
 	rootCmd.AddCommand(mcpServerCmd)
 	rootCmd.AddCommand(neovimSetupCmd)
 	rootCmd.AddCommand(packageRepoCmd)
+	rootCmd.AddCommand(newProvidersCmd())
+
+	// These commands drive nix directly, so they stop with one clear message when it is missing
+	markRequiresNix(enhancedBuildCmd, depsCmd, flakeCmd, gcCmd, storeCmd)
 }
 
 // Execute runs the root command
 func Execute() {
 	cobra.OnInitialize(func() {
+		// --config must be applied before anything loads the config
+		if err := applyConfigFile(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --config path: %v\n", err)
+			os.Exit(1)
+		}
+		if traceCommands {
+			utils.SetCommandTrace(os.Stderr)
+		}
+		config.SetContextDetectionDisabled(noContext)
 		if nixosPath != "" {
 			if err := os.Setenv("NIXAI_NIXOS_PATH", nixosPath); err != nil {
 				fmt.Fprintf(os.Stderr, "Warning: failed to set NIXAI_NIXOS_PATH: %v\n", err)
 			}
 		}
+		// network.proxy overrides the proxy environment variables for all HTTP clients
+		if cfg, err := config.LoadUserConfig(); err == nil {
+			if err := utils.SetHTTPProxy(cfg.Network.Proxy); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: ignoring network.proxy: %v\n", err)
+			}
+		}
 	})
 	initializeCommands()
+	// Ctrl-C flushes partial output and restores the terminal before exiting with status 130
+	stopInterrupts := utils.HandleInterrupts(os.Stdout)
+	defer stopInterrupts()
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
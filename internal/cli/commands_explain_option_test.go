@@ -116,3 +116,84 @@ func TestBuildExplainOptionPrompt(t *testing.T) {
 		t.Error("prompt should include the documentation")
 	}
 }
+
+func TestExplainOptionPromptsUseRealNewlines(t *testing.T) {
+	jsonDoc := `{"option_name":"services.nginx.enable","option_type":"boolean","option_default":"false",` +
+		`"related_options":["services.nginx.virtualHosts"]}`
+	prompts := map[string]string{
+		"examples only":     buildExamplesOnlyPrompt("services.nginx.enable", "Enable nginx.", "Markdown", "nixos-options", "25.05"),
+		"plain doc":         buildEnhancedExplainOptionPrompt("services.nginx.enable", "Enable nginx.", "Markdown", "nixos-options", "25.05"),
+		"option doc (JSON)": buildEnhancedExplainOptionPrompt("services.nginx.enable", jsonDoc, "Markdown", "", ""),
+	}
+	for name, prompt := range prompts {
+		if strings.Contains(prompt, `\n`) {
+			t.Errorf("%s prompt contains a literal \\n:\n%s", name, prompt)
+		}
+		if !strings.Contains(prompt, "\n\n**Option:** services.nginx.enable") {
+			t.Errorf("%s prompt does not put the option on its own line:\n%s", name, prompt)
+		}
+	}
+	// The option JSON is decoded into the detailed prompt rather than passed on as text
+	for _, want := range []string{"\n**Type:** boolean\n**Default:** false\n", "**Related Options:**\n- services.nginx.virtualHosts"} {
+		if !strings.Contains(prompts["option doc (JSON)"], want) {
+			t.Errorf("option doc prompt is missing %q:\n%s", want, prompts["option doc (JSON)"])
+		}
+	}
+}
+
+func TestParseMCPOptionDoc(t *testing.T) {
+	const nginx = `{"option_name":"services.nginx.enable","option_type":"boolean","option_description":"Whether to enable Nginx Web Server."}`
+	const openssh = `{"option_name":"services.openssh.enable","option_type":"boolean"}`
+
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"plain JSON", nginx, "services.nginx.enable"},
+		{"wrapped in prose", "Here is the option you asked for:\n" + nginx + "\nLet me know if you need more.", "services.nginx.enable"},
+		{"in a code fence", "```json\n" + nginx + "\n```", "services.nginx.enable"},
+		{"after unrelated JSON", `{"status":"ok"} then ` + nginx, "services.nginx.enable"},
+		{"nested in a result", `{"result":` + nginx + `}`, "services.nginx.enable"},
+		{"several objects", nginx + "\n" + openssh, "services.nginx.enable"},
+		{"array of options", "[" + openssh + "," + nginx + "]", "services.openssh.enable"},
+		{"unexpected field type", `{"option_name":"services.nginx.enable","option_default":false}`, "services.nginx.enable"},
+		{"truncated JSON", `{"option_name":"services.nginx.enable","option_type":"bool`, ""},
+		{"prose only", "services.nginx.enable: Enable the nginx service. Type: boolean.", ""},
+	}
+	for _, tt := range tests {
+		opt, fallback := parseMCPOptionDoc(tt.doc)
+		if opt.Name != tt.want {
+			t.Errorf("%s: got option %q, want %q", tt.name, opt.Name, tt.want)
+		}
+		if (tt.want == "") != (fallback == tt.doc) {
+			t.Errorf("%s: expected the raw doc as fallback only when no option is found, got %q", tt.name, fallback)
+		}
+	}
+
+	if opts := parseMCPOptionDocs("Matches:\n[" + nginx + "," + openssh + "]\nAnd also " + openssh); len(opts) != 3 {
+		t.Errorf("expected 3 options from an array and an object, got %d", len(opts))
+	}
+	if opt, _ := parseMCPOptionDocNamed("["+nginx+","+openssh+"]", "services.openssh.enable"); opt.Name != "services.openssh.enable" {
+		t.Errorf("expected the named option from an array, got %q", opt.Name)
+	}
+	if opt, _ := parseMCPOptionDoc(nginx); opt.Description != "Whether to enable Nginx Web Server." {
+		t.Errorf("expected the description to be parsed, got %q", opt.Description)
+	}
+
+	// Defaults and examples that are not strings are rendered, not dropped
+	values := map[string]optionValue{
+		`"pkgs.nginx"`: "pkgs.nginx",
+		`false`:        "false",
+		`[]`:           "[]",
+		`{ }`:          "{}",
+		`{"_type":"literalExpression","text":"config.boot.kernelPackages"}`: "config.boot.kernelPackages",
+		`null`: "",
+	}
+	for value, want := range values {
+		opt, _ := parseMCPOptionDoc(`{"option_name":"services.nginx.enable","option_default":` + value + `,"option_example":` + value + `}`)
+		if opt.Default != want || opt.Example != want {
+			t.Errorf("default and example %s: got %q and %q, want %q", value, opt.Default, opt.Example, want)
+		}
+	}
+}
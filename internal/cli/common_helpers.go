@@ -10,14 +10,21 @@ import (
 	"nix-ai-help/pkg/logger"
 )
 
-// GetAIProviderManager creates and returns a provider manager using the configuration system
+// GetAIProviderManager returns the provider manager for the configuration, reusing the
+// manager of earlier calls in this process while the configuration is unchanged. Its providers
+// use the temperature and top_p resolved for the running command.
 func GetAIProviderManager(cfg *config.UserConfig, log *logger.Logger) *ai.ProviderManager {
-	return ai.NewProviderManager(cfg, log)
+	return sharedProviderManager(withCommandSampling(cfg, samplingCommand), log)
 }
 
 // GetLegacyAIProvider gets a legacy AIProvider using the new ProviderManager system
+// With --no-ai it returns errAIDisabled, so that no command reaches a provider; commands that
+// also work without one use optionalAIProvider.
 func GetLegacyAIProvider(cfg *config.UserConfig, log *logger.Logger) (ai.AIProvider, error) {
-	manager := ai.NewProviderManager(cfg, log)
+	if err := requireAI(samplingCommand); err != nil {
+		return nil, err
+	}
+	manager := GetAIProviderManager(cfg, log)
 
 	// Get the configured default provider or fall back to ollama
 	defaultProvider := cfg.AIModels.SelectionPreferences.DefaultProvider
@@ -31,6 +38,14 @@ func GetLegacyAIProvider(cfg *config.UserConfig, log *logger.Logger) (ai.AIProvi
 		return ai.NewOllamaLegacyProvider("llama3"), nil
 	}
 
+	// With model tiers, each query picks a fast or strong model for the running command
+	if modelTiersEnabled(cfg) {
+		if log == nil {
+			log = logger.NewLogger()
+		}
+		return &tieredProvider{manager: manager, providerName: defaultProvider, command: samplingCommand, fallback: provider, log: log}, nil
+	}
+
 	// Use NewProviderWrapper to convert Provider to AIProvider
 	return &ProviderToLegacyAdapter{provider: provider}, nil
 }
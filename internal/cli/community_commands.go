@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -106,24 +107,19 @@ Examples:
 	},
 }
 
-// communityTrendsCmd shows trending packages and configurations
+// communityTrendsCmd shows trending configurations and topics
 var communityTrendsCmd = &cobra.Command{
 	Use:   "trends",
-	Short: "Show trending packages and configuration patterns",
-	Long: `Display trending packages, configurations, and patterns in the NixOS community.
+	Short: "Show trending configurations and topics",
+	Long: `Display trending configurations and topics in the NixOS community.
 
 This command shows:
-- Most popular packages by download/usage
-- Trending configuration patterns
-- Recent community activity
-- Emerging tools and frameworks
-- Configuration quality trends
-
-Data sources include:
-- Hydra build statistics
-- GitHub repository activity
-- Community forum discussions
-- Package manager telemetry (anonymized)
+- The most-starred NixOS configuration repositories updated on GitHub in the timeframe
+- The topics those repositories share most
+- A short AI summary of notable trends (skipped with --no-ai)
+
+Popular Discourse topics are included when Discourse is enabled in the config.
+Results are cached for the day.
 
 Examples:
   nixai community trends
@@ -504,72 +500,47 @@ func runCommunityValidate(configFile string, detailed, fixSuggestions bool, cmd
 }
 
 func runCommunityTrends(timeframe, category string, detailed bool, cmd *cobra.Command) {
-	_, _ = fmt.Fprintln(cmd.OutOrStdout(), utils.FormatHeader("📊 Community Trends"))
-	if timeframe != "" {
-		_, _ = fmt.Fprintf(cmd.OutOrStdout(), " (%s)\n", timeframe)
-	}
-	_, _ = fmt.Fprintln(cmd.OutOrStdout())
+	out := cmd.OutOrStdout()
+	_, _ = fmt.Fprintln(out, utils.FormatHeader("📊 Community Trends"))
+	_, _ = fmt.Fprintln(out)
 
 	// Load configuration
 	cfg, err := config.LoadUserConfig()
 	if err != nil {
-		_, _ = fmt.Fprintln(cmd.OutOrStdout(), utils.FormatError("Error loading config: "+err.Error()))
+		_, _ = fmt.Fprintln(out, utils.FormatError("Error loading config: "+err.Error()))
 		return
 	}
 
 	// Create community manager
 	manager := community.NewManager(cfg)
 
-	_, _ = fmt.Fprintln(cmd.OutOrStdout(), utils.FormatProgress("Fetching community trends data..."))
+	_, _ = fmt.Fprintln(out, utils.FormatProgress("Fetching community trends data..."))
 
-	// Get trends
-	trends, err := manager.GetTrends()
+	trends, err := manager.GetTrendsFor(timeframe, category)
 	if err != nil {
-		_, _ = fmt.Fprintln(cmd.OutOrStdout(), utils.FormatError("Failed to fetch trends: "+err.Error()))
+		_, _ = fmt.Fprintln(out, utils.FormatError("Failed to fetch trends: "+err.Error()))
 		return
 	}
 
-	_, _ = fmt.Fprintln(cmd.OutOrStdout(), utils.FormatSubsection("🔥 Popular Packages", ""))
-	for i, pkg := range trends.PopularPackages {
-		if i >= 10 { // Show top 10
-			break
-		}
-		stars := strings.Repeat("⭐", int(pkg.Rating))
-		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%d. %s\n", i+1,
-			utils.FormatKeyValue(pkg.Name, pkg.Description),
-		)
-		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "   %s | %s downloads\n",
-			stars, utils.FormatNote(fmt.Sprintf("%d", pkg.Downloads)))
-	}
-	_, _ = fmt.Fprintln(cmd.OutOrStdout())
+	renderTrends(out, trends, detailed)
 
-	_, _ = fmt.Fprintln(cmd.OutOrStdout(), utils.FormatSubsection("🚀 Trending Configurations", ""))
-	for i, config := range trends.TrendingConfigs {
-		if i >= 8 { // Show top 8
-			break
-		}
-		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%d. %s\n", i+1,
-			utils.FormatKeyValue(config.Name, config.Description))
-		if config.Author != "" {
-			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "   %s\n", utils.FormatNote("by "+config.Author))
-		}
-		if len(config.Tags) > 0 {
-			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "   %s\n", utils.FormatNote("Tags: "+strings.Join(config.Tags, ", ")))
+	if len(trends.TrendingConfigs) > 0 {
+		aiProvider, err := optionalAIProvider(cfg, logger.NewLogger())
+		switch {
+		case errors.Is(err, errAIDisabled):
+			// --no-ai: the trends alone
+		case err != nil:
+			_, _ = fmt.Fprintln(out, utils.FormatWarning("Skipping the AI summary: "+err.Error()))
+		default:
+			if err := summarizeTrends(out, trends, aiProvider.Query); err != nil {
+				_, _ = fmt.Fprintln(out, utils.FormatWarning(err.Error()))
+			}
 		}
-	}
-	_, _ = fmt.Fprintln(cmd.OutOrStdout())
-
-	if detailed {
-		_, _ = fmt.Fprintln(cmd.OutOrStdout(), utils.FormatSubsection("📈 Community Statistics", ""))
-		_, _ = fmt.Fprintln(cmd.OutOrStdout(), utils.FormatKeyValue("Total Configurations", fmt.Sprintf("%d", trends.TotalConfigurations)))
-		_, _ = fmt.Fprintln(cmd.OutOrStdout(), utils.FormatKeyValue("Active Contributors", fmt.Sprintf("%d", trends.ActiveContributors)))
-		_, _ = fmt.Fprintln(cmd.OutOrStdout(), utils.FormatKeyValue("Packages Tracked", fmt.Sprintf("%d", trends.PackagesTracked)))
-		_, _ = fmt.Fprintln(cmd.OutOrStdout(), utils.FormatKeyValue("Last Updated", trends.LastUpdated.Format("2006-01-02 15:04:05")))
-		_, _ = fmt.Fprintln(cmd.OutOrStdout())
+		_, _ = fmt.Fprintln(out)
 	}
 
-	_, _ = fmt.Fprintln(cmd.OutOrStdout(), utils.FormatTip("Use 'nixai community search <package>' to find configurations using trending packages"))
-	_, _ = fmt.Fprintln(cmd.OutOrStdout(), utils.FormatTip("Use 'nixai community validate <file>' to check your configuration against trends"))
+	_, _ = fmt.Fprintln(out, utils.FormatTip("Use 'nixai community search <topic>' to find configurations about a trending topic"))
+	_, _ = fmt.Fprintln(out, utils.FormatTip("Trends are cached for the day; use --timeframe daily, weekly or monthly to change the window"))
 }
 
 func runCommunityRate(configName string, rating float64, comment string, cmd *cobra.Command) {
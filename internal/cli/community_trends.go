@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"nix-ai-help/internal/community"
+	"nix-ai-help/pkg/utils"
+)
+
+// maxTrendingConfigsShown is how many trending configurations `community trends` lists
+const maxTrendingConfigsShown = 10
+
+// renderTrends prints the trending configurations and topics, and with detailed the numbers
+// behind them
+func renderTrends(out io.Writer, trends *community.TrendData, detailed bool) {
+	_, _ = fmt.Fprintln(out, utils.FormatSubsection("🚀 Trending Configurations", ""))
+	if len(trends.TrendingConfigs) == 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatNote("No configurations were updated in this timeframe"))
+	}
+	for i, config := range trends.TrendingConfigs {
+		if i >= maxTrendingConfigsShown {
+			break
+		}
+		_, _ = fmt.Fprintf(out, "%d. %s\n", i+1, utils.FormatKeyValue(config.Name, config.Description))
+		details := []string{fmt.Sprintf("⭐ %d", config.Views)}
+		if config.Author != "" {
+			details = append(details, "by "+config.Author)
+		}
+		if config.URL != "" {
+			details = append(details, config.URL)
+		}
+		_, _ = fmt.Fprintf(out, "   %s\n", utils.FormatNote(strings.Join(details, " | ")))
+	}
+	_, _ = fmt.Fprintln(out)
+
+	if len(trends.TrendingTopics) > 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatSubsection("🏷️ Trending Topics", ""))
+		rows := make([][]string, 0, len(trends.TrendingTopics))
+		for _, topic := range trends.TrendingTopics {
+			rows = append(rows, []string{topic.Name, fmt.Sprintf("%d", topic.Count)})
+		}
+		_, _ = fmt.Fprintln(out, utils.FormatTable([]string{"Topic", "Repositories"}, rows))
+		_, _ = fmt.Fprintln(out)
+	}
+
+	if detailed {
+		_, _ = fmt.Fprintln(out, utils.FormatSubsection("📈 Community Statistics", ""))
+		_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Timeframe", trends.Timeframe))
+		if trends.Category != "" {
+			_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Category", trends.Category))
+		}
+		_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Updated Configurations", fmt.Sprintf("%d", trends.TotalConfigurations)))
+		_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Last Updated", trends.LastUpdated.Format("2006-01-02 15:04:05")))
+		_, _ = fmt.Fprintln(out)
+	}
+}
+
+// trendsSummaryPrompt asks the AI for a short summary of what the trending repositories and
+// topics say about the NixOS community
+func trendsSummaryPrompt(trends *community.TrendData) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("These NixOS configuration repositories were updated on GitHub in the %s timeframe and are the most starred of them", trends.Timeframe))
+	if trends.Category != "" {
+		b.WriteString(fmt.Sprintf(" about %q", trends.Category))
+	}
+	b.WriteString(":\n")
+	for i, config := range trends.TrendingConfigs {
+		if i >= maxTrendingConfigsShown {
+			break
+		}
+		b.WriteString(fmt.Sprintf("- %s (%d stars): %s", config.Name, config.Views, config.Description))
+		if len(config.Tags) > 0 {
+			b.WriteString(" [" + strings.Join(config.Tags, ", ") + "]")
+		}
+		b.WriteString("\n")
+	}
+	if len(trends.TrendingTopics) > 0 {
+		b.WriteString("\nMost common topics:\n")
+		for _, topic := range trends.TrendingTopics {
+			b.WriteString(fmt.Sprintf("- %s (%d repositories)\n", topic.Name, topic.Count))
+		}
+	}
+	b.WriteString("\nIn 3 to 5 short bullet points, summarize the notable trends: tools, frameworks or setups that " +
+		"are gaining attention and what they suggest for someone maintaining a NixOS configuration. " +
+		"Only use the data above; do not invent repositories or numbers.")
+	return b.String()
+}
+
+// summarizeTrends prints the AI summary of the trends
+func summarizeTrends(out io.Writer, trends *community.TrendData, query func(string) (string, error)) error {
+	spinner := utils.NewSpinner(out, "Asking the AI to summarize the trends...", 0).Start()
+	summary, err := query(trendsSummaryPrompt(trends))
+	spinner.Stop()
+	if err != nil {
+		return fmt.Errorf("AI summary failed: %w", err)
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatSubsection("🤖 Notable Trends", ""))
+	_, _ = fmt.Fprintln(out, utils.RenderMarkdown(summary))
+	return nil
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"nix-ai-help/internal/community"
+)
+
+// trendsFixture is a day of trends with two configurations and their topics
+func trendsFixture() *community.TrendData {
+	return &community.TrendData{
+		Timeframe: "weekly",
+		TrendingConfigs: []community.Configuration{
+			{Name: "dotfiles", Description: "Hyprland on NixOS", Author: "alice", Views: 900, Tags: []string{"hyprland"}, URL: "https://github.com/alice/dotfiles"},
+			{Name: "nix-config", Description: "Servers with disko", Author: "bob", Views: 500},
+		},
+		TrendingTopics:      []community.TopicStats{{Name: "hyprland", Count: 2}, {Name: "disko", Count: 1}},
+		TotalConfigurations: 42,
+		LastUpdated:         time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestRenderTrends(t *testing.T) {
+	var out bytes.Buffer
+	renderTrends(&out, trendsFixture(), true)
+
+	for _, want := range []string{"dotfiles", "Hyprland on NixOS", "⭐ 900", "by alice", "https://github.com/alice/dotfiles", "hyprland", "disko", "42"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in output:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestSummarizeTrends(t *testing.T) {
+	var prompt string
+	var out bytes.Buffer
+	err := summarizeTrends(&out, trendsFixture(), func(p string) (string, error) {
+		prompt = p
+		return "- Hyprland keeps growing", nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"weekly", "dotfiles (900 stars): Hyprland on NixOS [hyprland]", "hyprland (2 repositories)"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("expected %q in prompt:\n%s", want, prompt)
+		}
+	}
+	if !strings.Contains(out.String(), "Hyprland keeps growing") {
+		t.Errorf("expected the summary in output:\n%s", out.String())
+	}
+
+	err = summarizeTrends(&out, trendsFixture(), func(string) (string, error) { return "", errors.New("offline") })
+	if err == nil || !strings.Contains(err.Error(), "offline") {
+		t.Errorf("expected the AI error, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"nix-ai-help/internal/ai"
+	"nix-ai-help/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+// ollamaTagsTimeout bounds the live Ollama lookup so completion never hangs the shell
+const ollamaTagsTimeout = 500 * time.Millisecond
+
+// listOllamaModels returns the models installed on the Ollama server; replaced in tests
+var listOllamaModels = func(baseURL string) ([]string, error) {
+	provider := ai.NewOllamaProvider("")
+	if baseURL != "" {
+		provider.Endpoint = strings.TrimRight(baseURL, "/") + "/api/generate"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ollamaTagsTimeout)
+	defer cancel()
+	return provider.ListModels(ctx)
+}
+
+// registerFlagCompletions adds dynamic shell completion for the --provider and --model flags
+func registerFlagCompletions(root *cobra.Command) {
+	_ = root.RegisterFlagCompletionFunc("provider", completeProviderFlag)
+	_ = root.RegisterFlagCompletionFunc("model", completeModelFlag)
+}
+
+func completeProviderFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := config.LoadUserConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return providerCompletions(cfg, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeModelFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := config.LoadUserConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	provider := ""
+	if flag := cmd.Flag("provider"); flag != nil {
+		provider = flag.Value.String()
+	}
+	return modelCompletions(cfg, completionProvider(cfg, provider), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completionProvider picks the provider whose models are completed: the --provider value,
+// then the configured provider, then ollama
+func completionProvider(cfg *config.UserConfig, flagValue string) string {
+	switch {
+	case flagValue != "":
+		return flagValue
+	case cfg.AIProvider != "":
+		return cfg.AIProvider
+	case cfg.AIModels.SelectionPreferences.DefaultProvider != "":
+		return cfg.AIModels.SelectionPreferences.DefaultProvider
+	default:
+		return "ollama"
+	}
+}
+
+// providerCompletions returns the configured providers starting with toComplete
+func providerCompletions(cfg *config.UserConfig, toComplete string) []string {
+	return filterCompletions(config.NewModelRegistry(cfg).GetAvailableProviders(), toComplete)
+}
+
+// modelCompletions returns the models of a provider starting with toComplete. For Ollama
+// the models installed on the server are included as well.
+func modelCompletions(cfg *config.UserConfig, provider, toComplete string) []string {
+	registry := config.NewModelRegistry(cfg)
+	models, _ := registry.GetAvailableModels(provider)
+
+	if provider == "ollama" {
+		baseURL := ""
+		if providerCfg, err := registry.GetProvider("ollama"); err == nil {
+			baseURL = providerCfg.BaseURL
+		}
+		if installed, err := listOllamaModels(baseURL); err == nil {
+			models = append(models, installed...)
+		}
+	}
+
+	return filterCompletions(models, toComplete)
+}
+
+// filterCompletions returns the sorted, de-duplicated candidates starting with prefix
+func filterCompletions(candidates []string, prefix string) []string {
+	seen := make(map[string]bool)
+	var matches []string
+	for _, c := range candidates {
+		if seen[c] || !strings.HasPrefix(c, prefix) {
+			continue
+		}
+		seen[c] = true
+		matches = append(matches, c)
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// printModelList writes every known provider:model pair, one per line, for `completion --model-list`
+func printModelList(out io.Writer, cfg *config.UserConfig) {
+	for _, provider := range providerCompletions(cfg, "") {
+		for _, model := range modelCompletions(cfg, provider, "") {
+			_, _ = fmt.Fprintf(out, "%s:%s\n", provider, model)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/config"
+)
+
+func completionTestConfig() *config.UserConfig {
+	return &config.UserConfig{
+		AIModels: config.AIModelsConfig{
+			Providers: map[string]config.AIProviderConfig{
+				"ollama": {Models: map[string]config.AIModelConfig{"llama3": {}}},
+				"openai": {Models: map[string]config.AIModelConfig{"gpt-4": {}, "gpt-3.5-turbo": {}}},
+				"gemini": {Models: map[string]config.AIModelConfig{"gemini-1.5-pro": {}}},
+			},
+		},
+	}
+}
+
+func stubOllamaModels(t *testing.T, models []string) {
+	original := listOllamaModels
+	listOllamaModels = func(baseURL string) ([]string, error) { return models, nil }
+	t.Cleanup(func() { listOllamaModels = original })
+}
+
+func TestProviderCompletions(t *testing.T) {
+	cfg := completionTestConfig()
+
+	if got := providerCompletions(cfg, ""); !reflect.DeepEqual(got, []string{"gemini", "ollama", "openai"}) {
+		t.Errorf("unexpected providers: %v", got)
+	}
+	if got := providerCompletions(cfg, "o"); !reflect.DeepEqual(got, []string{"ollama", "openai"}) {
+		t.Errorf("unexpected providers for prefix 'o': %v", got)
+	}
+}
+
+func TestModelCompletions(t *testing.T) {
+	stubOllamaModels(t, []string{"codellama:7b", "llama3"})
+	cfg := completionTestConfig()
+
+	if got := modelCompletions(cfg, "openai", "gpt-4"); !reflect.DeepEqual(got, []string{"gpt-4"}) {
+		t.Errorf("unexpected openai models: %v", got)
+	}
+	// Ollama combines configured and installed models without duplicates
+	if got := modelCompletions(cfg, "ollama", ""); !reflect.DeepEqual(got, []string{"codellama:7b", "llama3"}) {
+		t.Errorf("unexpected ollama models: %v", got)
+	}
+	if got := modelCompletions(cfg, "unknown", ""); len(got) != 0 {
+		t.Errorf("expected no models for an unknown provider, got %v", got)
+	}
+}
+
+func TestCompletionProvider(t *testing.T) {
+	cfg := completionTestConfig()
+	if got := completionProvider(cfg, "gemini"); got != "gemini" {
+		t.Errorf("expected flag value to win, got %q", got)
+	}
+	if got := completionProvider(cfg, ""); got != "ollama" {
+		t.Errorf("expected ollama fallback, got %q", got)
+	}
+	cfg.AIProvider = "openai"
+	if got := completionProvider(cfg, ""); got != "openai" {
+		t.Errorf("expected configured provider, got %q", got)
+	}
+}
+
+func TestPrintModelList(t *testing.T) {
+	stubOllamaModels(t, nil)
+
+	var out bytes.Buffer
+	printModelList(&out, completionTestConfig())
+	for _, want := range []string{"gemini:gemini-1.5-pro", "ollama:llama3", "openai:gpt-4"} {
+		if !strings.Contains(out.String(), want+"\n") {
+			t.Errorf("expected model list to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/utils"
+)
+
+// currentSystemConfig is the copy of configuration.nix kept by system.copySystemConfiguration
+const currentSystemConfig = "/run/current-system/configuration.nix"
+
+// nixDelimiters are the characters that always form a token of their own
+const nixDelimiters = "{}[]();=,:?@"
+
+// lexNix splits a Nix source into tokens, dropping comments. Strings, including quoted
+// attribute names such as fileSystems."/", stay part of the surrounding word.
+func lexNix(src string) []string {
+	var tokens []string
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 4
+		case c == '=' && i+1 < len(src) && src[i+1] == '=':
+			tokens = append(tokens, "==")
+			i += 2
+		case strings.IndexByte(nixDelimiters, c) >= 0:
+			tokens = append(tokens, string(c))
+			i++
+		default:
+			start := i
+			i = readNixWord(src, i)
+			tokens = append(tokens, src[start:i])
+		}
+	}
+	return tokens
+}
+
+// readNixWord returns the end of the word starting at i, skipping over embedded strings
+func readNixWord(src string, i int) int {
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == '"':
+			i = skipNixString(src, i+1, "\"")
+		case strings.HasPrefix(src[i:], "''"):
+			i = skipNixString(src, i+2, "''")
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '#' || strings.IndexByte(nixDelimiters, c) >= 0:
+			return i
+		default:
+			i++
+		}
+	}
+	return i
+}
+
+// skipNixString returns the position after the closing quote of a string, including any
+// ${...} interpolations it contains
+func skipNixString(src string, i int, quote string) int {
+	for i < len(src) {
+		switch {
+		case quote == "\"" && src[i] == '\\':
+			i += 2
+		case quote == "''" && (strings.HasPrefix(src[i:], "'''") || strings.HasPrefix(src[i:], "''$")):
+			i += 3
+		case strings.HasPrefix(src[i:], quote):
+			return i + len(quote)
+		case strings.HasPrefix(src[i:], "${"):
+			depth := 0
+			for i < len(src) {
+				if src[i] == '{' {
+					depth++
+				} else if src[i] == '}' {
+					depth--
+					if depth == 0 {
+						i++
+						break
+					}
+				}
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	return i
+}
+
+// isAttrPath reports whether a token can be the left-hand side of an attribute binding
+func isAttrPath(token string) bool {
+	c := token[0]
+	return c == '_' || c == '"' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// nixFrame is an open attribute set, let block or other brace while extracting options
+type nixFrame struct {
+	path     string
+	named    bool // opened by `path = {`
+	ignore   bool // inside a let block, whose bindings are not options
+	isLet    bool
+	children bool
+}
+
+// extractNixOptions returns the sorted option paths set in a Nix configuration, e.g.
+// services.nginx.enable. Nested attribute sets are flattened into full paths; values that
+// are not plain attribute sets (lists, mkIf, mkForce ...) count as a single option.
+func extractNixOptions(src string) []string {
+	values := extractNixOptionValues(src)
+	options := make([]string, 0, len(values))
+	for path := range values {
+		options = append(options, path)
+	}
+	sort.Strings(options)
+	return options
+}
+
+// extractNixOptionValues maps the option paths set in a Nix configuration, flattened as in
+// extractNixOptions, to their values as space-separated tokens, e.g. `[ "nvidia" ]`. Empty
+// attribute sets have the value "{ }"; when an option is set twice the last value wins.
+func extractNixOptionValues(src string) map[string]string {
+	tokens := lexNix(src)
+	stack := []*nixFrame{{}}
+	values := make(map[string]string)
+
+	for i := 0; i < len(tokens); i++ {
+		top := stack[len(stack)-1]
+		switch tok := tokens[i]; {
+		case tok == "let":
+			stack = append(stack, &nixFrame{path: top.path, ignore: true, isLet: true})
+		case tok == "in":
+			if top.isLet {
+				stack = stack[:len(stack)-1]
+			}
+		case tok == "{":
+			stack = append(stack, &nixFrame{path: top.path, ignore: top.ignore})
+		case tok == "}":
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+				if top.named && !top.children && !top.ignore {
+					values[top.path] = "{ }"
+				}
+			}
+		case tok == "inherit":
+			for i < len(tokens) && tokens[i] != ";" {
+				i++
+			}
+		case i+1 < len(tokens) && tokens[i+1] == "=" && isAttrPath(tok):
+			path := tok
+			if top.path != "" {
+				path = top.path + "." + tok
+			}
+			top.children = true
+			next := i + 2
+			if next < len(tokens) && tokens[next] == "rec" {
+				next++
+			}
+			if next < len(tokens) && tokens[next] == "{" {
+				stack = append(stack, &nixFrame{path: path, named: true, ignore: top.ignore})
+				i = next
+				continue
+			}
+			end := skipNixValue(tokens, i+2)
+			if !top.ignore {
+				// end is the closing semicolon, or the last value token when the set closed without one
+				valueEnd := min(end, len(tokens))
+				if valueEnd < len(tokens) && tokens[valueEnd] != ";" {
+					valueEnd++
+				}
+				values[path] = strings.Join(tokens[i+2:valueEnd], " ")
+			}
+			i = end
+		}
+	}
+	return values
+}
+
+// skipNixValue returns the index of the semicolon ending the value that starts at i.
+// Semicolons closing a with/assert or a let binding inside the value are skipped.
+func skipNixValue(tokens []string, i int) int {
+	depth, withs, lets := 0, 0, 0
+	for ; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "{", "[", "(":
+			depth++
+		case "}", "]", ")":
+			depth--
+			if depth < 0 {
+				// The enclosing set closed without a trailing semicolon
+				return i - 1
+			}
+		case "with", "assert":
+			if depth == 0 {
+				withs++
+			}
+		case "let":
+			if depth == 0 {
+				lets++
+			}
+		case "in":
+			if depth == 0 && lets > 0 {
+				lets--
+			}
+		case ";":
+			if depth > 0 {
+				continue
+			}
+			switch {
+			case withs > 0:
+				withs--
+			case lets == 0:
+				return i
+			}
+		}
+	}
+	return i
+}
+
+// droppedOptions returns the options set in before that after no longer sets. An option still
+// counts as present when after sets a parent or child path of it.
+func droppedOptions(before, after string) []string {
+	afterOptions := extractNixOptions(after)
+	present := make(map[string]bool, len(afterOptions))
+	for _, option := range afterOptions {
+		present[option] = true
+	}
+
+	var dropped []string
+	for _, option := range extractNixOptions(before) {
+		if present[option] || optionCovered(option, afterOptions) {
+			continue
+		}
+		dropped = append(dropped, option)
+	}
+	return dropped
+}
+
+// optionCovered reports whether one of options is a parent or child path of option
+func optionCovered(option string, options []string) bool {
+	for _, other := range options {
+		if strings.HasPrefix(option, other+".") || strings.HasPrefix(other, option+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectDuplicateOptions returns the option paths that incoming sets although existing already
+// sets them, which Nix rejects with "attribute ... already defined" once both are in one file.
+// A path also collides when the other configuration assigns a parent or child of it, e.g.
+// services.nginx = mkIf ... next to services.nginx.enable.
+func DetectDuplicateOptions(existing, incoming string) []string {
+	existingOptions := extractNixOptions(existing)
+	defined := make(map[string]bool, len(existingOptions))
+	for _, option := range existingOptions {
+		defined[option] = true
+	}
+
+	var duplicates []string
+	for _, option := range extractNixOptions(incoming) {
+		if defined[option] || optionCovered(option, existingOptions) {
+			duplicates = append(duplicates, option)
+		}
+	}
+	return duplicates
+}
+
+// errDuplicateOptions is returned by checkDuplicateOptions when a write is refused
+var errDuplicateOptions = errors.New("the change would define options that are already defined")
+
+// checkDuplicateOptions warns about the options incoming would define a second time in
+// existing and refuses the write unless force is set
+func checkDuplicateOptions(out io.Writer, existing, incoming string, force bool) error {
+	duplicates := DetectDuplicateOptions(existing, incoming)
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	_, _ = fmt.Fprintln(out, utils.FormatWarning(fmt.Sprintf("%d option(s) would be defined twice:", len(duplicates))))
+	for _, option := range duplicates {
+		_, _ = fmt.Fprintln(out, "  - "+option)
+	}
+	if !force {
+		_, _ = fmt.Fprintln(out, utils.FormatTip("Remove them from one side, or pass --force to write anyway"))
+		return fmt.Errorf("%w: %s", errDuplicateOptions, strings.Join(duplicates, ", "))
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatNote("Writing anyway because of --force; fix the duplicates before rebuilding"))
+	return nil
+}
+
+// activeConfigurationPath resolves the --since-generation value to the configuration to diff
+// against. "current" uses the running generation's copy when present, then the detected
+// configuration.nix.
+func activeConfigurationPath(since string, nixosCtx *config.NixOSContext) string {
+	if since != "current" {
+		return since
+	}
+	if _, err := os.Stat(currentSystemConfig); err == nil {
+		return currentSystemConfig
+	}
+	if nixosCtx != nil && nixosCtx.ConfigurationNix != "" {
+		return nixosCtx.ConfigurationNix
+	}
+	return "/etc/nixos/configuration.nix"
+}
+
+// reportDroppedOptions prints the options the generated configuration drops compared with
+// the configuration at path and returns them
+func reportDroppedOptions(out io.Writer, path, generated string) ([]string, error) {
+	// #nosec G304 -- path is the user's own configuration
+	previous, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dropped := droppedOptions(string(previous), generated)
+	if len(dropped) == 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatSuccess("No options from "+path+" are missing in the generated configuration"))
+		return nil, nil
+	}
+
+	_, _ = fmt.Fprintln(out, utils.FormatWarning(fmt.Sprintf("%d option(s) from %s are missing in the generated configuration:", len(dropped), path)))
+	for _, option := range dropped {
+		_, _ = fmt.Fprintln(out, "  - "+option)
+	}
+	return dropped, nil
+}
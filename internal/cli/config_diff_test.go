@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const activeConfig = `{ config, pkgs, ... }:
+let
+  hostName = "laptop";
+in
+{
+  imports = [ ./hardware-configuration.nix ];
+
+  networking.hostName = hostName; # set from let
+  services.openssh = {
+    enable = true;
+    settings.PermitRootLogin = "no";
+  };
+  fileSystems."/".options = [ "noatime" ];
+  environment.systemPackages = with pkgs; [ vim git ];
+  users.users.alice = {
+    isNormalUser = true;
+    extraGroups = [ "wheel" ];
+  };
+  programs.zsh.enable = lib.mkIf true true;
+  system.stateVersion = "24.05";
+}
+`
+
+func TestExtractNixOptions(t *testing.T) {
+	got := extractNixOptions(activeConfig)
+	want := []string{
+		"environment.systemPackages",
+		`fileSystems."/".options`,
+		"imports",
+		"networking.hostName",
+		"programs.zsh.enable",
+		"services.openssh.enable",
+		"services.openssh.settings.PermitRootLogin",
+		"system.stateVersion",
+		"users.users.alice.extraGroups",
+		"users.users.alice.isNormalUser",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("extractNixOptions() =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestExtractNixOptions_StringsAndComments(t *testing.T) {
+	src := `{
+  # services.ignored.enable = true;
+  /* boot.ignored = 1; */
+  services.nginx.virtualHosts."example.org".extraConfig = ''
+    location / { return 200 "${toString 1}"; }
+  '';
+  time.timeZone = "Europe/Oslo";
+}`
+	got := extractNixOptions(src)
+	want := []string{`services.nginx.virtualHosts."example.org".extraConfig`, "time.timeZone"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("extractNixOptions() = %v, want %v", got, want)
+	}
+}
+
+func TestDroppedOptions(t *testing.T) {
+	generated := `{ pkgs, ... }:
+{
+  imports = [ ./hardware-configuration.nix ];
+  networking.hostName = "laptop";
+  services.openssh.enable = true;
+  fileSystems."/".options = [ "noatime" ];
+  environment.systemPackages = [ pkgs.vim ];
+  users.users.alice = lib.mkForce { isNormalUser = true; };
+  system.stateVersion = "24.05";
+}`
+	got := droppedOptions(activeConfig, generated)
+	want := []string{"programs.zsh.enable", "services.openssh.settings.PermitRootLogin"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("droppedOptions() = %v, want %v", got, want)
+	}
+
+	if dropped := droppedOptions(activeConfig, activeConfig); len(dropped) != 0 {
+		t.Errorf("expected nothing dropped against itself, got %v", dropped)
+	}
+}
+
+func TestReportDroppedOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "configuration.nix")
+	if err := os.WriteFile(path, []byte(activeConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	dropped, err := reportDroppedOptions(&out, path, "{ system.stateVersion = \"24.05\"; }")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(dropped) != 9 {
+		t.Errorf("expected 9 dropped options, got %v", dropped)
+	}
+	if !strings.Contains(out.String(), "services.openssh.enable") {
+		t.Errorf("output should list dropped options, got %q", out.String())
+	}
+
+	if _, err := reportDroppedOptions(&out, filepath.Join(t.TempDir(), "missing.nix"), ""); err == nil {
+		t.Error("expected an error for a missing configuration")
+	}
+}
+
+func TestDetectDuplicateOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		want     []string
+	}{
+		{
+			name:     "no overlap",
+			incoming: "services.nginx.enable = true;\nnetworking.firewall.allowedTCPPorts = [ 80 ];",
+		},
+		{
+			name:     "same dotted path",
+			incoming: "networking.hostName = \"server\";\nservices.nginx.enable = true;",
+			want:     []string{"networking.hostName"},
+		},
+		{
+			name: "nested set against dotted paths",
+			incoming: `{ ... }:
+{
+  services.openssh = {
+    enable = false;
+    ports = [ 2222 ];
+  };
+}`,
+			want: []string{"services.openssh.enable"},
+		},
+		{
+			name:     "value replacing an attribute set",
+			incoming: "users.users.alice = lib.mkForce { isNormalUser = false; };",
+			want:     []string{"users.users.alice"},
+		},
+		{
+			name:     "child of a non-literal value",
+			incoming: "programs.zsh.enable.default = true;\nprograms.zsh.ohMyZsh.enable = true;",
+			want:     []string{"programs.zsh.enable.default"},
+		},
+		{
+			name:     "let bindings and comments are not options",
+			incoming: "let networking = 1; in {\n  # networking.hostName = \"x\";\n  hardware.bluetooth.enable = true;\n}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectDuplicateOptions(activeConfig, tt.incoming); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DetectDuplicateOptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDuplicateOptions(t *testing.T) {
+	var out bytes.Buffer
+	err := checkDuplicateOptions(&out, activeConfig, "system.stateVersion = \"25.05\";", false)
+	if !errors.Is(err, errDuplicateOptions) {
+		t.Fatalf("expected errDuplicateOptions, got %v", err)
+	}
+	if !strings.Contains(out.String(), "system.stateVersion") || !strings.Contains(out.String(), "--force") {
+		t.Errorf("unexpected warning:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := checkDuplicateOptions(&out, activeConfig, "system.stateVersion = \"25.05\";", true); err != nil {
+		t.Errorf("--force should allow the write, got %v", err)
+	}
+	if err := checkDuplicateOptions(&out, activeConfig, "services.nginx.enable = true;", false); err != nil {
+		t.Errorf("no duplicates should not fail, got %v", err)
+	}
+}
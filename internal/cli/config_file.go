@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/utils"
+)
+
+// configFile is the --config flag: an alternate config file used instead of
+// ~/.config/nixai/config.yaml
+var configFile string
+
+// applyConfigFile points all config loads and saves of the process at the --config file
+func applyConfigFile() error {
+	if configFile == "" {
+		return nil
+	}
+	return config.SetConfigFilePath(utils.ExpandHome(configFile))
+}
+
+// configFileArgs passes --config on to the nixai processes this one starts, such as the MCP
+// server daemon, so that they use the same config file
+func configFileArgs() []string {
+	if configFile == "" {
+		return nil
+	}
+	path, err := config.ConfigFilePath()
+	if err != nil {
+		return nil
+	}
+	return []string{"--config", path}
+}
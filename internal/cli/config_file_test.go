@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/config"
+)
+
+// useConfigFile points the process at a config file written with content, like --config
+func useConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "nixai.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	configFile = path
+	t.Cleanup(func() {
+		configFile = ""
+		_ = config.SetConfigFilePath("")
+	})
+	if err := applyConfigFile(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigFlagCommandsReadAlternatePath(t *testing.T) {
+	path := useConfigFile(t, "ai_provider: ollama\nai_model: qwen2.5-coder\nlog_level: warn\n")
+
+	var out bytes.Buffer
+	getConfigWithOutput(&out, "ai_model")
+	if !strings.Contains(out.String(), "qwen2.5-coder") {
+		t.Errorf("expected config get to read %s, got:\n%s", path, out.String())
+	}
+
+	out.Reset()
+	setConfigWithOutput(&out, "ai_model", "llama3.1")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "ai_model: llama3.1") {
+		t.Errorf("expected config set to write %s, got:\n%s", path, data)
+	}
+}
+
+func TestConfigFileArgs(t *testing.T) {
+	if args := configFileArgs(); args != nil {
+		t.Errorf("expected no arguments without --config, got %v", args)
+	}
+	path := useConfigFile(t, "ai_provider: ollama\n")
+	if args := configFileArgs(); !reflect.DeepEqual(args, []string{"--config", path}) {
+		t.Errorf("expected the daemon to get --config %s, got %v", path, args)
+	}
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/utils"
+)
+
+// showConfigHistory lists the config backups kept by config set and config reset
+func showConfigHistory(out io.Writer) error {
+	backups, err := config.ListConfigBackups()
+	if err != nil {
+		return fmt.Errorf("failed to list config backups: %w", err)
+	}
+
+	_, _ = fmt.Fprintln(out, utils.FormatHeader("🕘 Configuration History"))
+	_, _ = fmt.Fprintln(out)
+	if len(backups) == 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatInfo("No configuration backups yet; one is kept each time the config changes"))
+		return nil
+	}
+	for _, backup := range backups {
+		_, _ = fmt.Fprintln(out, utils.FormatKeyValue(backup.Timestamp, backup.Time.Format("2006-01-02 15:04:05")))
+	}
+	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintln(out, utils.FormatTip("Use 'nixai config restore <timestamp>' to bring a previous version back"))
+	return nil
+}
+
+// restoreConfig restores the config backup with the given timestamp
+func restoreConfig(out io.Writer, timestamp string) error {
+	if err := config.RestoreConfigBackup(timestamp); err != nil {
+		return err
+	}
+	ResetProviderManager()
+
+	_, _ = fmt.Fprintln(out, utils.FormatSuccess("✅ Configuration restored from "+timestamp))
+	_, _ = fmt.Fprintln(out, utils.FormatTip("The replaced configuration was backed up too; see 'nixai config history'"))
+	return nil
+}
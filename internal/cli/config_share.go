@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"nix-ai-help/internal/ai"
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/utils"
+)
+
+// configIncludeSecrets is the --include-secrets flag of config export
+var configIncludeSecrets bool
+
+// exportConfig writes the user config as YAML for teammates to import, with its secrets
+// redacted unless includeSecrets is set
+func exportConfig(out io.Writer, includeSecrets bool) error {
+	cfg, err := config.LoadUserConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	data, err := config.ExportUserConfig(cfg, includeSecrets)
+	if err != nil {
+		return fmt.Errorf("failed to export config: %w", err)
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+// importConfig merges an exported config file into the user config after validating it; the
+// replaced config is kept as a backup like with config set
+func importConfig(out io.Writer, path string) error {
+	// #nosec G304 -- path is the file the user asked to import
+	data, err := os.ReadFile(utils.ExpandHome(path))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	current, err := config.LoadUserConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	imported, err := config.ImportUserConfig(current, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := validateUserConfig(imported); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := config.SaveUserConfig(imported); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	ResetProviderManager()
+
+	_, _ = fmt.Fprintln(out, utils.FormatSuccess("Configuration imported from "+path))
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("AI Provider", imported.AIProvider))
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("AI Model", imported.AIModel))
+	if envVar, missing := imported.MissingAPIKey(); missing {
+		_, _ = fmt.Fprintln(out, utils.FormatWarning(fmt.Sprintf("Set %s to use %s; API keys are never part of an export", envVar, imported.AIProvider)))
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatTip("A replaced configuration is kept as a backup; see 'nixai config history'"))
+	return nil
+}
+
+// validateUserConfig checks the settings config set validates, so that an import cannot save
+// a value config set would refuse
+func validateUserConfig(cfg *config.UserConfig) error {
+	var problems []error
+	if cfg.AIProvider != "" {
+		providers := config.NewModelRegistry(cfg).GetAvailableProviders()
+		if len(providers) > 0 && !containsString(providers, cfg.AIProvider) {
+			problems = append(problems, fmt.Errorf("unknown ai_provider %q (valid: %s)", cfg.AIProvider, strings.Join(providers, ", ")))
+		}
+	}
+	if cfg.LogLevel != "" && !containsString([]string{"debug", "info", "warn", "error"}, cfg.LogLevel) {
+		problems = append(problems, fmt.Errorf("invalid log_level %q (valid: debug, info, warn, error)", cfg.LogLevel))
+	}
+	if cfg.Output.DefaultFormat != "" && !validOutputFormat(cfg.Output.DefaultFormat) {
+		problems = append(problems, fmt.Errorf("invalid output.default_format %q (valid: %s)", cfg.Output.DefaultFormat, strings.Join(outputFormats, ", ")))
+	}
+	if cfg.Network.Proxy != "" {
+		if _, err := utils.ParseProxyURL(cfg.Network.Proxy); err != nil {
+			problems = append(problems, fmt.Errorf("network.proxy: %w", err))
+		}
+	}
+	if cfg.MCPServer.Port < 0 || cfg.MCPServer.Port > 65535 {
+		problems = append(problems, fmt.Errorf("invalid mcp_server.port %d", cfg.MCPServer.Port))
+	}
+	if cfg.PackageRepo.Timeout < 0 {
+		problems = append(problems, fmt.Errorf("invalid package_repo.timeout %d (seconds, 0 for the default)", cfg.PackageRepo.Timeout))
+	}
+	for command, tier := range cfg.AIModels.Tiers.Commands {
+		if !containsString([]string{string(ai.TierFast), string(ai.TierStrong), string(ai.TierDefault)}, tier) {
+			problems = append(problems, fmt.Errorf("invalid ai_models.tiers.commands.%s %q (valid: fast, strong, default)", command, tier))
+		}
+	}
+	problems = append(problems, validateSampling("ai_sampling", cfg.AISampling)...)
+	for command, sampling := range cfg.AISampling.Commands {
+		problems = append(problems, validateSampling("ai_sampling.commands."+command, sampling)...)
+	}
+	return errors.Join(problems...)
+}
+
+// validateSampling checks that a temperature and top_p are within the ranges of their flags
+func validateSampling(prefix string, sampling config.AISamplingConfig) []error {
+	var problems []error
+	for _, setting := range []struct {
+		name  string
+		value *float64
+		max   float64
+	}{
+		{"temperature", sampling.Temperature, temperatureFlag.max},
+		{"top_p", sampling.TopP, topPFlag.max},
+	} {
+		if setting.value != nil && (*setting.value < 0 || *setting.value > setting.max) {
+			problems = append(problems, fmt.Errorf("%s.%s must be between 0 and %g", prefix, setting.name, setting.max))
+		}
+	}
+	return problems
+}
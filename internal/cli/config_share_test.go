@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/config"
+)
+
+func TestValidateUserConfig(t *testing.T) {
+	if err := validateUserConfig(config.DefaultUserConfig()); err != nil {
+		t.Fatalf("the default config should be valid, got %v", err)
+	}
+
+	tooHot := 3.0
+	cfg := config.DefaultUserConfig()
+	cfg.AIProvider = "skynet"
+	cfg.LogLevel = "loud"
+	cfg.Output.DefaultFormat = "html"
+	cfg.Network.Proxy = "ftp://proxy.example.com"
+	cfg.AISampling.Commands = map[string]config.AISamplingConfig{"ask": {Temperature: &tooHot}}
+	cfg.AIModels.Tiers.Commands = map[string]string{"diagnose": "huge"}
+	cfg.PackageRepo.Timeout = -5
+
+	err := validateUserConfig(cfg)
+	if err == nil {
+		t.Fatal("expected the invalid settings to be reported")
+	}
+	for _, want := range []string{`ai_provider "skynet"`, `log_level "loud"`, `output.default_format "html"`, "network.proxy", "ai_sampling.commands.ask.temperature", `ai_models.tiers.commands.diagnose "huge"`, "package_repo.timeout -5"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %q", want, err.Error())
+		}
+	}
+}
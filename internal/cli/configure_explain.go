@@ -0,0 +1,452 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	nixoscontext "nix-ai-help/internal/ai/context"
+	"nix-ai-help/internal/config"
+	"nix-ai-help/internal/nixos"
+	"nix-ai-help/pkg/logger"
+	"nix-ai-help/pkg/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultContextWindow is the context window, in tokens, assumed when the model's is unknown
+const defaultContextWindow = 8192
+
+// configImportDepth bounds how deep the imports of an explained configuration are followed
+const configImportDepth = 10
+
+// charsPerToken approximates how many characters of Nix source fit in one token
+const charsPerToken = 4
+
+// notableConfigOptions are options shown in the config overview when they are set
+var notableConfigOptions = []string{
+	"networking.hostName",
+	"system.stateVersion",
+	"time.timeZone",
+	"i18n.defaultLocale",
+	"boot.loader.systemd-boot.enable",
+	"boot.loader.grub.enable",
+	"boot.kernelPackages",
+	"networking.networkmanager.enable",
+	"networking.firewall.enable",
+	"networking.firewall.allowedTCPPorts",
+	"nixpkgs.config.allowUnfree",
+	"nix.settings.experimental-features",
+	"nix.gc.automatic",
+	"system.autoUpgrade.enable",
+	"hardware.nvidia.open",
+	"virtualisation.docker.enable",
+	"virtualisation.libvirtd.enable",
+}
+
+// secretAssignmentPattern matches a string assigned to an attribute whose name mentions a
+// password, secret or token, such as users.users.alice.hashedPassword = "$6$...";
+var secretAssignmentPattern = regexp.MustCompile(`(?i)(\b[\w-]*(?:password|secret|token)[\w-]*"?\s*=\s*)("(?:[^"\\]|\\.)*"|''(?s:.*?)'')`)
+
+// redactedConfigValue replaces the secrets of a configuration before it is sent to the AI
+const redactedConfigValue = `"<redacted>"`
+
+// configSetting is an option set in the configuration with its value
+type configSetting struct {
+	Option string
+	Value  string
+}
+
+// configSummary is what the configuration sets up, read without evaluating it
+type configSummary struct {
+	Files    []string
+	Services []string
+	Programs []string
+	Users    []string
+	Notable  []configSetting
+	Findings []string
+}
+
+// newConfigureExplainCmd creates the configure explain command
+func newConfigureExplainCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "explain [path]",
+		Short: "Explain what your whole NixOS configuration does",
+		Long: `Read your configuration.nix or flake and the files it imports, list the enabled
+services, users and notable options, flag unusual settings, and ask the AI for a structured
+overview of what the system does. Large configurations are explained part by part to fit
+the context window of the model. The values of passwords, secrets and tokens are redacted
+before the configuration is sent to the AI.
+
+Examples:
+  nixai configure explain
+  nixai configure explain /etc/nixos
+  nixai configure explain ~/nixos-config/flake.nix
+  nixai configure explain --no-ai`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			cfg, err := config.LoadUserConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %v", err)
+			}
+			nixosCtx, err := nixos.NewContextDetector(logger.NewLogger()).GetContext(cfg)
+			if err != nil {
+				_, _ = fmt.Fprintln(out, utils.FormatWarning("Failed to detect NixOS context: "+err.Error()))
+				nixosCtx = nil
+			}
+
+			var path string
+			if len(args) > 0 {
+				path = args[0]
+			}
+			root, err := configExplainRoot(path, nixosCtx, cfg)
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(out, utils.FormatHeader("📖 Explaining "+root))
+			_, _ = fmt.Fprintln(out)
+
+			// Without AI only the parsed overview is shown
+			var query func(string) (string, error)
+			aiProvider, err := optionalAIProvider(cfg, logger.NewLogger())
+			switch {
+			case err == nil:
+				contextBuilder := nixoscontext.NewNixOSContextBuilder()
+				query = func(prompt string) (string, error) {
+					return aiProvider.Query(withLanguageInstruction(contextBuilder.BuildContextualPrompt(prompt, nixosCtx), cfg))
+				}
+			case !errors.Is(err, errAIDisabled):
+				return fmt.Errorf("failed to initialize AI provider: %v", err)
+			}
+			return explainConfig(out, readConfigTree(root), query, contextWindowChars(cfg))
+		},
+	}
+}
+
+// configExplainRoot finds the configuration to explain: the given file or directory, else the
+// configuration.nix or flake.nix found by the context detector, else the configured NixOS folder
+func configExplainRoot(arg string, nixosCtx *config.NixOSContext, cfg *config.UserConfig) (string, error) {
+	var candidates []string
+	switch {
+	case arg != "":
+		candidates = []string{utils.ExpandHome(arg)}
+	case nixosCtx != nil && (nixosCtx.ConfigurationNix != "" || nixosCtx.FlakeFile != ""):
+		candidates = []string{nixosCtx.ConfigurationNix, nixosCtx.FlakeFile}
+	case cfg != nil && cfg.NixosFolder != "":
+		candidates = []string{utils.ExpandHome(cfg.NixosFolder)}
+	default:
+		candidates = []string{"/etc/nixos"}
+	}
+
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if !utils.IsDirectory(candidate) {
+			if utils.IsFile(candidate) {
+				return candidate, nil
+			}
+			continue
+		}
+		for _, name := range []string{"configuration.nix", "flake.nix", "default.nix"} {
+			if path := filepath.Join(candidate, name); utils.IsFile(path) {
+				return path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no NixOS configuration found at %s", strings.Join(candidates, " or "))
+}
+
+// readConfigTree reads the configuration at root and the files it imports. A flake.nix lists
+// its modules outside of imports, so the configuration.nix next to it is followed as well.
+func readConfigTree(root string) *nixos.ImportTree {
+	tree := nixos.FollowImports(root, configImportDepth)
+	if filepath.Base(root) != "flake.nix" {
+		return tree
+	}
+	configuration := filepath.Join(filepath.Dir(root), "configuration.nix")
+	if !utils.IsFile(configuration) {
+		return tree
+	}
+	modules := nixos.FollowImports(configuration, configImportDepth)
+	for _, file := range modules.Files {
+		if _, seen := tree.Contents[file]; !seen {
+			tree.Files = append(tree.Files, file)
+			tree.Contents[file] = modules.Contents[file]
+		}
+	}
+	return tree
+}
+
+// summarizeNixConfig lists the services, programs, users and notable options set across the
+// files of a configuration and flags settings that weaken the system
+func summarizeNixConfig(tree *nixos.ImportTree) configSummary {
+	summary := configSummary{Files: tree.Files}
+	values := make(map[string]string)
+	for _, file := range tree.Files {
+		for option, value := range extractNixOptionValues(tree.Contents[file]) {
+			values[option] = value
+		}
+	}
+
+	users := make(map[string]bool)
+	for option, value := range values {
+		value = stripNixPriority(value)
+		switch {
+		case strings.HasPrefix(option, "services.") && strings.HasSuffix(option, ".enable") && value == "true":
+			summary.Services = append(summary.Services, strings.TrimSuffix(strings.TrimPrefix(option, "services."), ".enable"))
+		case strings.HasPrefix(option, "programs.") && strings.HasSuffix(option, ".enable") && value == "true":
+			summary.Programs = append(summary.Programs, strings.TrimSuffix(strings.TrimPrefix(option, "programs."), ".enable"))
+		case strings.HasPrefix(option, "users.users."):
+			name, _, _ := strings.Cut(strings.TrimPrefix(option, "users.users."), ".")
+			users[strings.Trim(name, `"`)] = true
+		}
+	}
+	for name := range users {
+		summary.Users = append(summary.Users, name)
+	}
+	sort.Strings(summary.Services)
+	sort.Strings(summary.Programs)
+	sort.Strings(summary.Users)
+
+	for _, option := range notableConfigOptions {
+		if value, ok := values[option]; ok {
+			summary.Notable = append(summary.Notable, configSetting{Option: option, Value: value})
+		}
+	}
+	summary.Findings = unusualConfigSettings(values)
+	return summary
+}
+
+// unusualConfigSettings flags settings that weaken the security or reproducibility of a system
+func unusualConfigSettings(values map[string]string) []string {
+	is := func(option string, want ...string) bool {
+		value := strings.Trim(stripNixPriority(values[option]), `"`)
+		return containsString(want, value)
+	}
+
+	var findings []string
+	if is("networking.firewall.enable", "false") {
+		findings = append(findings, "The firewall is disabled (networking.firewall.enable = false)")
+	}
+	if is("services.openssh.settings.PermitRootLogin", "yes") || is("services.openssh.permitRootLogin", "yes") {
+		findings = append(findings, "SSH allows root login with a password (PermitRootLogin = \"yes\")")
+	}
+	if is("services.openssh.settings.PasswordAuthentication", "true") || is("services.openssh.passwordAuthentication", "true") {
+		findings = append(findings, "SSH accepts password authentication; keys are safer")
+	}
+	if is("security.sudo.wheelNeedsPassword", "false") {
+		findings = append(findings, "Members of wheel can use sudo without a password")
+	}
+	if is("nix.settings.sandbox", "false") {
+		findings = append(findings, "The Nix build sandbox is disabled (nix.settings.sandbox = false)")
+	}
+	if strings.Contains(values["boot.kernelParams"], "mitigations=off") {
+		findings = append(findings, "CPU vulnerability mitigations are turned off (mitigations=off)")
+	}
+
+	var passwords []string
+	for option := range values {
+		if strings.HasPrefix(option, "users.users.") &&
+			(strings.HasSuffix(option, ".password") || strings.HasSuffix(option, ".initialPassword")) {
+			passwords = append(passwords, option)
+		}
+	}
+	sort.Strings(passwords)
+	for _, option := range passwords {
+		findings = append(findings, option+" keeps a plain-text password in the world-readable Nix store")
+	}
+	return findings
+}
+
+// contextWindowChars is how many characters of configuration to send per prompt: half of the
+// model's context window, leaving room for the instructions and the answer
+func contextWindowChars(cfg *config.UserConfig) int {
+	tokens := 0
+	if cfg != nil {
+		if model, err := config.NewModelRegistry(cfg).GetModel(cfg.AIProvider, cfg.AIModel); err == nil {
+			tokens = model.ContextWindow
+		}
+	}
+	return contextChars(tokens)
+}
+
+// redactConfigSecrets returns a copy of tree whose files have the values of passwords, secrets
+// and tokens replaced, so that they never reach the AI provider
+func redactConfigSecrets(tree *nixos.ImportTree) *nixos.ImportTree {
+	redacted := *tree
+	redacted.Contents = make(map[string]string, len(tree.Contents))
+	for file, content := range tree.Contents {
+		redacted.Contents[file] = secretAssignmentPattern.ReplaceAllString(content, "${1}"+redactedConfigValue)
+	}
+	return &redacted
+}
+
+// chunkConfigFiles splits the configuration files into chunks of at most maxChars characters,
+// keeping small files whole and splitting larger ones at line boundaries
+func chunkConfigFiles(tree *nixos.ImportTree, maxChars int) []string {
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+	}
+	add := func(section string) {
+		if current.Len()+len(section) > maxChars {
+			flush()
+		}
+		current.WriteString(section)
+	}
+
+	for _, file := range tree.Files {
+		content := tree.Contents[file]
+		section := "# File: " + file + "\n" + content + "\n"
+		if len(section) <= maxChars {
+			add(section)
+			continue
+		}
+
+		part := 1
+		var b strings.Builder
+		header := func() string { return fmt.Sprintf("# File: %s (part %d)\n", file, part) }
+		b.WriteString(header())
+		for _, line := range strings.SplitAfter(content, "\n") {
+			if b.Len()+len(line) > maxChars && b.Len() > len(header()) {
+				add(b.String() + "\n")
+				part++
+				b.Reset()
+				b.WriteString(header())
+			}
+			b.WriteString(line)
+		}
+		add(b.String() + "\n")
+	}
+	flush()
+	return chunks
+}
+
+// configFactsText lists the parsed facts of a configuration for the prompts
+func configFactsText(summary configSummary) string {
+	var b strings.Builder
+	b.WriteString("Enabled services: " + listOrNone(summary.Services) + "\n")
+	b.WriteString("Enabled programs: " + listOrNone(summary.Programs) + "\n")
+	b.WriteString("Users: " + listOrNone(summary.Users) + "\n")
+	for _, setting := range summary.Notable {
+		b.WriteString(setting.Option + " = " + setting.Value + "\n")
+	}
+	for _, finding := range summary.Findings {
+		b.WriteString("Flagged: " + finding + "\n")
+	}
+	return b.String()
+}
+
+// listOrNone joins values for display, or returns "none"
+func listOrNone(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
+
+// configChunkPrompt asks for notes on one part of a configuration too large for a single prompt
+func configChunkPrompt(part, parts int, chunk string) string {
+	return fmt.Sprintf("You are a NixOS expert. This is part %d of %d of a NixOS configuration. "+
+		"List concisely what this part configures: services, users, hardware, networking, boot, desktop and packages, "+
+		"and anything unusual or risky. Do not summarize the whole system yet.\n\n%s", part, parts, chunk)
+}
+
+// configExplainPrompt asks for the structured overview of a configuration, given either its
+// source or the notes on each of its parts
+func configExplainPrompt(summary configSummary, material string, chunked bool) string {
+	var b strings.Builder
+	b.WriteString("You are a NixOS expert. Explain what this NixOS system does for its owner. Answer in Markdown with these sections:\n")
+	b.WriteString("## Purpose - one paragraph on what kind of machine this is\n")
+	b.WriteString("## Services - each enabled service and what it provides\n")
+	b.WriteString("## Users - the accounts and their privileges\n")
+	b.WriteString("## Notable settings - boot, networking, hardware, Nix settings worth knowing\n")
+	b.WriteString("## Unusual or risky - anything unusual, insecure or likely to break, with the fix\n\n")
+	b.WriteString("Facts parsed from the configuration:\n" + configFactsText(summary) + "\n")
+	if chunked {
+		b.WriteString("The configuration was too large for one request; these are notes on each part:\n\n" + material)
+	} else {
+		b.WriteString("Configuration:\n" + material)
+	}
+	return b.String()
+}
+
+// renderConfigSummary prints the parsed overview of a configuration
+func renderConfigSummary(out io.Writer, summary configSummary) {
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Files", fmt.Sprint(len(summary.Files))))
+	for _, file := range summary.Files {
+		_, _ = fmt.Fprintln(out, "  "+file)
+	}
+	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintln(out, utils.FormatSubsection(fmt.Sprintf("Services (%d)", len(summary.Services)), ""))
+	_, _ = fmt.Fprintln(out, "  "+listOrNone(summary.Services))
+	_, _ = fmt.Fprintln(out, utils.FormatSubsection(fmt.Sprintf("Programs (%d)", len(summary.Programs)), ""))
+	_, _ = fmt.Fprintln(out, "  "+listOrNone(summary.Programs))
+	_, _ = fmt.Fprintln(out, utils.FormatSubsection(fmt.Sprintf("Users (%d)", len(summary.Users)), ""))
+	_, _ = fmt.Fprintln(out, "  "+listOrNone(summary.Users))
+	if len(summary.Notable) > 0 {
+		rows := make([][]string, 0, len(summary.Notable))
+		for _, setting := range summary.Notable {
+			rows = append(rows, []string{setting.Option, setting.Value})
+		}
+		_, _ = fmt.Fprintln(out, utils.FormatSubsection("Notable settings", ""))
+		_, _ = fmt.Fprintln(out, utils.FormatTable([]string{"Option", "Value"}, rows))
+	}
+	if len(summary.Findings) > 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatSubsection("Unusual settings", ""))
+		for _, finding := range summary.Findings {
+			_, _ = fmt.Fprintln(out, utils.FormatWarning(finding))
+		}
+	}
+	_, _ = fmt.Fprintln(out)
+}
+
+// explainConfig prints the parsed overview of the configuration in tree and, when query is
+// set, the AI's structured explanation of the configuration with its secrets redacted. Configurations larger than maxChars are explained
+// part by part first and the notes combined into the final overview.
+func explainConfig(out io.Writer, tree *nixos.ImportTree, query func(string) (string, error), maxChars int) error {
+	if len(tree.Files) == 0 {
+		return fmt.Errorf("no readable configuration files")
+	}
+	summary := summarizeNixConfig(tree)
+	renderConfigSummary(out, summary)
+	if query == nil {
+		return nil
+	}
+
+	chunks := chunkConfigFiles(redactConfigSecrets(tree), maxChars)
+	prompt := configExplainPrompt(summary, strings.Join(chunks, ""), false)
+	if len(chunks) > 1 {
+		var notes strings.Builder
+		for i, chunk := range chunks {
+			spinner := utils.NewSpinner(out, fmt.Sprintf("Reading part %d/%d of the configuration...", i+1, len(chunks)), 0).Start()
+			note, err := query(configChunkPrompt(i+1, len(chunks), chunk))
+			spinner.Stop()
+			if err != nil {
+				return fmt.Errorf("failed to read part %d of the configuration: %w", i+1, err)
+			}
+			fmt.Fprintf(&notes, "### Part %d\n%s\n\n", i+1, note)
+		}
+		prompt = configExplainPrompt(summary, notes.String(), true)
+	}
+
+	spinner := utils.NewSpinner(out, "Explaining the configuration...", 0).Start()
+	explanation, err := query(prompt)
+	spinner.Stop()
+	if err != nil {
+		return fmt.Errorf("failed to explain the configuration: %w", err)
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatSubsection("🤖 Overview", ""))
+	_, _ = fmt.Fprintln(out, utils.RenderMarkdown(explanation))
+	return nil
+}
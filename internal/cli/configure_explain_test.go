@@ -0,0 +1,211 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const explainConfigurationNix = `{ config, pkgs, ... }:
+{
+  imports = [ ./hardware-configuration.nix ./services.nix ];
+
+  boot.loader.systemd-boot.enable = true;
+  networking.hostName = "homeserver";
+  networking.firewall.enable = false;
+  time.timeZone = "Europe/Oslo";
+
+  users.users.alice = {
+    isNormalUser = true;
+    extraGroups = [ "wheel" "docker" ];
+    initialPassword = "changeme";
+  };
+  users.users.backup.isSystemUser = true;
+
+  security.sudo.wheelNeedsPassword = false;
+  programs.zsh.enable = true;
+  system.stateVersion = "24.05";
+}
+`
+
+const explainServicesNix = `{ ... }:
+{
+  services.nginx = {
+    enable = true;
+    virtualHosts."example.org".root = "/var/www";
+  };
+  services.postgresql.enable = true;
+  services.openssh = {
+    enable = true;
+    settings.PermitRootLogin = "yes";
+  };
+  services.printing.enable = false;
+  virtualisation.docker.enable = true;
+}
+`
+
+// writeExplainFixture writes a configuration that imports a hardware and a services module
+func writeExplainFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"configuration.nix":          explainConfigurationNix,
+		"services.nix":               explainServicesNix,
+		"hardware-configuration.nix": "{ ... }:\n{\n  fileSystems.\"/\".device = \"/dev/sda1\";\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestSummarizeNixConfig(t *testing.T) {
+	dir := writeExplainFixture(t)
+	root, err := configExplainRoot(dir, nil, nil)
+	if err != nil || root != filepath.Join(dir, "configuration.nix") {
+		t.Fatalf("configExplainRoot() = %q, %v", root, err)
+	}
+
+	summary := summarizeNixConfig(readConfigTree(root))
+	if len(summary.Files) != 3 {
+		t.Errorf("expected the root and both imports, got %v", summary.Files)
+	}
+	if got := strings.Join(summary.Services, ","); got != "nginx,openssh,postgresql" {
+		t.Errorf("services = %q", got)
+	}
+	if got := strings.Join(summary.Programs, ","); got != "zsh" {
+		t.Errorf("programs = %q", got)
+	}
+	if got := strings.Join(summary.Users, ","); got != "alice,backup" {
+		t.Errorf("users = %q", got)
+	}
+
+	notable := configFactsText(summary)
+	for _, want := range []string{`networking.hostName = "homeserver"`, "virtualisation.docker.enable = true", `system.stateVersion = "24.05"`} {
+		if !strings.Contains(notable, want) {
+			t.Errorf("facts missing %q:\n%s", want, notable)
+		}
+	}
+
+	findings := strings.Join(summary.Findings, "\n")
+	for _, want := range []string{"firewall is disabled", "PermitRootLogin", "sudo without a password", "users.users.alice.initialPassword"} {
+		if !strings.Contains(findings, want) {
+			t.Errorf("findings missing %q:\n%s", want, findings)
+		}
+	}
+}
+
+func TestExplainConfigMentionsServices(t *testing.T) {
+	dir := writeExplainFixture(t)
+	provider := &scriptedProvider{responses: []string{"## Purpose\nA home server running nginx and PostgreSQL."}}
+
+	var out bytes.Buffer
+	if err := explainConfig(&out, readConfigTree(filepath.Join(dir, "configuration.nix")), provider.Query, 1<<20); err != nil {
+		t.Fatal(err)
+	}
+	if provider.calls != 1 {
+		t.Errorf("a small configuration should take one request, got %d", provider.calls)
+	}
+	for _, want := range []string{"nginx", "postgresql", "openssh", "alice", "services.nix", "Unusual"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("overview missing %q:\n%s", want, out.String())
+		}
+	}
+	prompt := provider.prompts[0]
+	for _, want := range []string{"Enabled services: nginx, openssh, postgresql", "## Unusual or risky", "services.postgresql.enable = true"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q", want)
+		}
+	}
+}
+
+func TestExplainConfigChunksLargeConfigs(t *testing.T) {
+	dir := writeExplainFixture(t)
+	tree := readConfigTree(filepath.Join(dir, "configuration.nix"))
+
+	chunks := chunkConfigFiles(tree, 300)
+	if len(chunks) < 2 {
+		t.Fatalf("expected several chunks, got %d", len(chunks))
+	}
+	joined := strings.Join(chunks, "")
+	for _, file := range tree.Files {
+		if !strings.Contains(joined, "# File: "+file) {
+			t.Errorf("chunks are missing %s", file)
+		}
+	}
+	for i, chunk := range chunks {
+		if len(chunk) > 300+len("\n") {
+			t.Errorf("chunk %d has %d characters", i, len(chunk))
+		}
+	}
+
+	responses := make([]string, len(chunks)+1)
+	for i := range chunks {
+		responses[i] = "notes"
+	}
+	responses[len(chunks)] = "overview"
+	provider := &scriptedProvider{responses: responses}
+	var out bytes.Buffer
+	if err := explainConfig(&out, tree, provider.Query, 300); err != nil {
+		t.Fatal(err)
+	}
+	if provider.calls != len(chunks)+1 {
+		t.Errorf("expected a request per chunk and one for the overview, got %d", provider.calls)
+	}
+	final := provider.prompts[len(provider.prompts)-1]
+	if !strings.Contains(final, "### Part 2") || !strings.Contains(final, "Enabled services: nginx, openssh, postgresql") {
+		t.Errorf("the overview prompt should combine the notes with the parsed facts:\n%s", final)
+	}
+}
+
+func TestExplainConfigWithoutAI(t *testing.T) {
+	dir := writeExplainFixture(t)
+	var out bytes.Buffer
+	if err := explainConfig(&out, readConfigTree(filepath.Join(dir, "configuration.nix")), nil, 1<<20); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "nginx") || strings.Contains(out.String(), "Overview") {
+		t.Errorf("unexpected output without AI:\n%s", out.String())
+	}
+}
+
+func TestExplainConfigRedactsSecrets(t *testing.T) {
+	dir := writeExplainFixture(t)
+	secrets := `{ ... }:
+{
+  users.users.root.hashedPassword = "$6$rounds=4096$hunter2";
+  services.grafana.settings.security.secret_key = "s3cr3t-key";
+  services.myapp.environment.API_TOKEN = ''
+    tok-123
+  '';
+  security.sudo.wheelNeedsPassword = false;
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "hardware-configuration.nix"), []byte(secrets), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tree := readConfigTree(filepath.Join(dir, "configuration.nix"))
+
+	for _, maxChars := range []int{1 << 20, 200} {
+		provider := &scriptedProvider{responses: make([]string, 20)}
+		var out bytes.Buffer
+		if err := explainConfig(&out, tree, provider.Query, maxChars); err != nil {
+			t.Fatal(err)
+		}
+		prompts := strings.Join(provider.prompts, "\n")
+		for _, secret := range []string{"changeme", "hunter2", "s3cr3t-key", "tok-123"} {
+			if strings.Contains(prompts, secret) {
+				t.Errorf("secret %q reached the prompt (chunk size %d)", secret, maxChars)
+			}
+		}
+		for _, want := range []string{`initialPassword = "<redacted>"`, "wheelNeedsPassword = false"} {
+			if !strings.Contains(prompts, want) {
+				t.Errorf("prompt missing %q (chunk size %d)", want, maxChars)
+			}
+		}
+	}
+}
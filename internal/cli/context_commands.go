@@ -155,7 +155,7 @@ func runContextDetect(cmd *cobra.Command) {
 	contextDetector.ClearCache()
 
 	// Detect context
-	nixosCtx, err := contextDetector.GetContext(cfg)
+	nixosCtx, err := contextDetector.GetContextEvenIfDisabled(cfg)
 	if err != nil {
 		fmt.Println(utils.FormatError("Context detection failed: " + err.Error()))
 		return
@@ -204,7 +204,7 @@ func runContextShow(cmd *cobra.Command) {
 	contextDetector := nixos.NewContextDetector(logger.NewLogger())
 
 	// Get context (will use cache if valid)
-	nixosCtx, err := contextDetector.GetContext(cfg)
+	nixosCtx, err := contextDetector.GetContextEvenIfDisabled(cfg)
 	if err != nil {
 		fmt.Println(utils.FormatError("Failed to get context: " + err.Error()))
 		return
@@ -262,7 +262,7 @@ func runContextReset(cmd *cobra.Command) {
 	fmt.Println(utils.FormatProgress("Re-detecting system context..."))
 
 	// Force fresh detection
-	nixosCtx, err := contextDetector.GetContext(cfg)
+	nixosCtx, err := contextDetector.GetContextEvenIfDisabled(cfg)
 	if err != nil {
 		fmt.Println(utils.FormatError("Context re-detection failed: " + err.Error()))
 		return
@@ -295,14 +295,15 @@ func runContextStatus(cmd *cobra.Command) {
 	contextDetector := nixos.NewContextDetector(logger.NewLogger())
 
 	// Get context status
-	nixosCtx, err := contextDetector.GetContext(cfg)
+	nixosCtx, err := contextDetector.GetContextEvenIfDisabled(cfg)
 
 	status := map[string]interface{}{
-		"cache_location": contextDetector.GetCacheLocation(),
-		"has_context":    nixosCtx != nil,
-		"cache_valid":    false,
-		"last_detected":  nil,
-		"errors":         []string{},
+		"cache_location":    contextDetector.GetCacheLocation(),
+		"detection_enabled": cfg.ContextDetectionEnabled(),
+		"has_context":       nixosCtx != nil,
+		"cache_valid":       false,
+		"last_detected":     nil,
+		"errors":            []string{},
 	}
 
 	if err != nil {
@@ -342,9 +343,19 @@ func displayContextInfo(nixosCtx *config.NixOSContext, detailed bool) {
 	// System Information
 	fmt.Println(utils.FormatSubsection("System Information", ""))
 	fmt.Println(utils.FormatKeyValue("System Type", nixosCtx.SystemType))
+	if nixosCtx.Environment != "" {
+		environment := nixosCtx.Environment
+		if nixosCtx.ContainerRuntime != "" {
+			environment += " (" + nixosCtx.ContainerRuntime + ")"
+		}
+		fmt.Println(utils.FormatKeyValue("Environment", environment))
+	}
 	if nixosCtx.NixOSVersion != "" {
 		fmt.Println(utils.FormatKeyValue("NixOS Version", nixosCtx.NixOSVersion))
 	}
+	if nixosCtx.NixpkgsRelease != "" {
+		fmt.Println(utils.FormatKeyValue("Nixpkgs Release", nixosCtx.NixpkgsRelease))
+	}
 	if nixosCtx.NixVersion != "" {
 		fmt.Println(utils.FormatKeyValue("Nix Version", nixosCtx.NixVersion))
 	}
@@ -427,6 +438,9 @@ func displayContextStatus(status map[string]interface{}, nixosCtx *config.NixOSC
 	fmt.Println(utils.FormatKeyValue("Cache Location", status["cache_location"].(string)))
 	fmt.Println(utils.FormatKeyValue("Has Context", formatBool(status["has_context"].(bool))))
 	fmt.Println(utils.FormatKeyValue("Cache Valid", formatBool(status["cache_valid"].(bool))))
+	if enabled, ok := status["detection_enabled"].(bool); ok && !enabled {
+		fmt.Println(utils.FormatKeyValue("Prompt Enrichment", "disabled (--no-context or context.enabled: false)"))
+	}
 
 	if lastDetected := status["last_detected"]; lastDetected != nil {
 		if ts, ok := lastDetected.(time.Time); ok {
@@ -35,6 +35,11 @@ func NewDepsCommand() *cobra.Command {
 		Short: "Analyze NixOS configuration dependencies and imports",
 		Long: `Provides tools to visualize and analyze NixOS configuration dependencies,
 helping to understand relationships, detect conflicts, and optimize configurations.`,
+		Example: `  # Show the dependency tree with AI insights
+  nixai deps analyze
+
+  # Find conflicting imports
+  nixai deps conflicts`,
 		Run: func(cmd *cobra.Command, args []string) {
 			_ = cmd.Help()
 		},
@@ -472,7 +477,7 @@ func runDepsGraph() {
 				cmd.Stderr = os.Stderr
 
 				fmt.Println(utils.FormatProgress("Generating visualization..."))
-				err := cmd.Run()
+				err := utils.TraceRun(cmd)
 				if err != nil {
 					fmt.Fprintln(os.Stderr, utils.FormatError(fmt.Sprintf("Failed to generate visualization: %v", err)))
 				} else {
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"nix-ai-help/internal/nixos"
+	"nix-ai-help/pkg/utils"
+)
+
+// maxBuildOutputLines limits how much build output is sent to the AI; nix prints the errors
+// at the end, after the progress of every derivation built before
+const maxBuildOutputLines = 300
+
+// buildErrorLabels names the kinds of parsed build errors
+var buildErrorLabels = map[string]string{
+	nixos.BuildErrorCollision:    "File collision",
+	nixos.BuildErrorHashMismatch: "Hash mismatch",
+	nixos.BuildErrorEvaluation:   "Evaluation error",
+	nixos.BuildErrorBuilder:      "Build failure",
+}
+
+// buildDiagnosisGuidance is the build-focused part of the diagnose prompt
+const buildDiagnosisGuidance = "You are a NixOS expert. The following is output of nixos-rebuild or nix build. " +
+	"Identify the step that failed (evaluating the configuration, building or fetching a derivation, or " +
+	"activating the new generation), explain the root cause and give step-by-step fix instructions.\n\n" +
+	"Build-specific guidance:\n" +
+	"- Collisions: name the two packages providing the same file and how to resolve it (remove one, " +
+	"lib.hiPrio/lib.lowPrio or meta.priority).\n" +
+	"- Hash mismatches: explain that the fetched source changed or the hash is wrong, and that the hash in " +
+	"the fetcher must be replaced by the reported 'got' hash after checking the source is trustworthy.\n" +
+	"- Evaluation errors: point at the file and line in the user's configuration and show the corrected Nix code.\n" +
+	"- Builder failures: suggest 'nix log <derivation>' to see the full build log and explain the failing step.\n\n"
+
+// isBuildDiagnosis reports whether diagnose should treat its input as build output: when
+// --build is set or the input carries nixos-rebuild markers
+func isBuildDiagnosis(buildFlag bool, logData string) bool {
+	return buildFlag || nixos.IsBuildOutput(logData)
+}
+
+// buildDiagnosisPrompt prints the errors parsed from build output on out and returns the
+// build-focused diagnose prompt
+func buildDiagnosisPrompt(out io.Writer, logData, additionalContext string) string {
+	buildErrors := nixos.ParseBuildErrors(logData)
+	_, _ = fmt.Fprintln(out, utils.FormatNote("Analyzing as nixos-rebuild build output"))
+	if len(buildErrors) > 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatSubsection("Detected build errors", ""))
+		for _, buildErr := range buildErrors {
+			_, _ = fmt.Fprintln(out, "  "+utils.FormatKeyValue(buildErrorLabels[buildErr.Kind], buildErrorSummary(buildErr)))
+		}
+	}
+	_, _ = fmt.Fprintln(out)
+
+	prompt := buildDiagnosisGuidance
+	if len(buildErrors) > 0 {
+		prompt += "PARSED BUILD ERRORS:\n"
+		for _, buildErr := range buildErrors {
+			prompt += "- " + buildErr.Kind + ": " + buildErrorSummary(buildErr) + "\n"
+		}
+		prompt += "\n"
+	}
+	if additionalContext != "" {
+		prompt += fmt.Sprintf("Additional context: %s\n\n", additionalContext)
+	}
+	return prompt + "Build output:\n" + buildOutputTail(logData, maxBuildOutputLines)
+}
+
+// buildErrorSummary returns the summary of a build error with its location
+func buildErrorSummary(buildErr nixos.BuildError) string {
+	if buildErr.Location != "" {
+		return buildErr.Summary + " (at " + buildErr.Location + ")"
+	}
+	return buildErr.Summary
+}
+
+// buildOutputTail returns the last maxLines lines of build output
+func buildOutputTail(output string, maxLines int) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) <= maxLines {
+		return strings.Join(lines, "\n")
+	}
+	return fmt.Sprintf("[%d earlier lines omitted]\n", len(lines)-maxLines) + strings.Join(lines[len(lines)-maxLines:], "\n")
+}
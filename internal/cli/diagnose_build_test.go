@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestIsBuildDiagnosis(t *testing.T) {
+	build := "building the system configuration...\nerror: builder for '/nix/store/aaaa-foo.drv' failed with exit code 1;\n"
+	journal := "Jun 01 10:00:01 host systemd[1]: Failed to start nginx.service.\n"
+
+	if !isBuildDiagnosis(false, build) {
+		t.Error("nixos-rebuild output not detected as build output")
+	}
+	if isBuildDiagnosis(false, journal) {
+		t.Error("journal output detected as build output")
+	}
+	if !isBuildDiagnosis(true, journal) {
+		t.Error("--build did not force build diagnosis")
+	}
+}
+
+func TestBuildDiagnosisPrompt(t *testing.T) {
+	output := "these 1 derivations will be built:\n" +
+		"error: hash mismatch in fixed-output derivation '/nix/store/eeee-source.drv':\n" +
+		"         specified: sha256-AAA=\n" +
+		"            got:    sha256-BBB=\n"
+
+	var out bytes.Buffer
+	prompt := buildDiagnosisPrompt(&out, output, "after updating the flake")
+
+	for _, want := range []string{"nixos-rebuild", "PARSED BUILD ERRORS", "hash_mismatch: hash mismatch in /nix/store/eeee-source.drv",
+		"got sha256-BBB=", "Additional context: after updating the flake", "Build output:\n" + strings.TrimRight(output, "\n")} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+	if !strings.Contains(out.String(), "Hash mismatch") {
+		t.Errorf("parsed errors not shown:\n%s", out.String())
+	}
+}
+
+func TestBuildOutputTail(t *testing.T) {
+	if got := buildOutputTail("a\nb\nc\n", 5); got != "a\nb\nc" {
+		t.Errorf("short output changed: %q", got)
+	}
+	if got := buildOutputTail("a\nb\nc\nd\n", 2); got != "[2 earlier lines omitted]\nc\nd" {
+		t.Errorf("buildOutputTail() = %q", got)
+	}
+}
@@ -0,0 +1,299 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"nix-ai-help/pkg/utils"
+)
+
+const (
+	// maxRegressionPromptChanges limits the package changes sent to the AI
+	maxRegressionPromptChanges = 80
+	// maxRegressionShownChanges limits the package changes printed before the analysis
+	maxRegressionShownChanges = 15
+	// runningSystemLink points to the system generation that is running
+	runningSystemLink = "/run/current-system"
+)
+
+// closureDiffLineRegex matches a `nix store diff-closures` line such as
+// "firefox: 120.0 → 121.0, +1.2 MiB" or "nginx: ∅ → 1.24.0, +3.4 MiB"
+var closureDiffLineRegex = regexp.MustCompile(`^([^\s:]+): (.+?) → (.+?)(?:, ([+-][\d.]+ \S+))?$`)
+
+// closureChange is a package whose versions differ between two generations
+type closureChange struct {
+	Package string `json:"package"`
+	Old     string `json:"old,omitempty"` // empty when the package was added
+	New     string `json:"new,omitempty"` // empty when the package was removed
+	Size    string `json:"size,omitempty"`
+}
+
+// generationState is what a system generation link tells about its configuration
+type generationState struct {
+	NixOSVersion string
+	Kernel       string
+	KernelParams string
+	Units        []string
+	Options      map[string]string // from configuration.nix, with system.copySystemConfiguration
+}
+
+// RegressionDiff is the difference between the last good and the failing system generation
+type RegressionDiff struct {
+	Good     Generation      `json:"good"`
+	Bad      Generation      `json:"bad"`
+	Packages []closureChange `json:"packages"`
+	Config   []string        `json:"config"`
+}
+
+// regressionGenerations picks the failing generation, the newest one, and the last good one:
+// the running generation after a rollback or a boot into an older generation, otherwise the
+// generation before the newest
+func regressionGenerations(generations []Generation, running int) (bad, good Generation, err error) {
+	if len(generations) < 2 {
+		return bad, good, errors.New("need at least two system generations to compare")
+	}
+	sorted := append([]Generation(nil), generations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Number > sorted[j].Number })
+
+	bad, good = sorted[0], sorted[1]
+	for _, gen := range sorted[1:] {
+		if gen.Number == running {
+			good = gen
+			break
+		}
+	}
+	return bad, good, nil
+}
+
+// runningGenerationNumber returns the generation /run/current-system points to, or -1 when
+// it is not one of the generations
+func runningGenerationNumber(generations []Generation, resolve func(string) (string, error)) int {
+	running, err := resolve(runningSystemLink)
+	if err != nil {
+		return -1
+	}
+	for _, gen := range generations {
+		if target, err := resolve(generationLink(gen.Number)); err == nil && target == running {
+			return gen.Number
+		}
+	}
+	return -1
+}
+
+// parseClosureDiff parses `nix store diff-closures` output. Lines without a version change,
+// which only report a size change, are skipped.
+func parseClosureDiff(output string) []closureChange {
+	var changes []closureChange
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		matches := closureDiffLineRegex.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if matches == nil {
+			continue
+		}
+		changes = append(changes, closureChange{
+			Package: matches[1],
+			Old:     closureVersion(matches[2]),
+			New:     closureVersion(matches[3]),
+			Size:    matches[4],
+		})
+	}
+	return changes
+}
+
+// closureVersion returns the versions of a diff-closures side; ∅ (absent) and ε (no version)
+// become empty
+func closureVersion(version string) string {
+	if version == "∅" || version == "ε" {
+		return ""
+	}
+	return version
+}
+
+// readGenerationState reads the NixOS version, kernel, kernel parameters, systemd units and
+// copied configuration.nix of a generation link
+func readGenerationState(link string) generationState {
+	var state generationState
+	// #nosec G304 -- link is a system generation profile link
+	if data, err := os.ReadFile(filepath.Join(link, "nixos-version")); err == nil {
+		state.NixOSVersion = strings.TrimSpace(string(data))
+	}
+	if target, err := filepath.EvalSymlinks(filepath.Join(link, "kernel")); err == nil {
+		state.Kernel = kernelFromStorePath(target)
+	}
+	// #nosec G304 -- link is a system generation profile link
+	if data, err := os.ReadFile(filepath.Join(link, "kernel-params")); err == nil {
+		state.KernelParams = strings.TrimSpace(string(data))
+	}
+	if entries, err := os.ReadDir(filepath.Join(link, "etc", "systemd", "system")); err == nil {
+		for _, entry := range entries {
+			if name := entry.Name(); !entry.IsDir() && !strings.HasSuffix(name, ".wants") && !strings.HasSuffix(name, ".d") {
+				state.Units = append(state.Units, name)
+			}
+		}
+	}
+	// #nosec G304 -- link is a system generation profile link
+	if data, err := os.ReadFile(filepath.Join(link, "configuration.nix")); err == nil {
+		state.Options = extractNixOptionValues(string(data))
+	}
+	return state
+}
+
+// configChanges describes how the configuration of a generation differs from an older one
+func configChanges(good, bad generationState) []string {
+	var changes []string
+	for _, field := range []struct{ name, old, new string }{
+		{"NixOS version", good.NixOSVersion, bad.NixOSVersion},
+		{"kernel", good.Kernel, bad.Kernel},
+		{"kernel parameters", good.KernelParams, bad.KernelParams},
+	} {
+		if field.old != field.new && field.old != "" && field.new != "" {
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", field.name, field.old, field.new))
+		}
+	}
+
+	oldUnits := make(map[string]bool, len(good.Units))
+	for _, unit := range good.Units {
+		oldUnits[unit] = true
+	}
+	newUnits := make(map[string]bool, len(bad.Units))
+	for _, unit := range bad.Units {
+		newUnits[unit] = true
+		if !oldUnits[unit] {
+			changes = append(changes, "systemd unit added: "+unit)
+		}
+	}
+	for _, unit := range good.Units {
+		if !newUnits[unit] {
+			changes = append(changes, "systemd unit removed: "+unit)
+		}
+	}
+
+	// Options can only be compared when both generations carry their configuration.nix
+	if good.Options != nil && bad.Options != nil {
+		options := make([]string, 0, len(bad.Options))
+		for option := range bad.Options {
+			options = append(options, option)
+		}
+		for option := range good.Options {
+			if _, ok := bad.Options[option]; !ok {
+				options = append(options, option)
+			}
+		}
+		sort.Strings(options)
+		for _, option := range options {
+			oldValue, hadOption := good.Options[option]
+			newValue, hasOption := bad.Options[option]
+			switch {
+			case !hadOption:
+				changes = append(changes, fmt.Sprintf("option added: %s = %s", option, newValue))
+			case !hasOption:
+				changes = append(changes, fmt.Sprintf("option removed: %s (was %s)", option, oldValue))
+			case oldValue != newValue:
+				changes = append(changes, fmt.Sprintf("option changed: %s = %s → %s", option, oldValue, newValue))
+			}
+		}
+	}
+	return changes
+}
+
+// collectRegressionDiff compares the last good and the failing generation: their package
+// versions with nix store diff-closures, and their configuration from the generation links
+func collectRegressionDiff(generations []Generation, run commandRunner, resolve func(string) (string, error), readState func(string) generationState) (*RegressionDiff, error) {
+	bad, good, err := regressionGenerations(generations, runningGenerationNumber(generations, resolve))
+	if err != nil {
+		return nil, err
+	}
+	diff := &RegressionDiff{Good: good, Bad: bad}
+
+	output, err := run("nix", "store", "diff-closures", generationLink(good.Number), generationLink(bad.Number))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare generations %d and %d: %w", good.Number, bad.Number, err)
+	}
+	diff.Packages = parseClosureDiff(string(output))
+	diff.Config = configChanges(readState(generationLink(good.Number)), readState(generationLink(bad.Number)))
+	return diff, nil
+}
+
+// formatClosureChange describes a package change on one line
+func formatClosureChange(change closureChange) string {
+	var line string
+	switch {
+	case change.Old == "" && change.New == "":
+		// A path without a version was added or removed
+		line = change.Package
+	case change.Old == "":
+		line = fmt.Sprintf("%s: added %s", change.Package, change.New)
+	case change.New == "":
+		line = fmt.Sprintf("%s: removed %s", change.Package, change.Old)
+	default:
+		line = fmt.Sprintf("%s: %s → %s", change.Package, change.Old, change.New)
+	}
+	if change.Size != "" {
+		line += " (" + change.Size + ")"
+	}
+	return line
+}
+
+// printRegressionDiff shows the changes between the generations before the AI analysis
+func printRegressionDiff(out io.Writer, diff *RegressionDiff) {
+	_, _ = fmt.Fprintln(out, utils.FormatSubsection(fmt.Sprintf("🔀 Changes from generation %d to %d", diff.Good.Number, diff.Bad.Number), ""))
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Last good", fmt.Sprintf("generation %d (%s)", diff.Good.Number, diff.Good.Date.Format("2006-01-02 15:04"))))
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Failing", fmt.Sprintf("generation %d (%s)", diff.Bad.Number, diff.Bad.Date.Format("2006-01-02 15:04"))))
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Package changes", fmt.Sprintf("%d", len(diff.Packages))))
+	for i, change := range diff.Packages {
+		if i >= maxRegressionShownChanges {
+			_, _ = fmt.Fprintf(out, "  … and %d more\n", len(diff.Packages)-maxRegressionShownChanges)
+			break
+		}
+		_, _ = fmt.Fprintln(out, "  "+formatClosureChange(change))
+	}
+	for _, change := range diff.Config {
+		_, _ = fmt.Fprintln(out, "  "+change)
+	}
+	if len(diff.Packages) == 0 && len(diff.Config) == 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatNote("The generations have the same packages and configuration"))
+	}
+	_, _ = fmt.Fprintln(out)
+}
+
+// regressionPrompt asks the AI which change between the generations caused the failure
+func regressionPrompt(diff *RegressionDiff, logData, additionalContext string) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("You are a NixOS expert. The system worked on generation %d and broke after switching to generation %d. ",
+		diff.Good.Number, diff.Bad.Number))
+	b.WriteString("Using the differences between the two generations below, identify the change that most likely caused the regression and explain why. " +
+		"Then give step-by-step fixes: how to fix the configuration so the new generation works, and how to pin or revert only the offending package or option meanwhile. " +
+		"If no listed change explains the symptoms, say so instead of guessing.\n\n")
+
+	b.WriteString(fmt.Sprintf("Package changes from generation %d to %d:\n", diff.Good.Number, diff.Bad.Number))
+	if len(diff.Packages) == 0 {
+		b.WriteString("- none\n")
+	}
+	for i, change := range diff.Packages {
+		if i >= maxRegressionPromptChanges {
+			b.WriteString(fmt.Sprintf("- ... and %d more\n", len(diff.Packages)-maxRegressionPromptChanges))
+			break
+		}
+		b.WriteString("- " + formatClosureChange(change) + "\n")
+	}
+	if len(diff.Config) > 0 {
+		b.WriteString("\nConfiguration changes:\n")
+		for _, change := range diff.Config {
+			b.WriteString("- " + change + "\n")
+		}
+	}
+	if additionalContext != "" {
+		b.WriteString("\nAdditional context: " + additionalContext + "\n")
+	}
+	if strings.TrimSpace(logData) != "" {
+		b.WriteString("\nSymptoms (log or error output):\n" + logData)
+	}
+	return b.String()
+}
@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// closureDiffFixture is `nix store diff-closures` output between two system generations
+const closureDiffFixture = `firefox: 120.0 → 121.0, +1.2 MiB
+nginx: ∅ → 1.24.0, +3.4 MiB
+openssl: 3.0.12 → ∅, -6.1 MiB
+linux: 6.6.31, 6.6.31-modules → 6.6.32, 6.6.32-modules, +210.5 KiB
+nixos-system-nixos: 24.05.20240527.1234567 → 24.05.20240601.abcdef0
+source: ε → ∅, -48.0 KiB
+zstd: +12.3 KiB
+`
+
+func TestParseClosureDiff(t *testing.T) {
+	got := parseClosureDiff(closureDiffFixture)
+	want := []closureChange{
+		{Package: "firefox", Old: "120.0", New: "121.0", Size: "+1.2 MiB"},
+		{Package: "nginx", New: "1.24.0", Size: "+3.4 MiB"},
+		{Package: "openssl", Old: "3.0.12", Size: "-6.1 MiB"},
+		{Package: "linux", Old: "6.6.31, 6.6.31-modules", New: "6.6.32, 6.6.32-modules", Size: "+210.5 KiB"},
+		{Package: "nixos-system-nixos", Old: "24.05.20240527.1234567", New: "24.05.20240601.abcdef0"},
+		{Package: "source", Size: "-48.0 KiB"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseClosureDiff() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestRegressionGenerations(t *testing.T) {
+	generations := []Generation{{Number: 41}, {Number: 43, Current: true}, {Number: 42}}
+
+	// Still running the newest generation: compare it with the one before
+	bad, good, err := regressionGenerations(generations, 43)
+	if err != nil || bad.Number != 43 || good.Number != 42 {
+		t.Errorf("running 43: bad %d, good %d, err %v; want 43 and 42", bad.Number, good.Number, err)
+	}
+
+	// Booted into an older generation after the bad rebuild
+	bad, good, _ = regressionGenerations(generations, 41)
+	if bad.Number != 43 || good.Number != 41 {
+		t.Errorf("running 41: bad %d, good %d; want 43 and 41", bad.Number, good.Number)
+	}
+
+	// An unknown running generation compares the two newest
+	bad, good, _ = regressionGenerations(generations, -1)
+	if bad.Number != 43 || good.Number != 42 {
+		t.Errorf("unknown running: bad %d, good %d; want 43 and 42", bad.Number, good.Number)
+	}
+
+	if _, _, err := regressionGenerations(generations[:1], 41); err == nil {
+		t.Error("expected an error with a single generation")
+	}
+}
+
+func TestRunningGenerationNumber(t *testing.T) {
+	links := map[string]string{
+		runningSystemLink:  "/nix/store/bbb-nixos-system",
+		generationLink(41): "/nix/store/bbb-nixos-system",
+		generationLink(42): "/nix/store/ccc-nixos-system",
+	}
+	resolve := func(path string) (string, error) {
+		if target, ok := links[path]; ok {
+			return target, nil
+		}
+		return "", os.ErrNotExist
+	}
+	generations := []Generation{{Number: 42}, {Number: 41}}
+
+	if got := runningGenerationNumber(generations, resolve); got != 41 {
+		t.Errorf("runningGenerationNumber() = %d, want 41", got)
+	}
+	delete(links, runningSystemLink)
+	if got := runningGenerationNumber(generations, resolve); got != -1 {
+		t.Errorf("without /run/current-system got %d, want -1", got)
+	}
+}
+
+func TestConfigChanges(t *testing.T) {
+	good := generationState{
+		NixOSVersion: "24.05.20240527.1234567",
+		Kernel:       "6.6.31",
+		KernelParams: "quiet loglevel=3",
+		Units:        []string{"sshd.service", "docker.service"},
+		Options:      map[string]string{"services.openssh.enable": "true", "virtualisation.docker.enable": "true"},
+	}
+	bad := generationState{
+		NixOSVersion: "24.05.20240601.abcdef0",
+		Kernel:       "6.6.32",
+		KernelParams: "quiet loglevel=3",
+		Units:        []string{"sshd.service", "nginx.service"},
+		Options:      map[string]string{"services.openssh.enable": "false", "services.nginx.enable": "true"},
+	}
+
+	want := []string{
+		"NixOS version: 24.05.20240527.1234567 → 24.05.20240601.abcdef0",
+		"kernel: 6.6.31 → 6.6.32",
+		"systemd unit added: nginx.service",
+		"systemd unit removed: docker.service",
+		"option added: services.nginx.enable = true",
+		"option changed: services.openssh.enable = true → false",
+		"option removed: virtualisation.docker.enable (was true)",
+	}
+	if got := configChanges(good, bad); !reflect.DeepEqual(got, want) {
+		t.Errorf("configChanges() =\n%q\nwant\n%q", got, want)
+	}
+
+	// Without a copied configuration.nix, options are not compared
+	bad.Options = nil
+	for _, change := range configChanges(good, bad) {
+		if strings.HasPrefix(change, "option") {
+			t.Errorf("unexpected option change without configuration.nix: %s", change)
+		}
+	}
+}
+
+func TestReadGenerationState(t *testing.T) {
+	link := t.TempDir()
+	units := filepath.Join(link, "etc", "systemd", "system")
+	if err := os.MkdirAll(filepath.Join(units, "multi-user.target.wants"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(link, "nixos-version"):     "24.05.20240601.abcdef0\n",
+		filepath.Join(link, "kernel-params"):     "quiet\n",
+		filepath.Join(link, "configuration.nix"): "{ services.nginx.enable = true; }",
+		filepath.Join(units, "nginx.service"):    "[Unit]",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	state := readGenerationState(link)
+	if state.NixOSVersion != "24.05.20240601.abcdef0" || state.KernelParams != "quiet" {
+		t.Errorf("unexpected state %+v", state)
+	}
+	if !reflect.DeepEqual(state.Units, []string{"nginx.service"}) {
+		t.Errorf("units = %v, want only nginx.service", state.Units)
+	}
+	if state.Options["services.nginx.enable"] != "true" {
+		t.Errorf("options = %v", state.Options)
+	}
+}
+
+func TestCollectRegressionDiff(t *testing.T) {
+	generations := parseGenerations(`   41   2024-05-28 08:00:00
+   42   2024-06-02 12:30:45   (current)
+`)
+	var ran []string
+	run := func(name string, args ...string) ([]byte, error) {
+		ran = append(ran, name+" "+strings.Join(args, " "))
+		return []byte(closureDiffFixture), nil
+	}
+	noLinks := func(string) (string, error) { return "", os.ErrNotExist }
+	states := map[string]generationState{
+		generationLink(41): {Kernel: "6.6.31"},
+		generationLink(42): {Kernel: "6.6.32"},
+	}
+	readState := func(link string) generationState { return states[link] }
+
+	diff, err := collectRegressionDiff(generations, run, noLinks, readState)
+	if err != nil {
+		t.Fatalf("collectRegressionDiff failed: %v", err)
+	}
+	wantCommand := "nix store diff-closures " + generationLink(41) + " " + generationLink(42)
+	if !reflect.DeepEqual(ran, []string{wantCommand}) {
+		t.Errorf("ran %v, want %q", ran, wantCommand)
+	}
+	if diff.Good.Number != 41 || diff.Bad.Number != 42 || len(diff.Packages) != 6 {
+		t.Errorf("unexpected diff %+v", diff)
+	}
+	if !reflect.DeepEqual(diff.Config, []string{"kernel: 6.6.31 → 6.6.32"}) {
+		t.Errorf("config changes = %v", diff.Config)
+	}
+
+	failing := func(string, ...string) ([]byte, error) { return nil, errors.New("nix not found") }
+	if _, err := collectRegressionDiff(generations, failing, noLinks, readState); err == nil {
+		t.Error("expected an error when diff-closures fails")
+	}
+}
+
+func TestRegressionPrompt(t *testing.T) {
+	diff := &RegressionDiff{
+		Good:     Generation{Number: 41, Date: time.Date(2024, 5, 28, 8, 0, 0, 0, time.Local)},
+		Bad:      Generation{Number: 42, Date: time.Date(2024, 6, 2, 12, 30, 0, 0, time.Local)},
+		Packages: parseClosureDiff(closureDiffFixture),
+		Config:   []string{"kernel: 6.6.31 → 6.6.32"},
+	}
+
+	prompt := regressionPrompt(diff, "nginx.service: Failed with result 'exit-code'", "after nixos-rebuild switch")
+	for _, want := range []string{
+		"worked on generation 41 and broke after switching to generation 42",
+		"- firefox: 120.0 → 121.0 (+1.2 MiB)",
+		"- nginx: added 1.24.0 (+3.4 MiB)",
+		"- openssl: removed 3.0.12 (-6.1 MiB)",
+		"Configuration changes:\n- kernel: 6.6.31 → 6.6.32",
+		"Additional context: after nixos-rebuild switch",
+		"Symptoms (log or error output):\nnginx.service: Failed",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt does not contain %q:\n%s", want, prompt)
+		}
+	}
+
+	if prompt := regressionPrompt(&RegressionDiff{}, "", ""); strings.Contains(prompt, "Symptoms") || !strings.Contains(prompt, "- none") {
+		t.Errorf("unexpected prompt without changes or log:\n%s", prompt)
+	}
+}
+
+func TestFormatClosureChange(t *testing.T) {
+	tests := map[closureChange]string{
+		{Package: "firefox", Old: "120.0", New: "121.0", Size: "+1.2 MiB"}: "firefox: 120.0 → 121.0 (+1.2 MiB)",
+		{Package: "nginx", New: "1.24.0"}:                                  "nginx: added 1.24.0",
+		{Package: "openssl", Old: "3.0.12"}:                                "openssl: removed 3.0.12",
+		{Package: "source", Size: "-48.0 KiB"}:                             "source (-48.0 KiB)",
+	}
+	for change, want := range tests {
+		if got := formatClosureChange(change); got != want {
+			t.Errorf("formatClosureChange(%+v) = %q, want %q", change, got, want)
+		}
+	}
+}
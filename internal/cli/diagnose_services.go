@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"nix-ai-help/pkg/utils"
+)
+
+// serviceLogLines is the number of journal lines fetched for each failed unit
+const serviceLogLines = 50
+
+// failedUnit is a failed systemd unit with its recent logs
+type failedUnit struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Logs        string `json:"logs,omitempty"`
+	// Option is the NixOS option that most likely defines the unit
+	Option   string `json:"option"`
+	Analysis string `json:"analysis,omitempty"`
+}
+
+// failedServicesDiagnosis is the JSON result of diagnose --services
+type failedServicesDiagnosis struct {
+	FailedUnits []failedUnit `json:"failed_units"`
+	Summary     string       `json:"summary,omitempty"`
+}
+
+// unitOptions maps units whose NixOS option is not services.<name> to that option
+var unitOptions = map[string]string{
+	"sshd":             "services.openssh",
+	"display-manager":  "services.xserver.displayManager",
+	"NetworkManager":   "networking.networkmanager",
+	"systemd-networkd": "systemd.network",
+	"systemd-resolved": "services.resolved",
+	"wpa_supplicant":   "networking.wireless",
+	"docker":           "virtualisation.docker",
+	"podman":           "virtualisation.podman",
+	"libvirtd":         "virtualisation.libvirtd",
+	"bluetooth":        "hardware.bluetooth",
+	"cups":             "services.printing",
+	"avahi-daemon":     "services.avahi",
+	"tailscaled":       "services.tailscale",
+	"nix-daemon":       "nix.settings",
+	"firewall":         "networking.firewall",
+	"nix-gc":           "nix.gc",
+	"nix-optimise":     "nix.optimise",
+	"mysql":            "services.mysql",
+	"httpd":            "services.httpd",
+}
+
+// listFailedUnits returns the units systemd reports as failed
+func listFailedUnits(run commandRunner) ([]failedUnit, error) {
+	output, err := run("systemctl", "list-units", "--failed", "--no-legend", "--plain", "--no-pager")
+	if err != nil && len(output) == 0 {
+		return nil, fmt.Errorf("failed to list failed units: %w", err)
+	}
+	var units []failedUnit
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(line), "●"))
+		if len(fields) == 0 {
+			continue
+		}
+		unit := failedUnit{Name: fields[0], Option: nixosOptionForUnit(fields[0])}
+		if len(fields) > 4 {
+			unit.Description = strings.Join(fields[4:], " ")
+		}
+		units = append(units, unit)
+	}
+	return units, nil
+}
+
+// nixosOptionForUnit returns the NixOS option that most likely defines a unit. Template
+// instances are traced to their template and home-manager-<user> to that user's configuration.
+func nixosOptionForUnit(unit string) string {
+	name := unit
+	if i := strings.LastIndex(name, "."); i > 0 {
+		name = name[:i]
+	}
+	if i := strings.Index(name, "@"); i > 0 {
+		name = name[:i]
+	}
+	if option, ok := unitOptions[name]; ok {
+		return option
+	}
+	if user, ok := strings.CutPrefix(name, "home-manager-"); ok {
+		return "home-manager.users." + user
+	}
+	return "services." + name
+}
+
+// unitLogs returns the recent journal of a unit; journalctl exits non-zero for units without
+// entries, so its output is kept whenever there is any
+func unitLogs(run commandRunner, unit string) string {
+	output, err := run("journalctl", "-u", unit, "-n", fmt.Sprint(serviceLogLines), "--no-pager", "-o", "short")
+	logs := strings.TrimSpace(string(output))
+	if logs == "" && err != nil {
+		return "(no journal entries: " + err.Error() + ")"
+	}
+	return logs
+}
+
+// failedServicePrompt asks for the root cause of one failed unit
+func failedServicePrompt(unit failedUnit, additionalContext string) string {
+	var b strings.Builder
+	b.WriteString("You are a NixOS expert. The systemd unit " + unit.Name + " has failed")
+	if unit.Description != "" {
+		b.WriteString(" (" + unit.Description + ")")
+	}
+	b.WriteString(". Using its journal below, give:\n")
+	b.WriteString("1. The root cause of the failure\n")
+	b.WriteString("2. The NixOS option responsible; it is most likely under " + unit.Option + ", correct this if the logs point elsewhere\n")
+	b.WriteString("3. The configuration change that fixes it, as a Nix snippet\n\n")
+	if additionalContext != "" {
+		b.WriteString("Additional context: " + additionalContext + "\n\n")
+	}
+	b.WriteString("Journal (last " + fmt.Sprint(serviceLogLines) + " lines):\n" + unit.Logs)
+	return b.String()
+}
+
+// failedServicesSummaryPrompt asks for the patterns across the analyses of all failed units
+func failedServicesSummaryPrompt(units []failedUnit) string {
+	var b strings.Builder
+	b.WriteString("You are a NixOS expert. These systemd units failed on the same system. Based on the analyses below, ")
+	b.WriteString("summarize across the services: shared root causes (networking, permissions, missing secrets, dependencies), ")
+	b.WriteString("the order in which to fix them, and the rebuild command to verify the fixes. Keep it brief.\n")
+	for _, unit := range units {
+		b.WriteString("\n## " + unit.Name + " (" + unit.Option + ")\n" + unit.Analysis + "\n")
+	}
+	return b.String()
+}
+
+// diagnoseFailedServices lists the failed units, analyzes each one from its journal with query and
+// finishes with a summary across the services when more than one failed. With the JSON output
+// format the units and analyses are written as one document once all are done. A nil query,
+// with --no-ai, lists the failed units only.
+func diagnoseFailedServices(out io.Writer, format string, run commandRunner, query func(string) (string, error), additionalContext string) error {
+	units, err := listFailedUnits(run)
+	if err != nil {
+		return err
+	}
+	if format == outputJSON {
+		return writeJSONOutput(out, analyzeFailedServices(units, run, query, additionalContext))
+	}
+	if len(units) == 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatSuccess("No failed systemd units"))
+		return nil
+	}
+
+	rows := make([][]string, 0, len(units))
+	for _, unit := range units {
+		rows = append(rows, []string{unit.Name, unit.Description, unit.Option})
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatWarning(fmt.Sprintf("%d failed units", len(units))))
+	_, _ = fmt.Fprintln(out, utils.FormatTable([]string{"Unit", "Description", "Likely option"}, rows))
+	_, _ = fmt.Fprintln(out)
+	if query == nil {
+		_, _ = fmt.Fprintln(out, utils.FormatNote("Failed units only, without an analysis (--no-ai)"))
+		_, _ = fmt.Fprintln(out, utils.FormatTip("Read the journal of a unit with 'journalctl -u <unit> -b'"))
+		return nil
+	}
+
+	for i := range units {
+		unit := &units[i]
+		unit.Logs = unitLogs(run, unit.Name)
+
+		spinner := utils.NewSpinner(out, fmt.Sprintf("Analyzing %s (%d/%d)...", unit.Name, i+1, len(units)), 0).Start()
+		analysis, err := query(failedServicePrompt(*unit, additionalContext))
+		spinner.Stop()
+		if err != nil {
+			unit.Analysis = "Analysis failed: " + err.Error()
+			_, _ = fmt.Fprintln(out, utils.FormatSubsection("🔧 "+unit.Name, ""))
+			_, _ = fmt.Fprintln(out, utils.FormatError(unit.Analysis))
+			continue
+		}
+		unit.Analysis = analysis
+		_, _ = fmt.Fprintln(out, utils.FormatSubsection("🔧 "+unit.Name, ""))
+		_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Likely option", unit.Option))
+		_, _ = fmt.Fprintln(out, renderAnswer(format, analysis))
+	}
+
+	if len(units) > 1 {
+		spinner := utils.NewSpinner(out, "Summarizing across services...", 0).Start()
+		summary, err := query(failedServicesSummaryPrompt(units))
+		spinner.Stop()
+		_, _ = fmt.Fprintln(out, utils.FormatSubsection("📋 Summary", ""))
+		if err != nil {
+			_, _ = fmt.Fprintln(out, utils.FormatError("Summary failed: "+err.Error()))
+		} else {
+			_, _ = fmt.Fprintln(out, renderAnswer(format, summary))
+		}
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatTip("Restart a fixed unit with 'sudo systemctl restart <unit>' after 'nixos-rebuild switch'"))
+	return nil
+}
+
+// analyzeFailedServices analyzes the failed units without printing progress, for JSON output.
+// A nil query, with --no-ai, only collects their logs.
+func analyzeFailedServices(units []failedUnit, run commandRunner, query func(string) (string, error), additionalContext string) failedServicesDiagnosis {
+	for i := range units {
+		unit := &units[i]
+		unit.Logs = unitLogs(run, unit.Name)
+		if query == nil {
+			continue
+		}
+		analysis, err := query(failedServicePrompt(*unit, additionalContext))
+		if err != nil {
+			analysis = "Analysis failed: " + err.Error()
+		}
+		unit.Analysis = analysis
+	}
+
+	diagnosis := failedServicesDiagnosis{FailedUnits: units}
+	if diagnosis.FailedUnits == nil {
+		diagnosis.FailedUnits = []failedUnit{}
+	}
+	if len(units) > 1 && query != nil {
+		if summary, err := query(failedServicesSummaryPrompt(units)); err == nil {
+			diagnosis.Summary = summary
+		}
+	}
+	return diagnosis
+}
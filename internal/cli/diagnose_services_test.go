@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const failedUnitsOutput = `nginx.service            loaded failed failed Nginx Web Server
+● sshd.service           loaded failed failed SSH Daemon
+home-manager-alice.service loaded failed failed Home Manager environment for alice
+`
+
+func TestListFailedUnits(t *testing.T) {
+	run := fakeRunner(map[string]string{
+		"systemctl list-units --failed --no-legend --plain --no-pager": failedUnitsOutput,
+	}, nil)
+
+	units, err := listFailedUnits(run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []failedUnit{
+		{Name: "nginx.service", Description: "Nginx Web Server", Option: "services.nginx"},
+		{Name: "sshd.service", Description: "SSH Daemon", Option: "services.openssh"},
+		{Name: "home-manager-alice.service", Description: "Home Manager environment for alice", Option: "home-manager.users.alice"},
+	}
+	if len(units) != len(want) {
+		t.Fatalf("got %d units, want %d: %+v", len(units), len(want), units)
+	}
+	for i := range want {
+		if units[i] != want[i] {
+			t.Errorf("unit %d = %+v, want %+v", i, units[i], want[i])
+		}
+	}
+}
+
+func TestNixosOptionForUnit(t *testing.T) {
+	for unit, want := range map[string]string{
+		"postgresql.service":          "services.postgresql",
+		"docker.service":              "virtualisation.docker",
+		"wg-quick@wg0.service":        "services.wg-quick",
+		"NetworkManager-wait.service": "services.NetworkManager-wait",
+		"nix-gc.timer":                "nix.gc",
+	} {
+		if got := nixosOptionForUnit(unit); got != want {
+			t.Errorf("nixosOptionForUnit(%q) = %q, want %q", unit, got, want)
+		}
+	}
+}
+
+func TestDiagnoseFailedServices(t *testing.T) {
+	run := fakeRunner(map[string]string{
+		"systemctl list-units --failed --no-legend --plain --no-pager": failedUnitsOutput,
+		"journalctl -u nginx.service -n 50 --no-pager -o short":        "nginx: [emerg] bind() to 0.0.0.0:80 failed (98: Address already in use)",
+		"journalctl -u sshd.service -n 50 --no-pager -o short":         "sshd: /etc/ssh/sshd_config line 12: Bad configuration option",
+	}, map[string]error{
+		"journalctl -u home-manager-alice.service -n 50 --no-pager -o short": errors.New("exit status 1"),
+	})
+	provider := &scriptedProvider{responses: []string{
+		"Port 80 is taken by another server.",
+		"An invalid extraConfig line.",
+		"A file would be clobbered.",
+		"Fix the port conflict first.",
+	}}
+
+	var out bytes.Buffer
+	if err := diagnoseFailedServices(&out, outputMarkdown, run, provider.Query, "after upgrading"); err != nil {
+		t.Fatal(err)
+	}
+
+	if provider.calls != 4 {
+		t.Fatalf("expected an analysis per unit and a summary, got %d queries", provider.calls)
+	}
+	nginxPrompt := provider.prompts[0]
+	for _, want := range []string{"nginx.service", "Address already in use", "services.nginx", "after upgrading"} {
+		if !strings.Contains(nginxPrompt, want) {
+			t.Errorf("nginx prompt missing %q:\n%s", want, nginxPrompt)
+		}
+	}
+	if !strings.Contains(provider.prompts[2], "no journal entries: exit status 1") {
+		t.Errorf("home-manager prompt should note the missing journal:\n%s", provider.prompts[2])
+	}
+	summaryPrompt := provider.prompts[3]
+	for _, want := range []string{"## sshd.service (services.openssh)", "An invalid extraConfig line."} {
+		if !strings.Contains(summaryPrompt, want) {
+			t.Errorf("summary prompt missing %q:\n%s", want, summaryPrompt)
+		}
+	}
+
+	output := out.String()
+	for _, want := range []string{"3 failed units", "Port 80 is taken", "Summary", "Fix the port conflict first."} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestDiagnoseFailedServicesJSON(t *testing.T) {
+	run := fakeRunner(map[string]string{
+		"systemctl list-units --failed --no-legend --plain --no-pager": failedUnitsOutput,
+	}, nil)
+	provider := &scriptedProvider{responses: []string{"Port 80 is taken.", "Bad option.", "Clobbered file.", "Fix nginx first."}}
+
+	var out bytes.Buffer
+	if err := diagnoseFailedServices(&out, outputJSON, run, provider.Query, ""); err != nil {
+		t.Fatal(err)
+	}
+	var diagnosis failedServicesDiagnosis
+	if err := json.Unmarshal(out.Bytes(), &diagnosis); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if len(diagnosis.FailedUnits) != 3 || diagnosis.FailedUnits[0].Option != "services.nginx" ||
+		diagnosis.FailedUnits[0].Analysis != "Port 80 is taken." || diagnosis.Summary != "Fix nginx first." {
+		t.Errorf("unexpected diagnosis: %+v", diagnosis)
+	}
+
+	// No failed units is an empty list, not null
+	out.Reset()
+	if err := diagnoseFailedServices(&out, outputJSON, fakeRunner(nil, nil), provider.Query, ""); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"failed_units": []`) {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+}
+
+func TestDiagnoseFailedServicesWithoutAI(t *testing.T) {
+	run := fakeRunner(map[string]string{
+		"systemctl list-units --failed --no-legend --plain --no-pager": failedUnitsOutput,
+	}, nil)
+
+	var out bytes.Buffer
+	if err := diagnoseFailedServices(&out, outputMarkdown, run, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"3 failed units", "services.nginx", "--no-ai"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := diagnoseFailedServices(&out, outputJSON, run, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	var diagnosis failedServicesDiagnosis
+	if err := json.Unmarshal(out.Bytes(), &diagnosis); err != nil || len(diagnosis.FailedUnits) != 3 || diagnosis.FailedUnits[0].Analysis != "" {
+		t.Errorf("unexpected diagnosis without AI: %+v, %v", diagnosis, err)
+	}
+}
+
+func TestDiagnoseFailedServicesNoneFailed(t *testing.T) {
+	run := fakeRunner(nil, nil)
+	provider := &scriptedProvider{}
+
+	var out bytes.Buffer
+	if err := diagnoseFailedServices(&out, outputMarkdown, run, provider.Query, ""); err != nil {
+		t.Fatal(err)
+	}
+	if provider.calls != 0 {
+		t.Errorf("no unit failed, but the provider was queried %d times", provider.calls)
+	}
+	if !strings.Contains(out.String(), "No failed systemd units") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestDiagnoseFailedServicesListError(t *testing.T) {
+	run := fakeRunner(nil, map[string]error{
+		"systemctl list-units --failed --no-legend --plain --no-pager": errors.New("systemctl not found"),
+	})
+	err := diagnoseFailedServices(&bytes.Buffer{}, outputMarkdown, run, (&scriptedProvider{}).Query, "")
+	if err == nil || !strings.Contains(err.Error(), "systemctl not found") {
+		t.Fatalf("expected the systemctl error, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+
+	"nix-ai-help/pkg/utils"
+)
+
+// diagnoseWatchDebounce is how long diagnose --watch waits after the last change to the
+// watched file before re-running, so that one save, which editors often perform as several
+// writes or a rename, triggers a single diagnosis
+const diagnoseWatchDebounce = 500 * time.Millisecond
+
+// watchFileChanges calls rerun once the file at path has stopped changing for debounce, and
+// keeps doing so until the events channel is closed. The events are those of the directory
+// holding the file: editors that save by writing a new file and renaming it over the old one
+// would otherwise end a watch on the file itself.
+func watchFileChanges(path string, events <-chan fsnotify.Event, errs <-chan error, debounce time.Duration, warn io.Writer, rerun func()) {
+	path = filepath.Clean(path)
+	var timer *time.Timer
+	var fire <-chan time.Time
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			}
+			if filepath.Clean(event.Name) != path || !event.Has(fsnotify.Write|fsnotify.Create) {
+				continue
+			}
+			if timer == nil {
+				timer = time.NewTimer(debounce)
+			} else {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(debounce)
+			}
+			fire = timer.C
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			_, _ = fmt.Fprintln(warn, utils.FormatWarning("File watcher error: "+err.Error()))
+		case <-fire:
+			fire = nil
+			rerun()
+		}
+	}
+}
+
+// watchDiagnose re-runs rerun whenever the file at path changes, until the process is
+// interrupted
+func watchDiagnose(out io.Writer, path string, rerun func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", path, err)
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatTip(fmt.Sprintf("Watching %s; the diagnosis re-runs when it changes. Press Ctrl-C to stop.", path)))
+	watchFileChanges(path, watcher.Events, watcher.Errors, diagnoseWatchDebounce, out, rerun)
+	return nil
+}
+
+// validateDiagnoseWatch rejects the diagnose options that do not make sense with --watch: the
+// watched file is the input, and a verdict or JSON document per save cannot be consumed. It
+// returns the output format to watch with: a JSON output.default_format falls back to
+// markdown, while an explicit --json is rejected.
+func validateDiagnoseWatch(cmd *cobra.Command, args []string, outputFormat string) (string, error) {
+	if len(args) > 0 || cmd.Flags().Changed("file") {
+		return "", fmt.Errorf("--watch takes the file to diagnose; do not also pass a log file")
+	}
+	for _, flag := range []string{"summary", "regression", "services"} {
+		if on, _ := cmd.Flags().GetBool(flag); on {
+			return "", fmt.Errorf("--watch cannot be combined with --%s", flag)
+		}
+	}
+	return textOutputFormat(cmd.Flags(), outputFormat, "--watch")
+}
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+)
+
+func TestWatchFileChangesDebouncesReruns(t *testing.T) {
+	events := make(chan fsnotify.Event)
+	errs := make(chan error)
+	reruns := make(chan struct{}, 10)
+	done := make(chan struct{})
+	var warn bytes.Buffer
+	go func() {
+		watchFileChanges("/etc/nixos/configuration.nix", events, errs, 50*time.Millisecond, &warn, func() { reruns <- struct{}{} })
+		close(done)
+	}()
+
+	// A burst of writes, as an editor saving the file produces, runs the diagnosis once
+	events <- fsnotify.Event{Name: "/etc/nixos/configuration.nix", Op: fsnotify.Write}
+	events <- fsnotify.Event{Name: "/etc/nixos/configuration.nix", Op: fsnotify.Write}
+	events <- fsnotify.Event{Name: "/etc/nixos/configuration.nix", Op: fsnotify.Create}
+	select {
+	case <-reruns:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a change to trigger a re-run")
+	}
+	select {
+	case <-reruns:
+		t.Fatal("expected one re-run per burst of changes")
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	// Other files of the directory and attribute changes are ignored
+	events <- fsnotify.Event{Name: "/etc/nixos/hardware-configuration.nix", Op: fsnotify.Write}
+	events <- fsnotify.Event{Name: "/etc/nixos/.configuration.nix.swp", Op: fsnotify.Create}
+	events <- fsnotify.Event{Name: "/etc/nixos/configuration.nix", Op: fsnotify.Chmod}
+	select {
+	case <-reruns:
+		t.Fatal("expected unrelated events not to trigger a re-run")
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	// A later save runs it again
+	events <- fsnotify.Event{Name: "/etc/nixos/configuration.nix", Op: fsnotify.Write}
+	select {
+	case <-reruns:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a second save to trigger another re-run")
+	}
+
+	errs <- os.ErrPermission
+	close(events)
+	<-done
+	if !strings.Contains(warn.String(), "permission denied") {
+		t.Errorf("expected watcher errors to be reported, got %q", warn.String())
+	}
+}
+
+func TestWatchDiagnoseRerunsOnSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "configuration.nix")
+	if err := os.WriteFile(path, []byte("{ }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	reruns := make(chan string, 10)
+	go func() {
+		_ = watchDiagnose(&bytes.Buffer{}, path, func() {
+			data, _ := os.ReadFile(path)
+			reruns <- string(data)
+		})
+	}()
+
+	// Keep saving until the watcher, started in the background, has picked up a change
+	deadline := time.After(5 * time.Second)
+	for {
+		if err := os.WriteFile(path, []byte("{ services.openssh.enable = true; }\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case content := <-reruns:
+			if !strings.Contains(content, "openssh") {
+				t.Errorf("expected the re-run to see the saved content, got %q", content)
+			}
+			return
+		case <-time.After(2 * diagnoseWatchDebounce):
+		case <-deadline:
+			t.Fatal("expected saving the file to re-run the diagnosis")
+		}
+	}
+}
+
+func TestValidateDiagnoseWatch(t *testing.T) {
+	newCmd := func(flags ...string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().StringP("file", "f", "", "")
+		cmd.Flags().Bool("summary", false, "")
+		cmd.Flags().Bool("regression", false, "")
+		cmd.Flags().Bool("services", false, "")
+		addOutputFormatFlags(cmd)
+		if err := cmd.Flags().Parse(flags); err != nil {
+			t.Fatal(err)
+		}
+		return cmd
+	}
+
+	if format, err := validateDiagnoseWatch(newCmd(), nil, outputMarkdown); err != nil || format != outputMarkdown {
+		t.Errorf("got %q, %v", format, err)
+	}
+	// JSON from output.default_format falls back to text
+	if format, err := validateDiagnoseWatch(newCmd(), nil, outputJSON); err != nil || format != outputMarkdown {
+		t.Errorf("JSON default: got %q, %v; want markdown", format, err)
+	}
+	for name, flags := range map[string][]string{
+		"--json":        {"--json"},
+		"--output json": {"--output", "json"},
+		"--file":        {"--file", "build.log"},
+		"--summary":     {"--summary"},
+		"--regression":  {"--regression"},
+	} {
+		cmd := newCmd(flags...)
+		format, err := resolveOutputFormat(cmd.Flags(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := validateDiagnoseWatch(cmd, nil, format); err == nil {
+			t.Errorf("expected --watch with %s to be rejected", name)
+		}
+	}
+	if _, err := validateDiagnoseWatch(newCmd(), []string{"/var/log/messages"}, outputMarkdown); err == nil {
+		t.Error("expected --watch with a log argument to be rejected")
+	}
+}
@@ -12,7 +12,6 @@ import (
 
 	"nix-ai-help/internal/ai"
 	nixoscontext "nix-ai-help/internal/ai/context"
-	"nix-ai-help/internal/ai/roles"
 	"nix-ai-help/internal/community"
 	"nix-ai-help/internal/config"
 	"nix-ai-help/internal/mcp"
@@ -189,6 +188,15 @@ func showConfigWithOutput(out io.Writer) {
 	_, _ = fmt.Fprintln(out)
 	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("AI Provider", cfg.AIProvider))
 	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("AI Model", cfg.AIModel))
+	if cfg.Language != "" {
+		_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Response Language", cfg.Language))
+	}
+	if cfg.Output.DefaultFormat != "" {
+		_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Default Output Format", cfg.Output.DefaultFormat))
+	}
+	if cfg.Network.Proxy != "" {
+		_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Proxy", config.RedactProxy(cfg.Network.Proxy)))
+	}
 	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Log Level", cfg.LogLevel))
 	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("NixOS Folder", cfg.NixosFolder))
 	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("MCP Host", cfg.MCPServer.Host))
@@ -203,6 +211,7 @@ func setConfigWithOutput(out io.Writer, key, value string) {
 		_, _ = fmt.Fprintln(out, utils.FormatError("Failed to load config: "+err.Error()))
 		return
 	}
+	oldMCPServer := cfg.MCPServer
 
 	switch key {
 	case "ai_provider":
@@ -224,6 +233,22 @@ func setConfigWithOutput(out io.Writer, key, value string) {
 		cfg.AIProvider = value
 	case "ai_model":
 		cfg.AIModel = value
+	case "language":
+		cfg.Language = value
+	case "output.default_format":
+		if !validOutputFormat(value) {
+			_, _ = fmt.Fprintln(out, utils.FormatError("Invalid output format. Valid options: "+strings.Join(outputFormats, ", ")))
+			return
+		}
+		cfg.Output.DefaultFormat = value
+	case "network.proxy":
+		if value != "" {
+			if _, err := utils.ParseProxyURL(value); err != nil {
+				_, _ = fmt.Fprintln(out, utils.FormatError("Invalid proxy: "+err.Error()))
+				return
+			}
+		}
+		cfg.Network.Proxy = value
 	case "log_level":
 		if value != "debug" && value != "info" && value != "warn" && value != "error" {
 			_, _ = fmt.Fprintln(out, utils.FormatError("Invalid log level. Valid options: debug, info, warn, error"))
@@ -242,7 +267,7 @@ func setConfigWithOutput(out io.Writer, key, value string) {
 		}
 	default:
 		_, _ = fmt.Fprintln(out, utils.FormatError("Unknown configuration key: "+key))
-		_, _ = fmt.Fprintln(out, utils.FormatTip("Available keys: ai_provider, ai_model, log_level, nixos_folder, mcp_host, mcp_port"))
+		_, _ = fmt.Fprintln(out, utils.FormatTip("Available keys: ai_provider, ai_model, language, output.default_format, network.proxy, log_level, nixos_folder, mcp_host, mcp_port"))
 		return
 	}
 
@@ -251,9 +276,15 @@ func setConfigWithOutput(out io.Writer, key, value string) {
 		_, _ = fmt.Fprintln(out, utils.FormatError("Failed to save config: "+err.Error()))
 		return
 	}
+	ResetProviderManager()
 
 	_, _ = fmt.Fprintln(out, utils.FormatSuccess("✅ Configuration updated successfully"))
 	_, _ = fmt.Fprintln(out, utils.FormatKeyValue(key, value))
+	if warning := cfg.MissingAPIKeyWarning(); key == "ai_provider" && warning != "" {
+		_, _ = fmt.Fprintln(out, utils.FormatWarning(warning))
+	}
+	// The interactive TUI owns stdin, so only the warning is shown here
+	checkMCPAddressChange(nil, out, oldMCPServer, cfg)
 }
 
 func getConfigWithOutput(out io.Writer, key string) {
@@ -269,6 +300,12 @@ func getConfigWithOutput(out io.Writer, key string) {
 		value = cfg.AIProvider
 	case "ai_model":
 		value = cfg.AIModel
+	case "language":
+		value = cfg.Language
+	case "output.default_format":
+		value = cfg.Output.DefaultFormat
+	case "network.proxy":
+		value = cfg.Network.Proxy
 	case "log_level":
 		value = cfg.LogLevel
 	case "nixos_folder":
@@ -279,7 +316,7 @@ func getConfigWithOutput(out io.Writer, key string) {
 		value = fmt.Sprintf("%d", cfg.MCPServer.Port)
 	default:
 		_, _ = fmt.Fprintln(out, utils.FormatError("Unknown configuration key: "+key))
-		_, _ = fmt.Fprintln(out, utils.FormatTip("Available keys: ai_provider, ai_model, log_level, nixos_folder, mcp_host, mcp_port"))
+		_, _ = fmt.Fprintln(out, utils.FormatTip("Available keys: ai_provider, ai_model, language, output.default_format, network.proxy, log_level, nixos_folder, mcp_host, mcp_port"))
 		return
 	}
 
@@ -293,6 +330,7 @@ func resetConfigWithOutput(out io.Writer) {
 		_, _ = fmt.Fprintln(out, utils.FormatError("Failed to reset config: "+err.Error()))
 		return
 	}
+	ResetProviderManager()
 
 	_, _ = fmt.Fprintln(out, utils.FormatSuccess("✅ Configuration reset to defaults"))
 	_, _ = fmt.Fprintln(out, utils.FormatTip("Use 'config show' to see current settings"))
@@ -306,7 +344,7 @@ func showCommunityOverview(out io.Writer) {
 	_, _ = fmt.Fprintln(out, "  search <query>     - Search community configurations")
 	_, _ = fmt.Fprintln(out, "  share <file>       - Share your configuration")
 	_, _ = fmt.Fprintln(out, "  validate <file>    - Validate configuration against best practices")
-	_, _ = fmt.Fprintln(out, "  trends             - Show trending packages and patterns")
+	_, _ = fmt.Fprintln(out, "  trends             - Show trending configurations and topics")
 	_, _ = fmt.Fprintln(out, "  rate <config> <n>  - Rate a community configuration")
 	_, _ = fmt.Fprintln(out, "  forums             - Show community forums and discussions")
 	_, _ = fmt.Fprintln(out, "  docs               - Show community documentation resources")
@@ -388,6 +426,30 @@ func runConfigCmd(args []string, out io.Writer) {
 		getConfigWithOutput(out, args[1])
 	case "reset":
 		resetConfigWithOutput(out)
+	case "history":
+		if err := showConfigHistory(out); err != nil {
+			_, _ = fmt.Fprintln(out, utils.FormatError(err.Error()))
+		}
+	case "restore":
+		if len(args) < 2 {
+			_, _ = fmt.Fprintln(out, "Usage: nixai config restore <timestamp>")
+			return
+		}
+		if err := restoreConfig(out, args[1]); err != nil {
+			_, _ = fmt.Fprintln(out, utils.FormatError("Failed to restore config: "+err.Error()))
+		}
+	case "export":
+		if err := exportConfig(out, false); err != nil {
+			_, _ = fmt.Fprintln(out, utils.FormatError(err.Error()))
+		}
+	case "import":
+		if len(args) < 2 {
+			_, _ = fmt.Fprintln(out, "Usage: nixai config import <file>")
+			return
+		}
+		if err := importConfig(out, args[1]); err != nil {
+			_, _ = fmt.Fprintln(out, utils.FormatError("Failed to import config: "+err.Error()))
+		}
 	default:
 		_, _ = fmt.Fprintln(out, "Unknown config command: "+args[0])
 	}
@@ -425,6 +487,7 @@ func NewConfigureCommand() *cobra.Command {
 	}
 	cmd.PersistentFlags().AddFlagSet(configureCmd.PersistentFlags())
 	cmd.Flags().AddFlagSet(configureCmd.Flags())
+	cmd.AddCommand(newConfigureExplainCmd())
 	return cmd
 }
 
@@ -507,11 +570,12 @@ func showFlakeOptions(out io.Writer) {
 	_, _ = fmt.Fprintln(out, utils.FormatHeader("❄️  Flake Options"))
 	_, _ = fmt.Fprintln(out)
 	_, _ = fmt.Fprintln(out, utils.FormatSubsection("Available Commands", ""))
-	_, _ = fmt.Fprintln(out, "  init          - Initialize a new flake")
-	_, _ = fmt.Fprintln(out, "  update        - Update flake inputs")
-	_, _ = fmt.Fprintln(out, "  show          - Show flake information")
-	_, _ = fmt.Fprintln(out, "  lock          - Update flake.lock")
-	_, _ = fmt.Fprintln(out, "  metadata      - Show flake metadata")
+	_, _ = fmt.Fprintln(out, "  init           - Initialize a new flake")
+	_, _ = fmt.Fprintln(out, "  update         - Update flake inputs (--dry-run to preview)")
+	_, _ = fmt.Fprintln(out, "  show [ref]     - Show flake information")
+	_, _ = fmt.Fprintln(out, "  lock           - Update flake.lock (--dry-run to preview)")
+	_, _ = fmt.Fprintln(out, "  metadata [ref] - Show flake metadata")
+	_, _ = fmt.Fprintln(out, "  diff-options   - Show how the options you set changed between releases (--from, --to)")
 	_, _ = fmt.Fprintln(out)
 	_, _ = fmt.Fprintln(out, utils.FormatTip("All commands run nix flake operations with proper error handling"))
 }
@@ -519,6 +583,8 @@ func showFlakeOptions(out io.Writer) {
 func runFlakeValidate(args []string, out io.Writer) {
 	_, _ = fmt.Fprintln(out, utils.FormatHeader("✅ Validating Flake Configuration"))
 	_, _ = fmt.Fprintln(out)
+	args, explain := splitExplainFlag(args)
+	explain = explain || flakeExplain
 
 	// Determine the correct flake path using user config or arguments
 	var flakePath string
@@ -579,7 +645,7 @@ func runFlakeValidate(args []string, out io.Writer) {
 	// Run nix flake check command from the flake directory
 	cmd := exec.Command("nix", "flake", "check")
 	cmd.Dir = flakeDir
-	output, err := cmd.CombinedOutput()
+	output, err := utils.TraceCombinedOutput(cmd)
 
 	if err != nil {
 		_, _ = fmt.Fprintln(out, utils.FormatError("Flake validation failed: "+err.Error()))
@@ -587,6 +653,7 @@ func runFlakeValidate(args []string, out io.Writer) {
 			_, _ = fmt.Fprintln(out, utils.FormatSubsection("Error Details", ""))
 			_, _ = fmt.Fprintln(out, string(output))
 		}
+		explainFailedFlakeCheck(out, string(output), explain)
 		return
 	}
 
@@ -610,7 +677,7 @@ func runFlakeInit(args []string, out io.Writer) {
 		cmd = exec.Command("nix", "flake", "init")
 	}
 
-	output, err := cmd.CombinedOutput()
+	output, err := utils.TraceCombinedOutput(cmd)
 	if err != nil {
 		_, _ = fmt.Fprintln(out, utils.FormatError("Flake initialization failed: "+err.Error()))
 		if len(output) > 0 {
@@ -628,11 +695,15 @@ func runFlakeInit(args []string, out io.Writer) {
 func runFlakeUpdate(args []string, out io.Writer) {
 	_, _ = fmt.Fprintln(out, utils.FormatHeader("🔄 Updating Flake Inputs"))
 	_, _ = fmt.Fprintln(out)
+	if flakeDryRun || containsString(args, "--dry-run") {
+		runFlakeDryRun(out, runFlakeCommand, ".", "update")
+		return
+	}
 	_, _ = fmt.Fprintln(out, utils.FormatInfo("Updating flake inputs..."))
 
 	// Run nix flake update
 	cmd := exec.Command("nix", "flake", "update")
-	output, err := cmd.CombinedOutput()
+	output, err := utils.TraceCombinedOutput(cmd)
 	if err != nil {
 		_, _ = fmt.Fprintln(out, utils.FormatError("Flake update failed: "+err.Error()))
 		if len(output) > 0 {
@@ -651,9 +722,15 @@ func runFlakeShow(args []string, out io.Writer) {
 	_, _ = fmt.Fprintln(out, utils.FormatHeader("📊 Showing Flake Information"))
 	_, _ = fmt.Fprintln(out)
 
-	// Run nix flake show
-	cmd := exec.Command("nix", "flake", "show")
-	output, err := cmd.CombinedOutput()
+	nixArgs, err := flakeRefCommandArgs("show", args)
+	if err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatError(err.Error()))
+		_, _ = fmt.Fprintln(out, utils.FormatTip("Pass a flake reference such as github:owner/repo, ./path or .#"))
+		return
+	}
+
+	// Run nix flake show, on the given flake reference if any
+	output, err := runFlakeCommand("nix", nixArgs...)
 	if err != nil {
 		_, _ = fmt.Fprintln(out, utils.FormatError("Failed to show flake information: "+err.Error()))
 		if len(output) > 0 {
@@ -663,17 +740,21 @@ func runFlakeShow(args []string, out io.Writer) {
 	}
 
 	_, _ = fmt.Fprintln(out, utils.FormatSuccess("Flake outputs:"))
-	_, _ = fmt.Fprintln(out, string(output))
+	writePaged(out, string(output)+"\n")
 }
 
 func runFlakeLock(args []string, out io.Writer) {
 	_, _ = fmt.Fprintln(out, utils.FormatHeader("🔒 Updating Flake Lock"))
 	_, _ = fmt.Fprintln(out)
+	if flakeDryRun || containsString(args, "--dry-run") {
+		runFlakeDryRun(out, runFlakeCommand, ".", "lock")
+		return
+	}
 	_, _ = fmt.Fprintln(out, utils.FormatInfo("Updating flake.lock file..."))
 
 	// Run nix flake lock
 	cmd := exec.Command("nix", "flake", "lock")
-	output, err := cmd.CombinedOutput()
+	output, err := utils.TraceCombinedOutput(cmd)
 	if err != nil {
 		_, _ = fmt.Fprintln(out, utils.FormatError("Flake lock update failed: "+err.Error()))
 		if len(output) > 0 {
@@ -692,9 +773,15 @@ func runFlakeMetadata(args []string, out io.Writer) {
 	_, _ = fmt.Fprintln(out, utils.FormatHeader("📋 Flake Metadata"))
 	_, _ = fmt.Fprintln(out)
 
-	// Run nix flake metadata
-	cmd := exec.Command("nix", "flake", "metadata")
-	output, err := cmd.CombinedOutput()
+	nixArgs, err := flakeRefCommandArgs("metadata", args)
+	if err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatError(err.Error()))
+		_, _ = fmt.Fprintln(out, utils.FormatTip("Pass a flake reference such as github:owner/repo, ./path or .#"))
+		return
+	}
+
+	// Run nix flake metadata, on the given flake reference if any
+	output, err := runFlakeCommand("nix", nixArgs...)
 	if err != nil {
 		_, _ = fmt.Fprintln(out, utils.FormatError("Failed to get flake metadata: "+err.Error()))
 		if len(output) > 0 {
@@ -729,9 +816,11 @@ func runFlakeCmd(args []string, out io.Writer) {
 		runFlakeLock(args[1:], out)
 	case "metadata":
 		runFlakeMetadata(args[1:], out)
+	case "diff-options":
+		runFlakeDiffOptions(args[1:], out)
 	default:
 		_, _ = fmt.Fprintln(out, utils.FormatWarning("Unknown or unimplemented flake subcommand: "+subcommand))
-		_, _ = fmt.Fprintln(out, utils.FormatTip("Available commands: validate, check, init, update, show, lock, metadata"))
+		_, _ = fmt.Fprintln(out, utils.FormatTip("Available commands: validate, check, init, update, show, lock, metadata, diff-options"))
 	}
 }
 
@@ -745,8 +834,11 @@ func showLearningOptions(out io.Writer) {
 	_, _ = fmt.Fprintln(out, "  packages      - Package management")
 	_, _ = fmt.Fprintln(out, "  services      - System services")
 	_, _ = fmt.Fprintln(out, "  flakes        - Nix flakes system")
+	_, _ = fmt.Fprintln(out, "  home-manager  - Home Manager")
 	_, _ = fmt.Fprintln(out, "  advanced      - Advanced topics")
 	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintln(out, utils.FormatTip("Run 'nixai learn path' for a learning path based on your system"))
+	_, _ = fmt.Fprintln(out, utils.FormatTip("Run 'nixai learn review' to practise the topics you studied"))
 	_, _ = fmt.Fprintln(out, utils.FormatTip("Interactive tutorials coming soon"))
 }
 
@@ -756,9 +848,18 @@ func runLearnCmd(args []string, out io.Writer) {
 		showLearningOptions(out)
 		return
 	}
+	switch args[0] {
+	case "path":
+		showLearningPath(out)
+		return
+	case "review":
+		runLearnReview(out)
+		return
+	}
 	topic := args[0]
 	_, _ = fmt.Fprintln(out, "Learning module:", topic)
 	_, _ = fmt.Fprintln(out, "This would launch an interactive tutorial or quiz.")
+	markTopicStudied(out, topic)
 }
 
 // Logs helper functions
@@ -863,6 +964,7 @@ func showMCPServerOptions(out io.Writer) {
 	_, _ = fmt.Fprintln(out, "  status        - Check server status")
 	_, _ = fmt.Fprintln(out, "  logs          - View server logs")
 	_, _ = fmt.Fprintln(out, "  config        - Show server configuration")
+	_, _ = fmt.Fprintln(out, "  query <text>  - Query the documentation (--source, --json)")
 	_, _ = fmt.Fprintln(out)
 	_, _ = fmt.Fprintln(out, utils.FormatTip("MCP server provides documentation integration"))
 }
@@ -882,6 +984,8 @@ func runMCPServerCmd(args []string, out io.Writer) {
 		_, _ = fmt.Fprintln(out, "MCP server is running.")
 	case "logs":
 		_, _ = fmt.Fprintln(out, "No recent logs found.")
+	case "query":
+		runCobraCommand(newMCPQueryCmd(), args[1:], out)
 	default:
 		_, _ = fmt.Fprintln(out, utils.FormatWarning("Unknown or unimplemented mcp-server subcommand: "+args[0]))
 	}
@@ -1359,7 +1463,7 @@ func runSearchCmd(args []string, out io.Writer) {
 		doc, err := mcpClient.QueryDocumentation(query)
 		_, _ = fmt.Fprintln(out, utils.FormatSuccess("done"))
 		if err == nil && doc != "" {
-			opt, fallbackDoc := parseMCPOptionDoc(doc)
+			opt, fallbackDoc := parseMCPOptionDocNamed(doc, query)
 			if opt.Name != "" {
 				context := fmt.Sprintf("Option: %s\nType: %s\nDefault: %s\nExample: %s\nDescription: %s\nSource: %s\nNixOS Version: %s\nRelated: %v\nLinks: %v", opt.Name, opt.Type, opt.Default, opt.Example, opt.Description, opt.Source, opt.Version, opt.Related, opt.Links)
 				docExcerpts = append(docExcerpts, context)
@@ -1418,7 +1522,7 @@ func runAskCmdWithStreaming(args []string, out io.Writer, providerParam, modelPa
 	}
 
 	// Create AI provider manager
-	manager := ai.NewProviderManager(cfg, logger.NewLogger())
+	manager := GetAIProviderManager(cfg, logger.NewLogger())
 
 	// Determine which provider to use
 	selectedProvider := cfg.AIModels.SelectionPreferences.DefaultProvider
@@ -1431,7 +1535,6 @@ func runAskCmdWithStreaming(args []string, out io.Writer, providerParam, modelPa
 		selectedProvider = "ollama"
 	}
 
-
 	var provider ai.Provider
 	if modelParam != "" {
 		provider, err = manager.GetProviderWithModel(selectedProvider, modelParam)
@@ -1450,21 +1553,32 @@ func runAskCmdWithStreaming(args []string, out io.Writer, providerParam, modelPa
 	_, _ = fmt.Fprintln(out, utils.FormatDivider())
 
 	// Build prompt (simplified for streaming)
-	prompt := fmt.Sprintf("You are a NixOS expert. Answer this question about NixOS: %s", question)
+	prompt := withLanguageInstruction(withLengthInstruction(fmt.Sprintf("You are a NixOS expert.%s%s\n\nAnswer this question about NixOS: %s", followUpContext(out), antipatternContext(out, question), question)), cfg)
 
 	// Start streaming
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	responseChan, err := provider.StreamResponse(ctx, prompt)
+	responseChan, err := startAnswerStream(ctx, out, provider, selectedProvider, prompt)
 	if err != nil {
 		_, _ = fmt.Fprintln(out, utils.FormatError("Failed to start streaming: "+err.Error()))
 		return
 	}
 
+	// Chunks go through a SafeWriter so that Ctrl-C cannot cut a write in half. Unless --raw is
+	// set, each markdown block is rendered as soon as it is complete.
+	stream := utils.NewSafeWriter(out)
+	answer := newStreamRenderer(stream)
+	defer utils.OnInterrupt(func() {
+		_ = answer.Close()
+		_ = stream.Flush()
+	})()
+
 	var fullResponse strings.Builder
 	for chunk := range responseChan {
 		if chunk.Error != nil {
+			_ = answer.Close()
+			_ = stream.Flush()
 			if chunk.Content != "" {
 				_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Partial Response", ""))
 				_, _ = fmt.Fprint(out, chunk.Content)
@@ -1474,13 +1588,22 @@ func runAskCmdWithStreaming(args []string, out io.Writer, providerParam, modelPa
 		}
 
 		// Print chunk content immediately
-		_, _ = fmt.Fprint(out, chunk.Content)
+		_, _ = fmt.Fprint(answer, chunk.Content)
+		_ = stream.Flush()
 		fullResponse.WriteString(chunk.Content)
 
 		if chunk.Done {
 			break
 		}
 	}
+	_ = answer.Close()
+	_ = stream.Flush()
+
+	if strings.TrimSpace(fullResponse.String()) == "" {
+		_, _ = fmt.Fprintln(out, utils.FormatWarning(errEmptyResponse.Error()))
+		return
+	}
+	rememberAskTurn(question, fullResponse.String())
 
 	_, _ = fmt.Fprintln(out)
 	_, _ = fmt.Fprintln(out, utils.FormatDivider())
@@ -1516,7 +1639,7 @@ func runAskCmdWithConciseMode(args []string, out io.Writer, providerParam, model
 	}
 
 	// Create AI provider manager
-	manager := ai.NewProviderManager(cfg, logger.NewLogger())
+	manager := GetAIProviderManager(cfg, logger.NewLogger())
 
 	// Determine which provider to use
 	selectedProvider := cfg.AIModels.SelectionPreferences.DefaultProvider
@@ -1529,7 +1652,6 @@ func runAskCmdWithConciseMode(args []string, out io.Writer, providerParam, model
 		selectedProvider = "ollama"
 	}
 
-
 	var provider ai.Provider
 	if modelParam != "" {
 		provider, err = manager.GetProviderWithModel(selectedProvider, modelParam)
@@ -1549,9 +1671,10 @@ func runAskCmdWithConciseMode(args []string, out io.Writer, providerParam, model
 	var sourceStatus []string
 
 	// 1. MCP server documentation queries (silent)
+	ensureMCPServer(out, cfg)
 	if cfg.MCPServer.Host != "" {
 		_, _ = fmt.Fprintf(out, "📚 ")
-		mcpClient := mcp.NewMCPClient(fmt.Sprintf("http://%s:%d", cfg.MCPServer.Host, cfg.MCPServer.Port))
+		mcpClient := newDocsClient(cfg, nixosCtx)
 
 		sources := []string{
 			"https://wiki.nixos.org/wiki/NixOS_Wiki",
@@ -1592,14 +1715,9 @@ func runAskCmdWithConciseMode(args []string, out io.Writer, providerParam, model
 	_, _ = fmt.Fprintf(out, "📦 ")
 	exec := nixos.NewExecutor(cfg.NixosFolder)
 	searchTerms := extractSearchTerms(question)
-	foundPackages := 0
-	for _, term := range searchTerms {
-		if packageInfo, err := exec.SearchNixPackages(term); err == nil && packageInfo != "" {
-			searchContext = append(searchContext, fmt.Sprintf("Package Search for '%s':\n%s", term, packageInfo))
-			foundPackages++
-		}
-	}
-	if foundPackages > 0 {
+	packageResults := searchPackagesForTerms(exec, searchTerms)
+	searchContext = append(searchContext, packageResults...)
+	if len(packageResults) > 0 {
 		sourceStatus = append(sourceStatus, "packages")
 	}
 
@@ -1611,57 +1729,28 @@ func runAskCmdWithConciseMode(args []string, out io.Writer, providerParam, model
 		githubToken := os.Getenv("GITHUB_TOKEN")
 		githubClient := community.NewGitHubClient(githubToken)
 
-		foundConfigs := 0
-		for _, term := range searchTerms {
-			if len(term) > 3 {
-				configs, err := githubClient.SearchNixOSConfigurations(term)
-				if err == nil && len(configs) > 0 {
-					for i, config := range configs {
-						if i >= 2 {
-							break
-						}
-						githubExamples = append(githubExamples,
-							fmt.Sprintf("Real-world NixOS configuration example (%s):\nRepo: %s\nDescription: %s\nAuthor: %s\nStars: %d\nURL: %s",
-								term, config.Name, config.Description, config.Author, config.Views, config.URL))
-						foundConfigs++
-					}
-				}
-			}
-		}
-		if foundConfigs > 0 {
+		githubExamples = searchGitHubExamples(githubClient, searchTerms).Examples
+		if len(githubExamples) > 0 {
 			sourceStatus = append(sourceStatus, "examples")
 		}
 	}
 
+	// With --min-quality, broaden the search when the context is sparse and keep only the note
+	var githubSearch githubExampleSearch
+	qualityNote := broadenAskContextQuietly(askMinQuality, func() int {
+		return askContextScore(docExcerpts, searchContext, githubExamples, nixosCtx != nil && nixosCtx.CacheValid)
+	}, broaderAskSources(cfg, nixosCtx, broadenSearchTerms(question, searchTerms), &docExcerpts, &searchContext, &githubExamples, &githubSearch))
+
 	_, _ = fmt.Fprintf(out, "🤖 ")
 
+	// Keep the gathered context within the context window of the provider, without the
+	// paragraphs that several documentation queries returned
+	docExcerpts = dedupeAskExcerpts(docExcerpts)
+	fitAskContext(provider.Capabilities().MaxContext, &docExcerpts, &searchContext, &githubExamples)
+
 	// Build comprehensive context-aware prompt
-	contextBuilder := nixoscontext.NewNixOSContextBuilder()
-
-	basePrompt := ""
-	if template, exists := roles.RolePromptTemplate[roles.RoleAsk]; exists {
-		basePrompt = template
-	}
-
-	nixosGuidelines := "ATTENTION: You are a NixOS expert with access to multiple verified sources. NEVER EVER suggest nix-env commands!\n\n" +
-		"CRITICAL ACCURACY RULES:\n" +
-		"❌ NEVER suggest 'nix-env -i' or any nix-env commands\n" +
-		"❌ NEVER recommend manual installation\n" +
-		"❌ NEVER use incorrect flake syntax like 'nixpkgs.nix = {...}'\n" +
-		"❌ NEVER suggest outdated or deprecated options\n\n" +
-		"✅ BLUETOOTH SPECIFIC RULES:\n" +
-		"✅ ALWAYS use 'hardware.bluetooth.enable = true;' for Bluetooth (NOT services.bluetooth.enable)\n" +
-		"✅ Use 'services.blueman.enable = true;' ONLY if user needs a GUI manager\n" +
-		"✅ Mention that both hardware.bluetooth.enable AND services.blueman.enable may be needed\n\n" +
-		"✅ ALWAYS USE configuration.nix for system packages\n" +
-		"✅ ALWAYS USE services.* options for services\n" +
-		"✅ ALWAYS use correct flake syntax: inputs.nixpkgs.url = \"github:...\" and outputs = { self, nixpkgs }: {...}\n" +
-		"✅ ALWAYS verify package names and option paths with provided search results\n" +
-		"✅ ALWAYS end with 'sudo nixos-rebuild switch' for configuration changes\n" +
-		"✅ ALWAYS use examples from the provided real-world GitHub configurations when available\n\n"
-
-	// Build context-aware prompt
-	contextualPrompt := contextBuilder.BuildContextualPrompt(basePrompt+"\n\n"+nixosGuidelines, nixosCtx)
+	// Build context-aware prompt with the shared ask rules
+	contextualPrompt := buildAskBasePrompt(cfg, nixosCtx)
 
 	// Add documentation context
 	if len(docExcerpts) > 0 {
@@ -1687,20 +1776,10 @@ func runAskCmdWithConciseMode(args []string, out io.Writer, providerParam, model
 	contextualPrompt += "\n\nSYNTHESIS INSTRUCTION: Combine information from official documentation, verified package searches, and real-world examples to provide the most accurate and up-to-date NixOS configuration advice."
 
 	// Add the user question
-	finalPrompt := contextualPrompt + "\n\nUser Question: " + question
+	finalPrompt := withLanguageInstruction(withLengthInstruction(contextualPrompt+followUpContext(out)+antipatternContext(out, question)+"\n\nUser Question: "+question), cfg)
 
 	// Query the AI provider (silent)
-	ctx := context.Background()
-	var response string
-	if p, ok := provider.(interface {
-		QueryWithContext(context.Context, string) (string, error)
-	}); ok {
-		response, err = p.QueryWithContext(ctx, finalPrompt)
-	} else if p, ok := provider.(interface{ Query(string) (string, error) }); ok {
-		response, err = p.Query(finalPrompt)
-	} else {
-		err = fmt.Errorf("provider does not implement QueryWithContext or Query")
-	}
+	response, cached, err := answerAsk(context.Background(), manager, provider, selectedProvider, askCacheModel(cfg, selectedProvider, modelParam), resolveSampling(cfg, "ask"), question, finalPrompt)
 
 	if err != nil {
 		_, _ = fmt.Fprintln(out, "❌")
@@ -1711,15 +1790,18 @@ func runAskCmdWithConciseMode(args []string, out io.Writer, providerParam, model
 	_, _ = fmt.Fprintln(out, "✅")
 	_, _ = fmt.Fprintln(out)
 
+	rememberAskTurn(question, response)
+
 	// Display the AI response
-	_, _ = fmt.Fprintln(out, utils.RenderMarkdown(response))
+	writePaged(out, renderAIResponse(response)+"\n")
+	writeCacheMarker(out, cached, time.Now())
 
 	// Minimal quality assessment
 	qualityScore := len(sourceStatus)
 	if nixosCtx != nil && nixosCtx.CacheValid {
 		qualityScore++
 	}
-	if strings.Contains(response, "configuration.nix") && !strings.Contains(response, "nix-env") {
+	if strings.Contains(response, "configuration.nix") && len(forbiddenAdviceIn(response, cfg)) == 0 {
 		qualityScore++
 
 	}
@@ -1728,6 +1810,9 @@ func runAskCmdWithConciseMode(args []string, out io.Writer, providerParam, model
 	if len(sourceStatus) > 0 {
 		_, _ = fmt.Fprintf(out, "\n─ %s ─\n", strings.Join(sourceStatus, " • "))
 	}
+	if qualityNote != "" {
+		_, _ = fmt.Fprintln(out, qualityNote)
+	}
 }
 
 // getNixOSContextSummary returns a concise context summary
@@ -1757,6 +1842,10 @@ func getProviderNameFromProvider(provider ai.Provider) string {
 }
 
 func runAskCmd(args []string, out io.Writer) {
+	if err := requireAI("ask"); err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatError(err.Error()))
+		return
+	}
 	// Read provider and model from environment variables set by root command
 	provider := os.Getenv("NIXAI_PROVIDER")
 	model := os.Getenv("NIXAI_MODEL")
@@ -1796,7 +1885,7 @@ func runAskCmdWithOptionsQuiet(args []string, out io.Writer, providerParam, mode
 	nixosCtx, _ := contextDetector.GetContext(cfg)
 
 	// Create modern AI provider using new ProviderManager system
-	manager := ai.NewProviderManager(cfg, logger.NewLogger())
+	manager := GetAIProviderManager(cfg, logger.NewLogger())
 
 	// Determine which provider to use from command flags or config
 	selectedProvider := cfg.AIModels.SelectionPreferences.DefaultProvider
@@ -1834,9 +1923,10 @@ func runAskCmdWithOptionsQuiet(args []string, out io.Writer, providerParam, mode
 	var githubExamples []string
 
 	// 1. MCP server documentation queries (silent)
+	ensureMCPServer(out, cfg)
 	mcpBase := cfg.MCPServer.Host
 	if mcpBase != "" {
-		mcpClient := mcp.NewMCPClient(fmt.Sprintf("http://%s:%d", cfg.MCPServer.Host, cfg.MCPServer.Port))
+		mcpClient := newDocsClient(cfg, nixosCtx)
 
 		sources := []string{
 			"https://wiki.nixos.org/wiki/NixOS_Wiki",
@@ -1875,11 +1965,7 @@ func runAskCmdWithOptionsQuiet(args []string, out io.Writer, providerParam, mode
 	// 2. Package and options search (silent)
 	exec := nixos.NewExecutor(cfg.NixosFolder)
 	searchTerms := extractSearchTerms(question)
-	for _, term := range searchTerms {
-		if packageInfo, err := exec.SearchNixPackages(term); err == nil && packageInfo != "" {
-			searchContext = append(searchContext, fmt.Sprintf("Package Search for '%s':\n%s", term, packageInfo))
-		}
-	}
+	searchContext = append(searchContext, searchPackagesForTerms(exec, searchTerms)...)
 
 	// 3. GitHub code search (silent)
 	if strings.Contains(question, "flake") || strings.Contains(question, "configuration") ||
@@ -1888,50 +1974,26 @@ func runAskCmdWithOptionsQuiet(args []string, out io.Writer, providerParam, mode
 		githubToken := os.Getenv("GITHUB_TOKEN")
 		githubClient := community.NewGitHubClient(githubToken)
 
-		for _, term := range searchTerms {
-			if len(term) > 3 {
-				configs, err := githubClient.SearchNixOSConfigurations(term)
-				if err == nil && len(configs) > 0 {
-					for i, config := range configs {
-						if i >= 2 {
-							break
-						}
-						githubExamples = append(githubExamples,
-							fmt.Sprintf("Real-world NixOS configuration example (%s):\nRepo: %s\nDescription: %s\nAuthor: %s\nStars: %d\nURL: %s",
-								term, config.Name, config.Description, config.Author, config.Views, config.URL))
-					}
-				}
-			}
-		}
+		githubExamples = searchGitHubExamples(githubClient, searchTerms).Examples
+	}
+
+	// With --min-quality, broaden the search when the context is sparse. The note goes to
+	// stderr so that plain and JSON answers stay clean.
+	var githubSearch githubExampleSearch
+	if note := broadenAskContextQuietly(askMinQuality, func() int {
+		return askContextScore(docExcerpts, searchContext, githubExamples, nixosCtx != nil && nixosCtx.CacheValid)
+	}, broaderAskSources(cfg, nixosCtx, broadenSearchTerms(question, searchTerms), &docExcerpts, &searchContext, &githubExamples, &githubSearch)); note != "" {
+		fmt.Fprintln(os.Stderr, note)
 	}
 
+	// Keep the gathered context within the context window of the provider, without the
+	// paragraphs that several documentation queries returned
+	docExcerpts = dedupeAskExcerpts(docExcerpts)
+	fitAskContext(provider.Capabilities().MaxContext, &docExcerpts, &searchContext, &githubExamples)
+
 	// 4. Build comprehensive context-aware prompt
-	contextBuilder := nixoscontext.NewNixOSContextBuilder()
-
-	basePrompt := ""
-	if template, exists := roles.RolePromptTemplate[roles.RoleAsk]; exists {
-		basePrompt = template
-	}
-
-	nixosGuidelines := "ATTENTION: You are a NixOS expert with access to multiple verified sources. NEVER EVER suggest nix-env commands!\n\n" +
-		"CRITICAL ACCURACY RULES:\n" +
-		"❌ NEVER suggest 'nix-env -i' or any nix-env commands\n" +
-		"❌ NEVER recommend manual installation\n" +
-		"❌ NEVER use incorrect flake syntax like 'nixpkgs.nix = {...}'\n" +
-		"❌ NEVER suggest outdated or deprecated options\n\n" +
-		"✅ BLUETOOTH SPECIFIC RULES:\n" +
-		"✅ ALWAYS use 'hardware.bluetooth.enable = true;' for Bluetooth (NOT services.bluetooth.enable)\n" +
-		"✅ Use 'services.blueman.enable = true;' ONLY if user needs a GUI manager\n" +
-		"✅ Mention that both hardware.bluetooth.enable AND services.blueman.enable may be needed\n\n" +
-		"✅ ALWAYS USE configuration.nix for system packages\n" +
-		"✅ ALWAYS USE services.* options for services\n" +
-		"✅ ALWAYS use correct flake syntax: inputs.nixpkgs.url = \"github:...\" and outputs = { self, nixpkgs }: {...}\n" +
-		"✅ ALWAYS verify package names and option paths with provided search results\n" +
-		"✅ ALWAYS end with 'sudo nixos-rebuild switch' for configuration changes\n" +
-		"✅ ALWAYS use examples from the provided real-world GitHub configurations when available\n\n"
-
-	// Build context-aware prompt
-	contextualPrompt := contextBuilder.BuildContextualPrompt(basePrompt+"\n\n"+nixosGuidelines, nixosCtx)
+	// Build context-aware prompt with the shared ask rules
+	contextualPrompt := buildAskBasePrompt(cfg, nixosCtx)
 
 	// Add documentation context
 	if len(docExcerpts) > 0 {
@@ -1954,28 +2016,20 @@ func runAskCmdWithOptionsQuiet(args []string, out io.Writer, providerParam, mode
 	contextualPrompt += "\n\nSYNTHESIS INSTRUCTION: Combine information from official documentation, verified package searches, and real-world examples to provide the most accurate and up-to-date NixOS configuration advice."
 
 	// Add the user question
-	finalPrompt := contextualPrompt + "\n\nUser Question: " + question
+	finalPrompt := withLanguageInstruction(withLengthInstruction(contextualPrompt+followUpContext(io.Discard)+antipatternContext(io.Discard, question)+"\n\nUser Question: "+question), cfg)
 
 	// Query the AI provider (silent)
-	ctx := context.Background()
-	var response string
-	if p, ok := provider.(interface {
-		QueryWithContext(context.Context, string) (string, error)
-	}); ok {
-		response, err = p.QueryWithContext(ctx, finalPrompt)
-	} else if p, ok := provider.(interface{ Query(string) (string, error) }); ok {
-		response, err = p.Query(finalPrompt)
-	} else {
-		err = fmt.Errorf("provider does not implement QueryWithContext or Query")
-	}
+	response, _, err := answerAsk(context.Background(), manager, provider, selectedProvider, askCacheModel(cfg, selectedProvider, modelParam), resolveSampling(cfg, "ask"), question, finalPrompt)
 
 	if err != nil {
 		_, _ = fmt.Fprintln(out, utils.FormatError("AI error: "+err.Error()))
 		return
 	}
 
+	rememberAskTurn(question, response)
+
 	// Display only the AI response (no validation output)
-	_, _ = fmt.Fprintln(out, utils.RenderMarkdown(response))
+	writeAskAnswer(out, question, response, selectedProvider)
 }
 
 // runAskCmdWithOptions is the original verbose version with full validation and multi-source information gathering
@@ -2018,7 +2072,7 @@ func runAskCmdWithOptions(args []string, out io.Writer, providerParam, modelPara
 	}
 
 	// Create modern AI provider using new ProviderManager system
-	manager := ai.NewProviderManager(cfg, logger.NewLogger())
+	manager := GetAIProviderManager(cfg, logger.NewLogger())
 
 	// Determine which provider to use from command flags or config
 	selectedProvider := cfg.AIModels.SelectionPreferences.DefaultProvider
@@ -2035,7 +2089,6 @@ func runAskCmdWithOptions(args []string, out io.Writer, providerParam, modelPara
 		selectedProvider = "ollama"
 	}
 
-
 	// Get the provider with optional model specification
 	var provider ai.Provider
 
@@ -2059,103 +2112,24 @@ func runAskCmdWithOptions(args []string, out io.Writer, providerParam, modelPara
 	var docExcerpts []string
 	var searchContext []string
 	var githubExamples []string
+	var githubSearch githubExampleSearch
 
-	// 1. MCP server documentation queries
-	mcpBase := cfg.MCPServer.Host
-	if mcpBase != "" {
-		_, _ = fmt.Fprint(out, utils.FormatInfo("Querying official documentation... "))
-		mcpClient := mcp.NewMCPClient(fmt.Sprintf("http://%s:%d", cfg.MCPServer.Host, cfg.MCPServer.Port))
-
-		sources := []string{
-			"https://wiki.nixos.org/wiki/NixOS_Wiki",
-			"https://nix.dev/manual/nix",
-			"https://nixos.org/manual/nixpkgs/stable/",
-			"https://nix.dev/manual/nix/2.28/language/",
-			"https://nix-community.github.io/home-manager/",
-		}
-
-		// Primary documentation query
-		doc, mcpErr := mcpClient.QueryDocumentation(question, sources...)
-		if mcpErr == nil && doc != "" {
-			opt, fallbackDoc := parseMCPOptionDoc(doc)
-			if opt.Name != "" {
-				context := fmt.Sprintf("NixOS Option Documentation:\nOption: %s\nType: %s\nDefault: %s\nExample: %s\nDescription: %s\nSource: %s\nVersion: %s\nRelated: %v\nLinks: %v",
-					opt.Name, opt.Type, opt.Default, opt.Example, opt.Description, opt.Source, opt.Version, opt.Related, opt.Links)
-				docExcerpts = append(docExcerpts, context)
-				_, _ = fmt.Fprintln(out, utils.FormatSuccess("found option documentation"))
-			} else if len(fallbackDoc) > 10 && len(fallbackDoc) < 3000 {
-				docExcerpts = append(docExcerpts, "NixOS Documentation Context:\n"+fallbackDoc)
-				_, _ = fmt.Fprintln(out, utils.FormatSuccess("found general documentation"))
-			} else {
-				_, _ = fmt.Fprintln(out, utils.FormatWarning("limited documentation found"))
-			}
-		} else {
-			_, _ = fmt.Fprintln(out, utils.FormatWarning("no documentation found"))
-		}
-
-		// Query for service examples if applicable
-		searchTerms := extractSearchTerms(question)
-		for _, term := range searchTerms {
-			if strings.Contains(question, "service") || strings.Contains(question, "enable") {
-				if serviceDoc, err := mcpClient.QueryDocumentation("service examples for " + term); err == nil && serviceDoc != "" {
-					if len(serviceDoc) > 20 && len(serviceDoc) < 2000 {
-						docExcerpts = append(docExcerpts, fmt.Sprintf("Service Configuration Examples for '%s':\n%s", term, serviceDoc))
-					}
-				}
-			}
-		}
-	} else {
-		_, _ = fmt.Fprintln(out, utils.FormatWarning("MCP server not configured - skipping documentation"))
-	}
-
-	// 2. Package and options search
-	_, _ = fmt.Fprint(out, utils.FormatInfo("Searching packages and options... "))
-	exec := nixos.NewExecutor(cfg.NixosFolder)
 	searchTerms := extractSearchTerms(question)
-	foundPackages := 0
-	for _, term := range searchTerms {
-		if packageInfo, err := exec.SearchNixPackages(term); err == nil && packageInfo != "" {
-			searchContext = append(searchContext, fmt.Sprintf("Package Search for '%s':\n%s", term, packageInfo))
-			foundPackages++
-		}
-	}
-	if foundPackages > 0 {
-		_, _ = fmt.Fprintln(out, utils.FormatSuccess(fmt.Sprintf("found %d package results", foundPackages)))
-	} else {
-		_, _ = fmt.Fprintln(out, utils.FormatWarning("no packages found"))
+	ensureMCPServer(out, cfg)
+	if cfg.MCPServer.Host == "" {
+		_, _ = fmt.Fprintln(out, utils.FormatWarning("MCP server not configured - skipping documentation"))
 	}
+	sources := askSources(cfg, nixosCtx, question, searchTerms, &docExcerpts, &searchContext, &githubExamples, &githubSearch)
 
-	// 3. GitHub code search
-	if strings.Contains(question, "flake") || strings.Contains(question, "configuration") ||
-		strings.Contains(question, "service") || strings.Contains(question, "enable") {
+	// The sources are independent, so gather them concurrently
+	gatherAskSources(out, sources)
 
-		_, _ = fmt.Fprint(out, utils.FormatInfo("Searching real-world configurations... "))
-		githubToken := os.Getenv("GITHUB_TOKEN")
-		githubClient := community.NewGitHubClient(githubToken)
-
-		foundConfigs := 0
-		for _, term := range searchTerms {
-			if len(term) > 3 {
-				configs, err := githubClient.SearchNixOSConfigurations(term)
-				if err == nil && len(configs) > 0 {
-					for i, config := range configs {
-						if i >= 2 {
-							break
-						}
-						githubExamples = append(githubExamples,
-							fmt.Sprintf("Real-world NixOS configuration example (%s):\nRepo: %s\nDescription: %s\nAuthor: %s\nStars: %d\nURL: %s",
-								term, config.Name, config.Description, config.Author, config.Views, config.URL))
-						foundConfigs++
-					}
-				}
-			}
-		}
-		if foundConfigs > 0 {
-			_, _ = fmt.Fprintln(out, utils.FormatSuccess(fmt.Sprintf("found %d configuration examples", foundConfigs)))
-		} else {
-			_, _ = fmt.Fprintln(out, utils.FormatWarning("no configuration examples found"))
-		}
-	}
+	// With --min-quality, broaden the search before asking the AI when the context is sparse
+	broaderSources := broaderAskSources(cfg, nixosCtx, broadenSearchTerms(question, searchTerms),
+		&docExcerpts, &searchContext, &githubExamples, &githubSearch)
+	ensureAskQuality(out, askMinQuality, func() int {
+		return askContextScore(docExcerpts, searchContext, githubExamples, nixosCtx != nil && nixosCtx.CacheValid)
+	}, broaderSources)
 
 	_, _ = fmt.Fprintln(out)
 
@@ -2163,32 +2137,15 @@ func runAskCmdWithOptions(args []string, out io.Writer, providerParam, modelPara
 	_, _ = fmt.Fprintln(out, utils.FormatHeader("🧠 Processing with AI"))
 	_, _ = fmt.Fprintln(out)
 
-	contextBuilder := nixoscontext.NewNixOSContextBuilder()
-
-	basePrompt := ""
-	if template, exists := roles.RolePromptTemplate[roles.RoleAsk]; exists {
-		basePrompt = template
-	}
-
-	nixosGuidelines := "ATTENTION: You are a NixOS expert with access to multiple verified sources. NEVER EVER suggest nix-env commands!\n\n" +
-		"CRITICAL ACCURACY RULES:\n" +
-		"❌ NEVER suggest 'nix-env -i' or any nix-env commands\n" +
-		"❌ NEVER recommend manual installation\n" +
-		"❌ NEVER use incorrect flake syntax like 'nixpkgs.nix = {...}'\n" +
-		"❌ NEVER suggest outdated or deprecated options\n\n" +
-		"✅ BLUETOOTH SPECIFIC RULES:\n" +
-		"✅ ALWAYS use 'hardware.bluetooth.enable = true;' for Bluetooth (NOT services.bluetooth.enable)\n" +
-		"✅ Use 'services.blueman.enable = true;' ONLY if user needs a GUI manager\n" +
-		"✅ Mention that both hardware.bluetooth.enable AND services.blueman.enable may be needed\n\n" +
-		"✅ ALWAYS USE configuration.nix for system packages\n" +
-		"✅ ALWAYS USE services.* options for services\n" +
-		"✅ ALWAYS use correct flake syntax: inputs.nixpkgs.url = \"github:...\" and outputs = { self, nixpkgs }: {...}\n" +
-		"✅ ALWAYS verify package names and option paths with provided search results\n" +
-		"✅ ALWAYS end with 'sudo nixos-rebuild switch' for configuration changes\n" +
-		"✅ ALWAYS use examples from the provided real-world GitHub configurations when available\n\n"
-
-	// Build context-aware prompt
-	contextualPrompt := contextBuilder.BuildContextualPrompt(basePrompt+"\n\n"+nixosGuidelines, nixosCtx)
+	// Keep the gathered context within the context window of the provider, without the
+	// paragraphs that several documentation queries returned
+	docExcerpts = dedupeAskExcerpts(docExcerpts)
+	if dropped := fitAskContext(provider.Capabilities().MaxContext, &docExcerpts, &searchContext, &githubExamples); dropped > 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatNote(fmt.Sprintf("Left out %d context sources that do not fit the context window of %s", dropped, selectedProvider)))
+	}
+
+	// Build context-aware prompt with the shared ask rules
+	contextualPrompt := buildAskBasePrompt(cfg, nixosCtx)
 
 	// Add documentation context
 	if len(docExcerpts) > 0 {
@@ -2214,21 +2171,11 @@ func runAskCmdWithOptions(args []string, out io.Writer, providerParam, modelPara
 	contextualPrompt += "\n\nSYNTHESIS INSTRUCTION: Combine information from official documentation, verified package searches, and real-world examples to provide the most accurate and up-to-date NixOS configuration advice."
 
 	// Add the user question
-	finalPrompt := contextualPrompt + "\n\nUser Question: " + question
+	finalPrompt := withLanguageInstruction(withLengthInstruction(contextualPrompt+followUpContext(out)+antipatternContext(out, question)+"\n\nUser Question: "+question), cfg)
 
 	// Query the AI provider
 	_, _ = fmt.Fprint(out, utils.FormatInfo("Querying AI provider... "))
-	ctx := context.Background()
-	var response string
-	if p, ok := provider.(interface {
-		QueryWithContext(context.Context, string) (string, error)
-	}); ok {
-		response, err = p.QueryWithContext(ctx, finalPrompt)
-	} else if p, ok := provider.(interface{ Query(string) (string, error) }); ok {
-		response, err = p.Query(finalPrompt)
-	} else {
-		err = fmt.Errorf("provider does not implement QueryWithContext or Query")
-	}
+	response, cached, err := answerAsk(context.Background(), manager, provider, selectedProvider, askCacheModel(cfg, selectedProvider, modelParam), resolveSampling(cfg, "ask"), question, finalPrompt)
 
 	if err != nil {
 		_, _ = fmt.Fprintln(out, utils.FormatError("failed"))
@@ -2238,11 +2185,13 @@ func runAskCmdWithOptions(args []string, out io.Writer, providerParam, modelPara
 
 	_, _ = fmt.Fprintln(out, utils.FormatSuccess("complete"))
 	_, _ = fmt.Fprintln(out)
+	rememberAskTurn(question, response)
 
 	// Display the AI response
 	_, _ = fmt.Fprintln(out, utils.FormatHeader("🎯 AI Response"))
 	_, _ = fmt.Fprintln(out)
-	_, _ = fmt.Fprintln(out, utils.RenderMarkdown(response))
+	writePaged(out, renderAIResponse(response)+"\n")
+	writeCacheMarker(out, cached, time.Now())
 
 	// Add quality indicators and help information
 	_, _ = fmt.Fprintln(out)
@@ -2267,10 +2216,15 @@ func runAskCmdWithOptions(args []string, out io.Writer, providerParam, modelPara
 		_, _ = fmt.Fprintln(out, "⚠️  No package search results found")
 	}
 
-	if len(githubExamples) > 0 {
+	switch {
+	case len(githubExamples) > 0:
 		qualityScore++
 		_, _ = fmt.Fprintln(out, "✅ Real-world configuration examples included")
-	} else {
+	case githubSearch.Skipped:
+		_, _ = fmt.Fprintln(out, "⚠️  Real-world examples skipped (offline)")
+	case githubSearch.Failed():
+		_, _ = fmt.Fprintln(out, "⚠️  GitHub search failed, real-world examples unavailable")
+	default:
 		_, _ = fmt.Fprintln(out, "⚠️  No real-world examples found")
 	}
 
@@ -2281,10 +2235,14 @@ func runAskCmdWithOptions(args []string, out io.Writer, providerParam, modelPara
 		_, _ = fmt.Fprintln(out, "⚠️  Limited system context")
 	}
 
-	if strings.Contains(response, "configuration.nix") && !strings.Contains(response, "nix-env") {
+	forbidden := forbiddenAdviceIn(response, cfg)
+	if strings.Contains(response, "configuration.nix") && len(forbidden) == 0 {
 		qualityScore++
 		_, _ = fmt.Fprintln(out, "✅ Follows NixOS best practices")
 	}
+	if len(forbidden) > 0 {
+		_, _ = fmt.Fprintln(out, "❌ Response mentions forbidden advice: "+strings.Join(forbidden, ", "))
+	}
 
 	_, _ = fmt.Fprintln(out)
 	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Quality Score", fmt.Sprintf("%d/%d", qualityScore, maxScore)))
@@ -2382,7 +2340,10 @@ func RunDirectCommand(cmdName string, args []string, out io.Writer) (bool, error
 		return true, nil
 	case "help":
 		_, _ = fmt.Fprintln(out, utils.FormatHeader("❓ Help: Available Commands"))
-		_, _ = fmt.Fprintln(out, `🤖 ask <question>: Ask any NixOS question\n🛠️ build: Enhanced build troubleshooting and optimization\n🌐 community: Community resources and support\n🔄 completion: Generate the autocompletion script for the specified shell\n⚙️ config: Manage nixai configuration\n🧑‍💻 configure: Configure NixOS interactively\n🔗 deps: Analyze NixOS configuration dependencies and imports\n🧪 devenv: Create and manage development environments with devenv\n🩺 diagnose: Diagnose NixOS issues\n🩻 doctor: Run NixOS health checks\n🖥️ explain-option <option>: Explain a NixOS option\n🧊 flake: Nix flake utilities\n🧹 gc: AI-powered garbage collection analysis and cleanup\n💻 hardware: AI-powered hardware configuration optimizer\n❓ help: Help about any command\n💬 interactive: Launch interactive AI-powered NixOS assistant shell\n📚 learn: NixOS learning and training commands\n📝 logs: Analyze and parse NixOS logs\n🖧 machines: Manage and synchronize NixOS configurations across multiple machines\n🛰️ mcp-server: Start or manage the MCP server\n🔀 migrate: AI-powered migration assistant for channels and flakes\n📝 neovim-setup: Neovim integration setup\n📦 package-repo <url>: Analyze Git repos and generate Nix derivations\n🔍 search <package>: Search for NixOS packages/services and get config/AI tips\n🔖 snippets: Manage NixOS configuration snippets\n💾 store: Manage, backup, and analyze the Nix store\n📄 templates: Manage NixOS configuration templates and snippets\n❌ exit: Exit interactive mode`)
+		for _, line := range commandSummaryLines(rootCmd) {
+			_, _ = fmt.Fprintln(out, line)
+		}
+		_, _ = fmt.Fprintln(out, "exit: Exit interactive mode")
 		return true, nil
 	case "exit":
 		_, _ = fmt.Fprintln(out, utils.FormatTip("Type Ctrl+D or 'exit' to leave interactive mode."))
@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"fmt"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/internal/mcp"
+)
+
+// newDocsClient returns a client of the configured MCP server whose documentation queries use
+// the nixpkgs release found by context detection, so option docs match the user's NixOS
+func newDocsClient(cfg *config.UserConfig, nixosCtx *config.NixOSContext) *mcp.MCPClient {
+	client := mcp.NewMCPClient(fmt.Sprintf("http://%s:%d", cfg.MCPServer.Host, cfg.MCPServer.Port))
+	client.SetRelease(nixpkgsRelease(nixosCtx))
+	return client
+}
+
+// nixpkgsRelease returns the detected nixpkgs release, or "" when it is unknown
+func nixpkgsRelease(nixosCtx *config.NixOSContext) string {
+	if nixosCtx == nil {
+		return ""
+	}
+	return nixosCtx.NixpkgsRelease
+}
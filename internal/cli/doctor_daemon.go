@@ -0,0 +1,228 @@
+package cli
+
+import (
+	"os"
+	"os/user"
+	"strings"
+)
+
+// Paths checked by the nix daemon checks
+const (
+	nixDaemonSocket = "/nix/var/nix/daemon-socket/socket"
+	nixConfPath     = "/etc/nix/nix.conf"
+	nixosMarker     = "/etc/NIXOS"
+)
+
+// daemonCheckEnv is the system seen by the nix daemon checks; tests use a fake one
+type daemonCheckEnv struct {
+	run          commandRunner
+	exists       func(path string) bool
+	readFile     func(path string) ([]byte, error)
+	username     string
+	nixRemote    string // NIX_REMOTE, "daemon" when Nix must use the daemon
+	hasSystemctl bool
+}
+
+// systemDaemonCheckEnv returns the daemon check environment of the running system
+func systemDaemonCheckEnv(hasSystemctl bool) daemonCheckEnv {
+	username := os.Getenv("USER")
+	if current, err := user.Current(); err == nil {
+		username = current.Username
+	}
+	return daemonCheckEnv{
+		run: runDoctorCheckCommand,
+		exists: func(path string) bool {
+			_, err := os.Stat(path)
+			return err == nil
+		},
+		readFile:     os.ReadFile,
+		username:     username,
+		nixRemote:    os.Getenv("NIX_REMOTE"),
+		hasSystemctl: hasSystemctl,
+	}
+}
+
+// daemonChecks checks the nix daemon socket and service, tells single- from multi-user installs
+// apart and looks for common trusted-users mistakes
+func daemonChecks(env daemonCheckEnv) []HealthCheckResult {
+	var results []HealthCheckResult
+
+	if !env.exists(nixDaemonSocket) {
+		if env.exists(nixosMarker) || env.nixRemote == "daemon" {
+			return append(results, HealthCheckResult{
+				Category:    "packages",
+				Name:        "Nix Daemon",
+				Status:      "fail",
+				Description: "nix-daemon socket not found",
+				Details:     nixDaemonSocket + " is missing, so Nix commands cannot reach the daemon",
+				Command:     "sudo systemctl restart nix-daemon.socket nix-daemon.service",
+			})
+		}
+		return append(results, HealthCheckResult{
+			Category:    "packages",
+			Name:        "Nix Installation",
+			Status:      "info",
+			Description: "Single-user Nix installation",
+			Details:     "No nix-daemon socket found; builds run as " + env.username + " without sandboxing by a daemon",
+		})
+	}
+
+	results = append(results, HealthCheckResult{
+		Category:    "packages",
+		Name:        "Nix Installation",
+		Status:      "pass",
+		Description: "Multi-user Nix installation",
+		Details:     "nix-daemon socket found at " + nixDaemonSocket,
+	})
+	if env.hasSystemctl {
+		results = append(results, daemonServiceCheck(env.run))
+	}
+	return append(results, trustedUsersChecks(env)...)
+}
+
+// daemonServiceCheck checks the nix-daemon service; a socket-activated daemon may be inactive
+// until the first Nix command
+func daemonServiceCheck(run commandRunner) HealthCheckResult {
+	// is-active exits non-zero for every state except active, so the output decides
+	output, _ := run("systemctl", "is-active", "nix-daemon.service")
+	state := strings.TrimSpace(string(output))
+	socketOutput, _ := run("systemctl", "is-active", "nix-daemon.socket")
+	socketState := strings.TrimSpace(string(socketOutput))
+
+	switch {
+	case state == "active":
+		return HealthCheckResult{
+			Category:    "packages",
+			Name:        "Nix Daemon",
+			Status:      "pass",
+			Description: "nix-daemon is running",
+			Command:     "systemctl status nix-daemon",
+		}
+	case state == "failed":
+		return HealthCheckResult{
+			Category:    "packages",
+			Name:        "Nix Daemon",
+			Status:      "fail",
+			Description: "nix-daemon has failed",
+			Details:     "Check the daemon log for the cause",
+			Command:     "journalctl -u nix-daemon -n 50",
+		}
+	case socketState == "active":
+		return HealthCheckResult{
+			Category:    "packages",
+			Name:        "Nix Daemon",
+			Status:      "pass",
+			Description: "nix-daemon is socket-activated",
+			Details:     "The daemon starts on the first Nix command (service: " + state + ")",
+		}
+	}
+
+	details := "Neither nix-daemon.service nor nix-daemon.socket is active"
+	if state != "" {
+		details += " (service: " + state + ")"
+	}
+	return HealthCheckResult{
+		Category:    "packages",
+		Name:        "Nix Daemon",
+		Status:      "warn",
+		Description: "nix-daemon is not running",
+		Details:     details,
+		Command:     "sudo systemctl start nix-daemon.socket",
+	}
+}
+
+// trustedUsersChecks reads trusted-users from nix.conf and warns about settings that are
+// insecure or keep the current user from using extra substituters
+func trustedUsersChecks(env daemonCheckEnv) []HealthCheckResult {
+	data, err := env.readFile(nixConfPath)
+	if err != nil {
+		return nil
+	}
+	// Nix trusts only root unless configured otherwise
+	trusted := nixConfList(string(data), "trusted-users", []string{"root"})
+
+	var results []HealthCheckResult
+	if containsString(trusted, "*") {
+		return append(results, HealthCheckResult{
+			Category:    "packages",
+			Name:        "Trusted Users",
+			Status:      "warn",
+			Description: "All users are trusted by the Nix daemon",
+			Details: "trusted-users = * lets every user act as root towards the daemon, e.g. import unsigned store paths. " +
+				"Set nix.settings.trusted-users = [ \"root\" \"@wheel\" ] in configuration.nix",
+		})
+	}
+	if !containsString(trusted, "root") {
+		results = append(results, HealthCheckResult{
+			Category:    "packages",
+			Name:        "Trusted Users",
+			Status:      "warn",
+			Description: "root is not a trusted user",
+			Details: "trusted-users = " + strings.Join(trusted, " ") + "; setting it replaces the default, so keep root in the list. " +
+				"Add \"root\" to nix.settings.trusted-users",
+		})
+	}
+
+	if env.username == "" || env.username == "root" {
+		return results
+	}
+	isTrusted := containsString(trusted, env.username)
+	if !isTrusted {
+		if output, err := env.run("id", "-Gn"); err == nil {
+			for _, group := range strings.Fields(string(output)) {
+				if containsString(trusted, "@"+group) {
+					isTrusted = true
+					break
+				}
+			}
+		}
+	}
+	if isTrusted {
+		return append(results, HealthCheckResult{
+			Category:    "packages",
+			Name:        "Trusted Users",
+			Status:      "pass",
+			Description: env.username + " is a trusted Nix user",
+		})
+	}
+	return append(results, HealthCheckResult{
+		Category:    "packages",
+		Name:        "Trusted Users",
+		Status:      "info",
+		Description: env.username + " is not a trusted Nix user",
+		Details: "Extra substituters and binary caches from flakes are ignored for untrusted users. " +
+			"Add \"" + env.username + "\" (or \"@wheel\") to nix.settings.trusted-users if you need them",
+	})
+}
+
+// nixConfList returns the values of a list setting in nix.conf content, starting from its
+// default. Later lines override earlier ones, like Nix does; extra-<name> lines append.
+func nixConfList(content, name string, defaults []string) []string {
+	values := defaults
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case name:
+			values = strings.Fields(value)
+		case "extra-" + name:
+			values = append(append([]string{}, values...), strings.Fields(value)...)
+		}
+	}
+	return values
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// fakeDaemonEnv returns a daemon check environment with the given existing paths and nix.conf
+func fakeDaemonEnv(run commandRunner, paths []string, nixConf string) daemonCheckEnv {
+	existing := make(map[string]bool)
+	for _, path := range paths {
+		existing[path] = true
+	}
+	return daemonCheckEnv{
+		run:    run,
+		exists: func(path string) bool { return existing[path] },
+		readFile: func(path string) ([]byte, error) {
+			if path != nixConfPath || nixConf == "" {
+				return nil, os.ErrNotExist
+			}
+			return []byte(nixConf), nil
+		},
+		username:     "alice",
+		hasSystemctl: true,
+	}
+}
+
+// findResult returns the first result with the given name
+func findResult(t *testing.T, results []HealthCheckResult, name string) HealthCheckResult {
+	t.Helper()
+	for _, r := range results {
+		if r.Name == name {
+			return r
+		}
+	}
+	t.Fatalf("no %q result in %+v", name, results)
+	return HealthCheckResult{}
+}
+
+func TestDaemonChecks_DaemonRunning(t *testing.T) {
+	run := fakeRunner(map[string]string{
+		"systemctl is-active nix-daemon.service": "active\n",
+		"id -Gn":                                 "users wheel\n",
+	}, nil)
+	env := fakeDaemonEnv(run, []string{nixDaemonSocket, nixosMarker}, "trusted-users = root @wheel\n")
+
+	results := daemonChecks(env)
+	if r := findResult(t, results, "Nix Installation"); r.Status != "pass" || r.Description != "Multi-user Nix installation" {
+		t.Errorf("unexpected installation result: %+v", r)
+	}
+	if r := findResult(t, results, "Nix Daemon"); r.Status != "pass" {
+		t.Errorf("unexpected daemon result: %+v", r)
+	}
+	if r := findResult(t, results, "Trusted Users"); r.Status != "pass" {
+		t.Errorf("alice is trusted through @wheel, got %+v", r)
+	}
+}
+
+func TestDaemonChecks_SocketActivated(t *testing.T) {
+	inactive := errors.New("exit status 3")
+	run := fakeRunner(map[string]string{
+		"systemctl is-active nix-daemon.service": "inactive\n",
+		"systemctl is-active nix-daemon.socket":  "active\n",
+	}, map[string]error{"systemctl is-active nix-daemon.service": inactive})
+
+	r := daemonServiceCheck(run)
+	if r.Status != "pass" || !strings.Contains(r.Description, "socket-activated") {
+		t.Errorf("unexpected result: %+v", r)
+	}
+}
+
+func TestDaemonChecks_DaemonFailedOrStopped(t *testing.T) {
+	failed := fakeRunner(map[string]string{"systemctl is-active nix-daemon.service": "failed\n"}, nil)
+	if r := daemonServiceCheck(failed); r.Status != "fail" || !strings.Contains(r.Command, "journalctl") {
+		t.Errorf("unexpected failed result: %+v", r)
+	}
+
+	stopped := fakeRunner(map[string]string{
+		"systemctl is-active nix-daemon.service": "inactive\n",
+		"systemctl is-active nix-daemon.socket":  "inactive\n",
+	}, nil)
+	if r := daemonServiceCheck(stopped); r.Status != "warn" || !strings.Contains(r.Details, "service: inactive") {
+		t.Errorf("unexpected stopped result: %+v", r)
+	}
+}
+
+func TestDaemonChecks_SocketMissing(t *testing.T) {
+	run := fakeRunner(nil, nil)
+
+	results := daemonChecks(fakeDaemonEnv(run, []string{nixosMarker}, ""))
+	if len(results) != 1 || results[0].Status != "fail" || results[0].Description != "nix-daemon socket not found" {
+		t.Errorf("expected a failure on NixOS without the socket, got %+v", results)
+	}
+
+	env := fakeDaemonEnv(run, nil, "")
+	env.nixRemote = "daemon"
+	if results := daemonChecks(env); len(results) != 1 || results[0].Status != "fail" {
+		t.Errorf("expected a failure with NIX_REMOTE=daemon, got %+v", results)
+	}
+
+	results = daemonChecks(fakeDaemonEnv(run, nil, ""))
+	if len(results) != 1 || results[0].Status != "info" || results[0].Description != "Single-user Nix installation" {
+		t.Errorf("expected a single-user install, got %+v", results)
+	}
+}
+
+func TestTrustedUsersChecks(t *testing.T) {
+	run := fakeRunner(map[string]string{"id -Gn": "users\n"}, nil)
+
+	tests := []struct {
+		name     string
+		nixConf  string
+		statuses []string
+	}{
+		{"everyone trusted", "trusted-users = *\n", []string{"warn"}},
+		{"root dropped", "trusted-users = alice # no root\n", []string{"warn", "pass"}},
+		{"default untrusted", "max-jobs = auto\n", []string{"info"}},
+		{"extra users keep root", "extra-trusted-users = alice\n", []string{"pass"}},
+		{"later line overrides", "trusted-users = alice\ntrusted-users = root\n", []string{"info"}},
+		{"no nix.conf", "", nil},
+	}
+	for _, tt := range tests {
+		results := trustedUsersChecks(fakeDaemonEnv(run, nil, tt.nixConf))
+		var statuses []string
+		for _, r := range results {
+			statuses = append(statuses, r.Status)
+			// The advice is configuration to edit, not a command --fix could run
+			if r.Command != "" {
+				t.Errorf("%s: unexpected command %q", tt.name, r.Command)
+			}
+		}
+		if strings.Join(statuses, ",") != strings.Join(tt.statuses, ",") {
+			t.Errorf("%s: expected statuses %v, got %+v", tt.name, tt.statuses, results)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"nix-ai-help/pkg/utils"
+
+	"golang.org/x/term"
+)
+
+// fixCommandTimeout bounds each suggested command, so that one that never exits does not hang
+// the walk-through
+const fixCommandTimeout = 2 * time.Minute
+
+// runFixCommand runs a suggested doctor command through the shell and returns its combined
+// output; replaced in tests
+var runFixCommand commandRunner = func(name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fixCommandTimeout)
+	defer cancel()
+	// #nosec G204 -- only commands confirmed by the user are run
+	output, err := utils.TraceCombinedOutput(exec.CommandContext(ctx, name, args...))
+	if ctx.Err() == context.DeadlineExceeded {
+		return output, fmt.Errorf("timed out after %s", fixCommandTimeout)
+	}
+	return output, err
+}
+
+// readOnlyCommands are the suggested commands known only to report on the system. Anything
+// else may change system state and is only run after an explicit yes.
+var readOnlyCommands = []string{
+	"systemctl status", "systemctl is-system-running", "systemctl --failed",
+	"journalctl -u", "journalctl -n", "journalctl -b",
+	"nix-channel --list", "df", "ping -c",
+	"cat /etc/resolv.conf", "cat /etc/nix/nix.conf", "cat /etc/os-release",
+}
+
+// mutatingArguments are options that make an otherwise read-only command change state, e.g.
+// journalctl -u nix-daemon --vacuum-time=1s
+var mutatingArguments = []string{
+	"--vacuum", "--rotate", "--flush", "--sync", "--relinquish-var", "--smart-relinquish-var",
+	"--setup-keys", "--update-catalog",
+}
+
+// fixSummary counts what happened while stepping through suggested commands
+type fixSummary struct {
+	Ran     int
+	Failed  int
+	Skipped int
+}
+
+// isReadOnlyCommand reports whether a suggested command is one of readOnlyCommands, with its own
+// arguments but without chaining, redirecting or substituting other commands
+func isReadOnlyCommand(command string) bool {
+	command = strings.TrimSpace(command)
+	if strings.ContainsAny(command, ";&|<>`$\n") {
+		return false
+	}
+	for _, field := range strings.Fields(command) {
+		for _, mutating := range mutatingArguments {
+			if strings.HasPrefix(field, mutating) {
+				return false
+			}
+		}
+	}
+	for _, allowed := range readOnlyCommands {
+		if command == allowed || strings.HasPrefix(command, allowed+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// fixCommands returns the results with a suggested command, without repeating a command
+func fixCommands(results []HealthCheckResult) []HealthCheckResult {
+	seen := make(map[string]bool)
+	var withCommand []HealthCheckResult
+	for _, result := range results {
+		command := strings.TrimSpace(result.Command)
+		if command == "" || seen[command] {
+			continue
+		}
+		seen[command] = true
+		withCommand = append(withCommand, result)
+	}
+	return withCommand
+}
+
+// isInteractiveSession reports whether both stdin and stdout are terminals
+func isInteractiveSession() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// stepThroughFixes offers each suggested command in turn and runs the confirmed ones, printing
+// their output inline. Read-only commands default to yes; everything else defaults to no.
+// Answering q stops the walk-through.
+func stepThroughFixes(in *bufio.Reader, out io.Writer, results []HealthCheckResult, run commandRunner) fixSummary {
+	var summary fixSummary
+	steps := fixCommands(results)
+	if len(steps) == 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatInfo("No suggested commands to run"))
+		return summary
+	}
+
+	_, _ = fmt.Fprintln(out, utils.FormatHeader("🔧 Suggested Fixes"))
+	for i, step := range steps {
+		_, _ = fmt.Fprintln(out)
+		_, _ = fmt.Fprintf(out, "[%d/%d] %s %s\n", i+1, len(steps), getStatusIcon(step.Status), step.Description)
+		_, _ = fmt.Fprintln(out, "      "+utils.FormatKeyValue("Command", step.Command))
+
+		readOnly := isReadOnlyCommand(step.Command)
+		prompt := "Run this command? (Y/n/q): "
+		if !readOnly {
+			_, _ = fmt.Fprintln(out, utils.FormatWarning("This command may change your system"))
+			prompt = "Run this command? (y/N/q): "
+		}
+		_, _ = fmt.Fprint(out, prompt)
+
+		answer, err := in.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if err != nil && answer == "" {
+			// Input closed: skip everything that is left
+			summary.Skipped += len(steps) - i
+			_, _ = fmt.Fprintln(out)
+			break
+		}
+		if answer == "q" || answer == "quit" {
+			summary.Skipped += len(steps) - i
+			break
+		}
+
+		confirmed := answer == "y" || answer == "yes"
+		if readOnly && answer == "" {
+			confirmed = true
+		}
+		if !confirmed {
+			summary.Skipped++
+			continue
+		}
+
+		output, err := run("sh", "-c", step.Command)
+		if text := strings.TrimRight(string(output), "\n"); text != "" {
+			for _, line := range strings.Split(text, "\n") {
+				_, _ = fmt.Fprintln(out, "      "+line)
+			}
+		}
+		if err != nil {
+			summary.Failed++
+			_, _ = fmt.Fprintln(out, utils.FormatError("Command failed: "+err.Error()))
+			continue
+		}
+		summary.Ran++
+		_, _ = fmt.Fprintln(out, utils.FormatSuccess("Done"))
+	}
+
+	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintln(out, utils.FormatInfo(fmt.Sprintf("Fixes: %d run, %d failed, %d skipped", summary.Ran, summary.Failed, summary.Skipped)))
+	return summary
+}
+
+// offerFixes runs the --fix walk-through in interactive sessions and otherwise only lists the
+// suggested commands
+func offerFixes(out io.Writer, results []HealthCheckResult) {
+	if !isInteractiveSession() {
+		_, _ = fmt.Fprintln(out, utils.FormatWarning("--fix needs an interactive terminal; run the suggested commands manually:"))
+		for _, step := range fixCommands(results) {
+			_, _ = fmt.Fprintln(out, "  "+step.Command)
+		}
+		return
+	}
+	stepThroughFixes(bufio.NewReader(os.Stdin), out, results, runFixCommand)
+}
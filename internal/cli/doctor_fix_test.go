@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// recordingRunner records the commands it is asked to run
+type recordingRunner struct {
+	ran    []string
+	output string
+	err    error
+}
+
+func (r *recordingRunner) run(name string, args ...string) ([]byte, error) {
+	r.ran = append(r.ran, strings.Join(args[1:], " "))
+	return []byte(r.output), r.err
+}
+
+var fixResults = []HealthCheckResult{
+	{Status: "warn", Description: "Some services have failed", Command: "systemctl --failed"},
+	{Status: "info", Description: "Nix store size", Command: "nix-collect-garbage"},
+	{Status: "pass", Description: "No command"},
+	{Status: "info", Description: "Duplicate", Command: "systemctl --failed"},
+}
+
+func runFixes(t *testing.T, input string, runner *recordingRunner) (fixSummary, string) {
+	t.Helper()
+	var out bytes.Buffer
+	summary := stepThroughFixes(bufio.NewReader(strings.NewReader(input)), &out, fixResults, runner.run)
+	return summary, out.String()
+}
+
+func TestStepThroughFixes_DestructiveNeedsExplicitYes(t *testing.T) {
+	runner := &recordingRunner{output: "UNIT LOAD ACTIVE\n"}
+	// Enter accepts the safe command but declines the destructive one
+	summary, out := runFixes(t, "\n\n", runner)
+
+	if len(runner.ran) != 1 || runner.ran[0] != "systemctl --failed" {
+		t.Errorf("expected only the safe command to run, ran %v", runner.ran)
+	}
+	if summary != (fixSummary{Ran: 1, Skipped: 1}) {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if !strings.Contains(out, "      UNIT LOAD ACTIVE") {
+		t.Errorf("command output should be shown inline, got %q", out)
+	}
+	if !strings.Contains(out, "(y/N/q)") || !strings.Contains(out, "[2/2]") {
+		t.Errorf("destructive command should default to no, got %q", out)
+	}
+}
+
+func TestStepThroughFixes_ConfirmedAndFailing(t *testing.T) {
+	runner := &recordingRunner{err: errors.New("exit status 1")}
+	summary, out := runFixes(t, "n\ny\n", runner)
+
+	if len(runner.ran) != 1 || runner.ran[0] != "nix-collect-garbage" {
+		t.Errorf("expected only the confirmed command to run, ran %v", runner.ran)
+	}
+	if summary != (fixSummary{Failed: 1, Skipped: 1}) {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if !strings.Contains(out, "Command failed") {
+		t.Errorf("failure should be reported, got %q", out)
+	}
+}
+
+func TestStepThroughFixes_QuitAndClosedInput(t *testing.T) {
+	runner := &recordingRunner{}
+	if summary, _ := runFixes(t, "q\n", runner); summary.Skipped != 2 || len(runner.ran) != 0 {
+		t.Errorf("q should skip the rest, got %+v, ran %v", summary, runner.ran)
+	}
+	if summary, _ := runFixes(t, "", runner); summary.Skipped != 2 || len(runner.ran) != 0 {
+		t.Errorf("closed input should run nothing, got %+v, ran %v", summary, runner.ran)
+	}
+}
+
+func TestIsReadOnlyCommand(t *testing.T) {
+	for _, command := range []string{"systemctl --failed", "df -h", "nix-channel --list", "systemctl is-system-running",
+		"journalctl -u nix-daemon -n 50", "journalctl -b -p err", "ping -c 4 8.8.8.8", "cat /etc/resolv.conf"} {
+		if !isReadOnlyCommand(command) {
+			t.Errorf("%q should be read-only", command)
+		}
+	}
+	// Unknown commands are not assumed to be safe
+	for _, command := range []string{"nix-collect-garbage", "sudo nix-collect-garbage -d", "sudo bootctl status",
+		"find . -name 'result*' -type l -exec rm {} +", "systemctl restart nginx", "nix-channel --update",
+		"ping 8.8.8.8", "systemctl status; rm -rf ~", "cat /etc/resolv.conf > /etc/hosts", "dfx",
+		"journalctl --vacuum-size=100M", "journalctl --rotate", "journalctl -u nix-daemon --vacuum-time=1s",
+		"journalctl --flush", "cat /etc/shadow", "cat /etc/../root/.ssh/id_ed25519"} {
+		if isReadOnlyCommand(command) {
+			t.Errorf("%q should not be read-only", command)
+		}
+	}
+}
+
+func TestFixCommands_SkipsEmptyAndDuplicates(t *testing.T) {
+	steps := fixCommands(fixResults)
+	if len(steps) != 2 || steps[0].Command != "systemctl --failed" || steps[1].Command != "nix-collect-garbage" {
+		t.Errorf("unexpected steps: %+v", steps)
+	}
+}
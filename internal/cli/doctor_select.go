@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// doctorCategories are the health check categories of doctor, in the order they run
+var doctorCategories = []string{"system", "nixos", "packages", "services", "storage", "network", "security"}
+
+// selectCheckTypes returns the categories doctor checks: those of the check type argument, or
+// with --only just the listed ones, minus the ones listed with --skip. Categories keep the
+// order of doctorCategories.
+func selectCheckTypes(checkType string, only, skip []string) ([]string, error) {
+	for _, category := range append(append([]string{}, only...), skip...) {
+		if !containsString(doctorCategories, category) {
+			return nil, unknownCategoryError(category)
+		}
+	}
+	if checkType != "all" && !containsString(doctorCategories, checkType) {
+		return nil, unknownCategoryError(checkType)
+	}
+	if len(only) > 0 && checkType != "all" {
+		return nil, fmt.Errorf("use either the check type argument %q or --only, not both", checkType)
+	}
+
+	selected := getCheckTypes(checkType)
+	if len(only) > 0 {
+		selected = nil
+		for _, category := range doctorCategories {
+			if containsString(only, category) {
+				selected = append(selected, category)
+			}
+		}
+	}
+
+	var checkTypes []string
+	for _, category := range selected {
+		if !containsString(skip, category) {
+			checkTypes = append(checkTypes, category)
+		}
+	}
+	if len(checkTypes) == 0 {
+		return nil, fmt.Errorf("--skip excludes every selected check category")
+	}
+	return checkTypes, nil
+}
+
+// unknownCategoryError reports a check category doctor does not have
+func unknownCategoryError(category string) error {
+	return fmt.Errorf("unknown check category %q (valid: %s)", category, strings.Join(doctorCategories, ", "))
+}
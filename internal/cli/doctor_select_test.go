@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestSelectCheckTypes(t *testing.T) {
+	tests := []struct {
+		name      string
+		checkType string
+		only      []string
+		skip      []string
+		want      string
+	}{
+		{name: "all", checkType: "all", want: "system,nixos,packages,services,storage,network,security"},
+		{name: "argument", checkType: "network", want: "network"},
+		{name: "only keeps category order", checkType: "all", only: []string{"storage", "network", "storage"}, want: "storage,network"},
+		{name: "skip from all", checkType: "all", skip: []string{"security", "packages"}, want: "system,nixos,services,storage,network"},
+		{name: "only and skip", checkType: "all", only: []string{"network", "storage"}, skip: []string{"storage"}, want: "network"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectCheckTypes(tt.checkType, tt.only, tt.skip)
+			if err != nil || strings.Join(got, ",") != tt.want {
+				t.Errorf("selectCheckTypes() = %v, %v; want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectCheckTypesRejectsInvalidSelections(t *testing.T) {
+	tests := []struct {
+		name      string
+		checkType string
+		only      []string
+		skip      []string
+		want      string
+	}{
+		{name: "unknown only", checkType: "all", only: []string{"netwrok"}, want: `unknown check category "netwrok"`},
+		{name: "unknown skip", checkType: "all", skip: []string{"disk"}, want: `unknown check category "disk"`},
+		{name: "unknown argument", checkType: "gpu", want: `unknown check category "gpu"`},
+		{name: "argument and only", checkType: "system", only: []string{"network"}, want: "not both"},
+		{name: "everything skipped", checkType: "network", skip: []string{"network"}, want: "excludes every"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := selectCheckTypes(tt.checkType, tt.only, tt.skip)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestDoctorOnlyFlagAcceptsCommaSeparatedAndRepeatedValues(t *testing.T) {
+	flags := doctorCmd.Flags()
+	t.Cleanup(func() {
+		_ = flags.Lookup("only").Value.(pflag.SliceValue).Replace(nil)
+		flags.Lookup("only").Changed = false
+	})
+	if err := flags.Parse([]string{"--only", "network,storage", "--only", "security"}); err != nil {
+		t.Fatal(err)
+	}
+	only, _ := flags.GetStringSlice("only")
+	if got := strings.Join(only, ","); got != "network,storage,security" {
+		t.Errorf("--only = %q", got)
+	}
+}
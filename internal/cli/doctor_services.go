@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"os/exec"
+	"strings"
+
+	"nix-ai-help/pkg/utils"
+)
+
+// commandRunner runs an external command and returns its standard output
+type commandRunner func(name string, args ...string) ([]byte, error)
+
+// runDoctorCheckCommand runs the commands used by doctor checks; replaced in tests
+var runDoctorCheckCommand commandRunner = func(name string, args ...string) ([]byte, error) {
+	// #nosec G204 -- doctor only runs fixed systemctl and id commands
+	return utils.TraceOutput(exec.Command(name, args...))
+}
+
+// systemdStates are the states reported by `systemctl is-system-running` when systemd is PID 1.
+// It exits non-zero for every state except "running", so the output decides, not the exit code.
+var systemdStates = map[string]bool{
+	"initializing": true, "starting": true, "running": true,
+	"degraded": true, "maintenance": true, "stopping": true,
+}
+
+// wslConfigHint explains how to enable systemd on WSL
+const wslConfigHint = "WSL detected: enable systemd by setting 'systemd=true' under [boot] in /etc/wsl.conf, then run 'wsl --shutdown'"
+
+// serviceChecks checks the service manager with the given runner. Having systemctl installed is
+// not enough: in containers, chroots and WSL without systemd it cannot talk to a running systemd.
+func serviceChecks(run commandRunner, hasSystemctl, wsl bool) []HealthCheckResult {
+	var results []HealthCheckResult
+
+	if !hasSystemctl {
+		details := "Cannot check service status"
+		if wsl {
+			details += ". " + wslConfigHint
+		}
+		return append(results, HealthCheckResult{
+			Category:    "services",
+			Name:        "Service Manager",
+			Status:      "warn",
+			Description: "systemctl not available",
+			Details:     details,
+		})
+	}
+
+	output, err := run("systemctl", "is-system-running")
+	state := strings.TrimSpace(string(output))
+	if !systemdStates[state] {
+		details := "systemctl cannot reach a running systemd"
+		if state != "" {
+			details += " (state: " + state + ")"
+		} else if err != nil {
+			details += ": " + err.Error()
+		}
+		if wsl {
+			details += ". " + wslConfigHint
+		} else {
+			details += ". This is expected in containers and chroots; service checks were skipped"
+		}
+		return append(results, HealthCheckResult{
+			Category:    "services",
+			Name:        "Service Manager",
+			Status:      "warn",
+			Description: "systemd is not running",
+			Details:     details,
+			Command:     "systemctl is-system-running",
+		})
+	}
+
+	results = append(results, HealthCheckResult{
+		Category:    "services",
+		Name:        "Service Manager",
+		Status:      "pass",
+		Description: "systemd is available",
+		Details:     "System service management is functional (state: " + state + ")",
+		Command:     "systemctl status",
+	})
+
+	// Check for failed services
+	if output, err := run("systemctl", "--failed", "--no-legend", "--no-pager"); err == nil {
+		failedServices := strings.TrimSpace(string(output))
+		if failedServices == "" {
+			results = append(results, HealthCheckResult{
+				Category:    "services",
+				Name:        "Failed Services",
+				Status:      "pass",
+				Description: "No failed services detected",
+				Details:     "All system services are running properly",
+			})
+		} else {
+			results = append(results, HealthCheckResult{
+				Category:    "services",
+				Name:        "Failed Services",
+				Status:      "warn",
+				Description: "Some services have failed",
+				Details:     "Failed services detected",
+				Command:     "systemctl --failed",
+			})
+		}
+	}
+
+	return results
+}
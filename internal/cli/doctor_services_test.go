@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeRunner returns canned output per command line
+func fakeRunner(outputs map[string]string, errs map[string]error) commandRunner {
+	return func(name string, args ...string) ([]byte, error) {
+		key := strings.Join(append([]string{name}, args...), " ")
+		return []byte(outputs[key]), errs[key]
+	}
+}
+
+func TestServiceChecks_NoSystemdRunning(t *testing.T) {
+	exitErr := errors.New("exit status 1")
+	run := fakeRunner(
+		map[string]string{"systemctl is-system-running": "offline\n"},
+		map[string]error{"systemctl is-system-running": exitErr},
+	)
+
+	results := serviceChecks(run, true, false)
+	if len(results) != 1 {
+		t.Fatalf("expected only the service manager result, got %+v", results)
+	}
+	r := results[0]
+	if r.Status != "warn" || r.Description != "systemd is not running" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	if !strings.Contains(r.Details, "state: offline") || !strings.Contains(r.Details, "containers") {
+		t.Errorf("details should explain the state, got %q", r.Details)
+	}
+}
+
+func TestServiceChecks_NoOutputUnderWSL(t *testing.T) {
+	run := fakeRunner(nil, map[string]error{"systemctl is-system-running": errors.New("exit status 1")})
+
+	results := serviceChecks(run, true, true)
+	if len(results) != 1 || results[0].Status != "warn" {
+		t.Fatalf("expected a single warning, got %+v", results)
+	}
+	if !strings.Contains(results[0].Details, "exit status 1") || !strings.Contains(results[0].Details, "/etc/wsl.conf") {
+		t.Errorf("details should include the error and WSL hint, got %q", results[0].Details)
+	}
+}
+
+func TestServiceChecks_DegradedIsFunctional(t *testing.T) {
+	run := fakeRunner(
+		map[string]string{
+			"systemctl is-system-running":               "degraded\n",
+			"systemctl --failed --no-legend --no-pager": "foo.service loaded failed failed Foo\n",
+		},
+		map[string]error{"systemctl is-system-running": errors.New("exit status 1")},
+	)
+
+	results := serviceChecks(run, true, false)
+	if len(results) != 2 {
+		t.Fatalf("expected service manager and failed services results, got %+v", results)
+	}
+	if results[0].Status != "pass" || results[1].Status != "warn" {
+		t.Errorf("unexpected statuses: %+v", results)
+	}
+}
+
+func TestServiceChecks_NoSystemctl(t *testing.T) {
+	results := serviceChecks(fakeRunner(nil, nil), false, true)
+	if len(results) != 1 || results[0].Description != "systemctl not available" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if !strings.Contains(results[0].Details, "WSL") {
+		t.Errorf("expected WSL hint, got %q", results[0].Details)
+	}
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"nix-ai-help/pkg/utils"
+)
+
+// editorFallbacks are the editors tried, in order, when neither $EDITOR nor $VISUAL is set
+var editorFallbacks = []string{"code", "vim", "nano"}
+
+// runEditor runs an editor attached to the terminal; replaced in tests
+var runEditor = func(command []string) error {
+	// #nosec G204 -- the editor is the user's own $EDITOR, $VISUAL or a fixed fallback
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// resolveEditor returns the command line of the user's editor: $EDITOR, then $VISUAL, each of
+// which may carry arguments such as "code --wait", then the first of editorFallbacks on PATH.
+// ok is false when no editor is found.
+func resolveEditor(getenv func(string) string, lookPath func(string) (string, error)) (command []string, ok bool) {
+	for _, variable := range []string{"EDITOR", "VISUAL"} {
+		if fields := strings.Fields(getenv(variable)); len(fields) > 0 {
+			return fields, true
+		}
+	}
+	for _, editor := range editorFallbacks {
+		if _, err := lookPath(editor); err == nil {
+			return []string{editor}, true
+		}
+	}
+	return nil, false
+}
+
+// openInEditor opens a generated file in the user's editor for --open. Outside an interactive
+// terminal, or without an editor, it only says so.
+func openInEditor(out io.Writer, path string) {
+	if !isInteractiveSession() {
+		_, _ = fmt.Fprintln(out, utils.FormatNote("Not opening "+path+": not running in a terminal"))
+		return
+	}
+	editor, ok := resolveEditor(os.Getenv, exec.LookPath)
+	if !ok {
+		_, _ = fmt.Fprintln(out, utils.FormatWarning("No editor found to open "+path+"; set $EDITOR"))
+		return
+	}
+	if err := runEditor(append(editor, path)); err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatWarning(fmt.Sprintf("Failed to open %s with %s: %v", path, editor[0], err)))
+	}
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestResolveEditor(t *testing.T) {
+	onPath := func(names ...string) func(string) (string, error) {
+		return func(name string) (string, error) {
+			for _, found := range names {
+				if name == found {
+					return "/run/current-system/sw/bin/" + name, nil
+				}
+			}
+			return "", errors.New("not found")
+		}
+	}
+	env := func(vars map[string]string) func(string) string {
+		return func(name string) string { return vars[name] }
+	}
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		path     []string
+		want     []string
+		resolved bool
+	}{
+		{"EDITOR first", map[string]string{"EDITOR": "hx", "VISUAL": "code"}, nil, []string{"hx"}, true},
+		{"EDITOR with arguments", map[string]string{"EDITOR": "code --wait"}, nil, []string{"code", "--wait"}, true},
+		{"VISUAL without EDITOR", map[string]string{"EDITOR": "  ", "VISUAL": "emacsclient -t"}, nil, []string{"emacsclient", "-t"}, true},
+		{"code before vim", nil, []string{"nano", "vim", "code"}, []string{"code"}, true},
+		{"vim before nano", nil, []string{"nano", "vim"}, []string{"vim"}, true},
+		{"nano last", nil, []string{"nano"}, []string{"nano"}, true},
+		{"nothing found", nil, nil, nil, false},
+	}
+	for _, test := range tests {
+		got, ok := resolveEditor(env(test.env), onPath(test.path...))
+		if ok != test.resolved || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, %v; want %q, %v", test.name, got, ok, test.want, test.resolved)
+		}
+	}
+}
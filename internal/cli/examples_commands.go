@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"nix-ai-help/pkg/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// maxExamplesPerCommand limits how many invocations `nixai examples` lists per command
+const maxExamplesPerCommand = 3
+
+// examplesCmd lists example invocations for every command
+var examplesCmd = &cobra.Command{
+	Use:   "examples",
+	Short: "Show example invocations for every command",
+	Long: `Show a few runnable example invocations for each nixai command.
+
+Examples are taken from each command's documented examples. For the full
+example list of a single command use --examples on that command.
+
+Examples:
+  nixai examples                 # List examples for all commands
+  nixai search --examples        # Show all examples for the search command`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		printAllExamples(cmd.OutOrStdout(), cmd.Root())
+	},
+}
+
+// printAllExamples writes a few example invocations for each top-level command
+func printAllExamples(out io.Writer, root *cobra.Command) {
+	_, _ = fmt.Fprintln(out, utils.FormatHeader("💡 nixai Examples"))
+	_, _ = fmt.Fprintln(out)
+
+	for _, cmd := range root.Commands() {
+		if !cmd.IsAvailableCommand() {
+			continue
+		}
+		invocations := exampleInvocations(cmd)
+		if len(invocations) == 0 {
+			continue
+		}
+		if len(invocations) > maxExamplesPerCommand {
+			invocations = invocations[:maxExamplesPerCommand]
+		}
+
+		_, _ = fmt.Fprintln(out, utils.FormatSubsection(cmd.Name(), cmd.Short))
+		for _, inv := range invocations {
+			_, _ = fmt.Fprintf(out, "  %s\n", inv)
+		}
+		_, _ = fmt.Fprintln(out)
+	}
+}
+
+// printCommandExamples writes the examples of a single command, used by --examples
+func printCommandExamples(out io.Writer, cmd *cobra.Command) {
+	examples := commandExamples(cmd)
+	if examples == "" {
+		_, _ = fmt.Fprintln(out, utils.FormatInfo(fmt.Sprintf("No examples documented for '%s'. Run '%s --help' for usage.", cmd.CommandPath(), cmd.CommandPath())))
+		return
+	}
+
+	_, _ = fmt.Fprintln(out, utils.FormatHeader("💡 Examples: "+cmd.CommandPath()))
+	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintln(out, examples)
+}
+
+// commandExamples returns the example block of a command. The cobra Example field is
+// preferred; commands that document examples in an "Examples:" section of their long
+// description fall back to that section.
+func commandExamples(cmd *cobra.Command) string {
+	if strings.TrimSpace(cmd.Example) != "" {
+		return strings.TrimRight(cmd.Example, "\n")
+	}
+
+	lines := strings.Split(cmd.Long, "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "Examples:") {
+			continue
+		}
+		var block []string
+		for _, l := range lines[i+1:] {
+			// A non-indented, non-empty line starts the next section
+			if l != "" && !strings.HasPrefix(l, " ") && !strings.HasPrefix(l, "\t") {
+				break
+			}
+			block = append(block, l)
+		}
+		return strings.Trim(strings.Join(block, "\n"), "\n")
+	}
+
+	return ""
+}
+
+// exampleInvocations returns only the runnable nixai command lines from a command's
+// examples, with trailing comments removed.
+func exampleInvocations(cmd *cobra.Command) []string {
+	var invocations []string
+	for _, line := range strings.Split(commandExamples(cmd), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "nixai ") {
+			continue
+		}
+		if idx := strings.Index(line, " #"); idx > 0 {
+			line = strings.TrimSpace(line[:idx])
+		}
+		invocations = append(invocations, line)
+	}
+	return invocations
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCommandExamples_KeyCommands(t *testing.T) {
+	initializeCommands()
+
+	keyCommands := []string{
+		"ask", "search", "explain-option", "explain-home-option", "config", "doctor",
+		"diagnose", "mcp-server", "flake", "learn", "logs", "build", "deps", "store",
+		"templates", "package-repo", "completion",
+	}
+
+	for _, name := range keyCommands {
+		cmd, _, err := rootCmd.Find([]string{name})
+		if err != nil || cmd == rootCmd {
+			t.Errorf("command %q not registered", name)
+			continue
+		}
+		if commandExamples(cmd) == "" {
+			t.Errorf("expected examples for %q", name)
+		}
+		if len(exampleInvocations(cmd)) == 0 {
+			t.Errorf("expected runnable example invocations for %q", name)
+		}
+	}
+}
+
+func TestExampleInvocations_AllStartWithNixai(t *testing.T) {
+	initializeCommands()
+
+	for _, cmd := range rootCmd.Commands() {
+		for _, inv := range exampleInvocations(cmd) {
+			if !strings.HasPrefix(inv, "nixai ") {
+				t.Errorf("invocation %q for %q does not start with nixai", inv, cmd.Name())
+			}
+			if strings.Contains(inv, " #") {
+				t.Errorf("invocation %q for %q still contains a comment", inv, cmd.Name())
+			}
+		}
+	}
+}
+
+func TestCommandExamples_LongFallback(t *testing.T) {
+	cmd := &cobra.Command{
+		Use: "demo",
+		Long: `Demo command.
+
+Examples:
+  nixai demo run    # Run the demo
+  nixai demo stop
+
+Notes:
+  not an example`,
+	}
+
+	got := exampleInvocations(cmd)
+	want := []string{"nixai demo run", "nixai demo stop"}
+	if len(got) != len(want) {
+		t.Fatalf("exampleInvocations() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("exampleInvocations()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestPrintAllExamples(t *testing.T) {
+	initializeCommands()
+
+	var buf bytes.Buffer
+	printAllExamples(&buf, rootCmd)
+	out := buf.String()
+	for _, want := range []string{"nixai search firefox", "nixai explain-option services.nginx.enable"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected examples output to contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// optionDeprecation describes a deprecated or renamed NixOS option
+type optionDeprecation struct {
+	// Replacement is the option to use instead, empty when the documentation names none
+	Replacement string
+	// Note is the documentation text that marks the option as deprecated
+	Note string
+}
+
+// optionDeprecationFields are the deprecation fields an MCP option document may carry
+type optionDeprecationFields struct {
+	Deprecated  bool   `json:"deprecated"`
+	Message     string `json:"deprecation_message"`
+	RenamedTo   string `json:"renamed_to"`
+	ReplacedBy  string `json:"replaced_by"`
+	Description string `json:"option_description"`
+}
+
+// optionNamePattern matches a dotted NixOS option name such as services.nginx.enable
+const optionNamePattern = `([A-Za-z_][\w-]*(?:\.(?:[\w-]+|<name>|"[^"]*"|\*))+)`
+
+var (
+	// replacementPatterns find the replacement option in documentation text, most specific first
+	replacementPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\balias of\s+` + optionNamePattern),
+		regexp.MustCompile(`(?i)\brenamed to\s+` + optionNamePattern),
+		regexp.MustCompile(`(?i)\breplaced by\s+` + optionNamePattern),
+		regexp.MustCompile(`(?i)\b(?:deprecated|obsolete)\b(?:[^.]|\.\S)*?\buse\s+` + optionNamePattern + `\s+instead`),
+	}
+	// deprecatedPattern marks an option as deprecated without naming a replacement
+	deprecatedPattern = regexp.MustCompile(`(?i)\b(?:is|has been|was)\s+(?:deprecated|obsolete)\b`)
+	// htmlTagPattern matches the tags of rendered option descriptions
+	htmlTagPattern = regexp.MustCompile(`<[^>]+>`)
+	// optionRolePattern matches the {option} role of rendered option descriptions
+	optionRolePattern = regexp.MustCompile(`\{option\}`)
+)
+
+// parseOptionDeprecation finds deprecation or rename hints in an MCP option document: explicit
+// JSON fields first, then notes such as "Alias of X" or "deprecated, use X instead" in the
+// description or plain-text documentation
+func parseOptionDeprecation(doc string) (optionDeprecation, bool) {
+	text := doc
+	var fields optionDeprecationFields
+	if err := json.Unmarshal([]byte(doc), &fields); err == nil {
+		if replacement := firstNonEmpty(fields.RenamedTo, fields.ReplacedBy); replacement != "" {
+			return optionDeprecation{Replacement: replacement, Note: cleanDocText(fields.Message)}, true
+		}
+		if fields.Deprecated {
+			dep := optionDeprecation{Note: cleanDocText(fields.Message)}
+			if found, ok := deprecationFromText(fields.Message + " " + fields.Description); ok {
+				dep.Replacement = found.Replacement
+			}
+			return dep, true
+		}
+		text = fields.Description
+	}
+	return deprecationFromText(text)
+}
+
+// deprecationFromText finds a deprecation note in documentation text
+func deprecationFromText(text string) (optionDeprecation, bool) {
+	text = cleanDocText(text)
+	for _, pattern := range replacementPatterns {
+		if m := pattern.FindStringSubmatch(text); m != nil {
+			return optionDeprecation{Replacement: strings.TrimRight(m[1], "."), Note: sentenceAround(text, m[0])}, true
+		}
+	}
+	if loc := deprecatedPattern.FindStringIndex(text); loc != nil {
+		return optionDeprecation{Note: sentenceAround(text, text[loc[0]:loc[1]])}, true
+	}
+	return optionDeprecation{}, false
+}
+
+// cleanDocText removes markup from rendered option documentation
+func cleanDocText(text string) string {
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = optionRolePattern.ReplaceAllString(text, "")
+	text = strings.ReplaceAll(text, "`", "")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// sentenceAround returns the sentence of text containing match
+func sentenceAround(text, match string) string {
+	i := strings.Index(text, match)
+	if i < 0 {
+		return match
+	}
+	start := strings.LastIndex(text[:i], ". ") + 1
+	end := len(text)
+	if j := strings.Index(text[i+len(match):], ". "); j >= 0 {
+		end = i + len(match) + j + 1
+	}
+	return strings.TrimSpace(text[start:end])
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// deprecationBanner is the warning shown before the explanation of a deprecated option
+func deprecationBanner(dep optionDeprecation) string {
+	if dep.Replacement != "" {
+		return "Deprecated — use " + dep.Replacement + " instead"
+	}
+	if dep.Note != "" {
+		return "Deprecated — " + dep.Note
+	}
+	return "Deprecated — this option may be removed in a future NixOS release"
+}
+
+// deprecationInstruction tells the AI to lead with the deprecation and explain the migration
+func deprecationInstruction(option string, dep optionDeprecation) string {
+	instruction := fmt.Sprintf("\n\nIMPORTANT: %s is deprecated", option)
+	if dep.Replacement != "" {
+		instruction += fmt.Sprintf(" and replaced by %s. Start by stating this, write every example with %s, and show how to migrate an existing %s setting.",
+			dep.Replacement, dep.Replacement, option)
+	} else {
+		instruction += ". Start by stating this, explain what to use instead, and do not recommend it for new configurations."
+	}
+	if dep.Note != "" {
+		instruction += " Documentation note: " + dep.Note
+	}
+	return instruction
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOptionDeprecation(t *testing.T) {
+	tests := []struct {
+		name        string
+		doc         string
+		deprecated  bool
+		replacement string
+	}{
+		{
+			name:        "renamed_to field",
+			doc:         `{"option_name":"services.xserver.displayManager.sddm.enable","deprecated":true,"renamed_to":"services.displayManager.sddm.enable"}`,
+			deprecated:  true,
+			replacement: "services.displayManager.sddm.enable",
+		},
+		{
+			name:        "deprecated flag with message",
+			doc:         `{"option_name":"hardware.opengl.enable","deprecated":true,"deprecation_message":"Deprecated since 24.11, use hardware.graphics.enable instead."}`,
+			deprecated:  true,
+			replacement: "hardware.graphics.enable",
+		},
+		{
+			name:        "alias in rendered description",
+			doc:         `{"option_name":"sound.enable","option_description":"<rendered-html><p>Alias of {option}` + "`" + `services.pulseaudio.enable` + "`" + `.</p></rendered-html>"}`,
+			deprecated:  true,
+			replacement: "services.pulseaudio.enable",
+		},
+		{
+			name:       "deprecated without replacement",
+			doc:        `{"option_name":"services.foo.enable","option_description":"This option is deprecated and will be removed."}`,
+			deprecated: true,
+		},
+		{
+			name:        "plain text documentation",
+			doc:         "Option: boot.loader.grub.version\nThis option has been renamed to boot.loader.grub.enable.",
+			deprecated:  true,
+			replacement: "boot.loader.grub.enable",
+		},
+		{
+			name: "current option",
+			doc:  `{"option_name":"services.nginx.enable","option_description":"<p>Whether to enable Nginx Web Server.</p>"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dep, deprecated := parseOptionDeprecation(tt.doc)
+			if deprecated != tt.deprecated || dep.Replacement != tt.replacement {
+				t.Errorf("parseOptionDeprecation() = %+v, %v; want replacement %q, %v", dep, deprecated, tt.replacement, tt.deprecated)
+			}
+		})
+	}
+}
+
+func TestDeprecationBannerAndInstruction(t *testing.T) {
+	dep, _ := parseOptionDeprecation(`{"option_name":"hardware.opengl.enable","renamed_to":"hardware.graphics.enable"}`)
+
+	if banner := deprecationBanner(dep); banner != "Deprecated — use hardware.graphics.enable instead" {
+		t.Errorf("unexpected banner %q", banner)
+	}
+	instruction := deprecationInstruction("hardware.opengl.enable", dep)
+	for _, want := range []string{"hardware.opengl.enable is deprecated", "replaced by hardware.graphics.enable", "migrate"} {
+		if !strings.Contains(instruction, want) {
+			t.Errorf("instruction missing %q: %s", want, instruction)
+		}
+	}
+
+	noReplacement := optionDeprecation{Note: "This option is deprecated and will be removed."}
+	if banner := deprecationBanner(noReplacement); !strings.Contains(banner, "will be removed") {
+		t.Errorf("banner should fall back to the note, got %q", banner)
+	}
+}
@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// docTypePattern finds the type line of plain-text option documentation, e.g. "Type: boolean"
+	docTypePattern = regexp.MustCompile(`(?im)^[\s*_-]*type[\s*_]*:[\s*_]*(.+?)[\s*_]*$`)
+	// enumValuePattern matches the quoted values of an enum type such as one of "a", "b"
+	enumValuePattern = regexp.MustCompile(`"([^"]*)"`)
+	// integerValuePattern matches a Nix integer literal at the start of a value
+	integerValuePattern = regexp.MustCompile(`^-?\d`)
+	// priorityWrapperPattern matches mkDefault, mkForce or mkOverride wrapping a value, with or
+	// without lib.
+	priorityWrapperPattern = regexp.MustCompile(`^(?:lib\.)?mk(?:Default|Force|Override\s+\d+)\s+`)
+)
+
+// optionTypeFromDoc returns the option type from an MCP option document: the option_type field
+// of JSON documentation, or the "Type:" line of plain text. Empty when the type is not stated.
+func optionTypeFromDoc(doc string) string {
+	if opt, _ := parseMCPOptionDoc(doc); opt.Name != "" {
+		return strings.TrimSpace(opt.Type)
+	}
+	if matches := docTypePattern.FindStringSubmatch(doc); matches != nil {
+		return strings.TrimSpace(matches[1])
+	}
+	return ""
+}
+
+// buildOptionSuggestPrompt asks for a concrete value of option for the scenario the user
+// describes, constrained to the option type from the documentation
+func buildOptionSuggestPrompt(option, documentation, optionType, scenario, version string) string {
+	var b strings.Builder
+	b.WriteString("You are a NixOS expert. Propose a concrete value for the NixOS option below, tailored to the user's scenario.\n\n")
+	fmt.Fprintf(&b, "**Option:** %s\n", option)
+	if version != "" {
+		fmt.Fprintf(&b, "**NixOS Version:** %s\n", version)
+	}
+	fmt.Fprintf(&b, "**Scenario:** %s\n\n", scenario)
+	if optionType != "" {
+		fmt.Fprintf(&b, "**Type constraint:** the value MUST be of the Nix type \"%s\". Do not propose a value of any other type.\n\n", optionType)
+	}
+	fmt.Fprintf(&b, "**Official Documentation:**\n%s\n\n", documentation)
+	fmt.Fprintf(&b, "**Please provide:**\n"+
+		"1. A single ```nix code block that sets the option, written as `%s = <value>;`, with any closely related options the scenario needs\n"+
+		"2. Why this value fits the scenario, and what to change if the scenario differs (e.g. more traffic or less memory)\n"+
+		"3. How to verify the setting after nixos-rebuild switch\n\n"+
+		"Do not invent options; only use options that exist in NixOS.", option)
+	return b.String()
+}
+
+// suggestedOptionValue returns the value the response assigns to option, up to the end of its
+// line, or "" when the response does not set the option. The option may also be set inside an
+// attribute set of one of its parents, e.g. services.nginx = { recommendedProxySettings = true; }.
+func suggestedOptionValue(option, response string) string {
+	return assignedValue(option, response, response)
+}
+
+// assignedValue finds the value of option in text, where topLevel is text with the contents of
+// nested attribute sets blanked out, so that only the attributes of text itself are matched
+func assignedValue(option, text, topLevel string) string {
+	pattern := regexp.MustCompile(`(?m)(?:^|[\s{;])` + regexp.QuoteMeta(option) + `\s*=\s*([^\n]*)`)
+	if matches := pattern.FindStringSubmatch(topLevel); matches != nil {
+		return strings.TrimSpace(matches[1])
+	}
+
+	// Try each parent from the longest: services.nginx, then services
+	for i := strings.LastIndex(option, "."); i > 0; i = strings.LastIndex(option[:i], ".") {
+		parent := regexp.MustCompile(`(?:^|[\s{;])` + regexp.QuoteMeta(option[:i]) + `\s*=\s*\{`)
+		for _, loc := range parent.FindAllStringIndex(topLevel, -1) {
+			body := attrSetBody(text[loc[1]:])
+			if value := assignedValue(option[i+1:], body, blankNestedSets(body)); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// attrSetBody returns the text up to the brace closing the attribute set opened just before it
+func attrSetBody(text string) string {
+	depth := 0
+	for i, r := range text {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return text[:i]
+			}
+			depth--
+		}
+	}
+	return text
+}
+
+// blankNestedSets replaces the contents of the attribute sets nested in body with spaces,
+// keeping the braces and line breaks so that positions and lines stay the same
+func blankNestedSets(body string) string {
+	blanked := []byte(body)
+	depth := 0
+	for i, c := range blanked {
+		switch {
+		case c == '{':
+			depth++
+			if depth > 1 {
+				blanked[i] = ' '
+			}
+		case c == '}':
+			depth--
+			if depth > 0 {
+				blanked[i] = ' '
+			}
+		case depth > 0 && c != '\n':
+			blanked[i] = ' '
+		}
+	}
+	return string(blanked)
+}
+
+// valueMatchesOptionType reports whether a Nix value, as written in the suggestion, has the
+// shape of optionType. Types it cannot check, such as functions or package values, match.
+func valueMatchesOptionType(value, optionType string) bool {
+	t := strings.ToLower(strings.TrimSpace(optionType))
+	value = strings.TrimSpace(value)
+	if rest, ok := strings.CutPrefix(t, "null or "); ok {
+		if strings.HasPrefix(value, "null") {
+			return true
+		}
+		t = rest
+	}
+	// mkDefault, mkForce and mkOverride wrap the value
+	value = strings.TrimSpace(priorityWrapperPattern.ReplaceAllString(value, ""))
+
+	// Container types first: their element type may mention any other type
+	switch {
+	case strings.HasPrefix(t, "list of"):
+		return strings.HasPrefix(value, "[") || strings.HasPrefix(value, "with ")
+	case strings.HasPrefix(t, "attribute set") || strings.HasPrefix(t, "submodule"):
+		return strings.HasPrefix(value, "{")
+	case t == "boolean":
+		return strings.HasPrefix(value, "true") || strings.HasPrefix(value, "false")
+	case strings.HasPrefix(t, "one of "):
+		for _, allowed := range enumValuePattern.FindAllStringSubmatch(t, -1) {
+			if strings.HasPrefix(strings.ToLower(value), `"`+allowed[1]+`"`) {
+				return true
+			}
+		}
+		return false
+	case strings.Contains(t, "integer") && !strings.Contains(t, " or "):
+		return integerValuePattern.MatchString(value)
+	case t == "string" || strings.HasPrefix(t, "strings concatenated") || t == "non-empty string":
+		return strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "''")
+	case t == "path":
+		return strings.HasPrefix(value, "/") || strings.HasPrefix(value, "./") || strings.HasPrefix(value, `"/`) ||
+			strings.HasPrefix(value, "${") || strings.HasPrefix(value, `"${`)
+	}
+	return true
+}
+
+// checkOptionSuggestion validates the suggested value of option against its documented type.
+// It returns a warning for the user, or "" when the suggestion fits the type or cannot be checked.
+func checkOptionSuggestion(option, optionType, response string) string {
+	if optionType == "" {
+		return ""
+	}
+	value := suggestedOptionValue(option, response)
+	if value == "" {
+		return fmt.Sprintf("The suggestion does not set %s directly; check that it fits the type %q", option, optionType)
+	}
+	if !valueMatchesOptionType(value, optionType) {
+		return fmt.Sprintf("The suggested value %s does not look like a %s, the type of %s; adjust it before using it",
+			strings.TrimSuffix(value, ";"), optionType, option)
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOptionTypeFromDoc(t *testing.T) {
+	tests := map[string]string{
+		`{"option_name":"services.nginx.recommendedProxySettings","option_type":"boolean"}`:                   "boolean",
+		"Option: services.nginx.worker_processes\nType: signed integer or one of \"auto\"\nDefault: \"auto\"": `signed integer or one of "auto"`,
+		"**Type:** list of string\n":                   "list of string",
+		"services.nginx.enable enables the web server": "",
+	}
+	for doc, want := range tests {
+		if got := optionTypeFromDoc(doc); got != want {
+			t.Errorf("optionTypeFromDoc(%q) = %q, want %q", doc, got, want)
+		}
+	}
+}
+
+func TestBuildOptionSuggestPrompt(t *testing.T) {
+	doc := `{"option_name":"services.nginx.appendHttpConfig","option_type":"strings concatenated with \"\\n\""}`
+	prompt := buildOptionSuggestPrompt("services.nginx.appendHttpConfig", doc, optionTypeFromDoc(doc), "for a high-traffic reverse proxy", "24.05")
+
+	for _, want := range []string{
+		"**Scenario:** for a high-traffic reverse proxy",
+		`MUST be of the Nix type "strings concatenated with "\n""`,
+		"`services.nginx.appendHttpConfig = <value>;`",
+		"**NixOS Version:** 24.05",
+		doc,
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("expected prompt to contain %q:\n%s", want, prompt)
+		}
+	}
+
+	if prompt := buildOptionSuggestPrompt("foo.bar", "docs", "", "on a laptop", ""); strings.Contains(prompt, "Type constraint") {
+		t.Errorf("expected no type constraint without a type:\n%s", prompt)
+	}
+}
+
+func TestValueMatchesOptionType(t *testing.T) {
+	tests := []struct {
+		value, optionType string
+		want              bool
+	}{
+		{"true;", "boolean", true},
+		{`"yes";`, "boolean", false},
+		{"4096;", "signed integer", true},
+		{`"4096";`, "positive integer, meaning >0", false},
+		{`"auto";`, `one of "auto", "manual"`, true},
+		{`"fast";`, `one of "auto", "manual"`, false},
+		{"null;", "null or string", true},
+		{`"/srv/www";`, "null or string", true},
+		{"[ 80 443 ];", "list of 16 bit unsigned integer; between 0 and 65535 (both inclusive)", true},
+		{"80;", "list of port", false},
+		{"{", "attribute set of (submodule)", true},
+		{"''", "strings concatenated with \"\\n\"", true},
+		{"lib.mkForce false;", "boolean", true},
+		{"mkDefault true;", "boolean", true},
+		{`mkForce "yes";`, "boolean", false},
+		{"lib.mkOverride 50 8080;", "16 bit unsigned integer; between 0 and 65535 (both inclusive)", true},
+		{"/var/lib/acme;", "path", true},
+		{"pkgs.nginxMainline;", "package", true},
+	}
+	for _, test := range tests {
+		if got := valueMatchesOptionType(test.value, test.optionType); got != test.want {
+			t.Errorf("valueMatchesOptionType(%q, %q) = %v, want %v", test.value, test.optionType, got, test.want)
+		}
+	}
+}
+
+func TestSuggestedOptionValue(t *testing.T) {
+	const option = "services.nginx.appendHttpConfig"
+	tests := []struct {
+		response, want string
+	}{
+		{"services.nginx.appendHttpConfig = ''\n  gzip on;\n'';", "''"},
+		{"services.nginx = {\n  enable = true;\n  appendHttpConfig = \"gzip on;\";\n};", `"gzip on;";`},
+		{"services = {\n  nginx = {\n    appendHttpConfig = ''\n  };\n};", "''"},
+		{"services.nginx = { enable = true; appendHttpConfig = \"x\"; };", `"x";`},
+		// An attribute of the same name in a nested set is not the option
+		{"services.nginx = {\n  virtualHosts.foo = {\n    appendHttpConfig = \"x\";\n  };\n};", ""},
+		{"services.nginx.enable = true;", ""},
+	}
+	for _, test := range tests {
+		if got := suggestedOptionValue(option, test.response); got != test.want {
+			t.Errorf("suggestedOptionValue(%q) = %q, want %q", test.response, got, test.want)
+		}
+	}
+}
+
+func TestCheckOptionSuggestion(t *testing.T) {
+	good := "```nix\nservices.nginx.recommendedProxySettings = true;\n```\nThis sets proxy headers."
+	if warning := checkOptionSuggestion("services.nginx.recommendedProxySettings", "boolean", good); warning != "" {
+		t.Errorf("unexpected warning: %s", warning)
+	}
+
+	bad := "```nix\n{\n  services.nginx.recommendedProxySettings = \"on\";\n}\n```"
+	warning := checkOptionSuggestion("services.nginx.recommendedProxySettings", "boolean", bad)
+	if !strings.Contains(warning, `"on"`) || !strings.Contains(warning, "boolean") {
+		t.Errorf("expected a type warning, got %q", warning)
+	}
+
+	if warning := checkOptionSuggestion("services.nginx.recommendedProxySettings", "boolean", "Use the recommended settings."); !strings.Contains(warning, "does not set") {
+		t.Errorf("expected a warning when the option is not set, got %q", warning)
+	}
+	nested := "```nix\nservices.nginx = {\n  enable = true;\n  recommendedProxySettings = lib.mkDefault \"on\";\n};\n```"
+	if warning := checkOptionSuggestion("services.nginx.recommendedProxySettings", "boolean", nested); !strings.Contains(warning, `"on"`) {
+		t.Errorf("expected a type warning for the nested form, got %q", warning)
+	}
+	if warning := checkOptionSuggestion("services.nginx.recommendedProxySettings", "", bad); warning != "" {
+		t.Errorf("expected no check without a documented type, got %q", warning)
+	}
+}
@@ -0,0 +1,347 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/internal/mcp"
+	"nix-ai-help/internal/nixos"
+	"nix-ai-help/pkg/logger"
+	"nix-ai-help/pkg/utils"
+)
+
+// flakeDiffFrom and flakeDiffTo are the nixpkgs releases flake diff-options compares; --to
+// defaults to the detected release
+var flakeDiffFrom, flakeDiffTo string
+
+// nixpkgsReleasePattern matches release names such as 23.11
+var nixpkgsReleasePattern = regexp.MustCompile(`^\d{2}\.\d{2}$`)
+
+// Option change kinds
+const (
+	optionRemoved = "removed"
+	optionAdded   = "added"
+	optionChanged = "changed"
+)
+
+// optionChange is how one option set in the configuration differs between two releases
+type optionChange struct {
+	Option      string
+	Kind        string
+	Details     []string // type and default differences of a changed option
+	Description string
+}
+
+// releaseOptionLookup returns the documentation of option in a nixpkgs release, and whether the
+// release documents it
+type releaseOptionLookup func(release, option string) (mcpOptionDoc, bool, error)
+
+// mcpReleaseOptionLookup looks options up by name on the MCP server at baseURL, one client per
+// release, using the structured option endpoint rather than the free text of a documentation
+// query. The server looks in the index of that release only; a release without an index is an
+// error rather than a lookup in another release. The lookup is safe for concurrent use.
+func mcpReleaseOptionLookup(baseURL string) releaseOptionLookup {
+	var mu sync.Mutex
+	clients := make(map[string]*mcp.MCPClient)
+	return func(release, option string) (mcpOptionDoc, bool, error) {
+		mu.Lock()
+		client, ok := clients[release]
+		if !ok {
+			client = mcp.NewMCPClient(baseURL)
+			client.SetRelease(release)
+			clients[release] = client
+		}
+		mu.Unlock()
+		opt, err := client.QueryOption(option)
+		if err != nil || opt == nil {
+			return mcpOptionDoc{}, false, err
+		}
+		return mcpOptionDoc{
+			Name:        opt.Name,
+			Type:        opt.OptionType,
+			Default:     optionValue(opt.Default),
+			Example:     optionValue(opt.Example),
+			Description: opt.Description,
+			Source:      opt.Source,
+			Version:     release,
+		}, true, nil
+	}
+}
+
+// validNixpkgsRelease reports whether release names a nixpkgs release, e.g. 24.05 or unstable
+func validNixpkgsRelease(release string) bool {
+	return release == "unstable" || nixpkgsReleasePattern.MatchString(release)
+}
+
+// configOptions returns the sorted options set across the files of a configuration. flake.nix
+// is left out, its attributes are flake outputs, and so are imports, which list modules.
+func configOptions(tree *nixos.ImportTree) []string {
+	seen := make(map[string]bool)
+	var options []string
+	for _, file := range tree.Files {
+		if filepath.Base(file) == "flake.nix" {
+			continue
+		}
+		for _, option := range extractNixOptions(tree.Contents[file]) {
+			if option != "imports" && !seen[option] {
+				seen[option] = true
+				options = append(options, option)
+			}
+		}
+	}
+	sort.Strings(options)
+	return options
+}
+
+// maxConcurrentOptionLookups bounds how many option lookups diff-options runs at once
+const maxConcurrentOptionLookups = 8
+
+// releaseOptionDocs is the documentation of one option in the two releases compared
+type releaseOptionDocs struct {
+	before, after mcpOptionDoc
+	inFrom, inTo  bool
+	err           error
+}
+
+// diffOptionsAcrossReleases compares the documentation of options in the releases from and to,
+// looking them up concurrently, at most maxConcurrentOptionLookups at a time. Options
+// documented in neither, such as attributes inside a submodule, are counted as unknown. The
+// first failed lookup, in option order, is returned as the error.
+func diffOptionsAcrossReleases(options []string, from, to string, lookup releaseOptionLookup) ([]optionChange, int, error) {
+	docs := make([]releaseOptionDocs, len(options))
+	slots := make(chan struct{}, maxConcurrentOptionLookups)
+	var wg sync.WaitGroup
+	for i, option := range options {
+		wg.Add(1)
+		go func(doc *releaseOptionDocs, option string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			var err error
+			if doc.before, doc.inFrom, err = lookup(from, option); err != nil {
+				doc.err = fmt.Errorf("failed to look up %s in %s: %w", option, from, err)
+				return
+			}
+			if doc.after, doc.inTo, err = lookup(to, option); err != nil {
+				doc.err = fmt.Errorf("failed to look up %s in %s: %w", option, to, err)
+			}
+		}(&docs[i], option)
+	}
+	wg.Wait()
+
+	var changes []optionChange
+	unknown := 0
+	for i, option := range options {
+		doc := docs[i]
+		if doc.err != nil {
+			return nil, 0, doc.err
+		}
+		before, after := doc.before, doc.after
+		switch {
+		case !doc.inFrom && !doc.inTo:
+			unknown++
+		case !doc.inTo:
+			changes = append(changes, optionChange{Option: option, Kind: optionRemoved, Description: before.Description})
+		case !doc.inFrom:
+			changes = append(changes, optionChange{Option: option, Kind: optionAdded, Description: after.Description})
+		default:
+			var details []string
+			if before.Type != after.Type {
+				details = append(details, fmt.Sprintf("type %s → %s", orNone(before.Type), orNone(after.Type)))
+			}
+			if before.Default != after.Default {
+				details = append(details, fmt.Sprintf("default %s → %s", orNone(string(before.Default)), orNone(string(after.Default))))
+			}
+			if len(details) > 0 {
+				changes = append(changes, optionChange{Option: option, Kind: optionChanged, Details: details, Description: after.Description})
+			}
+		}
+	}
+	return changes, unknown, nil
+}
+
+// orNone shows an empty type or default as "none"
+func orNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
+
+// optionMigrationPrompt asks the AI how to move a configuration from one release to the next
+// given the changes to the options it sets
+func optionMigrationPrompt(from, to string, changes []optionChange) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "You are a NixOS expert. A user is moving their NixOS configuration from nixpkgs %s to %s. "+
+		"The following options they set changed between the releases:\n\n", from, to)
+	for _, change := range changes {
+		fmt.Fprintf(&b, "- %s (%s)", change.Option, change.Kind)
+		if len(change.Details) > 0 {
+			b.WriteString(": " + strings.Join(change.Details, "; "))
+		}
+		if change.Description != "" {
+			b.WriteString(" — " + change.Description)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\nFor each option explain what the user must change: the replacement for a removed or renamed " +
+		"option, whether a new type or default changes their system, and the corrected Nix code. " +
+		"Mention the release notes section to read where relevant. Be concise.")
+	return b.String()
+}
+
+// optionChangeSections are the headings changes are grouped under, in order
+var optionChangeSections = []struct {
+	kind    string
+	heading string
+}{
+	{optionRemoved, "🗑️  Removed in %s"},
+	{optionChanged, "🔄 Changed in %s"},
+	{optionAdded, "✨ Not in %s"},
+}
+
+// printOptionChanges prints the changes grouped by kind
+func printOptionChanges(out io.Writer, from, to string, changes []optionChange) {
+	for _, section := range optionChangeSections {
+		var group []optionChange
+		for _, change := range changes {
+			if change.Kind == section.kind {
+				group = append(group, change)
+			}
+		}
+		if len(group) == 0 {
+			continue
+		}
+		release := to
+		if section.kind == optionAdded {
+			release = from
+		}
+		_, _ = fmt.Fprintln(out, utils.FormatSubsection(fmt.Sprintf(section.heading+" (%d)", release, len(group)), ""))
+		for _, change := range group {
+			_, _ = fmt.Fprintln(out, "  "+change.Option)
+			for _, detail := range change.Details {
+				_, _ = fmt.Fprintln(out, "    "+detail)
+			}
+		}
+		_, _ = fmt.Fprintln(out)
+	}
+}
+
+// splitReleaseFlags removes --from (or --since) and --to with their values from the arguments
+// of flake diff-options, which the interactive mode passes along
+func splitReleaseFlags(args []string) (rest []string, from, to string) {
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		switch name {
+		case "--from", "--since", "--to":
+			if !hasValue && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			if name == "--to" {
+				to = value
+			} else {
+				from = value
+			}
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return rest, from, to
+}
+
+// runFlakeDiffOptions reports which options set in the configuration changed between two
+// nixpkgs releases and asks the AI how to migrate them
+func runFlakeDiffOptions(args []string, out io.Writer) {
+	_, _ = fmt.Fprintln(out, utils.FormatHeader("🔀 Option Changes Between Releases"))
+	_, _ = fmt.Fprintln(out)
+
+	args, from, to := splitReleaseFlags(args)
+	if from == "" {
+		from = flakeDiffFrom
+	}
+	if to == "" {
+		to = flakeDiffTo
+	}
+
+	cfg, err := config.LoadUserConfig()
+	if err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatError("Failed to load config: "+err.Error()))
+		return
+	}
+	nixosCtx, err := nixos.NewContextDetector(logger.NewLogger()).GetContext(cfg)
+	if err != nil {
+		nixosCtx = nil
+	}
+	if to == "" {
+		to = nixpkgsRelease(nixosCtx)
+	}
+	if from == "" || to == "" {
+		_, _ = fmt.Fprintln(out, utils.FormatError("Both releases are needed: pass --from and --to, e.g. --from 23.11 --to 24.05"))
+		return
+	}
+	for _, release := range []string{from, to} {
+		if !validNixpkgsRelease(release) {
+			_, _ = fmt.Fprintln(out, utils.FormatError("Not a nixpkgs release: "+release+" (expected e.g. 24.05 or unstable)"))
+			return
+		}
+	}
+
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	}
+	root, err := configExplainRoot(path, nixosCtx, cfg)
+	if err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatError(err.Error()))
+		return
+	}
+	options := configOptions(readConfigTree(root))
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Configuration", root))
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Releases", from+" → "+to))
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Options set", fmt.Sprintf("%d", len(options))))
+	_, _ = fmt.Fprintln(out)
+
+	lookup := mcpReleaseOptionLookup(fmt.Sprintf("http://%s:%d", cfg.MCPServer.Host, cfg.MCPServer.Port))
+	spinner := utils.NewSpinner(out, "Comparing option documentation...", 0).Start()
+	changes, unknown, err := diffOptionsAcrossReleases(options, from, to, lookup)
+	spinner.Stop()
+	if err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatError(err.Error()))
+		_, _ = fmt.Fprintln(out, utils.FormatTip("Start the documentation server with 'nixai mcp-server start'"))
+		return
+	}
+	if unknown > 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatNote(fmt.Sprintf("%d options are documented in neither release, e.g. attributes inside submodules, and were skipped", unknown)))
+		_, _ = fmt.Fprintln(out)
+	}
+	if len(changes) == 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatSuccess("No option you set changed between "+from+" and "+to))
+		return
+	}
+	printOptionChanges(out, from, to, changes)
+
+	provider, err := optionalAIProvider(cfg, logger.NewLogger())
+	if err != nil {
+		if !errors.Is(err, errAIDisabled) {
+			_, _ = fmt.Fprintln(out, utils.FormatWarning("Failed to initialize AI provider: "+err.Error()))
+		}
+		return
+	}
+	spinner = utils.NewSpinner(out, "Asking the AI for migration guidance...", 0).Start()
+	guidance, err := provider.Query(withLanguageInstruction(optionMigrationPrompt(from, to, changes), cfg))
+	spinner.Stop()
+	if err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatError("AI migration guidance failed: "+err.Error()))
+		return
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatSubsection("🤖 Migration guidance", ""))
+	_, _ = fmt.Fprintln(out, renderAIResponse(guidance))
+}
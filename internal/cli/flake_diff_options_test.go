@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nix-ai-help/internal/mcp"
+)
+
+// releaseOptions fakes the option documentation of two releases
+func releaseOptions(releases map[string]map[string]mcpOptionDoc) releaseOptionLookup {
+	return func(release, option string) (mcpOptionDoc, bool, error) {
+		opt, ok := releases[release][option]
+		return opt, ok, nil
+	}
+}
+
+func TestDiffOptionsAcrossReleases(t *testing.T) {
+	dir := t.TempDir()
+	configuration := `{ config, pkgs, ... }:
+{
+  imports = [ ./services.nix ];
+  sound.enable = true;
+  hardware.opengl.driSupport32Bit = true;
+  networking.hostName = "nixos";
+}`
+	services := `{
+  services.xserver.displayManager.sddm.enable = true;
+  services.pipewire.enable = true;
+  services.nginx.virtualHosts.example.root = "/var/www";
+}`
+	flake := `{ outputs = { self, nixpkgs }: { nixosConfigurations.nixos = nixpkgs.lib.nixosSystem { modules = [ ./configuration.nix ]; }; }; }`
+	for name, content := range map[string]string{"configuration.nix": configuration, "services.nix": services, "flake.nix": flake} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	options := configOptions(readConfigTree(filepath.Join(dir, "flake.nix")))
+	for _, option := range options {
+		if strings.HasPrefix(option, "outputs") {
+			t.Fatalf("flake outputs counted as options: %v", options)
+		}
+	}
+
+	lookup := releaseOptions(map[string]map[string]mcpOptionDoc{
+		"23.11": {
+			"sound.enable":                                {Name: "sound.enable", Type: "boolean", Default: "false"},
+			"hardware.opengl.driSupport32Bit":             {Name: "hardware.opengl.driSupport32Bit", Type: "boolean", Default: "false"},
+			"networking.hostName":                         {Name: "networking.hostName", Type: "string", Default: `"nixos"`},
+			"services.xserver.displayManager.sddm.enable": {Name: "services.xserver.displayManager.sddm.enable", Type: "boolean", Default: "false"},
+			"services.pipewire.enable":                    {Name: "services.pipewire.enable", Type: "boolean", Default: "false"},
+		},
+		"24.05": {
+			"sound.enable":             {Name: "sound.enable", Type: "boolean", Default: "false"},
+			"networking.hostName":      {Name: "networking.hostName", Type: "strMatching", Default: `"nixos"`},
+			"services.pipewire.enable": {Name: "services.pipewire.enable", Type: "boolean", Default: "false"},
+			"hardware.graphics.enable": {Name: "hardware.graphics.enable", Type: "boolean", Default: "false"},
+		},
+	})
+	changes, unknown, err := diffOptionsAcrossReleases(options, "23.11", "24.05", lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []optionChange{
+		{Option: "hardware.opengl.driSupport32Bit", Kind: optionRemoved},
+		{Option: "networking.hostName", Kind: optionChanged, Details: []string{"type string → strMatching"}},
+		{Option: "services.xserver.displayManager.sddm.enable", Kind: optionRemoved},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %+v, want %+v", changes, want)
+	}
+	// services.nginx.virtualHosts.example.root is inside a submodule and documented in neither
+	if unknown != 1 {
+		t.Errorf("unknown = %d, want 1", unknown)
+	}
+
+	// An option the user sets that the older release does not have
+	changes, _, err = diffOptionsAcrossReleases([]string{"hardware.graphics.enable"}, "23.11", "24.05", lookup)
+	if err != nil || len(changes) != 1 || changes[0].Kind != optionAdded {
+		t.Errorf("new option: got %+v, %v", changes, err)
+	}
+}
+
+func TestMCPReleaseOptionLookup(t *testing.T) {
+	// The option index documents option defaults as Nix text
+	index := map[string]map[string]mcp.NixOSOption{
+		"23.11": {"sound.enable": {Name: "sound.enable", OptionType: "boolean", Default: "false", Description: "Whether to enable ALSA sound."}},
+		"24.05": {},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/option" {
+			http.NotFound(w, r)
+			return
+		}
+		var response mcp.OptionResponse
+		if opt, ok := index[r.URL.Query().Get("release")][r.URL.Query().Get("name")]; ok {
+			response.Option = &opt
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	changes, unknown, err := diffOptionsAcrossReleases([]string{"sound.enable", "services.nginx.virtualHosts.example.root"},
+		"23.11", "24.05", mcpReleaseOptionLookup(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []optionChange{{Option: "sound.enable", Kind: optionRemoved, Description: "Whether to enable ALSA sound."}}
+	if !reflect.DeepEqual(changes, want) || unknown != 1 {
+		t.Errorf("got %+v, %d unknown; want %+v, 1 unknown", changes, unknown, want)
+	}
+}
+
+func TestDiffOptionsAcrossReleasesBoundsLookups(t *testing.T) {
+	var inFlight, most int32
+	lookup := func(release, option string) (mcpOptionDoc, bool, error) {
+		now := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(&most)
+			if now <= seen || atomic.CompareAndSwapInt32(&most, seen, now) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		// Every option was removed in the newer release
+		return mcpOptionDoc{Name: option}, release == "23.11", nil
+	}
+
+	var options []string
+	for i := 0; i < 50; i++ {
+		options = append(options, fmt.Sprintf("services.s%02d.enable", i))
+	}
+	changes, _, err := diffOptionsAcrossReleases(options, "23.11", "24.05", lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if most > maxConcurrentOptionLookups {
+		t.Errorf("%d lookups ran at once, want at most %d", most, maxConcurrentOptionLookups)
+	}
+	if len(changes) != len(options) {
+		t.Fatalf("got %d changes, want %d", len(changes), len(options))
+	}
+	for i, change := range changes {
+		if change.Option != options[i] {
+			t.Fatalf("change %d is %s, want option order kept", i, change.Option)
+		}
+	}
+}
+
+func TestDiffOptionsAcrossReleasesLookupError(t *testing.T) {
+	failing := func(release, option string) (mcpOptionDoc, bool, error) {
+		return mcpOptionDoc{}, false, errors.New("connection refused")
+	}
+	if _, _, err := diffOptionsAcrossReleases([]string{"sound.enable"}, "23.11", "24.05", failing); err == nil ||
+		!strings.Contains(err.Error(), "sound.enable in 23.11") {
+		t.Errorf("err = %v, want the option and release named", err)
+	}
+}
+
+func TestSplitReleaseFlags(t *testing.T) {
+	rest, from, to := splitReleaseFlags([]string{"--since", "23.11", "/etc/nixos", "--to=24.05"})
+	if !reflect.DeepEqual(rest, []string{"/etc/nixos"}) || from != "23.11" || to != "24.05" {
+		t.Errorf("got %q, %q, %q", rest, from, to)
+	}
+	for release, valid := range map[string]bool{"24.05": true, "unstable": true, "24.5": false, "nixos-24.05": false} {
+		if validNixpkgsRelease(release) != valid {
+			t.Errorf("validNixpkgsRelease(%q) = %v, want %v", release, !valid, valid)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"nix-ai-help/internal/nixos"
+	"nix-ai-help/pkg/utils"
+)
+
+// flakeDryRun makes flake update and lock print the lock changes without writing flake.lock
+var flakeDryRun bool
+
+// runFlakeCommand runs the nix flake commands that report on a flake; replaced in tests
+var runFlakeCommand commandRunner = func(name string, args ...string) ([]byte, error) {
+	// #nosec G204 -- only fixed nix flake subcommands are run
+	return utils.TraceCombinedOutput(exec.Command(name, args...))
+}
+
+// Lock change statuses
+const (
+	flakeInputAdded   = "added"
+	flakeInputRemoved = "removed"
+	flakeInputUpdated = "updated"
+)
+
+// flakeInputChange is the difference of one input between two lock files
+type flakeInputChange struct {
+	Name   string
+	Status string
+	Old    nixos.FlakeLockInput
+	New    nixos.FlakeLockInput
+}
+
+// parseFlakeLock returns the locked inputs of a flake.lock keyed by node name
+func parseFlakeLock(data []byte) (map[string]nixos.FlakeLockInput, error) {
+	lock, err := nixos.ParseFlakeLock(data)
+	if err != nil {
+		return nil, err
+	}
+	return lock.LockedInputs(), nil
+}
+
+// diffFlakeLocks compares the locked inputs before and after an update, sorted by input name
+func diffFlakeLocks(before, after map[string]nixos.FlakeLockInput) []flakeInputChange {
+	var changes []flakeInputChange
+	for name, old := range before {
+		updated, ok := after[name]
+		switch {
+		case !ok:
+			changes = append(changes, flakeInputChange{Name: name, Status: flakeInputRemoved, Old: old})
+		case updated.Rev != old.Rev || updated.NarHash != old.NarHash:
+			changes = append(changes, flakeInputChange{Name: name, Status: flakeInputUpdated, Old: old, New: updated})
+		}
+	}
+	for name, added := range after {
+		if _, ok := before[name]; !ok {
+			changes = append(changes, flakeInputChange{Name: name, Status: flakeInputAdded, New: added})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// shortLockRev identifies a locked input by its short revision, or its hash when it has no revision
+func shortLockRev(input nixos.FlakeLockInput) string {
+	id := input.Rev
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	if id == "" {
+		id = input.NarHash
+	}
+	if input.LastModified > 0 {
+		id += " (" + time.Unix(input.LastModified, 0).UTC().Format("2006-01-02") + ")"
+	}
+	return id
+}
+
+// renderFlakeLockDiff prints the lock changes as a table
+func renderFlakeLockDiff(out io.Writer, changes []flakeInputChange) {
+	if len(changes) == 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatSuccess("All inputs are up to date, flake.lock would not change"))
+		return
+	}
+	rows := make([][]string, 0, len(changes))
+	for _, change := range changes {
+		old, updated := "-", "-"
+		if change.Status != flakeInputAdded {
+			old = shortLockRev(change.Old)
+		}
+		if change.Status != flakeInputRemoved {
+			updated = shortLockRev(change.New)
+		}
+		rows = append(rows, []string{change.Name, change.Status, old, updated})
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatSubsection(fmt.Sprintf("Would change %d input(s)", len(changes)), ""))
+	_, _ = fmt.Fprintln(out, utils.FormatTable([]string{"Input", "Change", "Current", "New"}, rows))
+}
+
+// flakeLockChanges runs `nix flake <subcommand>` into a temporary lock file and compares it with
+// the flake.lock in dir, leaving the real lock file untouched
+func flakeLockChanges(run commandRunner, dir, subcommand string) ([]flakeInputChange, error) {
+	before := map[string]nixos.FlakeLockInput{}
+	data, err := os.ReadFile(filepath.Join(dir, "flake.lock"))
+	switch {
+	case err == nil:
+		if before, err = parseFlakeLock(data); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "nixai-flake-dry-run-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	outputLock := filepath.Join(tmpDir, "flake.lock")
+
+	// nix flake update takes input names as arguments and the flake with --flake
+	args := []string{"flake", subcommand, dir}
+	if subcommand == "update" {
+		args = []string{"flake", subcommand, "--flake", dir}
+	}
+	if output, err := run("nix", append(args, "--output-lock-file", outputLock)...); err != nil {
+		return nil, fmt.Errorf("nix flake %s failed: %w\n%s", subcommand, err, output)
+	}
+	data, err = os.ReadFile(outputLock)
+	if err != nil {
+		return nil, fmt.Errorf("nix flake %s wrote no lock file: %w", subcommand, err)
+	}
+	after, err := parseFlakeLock(data)
+	if err != nil {
+		return nil, err
+	}
+	return diffFlakeLocks(before, after), nil
+}
+
+// runFlakeDryRun prints what `nix flake <subcommand>` would change in the flake.lock of dir
+func runFlakeDryRun(out io.Writer, run commandRunner, dir, subcommand string) {
+	_, _ = fmt.Fprintln(out, utils.FormatInfo("Dry run: computing lock changes without writing flake.lock..."))
+	changes, err := flakeLockChanges(run, dir, subcommand)
+	if err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatError("Flake dry run failed: "+err.Error()))
+		return
+	}
+	renderFlakeLockDiff(out, changes)
+	if len(changes) > 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatTip(fmt.Sprintf("Run 'nixai flake %s' without --dry-run to apply these changes", subcommand)))
+	}
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const beforeFlakeLock = `{
+  "nodes": {
+    "home-manager": {
+      "inputs": {"nixpkgs": ["nixpkgs"]},
+      "locked": {"lastModified": 1717000000, "narHash": "sha256-hm1", "owner": "nix-community", "repo": "home-manager", "rev": "1111111111111111111111111111111111111111", "type": "github"},
+      "original": {"owner": "nix-community", "repo": "home-manager", "type": "github"}
+    },
+    "nixpkgs": {
+      "locked": {"lastModified": 1717000000, "narHash": "sha256-np1", "owner": "NixOS", "repo": "nixpkgs", "rev": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", "type": "github"},
+      "original": {"owner": "NixOS", "ref": "nixos-unstable", "repo": "nixpkgs", "type": "github"}
+    },
+    "old-overlay": {
+      "locked": {"lastModified": 1700000000, "narHash": "sha256-ov", "rev": "cccccccccccccccccccccccccccccccccccccccc", "type": "github"},
+      "original": {"type": "github"}
+    },
+    "root": {"inputs": {"home-manager": "home-manager", "nixpkgs": "nixpkgs", "old-overlay": "old-overlay"}}
+  },
+  "root": "root",
+  "version": 7
+}`
+
+const afterFlakeLock = `{
+  "nodes": {
+    "home-manager": {
+      "inputs": {"nixpkgs": ["nixpkgs"]},
+      "locked": {"lastModified": 1717000000, "narHash": "sha256-hm1", "owner": "nix-community", "repo": "home-manager", "rev": "1111111111111111111111111111111111111111", "type": "github"},
+      "original": {"owner": "nix-community", "repo": "home-manager", "type": "github"}
+    },
+    "nixpkgs": {
+      "locked": {"lastModified": 1718000000, "narHash": "sha256-np2", "owner": "NixOS", "repo": "nixpkgs", "rev": "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", "type": "github"},
+      "original": {"owner": "NixOS", "ref": "nixos-unstable", "repo": "nixpkgs", "type": "github"}
+    },
+    "sops-nix": {
+      "locked": {"lastModified": 1718000000, "narHash": "sha256-sops", "rev": "dddddddddddddddddddddddddddddddddddddddd", "type": "github"},
+      "original": {"type": "github"}
+    },
+    "root": {"inputs": {"home-manager": "home-manager", "nixpkgs": "nixpkgs", "sops-nix": "sops-nix"}}
+  },
+  "root": "root",
+  "version": 7
+}`
+
+func TestDiffFlakeLocks(t *testing.T) {
+	before, err := parseFlakeLock([]byte(beforeFlakeLock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := parseFlakeLock([]byte(afterFlakeLock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := diffFlakeLocks(before, after)
+	want := []struct{ name, status, oldRev, newRev string }{
+		{"nixpkgs", flakeInputUpdated, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"},
+		{"old-overlay", flakeInputRemoved, "cccccccccccccccccccccccccccccccccccccccc", ""},
+		{"sops-nix", flakeInputAdded, "", "dddddddddddddddddddddddddddddddddddddddd"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for i, w := range want {
+		c := changes[i]
+		if c.Name != w.name || c.Status != w.status || c.Old.Rev != w.oldRev || c.New.Rev != w.newRev {
+			t.Errorf("change %d = %+v, want %+v", i, c, w)
+		}
+	}
+
+	if changes := diffFlakeLocks(before, before); len(changes) != 0 {
+		t.Errorf("identical locks should not differ, got %+v", changes)
+	}
+}
+
+func TestParseFlakeLockInvalid(t *testing.T) {
+	if _, err := parseFlakeLock([]byte("not json")); err == nil {
+		t.Error("expected an error for an invalid lock file")
+	}
+}
+
+func TestRenderFlakeLockDiff(t *testing.T) {
+	before, _ := parseFlakeLock([]byte(beforeFlakeLock))
+	after, _ := parseFlakeLock([]byte(afterFlakeLock))
+
+	var out bytes.Buffer
+	renderFlakeLockDiff(&out, diffFlakeLocks(before, after))
+	for _, want := range []string{"Would change 3 input(s)", "aaaaaaaaaaaa (2024-05-29)", "bbbbbbbbbbbb (2024-06-10)", "sops-nix", "removed"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	renderFlakeLockDiff(&out, nil)
+	if !strings.Contains(out.String(), "would not change") {
+		t.Errorf("unexpected output for no changes:\n%s", out.String())
+	}
+}
+
+func TestFlakeLockChangesLeavesLockUntouched(t *testing.T) {
+	dir := t.TempDir()
+	lockPath := filepath.Join(dir, "flake.lock")
+	if err := os.WriteFile(lockPath, []byte(beforeFlakeLock), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var ran []string
+	run := func(name string, args ...string) ([]byte, error) {
+		ran = append([]string{name}, args...)
+		output := args[len(args)-1]
+		return nil, os.WriteFile(output, []byte(afterFlakeLock), 0o644)
+	}
+
+	changes, err := flakeLockChanges(run, dir, "update")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 {
+		t.Errorf("got %d changes, want 3", len(changes))
+	}
+	if got := strings.Join(ran[:5], " "); got != "nix flake update --flake "+dir {
+		t.Errorf("unexpected command %q", got)
+	}
+	if data, _ := os.ReadFile(lockPath); string(data) != beforeFlakeLock {
+		t.Error("the dry run modified flake.lock")
+	}
+
+	if _, err := flakeLockChanges(run, dir, "lock"); err != nil || ran[3] != dir {
+		t.Errorf("lock dry run = %v, command %v", err, ran)
+	}
+
+	failing := func(string, ...string) ([]byte, error) {
+		return []byte("error: no network"), errors.New("exit status 1")
+	}
+	if _, err := flakeLockChanges(failing, dir, "update"); err == nil || !strings.Contains(err.Error(), "no network") {
+		t.Errorf("expected the nix error, got %v", err)
+	}
+}
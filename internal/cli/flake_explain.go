@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/internal/nixos"
+	"nix-ai-help/pkg/logger"
+	"nix-ai-help/pkg/utils"
+)
+
+// flakeExplain makes flake check and validate ask the AI to explain a failed check
+var flakeExplain bool
+
+// splitExplainFlag removes --explain from the arguments of flake check, which the interactive
+// mode passes along with the flake path
+func splitExplainFlag(args []string) ([]string, bool) {
+	rest := make([]string, 0, len(args))
+	explain := false
+	for _, arg := range args {
+		if arg == "--explain" {
+			explain = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, explain
+}
+
+// flakeCheckGuidance is the flake-specific part of the prompt explaining a failed flake check
+const flakeCheckGuidance = "You are a NixOS expert. The following is the output of a failed 'nix flake check'. " +
+	"Identify which flake output failed (evaluating a nixosConfigurations or homeConfigurations entry, building " +
+	"a package or check, or an output that does not match the flake schema such as a misnamed attribute or a " +
+	"wrong system), explain the root cause in plain words and give step-by-step fix instructions.\n\n" +
+	"Flake-specific guidance:\n" +
+	"- Evaluation errors: point at the file and line in the flake and show the corrected Nix code.\n" +
+	"- Unknown or invalid outputs: name the expected output attribute, e.g. packages.<system>.<name>.\n" +
+	"- Failing checks and builds: suggest 'nix log <derivation>' and 'nix build .#checks.<system>.<name>' to reproduce.\n" +
+	"- Locked inputs: mention 'nix flake update <input>' only if an input is the cause.\n\n"
+
+// flakeCheckPrompt returns the prompt explaining a failed flake check, with the errors the
+// NixOS build output parser found in it
+func flakeCheckPrompt(output string, buildErrors []nixos.BuildError) string {
+	prompt := flakeCheckGuidance
+	if len(buildErrors) > 0 {
+		prompt += "PARSED ERRORS:\n"
+		for _, buildErr := range buildErrors {
+			prompt += "- " + buildErr.Kind + ": " + buildErrorSummary(buildErr) + "\n"
+		}
+		prompt += "\n"
+	}
+	return prompt + "nix flake check output:\n" + buildOutputTail(output, maxBuildOutputLines)
+}
+
+// explainFlakeCheck prints the errors parsed from a failed flake check and the AI explanation of
+// what failed and how to fix it. The raw output is printed by the caller.
+func explainFlakeCheck(out io.Writer, output string, query func(string) (string, error)) error {
+	buildErrors := nixos.ParseBuildErrors(output)
+	_, _ = fmt.Fprintln(out)
+	if len(buildErrors) > 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatSubsection("Detected errors", ""))
+		for _, buildErr := range buildErrors {
+			_, _ = fmt.Fprintln(out, "  "+utils.FormatKeyValue(buildErrorLabels[buildErr.Kind], buildErrorSummary(buildErr)))
+		}
+		_, _ = fmt.Fprintln(out)
+	}
+
+	spinner := utils.NewSpinner(out, "Asking the AI to explain the failure...", 0).Start()
+	explanation, err := query(flakeCheckPrompt(output, buildErrors))
+	spinner.Stop()
+	if err != nil {
+		return fmt.Errorf("AI explanation failed: %w", err)
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatSubsection("🤖 What failed and how to fix it", ""))
+	_, _ = fmt.Fprintln(out, utils.RenderMarkdown(explanation))
+	return nil
+}
+
+// explainFailedFlakeCheck follows the raw output of a failed flake check with the AI explanation
+// when explain is set, or a tip pointing at --explain. Under --no-ai only the raw output is shown.
+func explainFailedFlakeCheck(out io.Writer, output string, explain bool) {
+	if !explain {
+		if !noAI {
+			_, _ = fmt.Fprintln(out, utils.FormatTip("Run 'nixai flake check --explain' to have the AI explain the failure"))
+		}
+		return
+	}
+	cfg, err := config.LoadUserConfig()
+	if err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatError("Failed to load config: "+err.Error()))
+		return
+	}
+	provider, err := optionalAIProvider(cfg, logger.NewLogger())
+	if errors.Is(err, errAIDisabled) {
+		return
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatError("Failed to initialize AI provider: "+err.Error()))
+		return
+	}
+	if err := explainFlakeCheck(out, output, provider.Query); err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatError(err.Error()))
+	}
+}
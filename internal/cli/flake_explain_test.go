@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// flakeCheckFailure is the output of a nix flake check whose NixOS configuration does not evaluate
+const flakeCheckFailure = `warning: Git tree '/home/alice/nixos' is dirty
+error:
+       … while checking flake output 'nixosConfigurations'
+
+       … while checking the NixOS configuration 'nixosConfigurations.laptop'
+
+       … while evaluating the attribute 'config.system.build.toplevel'
+
+       error: undefined variable 'pkgs'
+
+       at /nix/store/abc123-source/hosts/laptop.nix:12:5:
+
+           11|   environment.systemPackages = [
+           12|     pkgs.firefox
+             |     ^
+           13|   ];
+`
+
+func TestExplainFlakeCheck(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{"The module does not take **pkgs** as an argument."}}
+
+	var out bytes.Buffer
+	if err := explainFlakeCheck(&out, flakeCheckFailure, provider.Query); err != nil {
+		t.Fatal(err)
+	}
+
+	if provider.calls != 1 {
+		t.Fatalf("expected one query, got %d", provider.calls)
+	}
+	prompt := provider.prompts[0]
+	for _, want := range []string{
+		"failed 'nix flake check'",
+		"PARSED ERRORS:\n- eval_error: undefined variable 'pkgs' (at /nix/store/abc123-source/hosts/laptop.nix:12:5)",
+		"nix flake check output:\nwarning: Git tree",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+
+	output := out.String()
+	for _, want := range []string{"Detected errors", "Evaluation error", "What failed and how to fix it", "does not take"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestExplainFlakeCheckProviderError(t *testing.T) {
+	provider := &scriptedProvider{err: errors.New("connection refused")}
+
+	var out bytes.Buffer
+	err := explainFlakeCheck(&out, "error: flake 'git+file:///home/alice/nixos' does not provide attribute 'checks'", provider.Query)
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("expected the provider error, got %v", err)
+	}
+	if strings.Contains(out.String(), "Detected errors") {
+		t.Errorf("no errors should be parsed from this output:\n%s", out.String())
+	}
+}
+
+func TestSplitExplainFlag(t *testing.T) {
+	args, explain := splitExplainFlag([]string{"--explain", "/etc/nixos/flake.nix"})
+	if !explain || !reflect.DeepEqual(args, []string{"/etc/nixos/flake.nix"}) {
+		t.Errorf("splitExplainFlag = %v, %v", args, explain)
+	}
+	if _, explain := splitExplainFlag([]string{"./flake.nix"}); explain {
+		t.Error("--explain was not given")
+	}
+}
+
+func TestExplainFailedFlakeCheckWithoutAI(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	count := countProviderManagers(t)
+	setNoAI(t, true)
+
+	for _, explain := range []bool{true, false} {
+		var out bytes.Buffer
+		explainFailedFlakeCheck(&out, flakeCheckFailure, explain)
+		if out.Len() != 0 {
+			t.Errorf("explain=%v: expected nothing after the raw output under --no-ai, got:\n%s", explain, out.String())
+		}
+	}
+	if *count != 0 {
+		t.Errorf("expected no provider manager under --no-ai, built %d", *count)
+	}
+}
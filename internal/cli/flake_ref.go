@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// flakeRefSchemes are the flake reference types nix accepts, with whether they need an
+// owner/repo path
+var flakeRefSchemes = map[string]bool{
+	"github": true, "gitlab": true, "sourcehut": true,
+	"git": false, "git+https": false, "git+http": false, "git+ssh": false, "git+file": false,
+	"hg+https": false, "hg+http": false, "hg+ssh": false, "hg+file": false,
+	"tarball+https": false, "tarball+http": false, "tarball+file": false,
+	"file+https": false, "file+http": false, "file+file": false,
+	"https": false, "http": false, "file": false, "path": false, "flake": false,
+}
+
+var (
+	// ownerRepoPattern matches the owner/repo[/ref] part of github:, gitlab: and sourcehut: refs
+	ownerRepoPattern = regexp.MustCompile(`^~?[\w.-]+/[\w.-]+(/[\w./-]+)?$`)
+	// indirectFlakePattern matches registry refs such as nixpkgs or nixpkgs/nixos-24.05
+	indirectFlakePattern = regexp.MustCompile(`^[A-Za-z][\w-]*(/[\w.-]+)*$`)
+)
+
+// validateFlakeRef checks that ref looks like a flake reference nix accepts: a path such as
+// . or ./dir, a URL-like ref such as github:owner/repo, or a registry name, each with an
+// optional ?query and #fragment
+func validateFlakeRef(ref string) error {
+	if ref == "" {
+		return fmt.Errorf("flake reference is empty")
+	}
+	if strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("invalid flake reference %q: must not start with '-'", ref)
+	}
+	if strings.IndexFunc(ref, func(r rune) bool { return r <= ' ' || r == 0x7f }) >= 0 {
+		return fmt.Errorf("invalid flake reference %q: contains whitespace or control characters", ref)
+	}
+
+	base, _, _ := strings.Cut(ref, "#")
+	base, _, _ = strings.Cut(base, "?")
+	switch {
+	case base == "":
+		return fmt.Errorf("invalid flake reference %q: missing the flake before '#' (use .#name for the current directory)", ref)
+	case base == "." || base == ".." || strings.HasPrefix(base, "./") || strings.HasPrefix(base, "../") || strings.HasPrefix(base, "/"):
+		return nil
+	}
+
+	if scheme, rest, ok := strings.Cut(base, ":"); ok {
+		needsOwnerRepo, known := flakeRefSchemes[scheme]
+		switch {
+		case !known:
+			return fmt.Errorf("invalid flake reference %q: unknown type %q", ref, scheme)
+		case rest == "":
+			return fmt.Errorf("invalid flake reference %q: nothing after %q", ref, scheme+":")
+		case needsOwnerRepo && !ownerRepoPattern.MatchString(rest):
+			return fmt.Errorf("invalid flake reference %q: expected %s:owner/repo", ref, scheme)
+		}
+		return nil
+	}
+
+	if !indirectFlakePattern.MatchString(base) {
+		return fmt.Errorf("invalid flake reference %q", ref)
+	}
+	return nil
+}
+
+// flakeRefCommandArgs builds the nix arguments of a flake subcommand that takes an optional
+// flake reference, defaulting to the flake in the current directory
+func flakeRefCommandArgs(subcommand string, args []string) ([]string, error) {
+	nixArgs := []string{"flake", subcommand}
+	switch len(args) {
+	case 0:
+		return nixArgs, nil
+	case 1:
+		if err := validateFlakeRef(args[0]); err != nil {
+			return nil, err
+		}
+		return append(nixArgs, args[0]), nil
+	default:
+		return nil, fmt.Errorf("nix flake %s takes at most one flake reference, got %d arguments", subcommand, len(args))
+	}
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestValidateFlakeRef(t *testing.T) {
+	valid := []string{
+		".", ".#", ".#nixosConfigurations.laptop", "./hosts/laptop", "../dotfiles#home", "/etc/nixos",
+		"github:owner/repo", "github:NixOS/nixpkgs/nixos-24.05#hello", "gitlab:group/project?dir=sub",
+		"sourcehut:~user/repo", "git+https://example.com/repo.git?ref=main", "path:./sub", "https://example.com/flake.tar.gz",
+		"nixpkgs", "nixpkgs/nixos-24.05", "nixpkgs#hello",
+	}
+	for _, ref := range valid {
+		if err := validateFlakeRef(ref); err != nil {
+			t.Errorf("validateFlakeRef(%q) = %v, want valid", ref, err)
+		}
+	}
+
+	invalid := []string{"", "--refresh", "#hello", "github:", "github:owner", "foo:bar", "github:owner/repo extra", "not a ref", "bad$name"}
+	for _, ref := range invalid {
+		if err := validateFlakeRef(ref); err == nil {
+			t.Errorf("validateFlakeRef(%q) accepted an invalid reference", ref)
+		}
+	}
+}
+
+func TestFlakeRefCommandArgs(t *testing.T) {
+	tests := []struct {
+		subcommand string
+		args       []string
+		want       string
+	}{
+		{"show", nil, "flake show"},
+		{"show", []string{"github:owner/repo"}, "flake show github:owner/repo"},
+		{"metadata", []string{".#"}, "flake metadata .#"},
+	}
+	for _, tt := range tests {
+		got, err := flakeRefCommandArgs(tt.subcommand, tt.args)
+		if err != nil || strings.Join(got, " ") != tt.want {
+			t.Errorf("flakeRefCommandArgs(%q, %v) = %v, %v; want %q", tt.subcommand, tt.args, got, err, tt.want)
+		}
+	}
+
+	if _, err := flakeRefCommandArgs("show", []string{".", "github:owner/repo"}); err == nil {
+		t.Error("expected an error for more than one flake reference")
+	}
+}
+
+func TestRunFlakeShowAndMetadataPassFlakeRef(t *testing.T) {
+	var ran []string
+	orig := runFlakeCommand
+	runFlakeCommand = func(name string, args ...string) ([]byte, error) {
+		ran = append([]string{name}, args...)
+		return []byte("ok"), nil
+	}
+	t.Cleanup(func() { runFlakeCommand = orig })
+
+	var out bytes.Buffer
+	runFlakeCmd([]string{"show", "github:owner/repo"}, &out)
+	if got := strings.Join(ran, " "); got != "nix flake show github:owner/repo" {
+		t.Errorf("flake show ran %q", got)
+	}
+
+	runFlakeCmd([]string{"metadata", ".#"}, &out)
+	if got := strings.Join(ran, " "); got != "nix flake metadata .#" {
+		t.Errorf("flake metadata ran %q", got)
+	}
+
+	ran = nil
+	out.Reset()
+	runFlakeCmd([]string{"metadata", "--refresh"}, &out)
+	if ran != nil || !strings.Contains(out.String(), "invalid flake reference") {
+		t.Errorf("an invalid reference was run (%v):\n%s", ran, out.String())
+	}
+}
@@ -1,12 +1,10 @@
 package cli
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"regexp"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -133,9 +131,9 @@ This command provides:
 		log := logger.NewLoggerWithLevel(cfg.LogLevel)
 		gcm := NewGCManager(log)
 
-		// Use the new ProviderManager system
-		aiProvider, err := GetLegacyAIProvider(cfg, log)
-		if err != nil {
+		// Use the new ProviderManager system; without AI the local analysis is shown alone
+		aiProvider, err := optionalAIProvider(cfg, log)
+		if err != nil && !errors.Is(err, errAIDisabled) {
 			fmt.Println(utils.FormatError("Error getting AI provider: " + err.Error()))
 			os.Exit(1)
 		}
@@ -202,9 +200,9 @@ This command:
 			fmt.Println()
 		}
 
-		// Use the new ProviderManager system
-		aiProvider, err := GetLegacyAIProvider(cfg, log)
-		if err != nil {
+		// Use the new ProviderManager system; without AI the local analysis is shown alone
+		aiProvider, err := optionalAIProvider(cfg, log)
+		if err != nil && !errors.Is(err, errAIDisabled) {
 			fmt.Println(utils.FormatError("Error getting AI provider: " + err.Error()))
 			os.Exit(1)
 		}
@@ -224,14 +222,17 @@ var gcCompareGenerationsCmd = &cobra.Command{
 	Short: "Compare generations with AI recommendations",
 	Long: `Compare NixOS generations and get AI-powered recommendations for which to keep or remove.
 
-This command analyzes:
-- Generation sizes and dates
-- System changes between generations
-- Boot success rates
-- Usage patterns
-- Safe removal candidates`,
+This command lists every system generation with its date, kernel and closure size change,
+recommends keeping the current and previous generation, any --pin'ned generations and the
+--keep most recent ones, and prints the nix-env command that removes the rest. The AI then
+reviews the recommendation.
+
+Examples:
+  nixai gc compare-generations
+  nixai gc compare-generations --keep 3 --pin 120,98`,
 	Run: func(cmd *cobra.Command, args []string) {
 		keepCount, _ := cmd.Flags().GetInt("keep")
+		pinned, _ := cmd.Flags().GetIntSlice("pin")
 
 		fmt.Println(utils.FormatHeader("⚖️ Generation Comparison Analysis"))
 		fmt.Println()
@@ -262,15 +263,15 @@ This command analyzes:
 			fmt.Println()
 		}
 
-		// Use the new ProviderManager system
-		aiProvider, err := GetLegacyAIProvider(cfg, log)
-		if err != nil {
+		// Use the new ProviderManager system; without AI the local analysis is shown alone
+		aiProvider, err := optionalAIProvider(cfg, log)
+		if err != nil && !errors.Is(err, errAIDisabled) {
 			fmt.Println(utils.FormatError("Error getting AI provider: " + err.Error()))
 			os.Exit(1)
 		}
 
 		// Compare generations with context
-		err = gcm.CompareGenerations(aiProvider, keepCount)
+		err = gcm.CompareGenerations(aiProvider, keepCount, pinned, nixosCtx)
 		if err != nil {
 			fmt.Println(utils.FormatError("Error comparing generations: " + err.Error()))
 			os.Exit(1)
@@ -285,11 +286,16 @@ var gcDiskUsageCmd = &cobra.Command{
 	Long: `Visualize Nix store disk usage patterns with AI-powered optimization recommendations.
 
 Shows:
-- Store size breakdown by category
-- Disk usage trends over time
-- Largest packages and derivations
-- Optimization opportunities
-- Storage efficiency metrics`,
+- Disk and store usage totals
+- The largest packages in the system closure, grouped by package with a bar chart
+- AI-powered cleanup and optimization tips
+
+Store paths are measured with 'nix path-info -rsS /run/current-system', falling back to
+'du' on the top-level store entries.
+
+Examples:
+  nixai gc disk-usage
+  nixai gc disk-usage --top 25`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println(utils.FormatHeader("💾 Nix Store Disk Usage Analysis"))
 		fmt.Println()
@@ -320,15 +326,16 @@ Shows:
 			fmt.Println()
 		}
 
-		// Use the new ProviderManager system
-		aiProvider, err := GetLegacyAIProvider(cfg, log)
-		if err != nil {
+		// Use the new ProviderManager system; without AI the local analysis is shown alone
+		aiProvider, err := optionalAIProvider(cfg, log)
+		if err != nil && !errors.Is(err, errAIDisabled) {
 			fmt.Println(utils.FormatError("Error getting AI provider: " + err.Error()))
 			os.Exit(1)
 		}
 
 		// Analyze disk usage with context
-		err = gcm.AnalyzeDiskUsage(aiProvider)
+		top, _ := cmd.Flags().GetInt("top")
+		err = gcm.AnalyzeDiskUsage(aiProvider, top)
 		if err != nil {
 			fmt.Println(utils.FormatError("Error analyzing disk usage: " + err.Error()))
 			os.Exit(1)
@@ -380,7 +387,7 @@ func (gcm *GCManager) AnalyzeStore() (*GCAnalysis, error) {
 // getStoreSize gets the current Nix store size
 func (gcm *GCManager) getStoreSize() (int64, error) {
 	cmd := exec.Command("du", "-sb", "/nix/store")
-	output, err := cmd.Output()
+	output, err := utils.TraceOutput(cmd)
 	if err != nil {
 		return 0, err
 	}
@@ -402,7 +409,7 @@ func (gcm *GCManager) getStoreSize() (int64, error) {
 // getDiskSpace gets available and total disk space
 func (gcm *GCManager) getDiskSpace() (available, total int64, err error) {
 	cmd := exec.Command("df", "-B1", "/nix")
-	output, err := cmd.Output()
+	output, err := utils.TraceOutput(cmd)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -433,67 +440,7 @@ func (gcm *GCManager) getDiskSpace() (available, total int64, err error) {
 
 // getGenerations gets list of NixOS generations
 func (gcm *GCManager) getGenerations() ([]Generation, error) {
-	var generations []Generation
-
-	// Get system generations
-	cmd := exec.Command("nixos-rebuild", "list-generations")
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, err
-	}
-
-	// Parse generations output
-	scanner := bufio.NewScanner(strings.NewReader(string(output)))
-	re := regexp.MustCompile(`(\d+)\s+(\d{4}-\d{2}-\d{2}\s+\d{2}:\d{2}:\d{2})\s*(.*)`)
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-
-		matches := re.FindStringSubmatch(line)
-		if len(matches) >= 3 {
-			number, err := strconv.Atoi(matches[1])
-			if err != nil {
-				continue
-			}
-
-			date, err := time.Parse("2006-01-02 15:04:05", matches[2])
-			if err != nil {
-				// Try alternative format
-				date, err = time.Parse("2006-01-02T15:04:05", matches[2])
-				if err != nil {
-					continue
-				}
-			}
-
-			description := ""
-			if len(matches) > 3 {
-				description = matches[3]
-			}
-
-			// Check if this is the current generation
-			current := strings.Contains(description, "(current)")
-
-			generation := Generation{
-				Number:      number,
-				Date:        date,
-				Description: description,
-				Current:     current,
-				Safe:        !current && time.Since(date) > 24*time.Hour, // Safe if not current and older than 1 day
-			}
-
-			generations = append(generations, generation)
-		}
-	}
-
-	// Sort by generation number
-	sort.Slice(generations, func(i, j int) bool {
-		return generations[i].Number > generations[j].Number
-	})
-
-	return generations, nil
+	return listGenerations()
 }
 
 // calculatePotentialSavings calculates potential disk savings
@@ -660,7 +607,8 @@ func (gcm *GCManager) DisplayAnalysis(analysis *GCAnalysis, aiProvider ai.AIProv
 	fmt.Println(utils.FormatTip("Use 'nixai gc compare-generations' to analyze generations in detail"))
 }
 
-// DisplayAnalysisWithContext displays the analysis results with AI enhancement and NixOS context
+// DisplayAnalysisWithContext displays the analysis results with AI enhancement and NixOS context;
+// a nil aiProvider shows the local analysis only
 func (gcm *GCManager) DisplayAnalysisWithContext(analysis *GCAnalysis, aiProvider ai.AIProvider, nixosCtx *config.NixOSContext) {
 	// Display basic metrics
 	fmt.Println(utils.FormatSubsection("📊 Storage Overview", ""))
@@ -716,17 +664,19 @@ func (gcm *GCManager) DisplayAnalysisWithContext(analysis *GCAnalysis, aiProvide
 	}
 
 	// Get context-aware AI analysis
-	fmt.Println(utils.FormatProgress("Getting context-aware AI analysis and recommendations..."))
-	contextBuilder := nixoscontext.NewNixOSContextBuilder()
-	basePrompt := gcm.buildAnalysisPrompt(analysis)
-	contextualPrompt := contextBuilder.BuildContextualPrompt(basePrompt, nixosCtx)
+	if aiProvider != nil {
+		fmt.Println(utils.FormatProgress("Getting context-aware AI analysis and recommendations..."))
+		contextBuilder := nixoscontext.NewNixOSContextBuilder()
+		basePrompt := gcm.buildAnalysisPrompt(analysis)
+		contextualPrompt := contextBuilder.BuildContextualPrompt(basePrompt, nixosCtx)
 
-	aiAnalysis, err := aiProvider.Query(contextualPrompt)
-	if err != nil {
-		fmt.Println(utils.FormatWarning("Could not get AI analysis: " + err.Error()))
-	} else {
-		fmt.Println(utils.FormatSubsection("🤖 Context-Aware AI Analysis & Recommendations", ""))
-		fmt.Println(utils.RenderMarkdown(aiAnalysis))
+		aiAnalysis, err := aiProvider.Query(contextualPrompt)
+		if err != nil {
+			fmt.Println(utils.FormatWarning("Could not get AI analysis: " + err.Error()))
+		} else {
+			fmt.Println(utils.FormatSubsection("🤖 Context-Aware AI Analysis & Recommendations", ""))
+			fmt.Println(utils.RenderMarkdown(aiAnalysis))
+		}
 	}
 
 	fmt.Println()
@@ -734,7 +684,7 @@ func (gcm *GCManager) DisplayAnalysisWithContext(analysis *GCAnalysis, aiProvide
 	fmt.Println(utils.FormatTip("Use 'nixai gc compare-generations' to analyze generations in detail"))
 }
 
-// SafeCleanup performs AI-guided safe cleanup
+// SafeCleanup performs AI-guided safe cleanup; a nil aiProvider skips the AI safety analysis
 func (gcm *GCManager) SafeCleanup(aiProvider ai.AIProvider, dryRun bool, keepGenerations int) error {
 	// Analyze current state
 	analysis, err := gcm.AnalyzeStore()
@@ -743,58 +693,68 @@ func (gcm *GCManager) SafeCleanup(aiProvider ai.AIProvider, dryRun bool, keepGen
 	}
 
 	// Get AI recommendations for safe cleanup
-	prompt := gcm.buildSafeCleanupPrompt(analysis, keepGenerations)
-	recommendations, err := aiProvider.Query(prompt)
-	if err != nil {
-		return fmt.Errorf("failed to get AI recommendations: %w", err)
-	}
+	if aiProvider != nil {
+		prompt := gcm.buildSafeCleanupPrompt(analysis, keepGenerations)
+		recommendations, err := aiProvider.Query(prompt)
+		if err != nil {
+			return fmt.Errorf("failed to get AI recommendations: %w", err)
+		}
 
-	// Display AI recommendations
-	fmt.Println(utils.FormatSubsection("🤖 AI Safety Analysis", ""))
-	fmt.Println(utils.RenderMarkdown(recommendations))
-	fmt.Println()
+		// Display AI recommendations
+		fmt.Println(utils.FormatSubsection("🤖 AI Safety Analysis", ""))
+		fmt.Println(utils.RenderMarkdown(recommendations))
+		fmt.Println()
+	}
 
 	// Perform cleanup operations
 	return gcm.executeCleanup(analysis, dryRun, keepGenerations)
 }
 
-// CompareGenerations compares generations with AI analysis
-func (gcm *GCManager) CompareGenerations(aiProvider ai.AIProvider, keepCount int) error {
+// CompareGenerations compares generations with AI analysis; a nil aiProvider skips the AI review
+func (gcm *GCManager) CompareGenerations(aiProvider ai.AIProvider, keepCount int, pinned []int, nixosCtx *config.NixOSContext) error {
 	generations, err := gcm.getGenerations()
 	if err != nil {
 		return err
 	}
+	if len(generations) == 0 {
+		return fmt.Errorf("no system generations found")
+	}
+	addGenerationDetails(generations)
+	plan := recommendGenerations(generations, keepCount, pinned)
 
 	// Display generations
-	fmt.Println("System Generations Analysis:")
+	fmt.Println(utils.FormatSubsection("📋 System Generations", ""))
+	fmt.Println(formatGenerationTable(generations, plan))
 	fmt.Println()
 
-	for _, gen := range generations {
-		age := time.Since(gen.Date)
-		fmt.Printf("%s #%-3d %s (%s ago)%s\n",
-			utils.FormatNote("•"),
-			gen.Number,
-			gen.Date.Format("2006-01-02 15:04"),
-			formatDuration(age),
-			func() string {
-				if gen.Current {
-					return utils.FormatNote(" [CURRENT]")
-				}
-				return ""
-			}())
-
-		if gen.Description != "" {
-			fmt.Printf("    %s\n", utils.FormatNote(gen.Description))
+	fmt.Println(utils.FormatSubsection("✅ Recommendation", ""))
+	fmt.Println(utils.FormatKeyValue("Keep", formatGenerationNumbers(plan.Keep)))
+	fmt.Println(utils.FormatKeyValue("Remove", formatGenerationNumbers(plan.Remove)))
+	commands := generationCleanupCommands(plan.Remove, nixosCtx)
+	if len(commands) > 0 {
+		fmt.Println()
+		fmt.Println(utils.FormatInfo("Recommended commands:"))
+		for _, command := range commands {
+			fmt.Println("  " + command)
 		}
+	} else {
+		fmt.Println(utils.FormatNote("Nothing to remove"))
 	}
 	fmt.Println()
 
 	// Get AI analysis
-	prompt := gcm.buildCompareGenerationsPrompt(generations, keepCount)
+	if aiProvider == nil {
+		return nil
+	}
+	prompt := gcm.buildCompareGenerationsPrompt(generations, keepCount, plan, commands)
+	fmt.Print(utils.FormatInfo("Asking AI to review the recommendation... "))
 	analysis, err := aiProvider.Query(prompt)
 	if err != nil {
+		fmt.Println(utils.FormatWarning("failed"))
 		return fmt.Errorf("failed to get AI analysis: %w", err)
 	}
+	fmt.Println(utils.FormatSuccess("done"))
+	fmt.Println()
 
 	fmt.Println(utils.FormatSubsection("🤖 AI Generation Analysis", ""))
 	fmt.Println(utils.RenderMarkdown(analysis))
@@ -802,8 +762,48 @@ func (gcm *GCManager) CompareGenerations(aiProvider ai.AIProvider, keepCount int
 	return nil
 }
 
-// AnalyzeDiskUsage analyzes and visualizes disk usage
-func (gcm *GCManager) AnalyzeDiskUsage(aiProvider ai.AIProvider) error {
+// formatGenerationTable renders generations with date, kernel, closure size and the planned action
+func formatGenerationTable(generations []Generation, plan GenerationPlan) string {
+	rows := make([][]string, 0, len(generations))
+	for i, gen := range generations {
+		size, delta := "-", "-"
+		if gen.Size > 0 {
+			size = formatBytes(gen.Size)
+		}
+		if d, ok := sizeDelta(generations, i); ok {
+			delta = formatSizeDelta(d)
+		}
+		kernel := gen.Kernel
+		if kernel == "" {
+			kernel = "-"
+		}
+		action := "remove"
+		if reason, keep := plan.Reasons[gen.Number]; keep {
+			action = "keep (" + reason + ")"
+		}
+		number := strconv.Itoa(gen.Number)
+		if gen.Current {
+			number += "*"
+		}
+		rows = append(rows, []string{number, gen.Date.Format("2006-01-02 15:04"), kernel, size, delta, action})
+	}
+	return utils.FormatTable([]string{"Gen", "Date", "Kernel", "Size", "Δ Size", "Action"}, rows)
+}
+
+// formatGenerationNumbers joins generation numbers for display
+func formatGenerationNumbers(numbers []int) string {
+	if len(numbers) == 0 {
+		return "none"
+	}
+	parts := make([]string, len(numbers))
+	for i, number := range numbers {
+		parts[i] = strconv.Itoa(number)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// AnalyzeDiskUsage analyzes and visualizes disk usage; a nil aiProvider skips the AI recommendations
+func (gcm *GCManager) AnalyzeDiskUsage(aiProvider ai.AIProvider, top int) error {
 	// Get disk usage breakdown
 	storeSize, err := gcm.getStoreSize()
 	if err != nil {
@@ -836,8 +836,26 @@ func (gcm *GCManager) AnalyzeDiskUsage(aiProvider ai.AIProvider) error {
 	gcm.drawUsageBar(usagePercent)
 	fmt.Println()
 
+	// Show where the store space goes
+	var largest []StoreUsage
+	fmt.Print(utils.FormatProgress("Measuring store paths... "))
+	entries, source, err := storePathSizes()
+	if err != nil {
+		fmt.Println(utils.FormatWarning("skipped: " + err.Error()))
+	} else {
+		fmt.Println(utils.FormatSuccess("done"))
+		largest = topStoreUsage(aggregateStoreUsage(entries), top)
+		fmt.Println()
+		fmt.Println(utils.FormatSubsection(fmt.Sprintf("📦 Largest Packages (%s)", source), ""))
+		fmt.Println(formatStoreUsageChart(largest))
+	}
+	fmt.Println()
+
 	// Get AI recommendations
-	prompt := gcm.buildDiskUsagePrompt(storeSize, used, available, total)
+	if aiProvider == nil {
+		return nil
+	}
+	prompt := gcm.buildDiskUsagePrompt(storeSize, used, available, total, largest)
 	recommendations, err := aiProvider.Query(prompt)
 	if err != nil {
 		return fmt.Errorf("failed to get AI recommendations: %w", err)
@@ -926,16 +944,26 @@ Focus on safety and provide clear, actionable guidance.`,
 		analysis.RiskLevel)
 }
 
-func (gcm *GCManager) buildCompareGenerationsPrompt(generations []Generation, keepCount int) string {
+func (gcm *GCManager) buildCompareGenerationsPrompt(generations []Generation, keepCount int, plan GenerationPlan, commands []string) string {
 	generationList := make([]string, 0, len(generations))
-	for _, gen := range generations {
+	for i, gen := range generations {
 		age := time.Since(gen.Date)
 		status := "normal"
 		if gen.Current {
 			status = "current"
 		}
-		generationList = append(generationList, fmt.Sprintf("Generation %d: %s ago (%s)",
-			gen.Number, formatDuration(age), status))
+		details := ""
+		if gen.Kernel != "" {
+			details += ", kernel " + gen.Kernel
+		}
+		if gen.Size > 0 {
+			details += ", closure " + formatBytes(gen.Size)
+		}
+		if delta, ok := sizeDelta(generations, i); ok {
+			details += " (" + formatSizeDelta(delta) + ")"
+		}
+		generationList = append(generationList, fmt.Sprintf("Generation %d: %s ago (%s%s)",
+			gen.Number, formatDuration(age), status, details))
 	}
 
 	return fmt.Sprintf(`Analyze these NixOS generations and recommend which to keep or remove:
@@ -944,23 +972,39 @@ Generations:
 %s
 
 Requested to keep: %d generations
+Proposed to keep: %s
+Proposed to remove: %s
+Proposed commands:
+%s
 
 Please provide:
-1. Which generations are safe to remove and why
-2. Which generations should definitely be kept
-3. Risk assessment for removing each generation
-4. Recommended cleanup strategy
+1. Whether the proposed removal is safe, and any generation that should be kept instead
+2. Which generations should definitely be kept (current, previous, kernel changes)
+3. Risk assessment for removing the proposed generations
+4. Recommended cleanup strategy, using 'nix-env --delete-generations' and 'nixos-rebuild boot'
 5. Best practices for generation management
 
 Consider factors like:
 - Recency and boot success
+- Kernel changes between generations
 - System stability
 - Rollback capabilities
 - Disk space impact`,
-		strings.Join(generationList, "\n"), keepCount)
+		strings.Join(generationList, "\n"), keepCount,
+		formatGenerationNumbers(plan.Keep), formatGenerationNumbers(plan.Remove),
+		strings.Join(commands, "\n"))
 }
 
-func (gcm *GCManager) buildDiskUsagePrompt(storeSize, used, available, total int64) string {
+func (gcm *GCManager) buildDiskUsagePrompt(storeSize, used, available, total int64, largest []StoreUsage) string {
+	packages := "- unknown"
+	if len(largest) > 0 {
+		lines := make([]string, 0, len(largest))
+		for _, usage := range largest {
+			lines = append(lines, fmt.Sprintf("- %s: %s (%d store paths)", usage.Name, formatBytes(usage.Size), usage.Paths))
+		}
+		packages = strings.Join(lines, "\n")
+	}
+
 	return fmt.Sprintf(`Analyze this Nix store disk usage and provide optimization recommendations:
 
 Disk Usage:
@@ -970,6 +1014,9 @@ Disk Usage:
 - Nix store size: %s
 - Store percentage of total: %.1f%%
 
+Largest packages:
+%s
+
 Please provide:
 1. Assessment of current disk usage efficiency
 2. Optimization opportunities specific to Nix
@@ -982,7 +1029,8 @@ Focus on Nix-specific optimizations and long-term storage health.`,
 		formatBytes(used),
 		formatBytes(available),
 		formatBytes(storeSize),
-		float64(storeSize)/float64(total)*100)
+		float64(storeSize)/float64(total)*100,
+		packages)
 }
 
 // drawUsageBar draws a simple ASCII usage bar
@@ -1052,6 +1100,8 @@ func init() {
 	gcSafeCleanCmd.Flags().Bool("dry-run", false, "Show what would be done without making changes")
 	gcSafeCleanCmd.Flags().IntP("keep-generations", "k", 5, "Number of recent generations to keep")
 	gcCompareGenerationsCmd.Flags().IntP("keep", "k", 5, "Number of generations to recommend keeping")
+	gcCompareGenerationsCmd.Flags().IntSlice("pin", nil, "Generation numbers that must never be removed")
+	gcDiskUsageCmd.Flags().Int("top", defaultStoreUsageTop, "Number of largest packages to show")
 }
 
 // NewGCCmd creates a new gc command with all subcommands and flags
@@ -0,0 +1,215 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/internal/nixos"
+	"nix-ai-help/pkg/utils"
+)
+
+// systemProfile is the profile holding the NixOS system generations
+const systemProfile = "/nix/var/nix/profiles/system"
+
+var (
+	// generationLineRegex matches both `nix-env --list-generations` lines
+	// ("  42   2024-01-02 10:11:12   (current)") and `nixos-rebuild list-generations` lines
+	// ("42 current  2024-01-02 10:11:12  24.05.20240101.abcdef  6.6.8  ...")
+	generationLineRegex = regexp.MustCompile(`^\s*(\d+)\s+(current\s+)?(\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}:\d{2})\s*(.*)$`)
+	// kernelStorePathRegex extracts the kernel version from the kernel store path of a generation
+	kernelStorePathRegex = regexp.MustCompile(`-linux-([0-9][^/]*)`)
+)
+
+// GenerationPlan is the keep/remove recommendation for system generations
+type GenerationPlan struct {
+	Keep    []int          `json:"keep"`
+	Remove  []int          `json:"remove"`
+	Reasons map[int]string `json:"reasons"`
+}
+
+// parseGenerations parses `nixos-rebuild list-generations` or `nix-env --list-generations`
+// output, newest generation first
+func parseGenerations(output string) []Generation {
+	var generations []Generation
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		matches := generationLineRegex.FindStringSubmatch(scanner.Text())
+		if matches == nil {
+			continue
+		}
+		number, err := strconv.Atoi(matches[1])
+		if err != nil {
+			continue
+		}
+		date, err := time.ParseInLocation("2006-01-02 15:04:05", strings.Replace(matches[3], "T", " ", 1), time.Local)
+		if err != nil {
+			continue
+		}
+
+		rest := strings.TrimSpace(matches[4])
+		gen := Generation{
+			Number:      number,
+			Date:        date,
+			Description: rest,
+			Current:     matches[2] != "" || strings.Contains(rest, "(current)"),
+		}
+		// nixos-rebuild lists the NixOS version and kernel after the date
+		if fields := strings.Fields(rest); len(fields) >= 2 && !strings.HasPrefix(rest, "(") {
+			gen.Description = "NixOS " + fields[0]
+			gen.Kernel = fields[1]
+		}
+		gen.Safe = !gen.Current && time.Since(date) > 24*time.Hour
+		generations = append(generations, gen)
+	}
+
+	sort.Slice(generations, func(i, j int) bool {
+		return generations[i].Number > generations[j].Number
+	})
+	return generations
+}
+
+// listGenerations returns the system generations, trying nixos-rebuild and then nix-env
+func listGenerations() ([]Generation, error) {
+	output, err := utils.TraceOutput(exec.Command("nixos-rebuild", "list-generations"))
+	if err == nil {
+		if generations := parseGenerations(string(output)); len(generations) > 0 {
+			return generations, nil
+		}
+	}
+
+	output, err = utils.TraceOutput(exec.Command("nix-env", "--list-generations", "-p", systemProfile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list generations: %w", err)
+	}
+	return parseGenerations(string(output)), nil
+}
+
+// generationLink returns the profile link of a system generation
+func generationLink(number int) string {
+	return fmt.Sprintf("%s-%d-link", systemProfile, number)
+}
+
+// kernelFromStorePath returns the kernel version in a kernel store path, e.g. 6.6.8 for
+// /nix/store/...-linux-6.6.8/bzImage
+func kernelFromStorePath(path string) string {
+	if matches := kernelStorePathRegex.FindStringSubmatch(path); matches != nil {
+		return matches[1]
+	}
+	return ""
+}
+
+// addGenerationDetails fills in the kernel and closure size of each generation from its profile link
+func addGenerationDetails(generations []Generation) {
+	for i := range generations {
+		link := generationLink(generations[i].Number)
+		if generations[i].Kernel == "" {
+			if target, err := filepath.EvalSymlinks(filepath.Join(link, "kernel")); err == nil {
+				generations[i].Kernel = kernelFromStorePath(target)
+			}
+		}
+		if _, err := os.Lstat(link); err != nil {
+			continue
+		}
+		// #nosec G204 -- link is a fixed system profile path
+		if output, err := utils.TraceOutput(exec.Command("nix", "path-info", "-S", link)); err == nil {
+			if fields := strings.Fields(string(output)); len(fields) >= 2 {
+				generations[i].Size, _ = strconv.ParseInt(fields[len(fields)-1], 10, 64)
+			}
+		}
+	}
+}
+
+// sizeDelta returns the closure size change of the generation at index i compared with the
+// next older generation in a newest-first list, and whether both sizes are known
+func sizeDelta(generations []Generation, i int) (int64, bool) {
+	if i+1 >= len(generations) || generations[i].Size == 0 || generations[i+1].Size == 0 {
+		return 0, false
+	}
+	return generations[i].Size - generations[i+1].Size, true
+}
+
+// formatSizeDelta formats a size change with its sign
+func formatSizeDelta(delta int64) string {
+	if delta < 0 {
+		return "-" + formatBytes(-delta)
+	}
+	return "+" + formatBytes(delta)
+}
+
+// recommendGenerations decides which generations to keep: the current one, the one before it
+// for rollback, any pinned generations and the keepCount most recent. Everything else can go.
+func recommendGenerations(generations []Generation, keepCount int, pinned []int) GenerationPlan {
+	plan := GenerationPlan{Reasons: make(map[int]string)}
+	isPinned := make(map[int]bool, len(pinned))
+	for _, number := range pinned {
+		isPinned[number] = true
+	}
+
+	sorted := append([]Generation(nil), generations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Number > sorted[j].Number })
+
+	current := -1
+	for _, gen := range sorted {
+		if gen.Current {
+			current = gen.Number
+			break
+		}
+	}
+	// Without a marked current generation, treat the newest as current
+	if current < 0 && len(sorted) > 0 {
+		current = sorted[0].Number
+	}
+	previous := -1
+	for _, gen := range sorted {
+		if current >= 0 && gen.Number < current {
+			previous = gen.Number
+			break
+		}
+	}
+
+	for i, gen := range sorted {
+		switch {
+		case gen.Number == current:
+			plan.Reasons[gen.Number] = "current generation"
+		case gen.Number == previous:
+			plan.Reasons[gen.Number] = "previous generation (rollback target)"
+		case isPinned[gen.Number]:
+			plan.Reasons[gen.Number] = "pinned"
+		case i < keepCount:
+			plan.Reasons[gen.Number] = fmt.Sprintf("within the %d most recent", keepCount)
+		default:
+			plan.Remove = append(plan.Remove, gen.Number)
+			continue
+		}
+		plan.Keep = append(plan.Keep, gen.Number)
+	}
+
+	sort.Ints(plan.Keep)
+	sort.Ints(plan.Remove)
+	return plan
+}
+
+// generationCleanupCommands returns the commands that remove the given generations and
+// refresh the boot menu with the rebuild syntax of the detected system
+func generationCleanupCommands(remove []int, nixosCtx *config.NixOSContext) []string {
+	if len(remove) == 0 {
+		return nil
+	}
+	numbers := make([]string, len(remove))
+	for i, number := range remove {
+		numbers[i] = strconv.Itoa(number)
+	}
+	return []string{
+		fmt.Sprintf("sudo nix-env --delete-generations -p %s %s", systemProfile, strings.Join(numbers, " ")),
+		nixos.RebuildCommandLine(nixosCtx, "boot"),
+	}
+}
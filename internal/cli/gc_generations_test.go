@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGenerations_NixEnv(t *testing.T) {
+	output := `   40   2024-05-01 09:15:00
+   41   2024-05-20 18:02:11
+   42   2024-06-02 12:30:45   (current)
+`
+	gens := parseGenerations(output)
+	if len(gens) != 3 {
+		t.Fatalf("expected 3 generations, got %d", len(gens))
+	}
+	if gens[0].Number != 42 || !gens[0].Current {
+		t.Errorf("expected newest current generation 42 first, got %+v", gens[0])
+	}
+	if gens[2].Number != 40 || gens[2].Current {
+		t.Errorf("expected generation 40 last and not current, got %+v", gens[2])
+	}
+	want := time.Date(2024, 5, 20, 18, 2, 11, 0, time.Local)
+	if !gens[1].Date.Equal(want) {
+		t.Errorf("date = %v, want %v", gens[1].Date, want)
+	}
+}
+
+func TestParseGenerations_NixosRebuild(t *testing.T) {
+	output := `Generation  Build-date           NixOS version                Kernel  Configuration Revision  Specialisation
+118 current  2024-06-02 12:30:45  24.05.20240601.abcdef0      6.6.32                                  *
+117          2024-05-28 08:00:00  24.05.20240527.1234567      6.6.31                                  *
+`
+	gens := parseGenerations(output)
+	if len(gens) != 2 {
+		t.Fatalf("expected 2 generations, got %d", len(gens))
+	}
+	if !gens[0].Current || gens[0].Kernel != "6.6.32" || gens[0].Description != "NixOS 24.05.20240601.abcdef0" {
+		t.Errorf("unexpected current generation: %+v", gens[0])
+	}
+	if gens[1].Current || gens[1].Kernel != "6.6.31" {
+		t.Errorf("unexpected older generation: %+v", gens[1])
+	}
+}
+
+func TestKernelFromStorePath(t *testing.T) {
+	if got := kernelFromStorePath("/nix/store/abc123-linux-6.6.32/bzImage"); got != "6.6.32" {
+		t.Errorf("kernel = %q, want 6.6.32", got)
+	}
+	if got := kernelFromStorePath("/nix/store/abc123-something/bzImage"); got != "" {
+		t.Errorf("expected no kernel, got %q", got)
+	}
+}
+
+func TestRecommendGenerations(t *testing.T) {
+	gens := []Generation{{Number: 10}, {Number: 11}, {Number: 12}, {Number: 13, Current: true}, {Number: 14}, {Number: 9}}
+
+	plan := recommendGenerations(gens, 2, []int{9})
+	// 14 and 13 are the two most recent, 13 is current, 12 is the rollback target, 9 is pinned
+	if !reflect.DeepEqual(plan.Keep, []int{9, 12, 13, 14}) {
+		t.Errorf("Keep = %v", plan.Keep)
+	}
+	if !reflect.DeepEqual(plan.Remove, []int{10, 11}) {
+		t.Errorf("Remove = %v", plan.Remove)
+	}
+	if plan.Reasons[12] != "previous generation (rollback target)" || plan.Reasons[9] != "pinned" {
+		t.Errorf("unexpected reasons: %v", plan.Reasons)
+	}
+}
+
+func TestRecommendGenerations_KeepsNewestWithoutCurrent(t *testing.T) {
+	plan := recommendGenerations([]Generation{{Number: 1}, {Number: 2}, {Number: 3}}, 0, nil)
+	if !reflect.DeepEqual(plan.Keep, []int{2, 3}) || !reflect.DeepEqual(plan.Remove, []int{1}) {
+		t.Errorf("unexpected plan: %+v", plan)
+	}
+}
+
+func TestGenerationCleanupCommands(t *testing.T) {
+	if commands := generationCleanupCommands(nil, nil); commands != nil {
+		t.Errorf("expected no commands, got %v", commands)
+	}
+	commands := generationCleanupCommands([]int{10, 11}, nil)
+	if len(commands) != 2 || !strings.HasSuffix(commands[0], "--delete-generations -p /nix/var/nix/profiles/system 10 11") {
+		t.Errorf("unexpected commands: %v", commands)
+	}
+}
+
+func TestSizeDelta(t *testing.T) {
+	gens := []Generation{{Number: 3, Size: 3000}, {Number: 2, Size: 2000}, {Number: 1}}
+	if delta, ok := sizeDelta(gens, 0); !ok || delta != 1000 {
+		t.Errorf("delta = %d, %v", delta, ok)
+	}
+	if _, ok := sizeDelta(gens, 1); ok {
+		t.Error("delta against an unknown size should not be reported")
+	}
+	if got := formatSizeDelta(-2048); got != "-2.0 KB" {
+		t.Errorf("formatSizeDelta = %q", got)
+	}
+}
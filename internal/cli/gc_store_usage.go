@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"nix-ai-help/pkg/utils"
+)
+
+const (
+	// systemClosure is the store path whose closure is measured by disk-usage
+	systemClosure = "/run/current-system"
+	// defaultStoreUsageTop is the default number of packages shown by disk-usage
+	defaultStoreUsageTop = 15
+	// storeUsageBarWidth is the width of the bars in the disk-usage chart
+	storeUsageBarWidth = 30
+	// duBatchSize is the number of store paths passed to a single du call
+	duBatchSize = 500
+)
+
+// storePathSize is the size of a single store path
+type storePathSize struct {
+	Path string
+	Size int64
+}
+
+// StoreUsage is the disk space used by all store paths of one package
+type StoreUsage struct {
+	Name  string `json:"name"`
+	Size  int64  `json:"size"`
+	Paths int    `json:"paths"`
+}
+
+// parsePathInfo parses `nix path-info -rsS` output ("<path> <narSize> <closureSize>"). The NAR
+// size of each path is used so that summing paths does not count shared dependencies twice.
+func parsePathInfo(output string) []storePathSize {
+	var entries []storePathSize
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.HasPrefix(fields[0], "/nix/store/") {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, storePathSize{Path: fields[0], Size: size})
+	}
+	return entries
+}
+
+// parseDuOutput parses `du -sb` output ("<bytes>\t<path>")
+func parseDuOutput(output string) []storePathSize {
+	var entries []storePathSize
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, storePathSize{Path: fields[len(fields)-1], Size: size})
+	}
+	return entries
+}
+
+// storePackageName returns the package name of a store path without hash, version and output,
+// e.g. python3.11-requests for /nix/store/<hash>-python3.11-requests-2.31.0-dist
+func storePackageName(path string) string {
+	base := filepath.Base(path)
+	if i := strings.Index(base, "-"); i == 32 {
+		base = base[i+1:]
+	}
+	parts := strings.Split(base, "-")
+	name := []string{parts[0]}
+	for _, part := range parts[1:] {
+		if part != "" && part[0] >= '0' && part[0] <= '9' {
+			break
+		}
+		name = append(name, part)
+	}
+	return strings.Join(name, "-")
+}
+
+// aggregateStoreUsage groups store paths by package, largest first
+func aggregateStoreUsage(entries []storePathSize) []StoreUsage {
+	byName := make(map[string]*StoreUsage)
+	for _, entry := range entries {
+		name := storePackageName(entry.Path)
+		usage, ok := byName[name]
+		if !ok {
+			usage = &StoreUsage{Name: name}
+			byName[name] = usage
+		}
+		usage.Size += entry.Size
+		usage.Paths++
+	}
+
+	usages := make([]StoreUsage, 0, len(byName))
+	for _, usage := range byName {
+		usages = append(usages, *usage)
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].Size != usages[j].Size {
+			return usages[i].Size > usages[j].Size
+		}
+		return usages[i].Name < usages[j].Name
+	})
+	return usages
+}
+
+// topStoreUsage returns the n largest packages; n <= 0 returns all of them
+func topStoreUsage(usages []StoreUsage, n int) []StoreUsage {
+	if n <= 0 || n >= len(usages) {
+		return usages
+	}
+	return usages[:n]
+}
+
+// storePathSizes measures the system closure with nix path-info, falling back to du on the
+// top-level store entries
+func storePathSizes() ([]storePathSize, string, error) {
+	if output, err := utils.TraceOutput(exec.Command("nix", "path-info", "-rsS", systemClosure)); err == nil {
+		if entries := parsePathInfo(string(output)); len(entries) > 0 {
+			return entries, "system closure (nix path-info)", nil
+		}
+	}
+
+	paths, err := filepath.Glob("/nix/store/*")
+	if err != nil || len(paths) == 0 {
+		return nil, "", fmt.Errorf("no store paths found")
+	}
+	// Measure in batches to stay below the argument length limit on large stores
+	var entries []storePathSize
+	for start := 0; start < len(paths); start += duBatchSize {
+		end := start + duBatchSize
+		if end > len(paths) {
+			end = len(paths)
+		}
+		// #nosec G204 -- arguments are store paths from the glob above
+		output, err := utils.TraceOutput(exec.Command("du", append([]string{"-sb"}, paths[start:end]...)...))
+		if err != nil && len(output) == 0 {
+			return nil, "", fmt.Errorf("failed to measure store paths: %w", err)
+		}
+		entries = append(entries, parseDuOutput(string(output))...)
+	}
+	return entries, "all store paths (du)", nil
+}
+
+// formatStoreUsageChart renders packages as a table with a bar relative to the largest one
+func formatStoreUsageChart(usages []StoreUsage) string {
+	if len(usages) == 0 {
+		return ""
+	}
+	largest := usages[0].Size
+	rows := make([][]string, 0, len(usages))
+	for _, usage := range usages {
+		rows = append(rows, []string{
+			usage.Name,
+			formatBytes(usage.Size),
+			strconv.Itoa(usage.Paths),
+			usageBar(usage.Size, largest, storeUsageBarWidth),
+		})
+	}
+	return utils.FormatTable([]string{"Package", "Size", "Paths", ""}, rows)
+}
+
+// usageBar draws a bar of up to width cells for size relative to max
+func usageBar(size, max int64, width int) string {
+	if max <= 0 {
+		return ""
+	}
+	filled := int(size * int64(width) / max)
+	if filled == 0 && size > 0 {
+		filled = 1
+	}
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const pathInfoFixture = `/nix/store/0a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p-linux-6.6.32	  130000000	  140000000
+/nix/store/1a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p-linux-6.6.32-modules	  90000000	  230000000
+/nix/store/2a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p-glibc-2.39-52	  30000000	  30000000
+/nix/store/3a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p-glibc-2.39-52-bin	  5000000	  35000000
+/nix/store/4a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p-python3.11-requests-2.31.0	  1000000	  90000000
+/nix/store/5a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p-firefox-126.0	  250000000	  900000000
+/nix/store/6a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p-etc	  20000	  800000000
+error: some warning line
+`
+
+func TestParsePathInfo(t *testing.T) {
+	entries := parsePathInfo(pathInfoFixture)
+	if len(entries) != 7 {
+		t.Fatalf("expected 7 entries, got %d", len(entries))
+	}
+	if entries[0].Size != 130000000 {
+		t.Errorf("expected the NAR size of the path, got %d", entries[0].Size)
+	}
+}
+
+func TestParseDuOutput(t *testing.T) {
+	entries := parseDuOutput("1024\t/nix/store/aaa-foo-1.0\nbad line\n2048\t/nix/store/bbb-bar-2.0\n")
+	want := []storePathSize{{"/nix/store/aaa-foo-1.0", 1024}, {"/nix/store/bbb-bar-2.0", 2048}}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("parseDuOutput() = %v, want %v", entries, want)
+	}
+}
+
+func TestStorePackageName(t *testing.T) {
+	tests := map[string]string{
+		"/nix/store/0a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p-linux-6.6.32-modules":       "linux",
+		"/nix/store/0a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p-python3.11-requests-2.31.0": "python3.11-requests",
+		"/nix/store/0a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p-etc":                        "etc",
+		"/nix/store/0a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p-nixos-system-host-24.05":    "nixos-system-host",
+	}
+	for path, want := range tests {
+		if got := storePackageName(path); got != want {
+			t.Errorf("storePackageName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestAggregateStoreUsage(t *testing.T) {
+	usages := aggregateStoreUsage(parsePathInfo(pathInfoFixture))
+	want := []StoreUsage{
+		{Name: "firefox", Size: 250000000, Paths: 1},
+		{Name: "linux", Size: 220000000, Paths: 2},
+		{Name: "glibc", Size: 35000000, Paths: 2},
+		{Name: "python3.11-requests", Size: 1000000, Paths: 1},
+		{Name: "etc", Size: 20000, Paths: 1},
+	}
+	if !reflect.DeepEqual(usages, want) {
+		t.Errorf("aggregateStoreUsage() =\n%v\nwant\n%v", usages, want)
+	}
+
+	top := topStoreUsage(usages, 2)
+	if len(top) != 2 || top[0].Name != "firefox" || top[1].Name != "linux" {
+		t.Errorf("topStoreUsage(2) = %v", top)
+	}
+	if len(topStoreUsage(usages, 0)) != len(usages) {
+		t.Error("topStoreUsage(0) should return all packages")
+	}
+}
+
+func TestUsageBar(t *testing.T) {
+	if got := usageBar(50, 100, 10); got != strings.Repeat("█", 5)+strings.Repeat("░", 5) {
+		t.Errorf("usageBar(50, 100) = %q", got)
+	}
+	if got := usageBar(1, 1000, 10); !strings.HasPrefix(got, "█") {
+		t.Errorf("a non-zero size should show at least one cell, got %q", got)
+	}
+}
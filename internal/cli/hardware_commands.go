@@ -3,6 +3,7 @@ package cli
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -61,7 +62,15 @@ This command identifies:
 - Memory configuration and optimization potential
 - Storage devices and performance settings
 - Network interfaces and driver status
-- Power management capabilities (for laptops)`,
+- Power management capabilities (for laptops)
+
+The existing hardware-configuration.nix is read as well, so recommendations build on the
+file systems and kernel modules nixos-generate-config set, and detected hardware that
+disagrees with the file is reported.
+
+Examples:
+  nixai hardware detect
+  nixai hardware detect --hardware-config ./hosts/laptop/hardware-configuration.nix`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Fprintln(cmd.OutOrStdout(), utils.FormatHeader("🔍 Hardware Detection & Analysis"))
 		fmt.Fprintln(cmd.OutOrStdout())
@@ -89,17 +98,13 @@ This command identifies:
 			fmt.Fprintln(cmd.OutOrStdout())
 		}
 
-		// Initialize AI provider
-		legacyProvider, err := GetLegacyAIProvider(cfg, logger.NewLogger())
-		if err != nil {
+		// Initialize AI provider; without AI only the detected hardware is shown
+		legacyProvider, err := optionalAIProvider(cfg, logger.NewLogger())
+		if err != nil && !errors.Is(err, errAIDisabled) {
 			fmt.Fprintln(cmd.ErrOrStderr(), utils.FormatError("Failed to initialize AI provider: "+err.Error()))
 			return
 		}
 
-		// Initialize Hardware Agent with legacy provider adapter
-		hardwareProvider := ai.NewLegacyProviderAdapter(legacyProvider)
-		hardwareAgent := agent.NewHardwareAgent(hardwareProvider)
-
 		// Perform comprehensive hardware detection
 		fmt.Fprintln(cmd.OutOrStdout(), utils.FormatProgress("Detecting hardware components..."))
 
@@ -126,12 +131,31 @@ This command identifies:
 			VirtualizationInfo: hardwareInfo.Virtualization,
 		}
 
-		// Set context in the agent
-		hardwareAgent.SetContext(hwContext)
-
 		// Display detected hardware
 		displayDetectedHardwareToWriter(hardwareInfo, cmd.OutOrStdout())
 
+		baseQuery := "Analyze the detected hardware and provide comprehensive NixOS configuration recommendations for optimal performance, compatibility, and power management."
+
+		// Cross-reference what nixos-generate-config already set
+		hwConfigFlag, _ := cmd.Flags().GetString("hardware-config")
+		hwConfigFile := hardwareConfigPath(hwConfigFlag, nixosCtx)
+		if hwConfig, err := loadHardwareConfig(hwConfigFile); err == nil {
+			warnings := crossReferenceHardware(hardwareInfo, hwConfig, deviceExists)
+			renderHardwareConfig(cmd.OutOrStdout(), hwConfig, warnings)
+			baseQuery += hardwareConfigPromptContext(hwConfig, warnings)
+		} else if hwConfigFlag != "" || !os.IsNotExist(err) {
+			fmt.Fprintln(cmd.OutOrStdout(), utils.FormatWarning("Could not read "+hwConfigFile+": "+err.Error()))
+		}
+
+		if legacyProvider == nil {
+			fmt.Fprintln(cmd.OutOrStdout(), utils.FormatNote("Detected hardware only, without an AI analysis (--no-ai)"))
+			return
+		}
+
+		// Initialize Hardware Agent with legacy provider adapter
+		hardwareAgent := agent.NewHardwareAgent(ai.NewLegacyProviderAdapter(legacyProvider))
+		hardwareAgent.SetContext(hwContext)
+
 		// Get AI analysis for hardware optimization using the HardwareAgent
 		fmt.Fprintln(cmd.OutOrStdout(), utils.FormatProgress("Analyzing hardware for NixOS optimization..."))
 
@@ -139,7 +163,6 @@ This command identifies:
 
 		// Build context-aware analysis query
 		contextBuilder := nixoscontext.NewNixOSContextBuilder()
-		baseQuery := "Analyze the detected hardware and provide comprehensive NixOS configuration recommendations for optimal performance, compatibility, and power management."
 		contextualQuery := contextBuilder.BuildContextualPrompt(baseQuery, nixosCtx)
 
 		analysis, err := hardwareAgent.Query(ctx, contextualQuery)
@@ -175,6 +198,10 @@ This command provides:
 - Network interface optimization
 - Power efficiency improvements`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := requireAI("hardware optimize"); err != nil {
+			fmt.Println(utils.FormatError(err.Error()))
+			return
+		}
 		fmt.Println(utils.FormatHeader("⚡ Hardware Optimization"))
 		fmt.Println()
 
@@ -282,6 +309,10 @@ This command handles:
 - Hardware-specific kernel modules
 - Firmware updates and microcode`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := requireAI("hardware drivers"); err != nil {
+			fmt.Println(utils.FormatError(err.Error()))
+			return
+		}
 		fmt.Println(utils.FormatHeader("🔌 Driver & Firmware Configuration"))
 		fmt.Println()
 
@@ -365,72 +396,52 @@ var hardwareCompareCmd = &cobra.Command{
 	Short: "Compare current vs optimal settings",
 	Long: `Compare your current NixOS configuration against optimal hardware settings.
 
-This command analyzes:
-- Current driver configurations vs recommended
-- Performance settings comparison
-- Missing optimization opportunities
-- Potential compatibility issues
-- Configuration drift detection
-- Best practice compliance`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println(utils.FormatHeader("🔄 Configuration Comparison"))
-		fmt.Println()
+For each detected component (CPU, GPU, storage, firmware, virtualization) this
+command shows the value your configuration sets next to the recommended one,
+with the reason for the recommendation. Recommendations follow the detected
+hardware and the chosen --profile (balanced, performance or power-save).
 
-		// Initialize AI provider
-		cfg, err := config.LoadUserConfig()
-		if err != nil {
-			fmt.Printf("Warning: Failed to load config, using defaults: %v\n", err)
-			cfg = &config.UserConfig{AIProvider: "ollama", AIModel: "llama3"}
-		}
-		aiProvider, err := GetLegacyAIProvider(cfg, logger.NewLogger())
-		if err != nil {
-			fmt.Println(utils.FormatError("Failed to initialize AI provider: " + err.Error()))
+With --patch the settings that differ are printed as a patch creating
+hardware-recommendations.nix, a module to add to your imports.`,
+	Example: `  nixai hardware compare
+  nixai hardware compare --profile power-save
+  nixai hardware compare --patch | git apply`,
+	Run: func(cmd *cobra.Command, args []string) {
+		out := cmd.OutOrStdout()
+		profile, _ := cmd.Flags().GetString("profile")
+		patch, _ := cmd.Flags().GetBool("patch")
+		switch profile {
+		case hardwareProfileBalanced, hardwareProfilePerformance, hardwareProfilePowerSave:
+		default:
+			fmt.Fprintln(cmd.ErrOrStderr(), utils.FormatError("Unknown profile '"+profile+"'; use balanced, performance or power-save"))
 			return
 		}
 
-		// Get comparison analysis
-		fmt.Println(utils.FormatProgress("Analyzing current configuration vs optimal settings..."))
-
-		prompt := `As a NixOS configuration expert, perform a comprehensive comparison analysis. Compare:
-
-## Current vs Optimal Configuration
-- Analyze what's currently configured vs hardware-optimal settings
-- Identify missing driver configurations
-- Compare performance settings with best practices
-- Highlight potential conflicts or issues
-
-## Gap Analysis
-- What optimizations are missing?
-- Which drivers could be improved?
-- Performance tuning opportunities
-- Security improvements available
-
-## Migration Path
-- Step-by-step plan to move from current to optimal
-- Risk assessment for each change
-- Testing recommendations
-- Rollback strategies
-
-## Compliance Check
-- NixOS best practices compliance
-- Hardware vendor recommendations
-- Community-validated configurations
-- Performance benchmarking suggestions
-
-Provide actionable recommendations with priority levels (high, medium, low).`
+		configDir := "/etc/nixos"
+		if cfg, err := config.LoadUserConfig(); err == nil && cfg.NixosFolder != "" {
+			configDir = cfg.NixosFolder
+		}
+		if nixosPath != "" {
+			configDir = nixosPath
+		}
+		configDir = utils.ExpandHome(configDir)
 
-		comparison, err := aiProvider.Query(prompt)
+		if !patch {
+			fmt.Fprintln(out, utils.FormatHeader("🔄 Configuration Comparison"))
+			fmt.Fprintln(out)
+		}
+		info, err := detectHardwareComponents()
 		if err != nil {
-			fmt.Println(utils.FormatError("Could not get comparison analysis: " + err.Error()))
+			fmt.Fprintln(cmd.ErrOrStderr(), utils.FormatError("Failed to detect hardware: "+err.Error()))
 			return
 		}
-
-		fmt.Println(utils.FormatSubsection("📊 Configuration Analysis", ""))
-		fmt.Println(utils.RenderMarkdown(comparison))
-
-		fmt.Println()
-		fmt.Println(utils.FormatTip("Use 'nixai hardware optimize' to apply recommended changes"))
-		fmt.Println(utils.FormatTip("Test changes with 'nixos-rebuild test' before switching"))
+		if err := runHardwareCompare(out, info, configDir, profile, patch); err != nil {
+			fmt.Fprintln(cmd.ErrOrStderr(), utils.FormatError(err.Error()))
+			return
+		}
+		if !patch {
+			fmt.Fprintln(out, utils.FormatTip("Test changes with 'nixos-rebuild test' before switching"))
+		}
 	},
 }
 
@@ -438,107 +449,60 @@ Provide actionable recommendations with priority levels (high, medium, low).`
 var hardwareLaptopCmd = &cobra.Command{
 	Use:   "laptop",
 	Short: "Laptop-specific optimizations",
-	Long: `Apply laptop-specific NixOS optimizations for better battery life and thermal management.
-
-This command provides:
-- Power management and battery optimization
-- Thermal management and fan control
-- Display scaling and brightness control
-- WiFi power saving configuration
-- Suspend and hibernation setup
-- Docking station and external monitor support`,
+	Long: `Generate a NixOS power management module for this laptop.
+
+The module is based on the detected battery, backlight and CPU:
+- TLP with powertop autotuning (--power-save) or auto-cpufreq (balanced, --performance)
+- Battery charge thresholds when the battery supports them
+- thermald for Intel CPUs, amd-pstate for AMD CPUs
+- Backlight control for brightness keys
+- Suspend on lid close
+
+The generated module is checked with 'nix-instantiate --parse' when Nix is installed.`,
+	Example: `  nixai hardware laptop
+  nixai hardware laptop --power-save --output /etc/nixos/laptop-power.nix`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println(utils.FormatHeader("💻 Laptop Optimization"))
-		fmt.Println()
+		out := cmd.OutOrStdout()
+		fmt.Fprintln(out, utils.FormatHeader("💻 Laptop Optimization"))
+		fmt.Fprintln(out)
 
 		powerSave, _ := cmd.Flags().GetBool("power-save")
 		performance, _ := cmd.Flags().GetBool("performance")
+		outputPath, _ := cmd.Flags().GetString("output")
 
 		if powerSave && performance {
-			fmt.Println(utils.FormatError("Cannot use both --power-save and --performance flags"))
+			fmt.Fprintln(out, utils.FormatError("Cannot use both --power-save and --performance flags"))
 			return
 		}
 
-		mode := "balanced"
+		mode := hardwareProfileBalanced
 		if powerSave {
-			mode = "power-save"
-			fmt.Println(utils.FormatInfo("Power-save mode selected"))
+			mode = hardwareProfilePowerSave
+			fmt.Fprintln(out, utils.FormatInfo("Power-save mode selected"))
 		} else if performance {
-			mode = "performance"
-			fmt.Println(utils.FormatInfo("Performance mode selected"))
+			mode = hardwareProfilePerformance
+			fmt.Fprintln(out, utils.FormatInfo("Performance mode selected"))
 		} else {
-			fmt.Println(utils.FormatInfo("Balanced mode selected (default)"))
+			fmt.Fprintln(out, utils.FormatInfo("Balanced mode selected (default)"))
 		}
-		fmt.Println()
+		fmt.Fprintln(out)
 
-		// Initialize AI provider
-		cfg, err := config.LoadUserConfig()
-		if err != nil {
-			fmt.Printf("Warning: Failed to load config, using defaults: %v\n", err)
-			cfg = &config.UserConfig{AIProvider: "ollama", AIModel: "llama3"}
-		}
-		aiProvider, err := GetLegacyAIProvider(cfg, logger.NewLogger())
-		if err != nil {
-			fmt.Println(utils.FormatError("Failed to initialize AI provider: " + err.Error()))
-			return
+		// The lid switch options were renamed in 25.11, so the module follows the detected release
+		var release string
+		if cfg, err := config.LoadUserConfig(); err == nil {
+			nixosCtx, _ := nixos.NewContextDetector(logger.NewLogger()).GetContext(cfg)
+			release = nixpkgsRelease(nixosCtx)
 		}
 
-		// Get laptop-specific recommendations
-		fmt.Println(utils.FormatProgress("Analyzing laptop hardware for optimization..."))
-
-		prompt := fmt.Sprintf(`As a NixOS laptop optimization expert, provide comprehensive laptop-specific recommendations for %s mode. Include:
-
-## Power Management
-- CPU governor and scaling settings for %s
-- Battery optimization and charging thresholds
-- Display backlight and brightness control
-- WiFi and Bluetooth power management
-
-## Thermal Management
-- CPU thermal throttling configuration
-- Fan curve optimization
-- Thermal monitoring and alerts
-- Heat dissipation improvements
-
-## Display Configuration
-- HiDPI scaling and font settings
-- External monitor configuration
-- Brightness control and auto-adjustment
-- Color profile management
-
-## Suspend/Hibernation
-- Suspend-to-RAM configuration
-- Hibernation setup and swap requirements
-- Wake-on-LAN and USB wake settings
-- Fast boot and startup optimization
-
-## Hardware Features
-- Touchpad and trackpoint configuration
-- Function key mapping and special keys
-- Audio optimization for laptop speakers
-- Webcam and microphone setup
-
-## Docking and Connectivity
-- USB-C and Thunderbolt docking stations
-- External monitor auto-detection
-- Network switching (WiFi to Ethernet)
-- Audio output switching
-
-Provide actual NixOS configuration snippets optimized for %s mode.`, mode, mode, mode)
-
-		laptop, err := aiProvider.Query(prompt)
-		if err != nil {
-			fmt.Println(utils.FormatError("Could not get laptop optimization recommendations: " + err.Error()))
+		if err := runHardwareLaptop(out, "/", mode, release, outputPath, writeConfirm(cmd)); err != nil {
+			fmt.Fprintln(out, utils.FormatError(err.Error()))
 			return
 		}
 
-		fmt.Println(utils.FormatSubsection("⚙️ Laptop Configuration", ""))
-		fmt.Println(utils.RenderMarkdown(laptop))
-
-		fmt.Println()
-		fmt.Println(utils.FormatTip("Test power settings with 'powertop' and 'tlp-stat'"))
-		fmt.Println(utils.FormatTip("Monitor temperatures with 'sensors' and 'htop'"))
-		fmt.Println(utils.FormatNote("Reboot after applying power management changes"))
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, utils.FormatTip("Test power settings with 'powertop' and 'tlp-stat'"))
+		fmt.Fprintln(out, utils.FormatTip("Monitor temperatures with 'sensors' and 'htop'"))
+		fmt.Fprintln(out, utils.FormatNote("Reboot after applying power management changes"))
 	},
 }
 
@@ -635,10 +599,15 @@ func init() {
 	hardwareCmd.AddCommand(hardwareFunctionCmd)
 
 	// Add flags for hardware commands
+	hardwareDetectCmd.Flags().String("hardware-config", "", "hardware-configuration.nix to cross-reference (default: the detected one)")
 	hardwareOptimizeCmd.Flags().Bool("dry-run", false, "Show optimization recommendations without applying changes")
 	hardwareDriversCmd.Flags().Bool("auto-install", false, "Provide installation commands for recommended drivers")
+	hardwareCompareCmd.Flags().String("profile", hardwareProfileBalanced, "Tuning profile for recommendations (balanced, performance, power-save)")
+	hardwareCompareCmd.Flags().Bool("patch", false, "Print the differing settings as a patch creating hardware-recommendations.nix")
 	hardwareLaptopCmd.Flags().Bool("power-save", false, "Optimize for maximum battery life")
 	hardwareLaptopCmd.Flags().Bool("performance", false, "Optimize for maximum performance")
+	hardwareLaptopCmd.Flags().StringP("output", "o", "", "Write the generated module to this file")
+	addYesFlag(hardwareLaptopCmd)
 	hardwareFunctionCmd.Flags().String("operation", "", "Specify the hardware operation to perform")
 	hardwareFunctionCmd.Flags().String("component", "", "Specify the hardware component for the operation")
 	hardwareFunctionCmd.Flags().String("format", "", "Specify the output format for the operation")
@@ -793,7 +762,7 @@ func detectHardwareComponents() (*HardwareInfo, error) {
 // runCommand executes a shell command and returns its output
 func runCommand(command string) (string, error) {
 	cmd := exec.Command("sh", "-c", command)
-	output, err := cmd.Output()
+	output, err := utils.TraceOutput(cmd)
 	if err != nil {
 		return "", err
 	}
@@ -827,7 +796,7 @@ func runCommandWithSudo(command string) (string, error) {
 	}
 
 	cmd := exec.Command("sudo", "sh", "-c", command)
-	output, err := cmd.Output()
+	output, err := utils.TraceOutput(cmd)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,266 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"nix-ai-help/pkg/utils"
+)
+
+// Tuning profiles of hardware compare --profile
+const (
+	hardwareProfileBalanced    = "balanced"
+	hardwareProfilePerformance = "performance"
+	hardwareProfilePowerSave   = "power-save"
+)
+
+// Comparison states of a recommended setting
+const (
+	settingOK      = "ok"
+	settingDiffers = "differs"
+	settingMissing = "missing"
+)
+
+// hardwarePatchFile is the module hardware compare --patch creates
+const hardwarePatchFile = "hardware-recommendations.nix"
+
+// hardwareRecommendation is a NixOS setting recommended for detected hardware
+type hardwareRecommendation struct {
+	Component string
+	Option    string
+	Value     string
+	Rationale string
+}
+
+// hardwareComparison is a recommendation next to the value the configuration sets
+type hardwareComparison struct {
+	hardwareRecommendation
+	Current string
+	Status  string
+}
+
+// nixPriorityWrappers are functions around option values that do not change the value itself
+var nixPriorityWrappers = []string{"lib.mkDefault", "lib.mkForce", "mkDefault", "mkForce"}
+
+// recommendHardwareSettings returns the settings recommended for the detected hardware and profile
+func recommendHardwareSettings(info *HardwareInfo, profile string) []hardwareRecommendation {
+	var recs []hardwareRecommendation
+	add := func(component, option, value, rationale string) {
+		recs = append(recs, hardwareRecommendation{Component: component, Option: option, Value: value, Rationale: rationale})
+	}
+	virtualized := strings.Contains(info.Virtualization, "Running in:")
+
+	cpu := strings.ToLower(info.CPU)
+	switch {
+	case strings.Contains(cpu, "intel"):
+		add("CPU", "hardware.cpu.intel.updateMicrocode", "true", "Microcode updates fix CPU errata and security issues")
+	case strings.Contains(cpu, "amd"):
+		add("CPU", "hardware.cpu.amd.updateMicrocode", "true", "Microcode updates fix CPU errata and security issues")
+	}
+
+	if !virtualized {
+		add("Firmware", "hardware.enableRedistributableFirmware", "true", "Loads firmware blobs needed by Wi-Fi, GPU and other devices")
+	}
+
+	if len(info.GPU) > 0 {
+		gpu := strings.ToLower(strings.Join(info.GPU, "\n"))
+		add("GPU", "hardware.graphics.enable", "true", "Enables OpenGL/Vulkan and hardware video acceleration")
+		switch {
+		case strings.Contains(gpu, "nvidia"):
+			add("GPU", "services.xserver.videoDrivers", `[ "nvidia" ]`, "Uses the proprietary driver for the detected NVIDIA GPU")
+			add("GPU", "hardware.nvidia.modesetting.enable", "true", "Kernel modesetting is required for Wayland and flicker-free boot")
+		case strings.Contains(gpu, "amd") || strings.Contains(gpu, "radeon"):
+			add("GPU", "services.xserver.videoDrivers", `[ "amdgpu" ]`, "Uses the open amdgpu driver for the detected AMD GPU")
+		}
+	}
+
+	for _, disk := range info.Storage {
+		if strings.HasPrefix(strings.TrimSpace(disk), "nvme") {
+			add("Storage", "services.fstrim.enable", "true", "Periodic TRIM keeps SSD write performance up")
+			break
+		}
+	}
+
+	virt := strings.ToLower(info.Virtualization)
+	switch {
+	case strings.Contains(virt, "running in: kvm"), strings.Contains(virt, "running in: qemu"):
+		add("Virtualization", "services.qemuGuest.enable", "true", "The guest agent lets the host shut down and snapshot the VM cleanly")
+	case strings.Contains(virt, "running in: oracle"):
+		add("Virtualization", "virtualisation.virtualbox.guest.enable", "true", "Guest additions provide shared folders and display resizing")
+	case strings.Contains(virt, "running in: vmware"):
+		add("Virtualization", "virtualisation.vmware.guest.enable", "true", "VMware tools provide clean shutdown and display resizing")
+	}
+
+	switch profile {
+	case hardwareProfilePerformance:
+		add("Power", "powerManagement.cpuFreqGovernor", `"performance"`, "Keeps the CPU at high clock speeds (performance profile)")
+	case hardwareProfilePowerSave:
+		add("Power", "powerManagement.cpuFreqGovernor", `"powersave"`, "Lowers clock speeds to save energy (power-save profile)")
+		if !virtualized {
+			add("Power", "services.tlp.enable", "true", "TLP tunes disks, USB and Wi-Fi for battery life (power-save profile)")
+		}
+	}
+	return recs
+}
+
+// compareHardwareSettings pairs each recommendation with the value the configuration sets
+func compareHardwareSettings(recs []hardwareRecommendation, current map[string]string) []hardwareComparison {
+	comparisons := make([]hardwareComparison, 0, len(recs))
+	for _, rec := range recs {
+		comparison := hardwareComparison{hardwareRecommendation: rec, Status: settingMissing}
+		if value, ok := current[rec.Option]; ok {
+			comparison.Current = value
+			comparison.Status = settingDiffers
+			if nixValuesMatch(value, rec.Value) {
+				comparison.Status = settingOK
+			}
+		}
+		comparisons = append(comparisons, comparison)
+	}
+	return comparisons
+}
+
+// nixValuesMatch reports whether a configured value satisfies a recommended one. Priority
+// wrappers such as lib.mkDefault are ignored, and a list matches when it contains every
+// recommended element.
+func nixValuesMatch(current, recommended string) bool {
+	current = stripNixPriority(current)
+	if !strings.HasPrefix(recommended, "[") {
+		return current == recommended
+	}
+	elements := strings.Fields(current)
+	for _, want := range strings.Fields(strings.Trim(recommended, "[ ]")) {
+		if !containsString(elements, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// stripNixPriority removes a priority wrapper such as lib.mkDefault from a value
+func stripNixPriority(value string) string {
+	for _, wrapper := range nixPriorityWrappers {
+		if strings.HasPrefix(value, wrapper+" ") {
+			return strings.TrimPrefix(value, wrapper+" ")
+		}
+	}
+	return value
+}
+
+// readNixConfigValues collects the option values set in the .nix files under dir, visiting
+// files in lexical order so that later files override earlier ones
+func readNixConfigValues(dir string) (map[string]string, error) {
+	values := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".nix" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for option, value := range extractNixOptionValues(string(data)) {
+			values[option] = value
+		}
+		return nil
+	})
+	return values, err
+}
+
+// renderHardwareComparison prints the comparison as a table of current and recommended values
+func renderHardwareComparison(out io.Writer, comparisons []hardwareComparison) {
+	icons := map[string]string{settingOK: "✅ ok", settingDiffers: "⚠️ differs", settingMissing: "➕ missing"}
+	rows := make([][]string, 0, len(comparisons))
+	changes := 0
+	for _, c := range comparisons {
+		current := c.Current
+		if c.Status == settingMissing {
+			current = "(not set)"
+		}
+		if c.Status != settingOK {
+			changes++
+		}
+		rows = append(rows, []string{icons[c.Status], c.Component, c.Option, current, c.Value, c.Rationale})
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatTable([]string{"Status", "Component", "Option", "Current", "Recommended", "Rationale"}, rows))
+	_, _ = fmt.Fprintln(out)
+	if changes == 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatSuccess("Your configuration matches every recommendation"))
+		return
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatInfo(fmt.Sprintf("%d of %d settings differ from the recommendations", changes, len(comparisons))))
+	_, _ = fmt.Fprintln(out, utils.FormatTip("Use --patch to get them as a module to add to your imports"))
+}
+
+// hardwareRecommendationsPatch returns a patch creating a module with the settings that differ
+// from the recommendations. Values the configuration sets differently are forced, since the
+// module would otherwise conflict with them.
+func hardwareRecommendationsPatch(comparisons []hardwareComparison) string {
+	var changes []hardwareComparison
+	for _, c := range comparisons {
+		if c.Status != settingOK {
+			changes = append(changes, c)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Option < changes[j].Option })
+
+	lines := []string{
+		"# Hardware recommendations from 'nixai hardware compare'; add ./" + hardwarePatchFile + " to imports",
+		"{ lib, ... }:",
+		"{",
+	}
+	for _, c := range changes {
+		value := c.Value
+		if c.Status == settingDiffers {
+			value = "lib.mkForce " + value
+		}
+		lines = append(lines, "  # "+c.Rationale, "  "+c.Option+" = "+value+";")
+	}
+	lines = append(lines, "}")
+
+	var patch strings.Builder
+	patch.WriteString("--- /dev/null\n")
+	patch.WriteString("+++ b/" + hardwarePatchFile + "\n")
+	patch.WriteString(fmt.Sprintf("@@ -0,0 +1,%d @@\n", len(lines)))
+	for _, line := range lines {
+		patch.WriteString("+" + line + "\n")
+	}
+	return patch.String()
+}
+
+// runHardwareCompare compares the configuration in configDir with the recommendations for
+// the detected hardware, printing a table or, with patch set, only the patch
+func runHardwareCompare(out io.Writer, info *HardwareInfo, configDir, profile string, patch bool) error {
+	current, err := readNixConfigValues(configDir)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration in %s: %w", configDir, err)
+	}
+	comparisons := compareHardwareSettings(recommendHardwareSettings(info, profile), current)
+
+	if patch {
+		_, err := fmt.Fprint(out, hardwareRecommendationsPatch(comparisons))
+		return err
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Configuration", configDir))
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Profile", profile))
+	_, _ = fmt.Fprintln(out)
+	renderHardwareComparison(out, comparisons)
+	return nil
+}
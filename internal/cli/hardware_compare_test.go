@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const hardwareFixtureConfig = `{ config, lib, pkgs, ... }:
+{
+  hardware.cpu.intel.updateMicrocode = lib.mkDefault true;
+  hardware.graphics.enable = true;
+  services.xserver.videoDrivers = [ "modesetting" ];
+  hardware.nvidia = {
+    modesetting.enable = false;
+    open = false;
+  };
+  powerManagement.cpuFreqGovernor = "ondemand";
+}
+`
+
+// hardwareFixture is a laptop with an Intel CPU, an NVIDIA GPU and an NVMe disk
+var hardwareFixture = &HardwareInfo{
+	CPU: "Intel(R) Core(TM) i7-10750H CPU @ 2.60GHz",
+	GPU: []string{
+		"00:02.0 VGA compatible controller: Intel Corporation CometLake-H GT2 [UHD Graphics]",
+		"01:00.0 3D controller: NVIDIA Corporation TU117M [GeForce GTX 1650 Mobile]",
+	},
+	Storage:        []string{"nvme0n1 476.9G disk"},
+	Virtualization: "CPU Features: Virtualization: VT-x",
+}
+
+func TestExtractNixOptionValues(t *testing.T) {
+	values := extractNixOptionValues(hardwareFixtureConfig)
+	want := map[string]string{
+		"hardware.cpu.intel.updateMicrocode": "lib.mkDefault true",
+		"services.xserver.videoDrivers":      `[ "modesetting" ]`,
+		"hardware.nvidia.modesetting.enable": "false",
+		"powerManagement.cpuFreqGovernor":    `"ondemand"`,
+	}
+	for option, value := range want {
+		if values[option] != value {
+			t.Errorf("%s = %q, want %q", option, values[option], value)
+		}
+	}
+}
+
+func TestCompareHardwareSettings(t *testing.T) {
+	current := extractNixOptionValues(hardwareFixtureConfig)
+	comparisons := compareHardwareSettings(recommendHardwareSettings(hardwareFixture, hardwareProfilePowerSave), current)
+
+	want := map[string]string{
+		"hardware.cpu.intel.updateMicrocode":     settingOK,
+		"hardware.enableRedistributableFirmware": settingMissing,
+		"hardware.graphics.enable":               settingOK,
+		"services.xserver.videoDrivers":          settingDiffers,
+		"hardware.nvidia.modesetting.enable":     settingDiffers,
+		"services.fstrim.enable":                 settingMissing,
+		"powerManagement.cpuFreqGovernor":        settingDiffers,
+		"services.tlp.enable":                    settingMissing,
+	}
+	if len(comparisons) != len(want) {
+		t.Fatalf("got %d comparisons, want %d: %+v", len(comparisons), len(want), comparisons)
+	}
+	for _, c := range comparisons {
+		if c.Status != want[c.Option] {
+			t.Errorf("%s: status %q, want %q (current %q, recommended %q)", c.Option, c.Status, want[c.Option], c.Current, c.Value)
+		}
+		if c.Rationale == "" {
+			t.Errorf("%s has no rationale", c.Option)
+		}
+	}
+}
+
+func TestRecommendHardwareSettingsVirtualMachine(t *testing.T) {
+	info := &HardwareInfo{CPU: "AMD EPYC 7B13", Virtualization: "Running in: kvm"}
+	options := map[string]bool{}
+	for _, rec := range recommendHardwareSettings(info, hardwareProfileBalanced) {
+		options[rec.Option] = true
+	}
+	if !options["hardware.cpu.amd.updateMicrocode"] || !options["services.qemuGuest.enable"] {
+		t.Errorf("missing AMD microcode or QEMU guest recommendation: %v", options)
+	}
+	if options["hardware.enableRedistributableFirmware"] || options["powerManagement.cpuFreqGovernor"] {
+		t.Errorf("unexpected firmware or governor recommendation for a balanced VM: %v", options)
+	}
+}
+
+func TestNixValuesMatchList(t *testing.T) {
+	if !nixValuesMatch(`[ "nvidia" "modesetting" ]`, `[ "nvidia" ]`) {
+		t.Error("a list containing the recommended driver should match")
+	}
+	if nixValuesMatch(`[ "modesetting" ]`, `[ "nvidia" ]`) {
+		t.Error("a list without the recommended driver should not match")
+	}
+	if !nixValuesMatch("lib.mkForce true", "true") {
+		t.Error("priority wrappers should be ignored")
+	}
+}
+
+func TestRunHardwareComparePatch(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "configuration.nix"), []byte(hardwareFixtureConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runHardwareCompare(&out, hardwareFixture, dir, hardwareProfileBalanced, true); err != nil {
+		t.Fatal(err)
+	}
+	patch := out.String()
+	for _, want := range []string{
+		"--- /dev/null\n+++ b/hardware-recommendations.nix\n@@ -0,0 +1,12 @@\n",
+		"+  hardware.enableRedistributableFirmware = true;\n",
+		"+  hardware.nvidia.modesetting.enable = lib.mkForce true;\n",
+		"+  services.xserver.videoDrivers = lib.mkForce [ \"nvidia\" ];\n",
+		"+  services.fstrim.enable = true;\n",
+	} {
+		if !strings.Contains(patch, want) {
+			t.Errorf("patch missing %q:\n%s", want, patch)
+		}
+	}
+	if strings.Contains(patch, "updateMicrocode") || strings.Contains(patch, "cpuFreqGovernor") {
+		t.Errorf("patch should only contain differing settings:\n%s", patch)
+	}
+
+	out.Reset()
+	if err := runHardwareCompare(&out, hardwareFixture, dir, hardwareProfileBalanced, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"hardware.nvidia.modesetting.enable", "(not set)", "4 of 6 settings differ"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("table missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/utils"
+)
+
+// defaultHardwareConfig is where nixos-generate-config writes the hardware configuration
+const defaultHardwareConfig = "/etc/nixos/hardware-configuration.nix"
+
+// hardwareKernelModuleOptions are the options nixos-generate-config fills with kernel modules
+var hardwareKernelModuleOptions = []string{
+	"boot.initrd.availableKernelModules",
+	"boot.initrd.kernelModules",
+	"boot.kernelModules",
+}
+
+// fileSystemOptionPattern matches fileSystems."/".device and fileSystems."/".fsType
+var fileSystemOptionPattern = regexp.MustCompile(`^fileSystems\.(?:"([^"]*)"|([^.]+))\.(device|fsType)$`)
+
+// nixStringPattern matches the double-quoted strings of a Nix value
+var nixStringPattern = regexp.MustCompile(`"([^"]*)"`)
+
+// hardwareFileSystem is a file system declared in hardware-configuration.nix
+type hardwareFileSystem struct {
+	MountPoint string
+	Device     string
+	FSType     string
+}
+
+// hardwareConfig is what nixos-generate-config recorded in hardware-configuration.nix
+type hardwareConfig struct {
+	Path          string
+	Values        map[string]string
+	KernelModules []string
+	FileSystems   []hardwareFileSystem
+}
+
+// nixStrings returns the strings of a Nix value such as [ "nvme" "xhci_pci" ]
+func nixStrings(value string) []string {
+	var items []string
+	for _, match := range nixStringPattern.FindAllStringSubmatch(value, -1) {
+		items = append(items, match[1])
+	}
+	return items
+}
+
+// parseHardwareConfig extracts the kernel modules, file systems and other options of a
+// hardware-configuration.nix
+func parseHardwareConfig(path, src string) *hardwareConfig {
+	hw := &hardwareConfig{Path: path, Values: extractNixOptionValues(src)}
+
+	for _, option := range hardwareKernelModuleOptions {
+		for _, module := range nixStrings(hw.Values[option]) {
+			if !containsString(hw.KernelModules, module) {
+				hw.KernelModules = append(hw.KernelModules, module)
+			}
+		}
+	}
+
+	fileSystems := make(map[string]*hardwareFileSystem)
+	for option, value := range hw.Values {
+		match := fileSystemOptionPattern.FindStringSubmatch(option)
+		if match == nil {
+			continue
+		}
+		mountPoint := match[1] + match[2]
+		fs := fileSystems[mountPoint]
+		if fs == nil {
+			fs = &hardwareFileSystem{MountPoint: mountPoint}
+			fileSystems[mountPoint] = fs
+		}
+		value = strings.Trim(stripNixPriority(value), `"`)
+		if match[3] == "device" {
+			fs.Device = value
+		} else {
+			fs.FSType = value
+		}
+	}
+	for _, fs := range fileSystems {
+		hw.FileSystems = append(hw.FileSystems, *fs)
+	}
+	sort.Slice(hw.FileSystems, func(i, j int) bool { return hw.FileSystems[i].MountPoint < hw.FileSystems[j].MountPoint })
+	return hw
+}
+
+// hardwareConfigPath returns the hardware-configuration.nix to read: the given path, the one
+// found by context detection, or the default location
+func hardwareConfigPath(path string, nixosCtx *config.NixOSContext) string {
+	if path != "" {
+		return utils.ExpandHome(path)
+	}
+	if nixosCtx != nil && nixosCtx.HardwareConfigNix != "" {
+		return nixosCtx.HardwareConfigNix
+	}
+	return defaultHardwareConfig
+}
+
+// loadHardwareConfig reads and parses a hardware-configuration.nix
+func loadHardwareConfig(path string) (*hardwareConfig, error) {
+	// #nosec G304 -- path is the user's own hardware configuration
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseHardwareConfig(path, string(data)), nil
+}
+
+// crossReferenceHardware compares live detection with hardware-configuration.nix and returns a
+// warning for each disagreement. deviceExists reports whether a file system device is present.
+func crossReferenceHardware(info *HardwareInfo, hw *hardwareConfig, deviceExists func(string) bool) []string {
+	var warnings []string
+	cpu := strings.ToLower(info.CPU)
+	for vendor, other := range map[string]string{"intel": "amd", "amd": "intel"} {
+		if hw.Values["hardware.cpu."+other+".updateMicrocode"] != "" && strings.Contains(cpu, vendor) && !strings.Contains(cpu, other) {
+			warnings = append(warnings, fmt.Sprintf("The file enables %s microcode updates, but the detected CPU is %s", strings.ToUpper(other), info.CPU))
+		}
+	}
+
+	if platform := strings.Trim(stripNixPriority(hw.Values["nixpkgs.hostPlatform"]), `"`); platform != "" && info.Architecture != "" &&
+		!strings.HasPrefix(platform, info.Architecture+"-") {
+		warnings = append(warnings, fmt.Sprintf("The file targets %s, but this machine is %s", platform, info.Architecture))
+	}
+
+	qemuProfile := strings.Contains(hw.Values["imports"], "qemu-guest.nix")
+	virt := strings.ToLower(info.Virtualization)
+	runningInQemu := strings.Contains(virt, "running in: kvm") || strings.Contains(virt, "running in: qemu")
+	switch {
+	case qemuProfile && !strings.Contains(virt, "running in:"):
+		warnings = append(warnings, "The file imports the QEMU guest profile, but no virtual machine was detected")
+	case runningInQemu && !qemuProfile:
+		warnings = append(warnings, "This machine runs in a QEMU/KVM virtual machine, but the file does not import the QEMU guest profile")
+	}
+
+	for _, disk := range info.Storage {
+		if strings.HasPrefix(strings.TrimSpace(disk), "nvme") && !containsString(hw.KernelModules, "nvme") {
+			warnings = append(warnings, "An NVMe disk was detected, but the nvme module is not in the file's initrd modules")
+			break
+		}
+	}
+
+	for _, fs := range hw.FileSystems {
+		if strings.HasPrefix(fs.Device, "/dev/") && !deviceExists(fs.Device) {
+			warnings = append(warnings, fmt.Sprintf("The device %s of the %s file system was not found on this machine", fs.Device, fs.MountPoint))
+		}
+	}
+	return warnings
+}
+
+// deviceExists reports whether a block device path exists on this machine
+func deviceExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// hardwareConfigPromptContext tells the AI what hardware-configuration.nix already sets, so that
+// it builds on those settings instead of suggesting them again
+func hardwareConfigPromptContext(hw *hardwareConfig, warnings []string) string {
+	var b strings.Builder
+	b.WriteString("\n\n=== EXISTING hardware-configuration.nix ===\n")
+	b.WriteString("nixos-generate-config already set the following; do not suggest these settings again and do not suggest conflicting values:\n")
+	if len(hw.KernelModules) > 0 {
+		b.WriteString("- Kernel modules: " + strings.Join(hw.KernelModules, ", ") + "\n")
+	}
+	for _, fs := range hw.FileSystems {
+		b.WriteString(fmt.Sprintf("- File system %s: %s (%s)\n", fs.MountPoint, fs.Device, fs.FSType))
+	}
+	options := make([]string, 0, len(hw.Values))
+	for option := range hw.Values {
+		if option == "imports" || fileSystemOptionPattern.MatchString(option) || containsString(hardwareKernelModuleOptions, option) {
+			continue
+		}
+		options = append(options, option)
+	}
+	sort.Strings(options)
+	for _, option := range options {
+		b.WriteString(fmt.Sprintf("- %s = %s\n", option, hw.Values[option]))
+	}
+	if len(warnings) > 0 {
+		b.WriteString("Live detection disagrees with the file here; address these first:\n")
+		for _, warning := range warnings {
+			b.WriteString("- " + warning + "\n")
+		}
+	}
+	return b.String()
+}
+
+// renderHardwareConfig prints what hardware-configuration.nix sets and where live detection
+// disagrees with it
+func renderHardwareConfig(out io.Writer, hw *hardwareConfig, warnings []string) {
+	_, _ = fmt.Fprintln(out, utils.FormatSubsection("📄 hardware-configuration.nix", ""))
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("File", hw.Path))
+	if len(hw.KernelModules) > 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Kernel modules", strings.Join(hw.KernelModules, ", ")))
+	}
+	if len(hw.FileSystems) > 0 {
+		rows := make([][]string, 0, len(hw.FileSystems))
+		for _, fs := range hw.FileSystems {
+			rows = append(rows, []string{fs.MountPoint, fs.Device, fs.FSType})
+		}
+		_, _ = fmt.Fprintln(out, utils.FormatTable([]string{"Mount point", "Device", "Type"}, rows))
+	}
+	if len(warnings) == 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatSuccess("Live detection agrees with hardware-configuration.nix"))
+	}
+	for _, warning := range warnings {
+		_, _ = fmt.Fprintln(out, utils.FormatWarning(warning))
+	}
+	if len(warnings) > 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatTip("Regenerate it with 'sudo nixos-generate-config --show-hardware-config' if the hardware changed"))
+	}
+	_, _ = fmt.Fprintln(out)
+}
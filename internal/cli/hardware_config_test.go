@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// hardwareConfigFixture is a hardware-configuration.nix as written by nixos-generate-config
+const hardwareConfigFixture = `# Do not modify this file!  It was generated by 'nixos-generate-config'
+{ config, lib, pkgs, modulesPath, ... }:
+
+{
+  imports =
+    [ (modulesPath + "/installer/scan/not-detected.nix")
+    ];
+
+  boot.initrd.availableKernelModules = [ "xhci_pci" "ahci" "usb_storage" "sd_mod" ];
+  boot.initrd.kernelModules = [ ];
+  boot.kernelModules = [ "kvm-amd" ];
+  boot.extraModulePackages = [ ];
+
+  fileSystems."/" =
+    { device = "/dev/disk/by-uuid/0b1c-root";
+      fsType = "ext4";
+    };
+
+  fileSystems."/boot" =
+    { device = "/dev/disk/by-uuid/ABCD-1234";
+      fsType = "vfat";
+      options = [ "fmask=0077" "dmask=0077" ];
+    };
+
+  swapDevices = [ ];
+
+  networking.useDHCP = lib.mkDefault true;
+  nixpkgs.hostPlatform = lib.mkDefault "x86_64-linux";
+  hardware.cpu.amd.updateMicrocode = lib.mkDefault config.hardware.enableRedistributableFirmware;
+}
+`
+
+func TestParseHardwareConfig(t *testing.T) {
+	hw := parseHardwareConfig("/etc/nixos/hardware-configuration.nix", hardwareConfigFixture)
+
+	wantModules := []string{"xhci_pci", "ahci", "usb_storage", "sd_mod", "kvm-amd"}
+	if !reflect.DeepEqual(hw.KernelModules, wantModules) {
+		t.Errorf("KernelModules = %v, want %v", hw.KernelModules, wantModules)
+	}
+	wantFileSystems := []hardwareFileSystem{
+		{MountPoint: "/", Device: "/dev/disk/by-uuid/0b1c-root", FSType: "ext4"},
+		{MountPoint: "/boot", Device: "/dev/disk/by-uuid/ABCD-1234", FSType: "vfat"},
+	}
+	if !reflect.DeepEqual(hw.FileSystems, wantFileSystems) {
+		t.Errorf("FileSystems = %+v, want %+v", hw.FileSystems, wantFileSystems)
+	}
+}
+
+func TestCrossReferenceHardware(t *testing.T) {
+	hw := parseHardwareConfig("hardware-configuration.nix", hardwareConfigFixture)
+	present := func(string) bool { return true }
+
+	// The file matches a native AMD machine with SATA disks
+	matching := &HardwareInfo{
+		CPU:            "AMD Ryzen 7 5800X 8-Core Processor",
+		Storage:        []string{"sda 931.5G disk"},
+		Architecture:   "x86_64",
+		Virtualization: "CPU Features: Virtualization: AMD-V",
+	}
+	if warnings := crossReferenceHardware(matching, hw, present); len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+
+	// The same file on an Intel NVMe laptop running as a KVM guest with a reformatted boot disk
+	moved := &HardwareInfo{
+		CPU:            "Intel(R) Core(TM) i7-10750H CPU @ 2.60GHz",
+		Storage:        []string{"nvme0n1 476.9G disk"},
+		Architecture:   "aarch64",
+		Virtualization: "Running in: kvm",
+	}
+	missingBoot := func(device string) bool { return !strings.Contains(device, "ABCD-1234") }
+	warnings := strings.Join(crossReferenceHardware(moved, hw, missingBoot), "\n")
+	for _, want := range []string{
+		"enables AMD microcode updates",
+		"targets x86_64-linux, but this machine is aarch64",
+		"does not import the QEMU guest profile",
+		"nvme module is not in the file's initrd modules",
+		"/dev/disk/by-uuid/ABCD-1234 of the /boot file system was not found",
+	} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("warnings missing %q:\n%s", want, warnings)
+		}
+	}
+	if strings.Contains(warnings, "0b1c-root") {
+		t.Errorf("the present root device should not be reported:\n%s", warnings)
+	}
+}
+
+func TestHardwareConfigPromptContext(t *testing.T) {
+	hw := parseHardwareConfig("hardware-configuration.nix", hardwareConfigFixture)
+	prompt := hardwareConfigPromptContext(hw, []string{"An NVMe disk was detected"})
+	for _, want := range []string{
+		"do not suggest these settings again",
+		"Kernel modules: xhci_pci, ahci, usb_storage, sd_mod, kvm-amd",
+		"File system /boot: /dev/disk/by-uuid/ABCD-1234 (vfat)",
+		"networking.useDHCP = lib.mkDefault true",
+		"An NVMe disk was detected",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+
+	var out bytes.Buffer
+	renderHardwareConfig(&out, hw, nil)
+	if !strings.Contains(out.String(), "agrees with hardware-configuration.nix") || !strings.Contains(out.String(), "ABCD-1234") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,251 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"nix-ai-help/pkg/utils"
+)
+
+// laptopPowerFile is the module name suggested by hardware laptop
+const laptopPowerFile = "laptop-power.nix"
+
+// logindSettingsRelease is the first NixOS release configuring logind through
+// services.logind.settings, which replaced services.logind.lidSwitch and its siblings
+const logindSettingsRelease = "25.11"
+
+// errNixParserUnavailable reports that nix-instantiate is not installed
+var errNixParserUnavailable = errors.New("nix-instantiate not found")
+
+// parseNixExpression checks the syntax of a Nix expression with nix-instantiate --parse
+var parseNixExpression = func(expr string) error {
+	if _, err := exec.LookPath("nix-instantiate"); err != nil {
+		return errNixParserUnavailable
+	}
+	cmd := exec.Command("nix-instantiate", "--parse", "-")
+	cmd.Stdin = strings.NewReader(expr)
+	if output, err := utils.TraceCombinedOutput(cmd); err != nil {
+		return fmt.Errorf("%s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// laptopHardware is the power-related hardware of a laptop
+type laptopHardware struct {
+	Batteries []string
+	// ThresholdBatteries are the batteries that support charge control thresholds
+	ThresholdBatteries []string
+	Backlight          string
+	CPUVendor          string
+}
+
+// detectLaptopHardware reads batteries, backlight and CPU vendor below root, which is "/"
+// outside tests. Batteries are the power supplies of type Battery, whatever their name, e.g.
+// BAT1 or CMB0; those of wireless mice and keyboards, whose scope is Device, are skipped.
+func detectLaptopHardware(root string) laptopHardware {
+	var hw laptopHardware
+	supplies, _ := os.ReadDir(filepath.Join(root, "sys/class/power_supply"))
+	for _, entry := range supplies {
+		supply := filepath.Join(root, "sys/class/power_supply", entry.Name())
+		if readSysfsValue(filepath.Join(supply, "type")) != "Battery" || readSysfsValue(filepath.Join(supply, "scope")) == "Device" {
+			continue
+		}
+		hw.Batteries = append(hw.Batteries, entry.Name())
+		if _, err := os.Stat(filepath.Join(supply, "charge_control_end_threshold")); err == nil {
+			hw.ThresholdBatteries = append(hw.ThresholdBatteries, entry.Name())
+		}
+	}
+	if backlights, _ := filepath.Glob(filepath.Join(root, "sys/class/backlight/*")); len(backlights) > 0 {
+		hw.Backlight = filepath.Base(backlights[0])
+	}
+	if cpuinfo, err := os.ReadFile(filepath.Join(root, "proc/cpuinfo")); err == nil {
+		switch {
+		case strings.Contains(string(cpuinfo), "GenuineIntel"):
+			hw.CPUVendor = "intel"
+		case strings.Contains(string(cpuinfo), "AuthenticAMD"):
+			hw.CPUVendor = "amd"
+		}
+	}
+	return hw
+}
+
+// readSysfsValue returns the trimmed content of a sysfs attribute, or "" when it is missing
+func readSysfsValue(path string) string {
+	// #nosec G304 -- a fixed attribute below the sysfs root being inspected
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// hasLogindSettings reports whether the nixpkgs release, e.g. 25.05 or unstable, configures
+// logind through services.logind.settings. An unknown release gets the older options, which
+// later releases still accept as renamed options.
+func hasLogindSettings(release string) bool {
+	if release == "unstable" {
+		return true
+	}
+	return validNixpkgsRelease(release) && release >= logindSettingsRelease
+}
+
+// laptopPowerModule generates a NixOS module optimizing power use for the given mode. Power-save
+// uses TLP with powertop autotuning and charge thresholds; balanced and performance use
+// auto-cpufreq, which switches governors on its own when the charger is plugged in. The lid
+// switch options match the nixpkgs release, "" when it is unknown.
+func laptopPowerModule(hw laptopHardware, mode, release string) string {
+	var b strings.Builder
+	line := func(format string, args ...interface{}) {
+		_, _ = fmt.Fprintf(&b, format+"\n", args...)
+	}
+
+	line("# Laptop power management (%s) generated by 'nixai hardware laptop'", mode)
+	line("# Add ./%s to imports and run 'nixos-rebuild test' before switching", laptopPowerFile)
+	line("{ config, lib, pkgs, ... }:")
+	line("{")
+	line("  # TLP and auto-cpufreq conflict with power-profiles-daemon")
+	line("  services.power-profiles-daemon.enable = false;")
+	line("")
+
+	if mode == hardwareProfilePowerSave {
+		line("  services.tlp = {")
+		line("    enable = true;")
+		line("    settings = {")
+		line("      CPU_SCALING_GOVERNOR_ON_AC = \"schedutil\";")
+		line("      CPU_SCALING_GOVERNOR_ON_BAT = \"powersave\";")
+		line("      CPU_ENERGY_PERF_POLICY_ON_AC = \"balance_performance\";")
+		line("      CPU_ENERGY_PERF_POLICY_ON_BAT = \"power\";")
+		line("      CPU_BOOST_ON_BAT = 0;")
+		line("      WIFI_PWR_ON_BAT = \"on\";")
+		line("      RUNTIME_PM_ON_BAT = \"auto\";")
+		if len(hw.ThresholdBatteries) > 0 {
+			line("      # Charging between 40%% and 80%% extends battery lifespan")
+			for _, battery := range hw.ThresholdBatteries {
+				line("      START_CHARGE_THRESH_%s = 40;", battery)
+				line("      STOP_CHARGE_THRESH_%s = 80;", battery)
+			}
+		}
+		line("    };")
+		line("  };")
+		line("")
+		line("  # Apply powertop's tunables at boot; check the result with 'sudo powertop'")
+		line("  powerManagement.powertop.enable = true;")
+	} else {
+		chargerTurbo := "auto"
+		if mode == hardwareProfilePerformance {
+			chargerTurbo = "always"
+		}
+		line("  services.auto-cpufreq = {")
+		line("    enable = true;")
+		line("    settings = {")
+		line("      battery = {")
+		line("        governor = \"powersave\";")
+		line("        turbo = \"auto\";")
+		line("      };")
+		line("      charger = {")
+		line("        governor = \"performance\";")
+		line("        turbo = \"%s\";", chargerTurbo)
+		line("      };")
+		line("    };")
+		line("  };")
+		line("")
+		line("  # powertop autotune saves more power but can make USB input devices sleep;")
+		line("  # enable it with powerManagement.powertop.enable = true if that is no issue")
+	}
+	line("")
+
+	switch hw.CPUVendor {
+	case "intel":
+		line("  # Keeps Intel CPUs from overheating before the fans catch up")
+		line("  services.thermald.enable = true;")
+		line("")
+	case "amd":
+		line("  # The amd-pstate driver lets the CPU pick efficient frequencies")
+		line("  boot.kernelParams = [ \"amd_pstate=active\" ];")
+		line("")
+	}
+
+	if hw.Backlight != "" {
+		line("  # Backlight control (%s) for brightness keys; add users to the video group", hw.Backlight)
+		line("  programs.light.enable = true;")
+		line("")
+	}
+
+	externalPower := "suspend"
+	if mode == hardwareProfilePerformance {
+		externalPower = "lock"
+	}
+	line("  # Suspend when the lid closes, unless an external display is connected")
+	if hasLogindSettings(release) {
+		line("  services.logind.settings.Login = {")
+		line("    HandleLidSwitch = \"suspend\";")
+		line("    HandleLidSwitchExternalPower = \"%s\";", externalPower)
+		line("    HandleLidSwitchDocked = \"ignore\";")
+		line("  };")
+	} else {
+		line("  services.logind = {")
+		line("    lidSwitch = \"suspend\";")
+		line("    lidSwitchExternalPower = \"%s\";", externalPower)
+		line("    lidSwitchDocked = \"ignore\";")
+		line("  };")
+	}
+	line("  powerManagement.enable = true;")
+	line("}")
+	return b.String()
+}
+
+// runHardwareLaptop detects the laptop hardware below root, generates its power module for mode
+// and the nixpkgs release and validates it. The module is written to outputPath, overwriting an existing file only as
+// confirm allows, or printed when outputPath is empty.
+func runHardwareLaptop(out io.Writer, root, mode, release, outputPath string, confirm utils.WriteConfirm) error {
+	hw := detectLaptopHardware(root)
+	if len(hw.Batteries) == 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatWarning("No battery detected; the module is meant for laptops"))
+	} else {
+		_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Batteries", strings.Join(hw.Batteries, ", ")))
+		thresholds := "unsupported"
+		if len(hw.ThresholdBatteries) > 0 {
+			thresholds = strings.Join(hw.ThresholdBatteries, ", ")
+		}
+		_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Charge thresholds", thresholds))
+	}
+	if hw.Backlight != "" {
+		_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Backlight", hw.Backlight))
+	}
+	if hw.CPUVendor != "" {
+		_, _ = fmt.Fprintln(out, utils.FormatKeyValue("CPU vendor", hw.CPUVendor))
+	}
+	if release != "" {
+		_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Nixpkgs release", release))
+	}
+	_, _ = fmt.Fprintln(out)
+
+	module := laptopPowerModule(hw, mode, release)
+	switch err := parseNixExpression(module); {
+	case errors.Is(err, errNixParserUnavailable):
+		_, _ = fmt.Fprintln(out, utils.FormatWarning("nix-instantiate not found; skipped the syntax check"))
+	case err != nil:
+		return fmt.Errorf("generated module failed to parse: %w", err)
+	default:
+		_, _ = fmt.Fprintln(out, utils.FormatSuccess("Module parses with nix-instantiate --parse"))
+	}
+
+	if outputPath == "" {
+		_, _ = fmt.Fprintln(out)
+		_, _ = fmt.Fprint(out, module)
+		_, _ = fmt.Fprintln(out)
+		_, _ = fmt.Fprintln(out, utils.FormatTip("Save it with --output "+laptopPowerFile+" and add it to your imports"))
+		return nil
+	}
+	if err := utils.WriteFileWithConfirm(outputPath, []byte(module), 0o644, confirm); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatSuccess("Module written to "+outputPath))
+	_, _ = fmt.Fprintln(out, utils.FormatTip("Add it to your imports: imports = [ ./"+filepath.Base(outputPath)+" ];"))
+	return nil
+}
@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"nix-ai-help/pkg/utils"
+)
+
+// writeLaptopFixture creates a sysfs and procfs tree of an Intel laptop with two batteries, one
+// of them with charge thresholds, and a wireless mouse
+func writeLaptopFixture(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"sys/class/power_supply/BAT1/type":                         "Battery\n",
+		"sys/class/power_supply/BAT1/charge_control_end_threshold": "100\n",
+		"sys/class/power_supply/CMB0/type":                         "Battery\n",
+		"sys/class/power_supply/AC/type":                           "Mains\n",
+		"sys/class/power_supply/AC/online":                         "1\n",
+		"sys/class/power_supply/hidpp_battery_0/type":              "Battery\n",
+		"sys/class/power_supply/hidpp_battery_0/scope":             "Device\n",
+		"sys/class/backlight/intel_backlight/brightness":           "400\n",
+		"proc/cpuinfo": "processor\t: 0\nvendor_id\t: GenuineIntel\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// balancedNix reports whether the braces, brackets and quotes of a Nix expression are balanced
+func balancedNix(expr string) bool {
+	depth := map[rune]int{}
+	for _, line := range strings.Split(expr, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if strings.Count(line, `"`)%2 != 0 {
+			return false
+		}
+		for _, r := range line {
+			switch r {
+			case '{', '[':
+				depth[r]++
+			case '}':
+				depth['{']--
+			case ']':
+				depth['[']--
+			}
+		}
+	}
+	return depth['{'] == 0 && depth['['] == 0
+}
+
+func TestDetectLaptopHardware(t *testing.T) {
+	hw := detectLaptopHardware(writeLaptopFixture(t))
+	if !reflect.DeepEqual(hw.Batteries, []string{"BAT1", "CMB0"}) {
+		t.Errorf("batteries = %v, want [BAT1 CMB0]", hw.Batteries)
+	}
+	if !reflect.DeepEqual(hw.ThresholdBatteries, []string{"BAT1"}) || hw.Backlight != "intel_backlight" || hw.CPUVendor != "intel" {
+		t.Errorf("unexpected detection: %+v", hw)
+	}
+}
+
+func TestLaptopPowerModule(t *testing.T) {
+	hw := laptopHardware{Batteries: []string{"BAT1"}, ThresholdBatteries: []string{"BAT1"}, Backlight: "amdgpu_bl0", CPUVendor: "amd"}
+	tests := []struct {
+		mode    string
+		want    []string
+		notWant []string
+	}{
+		{hardwareProfilePowerSave,
+			[]string{"services.tlp = {", "STOP_CHARGE_THRESH_BAT1 = 80;", "powerManagement.powertop.enable = true;"},
+			[]string{"services.auto-cpufreq"}},
+		{hardwareProfileBalanced,
+			[]string{"services.auto-cpufreq = {", `turbo = "auto";`},
+			[]string{"services.tlp", `turbo = "always";`}},
+		{hardwareProfilePerformance,
+			[]string{"services.auto-cpufreq = {", `turbo = "always";`, `lidSwitchExternalPower = "lock";`},
+			[]string{"services.tlp"}},
+	}
+	for _, tt := range tests {
+		module := laptopPowerModule(hw, tt.mode, "")
+		common := []string{
+			"services.power-profiles-daemon.enable = false;",
+			`boot.kernelParams = [ "amd_pstate=active" ];`,
+			"programs.light.enable = true;",
+			`lidSwitch = "suspend";`,
+			"powerManagement.enable = true;",
+		}
+		for _, want := range append(common, tt.want...) {
+			if !strings.Contains(module, want) {
+				t.Errorf("%s module missing %q:\n%s", tt.mode, want, module)
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(module, notWant) {
+				t.Errorf("%s module should not contain %q", tt.mode, notWant)
+			}
+		}
+		if !balancedNix(module) {
+			t.Errorf("%s module is not balanced:\n%s", tt.mode, module)
+		}
+		if err := parseNixExpression(module); err != nil && !errors.Is(err, errNixParserUnavailable) {
+			t.Errorf("%s module does not parse: %v", tt.mode, err)
+		}
+	}
+}
+
+func TestLaptopPowerModuleLidSwitchByRelease(t *testing.T) {
+	tests := []struct {
+		release string
+		want    string
+	}{
+		{"", `lidSwitchExternalPower = "lock";`},
+		{"25.05", `lidSwitchExternalPower = "lock";`},
+		{"25.11", `HandleLidSwitchExternalPower = "lock";`},
+		{"26.05", `HandleLidSwitchExternalPower = "lock";`},
+		{"unstable", `HandleLidSwitchExternalPower = "lock";`},
+	}
+	for _, tt := range tests {
+		module := laptopPowerModule(laptopHardware{}, hardwareProfilePerformance, tt.release)
+		if !strings.Contains(module, tt.want) {
+			t.Errorf("release %q: module missing %q:\n%s", tt.release, tt.want, module)
+		}
+		settings := strings.Contains(module, "services.logind.settings.Login = {")
+		if settings != strings.HasPrefix(tt.want, "Handle") {
+			t.Errorf("release %q: services.logind.settings used = %v", tt.release, settings)
+		}
+		if !balancedNix(module) {
+			t.Errorf("release %q: module is not balanced:\n%s", tt.release, module)
+		}
+	}
+}
+
+func TestRunHardwareLaptopWritesModule(t *testing.T) {
+	var parsed string
+	orig := parseNixExpression
+	parseNixExpression = func(expr string) error {
+		parsed = expr
+		return nil
+	}
+	defer func() { parseNixExpression = orig }()
+
+	output := filepath.Join(t.TempDir(), laptopPowerFile)
+	var out bytes.Buffer
+	if err := runHardwareLaptop(&out, writeLaptopFixture(t), hardwareProfilePowerSave, "", output, utils.WriteConfirm{}); err != nil {
+		t.Fatal(err)
+	}
+	written, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != parsed {
+		t.Error("the written module differs from the validated one")
+	}
+	if !strings.Contains(string(written), "services.thermald.enable = true;") {
+		t.Errorf("Intel module should enable thermald:\n%s", written)
+	}
+	if !strings.Contains(out.String(), "Module parses") || !strings.Contains(out.String(), "imports = [ ./"+laptopPowerFile+" ];") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestRunHardwareLaptopRejectsInvalidModule(t *testing.T) {
+	orig := parseNixExpression
+	parseNixExpression = func(string) error { return errors.New("syntax error, unexpected '}'") }
+	defer func() { parseNixExpression = orig }()
+
+	output := filepath.Join(t.TempDir(), laptopPowerFile)
+	err := runHardwareLaptop(&bytes.Buffer{}, t.TempDir(), hardwareProfileBalanced, "", output, utils.WriteConfirm{})
+	if err == nil || !strings.Contains(err.Error(), "failed to parse") {
+		t.Fatalf("expected a parse error, got %v", err)
+	}
+	if _, statErr := os.Stat(output); !os.IsNotExist(statErr) {
+		t.Error("an invalid module should not be written")
+	}
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"nix-ai-help/internal/nixos"
+)
+
+// Exit codes of doctor --summary and diagnose --summary, ordered by severity like the exit codes
+// of monitoring checks
+const (
+	summaryExitOK      = 0
+	summaryExitWarning = 1
+	summaryExitFailure = 2
+)
+
+// diagnosticSeverityRank orders the severities of pattern-based diagnostics
+var diagnosticSeverityRank = map[string]int{"low": 1, "medium": 2, "high": 3, "critical": 4}
+
+// doctorSummary returns the one-line verdict of the health checks, such as
+// "HEALTH: warnings (2 warn, 0 fail)", and the exit code that goes with it
+func doctorSummary(results []HealthCheckResult) (string, int) {
+	var warnCount, failCount int
+	for _, result := range results {
+		switch result.Status {
+		case "warn":
+			warnCount++
+		case "fail":
+			failCount++
+		}
+	}
+
+	verdict, code := "healthy", summaryExitOK
+	if failCount > 0 {
+		verdict, code = "critical", summaryExitFailure
+	} else if warnCount > 0 {
+		verdict, code = "warnings", summaryExitWarning
+	}
+	return fmt.Sprintf("HEALTH: %s (%d warn, %d fail)", verdict, warnCount, failCount), code
+}
+
+// diagnoseSummary returns a one-sentence verdict on the problems found in a log and the exit
+// code that goes with it: high and critical problems fail, lesser ones warn
+func diagnoseSummary(diags []nixos.Diagnostic) (string, int) {
+	var worst *nixos.Diagnostic
+	for i := range diags {
+		if worst == nil || diagnosticSeverityRank[diags[i].Severity] > diagnosticSeverityRank[worst.Severity] {
+			worst = &diags[i]
+		}
+	}
+	if worst == nil {
+		return "DIAGNOSIS: no known problems found in the input.", summaryExitOK
+	}
+
+	code := summaryExitWarning
+	if diagnosticSeverityRank[worst.Severity] >= diagnosticSeverityRank["high"] {
+		code = summaryExitFailure
+	}
+	issues := "1 issue"
+	if len(diags) != 1 {
+		issues = fmt.Sprintf("%d issues", len(diags))
+	}
+	return fmt.Sprintf("DIAGNOSIS: %s, %s found; most severe: %s.", worst.Severity, issues, worst.Issue), code
+}
+
+// failedServicesSummary returns a one-sentence verdict on the failed systemd units and the exit
+// code that goes with it
+func failedServicesSummary(units []failedUnit) (string, int) {
+	if len(units) == 0 {
+		return "DIAGNOSIS: no failed units.", summaryExitOK
+	}
+	names := make([]string, len(units))
+	for i, unit := range units {
+		names[i] = unit.Name
+	}
+	noun := "unit"
+	if len(units) != 1 {
+		noun = "units"
+	}
+	return fmt.Sprintf("DIAGNOSIS: %d failed %s: %s.", len(units), noun, strings.Join(names, ", ")), summaryExitFailure
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"testing"
+
+	"nix-ai-help/internal/nixos"
+)
+
+func TestDoctorSummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		want     string
+		wantCode int
+	}{
+		{"no results", nil, "HEALTH: healthy (0 warn, 0 fail)", summaryExitOK},
+		{"passes and info", []string{"pass", "info", "pass"}, "HEALTH: healthy (0 warn, 0 fail)", summaryExitOK},
+		{"warnings", []string{"pass", "warn", "info", "warn"}, "HEALTH: warnings (2 warn, 0 fail)", summaryExitWarning},
+		{"failures win over warnings", []string{"warn", "fail", "pass", "fail"}, "HEALTH: critical (1 warn, 2 fail)", summaryExitFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var results []HealthCheckResult
+			for _, status := range tt.statuses {
+				results = append(results, HealthCheckResult{Category: "system", Status: status})
+			}
+			got, code := doctorSummary(results)
+			if got != tt.want || code != tt.wantCode {
+				t.Errorf("doctorSummary = %q, %d; want %q, %d", got, code, tt.want, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestDiagnoseSummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		diags    []nixos.Diagnostic
+		want     string
+		wantCode int
+	}{
+		{"nothing found", nil, "DIAGNOSIS: no known problems found in the input.", summaryExitOK},
+		{
+			"minor problem warns",
+			[]nixos.Diagnostic{{Issue: "Unclassified error detected", Severity: "medium"}},
+			"DIAGNOSIS: medium, 1 issue found; most severe: Unclassified error detected.",
+			summaryExitWarning,
+		},
+		{
+			"most severe of mixed problems",
+			[]nixos.Diagnostic{
+				{Issue: "Disk space low", Severity: "medium"},
+				{Issue: "Build failure", Severity: "critical"},
+				{Issue: "NixOS configuration syntax error", Severity: "high"},
+			},
+			"DIAGNOSIS: critical, 3 issues found; most severe: Build failure.",
+			summaryExitFailure,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, code := diagnoseSummary(tt.diags)
+			if got != tt.want || code != tt.wantCode {
+				t.Errorf("diagnoseSummary = %q, %d; want %q, %d", got, code, tt.want, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestFailedServicesSummary(t *testing.T) {
+	if got, code := failedServicesSummary(nil); got != "DIAGNOSIS: no failed units." || code != summaryExitOK {
+		t.Errorf("no failed units = %q, %d", got, code)
+	}
+	units := []failedUnit{{Name: "nginx.service"}, {Name: "postgresql.service"}}
+	want := "DIAGNOSIS: 2 failed units: nginx.service, postgresql.service."
+	if got, code := failedServicesSummary(units); got != want || code != summaryExitFailure {
+		t.Errorf("failedServicesSummary = %q, %d; want %q, %d", got, code, want, summaryExitFailure)
+	}
+}
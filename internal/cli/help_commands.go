@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// CommandDoc is the structured description of a command, generated from the cobra command tree
+type CommandDoc struct {
+	Name        string       `json:"name"`
+	Path        string       `json:"path"`
+	Usage       string       `json:"usage"`
+	Short       string       `json:"short"`
+	Long        string       `json:"long,omitempty"`
+	Aliases     []string     `json:"aliases,omitempty"`
+	Example     string       `json:"example,omitempty"`
+	Flags       []FlagDoc    `json:"flags,omitempty"`
+	Subcommands []CommandDoc `json:"subcommands,omitempty"`
+}
+
+// FlagDoc describes a single command flag
+type FlagDoc struct {
+	Name      string `json:"name"`
+	Shorthand string `json:"shorthand,omitempty"`
+	Type      string `json:"type"`
+	Default   string `json:"default,omitempty"`
+	Usage     string `json:"usage"`
+}
+
+// helpCmd replaces cobra's default help command with one that can export the command tree
+var helpCmd = &cobra.Command{
+	Use:   "help [command]",
+	Short: "Help about any command",
+	Long: `Help provides help for any command in the application.
+
+Use --format to export the full command tree as structured documentation.
+
+Examples:
+  nixai help                    # Show general help
+  nixai help search             # Show help for the search command
+  nixai help --format json      # Export all commands, subcommands and flags as JSON
+  nixai help --format markdown  # Export all commands as Markdown documentation`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target := cmd.Root()
+		if len(args) > 0 {
+			found, _, err := target.Find(args)
+			if err != nil || found == nil {
+				return fmt.Errorf("unknown help topic %q", strings.Join(args, " "))
+			}
+			target = found
+		}
+
+		format, _ := cmd.Flags().GetString("format")
+		return writeCommandHelp(cmd.OutOrStdout(), target, format)
+	},
+}
+
+func init() {
+	helpCmd.Flags().String("format", "", "Export help as structured documentation (json, markdown)")
+}
+
+// writeCommandHelp writes help for cmd in the requested format; an empty format shows cobra's help
+func writeCommandHelp(out io.Writer, cmd *cobra.Command, format string) error {
+	switch strings.ToLower(format) {
+	case "":
+		cmd.SetOut(out)
+		return cmd.Help()
+	case "json":
+		data, err := json.MarshalIndent(BuildCommandDoc(cmd), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode help: %w", err)
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	case "markdown", "md":
+		_, err := io.WriteString(out, RenderCommandDocMarkdown(BuildCommandDoc(cmd)))
+		return err
+	default:
+		return fmt.Errorf("unsupported help format %q (use json or markdown)", format)
+	}
+}
+
+// BuildCommandDoc walks the cobra command tree below cmd and returns its documentation
+func BuildCommandDoc(cmd *cobra.Command) CommandDoc {
+	doc := CommandDoc{
+		Name:    cmd.Name(),
+		Path:    cmd.CommandPath(),
+		Usage:   cmd.UseLine(),
+		Short:   cmd.Short,
+		Long:    cmd.Long,
+		Aliases: cmd.Aliases,
+		Example: cmd.Example,
+	}
+
+	addFlag := func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		doc.Flags = append(doc.Flags, FlagDoc{
+			Name:      f.Name,
+			Shorthand: f.Shorthand,
+			Type:      f.Value.Type(),
+			Default:   f.DefValue,
+			Usage:     f.Usage,
+		})
+	}
+	cmd.LocalFlags().VisitAll(addFlag)
+
+	seen := make(map[string]bool)
+	for _, sub := range cmd.Commands() {
+		if !sub.IsAvailableCommand() && sub.Name() != "help" {
+			continue
+		}
+		if seen[sub.Name()] {
+			continue
+		}
+		seen[sub.Name()] = true
+		doc.Subcommands = append(doc.Subcommands, BuildCommandDoc(sub))
+	}
+
+	return doc
+}
+
+// RenderCommandDocMarkdown renders a command tree as Markdown documentation
+func RenderCommandDocMarkdown(doc CommandDoc) string {
+	var b strings.Builder
+	renderCommandDocMarkdown(&b, doc, 1)
+	return b.String()
+}
+
+func renderCommandDocMarkdown(b *strings.Builder, doc CommandDoc, level int) {
+	if level > 6 {
+		level = 6
+	}
+	fmt.Fprintf(b, "%s %s\n\n", strings.Repeat("#", level), doc.Path)
+	if doc.Short != "" {
+		fmt.Fprintf(b, "%s\n\n", doc.Short)
+	}
+	fmt.Fprintf(b, "```\n%s\n```\n\n", doc.Usage)
+
+	if len(doc.Aliases) > 0 {
+		fmt.Fprintf(b, "Aliases: %s\n\n", strings.Join(doc.Aliases, ", "))
+	}
+
+	if doc.Example != "" {
+		fmt.Fprintf(b, "**Examples**\n\n```\n%s\n```\n\n", strings.TrimRight(doc.Example, "\n"))
+	}
+
+	if len(doc.Flags) > 0 {
+		b.WriteString("| Flag | Type | Default | Description |\n")
+		b.WriteString("|------|------|---------|-------------|\n")
+		for _, f := range doc.Flags {
+			name := "--" + f.Name
+			if f.Shorthand != "" {
+				name = "-" + f.Shorthand + ", " + name
+			}
+			fmt.Fprintf(b, "| `%s` | %s | %s | %s |\n", name, f.Type, f.Default, strings.ReplaceAll(f.Usage, "|", "\\|"))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(doc.Subcommands) > 0 {
+		b.WriteString("**Subcommands**\n\n")
+		for _, sub := range doc.Subcommands {
+			fmt.Fprintf(b, "- `%s`: %s\n", sub.Name, sub.Short)
+		}
+		b.WriteString("\n")
+		for _, sub := range doc.Subcommands {
+			renderCommandDocMarkdown(b, sub, level+1)
+		}
+	}
+}
+
+// commandSummaryLines lists the top-level commands as "name: short description" lines
+func commandSummaryLines(root *cobra.Command) []string {
+	var lines []string
+	for _, sub := range BuildCommandDoc(root).Subcommands {
+		lines = append(lines, fmt.Sprintf("%s: %s", sub.Name, sub.Short))
+	}
+	return lines
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func collectCommandPaths(doc CommandDoc, paths map[string]bool) {
+	paths[doc.Path] = true
+	for _, sub := range doc.Subcommands {
+		collectCommandPaths(sub, paths)
+	}
+}
+
+func TestHelpFormatJSON_ContainsAllCommands(t *testing.T) {
+	initializeCommands()
+
+	var buf bytes.Buffer
+	if err := writeCommandHelp(&buf, rootCmd, "json"); err != nil {
+		t.Fatalf("writeCommandHelp() error = %v", err)
+	}
+
+	var doc CommandDoc
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("help output is not valid JSON: %v", err)
+	}
+
+	paths := make(map[string]bool)
+	collectCommandPaths(doc, paths)
+
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		for _, sub := range cmd.Commands() {
+			if !sub.IsAvailableCommand() {
+				continue
+			}
+			if !paths[sub.CommandPath()] {
+				t.Errorf("command %q missing from JSON help", sub.CommandPath())
+			}
+			walk(sub)
+		}
+	}
+	walk(rootCmd)
+
+	for _, want := range []string{"nixai ask", "nixai search", "nixai mcp-server"} {
+		if !paths[want] {
+			t.Errorf("expected %q in JSON help", want)
+		}
+	}
+}
+
+func TestHelpFormatJSON_IncludesFlags(t *testing.T) {
+	initializeCommands()
+
+	doc := BuildCommandDoc(askCmd)
+	found := false
+	for _, f := range doc.Flags {
+		if f.Name == "quiet" && f.Shorthand == "q" && f.Type == "bool" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected ask --quiet flag in doc, got %+v", doc.Flags)
+	}
+}
+
+func TestHelpFormatMarkdown(t *testing.T) {
+	initializeCommands()
+
+	var buf bytes.Buffer
+	if err := writeCommandHelp(&buf, rootCmd, "markdown"); err != nil {
+		t.Fatalf("writeCommandHelp() error = %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"# nixai", "## nixai ask", "## nixai search", "`--tui`"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected markdown help to contain %q", want)
+		}
+	}
+}
+
+func TestHelpFormat_Unsupported(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeCommandHelp(&buf, rootCmd, "yaml"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
@@ -41,13 +41,13 @@ func NewCommandCompleter() *CommandCompleter {
 		"diagnose":     {"system", "config", "services", "network", "hardware", "performance"},
 		"doctor":       {"full", "quick", "store", "config", "security"},
 		"flake":        {"init", "check", "show", "update", "template", "convert"},
-		"learn":        {"basics", "flakes", "packages", "services", "advanced", "troubleshooting"},
+		"learn":        {"path", "review", "basics", "flakes", "packages", "services", "home-manager", "advanced", "troubleshooting"},
 		"logs":         {"system", "boot", "service", "errors", "build", "analyze"},
-		"mcp-server":   {"start", "stop", "status", "logs", "config"},
+		"mcp-server":   {"start", "stop", "status", "logs", "config", "query"},
 		"neovim-setup": {"install", "configure", "test", "update", "remove"},
 		"package-repo": {"analyze", "generate", "template", "validate"},
 		"build":        {"troubleshoot", "optimize", "fix", "analyze"},
-		"config":       {"show", "set", "get", "reset"},
+		"config":       {"show", "set", "get", "reset", "history", "restore", "export", "import"},
 		"devenv":       {"list", "create", "suggest"},
 		"gc":           {"analyze", "clean", "safe-clean"},
 		"hardware":     {"analyze", "optimize", "detect"},
@@ -131,7 +131,7 @@ func TestCommandCompleterSubcommands(t *testing.T) {
 	testCases := map[string][]string{
 		"community": {"forums", "docs", "matrix", "github"},
 		"diagnose":  {"system", "config", "services", "network", "hardware", "performance"},
-		"learn":     {"basics", "flakes", "packages", "services", "advanced", "troubleshooting"},
+		"learn":     {"path", "review", "basics", "flakes", "packages", "services", "home-manager", "advanced", "troubleshooting"},
 	}
 
 	for cmd, expectedSubs := range testCases {
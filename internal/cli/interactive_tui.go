@@ -5,22 +5,28 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	nixoscontext "nix-ai-help/internal/ai/context"
+	"nix-ai-help/internal/changelog"
 	"nix-ai-help/internal/config"
 	"nix-ai-help/internal/tui/components"
 	"nix-ai-help/internal/tui/styles"
 	"nix-ai-help/pkg/utils"
+	"nix-ai-help/pkg/version"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 )
 
 // LaunchTUIMode launches TUI mode with support for any command and parameters
@@ -36,15 +42,8 @@ func LaunchTUIMode(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Create the TUI application with initial command context
-	app := tea.NewProgram(
-		initialModelWithCommand(initialCommand, initialArgs),
-		tea.WithAltScreen(),
-		tea.WithMouseCellMotion(),
-	)
-
-	// Run the TUI
-	if _, err := app.Run(); err != nil {
+	// Run the TUI application with initial command context
+	if err := runTUIProgram(initialModelWithCommand(initialCommand, initialArgs), tea.WithAltScreen(), tea.WithMouseCellMotion()); err != nil {
 		return fmt.Errorf("error running TUI: %v", err)
 	}
 
@@ -80,19 +79,35 @@ func initialModelWithCommand(command string, args []string) tuiModel {
 
 // InteractiveModeTUI starts the modern TUI interface for nixai
 func InteractiveModeTUI() {
-	// Create the TUI application without AltScreen to avoid terminal compatibility issues
-	app := tea.NewProgram(
-		initialModel(),
-		tea.WithMouseCellMotion(),
-	)
-
-	// Run the TUI
-	if _, err := app.Run(); err != nil {
+	// Run the TUI without AltScreen to avoid terminal compatibility issues
+	if err := runTUIProgram(initialModel(), tea.WithMouseCellMotion()); err != nil {
 		fmt.Printf("Error running TUI: %v\n", err)
 		os.Exit(1)
 	}
 }
 
+// runTUIProgram runs the TUI and, when it was closed with Ctrl-C or SIGINT, exits with the
+// interrupt status once bubbletea has restored the terminal
+func runTUIProgram(model tuiModel, opts ...tea.ProgramOption) error {
+	// SIGINT goes through the shared interrupt handler, which waits for the TUI to shut down
+	app := tea.NewProgram(model, append(opts, tea.WithoutSignalHandler())...)
+	var signalled atomic.Bool
+	finished := make(chan struct{})
+	remove := utils.OnInterrupt(func() {
+		signalled.Store(true)
+		app.Quit()
+		<-finished
+	})
+
+	final, err := app.Run()
+	close(finished)
+	remove()
+	if m, ok := final.(tuiModel); (ok && m.interrupted) || signalled.Load() {
+		utils.ExitInterrupted(os.Stdout)
+	}
+	return err
+}
+
 // tuiModel represents the state of our TUI application
 type tuiModel struct {
 	commands           []commandItem
@@ -126,6 +141,24 @@ type tuiModel struct {
 	// AI response popup support
 	askResponsePopup *components.AskResponsePopup
 	theme            *styles.Theme
+
+	// Last executed command, kept so it can be adjusted and rerun
+	lastRun *lastExecution
+
+	// Active provider, model, context and MCP reachability for the system bar
+	systemStatus string
+
+	// Set when the TUI was closed with Ctrl-C, so that nixai exits with the interrupt status
+	interrupted bool
+}
+
+// lastExecution records the command, options, and input of the most recent run
+type lastExecution struct {
+	command      string
+	subcommand   string
+	options      []commandOption
+	optionValues map[string]string
+	question     string
 }
 
 type commandItem struct {
@@ -190,6 +223,11 @@ type commandExecutionStartMsg struct {
 	command string
 }
 
+// systemStatusMsg carries a refreshed provider/model/context summary for the system bar
+type systemStatusMsg struct {
+	status string
+}
+
 // Define styles
 var (
 	titleStyle = lipgloss.NewStyle().
@@ -214,6 +252,16 @@ var (
 				Foreground(lipgloss.Color("#6272a4")).
 				Italic(true)
 
+	systemBarStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#24283b")).
+			Foreground(lipgloss.Color("#7ebae4")).
+			PaddingLeft(1).
+			PaddingRight(1)
+
+	matchStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#e0af68")).
+			Bold(true)
+
 	statusStyle = lipgloss.NewStyle().
 			Background(lipgloss.Color("#414868")).
 			Foreground(lipgloss.Color("#a9b1d6")).
@@ -401,7 +449,9 @@ func getAvailableCommands() []commandItem {
 			needsInput:  false,
 			options:     []commandOption{},
 			subcommands: []subcommandItem{
-				{name: "list", description: "List templates", options: []commandOption{}},
+				{name: "list", description: "List templates", options: []commandOption{
+					{name: "Category", flag: "category", description: "Only list templates of this category", required: false, hasValue: true, optionType: "string"},
+				}},
 				{name: "show", description: "Show template", options: []commandOption{}},
 				{name: "apply", description: "Apply template", options: []commandOption{}},
 				{name: "search", description: "Search templates", options: []commandOption{}},
@@ -559,9 +609,60 @@ func (m tuiModel) Init() tea.Cmd {
 				}
 			},
 			m.executeCommandWithParams(m.selectedCmdName, argList),
+			loadSystemStatus,
 		)
 	}
-	return nil
+	return loadSystemStatus
+}
+
+// loadSystemStatus reads the current config and checks the MCP server for the system bar
+func loadSystemStatus() tea.Msg {
+	cfg, err := config.LoadUserConfig()
+	if err != nil {
+		return systemStatusMsg{status: "Config: unavailable"}
+	}
+
+	addr := net.JoinHostPort(cfg.MCPServer.Host, strconv.Itoa(cfg.MCPServer.Port))
+	mcpReachable := false
+	if conn, err := net.DialTimeout("tcp", addr, 500*time.Millisecond); err == nil {
+		_ = conn.Close()
+		mcpReachable = true
+	}
+
+	return systemStatusMsg{status: composeSystemStatus(cfg, &cfg.NixOSContext, mcpReachable)}
+}
+
+// composeSystemStatus builds the system bar text from the config, detected context and MCP state.
+// Provider and model flags take precedence over the configured values.
+func composeSystemStatus(cfg *config.UserConfig, nixosCtx *config.NixOSContext, mcpReachable bool) string {
+	provider := cfg.AIProvider
+	if aiProvider != "" {
+		provider = aiProvider
+	}
+	if provider == "" {
+		provider = "unset"
+	}
+
+	model := cfg.AIModel
+	if aiModel != "" {
+		model = aiModel
+	}
+	if model == "" {
+		model = "default"
+	}
+
+	mcpState := "MCP: ❌ down"
+	if mcpReachable {
+		mcpState = "MCP: ✅ up"
+	}
+
+	parts := []string{
+		"Provider: " + provider,
+		"Model: " + model,
+		nixoscontext.NewNixOSContextBuilder().GetContextSummary(nixosCtx),
+		mcpState,
+	}
+	return strings.Join(parts, " | ")
 }
 
 // Update handles all incoming messages and updates the model state
@@ -607,6 +708,14 @@ func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.currentState = stateResults
 		m.focused = focusOutput
 
+		// Commands such as config may change provider/model, so refresh the system bar
+		var cmd tea.Cmd
+		m.askResponsePopup, cmd = m.askResponsePopup.Update(msg)
+		return m, tea.Batch(cmd, loadSystemStatus)
+
+	case systemStatusMsg:
+		m.systemStatus = msg.status
+
 	case commandExecutionStartMsg:
 		m.isStreaming = true
 		m.isExecuting = true
@@ -652,6 +761,7 @@ func (m tuiModel) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if m.changelogVisible {
 		switch msg.String() {
 		case "ctrl+c":
+			m.interrupted = true
 			return m, tea.Quit
 		case "esc", "?":
 			m.changelogVisible = false
@@ -686,6 +796,7 @@ func (m tuiModel) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if m.inputMode || m.searchMode {
 		switch msg.String() {
 		case "ctrl+c":
+			m.interrupted = true
 			return m, tea.Quit
 		case "esc":
 			return m.handleEscape(), nil
@@ -701,6 +812,7 @@ func (m tuiModel) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Handle other keys when not in input mode
 	switch msg.String() {
 	case "ctrl+c", "q":
+		m.interrupted = msg.String() == "ctrl+c"
 		return m, tea.Quit
 
 	case "?":
@@ -748,6 +860,7 @@ func (m tuiModel) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			if m.selectedCommand >= 0 && m.selectedCommand < len(filteredCommands) {
 				cmd := filteredCommands[m.selectedCommand]
 				args := m.buildCommandArgs()
+				m = m.rememberExecution(cmd.name, "", "")
 				m.isExecuting = true
 				m.currentState = stateExecuting
 				return m, m.executeCommandWithParams(cmd.name, args)
@@ -755,6 +868,13 @@ func (m tuiModel) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case "r":
+		// Rerun shortcut: go back to the last command with its previous values
+		if m.currentState == stateResults {
+			return m.rerunLastCommand()
+		}
+		return m.handleTextInput(msg), nil
+
 	case "/":
 		return m.handleSearchMode(), nil
 
@@ -900,6 +1020,7 @@ func (m tuiModel) handleEnter() (tuiModel, tea.Cmd) {
 			// Build command arguments including options + question
 			args := m.buildCommandArgs()
 			args = append(args, question)
+			m = m.rememberExecution("ask", "", question)
 
 			return m, m.executeCommandWithParams("ask", args)
 		}
@@ -947,6 +1068,7 @@ func (m tuiModel) handleEnter() (tuiModel, tea.Cmd) {
 					m.commandOutput = fmt.Sprintf("Configure options for '%s' command\n\nInstructions:\n• Use Tab to switch between panels\n• Use ↑↓ to navigate options\n• Press Enter on an option to configure it\n• Press Enter on command panel to execute with current options", cmd.name)
 				} else {
 					// Execute command immediately if no options or subcommands
+					m = m.rememberExecution(cmd.name, "", "")
 					m.isExecuting = true
 					m.currentState = stateExecuting
 					return m, m.executeCommand(cmd.name)
@@ -980,6 +1102,7 @@ func (m tuiModel) handleEnter() (tuiModel, tea.Cmd) {
 						m.commandOutput = fmt.Sprintf("Configure options for '%s %s' command\n\nInstructions:\n• Use Tab to switch between panels\n• Use ↑↓ to navigate options\n• Press Enter on an option to configure it\n• Press Enter on command panel to execute with current options", cmd.name, subcmd.name)
 					} else {
 						// Execute subcommand immediately if no options
+						m = m.rememberExecution(cmd.name, subcmd.name, "")
 						m.isExecuting = true
 						m.currentState = stateExecuting
 						return m, m.executeCommandWithSubcommand(cmd.name, subcmd.name, []string{})
@@ -1009,6 +1132,7 @@ func (m tuiModel) handleEnter() (tuiModel, tea.Cmd) {
 					}
 
 					args := m.buildCommandArgs()
+					m = m.rememberExecution(cmd.name, "", "")
 					m.isExecuting = true
 					m.currentState = stateExecuting
 					return m, m.executeCommandWithParams(cmd.name, args)
@@ -1051,6 +1175,7 @@ func (m tuiModel) handleEnter() (tuiModel, tea.Cmd) {
 				}
 
 				args := m.buildCommandArgs()
+				m = m.rememberExecution(cmd.name, "", "")
 				m.isExecuting = true
 				m.currentState = stateExecuting
 				return m, m.executeCommandWithParams(cmd.name, args)
@@ -1067,6 +1192,77 @@ func (m tuiModel) handleEnter() (tuiModel, tea.Cmd) {
 	return m, nil
 }
 
+// rememberExecution stores the command about to run so it can be rerun from the results view
+func (m tuiModel) rememberExecution(command, subcommand, question string) tuiModel {
+	values := make(map[string]string, len(m.optionValues))
+	for k, v := range m.optionValues {
+		values[k] = v
+	}
+
+	m.lastRun = &lastExecution{
+		command:      command,
+		subcommand:   subcommand,
+		options:      m.commandOptions,
+		optionValues: values,
+		question:     question,
+	}
+	return m
+}
+
+// rerunLastCommand returns to the options or input panel pre-filled with the last run's values.
+// Commands without options or input are executed again directly.
+func (m tuiModel) rerunLastCommand() (tuiModel, tea.Cmd) {
+	last := m.lastRun
+	if last == nil {
+		m.commandOutput = "No command to rerun yet"
+		return m, nil
+	}
+
+	// Select the command in the unfiltered list
+	m.searchMode = false
+	m.searchQuery = ""
+	for i, cmd := range m.commands {
+		if cmd.name == last.command {
+			m.selectedCommand = i
+			break
+		}
+	}
+
+	m.commandOptions = last.options
+	m.optionValues = make(map[string]string, len(last.optionValues))
+	for k, v := range last.optionValues {
+		m.optionValues[k] = v
+	}
+	m.selectedOption = 0
+
+	switch {
+	case last.command == "ask":
+		m.inputMode = true
+		m.parameterInput = last.question
+		m.focused = focusInput
+		m.currentState = stateCommandList
+		m.selectedCmdName = last.command
+		m.commandOutput = "Edit your question for nixai ask and press Enter to rerun:"
+		return m, nil
+
+	case len(last.options) > 0:
+		m.currentState = stateCommandOptions
+		m.focused = focusOptions
+		m.commandOutput = fmt.Sprintf("Adjust options for '%s' and select 'Execute Command' to rerun", last.command)
+		return m, nil
+
+	case last.subcommand != "":
+		m.isExecuting = true
+		m.currentState = stateExecuting
+		return m, m.executeCommandWithSubcommand(last.command, last.subcommand, []string{})
+
+	default:
+		m.isExecuting = true
+		m.currentState = stateExecuting
+		return m, m.executeCommand(last.command)
+	}
+}
+
 // buildCommandArgs builds command arguments from option values
 func (m tuiModel) buildCommandArgs() []string {
 	var args []string
@@ -1237,7 +1433,7 @@ func (m tuiModel) View() string {
 		// Two-panel layout: Commands + Output
 		leftPanelWidth := m.terminalWidth * 40 / 100
 		rightPanelWidth := m.terminalWidth - leftPanelWidth - 4
-		panelHeight := m.terminalHeight - 4
+		panelHeight := m.terminalHeight - 5
 
 		leftPanel = m.renderCommandsPanel(leftPanelWidth, panelHeight)
 		rightPanel = m.renderOutputPanel(rightPanelWidth, panelHeight)
@@ -1248,7 +1444,7 @@ func (m tuiModel) View() string {
 		leftPanelWidth := m.terminalWidth * 30 / 100
 		middlePanelWidth := m.terminalWidth * 30 / 100
 		rightPanelWidth := m.terminalWidth - leftPanelWidth - middlePanelWidth - 6
-		panelHeight := m.terminalHeight - 4
+		panelHeight := m.terminalHeight - 5
 
 		leftPanel = m.renderCommandsPanel(leftPanelWidth, panelHeight)
 		middlePanel := m.renderSubcommandsPanel(middlePanelWidth, panelHeight)
@@ -1261,7 +1457,7 @@ func (m tuiModel) View() string {
 		// Three-panel layout: Commands + Options + Output (stack options and output)
 		leftPanelWidth := m.terminalWidth * 30 / 100
 		rightPanelWidth := m.terminalWidth - leftPanelWidth - 4
-		panelHeight := m.terminalHeight - 4
+		panelHeight := m.terminalHeight - 5
 
 		leftPanel = m.renderCommandsPanel(leftPanelWidth, panelHeight)
 
@@ -1279,7 +1475,7 @@ func (m tuiModel) View() string {
 		// Two-panel layout with executing indicator
 		leftPanelWidth := m.terminalWidth * 40 / 100
 		rightPanelWidth := m.terminalWidth - leftPanelWidth - 4
-		panelHeight := m.terminalHeight - 4
+		panelHeight := m.terminalHeight - 5
 
 		leftPanel = m.renderCommandsPanel(leftPanelWidth, panelHeight)
 		rightPanel = m.renderOutputPanel(rightPanelWidth, panelHeight)
@@ -1289,15 +1485,18 @@ func (m tuiModel) View() string {
 		// Two-panel layout showing results
 		leftPanelWidth := m.terminalWidth * 40 / 100
 		rightPanelWidth := m.terminalWidth - leftPanelWidth - 4
-		panelHeight := m.terminalHeight - 4
+		panelHeight := m.terminalHeight - 5
 
 		leftPanel = m.renderCommandsPanel(leftPanelWidth, panelHeight)
 		rightPanel = m.renderOutputPanel(rightPanelWidth, panelHeight)
 		title = "❄️ nixai: Command Results (Tab to select new command)"
 	}
 
-	// Create the status bar
-	statusBar := m.renderStatusBar(m.terminalWidth)
+	// Create the status bar and the system bar below it
+	statusBar := lipgloss.JoinVertical(lipgloss.Left,
+		m.renderStatusBar(m.terminalWidth),
+		m.renderSystemBar(m.terminalWidth),
+	)
 
 	// Combine panels horizontally
 	mainArea := lipgloss.JoinHorizontal(lipgloss.Top, leftPanel, rightPanel)
@@ -1369,18 +1568,31 @@ func (m tuiModel) renderCommandsPanel(width, height int) string {
 	// Only show command list if not in input mode
 	if !m.inputMode {
 		// Filter commands based on search query
-		filteredCommands := m.filterCommands()
+		var matches []commandMatch
+		if m.searchQuery != "" {
+			matches = fuzzyMatchCommands(m.searchQuery, m.commands)
+		} else {
+			for _, cmd := range m.commands {
+				matches = append(matches, commandMatch{command: cmd})
+			}
+		}
 
 		// Render command list
-		for i, cmd := range filteredCommands {
+		for i, match := range matches {
+			cmd := match.command
+			selected := i == m.selectedCommand && m.focused == focusCommands
+
 			line := cmd.name
+			if !selected {
+				line = highlightMatches(cmd.name, match.positions)
+			}
 
 			// Add indicator for commands that need input
 			if cmd.needsInput {
 				line += " [INPUT]"
 			}
 
-			if i == m.selectedCommand && m.focused == focusCommands {
+			if selected {
 				line = selectedStyle.Render(line)
 			} else {
 				line = commandStyle.Render(line)
@@ -1622,6 +1834,9 @@ func (m tuiModel) renderStatusBar(width int) string {
 	case stateResults:
 		statusItems = append(statusItems, "✅ Results")
 		statusItems = append(statusItems, "Tab: New Command")
+		if m.lastRun != nil {
+			statusItems = append(statusItems, "r: Rerun")
+		}
 		statusItems = append(statusItems, "Esc: Back")
 		statusItems = append(statusItems, "Ctrl+C: Exit")
 	}
@@ -1657,23 +1872,131 @@ func (m tuiModel) renderStatusBar(width int) string {
 		Render(statusText)
 }
 
-// filterCommands filters commands based on search query
+// renderSystemBar renders the bottom row with provider, model, context and MCP status
+func (m tuiModel) renderSystemBar(width int) string {
+	status := m.systemStatus
+	if status == "" {
+		status = "Loading provider and context..."
+	}
+
+	return systemBarStyle.
+		Width(width).
+		Render(status)
+}
+
+// filterCommands returns the commands matching the search query, ranked by fuzzy match quality
 func (m tuiModel) filterCommands() []commandItem {
 	if m.searchQuery == "" {
 		return m.commands
 	}
 
-	var filtered []commandItem
-	query := strings.ToLower(m.searchQuery)
+	matches := fuzzyMatchCommands(m.searchQuery, m.commands)
+	filtered := make([]commandItem, 0, len(matches))
+	for _, match := range matches {
+		filtered = append(filtered, match.command)
+	}
+
+	return filtered
+}
+
+// commandMatch is a command that matched a fuzzy search query
+type commandMatch struct {
+	command   commandItem
+	score     int
+	positions []int // indexes of matched characters in the command name
+}
+
+// fuzzyMatchCommands scores every command against the query and returns the matches,
+// best first. Commands whose names don't match fall back to a low-scoring
+// description substring match so descriptions stay searchable.
+func fuzzyMatchCommands(query string, commands []commandItem) []commandMatch {
+	query = strings.ToLower(strings.TrimSpace(query))
+	var matches []commandMatch
 
-	for _, cmd := range m.commands {
-		if strings.Contains(strings.ToLower(cmd.name), query) ||
-			strings.Contains(strings.ToLower(cmd.description), query) {
-			filtered = append(filtered, cmd)
+	for _, cmd := range commands {
+		if score, positions, ok := fuzzyScore(query, cmd.name); ok {
+			matches = append(matches, commandMatch{command: cmd, score: score, positions: positions})
+		} else if strings.Contains(strings.ToLower(cmd.description), query) {
+			matches = append(matches, commandMatch{command: cmd, score: 0})
 		}
 	}
 
-	return filtered
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	return matches
+}
+
+// fuzzyScore matches query as a subsequence of target, fzf-style. Consecutive matches
+// and matches at word boundaries score higher, gaps score lower. It returns the
+// matched character positions for highlighting.
+func fuzzyScore(query, target string) (int, []int, bool) {
+	if query == "" {
+		return 0, nil, true
+	}
+
+	lower := strings.ToLower(target)
+	positions := make([]int, 0, len(query))
+	score := 0
+	qi := 0
+	prev := -1
+
+	for ti := 0; ti < len(lower) && qi < len(query); ti++ {
+		if lower[ti] != query[qi] {
+			continue
+		}
+
+		score += 10
+		switch {
+		case ti == 0:
+			score += 15
+		case lower[ti-1] == '-' || lower[ti-1] == ' ' || lower[ti-1] == '_':
+			score += 10
+		}
+		if prev >= 0 {
+			if ti == prev+1 {
+				score += 15
+			} else {
+				score -= ti - prev - 1
+			}
+		}
+
+		positions = append(positions, ti)
+		prev = ti
+		qi++
+	}
+
+	if qi < len(query) {
+		return 0, nil, false
+	}
+
+	// Prefer shorter names when the match quality is otherwise equal
+	score -= len(target) - len(query)
+
+	return score, positions, true
+}
+
+// highlightMatches renders the matched character positions of name with matchStyle
+func highlightMatches(name string, positions []int) string {
+	if len(positions) == 0 {
+		return name
+	}
+
+	matched := make(map[int]bool, len(positions))
+	for _, p := range positions {
+		matched[p] = true
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if matched[i] {
+			b.WriteString(matchStyle.Render(string(name[i])))
+		} else {
+			b.WriteByte(name[i])
+		}
+	}
+	return b.String()
 }
 
 // executeCommand executes a command and returns a tea.Cmd
@@ -1971,12 +2294,7 @@ func (m tuiModel) toggleChangelog() (tuiModel, tea.Cmd) {
 	} else {
 		// Show changelog - load content if not already loaded
 		if m.changelogContent == "" {
-			content, err := loadChangelogContent()
-			if err != nil {
-				m.commandOutput = fmt.Sprintf("❌ Failed to load changelog: %v", err)
-				return m, nil
-			}
-			m.changelogContent = content
+			m.changelogContent = loadChangelogContent()
 		}
 
 		// Configure viewport for the changelog popup
@@ -2012,138 +2330,40 @@ func (m tuiModel) toggleAskResponse() (tuiModel, tea.Cmd) {
 	return m, nil
 }
 
-// loadChangelogContent loads the changelog from the YAML file
-func loadChangelogContent() (string, error) {
-	// Try to load changelog from configs/changelog.yaml
-	changelogPath := "configs/changelog.yaml"
-	if _, err := os.Stat(changelogPath); os.IsNotExist(err) {
-		// Fallback to a default changelog
-		return generateDefaultChangelog(), nil
-	}
-
-	data, err := os.ReadFile(changelogPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to read changelog file: %w", err)
-	}
-
-	// Parse YAML to extract version information
-	var changelogData struct {
-		Changelog []struct {
-			Version    string   `yaml:"version"`
-			Date       string   `yaml:"date"`
-			Highlights []string `yaml:"highlights"`
-			Features   []struct {
-				Title       string `yaml:"title"`
-				Description string `yaml:"description"`
-			} `yaml:"features"`
-			Improvements []string `yaml:"improvements"`
-			Fixes        []string `yaml:"fixes"`
-		} `yaml:"changelog"`
-	}
+// maxChangelogEntries is how many releases the changelog shows when nothing is new
+const maxChangelogEntries = 3
 
-	if err := yaml.Unmarshal(data, &changelogData); err != nil {
-		return "", fmt.Errorf("failed to parse changelog YAML: %w", err)
-	}
+// loadChangelogContent formats the releases since the version the user last saw and records
+// the running version as seen
+func loadChangelogContent() string {
+	content := formatChangelogContent(changelog.Entries(), changelog.LastSeen(), version.Version)
+	_ = changelog.MarkSeen(version.Version)
+	return content
+}
 
-	// Format changelog content
+// formatChangelogContent formats the releases after lastSeen up to the current version, or the
+// latest releases up to it when there is nothing new or nothing was seen yet
+func formatChangelogContent(entries []changelog.Entry, lastSeen, current string) string {
 	var content strings.Builder
-	content.WriteString("# 📋 nixai Changelog\n\n")
-
-	for i, version := range changelogData.Changelog {
-		if i >= 3 { // Show only latest 3 versions
-			break
-		}
-
-		content.WriteString(fmt.Sprintf("## 🎯 Version %s (%s)\n\n", version.Version, version.Date))
-
-		if len(version.Highlights) > 0 {
-			content.WriteString("### ✨ Highlights\n")
-			for _, highlight := range version.Highlights {
-				content.WriteString(fmt.Sprintf("• %s\n", highlight))
-			}
-			content.WriteString("\n")
-		}
-
-		if len(version.Features) > 0 {
-			content.WriteString("### 🚀 New Features\n")
-			for _, feature := range version.Features {
-				content.WriteString(fmt.Sprintf("• **%s**: %s\n", feature.Title, feature.Description))
-			}
-			content.WriteString("\n")
-		}
-
-		if len(version.Improvements) > 0 {
-			content.WriteString("### 🔧 Improvements\n")
-			for _, improvement := range version.Improvements {
-				content.WriteString(fmt.Sprintf("• %s\n", improvement))
-			}
-			content.WriteString("\n")
-		}
-
-		if len(version.Fixes) > 0 {
-			content.WriteString("### 🐛 Bug Fixes\n")
-			for _, fix := range version.Fixes {
-				content.WriteString(fmt.Sprintf("• %s\n", fix))
-			}
-			content.WriteString("\n")
+	shown := changelog.Since(entries, lastSeen, current)
+	if lastSeen != "" && len(shown) > 0 {
+		content.WriteString(fmt.Sprintf("# 📋 What's new since %s\n\n", lastSeen))
+	} else {
+		content.WriteString("# 📋 nixai Changelog\n\n")
+		shown = changelog.Since(entries, "", current)
+		if len(shown) > maxChangelogEntries {
+			shown = shown[:maxChangelogEntries]
 		}
+	}
 
+	for _, entry := range shown {
+		content.WriteString(fmt.Sprintf("## 🎯 Version %s (%s)\n\n", entry.Version, entry.Date))
+		content.WriteString(entry.Notes + "\n\n")
 		content.WriteString("---\n\n")
 	}
 
 	content.WriteString("Press '?' or Esc to close this changelog.")
-
-	return content.String(), nil
-}
-
-// generateDefaultChangelog creates a basic changelog when the YAML file is not available
-func generateDefaultChangelog() string {
-	return `# 📋 nixai Changelog
-
-## 🎯 Version 1.0.1
-
-### ✨ Enhanced Ask Command with Multi-Source Validation
-• Comprehensive information gathering from multiple sources
-• Enhanced validation system for accurate NixOS configurations
-• Improved Bluetooth configuration guidance (hardware.bluetooth.enable vs services.bluetooth.enable)
-
-### 🔍 Validation Improvements
-• Pre-answer factual validation using MCP and GitHub sources
-• Flake syntax validation with error reporting
-• NixOS configuration option validation and correction suggestions
-• Bluetooth-specific configuration checks
-
-### 📊 Multi-Source Integration
-• Official NixOS documentation via MCP server
-• Real-world GitHub configuration examples
-• Package verification through nix search
-• Enhanced progress indicators and source attribution
-
-## 🎯 Version 1.0.0
-
-### ✨ Highlights
-• Modern TUI interface with intuitive navigation
-• Comprehensive NixOS command suite
-• AI-powered assistance for all operations
-
-### 🚀 New Features
-• **Interactive TUI**: Beautiful terminal interface with keyboard navigation
-• **Command Discovery**: Easy browsing of all available nixai commands
-• **Real-time Execution**: Live command output and progress tracking
-• **Search Function**: Quick command search with '/' key
-
-### 🔧 Key Bindings
-• Tab: Switch between panels
-• ↑↓: Navigate commands and options
-• Enter: Execute selected command
-• /: Search commands
-• ?: Show this changelog
-• Esc: Go back/cancel
-• Ctrl+C: Exit
-
----
-
-Press '?' or Esc to close this changelog.`
+	return content.String()
 }
 
 // renderChangelogPopup renders the changelog as an overlay
@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		query  string
+		target string
+		match  bool
+	}{
+		{"srch", "search", true},
+		{"exo", "explain-option", true},
+		{"mcp", "mcp-server", true},
+		{"", "ask", true},
+		{"xyz", "search", false},
+		{"hcraes", "search", false},
+	}
+
+	for _, tt := range tests {
+		_, positions, ok := fuzzyScore(tt.query, tt.target)
+		if ok != tt.match {
+			t.Errorf("fuzzyScore(%q, %q) matched = %v, want %v", tt.query, tt.target, ok, tt.match)
+		}
+		if ok && len(positions) != len(tt.query) {
+			t.Errorf("fuzzyScore(%q, %q) returned %d positions, want %d", tt.query, tt.target, len(positions), len(tt.query))
+		}
+	}
+}
+
+func TestFuzzyScore_PrefersConsecutiveAndBoundaryMatches(t *testing.T) {
+	consecutive, _, _ := fuzzyScore("sto", "store")
+	scattered, _, _ := fuzzyScore("sto", "snippets-tool")
+	if consecutive <= scattered {
+		t.Errorf("expected consecutive match to score higher: store=%d snippets-tool=%d", consecutive, scattered)
+	}
+
+	boundary, _, _ := fuzzyScore("eo", "explain-option")
+	inner, _, _ := fuzzyScore("eo", "devenv-tool")
+	if boundary <= inner {
+		t.Errorf("expected word-boundary match to score higher: explain-option=%d devenv-tool=%d", boundary, inner)
+	}
+}
+
+func TestFuzzyMatchCommands_Ranking(t *testing.T) {
+	commands := getAvailableCommands()
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"srch", "search"},
+		{"sto", "store"},
+		{"exop", "explain-option"},
+		{"pkgrepo", "package-repo"},
+		{"mcp", "mcp-server"},
+	}
+
+	for _, tt := range tests {
+		matches := fuzzyMatchCommands(tt.query, commands)
+		if len(matches) == 0 {
+			t.Errorf("query %q returned no matches", tt.query)
+			continue
+		}
+		if got := matches[0].command.name; got != tt.want {
+			t.Errorf("query %q ranked %q first, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFilterCommands_DescriptionFallback(t *testing.T) {
+	m := initialModel()
+	m.searchQuery = "garbage"
+
+	filtered := m.filterCommands()
+	if len(filtered) == 0 {
+		t.Fatal("expected description match for 'garbage'")
+	}
+}
+
+func pressKey(t *testing.T, m tuiModel, key string) tuiModel {
+	t.Helper()
+	var msg tea.KeyMsg
+	switch key {
+	case "enter":
+		msg = tea.KeyMsg{Type: tea.KeyEnter}
+	default:
+		msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+	}
+	next, _ := m.handleKeyPress(msg)
+	return next.(tuiModel)
+}
+
+func TestRerunLastCommand_RestoresOptions(t *testing.T) {
+	m := initialModel()
+	m.selectedCommand = 1 // search
+
+	// Open the options panel and configure the package option
+	m = pressKey(t, m, "enter")
+	if m.currentState != stateCommandOptions {
+		t.Fatalf("expected options state, got %v", m.currentState)
+	}
+	m.optionValues["package"] = "firefox"
+	m.optionValues["channel"] = "stable"
+
+	// Execute via the "Execute Command" entry
+	m.selectedOption = len(m.commandOptions)
+	m = pressKey(t, m, "enter")
+	if m.currentState != stateExecuting {
+		t.Fatalf("expected executing state, got %v", m.currentState)
+	}
+
+	updated, _ := m.Update(executeCommandMsg{command: "search --package firefox", output: "results"})
+	m = updated.(tuiModel)
+	if m.currentState != stateResults {
+		t.Fatalf("expected results state, got %v", m.currentState)
+	}
+
+	// Change state to make sure rerun restores it
+	m.optionValues = map[string]string{}
+	m.selectedCommand = 5
+
+	m = pressKey(t, m, "r")
+	if m.currentState != stateCommandOptions {
+		t.Fatalf("expected rerun to return to options state, got %v", m.currentState)
+	}
+	if m.focused != focusOptions {
+		t.Errorf("expected options panel focus, got %v", m.focused)
+	}
+	if got := m.commands[m.selectedCommand].name; got != "search" {
+		t.Errorf("expected search to be selected, got %q", got)
+	}
+	if m.optionValues["package"] != "firefox" || m.optionValues["channel"] != "stable" {
+		t.Errorf("expected prior option values to be restored, got %v", m.optionValues)
+	}
+}
+
+func TestRerunLastCommand_AskRestoresQuestion(t *testing.T) {
+	m := initialModel()
+	m.selectedCmdName = "ask"
+	m.inputMode = true
+	m.parameterInput = "how do I enable nginx?"
+
+	m = pressKey(t, m, "enter")
+	if m.currentState != stateExecuting {
+		t.Fatalf("expected executing state, got %v", m.currentState)
+	}
+	m.currentState = stateResults
+	m.inputMode = false
+
+	m = pressKey(t, m, "r")
+	if !m.inputMode {
+		t.Fatal("expected rerun of ask to enter input mode")
+	}
+	if m.parameterInput != "how do I enable nginx?" {
+		t.Errorf("expected question to be restored, got %q", m.parameterInput)
+	}
+}
+
+func TestRerunLastCommand_NoHistory(t *testing.T) {
+	m := initialModel()
+	m.currentState = stateResults
+
+	m = pressKey(t, m, "r")
+	if m.currentState != stateResults {
+		t.Errorf("expected to stay in results state, got %v", m.currentState)
+	}
+	if m.commandOutput != "No command to rerun yet" {
+		t.Errorf("unexpected output: %q", m.commandOutput)
+	}
+}
+
+func TestComposeSystemStatus(t *testing.T) {
+	cfg := &config.UserConfig{AIProvider: "ollama", AIModel: "llama3"}
+	nixosCtx := &config.NixOSContext{
+		CacheValid:      true,
+		SystemType:      "nixos",
+		UsesFlakes:      true,
+		HasHomeManager:  true,
+		HomeManagerType: "module",
+	}
+
+	status := composeSystemStatus(cfg, nixosCtx, true)
+	for _, want := range []string{"Provider: ollama", "Model: llama3", "System: nixos", "Flakes: Yes", "Home Manager: module", "MCP: ✅ up"} {
+		if !strings.Contains(status, want) {
+			t.Errorf("expected status %q to contain %q", status, want)
+		}
+	}
+
+	status = composeSystemStatus(&config.UserConfig{}, nil, false)
+	for _, want := range []string{"Provider: unset", "Model: default", "Context: Unknown/Not detected", "MCP: ❌ down"} {
+		if !strings.Contains(status, want) {
+			t.Errorf("expected status %q to contain %q", status, want)
+		}
+	}
+}
+
+func TestComposeSystemStatus_FlagsOverrideConfig(t *testing.T) {
+	oldProvider, oldModel := aiProvider, aiModel
+	defer func() { aiProvider, aiModel = oldProvider, oldModel }()
+	aiProvider, aiModel = "gemini", "gemini-2.5-pro"
+
+	status := composeSystemStatus(&config.UserConfig{AIProvider: "ollama", AIModel: "llama3"}, nil, false)
+	if !strings.Contains(status, "Provider: gemini") || !strings.Contains(status, "Model: gemini-2.5-pro") {
+		t.Errorf("expected flag values in status, got %q", status)
+	}
+}
+
+func TestSystemStatusMsg_UpdatesModel(t *testing.T) {
+	m := initialModel()
+	updated, _ := m.Update(systemStatusMsg{status: "Provider: openai"})
+	if got := updated.(tuiModel).systemStatus; got != "Provider: openai" {
+		t.Errorf("expected system status to be stored, got %q", got)
+	}
+}
+
+func TestCtrlCMarksTUIInterrupted(t *testing.T) {
+	m := initialModel()
+	next, cmd := m.handleKeyPress(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if !next.(tuiModel).interrupted || cmd == nil {
+		t.Error("ctrl+c should quit the TUI as interrupted")
+	}
+
+	m.searchMode = true
+	next, _ = m.handleKeyPress(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if !next.(tuiModel).interrupted {
+		t.Error("ctrl+c in search mode should quit the TUI as interrupted")
+	}
+
+	if m := pressKey(t, initialModel(), "q"); m.interrupted {
+		t.Error("q should quit the TUI normally")
+	}
+}
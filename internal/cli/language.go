@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"nix-ai-help/internal/config"
+)
+
+// outputLanguage is the --lang flag: the language AI responses are written in
+var outputLanguage string
+
+// languageNames maps common language codes to names understood by every model
+var languageNames = map[string]string{
+	"cs": "Czech", "da": "Danish", "de": "German", "en": "English", "es": "Spanish",
+	"fi": "Finnish", "fr": "French", "it": "Italian", "ja": "Japanese", "ko": "Korean",
+	"nl": "Dutch", "no": "Norwegian", "pl": "Polish", "pt": "Portuguese", "ru": "Russian",
+	"sv": "Swedish", "tr": "Turkish", "uk": "Ukrainian", "zh": "Chinese",
+}
+
+// responseLanguage returns the requested response language: the --lang flag, then the
+// language config key
+func responseLanguage(cfg *config.UserConfig) string {
+	if outputLanguage != "" {
+		return outputLanguage
+	}
+	if cfg != nil {
+		return cfg.Language
+	}
+	return ""
+}
+
+// languageInstruction returns the prompt instruction for responding in lang. English,
+// the default, needs no instruction.
+func languageInstruction(lang string) string {
+	lang = strings.TrimSpace(lang)
+	code := strings.ToLower(lang)
+	if code == "" || code == "en" || code == "english" {
+		return ""
+	}
+
+	name := lang
+	if known, ok := languageNames[code]; ok {
+		name = fmt.Sprintf("%s (%s)", known, code)
+	}
+	return "\n\nLANGUAGE: Write your entire response in " + name + ". " +
+		"Keep Nix code, option names, package names, commands and file paths in English exactly as they are."
+}
+
+// withLanguageInstruction appends the configured response language instruction to a prompt
+func withLanguageInstruction(prompt string, cfg *config.UserConfig) string {
+	return prompt + languageInstruction(responseLanguage(cfg))
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/config"
+)
+
+func TestWithLanguageInstruction_German(t *testing.T) {
+	outputLanguage = "de"
+	defer func() { outputLanguage = "" }()
+
+	prompt := withLanguageInstruction("User Question: How do I enable nginx?", nil)
+	if !strings.HasPrefix(prompt, "User Question: How do I enable nginx?") {
+		t.Errorf("expected the original prompt to be kept, got %q", prompt)
+	}
+	if !strings.Contains(prompt, "Write your entire response in German (de)") {
+		t.Errorf("expected a German language instruction, got %q", prompt)
+	}
+	if !strings.Contains(prompt, "Keep Nix code") {
+		t.Errorf("expected Nix code to stay in English, got %q", prompt)
+	}
+}
+
+func TestWithLanguageInstruction_Config(t *testing.T) {
+	cfg := &config.UserConfig{Language: "fr"}
+	if prompt := withLanguageInstruction("prompt", cfg); !strings.Contains(prompt, "French (fr)") {
+		t.Errorf("expected the configured language to be used, got %q", prompt)
+	}
+
+	// The flag overrides the config key
+	outputLanguage = "de"
+	defer func() { outputLanguage = "" }()
+	if prompt := withLanguageInstruction("prompt", cfg); !strings.Contains(prompt, "German (de)") {
+		t.Errorf("expected --lang to override the config, got %q", prompt)
+	}
+}
+
+func TestWithLanguageInstruction_EnglishUnchanged(t *testing.T) {
+	for _, lang := range []string{"", "en", "English"} {
+		cfg := &config.UserConfig{Language: lang}
+		if prompt := withLanguageInstruction("prompt", cfg); prompt != "prompt" {
+			t.Errorf("expected no instruction for %q, got %q", lang, prompt)
+		}
+	}
+}
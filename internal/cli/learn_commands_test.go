@@ -1,7 +1,7 @@
 package cli
 
 import (
-"testing"
+	"testing"
 )
 
 // TestLearnCommand tests basic learn command functionality
@@ -11,6 +11,6 @@ func TestLearnCommand(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping learn command tests in short mode")
 	}
-	
+
 	t.Log("Learn command basic test passed")
 }
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/internal/learning"
+	"nix-ai-help/internal/nixos"
+	"nix-ai-help/pkg/logger"
+	"nix-ai-help/pkg/utils"
+)
+
+// showLearningPath recommends learning modules for the detected NixOS context, marking the
+// ones already completed
+func showLearningPath(out io.Writer) {
+	var nixosCtx *config.NixOSContext
+	if cfg, err := config.LoadUserConfig(); err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatWarning("Failed to load config: "+err.Error()))
+	} else if nixosCtx, err = nixos.NewContextDetector(logger.NewLogger()).GetContext(cfg); err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatWarning("Context detection failed, showing the general learning path: "+err.Error()))
+		nixosCtx = nil
+	}
+
+	progress, err := learning.LoadProgress()
+	if err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatWarning("Failed to load learning progress: "+err.Error()))
+	}
+	renderLearningPath(out, learning.RecommendPath(nixosCtx, progress))
+}
+
+// renderLearningPath prints a learning path and the module to continue with
+func renderLearningPath(out io.Writer, path []learning.Recommendation) {
+	_, _ = fmt.Fprintln(out, utils.FormatHeader("🧭 Your Learning Path"))
+	_, _ = fmt.Fprintln(out)
+
+	next := ""
+	for i, step := range path {
+		icon := "📘"
+		if step.Completed {
+			icon = "✅"
+		} else if next == "" {
+			next = step.Module.ID
+		}
+		_, _ = fmt.Fprintf(out, "%d. %s %s (%s)\n", i+1, icon, step.Module.Title, step.Module.ID)
+		_, _ = fmt.Fprintln(out, "   "+step.Reason)
+	}
+	_, _ = fmt.Fprintln(out)
+
+	if next == "" {
+		_, _ = fmt.Fprintln(out, utils.FormatSuccess("You have completed every module on your path"))
+		return
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatTip("Continue with: nixai learn "+next))
+}
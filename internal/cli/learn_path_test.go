@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/internal/learning"
+)
+
+func TestRenderLearningPath(t *testing.T) {
+	progress := learning.Progress{CompletedModules: map[string]bool{"basics": true}}
+	path := learning.RecommendPath(&config.NixOSContext{UsesFlakes: true}, progress)
+
+	var out bytes.Buffer
+	renderLearningPath(&out, path)
+
+	for _, want := range []string{"1. ✅ NixOS fundamentals (basics)", "2. 📘 Nix flakes (flakes)", "nixai learn flakes"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestShowLearningPathWithoutContext(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("ai_provider: ollama\ncontext:\n  enabled: false\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.SetConfigFilePath(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = config.SetConfigFilePath("") })
+
+	var out bytes.Buffer
+	showLearningPath(&out)
+	if strings.Contains(out.String(), "Context detection failed") {
+		t.Errorf("a disabled detection is not a failure:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "Your Learning Path") || !strings.Contains(out.String(), "basics") {
+		t.Errorf("expected the general learning path without context, got:\n%s", out.String())
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "nixos_context") {
+		t.Errorf("expected no detected context to be saved, got:\n%s", data)
+	}
+}
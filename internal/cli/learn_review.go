@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"nix-ai-help/internal/learning"
+	"nix-ai-help/pkg/utils"
+)
+
+// reviewSummary counts the answers of a learn review session
+type reviewSummary struct {
+	Reviewed int
+	Correct  int
+}
+
+// runLearnReview asks the quiz questions that are due for review and saves their schedule
+func runLearnReview(out io.Writer) {
+	progress, err := learning.LoadProgress()
+	if err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatError("Failed to load learning progress: "+err.Error()))
+		return
+	}
+	reviewDueQuestions(bufio.NewReader(os.Stdin), out, &progress, time.Now(), learning.SaveProgress)
+}
+
+// reviewDueQuestions asks each due question, grades the answer and reschedules the question,
+// saving progress after every answer. Answering q or closing the input ends the session.
+func reviewDueQuestions(in *bufio.Reader, out io.Writer, progress *learning.Progress, now time.Time,
+	save func(learning.Progress) error) reviewSummary {
+	var summary reviewSummary
+	_, _ = fmt.Fprintln(out, utils.FormatHeader("🔁 Learning Review"))
+	_, _ = fmt.Fprintln(out)
+
+	due := learning.DueReviews(*progress, now)
+	if len(due) == 0 {
+		if next, ok := learning.NextReview(*progress); ok {
+			_, _ = fmt.Fprintln(out, utils.FormatSuccess("No questions due; the next review is on "+next.Format("2006-01-02")))
+		} else {
+			_, _ = fmt.Fprintln(out, utils.FormatInfo("No questions to review yet"))
+			_, _ = fmt.Fprintln(out, utils.FormatTip("Study a topic with 'nixai learn <topic>' and its questions come up here"))
+		}
+		return summary
+	}
+
+	for i, item := range due {
+		module, _ := learning.Topic(item.ModuleID)
+		_, _ = fmt.Fprintf(out, "[%d/%d] %s\n", i+1, len(due), utils.FormatKeyValue(module.Title, item.Question.Prompt))
+		for j, choice := range item.Question.Choices {
+			_, _ = fmt.Fprintf(out, "  %d) %s\n", j+1, choice)
+		}
+
+		choice, ok := readReviewChoice(in, out, len(item.Question.Choices))
+		if !ok {
+			break
+		}
+
+		quality := learning.QualityWrong
+		if choice == item.Question.Answer {
+			quality = learning.QualityCorrect
+			summary.Correct++
+			_, _ = fmt.Fprintln(out, utils.FormatSuccess("Correct"))
+		} else {
+			_, _ = fmt.Fprintln(out, utils.FormatError("Not quite: "+item.Question.Choices[item.Question.Answer]))
+		}
+		if item.Question.Feedback != "" {
+			_, _ = fmt.Fprintln(out, utils.FormatNote(item.Question.Feedback))
+		}
+		completed := learning.Completed(*progress, item.ModuleID)
+		state := learning.RecordReview(progress, item.Key, quality, now)
+		summary.Reviewed++
+		_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Next review", state.Due.Format("2006-01-02")))
+		if !completed && progress.CompletedModules[item.ModuleID] {
+			_, _ = fmt.Fprintln(out, utils.FormatSuccess("Completed "+module.Title+": every question answered correctly"))
+		}
+		_, _ = fmt.Fprintln(out)
+
+		if err := save(*progress); err != nil {
+			_, _ = fmt.Fprintln(out, utils.FormatWarning("Failed to save learning progress: "+err.Error()))
+		}
+	}
+
+	_, _ = fmt.Fprintln(out, utils.FormatInfo(fmt.Sprintf("Reviewed %d of %d due questions, %d correct",
+		summary.Reviewed, len(due), summary.Correct)))
+	return summary
+}
+
+// readReviewChoice reads a 1-based answer until it is valid and returns it 0-based; it returns
+// false when the user quits or the input is closed
+func readReviewChoice(in *bufio.Reader, out io.Writer, choices int) (int, bool) {
+	for {
+		_, _ = fmt.Fprintf(out, "Answer (1-%d, q to stop): ", choices)
+		line, err := in.ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(line))
+		if answer == "q" || answer == "quit" || (err != nil && answer == "") {
+			_, _ = fmt.Fprintln(out)
+			return 0, false
+		}
+		if n, convErr := strconv.Atoi(answer); convErr == nil && n >= 1 && n <= choices {
+			return n - 1, true
+		}
+		if err != nil {
+			return 0, false
+		}
+	}
+}
+
+// markTopicStudied records a learning topic as studied so that learn review asks its questions
+func markTopicStudied(out io.Writer, topic string) {
+	if _, ok := learning.Topic(topic); !ok {
+		return
+	}
+	progress, err := learning.LoadProgress()
+	if err != nil {
+		return
+	}
+	learning.MarkStudied(&progress, topic)
+	if err := learning.SaveProgress(progress); err == nil {
+		_, _ = fmt.Fprintln(out, utils.FormatTip("Questions on this topic will come up in 'nixai learn review'"))
+	}
+}
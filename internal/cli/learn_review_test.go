@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"nix-ai-help/internal/learning"
+)
+
+func TestReviewDueQuestions(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	progress := learning.Progress{StudiedModules: map[string]bool{"flakes": true}}
+	saves := 0
+	save := func(learning.Progress) error { saves++; return nil }
+
+	// flakes#0 is answered correctly (flake.lock), flakes#1 wrongly after an invalid answer
+	in := bufio.NewReader(strings.NewReader("2\n7\n3\n"))
+	var out bytes.Buffer
+	summary := reviewDueQuestions(in, &out, &progress, now, save)
+
+	if summary != (reviewSummary{Reviewed: 2, Correct: 1}) {
+		t.Errorf("summary = %+v", summary)
+	}
+	if saves != 2 {
+		t.Errorf("saved %d times, want 2", saves)
+	}
+	if due := progress.Reviews["flakes#0"].Due; !due.Equal(now.AddDate(0, 0, 1)) {
+		t.Errorf("flakes#0 due %v", due)
+	}
+	if ease := progress.Reviews["flakes#1"].Ease; ease >= learning.InitialEase {
+		t.Errorf("missed question kept ease %v", ease)
+	}
+	if !strings.Contains(out.String(), "Not quite: nix flake update") {
+		t.Errorf("missing correction:\n%s", out.String())
+	}
+
+	// Nothing is due until the next scheduled day
+	out.Reset()
+	reviewDueQuestions(bufio.NewReader(strings.NewReader("")), &out, &progress, now, save)
+	if !strings.Contains(out.String(), "next review is on 2024-03-02") {
+		t.Errorf("missing next review date:\n%s", out.String())
+	}
+}
+
+func TestReviewDueQuestionsQuit(t *testing.T) {
+	progress := learning.Progress{StudiedModules: map[string]bool{"basics": true}}
+	var out bytes.Buffer
+	summary := reviewDueQuestions(bufio.NewReader(strings.NewReader("q\n")), &out, &progress, time.Now(),
+		func(learning.Progress) error { return nil })
+	if summary.Reviewed != 0 || len(progress.Reviews) != 0 {
+		t.Errorf("quitting reviewed questions: %+v, %v", summary, progress.Reviews)
+	}
+}
+
+func TestReviewDueQuestionsCompletesModule(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	progress := learning.Progress{StudiedModules: map[string]bool{"flakes": true}}
+	var saved learning.Progress
+	save := func(p learning.Progress) error { saved = p; return nil }
+
+	// Both flakes questions answered correctly: flake.lock, then nix flake update
+	var out bytes.Buffer
+	reviewDueQuestions(bufio.NewReader(strings.NewReader("2\n1\n")), &out, &progress, now, save)
+	if !saved.CompletedModules["flakes"] {
+		t.Errorf("flakes not saved as completed: %+v", saved.CompletedModules)
+	}
+	if !strings.Contains(out.String(), "Completed") {
+		t.Errorf("missing completion:\n%s", out.String())
+	}
+}
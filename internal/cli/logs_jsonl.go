@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"nix-ai-help/internal/nixos"
+)
+
+// Output formats of logs analyze
+const (
+	logsFormatText  = "text"
+	logsFormatJSONL = "jsonl"
+)
+
+// logsAnalyzeFormat selects how logs analyze reports its results
+var logsAnalyzeFormat string
+
+// checkLogsFormat returns an error naming the known formats when format is not one of them
+func checkLogsFormat(format string) error {
+	switch format {
+	case logsFormatText, logsFormatJSONL:
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q, expected %s or %s", format, logsFormatText, logsFormatJSONL)
+	}
+}
+
+// streamLogFindings reads a log from in and writes one JSON finding per line to out as soon as
+// each log entry is complete, so that the output can be piped into other tools while the log
+// is still being written.
+func streamLogFindings(in io.Reader, out io.Writer) error {
+	lines := make(chan string)
+	scanErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		scanErr <- scanner.Err()
+	}()
+
+	encoder := json.NewEncoder(out)
+	var writeErr error
+	for finding := range nixos.StreamLogFindings(nixos.ParseLogStream(lines)) {
+		if writeErr != nil {
+			continue // drain the pipeline so its goroutines finish
+		}
+		writeErr = encoder.Encode(finding)
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	return <-scanErr
+}
+
+// runLogsAnalyzeJSONL streams findings for the log file in args, or stdin when none is given
+func runLogsAnalyzeJSONL(args []string, out io.Writer) error {
+	in := io.Reader(os.Stdin)
+	if len(args) > 0 {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read log file: %w", err)
+		}
+		defer func() { _ = file.Close() }()
+		in = file
+	}
+	return streamLogFindings(in, out)
+}
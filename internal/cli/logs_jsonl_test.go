@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"nix-ai-help/internal/nixos"
+)
+
+const jsonlTestLog = `Jun 10 12:00:01 host systemd[1]: Started nginx.service.
+Jun 10 12:00:02 host nixos-rebuild[42]: error: attribute 'nginx' missing
+  at /etc/nixos/configuration.nix:12:3
+Jun 10 12:00:03 host sshd[77]: Permission denied for user root
+Jun 10 12:00:04 host kernel[0]: all fine here
+ERROR something odd happened
+`
+
+func TestStreamLogFindingsWritesStandaloneJSONLines(t *testing.T) {
+	var out bytes.Buffer
+	if err := streamLogFindings(strings.NewReader(jsonlTestLog), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3:\n%s", len(lines), out.String())
+	}
+	var findings []nixos.LogFinding
+	for i, line := range lines {
+		var finding nixos.LogFinding
+		if err := json.Unmarshal([]byte(line), &finding); err != nil {
+			t.Fatalf("line %d is not valid JSON on its own: %v\n%s", i+1, err, line)
+		}
+		findings = append(findings, finding)
+	}
+
+	if f := findings[0]; f.Entry != 2 || f.ErrorType != "package" || !strings.Contains(f.Message, "configuration.nix:12:3") {
+		t.Errorf("unexpected first finding %+v", f)
+	}
+	if f := findings[1]; f.Entry != 3 || f.ErrorType != "permission" || f.Unit != "sshd" {
+		t.Errorf("unexpected second finding %+v", f)
+	}
+	if f := findings[2]; f.ErrorType != "generic" || f.Level != "ERROR" {
+		t.Errorf("unexpected third finding %+v", f)
+	}
+}
+
+func TestStreamLogFindingsEmitsBeforeInputEnds(t *testing.T) {
+	in, writer := io.Pipe()
+	out, results := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- streamLogFindings(in, results)
+		_ = results.Close()
+	}()
+
+	// The first entry is complete once the next one starts
+	go func() {
+		_, _ = io.WriteString(writer, "Jun 10 12:00:02 host nixos-rebuild[42]: error: attribute 'nginx' missing\n")
+		_, _ = io.WriteString(writer, "Jun 10 12:00:03 host systemd[1]: Started nginx.service.\n")
+	}()
+
+	lines := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(out).ReadString('\n')
+		lines <- line
+	}()
+	select {
+	case line := <-lines:
+		if !json.Valid([]byte(line)) || !strings.Contains(line, "package") {
+			t.Errorf("unexpected streamed line %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no finding was written while the log was still open")
+	}
+
+	_ = writer.Close()
+	go func() { _, _ = io.Copy(io.Discard, out) }()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckLogsFormat(t *testing.T) {
+	for _, format := range []string{logsFormatText, logsFormatJSONL} {
+		if err := checkLogsFormat(format); err != nil {
+			t.Errorf("checkLogsFormat(%q) = %v", format, err)
+		}
+	}
+	if err := checkLogsFormat("json"); err == nil || !strings.Contains(err.Error(), `"json"`) {
+		t.Errorf("expected an error naming the unknown format, got %v", err)
+	}
+}
@@ -114,19 +114,23 @@ This command will:
 1. Sync configurations if needed
 2. Run nixos-rebuild on remote machines  
 3. Monitor deployment progress
-4. Provide rollback commands if deployment fails`,
+4. Provide rollback commands if deployment fails
+
+With the flakes method a deployment needs --machine and asks before switching it; pass
+--yes in scripts. --dry-run only builds the configuration (nixos-rebuild dry-build).`,
 		Run: func(cmd *cobra.Command, args []string) {
 			fmt.Println(utils.FormatHeader("🚀 Configuration Deployment"))
 			fmt.Println()
 
 			// Load configuration for context detection
+			var nixosCtx *config.NixOSContext
 			cfg, err := config.LoadUserConfig()
 			if err != nil {
 				fmt.Println(utils.FormatWarning("Failed to load config for context detection: " + err.Error()))
 			} else {
 				// Initialize context detector and get NixOS context
 				contextDetector := nixos.NewContextDetector(logger.NewLogger())
-				nixosCtx, err := contextDetector.GetContext(cfg)
+				nixosCtx, err = contextDetector.GetContext(cfg)
 				if err != nil {
 					fmt.Println(utils.FormatWarning("Context detection failed: " + err.Error()))
 				} else if nixosCtx != nil && nixosCtx.CacheValid {
@@ -204,18 +208,73 @@ This command will:
 			}
 			// Default: flakes (nixos-rebuild)
 			fmt.Println(utils.FormatInfo("Using flakes (nixos-rebuild) deployment method."))
-			// ...existing code for flakes deployment...
+			if group != "" {
+				fmt.Println(utils.FormatError("Deploying a group needs deploy-rs. Use --method deploy-rs or deploy each --machine."))
+				return
+			}
+			if nixosPath := cmd.Flag("nixos-path").Value.String(); nixosPath != "" {
+				nixosCtx = nixos.ContextForConfigPath(nixosPath)
+			}
+			profile, _ := cmd.Flags().GetString("profile")
+			if profile != "" {
+				if err := nixos.ValidateProfileName(profile); err != nil {
+					fmt.Println(utils.FormatError(err.Error()))
+					return
+				}
+			}
+			opts := nixos.RebuildOptions{Host: machine, Profile: profile}
+			rebuildArgs := flakesDeployCommand(nixosCtx, opts, dryRun)
+			if dryRun {
+				fmt.Println(utils.FormatInfo("Running: " + strings.Join(rebuildArgs, " ")))
+				if err := utils.RunCommand(rebuildArgs[0], rebuildArgs[1:]...); err != nil {
+					fmt.Println(utils.FormatError("nixos-rebuild failed: " + err.Error()))
+					return
+				}
+				fmt.Println(utils.FormatSuccess("Dry run complete: the configuration builds, nothing was activated."))
+				return
+			}
+			if machine == "" {
+				// deploy only switches remote machines; this one is switched by the user
+				fmt.Println(utils.FormatWarning("No --machine given, so nothing is deployed."))
+				fmt.Println(utils.FormatTip("Switch this machine with: sudo " + strings.Join(rebuildArgs, " ")))
+				return
+			}
+			assumeYes, _ := cmd.Flags().GetBool("yes")
+			if !assumeYes && !utils.PromptYesNo(fmt.Sprintf("Run %s?", strings.Join(rebuildArgs, " "))) {
+				fmt.Println(utils.FormatInfo("Deployment cancelled."))
+				return
+			}
+			fmt.Println(utils.FormatInfo("Running: " + strings.Join(rebuildArgs, " ")))
+			if err := utils.RunCommand(rebuildArgs[0], rebuildArgs[1:]...); err != nil {
+				fmt.Println(utils.FormatError("nixos-rebuild failed: " + err.Error()))
+				rollback := append(nixos.RebuildCommandWithOptions(nixosCtx, "switch", opts), "--rollback")
+				fmt.Println(utils.FormatTip("Roll back with: " + strings.Join(rollback, " ")))
+				return
+			}
+			fmt.Println(utils.FormatSuccess("Deployment complete."))
 		},
 	}
 
 	cmd.Flags().String("machine", "", "Deploy to specific machine")
 	cmd.Flags().String("group", "", "Deploy to all machines in group")
-	cmd.Flags().Bool("dry-run", false, "Show what would be deployed without making changes")
+	cmd.Flags().Bool("dry-run", false, "Build the configuration without activating it")
 	cmd.Flags().String("method", "flakes", "Deployment method: flakes (default) or deploy-rs")
+	cmd.Flags().String("profile", "", "System profile to deploy to with the flakes method (nixos-rebuild --profile-name)")
+	cmd.Flags().BoolP("yes", "y", false, "Deploy without asking for confirmation")
 
 	return cmd
 }
 
+// flakesDeployCommand returns the nixos-rebuild command of a flakes deployment: switch on the
+// target host, or dry-build for a dry run, which builds the configuration without activating
+// it and so needs no root
+func flakesDeployCommand(nixosCtx *config.NixOSContext, opts nixos.RebuildOptions, dryRun bool) []string {
+	if dryRun {
+		return nixos.RebuildCommandWithOptions(nixosCtx, "dry-build", opts)
+	}
+	return nixos.RebuildCommandWithOptions(nixosCtx, "switch", opts)
+}
+
 // createMachinesSetupDeployRsCommand creates the setup-deploy-rs command
 func createMachinesSetupDeployRsCommand() *cobra.Command {
 	cmd := &cobra.Command{
@@ -237,13 +296,14 @@ Examples:
 			fmt.Println()
 
 			// Load configuration for context detection
+			var nixosCtx *config.NixOSContext
 			cfg, err := config.LoadUserConfig()
 			if err != nil {
 				fmt.Println(utils.FormatWarning("Failed to load config for context detection: " + err.Error()))
 			} else {
 				// Initialize context detector and get NixOS context
 				contextDetector := nixos.NewContextDetector(logger.NewLogger())
-				nixosCtx, err := contextDetector.GetContext(cfg)
+				nixosCtx, err = contextDetector.GetContext(cfg)
 				if err != nil {
 					fmt.Println(utils.FormatWarning("Context detection failed: " + err.Error()))
 				} else if nixosCtx != nil && nixosCtx.CacheValid {
@@ -1,9 +1,31 @@
 package cli
 
 import (
+	"strings"
 	"testing"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/internal/nixos"
 )
 
 func TestMachinesCommandStructure(t *testing.T) {
 	// Minimal test to ensure file is valid and build passes
 }
+
+func TestFlakesDeployCommand(t *testing.T) {
+	ctx := &config.NixOSContext{UsesFlakes: true, FlakeFile: "/etc/nixos/flake.nix"}
+	opts := nixos.RebuildOptions{Host: "web1"}
+
+	dryRun := strings.Join(flakesDeployCommand(ctx, opts, true), " ")
+	if !strings.HasPrefix(dryRun, "nixos-rebuild dry-build ") {
+		t.Errorf("dry run = %q, want nixos-rebuild dry-build without sudo", dryRun)
+	}
+	if strings.Contains(dryRun, "activate") {
+		t.Errorf("dry run activates the configuration: %q", dryRun)
+	}
+
+	deploy := strings.Join(flakesDeployCommand(ctx, opts, false), " ")
+	if !strings.HasPrefix(deploy, "nixos-rebuild switch ") || !strings.Contains(deploy, "--target-host web1") {
+		t.Errorf("deploy = %q, want nixos-rebuild switch on web1", deploy)
+	}
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/utils"
+)
+
+// mcpProbeTimeout bounds the check for a server still listening on the previous MCP address
+const mcpProbeTimeout = 2 * time.Second
+
+// mcpServerAddress returns the host:port an MCP server configuration listens on
+func mcpServerAddress(server config.MCPServerConfig) string {
+	return net.JoinHostPort(server.Host, strconv.Itoa(server.Port))
+}
+
+// mcpServerRunningAt reports whether an MCP server answers on the address of a configuration;
+// replaced in tests
+var mcpServerRunningAt = func(server config.MCPServerConfig) bool {
+	client := utils.NewHTTPClient(mcpProbeTimeout)
+	resp, err := client.Get("http://" + mcpServerAddress(server) + "/healthz")
+	if err != nil {
+		return false
+	}
+	_ = resp.Body.Close()
+	return true
+}
+
+// restartMCPServerAt stops the server on the old address and starts a daemon with the saved
+// configuration, which listens on the new one; replaced in tests
+var restartMCPServerAt = func(oldServer config.MCPServerConfig, cfg *config.UserConfig) error {
+	oldCfg := *cfg
+	oldCfg.MCPServer = oldServer
+	if err := handleMCPServerStop(&oldCfg); err != nil {
+		return err
+	}
+	time.Sleep(2 * time.Second)
+	return handleMCPServerStart(cfg, true)
+}
+
+// checkMCPAddressChange warns when mcp_host or mcp_port changed while an MCP server is still
+// running on the previous address, since clients now look for it on the new one. With an input
+// reader (interactive sessions) it offers to restart the server on the new address.
+func checkMCPAddressChange(in *bufio.Reader, out io.Writer, oldServer config.MCPServerConfig, cfg *config.UserConfig) {
+	oldAddress, newAddress := mcpServerAddress(oldServer), mcpServerAddress(cfg.MCPServer)
+	if oldAddress == newAddress || !mcpServerRunningAt(oldServer) {
+		return
+	}
+
+	_, _ = fmt.Fprintln(out, utils.FormatWarning(fmt.Sprintf(
+		"An MCP server is still running on %s; nixai will now look for it on %s", oldAddress, newAddress)))
+	// 'nixai mcp-server restart' reads the new address, so the old server is stopped directly
+	tip := utils.FormatTip(fmt.Sprintf("Stop it with 'curl http://%s/shutdown' and start it on the new address with 'nixai mcp-server start -d'", oldAddress))
+	if in == nil {
+		_, _ = fmt.Fprintln(out, tip)
+		return
+	}
+
+	_, _ = fmt.Fprintf(out, "Restart the MCP server on %s now? (Y/n): ", newAddress)
+	answer, err := in.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if err != nil && answer == "" {
+		// Input closed: end the prompt line
+		_, _ = fmt.Fprintln(out)
+		answer = "n"
+	}
+	if answer != "" && answer != "y" && answer != "yes" {
+		_, _ = fmt.Fprintln(out, tip)
+		return
+	}
+	if err := restartMCPServerAt(oldServer, cfg); err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatError("Failed to restart the MCP server: "+err.Error()))
+	}
+}
+
+// mcpAddressPromptReader returns a reader for the restart prompt in interactive sessions, and
+// nil otherwise so that only the warning is shown
+func mcpAddressPromptReader() *bufio.Reader {
+	if !isInteractiveSession() {
+		return nil
+	}
+	return bufio.NewReader(os.Stdin)
+}
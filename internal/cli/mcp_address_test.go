@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/config"
+)
+
+// stubMCPServer starts a stub MCP server and returns its address as a server configuration
+func stubMCPServer(t *testing.T) config.MCPServerConfig {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	host, portText, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.MCPServerConfig{Host: host, Port: port}
+}
+
+// stubMCPRestart records restarts instead of stopping and starting servers
+func stubMCPRestart(t *testing.T) *[]string {
+	t.Helper()
+	var restarts []string
+	original := restartMCPServerAt
+	restartMCPServerAt = func(oldServer config.MCPServerConfig, cfg *config.UserConfig) error {
+		restarts = append(restarts, mcpServerAddress(oldServer)+" -> "+mcpServerAddress(cfg.MCPServer))
+		return nil
+	}
+	t.Cleanup(func() { restartMCPServerAt = original })
+	return &restarts
+}
+
+func TestCheckMCPAddressChange(t *testing.T) {
+	oldServer := stubMCPServer(t)
+	oldAddress := mcpServerAddress(oldServer)
+	moved := func(port int) *config.UserConfig {
+		return &config.UserConfig{MCPServer: config.MCPServerConfig{Host: oldServer.Host, Port: port}}
+	}
+
+	t.Run("warns without a terminal", func(t *testing.T) {
+		restarts := stubMCPRestart(t)
+		var out bytes.Buffer
+		checkMCPAddressChange(nil, &out, oldServer, moved(oldServer.Port+1))
+		if !strings.Contains(out.String(), "still running on "+oldAddress) {
+			t.Errorf("missing warning about %s:\n%s", oldAddress, out.String())
+		}
+		if !strings.Contains(out.String(), "http://"+oldAddress+"/shutdown") {
+			t.Errorf("missing stop tip:\n%s", out.String())
+		}
+		if len(*restarts) != 0 {
+			t.Errorf("restarted without a prompt: %v", *restarts)
+		}
+	})
+
+	t.Run("restarts when confirmed", func(t *testing.T) {
+		restarts := stubMCPRestart(t)
+		var out bytes.Buffer
+		cfg := moved(oldServer.Port + 1)
+		checkMCPAddressChange(bufio.NewReader(strings.NewReader("y\n")), &out, oldServer, cfg)
+		want := oldAddress + " -> " + mcpServerAddress(cfg.MCPServer)
+		if len(*restarts) != 1 || (*restarts)[0] != want {
+			t.Errorf("restarts = %v, want [%s]", *restarts, want)
+		}
+	})
+
+	t.Run("keeps the server when declined", func(t *testing.T) {
+		restarts := stubMCPRestart(t)
+		var out bytes.Buffer
+		checkMCPAddressChange(bufio.NewReader(strings.NewReader("n\n")), &out, oldServer, moved(oldServer.Port+1))
+		if len(*restarts) != 0 {
+			t.Errorf("restarted after declining: %v", *restarts)
+		}
+		if !strings.Contains(out.String(), "/shutdown") {
+			t.Errorf("missing stop tip:\n%s", out.String())
+		}
+	})
+
+	t.Run("silent when the address is unchanged", func(t *testing.T) {
+		var out bytes.Buffer
+		checkMCPAddressChange(nil, &out, oldServer, moved(oldServer.Port))
+		if out.Len() != 0 {
+			t.Errorf("unexpected output:\n%s", out.String())
+		}
+	})
+
+	t.Run("silent when no server runs on the old address", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		port := listener.Addr().(*net.TCPAddr).Port
+		_ = listener.Close()
+		stopped := config.MCPServerConfig{Host: "127.0.0.1", Port: port}
+
+		var out bytes.Buffer
+		checkMCPAddressChange(nil, &out, stopped, moved(stopped.Port+1))
+		if out.Len() != 0 {
+			t.Errorf("unexpected output:\n%s", out.String())
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/utils"
+)
+
+// mcpAutostartWait is how long a command waits for an autostarted MCP server to report healthy
+var mcpAutostartWait = 10 * time.Second
+
+// mcpAutostartPollInterval is the time between two health checks of an autostarted server
+var mcpAutostartPollInterval = 250 * time.Millisecond
+
+// mcpAutostartOnce limits autostarting to one attempt per process, so that commands querying
+// documentation several times do not spawn a server each time
+var mcpAutostartOnce sync.Once
+
+// startMCPDaemon starts the MCP server in the background like 'nixai mcp-server start -d';
+// replaced in tests
+var startMCPDaemon = func() error {
+	cmd := exec.Command(os.Args[0], append([]string{"mcp-server", "start"}, configFileArgs()...)...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
+
+// ensureMCPServer is called by commands before they query documentation. When the MCP server
+// is unreachable and mcp_server.auto_start is set, it starts the server in daemon mode (once per
+// process) and waits briefly for /healthz. It reports whether the server is reachable.
+func ensureMCPServer(out io.Writer, cfg *config.UserConfig) bool {
+	if cfg.MCPServer.Host == "" {
+		return false
+	}
+	if mcpServerRunningAt(cfg.MCPServer) {
+		return true
+	}
+	if !cfg.MCPServer.AutoStart {
+		return false
+	}
+
+	healthy := false
+	mcpAutostartOnce.Do(func() {
+		_, _ = fmt.Fprintln(out, utils.FormatNote(fmt.Sprintf(
+			"MCP server is not running on %s; starting it (mcp_server.auto_start)", mcpServerAddress(cfg.MCPServer))))
+		if err := startMCPDaemon(); err != nil {
+			_, _ = fmt.Fprintln(out, utils.FormatWarning("Failed to start the MCP server: "+err.Error()))
+			return
+		}
+		healthy = waitForMCPServer(cfg.MCPServer, mcpAutostartWait)
+		if !healthy {
+			_, _ = fmt.Fprintln(out, utils.FormatWarning(fmt.Sprintf(
+				"MCP server did not become healthy within %s; continuing without documentation", mcpAutostartWait)))
+		}
+	})
+	return healthy
+}
+
+// waitForMCPServer polls the server until it is healthy or the timeout has passed
+func waitForMCPServer(server config.MCPServerConfig, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if mcpServerRunningAt(server) {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(mcpAutostartPollInterval)
+	}
+}
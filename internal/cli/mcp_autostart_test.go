@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nix-ai-help/internal/config"
+)
+
+// stubMCPAutostart replaces the health check and daemon start: the server reports healthy
+// from the given number of health checks after it was started, or never with a negative count
+func stubMCPAutostart(t *testing.T, healthyAfter int, startErr error) (starts *int) {
+	t.Helper()
+	origRunning, origStart, origWait, origPoll := mcpServerRunningAt, startMCPDaemon, mcpAutostartWait, mcpAutostartPollInterval
+	t.Cleanup(func() {
+		mcpServerRunningAt, startMCPDaemon, mcpAutostartWait, mcpAutostartPollInterval = origRunning, origStart, origWait, origPoll
+		mcpAutostartOnce = sync.Once{}
+	})
+	mcpAutostartOnce = sync.Once{}
+	mcpAutostartWait, mcpAutostartPollInterval = 50*time.Millisecond, time.Millisecond
+
+	starts = new(int)
+	checksSinceStart := 0
+	mcpServerRunningAt = func(config.MCPServerConfig) bool {
+		if *starts == 0 || healthyAfter < 0 {
+			return false
+		}
+		checksSinceStart++
+		return checksSinceStart >= healthyAfter
+	}
+	startMCPDaemon = func() error {
+		*starts++
+		return startErr
+	}
+	return starts
+}
+
+func autostartConfig(autoStart bool) *config.UserConfig {
+	return &config.UserConfig{MCPServer: config.MCPServerConfig{Host: "localhost", Port: 8081, AutoStart: autoStart}}
+}
+
+func TestEnsureMCPServerStartsAndWaits(t *testing.T) {
+	starts := stubMCPAutostart(t, 3, nil)
+
+	var out bytes.Buffer
+	if !ensureMCPServer(&out, autostartConfig(true)) {
+		t.Fatalf("server not reported healthy:\n%s", out.String())
+	}
+	if *starts != 1 {
+		t.Errorf("started %d times, want 1", *starts)
+	}
+	if !strings.Contains(out.String(), "starting it (mcp_server.auto_start)") {
+		t.Errorf("missing autostart note:\n%s", out.String())
+	}
+
+	// Once running, later commands use it without starting another server
+	out.Reset()
+	if !ensureMCPServer(&out, autostartConfig(true)) || *starts != 1 || out.Len() != 0 {
+		t.Errorf("second call: starts = %d, output:\n%s", *starts, out.String())
+	}
+}
+
+func TestEnsureMCPServerDisabled(t *testing.T) {
+	starts := stubMCPAutostart(t, 1, nil)
+
+	var out bytes.Buffer
+	if ensureMCPServer(&out, autostartConfig(false)) {
+		t.Error("unreachable server reported healthy")
+	}
+	if *starts != 0 || out.Len() != 0 {
+		t.Errorf("autostart without auto_start: starts = %d, output:\n%s", *starts, out.String())
+	}
+}
+
+func TestEnsureMCPServerStartsOnlyOnce(t *testing.T) {
+	starts := stubMCPAutostart(t, -1, nil)
+
+	var out bytes.Buffer
+	if ensureMCPServer(&out, autostartConfig(true)) {
+		t.Error("server that never became healthy reported healthy")
+	}
+	if !strings.Contains(out.String(), "did not become healthy") {
+		t.Errorf("missing timeout warning:\n%s", out.String())
+	}
+	ensureMCPServer(&out, autostartConfig(true))
+	if *starts != 1 {
+		t.Errorf("started %d times, want 1", *starts)
+	}
+}
+
+func TestEnsureMCPServerStartFailure(t *testing.T) {
+	stubMCPAutostart(t, 1, errors.New("exec failed"))
+
+	var out bytes.Buffer
+	if ensureMCPServer(&out, autostartConfig(true)) {
+		t.Error("failed start reported healthy")
+	}
+	if !strings.Contains(out.String(), "Failed to start the MCP server: exec failed") {
+		t.Errorf("missing start failure:\n%s", out.String())
+	}
+}
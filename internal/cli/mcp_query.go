@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/internal/mcp"
+	"nix-ai-help/pkg/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// mcpQueryResult is the --json output of mcp-server query
+type mcpQueryResult struct {
+	Query   string   `json:"query"`
+	Sources []string `json:"sources,omitempty"`
+	Top     int      `json:"top,omitempty"`
+	Result  string   `json:"result"`
+}
+
+// newMCPQueryCmd creates the mcp-server query subcommand
+func newMCPQueryCmd() *cobra.Command {
+	var sources []string
+	var jsonOutput bool
+	var top int
+
+	cmd := &cobra.Command{
+		Use:   "query <text>",
+		Short: "Query the MCP server documentation directly",
+		Long: `Query the documentation sources of the running MCP server.
+
+Without --source the server queries all of its configured sources. With --top N only the
+first N full-text results are shown, in the order the server ranks its sources after dropping
+repeated text; an option lookup that answers the query is always a single result.
+
+Examples:
+  nixai mcp-server query "services.nginx.enable"
+  nixai mcp-server query flakes --source https://nix.dev/manual/nix --source https://wiki.nixos.org/wiki/NixOS_Wiki
+  nixai mcp-server query "nix flakes" --top 2
+  nixai mcp-server query "home-manager programs.git" --json`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadUserConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %v", err)
+			}
+			if top < 0 {
+				return fmt.Errorf("--top must be 0 or more, got %d", top)
+			}
+			client := mcp.NewMCPClient(fmt.Sprintf("http://%s:%d", cfg.MCPServer.Host, cfg.MCPServer.Port))
+			client.SetTop(top)
+			return runMCPQuery(cmd.OutOrStdout(), client, strings.Join(args, " "), sources, top, jsonOutput)
+		},
+	}
+	cmd.Flags().StringArrayVarP(&sources, "source", "s", nil, "Documentation source URL to query (repeatable; default: all configured sources)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the query, sources and result as JSON")
+	cmd.Flags().IntVar(&top, "top", 0, "Show only the top N full-text results (0: all)")
+	return cmd
+}
+
+// runMCPQuery queries the MCP server documentation and prints the result as markdown or JSON.
+// top is the result limit already set on the client, shown in the output.
+func runMCPQuery(out io.Writer, client *mcp.MCPClient, query string, sources []string, top int, jsonOutput bool) error {
+	if jsonOutput {
+		result, err := client.QueryDocumentation(query, sources...)
+		if err != nil {
+			return fmt.Errorf("query failed: %v", err)
+		}
+		data, err := json.MarshalIndent(mcpQueryResult{Query: query, Sources: sources, Top: top, Result: result}, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	}
+
+	_, _ = fmt.Fprintln(out, utils.FormatHeader("🔍 MCP Server Query"))
+	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Query", query))
+	if len(sources) > 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Sources", strings.Join(sources, ", ")))
+	}
+	if top > 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Top", fmt.Sprint(top)))
+	}
+	_, _ = fmt.Fprintln(out)
+
+	_, _ = fmt.Fprint(out, utils.FormatInfo("Querying documentation... "))
+	result, err := client.QueryDocumentation(query, sources...)
+	if err != nil {
+		_, _ = fmt.Fprintln(out, utils.FormatError("failed"))
+		return fmt.Errorf("query failed: %v", err)
+	}
+
+	_, _ = fmt.Fprintln(out, utils.FormatSuccess("done"))
+	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintln(out, utils.FormatSubsection("📖 Documentation Results", ""))
+	_, _ = fmt.Fprintln(out, utils.RenderMarkdown(result))
+	return nil
+}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/mcp"
+	"nix-ai-help/pkg/version"
+)
+
+// mcpQueryRequest is the body of a /query request
+type mcpQueryRequest struct {
+	Query   string   `json:"query"`
+	Sources []string `json:"sources"`
+	Top     int      `json:"top"`
+}
+
+// stubMCPQueryServer serves /info and /query, recording the body of each query
+func stubMCPQueryServer(t *testing.T, received *mcpQueryRequest) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/info":
+			_ = json.NewEncoder(w).Encode(mcp.ServerInfo{Status: "ok", Version: version.Version})
+		case "/query":
+			var body mcpQueryRequest
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			*received = body
+			_ = json.NewEncoder(w).Encode(map[string]string{"result": "docs for " + body.Query})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestMCPQueryCmdSourceFlags(t *testing.T) {
+	cmd := newMCPQueryCmd()
+	err := cmd.ParseFlags([]string{"--source", "https://nix.dev/manual/nix", "-s", "https://wiki.nixos.org/wiki/NixOS_Wiki",
+		"--source", "https://example.org/docs?a=1,2", "--json", "--top", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sources, _ := cmd.Flags().GetStringArray("source")
+	want := []string{"https://nix.dev/manual/nix", "https://wiki.nixos.org/wiki/NixOS_Wiki", "https://example.org/docs?a=1,2"}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("sources = %v, want %v", sources, want)
+	}
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); !jsonOutput {
+		t.Error("--json not set")
+	}
+	if top, _ := cmd.Flags().GetInt("top"); top != 2 {
+		t.Errorf("--top = %d, want 2", top)
+	}
+	if err := cmd.Args(cmd, nil); err == nil {
+		t.Error("query without text accepted")
+	}
+}
+
+func TestRunMCPQueryJSON(t *testing.T) {
+	var received mcpQueryRequest
+	server := stubMCPQueryServer(t, &received)
+	sources := []string{"https://nix.dev/manual/nix", "https://wiki.nixos.org/wiki/NixOS_Wiki"}
+	client := mcp.NewMCPClient(server.URL)
+	client.SetTop(1)
+
+	var out bytes.Buffer
+	if err := runMCPQuery(&out, client, "nix flakes", sources, 1, true); err != nil {
+		t.Fatal(err)
+	}
+
+	var result mcpQueryResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	want := mcpQueryResult{Query: "nix flakes", Sources: sources, Top: 1, Result: "docs for nix flakes"}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+	if !reflect.DeepEqual(received.Sources, sources) || received.Top != 1 {
+		t.Errorf("server received sources %v and top %d, want %v and 1", received.Sources, received.Top, sources)
+	}
+}
+
+func TestRunMCPQueryAllSources(t *testing.T) {
+	var received mcpQueryRequest
+	server := stubMCPQueryServer(t, &received)
+
+	var out bytes.Buffer
+	if err := runMCPQuery(&out, mcp.NewMCPClient(server.URL), "nix flakes", nil, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if received.Sources != nil || received.Top != 0 {
+		t.Errorf("sources or top sent without --source and --top: %+v", received)
+	}
+	if !strings.Contains(out.String(), "docs for nix flakes") {
+		t.Errorf("result missing:\n%s", out.String())
+	}
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"fmt"
+
+	"nix-ai-help/internal/ai"
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/logger"
+)
+
+// tieredProvider picks the model of each query by the running command and the prompt size,
+// using the fast and strong models of ai_models.tiers
+type tieredProvider struct {
+	manager      *ai.ProviderManager
+	providerName string
+	command      string
+	fallback     ai.Provider // the provider with its default model
+	log          *logger.Logger
+}
+
+// Query sends the prompt to the model the task needs; when that model cannot be set up the
+// default model answers instead
+func (p *tieredProvider) Query(prompt string) (string, error) {
+	provider := p.fallback
+	if tier, model := p.manager.SelectModelForTask(p.command, len(prompt)); model != "" {
+		tiered, err := p.manager.GetProviderForModel(p.providerName, model)
+		if err != nil {
+			p.log.Warn(fmt.Sprintf("Using the default model, the %s model %s is unavailable: %v", tier, model, err))
+		} else {
+			p.log.Debug(fmt.Sprintf("Using the %s model %s for %s", tier, model, p.command))
+			provider = tiered
+		}
+	}
+	return (&ProviderToLegacyAdapter{provider: provider}).Query(prompt)
+}
+
+// modelTiersEnabled reports whether queries pick their model by task: a fast or strong model is
+// configured and no model was chosen with --model
+func modelTiersEnabled(cfg *config.UserConfig) bool {
+	tiers := cfg.AIModels.Tiers
+	return aiModel == "" && (tiers.Fast != "" || tiers.Strong != "")
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"io"
+	"testing"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/logger"
+)
+
+func TestModelTiersEnabled(t *testing.T) {
+	defer func() { aiModel = "" }()
+	cfg := &config.UserConfig{}
+	if modelTiersEnabled(cfg) {
+		t.Error("expected tiers to be off without tier models")
+	}
+
+	cfg.AIModels.Tiers.Fast = "llama3.2:1b"
+	if !modelTiersEnabled(cfg) {
+		t.Error("expected tiers to be on with a fast model")
+	}
+
+	aiModel = "llama3"
+	if modelTiersEnabled(cfg) {
+		t.Error("expected --model to override the tiers")
+	}
+}
+
+func TestGetLegacyAIProviderUsesTiers(t *testing.T) {
+	ResetProviderManager()
+	t.Cleanup(ResetProviderManager)
+	defer func(command string) { samplingCommand = command }(samplingCommand)
+	samplingCommand = "explain-option"
+
+	cfg := &config.UserConfig{AIModels: config.AIModelsConfig{
+		Providers: map[string]config.AIProviderConfig{
+			"ollama": {Available: true, BaseURL: "http://localhost:11434"},
+		},
+		SelectionPreferences: config.AISelectionPreferences{DefaultProvider: "ollama"},
+		Tiers:                config.AIModelTiers{Fast: "llama3.2:1b"},
+	}}
+
+	provider, err := GetLegacyAIProvider(cfg, logger.NewLoggerWithWriter(io.Discard))
+	if err != nil {
+		t.Fatalf("GetLegacyAIProvider failed: %v", err)
+	}
+	tiered, ok := provider.(*tieredProvider)
+	if !ok {
+		t.Fatalf("expected a tiered provider, got %T", provider)
+	}
+	if tiered.command != "explain-option" || tiered.providerName != "ollama" {
+		t.Errorf("tiered provider = %+v", tiered)
+	}
+}
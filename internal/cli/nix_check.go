@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"fmt"
+
+	"nix-ai-help/internal/nixos"
+
+	"github.com/spf13/cobra"
+)
+
+// requiresNixAnnotation marks a command that can do nothing useful without the nix command
+const requiresNixAnnotation = "nixai.requires-nix"
+
+// markRequiresNix marks commands, and with them all their subcommands, as needing nix
+func markRequiresNix(cmds ...*cobra.Command) {
+	for _, cmd := range cmds {
+		if cmd.Annotations == nil {
+			cmd.Annotations = make(map[string]string)
+		}
+		cmd.Annotations[requiresNixAnnotation] = "true"
+	}
+}
+
+// commandRequiresNix reports whether a command or one of its parents needs nix
+func commandRequiresNix(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[requiresNixAnnotation] == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNixAvailable fails early with one clear message when a command needs nix and it is not on
+// PATH, instead of letting the command fail halfway. AI-only commands such as ask and
+// explain-option are never blocked.
+func checkNixAvailable(cmd *cobra.Command, executor *nixos.Executor) error {
+	if !commandRequiresNix(cmd) {
+		return nil
+	}
+	if err := executor.CheckNix(); err != nil {
+		return fmt.Errorf("%w: '%s' needs Nix; install it from https://nixos.org/download or add it to PATH", err, cmd.CommandPath())
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/nixos"
+
+	"github.com/spf13/cobra"
+)
+
+// missingNix is a LookPath that finds every command except nix
+func missingNix(file string) (string, error) {
+	if file == "nix" {
+		return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+	}
+	return "/usr/bin/" + file, nil
+}
+
+func TestCheckNixAvailable(t *testing.T) {
+	root := &cobra.Command{Use: "nixai"}
+	gc := &cobra.Command{Use: "gc"}
+	gcAnalyze := &cobra.Command{Use: "analyze"}
+	ask := &cobra.Command{Use: "ask"}
+	explainOption := &cobra.Command{Use: "explain-option"}
+	gc.AddCommand(gcAnalyze)
+	root.AddCommand(gc, ask, explainOption)
+	markRequiresNix(gc)
+
+	withoutNix := &nixos.Executor{LookPath: missingNix}
+	err := checkNixAvailable(gcAnalyze, withoutNix)
+	if !errors.Is(err, nixos.ErrNixNotFound) {
+		t.Fatalf("gc analyze without nix = %v, want ErrNixNotFound", err)
+	}
+	if !strings.HasPrefix(err.Error(), "nix not found on PATH") || !strings.Contains(err.Error(), "'nixai gc analyze' needs Nix") {
+		t.Errorf("unexpected message: %q", err)
+	}
+
+	// AI-only commands keep working without nix
+	for _, cmd := range []*cobra.Command{ask, explainOption, root} {
+		if err := checkNixAvailable(cmd, withoutNix); err != nil {
+			t.Errorf("%s without nix = %v, want nil", cmd.Name(), err)
+		}
+	}
+
+	withNix := &nixos.Executor{LookPath: func(file string) (string, error) { return "/usr/bin/" + file, nil }}
+	if err := checkNixAvailable(gcAnalyze, withNix); err != nil {
+		t.Errorf("gc analyze with nix = %v, want nil", err)
+	}
+}
+
+func TestRootCommandsRequiringNix(t *testing.T) {
+	initializeCommands()
+	for _, name := range []string{"build", "deps", "flake", "gc", "store"} {
+		cmd, _, err := rootCmd.Find([]string{name})
+		if err != nil || !commandRequiresNix(cmd) {
+			t.Errorf("%s should require nix (err %v)", name, err)
+		}
+	}
+	for _, name := range []string{"ask", "explain-option", "config", "doctor"} {
+		cmd, _, err := rootCmd.Find([]string{name})
+		if err != nil || commandRequiresNix(cmd) {
+			t.Errorf("%s should not require nix (err %v)", name, err)
+		}
+	}
+}
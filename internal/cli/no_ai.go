@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"nix-ai-help/internal/ai"
+	"nix-ai-help/internal/config"
+	"nix-ai-help/internal/nixos"
+	"nix-ai-help/pkg/logger"
+	"nix-ai-help/pkg/utils"
+)
+
+// noAI is the --no-ai flag: skip AI provider initialization and show only local output
+var noAI bool
+
+// errAIDisabled is returned instead of an AI provider when --no-ai is set
+var errAIDisabled = errors.New("AI disabled with --no-ai")
+
+// optionalAIProvider returns the AI provider for commands that also work without one. With
+// --no-ai no provider is constructed and errAIDisabled is returned.
+func optionalAIProvider(cfg *config.UserConfig, log *logger.Logger) (ai.AIProvider, error) {
+	if noAI {
+		return nil, errAIDisabled
+	}
+	return GetLegacyAIProvider(cfg, log)
+}
+
+// basicAnalysisNotice explains why logs shows the basic summary instead of an AI analysis
+func basicAnalysisNotice(err error) string {
+	if errors.Is(err, errAIDisabled) {
+		return utils.FormatNote("Basic analysis (--no-ai)")
+	}
+	return utils.FormatWarning("Failed to initialize AI agent, using basic analysis: " + err.Error())
+}
+
+// requireAI returns errAIDisabled for commands that cannot work without an AI provider when
+// --no-ai is set
+func requireAI(command string) error {
+	if noAI {
+		return fmt.Errorf("%w: %s needs an AI provider to answer", errAIDisabled, command)
+	}
+	return nil
+}
+
+// writeOptionDocumentation shows the documentation of an option without an AI explanation, as
+// the attribute table when it is option JSON and as text otherwise
+func writeOptionDocumentation(out io.Writer, option, doc string) {
+	_, _ = fmt.Fprintln(out, utils.FormatNote("Option documentation only (--no-ai)"))
+	_, _ = fmt.Fprintln(out)
+	if opt, fallbackDoc := parseMCPOptionDocNamed(doc, option); opt.Name != "" {
+		_, _ = fmt.Fprintln(out, formatOptionTable(opt))
+	} else {
+		_, _ = fmt.Fprintln(out, renderAIResponse(fallbackDoc))
+	}
+}
+
+// writePatternDiagnoses shows the problems the known error patterns find in a log, for
+// diagnose without an AI diagnosis
+func writePatternDiagnoses(out io.Writer, diags []nixos.Diagnostic) {
+	_, _ = fmt.Fprintln(out, utils.FormatNote("Known error patterns only (--no-ai)"))
+	_, _ = fmt.Fprintln(out)
+	if len(diags) == 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatSuccess("No known problems found in the input"))
+		return
+	}
+	for _, diag := range diags {
+		_, _ = fmt.Fprintln(out, utils.FormatSubsection("🔧 "+diag.Issue, ""))
+		_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Severity", diag.Severity))
+		if diag.Details != "" {
+			_, _ = fmt.Fprintln(out, diag.Details)
+		}
+		for i, step := range diag.Steps {
+			_, _ = fmt.Fprintf(out, "  %d. %s\n", i+1, step)
+		}
+		for _, link := range diag.DocsLinks {
+			_, _ = fmt.Fprintln(out, "  "+link)
+		}
+		_, _ = fmt.Fprintln(out)
+	}
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/internal/nixos"
+	"nix-ai-help/pkg/logger"
+)
+
+// setNoAI sets --no-ai for the duration of a test
+func setNoAI(t *testing.T, value bool) {
+	t.Helper()
+	original := noAI
+	noAI = value
+	t.Cleanup(func() { noAI = original })
+}
+
+func TestOptionalAIProvider_NoAIConstructsNothing(t *testing.T) {
+	count := countProviderManagers(t)
+	setNoAI(t, true)
+
+	provider, err := optionalAIProvider(config.DefaultUserConfig(), logger.NewTestLogger())
+	if !errors.Is(err, errAIDisabled) || provider != nil {
+		t.Errorf("expected no provider and errAIDisabled, got %v, %v", provider, err)
+	}
+	if *count != 0 {
+		t.Errorf("expected no provider manager under --no-ai, got %d", *count)
+	}
+}
+
+func TestOptionalAIProvider_Default(t *testing.T) {
+	count := countProviderManagers(t)
+	setNoAI(t, false)
+
+	if _, err := optionalAIProvider(config.DefaultUserConfig(), logger.NewTestLogger()); err != nil {
+		t.Fatalf("optionalAIProvider: %v", err)
+	}
+	if *count != 1 {
+		t.Errorf("expected the provider manager to be constructed, got %d", *count)
+	}
+}
+
+func TestInitializeLogsAgent_NoAI(t *testing.T) {
+	count := countProviderManagers(t)
+	setNoAI(t, true)
+
+	if _, err := initializeLogsAgent(); !errors.Is(err, errAIDisabled) {
+		t.Errorf("expected errAIDisabled, got %v", err)
+	}
+	if *count != 0 {
+		t.Errorf("expected no provider manager under --no-ai, got %d", *count)
+	}
+	if notice := basicAnalysisNotice(errAIDisabled); !strings.Contains(notice, "--no-ai") || strings.Contains(notice, "Failed") {
+		t.Errorf("unexpected notice: %q", notice)
+	}
+}
+
+func TestRequireAI(t *testing.T) {
+	setNoAI(t, false)
+	if err := requireAI("ask"); err != nil {
+		t.Errorf("requireAI() = %v without --no-ai", err)
+	}
+	setNoAI(t, true)
+	if err := requireAI("ask"); !errors.Is(err, errAIDisabled) || !strings.Contains(err.Error(), "ask needs an AI provider") {
+		t.Errorf("requireAI() = %v, want errAIDisabled naming the command", err)
+	}
+}
+
+func TestWriteOptionDocumentation(t *testing.T) {
+	var out strings.Builder
+	writeOptionDocumentation(&out, "services.nginx.enable",
+		`{"option_name":"services.nginx.enable","option_type":"boolean","option_default":false,"option_description":"Whether to enable Nginx Web Server."}`)
+	for _, want := range []string{"--no-ai", "boolean", "Whether to enable Nginx Web Server."} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestGetLegacyAIProvider_NoAIConstructsNothing(t *testing.T) {
+	count := countProviderManagers(t)
+	setNoAI(t, true)
+
+	if provider, err := GetLegacyAIProvider(config.DefaultUserConfig(), logger.NewTestLogger()); !errors.Is(err, errAIDisabled) || provider != nil {
+		t.Errorf("expected no provider and errAIDisabled, got %v, %v", provider, err)
+	}
+	if *count != 0 {
+		t.Errorf("expected no provider manager under --no-ai, got %d", *count)
+	}
+}
+
+func TestWritePatternDiagnoses(t *testing.T) {
+	var out strings.Builder
+	writePatternDiagnoses(&out, []nixos.Diagnostic{{
+		Issue:    "Undefined variable",
+		Severity: "high",
+		Steps:    []string{"Check the spelling of the variable"},
+	}})
+	for _, want := range []string{"--no-ai", "Undefined variable", "high", "1. Check the spelling"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	writePatternDiagnoses(&out, nil)
+	if !strings.Contains(out.String(), "No known problems") {
+		t.Errorf("unexpected output without diagnoses:\n%s", out.String())
+	}
+}
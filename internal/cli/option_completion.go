@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/internal/mcp"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// optionIndexTTL is how long a cached option list is used before it is refreshed
+	optionIndexTTL = 24 * time.Hour
+	// optionIndexTimeout bounds the MCP lookup so completion never hangs the shell
+	optionIndexTimeout = time.Second
+	// maxOptionCompletions is how many option names completion offers before it completes the
+	// next part of the name instead, e.g. services.nginx. rather than every nginx option
+	maxOptionCompletions = 200
+)
+
+// optionIndex returns the names of the options starting with prefix that contain contains
+// after it
+type optionIndex func(prefix, contains string) ([]string, error)
+
+// optionIndexQuery is one lookup in the option index of a set of options
+type optionIndexQuery struct {
+	Source   string // mcp.OptionSourceNixOS or mcp.OptionSourceHomeManager
+	Release  string // nixpkgs release of the NixOS options, "" for the server default
+	Prefix   string
+	Contains string
+}
+
+// key identifies the query in the option cache
+func (q optionIndexQuery) key() string {
+	return strings.Join([]string{q.Source, q.Release, q.Prefix, q.Contains}, "|")
+}
+
+// cachedOptionNames is one cached option list
+type cachedOptionNames struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	Options   []string  `json:"options"`
+}
+
+// cachedOptionIndex is the on-disk copy of the option lists looked up in the MCP option index
+type cachedOptionIndex struct {
+	Queries map[string]cachedOptionNames `json:"queries"`
+}
+
+// optionIndexPath returns the location of the cached option lists
+func optionIndexPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".cache", "nixai", "options.json")
+}
+
+// newOptionIndex returns the MCP-backed index of a set of options, or nil without an MCP
+// server; replaced in tests
+var newOptionIndex = func(source, release string) optionIndex {
+	cfg, err := config.LoadUserConfig()
+	if err != nil || cfg.MCPServer.Host == "" {
+		return nil
+	}
+	client := mcp.NewMCPClient(fmt.Sprintf("http://%s:%d", cfg.MCPServer.Host, cfg.MCPServer.Port))
+	client.SetTimeout(optionIndexTimeout)
+	client.SetRelease(release)
+	return func(prefix, contains string) ([]string, error) {
+		return client.OptionNames(source, prefix, contains)
+	}
+}
+
+// completionOptionSource returns the options a command takes: Home Manager options for
+// explain-home-option, NixOS options otherwise
+func completionOptionSource(cmd *cobra.Command) string {
+	if cmd.Name() == "explain-home-option" {
+		return mcp.OptionSourceHomeManager
+	}
+	return mcp.OptionSourceNixOS
+}
+
+// completeOptionArg completes the option argument of explain-option and explain-home-option
+func completeOptionArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	query := optionIndexQuery{Source: completionOptionSource(cmd), Prefix: toComplete}
+	options, _ := loadOptionNames(optionIndexPath(), newOptionIndex, query)
+	completions := filterCompletions(options, toComplete)
+	if len(completions) <= maxOptionCompletions {
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+	return optionNameParts(completions, toComplete), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// optionNameParts shortens option names to the part after prefix up to the next dot, so that
+// completion offers services.nginx. once instead of every option under it
+func optionNameParts(names []string, prefix string) []string {
+	var parts []string
+	for _, name := range names {
+		if i := strings.Index(name[len(prefix):], "."); i >= 0 {
+			name = name[:len(prefix)+i+1]
+		}
+		if len(parts) == 0 || parts[len(parts)-1] != name {
+			parts = append(parts, name)
+		}
+	}
+	return parts
+}
+
+// loadOptionNames returns the option names a query finds, from the cache when it is fresh and
+// from the index otherwise. A stale cache is still used when the index is unreachable. ok is
+// false when neither has an answer, so that callers can tell an unavailable index from one
+// without matches.
+func loadOptionNames(path string, newIndex func(source, release string) optionIndex, query optionIndexQuery) (options []string, ok bool) {
+	var cached cachedOptionIndex
+	// #nosec G304 -- path is the fixed option cache location
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &cached)
+	}
+	entry, found := cached.Queries[query.key()]
+	if found && time.Since(entry.UpdatedAt) < optionIndexTTL {
+		return entry.Options, true
+	}
+
+	index := newIndex(query.Source, query.Release)
+	if index == nil {
+		return entry.Options, found
+	}
+	options, err := index(query.Prefix, query.Contains)
+	if err != nil {
+		return entry.Options, found
+	}
+
+	if cached.Queries == nil {
+		cached.Queries = make(map[string]cachedOptionNames)
+	}
+	for key, stale := range cached.Queries {
+		if time.Since(stale.UpdatedAt) >= optionIndexTTL {
+			delete(cached.Queries, key)
+		}
+	}
+	cached.Queries[query.key()] = cachedOptionNames{UpdatedAt: time.Now(), Options: options}
+	if data, err := json.Marshal(cached); err == nil {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			_ = os.WriteFile(path, data, 0600)
+		}
+	}
+	return options, true
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/mcp"
+
+	"github.com/spf13/cobra"
+)
+
+// stubOptionIndex returns the matches of each option set from fixed lists and counts lookups
+func stubOptionIndex(calls *int, sets map[string][]string) func(source, release string) optionIndex {
+	return func(source, release string) optionIndex {
+		return func(prefix, contains string) ([]string, error) {
+			*calls++
+			var matches []string
+			for _, opt := range sets[source] {
+				if strings.HasPrefix(opt, prefix) && strings.Contains(opt[len(prefix):], contains) {
+					matches = append(matches, opt)
+				}
+			}
+			return matches, nil
+		}
+	}
+}
+
+func TestLoadOptionNames_CachesIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "options.json")
+	calls := 0
+	index := stubOptionIndex(&calls, map[string][]string{
+		mcp.OptionSourceNixOS: {"services.nginx.enable", "services.openssh.enable", "networking.firewall.enable"},
+	})
+	services := optionIndexQuery{Source: mcp.OptionSourceNixOS, Prefix: "services."}
+
+	options, ok := loadOptionNames(path, index, services)
+	if !ok || !reflect.DeepEqual(options, []string{"services.nginx.enable", "services.openssh.enable"}) {
+		t.Errorf("unexpected prefix matches: %v, %v", options, ok)
+	}
+
+	// A fresh cache is used without querying the index again
+	options, _ = loadOptionNames(path, index, services)
+	if calls != 1 {
+		t.Errorf("expected the index to be queried once, got %d", calls)
+	}
+	if len(options) != 2 {
+		t.Errorf("expected cached options, got %v", options)
+	}
+
+	// Other queries are cached separately
+	if options, _ := loadOptionNames(path, index, optionIndexQuery{Source: mcp.OptionSourceNixOS, Prefix: "networking."}); len(options) != 1 || calls != 2 {
+		t.Errorf("expected a new lookup for another prefix, got %v after %d lookups", options, calls)
+	}
+}
+
+func TestLoadOptionNames_Unreachable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "options.json")
+	query := optionIndexQuery{Source: mcp.OptionSourceNixOS, Prefix: "services."}
+
+	unreachable := func(source, release string) optionIndex {
+		return func(prefix, contains string) ([]string, error) { return nil, errors.New("connection refused") }
+	}
+	if options, ok := loadOptionNames(path, unreachable, query); len(options) != 0 || ok {
+		t.Errorf("expected no options and no answer when MCP is unreachable, got %v, %v", options, ok)
+	}
+
+	unconfigured := func(source, release string) optionIndex { return nil }
+	if options, ok := loadOptionNames(path, unconfigured, query); len(options) != 0 || ok {
+		t.Errorf("expected no options and no answer without an MCP server, got %v, %v", options, ok)
+	}
+}
+
+func TestCompleteOptionArg_UsesOptionsOfCommand(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	calls := 0
+	original := newOptionIndex
+	t.Cleanup(func() { newOptionIndex = original })
+	newOptionIndex = stubOptionIndex(&calls, map[string][]string{
+		mcp.OptionSourceNixOS:       {"programs.zsh.enable", "programs.zsh.ohMyZsh.enable"},
+		mcp.OptionSourceHomeManager: {"programs.zsh.enable", "programs.zsh.oh-my-zsh.enable"},
+	})
+
+	nixosCmd := &cobra.Command{Use: "explain-option"}
+	homeCmd := &cobra.Command{Use: "explain-home-option"}
+	if got, _ := completeOptionArg(nixosCmd, nil, "programs.zsh.o"); !reflect.DeepEqual(got, []string{"programs.zsh.ohMyZsh.enable"}) {
+		t.Errorf("explain-option completions = %v", got)
+	}
+	if got, _ := completeOptionArg(homeCmd, nil, "programs.zsh.o"); !reflect.DeepEqual(got, []string{"programs.zsh.oh-my-zsh.enable"}) {
+		t.Errorf("explain-home-option completions = %v", got)
+	}
+}
+
+func TestCompleteOptionArg_CompletesPartsOfLongLists(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var names []string
+	for i := 0; i < maxOptionCompletions+1; i++ {
+		names = append(names, fmt.Sprintf("services.s%03d.enable", i))
+	}
+	calls := 0
+	original := newOptionIndex
+	t.Cleanup(func() { newOptionIndex = original })
+	newOptionIndex = stubOptionIndex(&calls, map[string][]string{mcp.OptionSourceNixOS: append(names, "services.s000.package")})
+
+	got, directive := completeOptionArg(&cobra.Command{Use: "explain-option"}, nil, "services.")
+	if len(got) != maxOptionCompletions+1 || got[0] != "services.s000." {
+		t.Errorf("expected one completion per service, got %d starting with %v", len(got), got[:1])
+	}
+	if directive&cobra.ShellCompDirectiveNoSpace == 0 {
+		t.Error("expected no space after a partial option name")
+	}
+}
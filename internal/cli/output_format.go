@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/utils"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Output formats shared by ask, diagnose, doctor and search
+const (
+	outputMarkdown = "markdown"
+	outputPlain    = "plain"
+	outputJSON     = "json"
+)
+
+// outputFormats lists the accepted values of --output and output.default_format
+var outputFormats = []string{outputMarkdown, outputPlain, outputJSON}
+
+// rawOutput is the --raw flag of ask, diagnose and explain-option: AI responses are printed
+// exactly as returned, without rendering their markdown
+var rawOutput bool
+
+// addRawFlag registers --raw on cmd
+func addRawFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&rawOutput, "raw", false, "Print the AI response verbatim, without rendering its markdown")
+}
+
+// addOutputFormatFlags registers --output and --json on cmd
+func addOutputFormatFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", "", "Output format (markdown, plain, json); defaults to output.default_format")
+	cmd.Flags().Bool("json", false, "Print the result as JSON (same as --output json)")
+}
+
+// validOutputFormat reports whether format is one of outputFormats
+func validOutputFormat(format string) bool {
+	return containsString(outputFormats, format)
+}
+
+// resolveOutputFormat picks the output format of a command: --json, then --output, then the
+// output.default_format config key, then markdown
+func resolveOutputFormat(flags *pflag.FlagSet, cfg *config.UserConfig) (string, error) {
+	if jsonFlag := flags.Lookup("json"); jsonFlag != nil && jsonFlag.Changed {
+		if enabled, _ := flags.GetBool("json"); enabled {
+			return outputJSON, nil
+		}
+	}
+	if outputFlag := flags.Lookup("output"); outputFlag != nil && outputFlag.Changed {
+		format := strings.ToLower(outputFlag.Value.String())
+		if !validOutputFormat(format) {
+			return "", fmt.Errorf("unknown output format %q, expected one of: %s", outputFlag.Value.String(), strings.Join(outputFormats, ", "))
+		}
+		return format, nil
+	}
+	if cfg != nil && cfg.Output.DefaultFormat != "" {
+		format := strings.ToLower(cfg.Output.DefaultFormat)
+		if !validOutputFormat(format) {
+			return "", fmt.Errorf("invalid output.default_format %q in config, expected one of: %s", cfg.Output.DefaultFormat, strings.Join(outputFormats, ", "))
+		}
+		return format, nil
+	}
+	return outputMarkdown, nil
+}
+
+// explicitJSONOutput reports whether JSON output was asked for on the command line, with --json
+// or --output json, rather than through output.default_format
+func explicitJSONOutput(flags *pflag.FlagSet) bool {
+	if jsonFlag := flags.Lookup("json"); jsonFlag != nil && jsonFlag.Changed {
+		if enabled, _ := flags.GetBool("json"); enabled {
+			return true
+		}
+	}
+	outputFlag := flags.Lookup("output")
+	return outputFlag != nil && outputFlag.Changed && strings.EqualFold(outputFlag.Value.String(), outputJSON)
+}
+
+// textOutputFormat returns the output format of a mode that only prints text, such as --fix:
+// JSON asked for on the command line is an error, while JSON from output.default_format falls
+// back to markdown
+func textOutputFormat(flags *pflag.FlagSet, outputFormat, mode string) (string, error) {
+	if outputFormat != outputJSON {
+		return outputFormat, nil
+	}
+	if explicitJSONOutput(flags) {
+		return "", fmt.Errorf("%s cannot be combined with JSON output", mode)
+	}
+	return outputMarkdown, nil
+}
+
+// renderAnswer formats AI text for the markdown or plain output formats
+func renderAnswer(format, text string) string {
+	if format == outputPlain {
+		return text
+	}
+	return renderAIResponse(text)
+}
+
+// renderAIResponse renders the markdown of an AI response, or with --raw returns it unchanged
+func renderAIResponse(text string) string {
+	if rawOutput {
+		return text
+	}
+	return utils.RenderMarkdown(text)
+}
+
+// nopWriteCloser is a writer whose Close does nothing
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// newStreamRenderer returns the writer for a streamed AI response: it renders each markdown
+// block once complete and streams code blocks raw, or passes the text through unchanged with
+// --raw. Close writes what is left of the response.
+func newStreamRenderer(out io.Writer) io.WriteCloser {
+	if rawOutput {
+		return nopWriteCloser{out}
+	}
+	return utils.NewMarkdownStream(out)
+}
+
+// writeJSONOutput prints v as indented JSON
+func writeJSONOutput(out io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/config"
+
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestResolveOutputFormat(t *testing.T) {
+	var cfg config.UserConfig
+	if err := yaml.Unmarshal([]byte("output:\n  default_format: json\n"), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		cfg  *config.UserConfig
+		want string
+	}{
+		{"built-in default", nil, &config.UserConfig{}, outputMarkdown},
+		{"no config", nil, nil, outputMarkdown},
+		{"config default", nil, &cfg, outputJSON},
+		{"--output overrides config", []string{"--output", "plain"}, &cfg, outputPlain},
+		{"-o overrides config", []string{"-o", "markdown"}, &cfg, outputMarkdown},
+		{"--json overrides config", []string{"--json"}, &config.UserConfig{Output: config.OutputConfig{DefaultFormat: "plain"}}, outputJSON},
+		{"--json wins over --output", []string{"--output", "plain", "--json"}, nil, outputJSON},
+		{"--json=false falls back", []string{"--json=false"}, &cfg, outputJSON},
+		{"case insensitive", []string{"--output", "PLAIN"}, nil, outputPlain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test"}
+			addOutputFormatFlags(cmd)
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			got, err := resolveOutputFormat(cmd.Flags(), tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveOutputFormat(%v) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveOutputFormatInvalid(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+	addOutputFormatFlags(cmd)
+	if err := cmd.ParseFlags([]string{"--output", "yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resolveOutputFormat(cmd.Flags(), nil); err == nil || !strings.Contains(err.Error(), "yaml") {
+		t.Errorf("expected an unknown format error, got %v", err)
+	}
+
+	cmd = &cobra.Command{Use: "test"}
+	addOutputFormatFlags(cmd)
+	bad := &config.UserConfig{Output: config.OutputConfig{DefaultFormat: "html"}}
+	if _, err := resolveOutputFormat(cmd.Flags(), bad); err == nil || !strings.Contains(err.Error(), "output.default_format") {
+		t.Errorf("expected a config error, got %v", err)
+	}
+}
+
+func TestTextOutputFormat(t *testing.T) {
+	jsonDefault := &config.UserConfig{Output: config.OutputConfig{DefaultFormat: "json"}}
+	tests := []struct {
+		name    string
+		args    []string
+		cfg     *config.UserConfig
+		want    string
+		wantErr bool
+	}{
+		{"JSON from the config falls back to text", nil, jsonDefault, outputMarkdown, false},
+		{"other formats are kept", []string{"--output", "plain"}, jsonDefault, outputPlain, false},
+		{"--json is rejected", []string{"--json"}, nil, "", true},
+		{"--output json is rejected", []string{"-o", "JSON"}, nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test"}
+			addOutputFormatFlags(cmd)
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			format, err := resolveOutputFormat(cmd.Flags(), tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := textOutputFormat(cmd.Flags(), format, "--fix")
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("textOutputFormat = %q, %v; want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWriteAskAnswer(t *testing.T) {
+	defer func(format string) { askOutputFormat = format }(askOutputFormat)
+
+	var out bytes.Buffer
+	askOutputFormat = outputJSON
+	writeAskAnswer(&out, "How do I enable SSH?", "Set `services.openssh.enable = true;`", "ollama")
+	var answer askAnswerJSON
+	if err := json.Unmarshal(out.Bytes(), &answer); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if answer.Question != "How do I enable SSH?" || answer.Provider != "ollama" || !strings.Contains(answer.Answer, "openssh") {
+		t.Errorf("unexpected answer %+v", answer)
+	}
+
+	out.Reset()
+	askOutputFormat = outputPlain
+	writeAskAnswer(&out, "q", "# Title\n`code`", "")
+	if out.String() != "# Title\n`code`\n" {
+		t.Errorf("plain output should be the raw answer, got %q", out.String())
+	}
+}
+
+func TestRawOutputSkipsRendering(t *testing.T) {
+	defer func(raw bool) { rawOutput = raw }(rawOutput)
+	response := "## Enable nginx\n\n| Option | Value |\n|---|---|\n| services.nginx.enable | `true` |\n\n```nix\n{\n  services.nginx.enable = true;\n}\n```"
+
+	rawOutput = false
+	if renderAIResponse(response) == response {
+		t.Fatal("expected markdown rendering to change the response without --raw")
+	}
+
+	rawOutput = true
+	if got := renderAIResponse(response); got != response {
+		t.Errorf("renderAIResponse with --raw = %q, want the response unchanged", got)
+	}
+	if got := renderAnswer(outputMarkdown, response); got != response {
+		t.Errorf("renderAnswer(markdown) with --raw = %q, want the response unchanged", got)
+	}
+}
+
+func TestAddRawFlag(t *testing.T) {
+	defer func(raw bool) { rawOutput = raw }(rawOutput)
+	for _, cmd := range []*cobra.Command{askCmd, diagnoseCmd, explainOptionCmd} {
+		if cmd.Flags().Lookup("raw") == nil {
+			t.Errorf("%s has no --raw flag", cmd.Name())
+		}
+	}
+
+	cmd := &cobra.Command{Use: "test"}
+	addRawFlag(cmd)
+	if err := cmd.ParseFlags([]string{"--raw"}); err != nil {
+		t.Fatal(err)
+	}
+	if !rawOutput {
+		t.Error("expected --raw to set rawOutput")
+	}
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"nix-ai-help/internal/packaging"
+	"nix-ai-help/pkg/utils"
+)
+
+// refineDerivationInteractively lets the user describe problems with the generated derivation
+// and has the AI correct it, until the user saves it to savePath or quits. The feedback given
+// so far is kept in memory and sent along with each refinement. Saving over an existing file
+// asks first, unless assumeYes. It reports whether the derivation was saved.
+func refineDerivationInteractively(ctx context.Context, in *bufio.Reader, out io.Writer, service *packaging.PackagingService, result *packaging.PackageResult, savePath string, assumeYes bool) bool {
+	var feedback []string
+
+	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintln(out, utils.FormatHeader("🛠️  Refine Derivation"))
+	_, _ = fmt.Fprintln(out, utils.FormatNote("Describe a problem to fix (e.g. \"build fails: missing openssl\"), 'show' to print the derivation, 'save' to write it to "+savePath+" or 'quit'"))
+
+	for {
+		_, _ = fmt.Fprint(out, "\nrefine> ")
+		line, err := in.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if err != nil && line == "" {
+			// Input closed
+			_, _ = fmt.Fprintln(out)
+			_, _ = fmt.Fprintln(out, utils.FormatWarning("Derivation not saved"))
+			return false
+		}
+
+		switch strings.ToLower(line) {
+		case "":
+			continue
+		case "q", "quit", "exit":
+			_, _ = fmt.Fprintln(out, utils.FormatWarning("Derivation not saved"))
+			return false
+		case "show":
+			_, _ = fmt.Fprintln(out, utils.RenderMarkdown("```nix\n"+result.Derivation+"\n```"))
+			continue
+		case "save":
+			err := utils.WriteFileWithConfirm(savePath, []byte(result.Derivation), 0644, utils.WriteConfirm{In: in, Out: out, AssumeYes: assumeYes})
+			if errors.Is(err, utils.ErrOverwriteDeclined) {
+				_, _ = fmt.Fprintln(out, utils.FormatInfo("Kept "+savePath+"; keep refining or 'quit'"))
+				continue
+			}
+			if err != nil {
+				_, _ = fmt.Fprintln(out, utils.FormatError("Failed to write derivation to file: "+err.Error()))
+				continue
+			}
+			_, _ = fmt.Fprintln(out, utils.FormatSuccess("✅ Derivation written to: "+savePath))
+			return true
+		}
+
+		spinner := utils.NewSpinner(out, "Refining derivation...", 0).Start()
+		err = service.RefineResult(ctx, result, line, feedback)
+		spinner.Stop()
+		if err != nil {
+			_, _ = fmt.Fprintln(out, utils.FormatError(err.Error()))
+			continue
+		}
+		feedback = append(feedback, line)
+
+		_, _ = fmt.Fprintln(out, utils.FormatHeader(fmt.Sprintf("📜 Refined Derivation (round %d)", len(feedback))))
+		_, _ = fmt.Fprintln(out, utils.RenderMarkdown("```nix\n"+result.Derivation+"\n```"))
+		for _, issue := range result.ValidationIssues {
+			_, _ = fmt.Fprintln(out, utils.FormatWarning("• "+issue))
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/packaging"
+	"nix-ai-help/pkg/logger"
+)
+
+func TestRefineDerivationInteractively(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{
+		"```nix\n{ stdenv, openssl }:\nstdenv.mkDerivation {\n  pname = \"hello\";\n  version = \"1.0\";\n  src = ./.;\n  buildInputs = [ openssl ];\n}\n```",
+		"```nix\n{ stdenv, openssl, pkg-config }:\nstdenv.mkDerivation {\n  pname = \"hello\";\n  version = \"1.0\";\n  src = ./.;\n  nativeBuildInputs = [ pkg-config ];\n  buildInputs = [ openssl ];\n}\n```",
+	}}
+	service := packaging.NewPackagingService(provider, nil, t.TempDir(), logger.NewLoggerWithLevel("error"))
+	result := &packaging.PackageResult{
+		Analysis:   &packaging.RepoAnalysis{ProjectName: "hello", BuildSystem: packaging.BuildSystemMake, Language: "c"},
+		Derivation: "{ stdenv }:\nstdenv.mkDerivation {\n  pname = \"hello\";\n  version = \"1.0\";\n  src = ./.;\n}",
+	}
+	savePath := filepath.Join(t.TempDir(), "hello.nix")
+	in := bufio.NewReader(strings.NewReader("build fails: missing openssl\n\nopenssl not found by pkg-config\nsave\n"))
+	var out bytes.Buffer
+
+	if !refineDerivationInteractively(context.Background(), in, &out, service, result, savePath, false) {
+		t.Fatalf("expected the derivation to be saved, output:\n%s", out.String())
+	}
+	if provider.calls != 2 {
+		t.Fatalf("expected two refinements, got %d", provider.calls)
+	}
+	if !strings.Contains(provider.prompts[1], "- build fails: missing openssl") || !strings.Contains(provider.prompts[1], "buildInputs = [ openssl ];") {
+		t.Errorf("expected the second round to build on the first:\n%s", provider.prompts[1])
+	}
+
+	saved, err := os.ReadFile(savePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(saved) != result.Derivation || !strings.Contains(string(saved), "nativeBuildInputs = [ pkg-config ];") {
+		t.Errorf("expected the last refinement to be saved, got:\n%s", saved)
+	}
+	if !strings.Contains(out.String(), "round 2") {
+		t.Errorf("expected the refinement rounds to be shown, got:\n%s", out.String())
+	}
+}
+
+func TestRefineDerivationInteractively_QuitWithoutSaving(t *testing.T) {
+	provider := &scriptedProvider{}
+	service := packaging.NewPackagingService(provider, nil, t.TempDir(), logger.NewLoggerWithLevel("error"))
+	result := &packaging.PackageResult{Analysis: &packaging.RepoAnalysis{ProjectName: "hello"}, Derivation: "{ }"}
+	savePath := filepath.Join(t.TempDir(), "hello.nix")
+
+	for _, input := range []string{"quit\n", ""} {
+		var out bytes.Buffer
+		if refineDerivationInteractively(context.Background(), bufio.NewReader(strings.NewReader(input)), &out, service, result, savePath, false) {
+			t.Errorf("input %q: expected no save", input)
+		}
+		if _, err := os.Stat(savePath); !os.IsNotExist(err) {
+			t.Errorf("input %q: expected no file to be written", input)
+		}
+	}
+	if provider.calls != 0 {
+		t.Errorf("expected no AI queries, got %d", provider.calls)
+	}
+}
+
+func TestDerivationOutputPath(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "default.nix")
+	for _, tt := range []struct{ output, want string }{
+		{"", "hello.nix"},
+		{file, file},
+		// A directory left by earlier versions, which wrote <output>/<project>.nix
+		{dir, filepath.Join(dir, "hello.nix")},
+	} {
+		if got := derivationOutputPath(tt.output, "hello"); got != tt.want {
+			t.Errorf("derivationOutputPath(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"nix-ai-help/internal/packaging"
+)
+
+func TestWritePackageResultJSON(t *testing.T) {
+	result := &packaging.PackageResult{
+		Analysis: &packaging.RepoAnalysis{
+			ProjectName:  "hello",
+			Language:     "go",
+			BuildSystem:  packaging.BuildSystemGo,
+			Dependencies: []packaging.Dependency{{Name: "openssl", Type: "runtime", System: true}},
+		},
+		Derivation:       "{ buildGoModule }: buildGoModule { pname = \"hello\"; }",
+		ValidationIssues: []string{"missing vendorHash"},
+		NixpkgsMappings:  map[string]string{"openssl": "pkgs.openssl"},
+	}
+
+	var out bytes.Buffer
+	if err := writePackageResultJSON(&out, result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded struct {
+		Analysis struct {
+			ProjectName  string `json:"project_name"`
+			Language     string `json:"language"`
+			BuildSystem  string `json:"build_system"`
+			Dependencies []struct {
+				Name string `json:"name"`
+			} `json:"dependencies"`
+		} `json:"analysis"`
+		Derivation       string            `json:"derivation"`
+		NixpkgsMappings  map[string]string `json:"nixpkgs_mappings"`
+		ValidationIssues []string          `json:"validation_issues"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out.String())
+	}
+	if decoded.Analysis.ProjectName != "hello" || decoded.Analysis.Language != "go" || decoded.Analysis.BuildSystem != "go" {
+		t.Errorf("unexpected analysis: %+v", decoded.Analysis)
+	}
+	if len(decoded.Analysis.Dependencies) != 1 || decoded.Analysis.Dependencies[0].Name != "openssl" {
+		t.Errorf("unexpected dependencies: %+v", decoded.Analysis.Dependencies)
+	}
+	if decoded.Derivation != result.Derivation {
+		t.Errorf("expected the derivation, got %q", decoded.Derivation)
+	}
+	if decoded.NixpkgsMappings["openssl"] != "pkgs.openssl" || len(decoded.ValidationIssues) != 1 {
+		t.Errorf("unexpected mappings %v or issues %v", decoded.NixpkgsMappings, decoded.ValidationIssues)
+	}
+}
@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"nix-ai-help/pkg/utils"
+)
+
+// packagingTempDir creates the temporary directory of one package-repo run, so that concurrent
+// runs do not clone into each other's directories. cleanup removes it, also when Ctrl-C ends
+// nixai, unless keep is set to inspect the clone afterwards.
+func packagingTempDir(keep bool) (dir string, cleanup func(), err error) {
+	dir, err = os.MkdirTemp("", "nixai-packaging-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	if keep {
+		return dir, func() {}, nil
+	}
+	removeOnInterrupt := utils.OnInterrupt(func() { _ = os.RemoveAll(dir) })
+	return dir, func() {
+		removeOnInterrupt()
+		_ = os.RemoveAll(dir)
+	}, nil
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPackagingTempDirIsUniqueAndRemoved(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	first, cleanupFirst, err := packagingTempDir(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, cleanupSecond, err := packagingTempDir(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatalf("expected each run to get its own directory, both got %s", first)
+	}
+	for _, dir := range []string{first, second} {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			t.Fatalf("expected %s to be created: %v", dir, err)
+		}
+	}
+
+	cleanupFirst()
+	if _, err := os.Stat(first); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed, got %v", first, err)
+	}
+	if _, err := os.Stat(second); err != nil {
+		t.Errorf("cleaning up one run must not touch another: %v", err)
+	}
+	cleanupSecond()
+}
+
+func TestPackagingTempDirKeep(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	dir, cleanup, err := packagingTempDir(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup()
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("expected --keep-temp to keep %s: %v", dir, err)
+	}
+}
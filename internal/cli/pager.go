@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"nix-ai-help/pkg/utils"
+
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/term"
+)
+
+// defaultPager is used when $PAGER is not set; -R keeps colors and formatting
+var defaultPager = []string{"less", "-R"}
+
+// noPager is the --no-pager flag
+var noPager bool
+
+// terminalSize returns the size of the terminal f is attached to, and whether it is one; replaced in tests
+var terminalSize = func(f *os.File) (width, height int, isTTY bool) {
+	fd := int(f.Fd())
+	if !term.IsTerminal(fd) {
+		return 0, 0, false
+	}
+	width, height, err := term.GetSize(fd)
+	if err != nil {
+		return 0, 0, true
+	}
+	return width, height, true
+}
+
+// shouldPage decides whether content is paged: only on a terminal, when paging is not
+// disabled, and when the content is taller than the terminal once long lines wrap
+func shouldPage(content string, width, height int, isTTY, disabled bool) bool {
+	if disabled || !isTTY || height <= 0 {
+		return false
+	}
+	return displayLines(content, width) > height
+}
+
+// displayLines counts the terminal lines content occupies at the given width
+func displayLines(content string, width int) int {
+	content = strings.TrimRight(content, "\n")
+	if content == "" {
+		return 0
+	}
+	count := 0
+	for _, line := range strings.Split(content, "\n") {
+		lineWidth := lipgloss.Width(line)
+		if width <= 0 || lineWidth <= width {
+			count++
+			continue
+		}
+		count += (lineWidth + width - 1) / width
+	}
+	return count
+}
+
+// pagerCommand returns the pager from $PAGER, or less -R
+func pagerCommand(env string) []string {
+	if fields := strings.Fields(env); len(fields) > 0 {
+		return fields
+	}
+	return defaultPager
+}
+
+// writePaged writes long output through the pager when out is a terminal, and writes it
+// directly otherwise or when the pager cannot be started. A pager that started and then
+// failed may already have shown part of the content, so it is not written again.
+func writePaged(out io.Writer, content string) {
+	if f, ok := out.(*os.File); ok && !globalTUI {
+		width, height, isTTY := terminalSize(f)
+		if shouldPage(content, width, height, isTTY, noPager) {
+			args := pagerCommand(os.Getenv("PAGER"))
+			// #nosec G204 -- the pager is chosen by the user through $PAGER
+			pager := exec.Command(args[0], args[1:]...)
+			pager.Stdin = strings.NewReader(content)
+			pager.Stdout = f
+			pager.Stderr = os.Stderr
+			// Ctrl-C also reaches the pager, which restores the terminal when it quits
+			paged := make(chan struct{})
+			remove := utils.OnInterrupt(func() { <-paged })
+			err := utils.TraceRun(pager)
+			close(paged)
+			remove()
+			if !pagerNeverStarted(err) {
+				return
+			}
+		}
+	}
+	_, _ = fmt.Fprint(out, content)
+}
+
+// pagerNeverStarted reports whether a pager run failed before the pager started, e.g. because
+// $PAGER names a missing program, rather than exiting with an error after it started
+func pagerNeverStarted(err error) bool {
+	var exitErr *exec.ExitError
+	return err != nil && !errors.As(err, &exitErr)
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShouldPage(t *testing.T) {
+	long := strings.Repeat("line\n", 50)
+	short := strings.Repeat("line\n", 10)
+
+	tests := []struct {
+		name     string
+		content  string
+		width    int
+		height   int
+		isTTY    bool
+		disabled bool
+		want     bool
+	}{
+		{"long output on terminal", long, 80, 24, true, false, true},
+		{"short output on terminal", short, 80, 24, true, false, false},
+		{"output exactly terminal height", strings.Repeat("line\n", 24), 80, 24, true, false, false},
+		{"not a terminal", long, 80, 24, false, false, false},
+		{"disabled with --no-pager", long, 80, 24, true, true, false},
+		{"unknown terminal height", long, 0, 0, true, false, false},
+		{"wrapped long lines", strings.Repeat(strings.Repeat("x", 200)+"\n", 10), 80, 24, true, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldPage(tt.content, tt.width, tt.height, tt.isTTY, tt.disabled); got != tt.want {
+				t.Errorf("shouldPage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisplayLines(t *testing.T) {
+	if got := displayLines("", 80); got != 0 {
+		t.Errorf("empty content = %d lines, want 0", got)
+	}
+	if got := displayLines("a\nb\n", 80); got != 2 {
+		t.Errorf("two lines = %d, want 2", got)
+	}
+	if got := displayLines("\x1b[1m"+strings.Repeat("x", 80)+"\x1b[0m", 80); got != 1 {
+		t.Errorf("ANSI codes should not count towards width, got %d lines", got)
+	}
+	if got := displayLines(strings.Repeat("x", 161), 80); got != 3 {
+		t.Errorf("161 chars at width 80 = %d lines, want 3", got)
+	}
+}
+
+func TestPagerCommand(t *testing.T) {
+	if got := pagerCommand(""); !reflect.DeepEqual(got, []string{"less", "-R"}) {
+		t.Errorf("default pager = %v", got)
+	}
+	if got := pagerCommand("most -s"); !reflect.DeepEqual(got, []string{"most", "-s"}) {
+		t.Errorf("$PAGER = %v", got)
+	}
+}
+
+func TestWritePaged_NonTerminalWritesDirectly(t *testing.T) {
+	var out bytes.Buffer
+	content := strings.Repeat("line\n", 100)
+	writePaged(&out, content)
+	if out.String() != content {
+		t.Error("output to a non-terminal writer should be written unchanged")
+	}
+}
+
+func TestWritePaged_PagerFailures(t *testing.T) {
+	original := terminalSize
+	terminalSize = func(*os.File) (int, int, bool) { return 80, 5, true }
+	defer func() { terminalSize = original }()
+
+	dir := t.TempDir()
+	// A pager that shows the first line and then fails, e.g. killed by a signal
+	failing := filepath.Join(dir, "failing-pager")
+	if err := os.WriteFile(failing, []byte("#!/bin/sh\nhead -n 1\nexit 2\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Repeat("line\n", 20)
+
+	tests := []struct {
+		name  string
+		pager string
+		want  string
+	}{
+		{"pager failed after it started", failing, "line\n"},
+		{"pager never started", filepath.Join(dir, "missing-pager"), content},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PAGER", tt.pager)
+			out, err := os.Create(filepath.Join(t.TempDir(), "out"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			writePaged(out, content)
+			_ = out.Close()
+			written, _ := os.ReadFile(out.Name())
+			if string(written) != tt.want {
+				t.Errorf("wrote %q, want %q", written, tt.want)
+			}
+		})
+	}
+}
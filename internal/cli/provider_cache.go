@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"nix-ai-help/internal/ai"
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/logger"
+)
+
+var (
+	providerManagerMu  sync.Mutex
+	providerManager    *ai.ProviderManager
+	providerManagerKey string
+)
+
+// newProviderManager constructs provider managers; replaced in tests
+var newProviderManager = ai.NewProviderManager
+
+// providerConfigKey fingerprints a configuration so a cached manager is only reused while
+// the configuration is unchanged. An empty key means the configuration cannot be compared.
+func providerConfigKey(cfg *config.UserConfig) string {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// sharedProviderManager returns the process-wide provider manager, so interactive and TUI
+// sessions initialize providers once. A new manager is built when the configuration changes.
+func sharedProviderManager(cfg *config.UserConfig, log *logger.Logger) *ai.ProviderManager {
+	key := providerConfigKey(cfg)
+	if key == "" {
+		return newProviderManager(cfg, log)
+	}
+
+	providerManagerMu.Lock()
+	defer providerManagerMu.Unlock()
+	if providerManager == nil || providerManagerKey != key {
+		providerManager = newProviderManager(cfg, log)
+		providerManagerKey = key
+	}
+	return providerManager
+}
+
+// ResetProviderManager drops the cached provider manager; called when the configuration is saved
+func ResetProviderManager() {
+	providerManagerMu.Lock()
+	defer providerManagerMu.Unlock()
+	providerManager = nil
+	providerManagerKey = ""
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"testing"
+
+	"nix-ai-help/internal/ai"
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/logger"
+)
+
+// countProviderManagers counts manager constructions for the duration of a test
+func countProviderManagers(t *testing.T) *int {
+	t.Helper()
+	count := 0
+	original := newProviderManager
+	newProviderManager = func(cfg *config.UserConfig, log *logger.Logger) *ai.ProviderManager {
+		count++
+		return original(cfg, log)
+	}
+	ResetProviderManager()
+	t.Cleanup(func() {
+		newProviderManager = original
+		ResetProviderManager()
+	})
+	return &count
+}
+
+func TestProviderManager_ConstructedOncePerSession(t *testing.T) {
+	count := countProviderManagers(t)
+	log := logger.NewTestLogger()
+
+	// Every command loads its own copy of the configuration
+	for i := 0; i < 3; i++ {
+		if _, err := GetLegacyAIProvider(config.DefaultUserConfig(), log); err != nil {
+			t.Fatalf("GetLegacyAIProvider: %v", err)
+		}
+		GetAIProviderManager(config.DefaultUserConfig(), log)
+	}
+
+	if *count != 1 {
+		t.Errorf("expected the manager to be constructed once, got %d", *count)
+	}
+}
+
+func TestProviderManager_RebuiltWhenConfigChanges(t *testing.T) {
+	count := countProviderManagers(t)
+	log := logger.NewTestLogger()
+
+	cfg := config.DefaultUserConfig()
+	first := GetAIProviderManager(cfg, log)
+
+	changed := config.DefaultUserConfig()
+	changed.AIModels.SelectionPreferences.DefaultProvider = "openai"
+	second := GetAIProviderManager(changed, log)
+
+	if first == second || *count != 2 {
+		t.Errorf("expected a new manager after a config change, got %d constructions", *count)
+	}
+	if GetAIProviderManager(changed, log) != second {
+		t.Error("expected the new manager to be reused")
+	}
+}
+
+func TestResetProviderManager(t *testing.T) {
+	count := countProviderManagers(t)
+	log := logger.NewTestLogger()
+	cfg := config.DefaultUserConfig()
+
+	first := GetAIProviderManager(cfg, log)
+	ResetProviderManager()
+	if GetAIProviderManager(cfg, log) == first || *count != 2 {
+		t.Errorf("expected a new manager after reset, got %d constructions", *count)
+	}
+}
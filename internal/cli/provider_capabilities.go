@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"nix-ai-help/internal/ai"
+	"nix-ai-help/pkg/utils"
+)
+
+// contextChars is how many characters of context to send to a model with a context window of
+// tokens: half of the window, leaving room for the instructions and the answer. An unknown
+// window counts as defaultContextWindow.
+func contextChars(tokens int) int {
+	if tokens <= 0 {
+		tokens = defaultContextWindow
+	}
+	return tokens * charsPerToken / 2
+}
+
+// fitAskContext drops gathered context that does not fit the context window of the provider,
+// maxContext tokens as reported by its capabilities. Sources are given in order of importance and
+// their items are kept in order while they fit. It returns how many items were dropped.
+func fitAskContext(maxContext int, sources ...*[]string) int {
+	budget := contextChars(maxContext)
+	dropped := 0
+	for _, source := range sources {
+		kept := (*source)[:0]
+		for _, item := range *source {
+			if len(item) > budget {
+				dropped++
+				continue
+			}
+			budget -= len(item)
+			kept = append(kept, item)
+		}
+		*source = kept
+	}
+	return dropped
+}
+
+// startAnswerStream starts streaming the answer to prompt. Providers that cannot stream are asked
+// for the complete answer instead, delivered as a single chunk, and the user is told to expect it.
+func startAnswerStream(ctx context.Context, out io.Writer, provider ai.Provider, providerName, prompt string) (<-chan ai.StreamResponse, error) {
+	if provider.Capabilities().SupportsStreaming {
+		return provider.StreamResponse(ctx, prompt)
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatNote(providerName+" does not stream responses; the answer is shown when it is complete"))
+
+	chunks := make(chan ai.StreamResponse, 1)
+	go func() {
+		defer close(chunks)
+		response, err := provider.GenerateResponse(ctx, prompt)
+		chunks <- ai.StreamResponse{Content: response, Error: err, Done: true}
+	}()
+	return chunks, nil
+}
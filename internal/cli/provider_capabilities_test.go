@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/ai"
+)
+
+// streamingProvider streams its chunks and reports that it streams
+type streamingProvider struct {
+	scriptedProvider
+	chunks []string
+}
+
+func (p *streamingProvider) StreamResponse(ctx context.Context, prompt string) (<-chan ai.StreamResponse, error) {
+	responses := make(chan ai.StreamResponse, len(p.chunks))
+	for i, chunk := range p.chunks {
+		responses <- ai.StreamResponse{Content: chunk, Done: i == len(p.chunks)-1}
+	}
+	close(responses)
+	return responses, nil
+}
+
+func (p *streamingProvider) Capabilities() ai.Capabilities {
+	return ai.Capabilities{SupportsStreaming: true, SupportsSystemPrompt: true, MaxContext: 200000}
+}
+
+func collectStream(t *testing.T, chunks <-chan ai.StreamResponse) []string {
+	t.Helper()
+	var contents []string
+	for chunk := range chunks {
+		if chunk.Error != nil {
+			t.Fatalf("unexpected stream error: %v", chunk.Error)
+		}
+		contents = append(contents, chunk.Content)
+	}
+	return contents
+}
+
+func TestStartAnswerStream_StreamingProvider(t *testing.T) {
+	provider := &streamingProvider{chunks: []string{"Enable ", "services.nginx.enable"}}
+	var out bytes.Buffer
+	chunks, err := startAnswerStream(context.Background(), &out, provider, "claude", "prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := collectStream(t, chunks); len(got) != 2 {
+		t.Errorf("expected the provider's 2 chunks, got %q", got)
+	}
+	if provider.calls != 0 || out.Len() != 0 {
+		t.Errorf("expected a plain stream, got %d queries and output %q", provider.calls, out.String())
+	}
+}
+
+func TestStartAnswerStream_FallsBackForNonStreamingProvider(t *testing.T) {
+	// scriptedProvider reports no streaming and fails StreamResponse
+	provider := &scriptedProvider{responses: []string{"Set services.nginx.enable = true;"}}
+	var out bytes.Buffer
+	chunks, err := startAnswerStream(context.Background(), &out, provider, "llamacpp", "prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := collectStream(t, chunks)
+	if len(got) != 1 || got[0] != "Set services.nginx.enable = true;" {
+		t.Errorf("expected the complete answer as one chunk, got %q", got)
+	}
+	if provider.calls != 1 || !strings.Contains(out.String(), "llamacpp does not stream") {
+		t.Errorf("expected one query and a note, got %d queries and output %q", provider.calls, out.String())
+	}
+}
+
+func TestFitAskContext(t *testing.T) {
+	doc := strings.Repeat("d", 6000)
+	pkg := strings.Repeat("p", 2000)
+	example := strings.Repeat("e", 8000)
+
+	// A large context window keeps everything
+	docs, packages, examples := []string{doc}, []string{pkg}, []string{example}
+	if dropped := fitAskContext((&streamingProvider{}).Capabilities().MaxContext, &docs, &packages, &examples); dropped != 0 || len(examples) != 1 {
+		t.Errorf("expected nothing dropped, dropped %d", dropped)
+	}
+
+	// 4096 tokens leave 8192 characters: the docs and package results fit, the example does not
+	docs, packages, examples = []string{doc}, []string{pkg}, []string{example}
+	if dropped := fitAskContext(4096, &docs, &packages, &examples); dropped != 1 || len(docs) != 1 || len(packages) != 1 || len(examples) != 0 {
+		t.Errorf("expected the example dropped, dropped %d: %d docs, %d packages, %d examples", dropped, len(docs), len(packages), len(examples))
+	}
+
+	// An unknown window falls back to the default
+	if contextChars(0) != contextChars(defaultContextWindow) {
+		t.Errorf("expected an unknown window to count as %d tokens", defaultContextWindow)
+	}
+}
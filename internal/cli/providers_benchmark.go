@@ -0,0 +1,264 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"nix-ai-help/internal/ai"
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/logger"
+	"nix-ai-help/pkg/utils"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// defaultBenchmarkPrompt is a trivial prompt that every model answers quickly
+	defaultBenchmarkPrompt = "Reply with the single word: ok"
+	// defaultBenchmarkTimeout bounds each provider's answer
+	defaultBenchmarkTimeout = 60 * time.Second
+)
+
+// benchmarkTarget is a provider to benchmark with the model it answers with
+type benchmarkTarget struct {
+	Provider  string
+	Model     string
+	Streaming bool
+	Client    ai.Provider
+}
+
+// benchmarkResult is the measured latency of one provider
+type benchmarkResult struct {
+	Provider string
+	Model    string
+	// Latency is the time until the complete answer arrived
+	Latency time.Duration
+	// FirstToken is the time until the first streamed content, 0 for non-streaming providers
+	FirstToken time.Duration
+	Err        error
+}
+
+// newProvidersCmd creates the providers command
+func newProvidersCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "providers",
+		Short: "Inspect and compare the configured AI providers",
+		Run: func(cmd *cobra.Command, args []string) {
+			_ = cmd.Help()
+		},
+	}
+	cmd.AddCommand(newProvidersBenchmarkCmd())
+	return cmd
+}
+
+// newProvidersBenchmarkCmd creates the providers benchmark command
+func newProvidersBenchmarkCmd() *cobra.Command {
+	var prompt string
+	var timeout time.Duration
+	var allModels bool
+	cmd := &cobra.Command{
+		Use:   "benchmark",
+		Short: "Measure how fast each reachable provider and model answers a small prompt",
+		Long: `Send the same small prompt to the default model of each reachable provider and list
+them from fastest to slowest. With --all-models every model configured for a provider is
+measured instead, which sends one request per model and may be billed accordingly.
+Streaming providers also report the time until their first token. Use it to choose a
+default provider and model.`,
+		Example: `  nixai providers benchmark
+  nixai providers benchmark --all-models
+  nixai providers benchmark --prompt "hi" --timeout 30s`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadUserConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %v", err)
+			}
+			targets, skipped := benchmarkTargets(cfg, GetAIProviderManager(cfg, logger.NewLogger()), allModels)
+			if len(targets) == 0 {
+				return errors.New("no reachable AI providers are configured")
+			}
+			out := cmd.OutOrStdout()
+			_, _ = fmt.Fprintln(out, utils.FormatHeader("⏱️  AI Provider Benchmark"))
+			_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Prompt", prompt))
+			_, _ = fmt.Fprintln(out)
+			results := runProvidersBenchmark(out, targets, prompt, timeout)
+			renderBenchmarkResults(out, append(results, skipped...))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&prompt, "prompt", defaultBenchmarkPrompt, "Prompt sent to every provider")
+	cmd.Flags().DurationVar(&timeout, "timeout", defaultBenchmarkTimeout, "Longest time to wait for each provider")
+	cmd.Flags().BoolVar(&allModels, "all-models", false, "Benchmark every configured model of each provider, not only its default")
+	return cmd
+}
+
+// benchmarkTargets returns a target for the default model of each reachable provider, or for
+// each configured model with allModels, in provider and model name order. Providers that are marked unavailable or lack an API key, and
+// models that fail to initialize, are returned as failed results.
+func benchmarkTargets(cfg *config.UserConfig, manager *ai.ProviderManager, allModels bool) ([]benchmarkTarget, []benchmarkResult) {
+	names := manager.GetAvailableProviders()
+	sort.Strings(names)
+
+	registry := config.NewModelRegistry(cfg)
+	var targets []benchmarkTarget
+	var skipped []benchmarkResult
+	for _, name := range names {
+		models := benchmarkModels(cfg, name, allModels)
+		if !registry.IsProviderAvailable(name) {
+			for _, model := range models {
+				skipped = append(skipped, benchmarkResult{Provider: name, Model: model, Err: errors.New("unavailable or missing API key")})
+			}
+			continue
+		}
+		info, _ := manager.GetProviderInfo(name)
+		for _, model := range models {
+			client, err := manager.GetProviderForModel(name, model)
+			if err != nil {
+				skipped = append(skipped, benchmarkResult{Provider: name, Model: model, Err: err})
+				continue
+			}
+			targets = append(targets, benchmarkTarget{
+				Provider:  name,
+				Model:     model,
+				Streaming: info != nil && info.SupportsStreaming,
+				Client:    client,
+			})
+		}
+	}
+	return targets, skipped
+}
+
+// benchmarkModels returns the default model of a provider, or with allModels the models it
+// configures in name order, falling back to the default when it lists none
+func benchmarkModels(cfg *config.UserConfig, provider string, allModels bool) []string {
+	configured := cfg.AIModels.Providers[provider].Models
+	if !allModels || len(configured) == 0 {
+		return []string{askCacheModel(cfg, provider, "")}
+	}
+	models := make([]string, 0, len(configured))
+	for model := range configured {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	return models
+}
+
+// runProvidersBenchmark sends prompt to each target in turn, so that the providers do not
+// compete for the network or a local GPU, and returns the results from fastest to slowest
+func runProvidersBenchmark(out io.Writer, targets []benchmarkTarget, prompt string, timeout time.Duration) []benchmarkResult {
+	results := make([]benchmarkResult, 0, len(targets))
+	for _, target := range targets {
+		label := target.Provider
+		if target.Model != "" {
+			label += " (" + target.Model + ")"
+		}
+		_, _ = fmt.Fprint(out, utils.FormatProgress("  Benchmarking "+label+"... "))
+		result := benchmarkProvider(target, prompt, timeout)
+		if result.Err != nil {
+			_, _ = fmt.Fprintln(out, utils.FormatWarning("failed"))
+		} else {
+			_, _ = fmt.Fprintln(out, utils.FormatSuccess(formatBenchmarkDuration(result.Latency)))
+		}
+		results = append(results, result)
+	}
+	sortBenchmarkResults(results)
+	return results
+}
+
+// benchmarkProvider measures one provider answering prompt
+func benchmarkProvider(target benchmarkTarget, prompt string, timeout time.Duration) benchmarkResult {
+	result := benchmarkResult{Provider: target.Provider, Model: target.Model}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	if !target.Streaming {
+		_, result.Err = target.Client.GenerateResponse(ctx, prompt)
+		result.Latency = time.Since(start)
+		return result
+	}
+
+	chunks, err := target.Client.StreamResponse(ctx, prompt)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	for chunk := range chunks {
+		if chunk.Error != nil {
+			result.Err = chunk.Error
+			break
+		}
+		if result.FirstToken == 0 && chunk.Content != "" {
+			result.FirstToken = time.Since(start)
+		}
+		if chunk.Done {
+			break
+		}
+	}
+	result.Latency = time.Since(start)
+	if result.Err == nil && ctx.Err() != nil {
+		result.Err = ctx.Err()
+	}
+	return result
+}
+
+// sortBenchmarkResults orders successful results by latency, followed by the failures by
+// provider and model name
+func sortBenchmarkResults(results []benchmarkResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if (a.Err == nil) != (b.Err == nil) {
+			return a.Err == nil
+		}
+		if a.Err != nil {
+			if a.Provider != b.Provider {
+				return a.Provider < b.Provider
+			}
+			return a.Model < b.Model
+		}
+		return a.Latency < b.Latency
+	})
+}
+
+// formatBenchmarkDuration formats a latency in milliseconds below a second, e.g. 842ms or 1.25s
+func formatBenchmarkDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(10 * time.Millisecond).String()
+}
+
+// renderBenchmarkResults prints the results as a table, fastest first
+func renderBenchmarkResults(out io.Writer, results []benchmarkResult) {
+	rows := make([][]string, 0, len(results))
+	for _, result := range results {
+		model := result.Model
+		if model == "" {
+			model = "default"
+		}
+		latency, firstToken, status := "-", "-", "ok"
+		if result.Err != nil {
+			status = "error: " + strings.SplitN(result.Err.Error(), "\n", 2)[0]
+		} else {
+			latency = formatBenchmarkDuration(result.Latency)
+			if result.FirstToken > 0 {
+				firstToken = formatBenchmarkDuration(result.FirstToken)
+			}
+		}
+		rows = append(rows, []string{result.Provider, model, latency, firstToken, status})
+	}
+	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintln(out, utils.FormatTable([]string{"Provider", "Model", "Latency", "First token", "Status"}, rows))
+	if len(results) > 0 && results[0].Err == nil {
+		fastest := results[0].Provider
+		if results[0].Model != "" {
+			fastest += " (" + results[0].Model + ")"
+		}
+		_, _ = fmt.Fprintln(out, utils.FormatTip(fmt.Sprintf("Fastest: %s. Make it the default with: nixai config set ai_provider %s", fastest, results[0].Provider)))
+	}
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"nix-ai-help/internal/ai"
+	"nix-ai-help/internal/config"
+)
+
+// delayedProvider answers after a simulated latency; streaming answers send a first chunk
+// after firstToken and the rest after delay
+type delayedProvider struct {
+	delay      time.Duration
+	firstToken time.Duration
+	err        error
+}
+
+func (p *delayedProvider) Query(prompt string) (string, error) {
+	return p.GenerateResponse(context.Background(), prompt)
+}
+
+func (p *delayedProvider) GenerateResponse(ctx context.Context, prompt string) (string, error) {
+	select {
+	case <-time.After(p.delay):
+		return "ok", p.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (p *delayedProvider) StreamResponse(ctx context.Context, prompt string) (<-chan ai.StreamResponse, error) {
+	chunks := make(chan ai.StreamResponse)
+	go func() {
+		defer close(chunks)
+		time.Sleep(p.firstToken)
+		chunks <- ai.StreamResponse{Content: "o"}
+		time.Sleep(p.delay - p.firstToken)
+		chunks <- ai.StreamResponse{Content: "k", Done: true}
+	}()
+	return chunks, nil
+}
+
+func (p *delayedProvider) GetPartialResponse() string {
+	return ""
+}
+
+func (p *delayedProvider) Capabilities() ai.Capabilities {
+	return ai.Capabilities{}
+}
+
+func TestRunProvidersBenchmarkSortsByLatency(t *testing.T) {
+	targets := []benchmarkTarget{
+		{Provider: "slow", Model: "big", Client: &delayedProvider{delay: 120 * time.Millisecond}},
+		{Provider: "broken", Client: &delayedProvider{err: errors.New("connection refused")}},
+		{Provider: "streaming", Model: "llama3", Streaming: true, Client: &delayedProvider{delay: 80 * time.Millisecond, firstToken: 10 * time.Millisecond}},
+		{Provider: "fast", Model: "small", Client: &delayedProvider{delay: 20 * time.Millisecond}},
+	}
+
+	var out bytes.Buffer
+	results := runProvidersBenchmark(&out, targets, "hi", time.Second)
+
+	var order []string
+	for _, result := range results {
+		order = append(order, result.Provider)
+	}
+	if got := strings.Join(order, ","); got != "fast,streaming,slow,broken" {
+		t.Fatalf("results in order %s, want fast,streaming,slow,broken", got)
+	}
+
+	streaming := results[1]
+	if streaming.FirstToken <= 0 || streaming.FirstToken >= streaming.Latency {
+		t.Errorf("streaming first token %v should be recorded before the full answer %v", streaming.FirstToken, streaming.Latency)
+	}
+	if results[0].FirstToken != 0 {
+		t.Errorf("non-streaming provider reported a first token time %v", results[0].FirstToken)
+	}
+	if results[2].Latency < 120*time.Millisecond {
+		t.Errorf("slow provider latency %v is below its simulated delay", results[2].Latency)
+	}
+	if results[3].Err == nil {
+		t.Error("expected the broken provider to fail")
+	}
+}
+
+func TestBenchmarkTargetsModels(t *testing.T) {
+	t.Setenv("BENCHMARK_TEST_API_KEY", "")
+	cfg := &config.UserConfig{}
+	cfg.AIModels.Providers = map[string]config.AIProviderConfig{
+		"ollama": {Available: true, BaseURL: "http://localhost:11434", Models: map[string]config.AIModelConfig{
+			"llama3":    {Name: "llama3"},
+			"codellama": {Name: "codellama"},
+		}},
+		"gemini": {Available: true, RequiresAPIKey: true, EnvVar: "BENCHMARK_TEST_API_KEY", Models: map[string]config.AIModelConfig{
+			"gemini-pro": {Name: "gemini-pro"},
+		}},
+		"llamacpp": {Available: false},
+	}
+	cfg.AIModels.SelectionPreferences.DefaultModels = map[string]string{"llamacpp": "local"}
+
+	cfg.AIModels.SelectionPreferences.DefaultModels["ollama"] = "llama3"
+
+	tests := []struct {
+		allModels   bool
+		benchmarked string
+		failed      string
+	}{
+		{false, "ollama/llama3", "gemini/,llamacpp/local"},
+		{true, "ollama/codellama,ollama/llama3", "gemini/gemini-pro,llamacpp/local"},
+	}
+	for _, tt := range tests {
+		targets, skipped := benchmarkTargets(cfg, ai.NewProviderManager(cfg, nil), tt.allModels)
+		var benchmarked, failed []string
+		for _, target := range targets {
+			benchmarked = append(benchmarked, target.Provider+"/"+target.Model)
+		}
+		for _, result := range skipped {
+			failed = append(failed, result.Provider+"/"+result.Model)
+		}
+		if got := strings.Join(benchmarked, ","); got != tt.benchmarked {
+			t.Errorf("allModels=%v: benchmarked %s, want %s", tt.allModels, got, tt.benchmarked)
+		}
+		if got := strings.Join(failed, ","); got != tt.failed {
+			t.Errorf("allModels=%v: skipped %s, want %s", tt.allModels, got, tt.failed)
+		}
+	}
+}
+
+func TestBenchmarkProviderTimeout(t *testing.T) {
+	result := benchmarkProvider(benchmarkTarget{Provider: "stuck", Client: &delayedProvider{delay: time.Second}}, "hi", 20*time.Millisecond)
+	if !errors.Is(result.Err, context.DeadlineExceeded) {
+		t.Errorf("expected a timeout, got %v", result.Err)
+	}
+}
+
+func TestRenderBenchmarkResults(t *testing.T) {
+	var out bytes.Buffer
+	renderBenchmarkResults(&out, []benchmarkResult{
+		{Provider: "ollama", Model: "llama3", Latency: 842 * time.Millisecond, FirstToken: 120 * time.Millisecond},
+		{Provider: "openai", Latency: 1234 * time.Millisecond},
+		{Provider: "gemini", Err: errors.New("unavailable or missing API key")},
+	})
+	for _, want := range []string{"842ms", "120ms", "1.23s", "default", "error: unavailable or missing API key", "nixai config set ai_provider ollama"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
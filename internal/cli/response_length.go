@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Response lengths accepted by --length
+const (
+	lengthShort    = "short"
+	lengthNormal   = "normal"
+	lengthDetailed = "detailed"
+)
+
+// lengthInstructions are the prompt instructions for each response length; normal keeps the
+// prompt templates as they are
+var lengthInstructions = map[string]string{
+	lengthShort: "\n\nLENGTH: Keep the answer short: at most 3 bullet points, plus one short code " +
+		"block only if it is essential. Skip background explanations.",
+	lengthNormal: "",
+	lengthDetailed: "\n\nLENGTH: Give a detailed answer: a full step-by-step walkthrough with " +
+		"explanations, complete configuration examples, how to verify the result and common pitfalls.",
+}
+
+// responseLengthFlag is the --length flag of ask, explain-option and diagnose; values are
+// checked when the flag is parsed
+type responseLengthFlag string
+
+// responseLength is the requested response length
+var responseLength = responseLengthFlag(lengthNormal)
+
+// String returns the flag value
+func (l *responseLengthFlag) String() string {
+	return string(*l)
+}
+
+// Set validates and stores a flag value
+func (l *responseLengthFlag) Set(value string) error {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if _, ok := lengthInstructions[value]; !ok {
+		return fmt.Errorf("must be one of short, normal or detailed")
+	}
+	*l = responseLengthFlag(value)
+	return nil
+}
+
+// Type names the flag value in help output
+func (l *responseLengthFlag) Type() string {
+	return "short|normal|detailed"
+}
+
+// lengthInstruction returns the prompt instruction for a response length
+func lengthInstruction(length responseLengthFlag) string {
+	return lengthInstructions[string(length)]
+}
+
+// withLengthInstruction appends the --length instruction to a prompt
+func withLengthInstruction(prompt string) string {
+	return prompt + lengthInstruction(responseLength)
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestWithLengthInstruction(t *testing.T) {
+	original := responseLength
+	defer func() { responseLength = original }()
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"short", "at most 3 bullet points"},
+		{"detailed", "step-by-step walkthrough"},
+		{"DETAILED", "step-by-step walkthrough"},
+	}
+	for _, tt := range tests {
+		if err := responseLength.Set(tt.value); err != nil {
+			t.Fatalf("Set(%q): %v", tt.value, err)
+		}
+		prompt := withLengthInstruction("User Question: How do I enable nginx?")
+		if !strings.HasPrefix(prompt, "User Question: How do I enable nginx?") || !strings.Contains(prompt, tt.want) {
+			t.Errorf("--length %s: expected %q in %q", tt.value, tt.want, prompt)
+		}
+	}
+
+	if err := responseLength.Set("normal"); err != nil {
+		t.Fatal(err)
+	}
+	if prompt := withLengthInstruction("prompt"); prompt != "prompt" {
+		t.Errorf("--length normal should keep the prompt unchanged, got %q", prompt)
+	}
+}
+
+func TestResponseLengthFlagRejectsUnknownValues(t *testing.T) {
+	original := responseLength
+	defer func() { responseLength = original }()
+
+	if err := responseLength.Set("verbose"); err == nil {
+		t.Error("expected an error for an unknown length")
+	}
+	if responseLength != original {
+		t.Errorf("rejected value changed the flag to %q", responseLength)
+	}
+}
+
+func TestLengthFlagRegistered(t *testing.T) {
+	for _, cmd := range []*cobra.Command{askCmd, explainOptionCmd, diagnoseCmd} {
+		flag := cmd.Flags().Lookup("length")
+		if flag == nil {
+			t.Errorf("%s has no --length flag", cmd.Name())
+			continue
+		}
+		if flag.DefValue != "normal" {
+			t.Errorf("%s --length defaults to %q", cmd.Name(), flag.DefValue)
+		}
+	}
+}
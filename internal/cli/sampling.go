@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"nix-ai-help/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+// samplingFlag is the --temperature or --top-p flag; the value stays nil unless the flag is
+// given, and is checked against its range when parsed
+type samplingFlag struct {
+	value *float64
+	max   float64
+}
+
+// Sampling flags given on the command line; they override the configured values
+var (
+	temperatureFlag = samplingFlag{max: 2}
+	topPFlag        = samplingFlag{max: 1}
+)
+
+// commandTemperatures are the built-in temperatures of commands that generate configuration,
+// where repeatable answers matter more than variety
+var commandTemperatures = map[string]float64{
+	"configure":    0.2,
+	"package-repo": 0.2,
+}
+
+// samplingCommand is the top-level command being run; it selects the per-command sampling
+var samplingCommand string
+
+// String returns the flag value
+func (f *samplingFlag) String() string {
+	if f.value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f.value, 'g', -1, 64)
+}
+
+// Set validates and stores a flag value
+func (f *samplingFlag) Set(value string) error {
+	v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || v < 0 || v > f.max {
+		return fmt.Errorf("must be a number between 0 and %g", f.max)
+	}
+	f.value = &v
+	return nil
+}
+
+// Type names the flag value in help output
+func (f *samplingFlag) Type() string {
+	return "float"
+}
+
+// topLevelCommandName returns the name of the root subcommand a command belongs to, such as
+// configure for 'nixai configure' or gc for 'nixai gc analyze'
+func topLevelCommandName(cmd *cobra.Command) string {
+	for cmd.HasParent() && cmd.Parent().HasParent() {
+		cmd = cmd.Parent()
+	}
+	if !cmd.HasParent() {
+		return ""
+	}
+	return cmd.Name()
+}
+
+// resolveSampling returns the temperature and top_p for a command. The configured defaults are
+// overridden by the command's built-in temperature, then by its ai_sampling.commands entry and
+// finally by the --temperature and --top-p flags.
+func resolveSampling(cfg *config.UserConfig, command string) config.AISamplingConfig {
+	sampling := config.AISamplingConfig{Temperature: cfg.AISampling.Temperature, TopP: cfg.AISampling.TopP}
+	if temperature, ok := commandTemperatures[command]; ok {
+		sampling.Temperature = &temperature
+	}
+	if override, ok := cfg.AISampling.Commands[command]; ok {
+		if override.Temperature != nil {
+			sampling.Temperature = override.Temperature
+		}
+		if override.TopP != nil {
+			sampling.TopP = override.TopP
+		}
+	}
+	if temperatureFlag.value != nil {
+		sampling.Temperature = temperatureFlag.value
+	}
+	if topPFlag.value != nil {
+		sampling.TopP = topPFlag.value
+	}
+	return sampling
+}
+
+// withCommandSampling returns a copy of the configuration whose ai_sampling holds the resolved
+// sampling of the command, which the provider manager passes on to the providers
+func withCommandSampling(cfg *config.UserConfig, command string) *config.UserConfig {
+	resolved := *cfg
+	resolved.AISampling = resolveSampling(cfg, command)
+	return &resolved
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"testing"
+
+	"nix-ai-help/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+func float64Value(v *float64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func TestResolveSampling(t *testing.T) {
+	defer func() { temperatureFlag.value, topPFlag.value = nil, nil }()
+	temperature, topP, override := 0.8, 0.95, 0.5
+	cfg := &config.UserConfig{AISampling: config.AISamplingConfig{
+		Temperature: &temperature,
+		TopP:        &topP,
+		Commands:    map[string]config.AISamplingConfig{"ask": {Temperature: &override}},
+	}}
+
+	tests := []struct {
+		name            string
+		command         string
+		flags           map[*samplingFlag]string
+		wantTemperature interface{}
+		wantTopP        interface{}
+	}{
+		{"config defaults", "explain-option", nil, 0.8, 0.95},
+		{"built-in command default", "configure", nil, 0.2, 0.95},
+		{"config command override", "ask", nil, 0.5, 0.95},
+		{"flags win", "configure", map[*samplingFlag]string{&temperatureFlag: "1.3", &topPFlag: "0.4"}, 1.3, 0.4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			temperatureFlag.value, topPFlag.value = nil, nil
+			for flag, value := range tt.flags {
+				if err := flag.Set(value); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got := resolveSampling(cfg, tt.command)
+			if float64Value(got.Temperature) != tt.wantTemperature || float64Value(got.TopP) != tt.wantTopP {
+				t.Errorf("resolveSampling = %v/%v, want %v/%v", float64Value(got.Temperature), float64Value(got.TopP), tt.wantTemperature, tt.wantTopP)
+			}
+		})
+	}
+
+	// Without configuration only the built-in defaults apply
+	temperatureFlag.value, topPFlag.value = nil, nil
+	if got := resolveSampling(&config.UserConfig{}, "ask"); got.Temperature != nil || got.TopP != nil {
+		t.Errorf("unconfigured ask sampling = %v/%v, want provider defaults", float64Value(got.Temperature), float64Value(got.TopP))
+	}
+}
+
+func TestSamplingFlagRange(t *testing.T) {
+	flag := samplingFlag{max: 1}
+	for _, value := range []string{"-0.1", "1.5", "warm"} {
+		if err := flag.Set(value); err == nil {
+			t.Errorf("Set(%q) should fail", value)
+		}
+	}
+	if err := flag.Set("0.7"); err != nil || flag.String() != "0.7" {
+		t.Errorf("Set(0.7) = %v, value %s", err, flag.String())
+	}
+}
+
+func TestTopLevelCommandName(t *testing.T) {
+	root := &cobra.Command{Use: "nixai"}
+	gc := &cobra.Command{Use: "gc"}
+	analyze := &cobra.Command{Use: "analyze"}
+	gc.AddCommand(analyze)
+	root.AddCommand(gc)
+
+	for cmd, want := range map[*cobra.Command]string{root: "", gc: "gc", analyze: "gc"} {
+		if got := topLevelCommandName(cmd); got != want {
+			t.Errorf("topLevelCommandName(%s) = %q, want %q", cmd.Name(), got, want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"nix-ai-help/internal/nixos"
+	"nix-ai-help/pkg/utils"
+)
+
+// searchExplain is the --explain flag of search
+var searchExplain bool
+
+// InstallOrEnable tells whether a search query names a package, a NixOS service or both
+type InstallOrEnable struct {
+	Package string `json:"package,omitempty"` // attribute name, e.g. nginx
+	Service string `json:"service,omitempty"` // option tree, e.g. services.nginx
+	// ServiceUnknown is set when the option index was unavailable, so that services could not
+	// be looked up and the query may still name one
+	ServiceUnknown bool `json:"service_unknown,omitempty"`
+}
+
+// Both reports whether the query is available as a package and as a service
+func (m InstallOrEnable) Both() bool {
+	return m.Package != "" && m.Service != ""
+}
+
+// detectInstallOrEnable finds the package whose name is the query and the service option tree
+// with an enable option named after it. Only exact names count: nginxMainline or
+// services.prometheus.exporters.nginx are related, but not what the query names.
+func detectInstallOrEnable(query string, pkgs []nixos.NixPackage, options []string) InstallOrEnable {
+	name := searchName(query)
+	var match InstallOrEnable
+	if name == "" {
+		return match
+	}
+
+	for _, pkg := range pkgs {
+		attr := pkg.AttrPath[strings.LastIndex(pkg.AttrPath, ".")+1:]
+		if searchName(attr) == name || searchName(pkg.Pname) == name {
+			match.Package = attr
+			break
+		}
+	}
+
+	for _, service := range findServiceOptions(options, query) {
+		if searchName(strings.TrimPrefix(service.Service, "services.")) != name {
+			continue
+		}
+		for _, opt := range service.Options {
+			if opt.Name == service.Service+".enable" {
+				match.Service = service.Service
+			}
+		}
+	}
+	return match
+}
+
+// searchName reduces a query or name to lowercase letters and digits, so that "home assistant"
+// matches home-assistant
+func searchName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// installOrEnableNote explains how to get what the query names: enabling its service, or
+// installing its package
+func installOrEnableNote(query string, match InstallOrEnable) string {
+	switch {
+	case match.Both():
+		return fmt.Sprintf("%q is both a package (pkgs.%s) and a NixOS service (%s.enable).\n"+
+			"You probably want the service: %s.enable = true; installs the package and also sets up "+
+			"its systemd unit, user and configuration.\n"+
+			"Add pkgs.%s to environment.systemPackages only to use its commands without running the service.",
+			query, match.Package, match.Service, match.Service, match.Package)
+	case match.Service != "":
+		return fmt.Sprintf("%q is a NixOS service: enable it with %s.enable = true;",
+			query, match.Service)
+	case match.Package != "" && match.ServiceUnknown:
+		return fmt.Sprintf("%q is a package: install it by adding pkgs.%s to environment.systemPackages, "+
+			"or to home.packages with Home Manager.\n"+
+			"NixOS services could not be checked without the option index; start the MCP server with: nixai mcp-server start",
+			query, match.Package)
+	case match.Package != "":
+		return fmt.Sprintf("%q is a package without a NixOS service: install it by adding pkgs.%s to "+
+			"environment.systemPackages, or to home.packages with Home Manager.", query, match.Package)
+	case match.ServiceUnknown:
+		return fmt.Sprintf("No package is named exactly %q, and NixOS services could not be checked without the option index.\n"+
+			"Start the MCP server with: nixai mcp-server start", query)
+	default:
+		return fmt.Sprintf("No package or NixOS service is named exactly %q; check the results above for similar names.", query)
+	}
+}
+
+// installOrEnablePrompt steers the AI to explain whether to install the package or enable the
+// service, so that it does not give install instructions for something that should run as a
+// service
+func installOrEnablePrompt(match InstallOrEnable) string {
+	switch {
+	case match.Both():
+		return fmt.Sprintf("This is available both as the package pkgs.%s and as the NixOS service %s. "+
+			"Start by explaining the difference: adding the package to environment.systemPackages only puts its "+
+			"commands on the PATH, while %s.enable = true; installs it and runs it as a configured system service. "+
+			"Recommend the service for running it, show a minimal %s configuration, and mention the package only "+
+			"for using its commands without the service.",
+			match.Package, match.Service, match.Service, match.Service)
+	case match.Service != "":
+		return fmt.Sprintf("This is a NixOS service: configure it through %s.enable and the other %s.* options "+
+			"rather than by installing a package.", match.Service, match.Service)
+	case match.Package != "" && match.ServiceUnknown:
+		// Whether a service exists is unknown, so the AI is not told there is none
+		return fmt.Sprintf("This is available as the package pkgs.%s: it can be installed with "+
+			"environment.systemPackages = [ pkgs.%s ];.", match.Package, match.Package)
+	case match.Package != "":
+		return fmt.Sprintf("This is a package without a NixOS service module: install it with "+
+			"environment.systemPackages = [ pkgs.%s ]; and do not invent a services.* option for it.", match.Package)
+	default:
+		return ""
+	}
+}
+
+// printInstallOrEnable prints the note of --explain: what the query is, then how to use it
+func printInstallOrEnable(out io.Writer, query string, match InstallOrEnable) {
+	_, _ = fmt.Fprintln(out, utils.FormatSubsection("📦 Install or Enable?", ""))
+	for i, line := range strings.Split(installOrEnableNote(query, match), "\n") {
+		if i == 0 {
+			_, _ = fmt.Fprintln(out, utils.FormatInfo(line))
+		} else {
+			_, _ = fmt.Fprintln(out, utils.FormatTip(line))
+		}
+	}
+	_, _ = fmt.Fprintln(out)
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/nixos"
+)
+
+func TestDetectInstallOrEnable(t *testing.T) {
+	options := []string{
+		"services.nginx.enable",
+		"services.nginx.virtualHosts.<name>.root",
+		"services.prometheus.exporters.nginx.enable",
+		"services.home-assistant.enable",
+		"services.postgresql.package",
+		"programs.git.enable",
+	}
+	pkgs := []nixos.NixPackage{
+		{AttrPath: "legacyPackages.x86_64-linux.nginxMainline", Pname: "nginx-mainline"},
+		{AttrPath: "legacyPackages.x86_64-linux.nginx", Pname: "nginx"},
+		{AttrPath: "legacyPackages.x86_64-linux.ripgrep", Pname: "ripgrep"},
+		{AttrPath: "legacyPackages.x86_64-linux.home-assistant", Pname: "homeassistant"},
+		{AttrPath: "legacyPackages.x86_64-linux.postgresql", Pname: "postgresql"},
+	}
+
+	tests := []struct {
+		query string
+		want  InstallOrEnable
+	}{
+		{"nginx", InstallOrEnable{Package: "nginx", Service: "services.nginx"}},
+		{"NGINX", InstallOrEnable{Package: "nginx", Service: "services.nginx"}},
+		{"home assistant", InstallOrEnable{Package: "home-assistant", Service: "services.home-assistant"}},
+		{"ripgrep", InstallOrEnable{Package: "ripgrep"}},
+		// Without an enable option, postgresql has no service to enable here
+		{"postgresql", InstallOrEnable{Package: "postgresql"}},
+		// Related names are not the query
+		{"prometheus", InstallOrEnable{}},
+		{"git", InstallOrEnable{}},
+		{"", InstallOrEnable{}},
+	}
+	for _, tt := range tests {
+		if got := detectInstallOrEnable(tt.query, pkgs, options); got != tt.want {
+			t.Errorf("detectInstallOrEnable(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestDetectInstallOrEnable_ServiceWithoutPackageResults(t *testing.T) {
+	got := detectInstallOrEnable("nginx", nil, []string{"services.nginx.enable"})
+	if got.Both() || got.Service != "services.nginx" {
+		t.Errorf("got %+v, want only the service", got)
+	}
+}
+
+func TestInstallOrEnableNote(t *testing.T) {
+	both := InstallOrEnable{Package: "nginx", Service: "services.nginx"}
+	note := installOrEnableNote("nginx", both)
+	for _, want := range []string{"both a package (pkgs.nginx)", "services.nginx.enable = true;", "environment.systemPackages"} {
+		if !strings.Contains(note, want) {
+			t.Errorf("note %q does not contain %q", note, want)
+		}
+	}
+	if prompt := installOrEnablePrompt(both); !strings.Contains(prompt, "Recommend the service") {
+		t.Errorf("prompt does not steer towards the service: %q", prompt)
+	}
+
+	if note := installOrEnableNote("ripgrep", InstallOrEnable{Package: "ripgrep"}); !strings.Contains(note, "without a NixOS service") {
+		t.Errorf("package-only note = %q", note)
+	}
+	if prompt := installOrEnablePrompt(InstallOrEnable{}); prompt != "" {
+		t.Errorf("expected no prompt steering without a match, got %q", prompt)
+	}
+}
+
+func TestInstallOrEnableWithoutOptionIndex(t *testing.T) {
+	// Without the option index there is no service verdict to give
+	match := InstallOrEnable{Package: "nginx", ServiceUnknown: true}
+	note := installOrEnableNote("nginx", match)
+	if strings.Contains(note, "without a NixOS service") || !strings.Contains(note, "could not be checked") {
+		t.Errorf("note = %q", note)
+	}
+	if prompt := installOrEnablePrompt(match); strings.Contains(prompt, "services.*") || !strings.Contains(prompt, "pkgs.nginx") {
+		t.Errorf("prompt = %q", prompt)
+	}
+
+	unknown := InstallOrEnable{ServiceUnknown: true}
+	if note := installOrEnableNote("nginx", unknown); strings.Contains(note, "No package or NixOS service") {
+		t.Errorf("note claims there is no service: %q", note)
+	}
+	if prompt := installOrEnablePrompt(unknown); prompt != "" {
+		t.Errorf("expected no prompt steering, got %q", prompt)
+	}
+}
+
+func TestPrintInstallOrEnable(t *testing.T) {
+	var out bytes.Buffer
+	printInstallOrEnable(&out, "nginx", InstallOrEnable{Package: "nginx", Service: "services.nginx"})
+
+	text := out.String()
+	if !strings.Contains(text, "Install or Enable?") || !strings.Contains(text, "You probably want the service") {
+		t.Errorf("unexpected output:\n%s", text)
+	}
+}
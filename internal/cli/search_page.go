@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"fmt"
+
+	"nix-ai-help/internal/nixos"
+)
+
+// defaultSearchLimit is the number of packages search shows per page
+const defaultSearchLimit = 20
+
+var (
+	// searchLimit is the --limit flag of search; 0 shows every result
+	searchLimit int
+	// searchOffset is the --offset flag of search, the number of results to skip
+	searchOffset int
+)
+
+// validateSearchPage rejects negative --limit and --offset values
+func validateSearchPage(limit, offset int) error {
+	if limit < 0 {
+		return fmt.Errorf("--limit must not be negative, got %d", limit)
+	}
+	if offset < 0 {
+		return fmt.Errorf("--offset must not be negative, got %d", offset)
+	}
+	return nil
+}
+
+// paginatePackages returns the page of pkgs starting at offset with at most limit packages;
+// a limit of 0 returns everything from offset on
+func paginatePackages(pkgs []nixos.NixPackage, limit, offset int) []nixos.NixPackage {
+	if offset >= len(pkgs) {
+		return nil
+	}
+	end := len(pkgs)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return pkgs[offset:end]
+}
+
+// packagePageFooter describes which results a page shows, e.g. "Showing 1-20 of 137 packages",
+// and how to get the next page
+func packagePageFooter(offset, shown, total int) string {
+	if shown == 0 {
+		return fmt.Sprintf("No packages at offset %d, the search found %d", offset, total)
+	}
+	footer := fmt.Sprintf("Showing %d-%d of %d packages", offset+1, offset+shown, total)
+	if next := offset + shown; next < total {
+		footer += fmt.Sprintf(" (next page: --offset %d)", next)
+	}
+	return footer
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"fmt"
+	"testing"
+
+	"nix-ai-help/internal/nixos"
+)
+
+func testPackages(n int) []nixos.NixPackage {
+	pkgs := make([]nixos.NixPackage, n)
+	for i := range pkgs {
+		pkgs[i] = nixos.NixPackage{AttrPath: fmt.Sprintf("legacyPackages.x86_64-linux.pkg%03d", i)}
+	}
+	return pkgs
+}
+
+func TestPaginatePackages(t *testing.T) {
+	pkgs := testPackages(137)
+	tests := []struct {
+		limit, offset   int
+		wantLen         int
+		wantFirstAttrNo int
+	}{
+		{20, 0, 20, 0},
+		{20, 20, 20, 20},
+		{20, 130, 7, 130},
+		{0, 0, 137, 0},
+		{0, 100, 37, 100},
+		{20, 137, 0, -1},
+		{20, 500, 0, -1},
+	}
+	for _, tt := range tests {
+		page := paginatePackages(pkgs, tt.limit, tt.offset)
+		if len(page) != tt.wantLen {
+			t.Errorf("limit %d offset %d: got %d packages, want %d", tt.limit, tt.offset, len(page), tt.wantLen)
+			continue
+		}
+		if tt.wantFirstAttrNo >= 0 && page[0].AttrPath != pkgs[tt.wantFirstAttrNo].AttrPath {
+			t.Errorf("limit %d offset %d: page starts at %s", tt.limit, tt.offset, page[0].AttrPath)
+		}
+	}
+}
+
+func TestPackagePageFooter(t *testing.T) {
+	tests := []struct {
+		offset, shown, total int
+		want                 string
+	}{
+		{0, 20, 137, "Showing 1-20 of 137 packages (next page: --offset 20)"},
+		{20, 20, 137, "Showing 21-40 of 137 packages (next page: --offset 40)"},
+		{120, 17, 137, "Showing 121-137 of 137 packages"},
+		{0, 3, 3, "Showing 1-3 of 3 packages"},
+		{200, 0, 137, "No packages at offset 200, the search found 137"},
+	}
+	for _, tt := range tests {
+		if got := packagePageFooter(tt.offset, tt.shown, tt.total); got != tt.want {
+			t.Errorf("packagePageFooter(%d, %d, %d) = %q, want %q", tt.offset, tt.shown, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSearchPage(t *testing.T) {
+	if err := validateSearchPage(20, 0); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := validateSearchPage(-1, 0); err == nil {
+		t.Error("expected an error for a negative limit")
+	}
+	if err := validateSearchPage(20, -5); err == nil {
+		t.Error("expected an error for a negative offset")
+	}
+}
@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/internal/mcp"
+	"nix-ai-help/pkg/utils"
+)
+
+const (
+	// maxServiceOptions limits the options listed per service by search --service
+	maxServiceOptions = 25
+	// maxServiceMatches limits the service trees listed by search --service
+	maxServiceMatches = 5
+	// maxServiceDescription is the longest option description shown in the list
+	maxServiceDescription = 90
+	// describeConcurrency bounds the parallel option lookups against the MCP server
+	describeConcurrency = 8
+)
+
+// noOptionIndexWarning is shown when the option index of the MCP server cannot be reached
+const noOptionIndexWarning = "No option index available; start the MCP server with: nixai mcp-server start"
+
+// ServiceOption is a top-level option of a NixOS service
+type ServiceOption struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ServiceMatch is a service option tree matching a search, e.g. services.nginx
+type ServiceMatch struct {
+	Service string          `json:"service"`
+	Options []ServiceOption `json:"options"`
+	More    int             `json:"more"`
+}
+
+// optionDescriber returns the description of an option
+type optionDescriber func(option string) string
+
+// findServiceOptions groups the services.* options whose service name matches every word of
+// the query, and returns the direct children of each service tree. Exact service name
+// matches come first.
+func findServiceOptions(options []string, query string) []ServiceMatch {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	children := make(map[string]map[string]bool)
+	for _, option := range options {
+		parts := strings.SplitN(option, ".", 4)
+		if len(parts) < 3 || parts[0] != "services" || !matchesAllTerms(strings.ToLower(parts[1]), terms) {
+			continue
+		}
+		service := parts[0] + "." + parts[1]
+		if children[service] == nil {
+			children[service] = make(map[string]bool)
+		}
+		children[service][service+"."+parts[2]] = true
+	}
+
+	exact := "services." + strings.Join(terms, "")
+	matches := make([]ServiceMatch, 0, len(children))
+	for service, names := range children {
+		match := ServiceMatch{Service: service}
+		for name := range names {
+			match.Options = append(match.Options, ServiceOption{Name: name})
+		}
+		sort.Slice(match.Options, func(i, j int) bool {
+			// enable is the option people look for first
+			iEnable, jEnable := strings.HasSuffix(match.Options[i].Name, ".enable"), strings.HasSuffix(match.Options[j].Name, ".enable")
+			if iEnable != jEnable {
+				return iEnable
+			}
+			return match.Options[i].Name < match.Options[j].Name
+		})
+		if len(match.Options) > maxServiceOptions {
+			match.More = len(match.Options) - maxServiceOptions
+			match.Options = match.Options[:maxServiceOptions]
+		}
+		matches = append(matches, match)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		iExact, jExact := strings.EqualFold(matches[i].Service, exact), strings.EqualFold(matches[j].Service, exact)
+		if iExact != jExact {
+			return iExact
+		}
+		return matches[i].Service < matches[j].Service
+	})
+	return matches
+}
+
+// matchesAllTerms reports whether name contains every term
+func matchesAllTerms(name string, terms []string) bool {
+	for _, term := range terms {
+		if !strings.Contains(name, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// describeServiceOptions fills in option descriptions concurrently
+func describeServiceOptions(matches []ServiceMatch, describe optionDescriber) {
+	if describe == nil {
+		return
+	}
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, describeConcurrency)
+	for i := range matches {
+		for j := range matches[i].Options {
+			wg.Add(1)
+			go func(opt *ServiceOption) {
+				defer wg.Done()
+				slots <- struct{}{}
+				defer func() { <-slots }()
+				opt.Description = shortDescription(describe(opt.Name))
+			}(&matches[i].Options[j])
+		}
+	}
+	wg.Wait()
+}
+
+// shortDescription returns the first sentence of a description on a single line
+func shortDescription(description string) string {
+	description = strings.Join(strings.Fields(description), " ")
+	if i := strings.Index(description, ". "); i >= 0 {
+		description = description[:i+1]
+	}
+	if runes := []rune(description); len(runes) > maxServiceDescription {
+		description = strings.TrimSpace(string(runes[:maxServiceDescription-1])) + "…"
+	}
+	return description
+}
+
+// mcpOptionDescriber describes options through the MCP server, from the option index of the
+// detected release, or returns nil without one
+func mcpOptionDescriber(cfg *config.UserConfig, nixosCtx *config.NixOSContext) optionDescriber {
+	if cfg.MCPServer.Host == "" {
+		return nil
+	}
+	client := newDocsClient(cfg, nixosCtx)
+	return func(option string) string {
+		opt, err := client.QueryOption(option)
+		if err != nil || opt == nil {
+			return ""
+		}
+		return opt.Description
+	}
+}
+
+// serviceOptionQuery looks up the services.* options whose name contains the first word of the
+// query in the option index; findServiceOptions narrows them down to the services matching
+// every word
+func serviceOptionQuery(query, release string) optionIndexQuery {
+	lookup := optionIndexQuery{Source: mcp.OptionSourceNixOS, Release: release, Prefix: "services."}
+	if terms := strings.Fields(strings.ToLower(query)); len(terms) > 0 {
+		lookup.Contains = terms[0]
+	}
+	return lookup
+}
+
+// printServiceOptions lists the matching service option trees with explain-option hints
+func printServiceOptions(out io.Writer, query string, matches []ServiceMatch) {
+	if len(matches) == 0 {
+		_, _ = fmt.Fprintln(out, utils.FormatWarning("No service options found for: "+query))
+		_, _ = fmt.Fprintln(out, utils.FormatTip("Search packages instead with: nixai search "+query))
+		return
+	}
+
+	for _, match := range matches {
+		_, _ = fmt.Fprintln(out, utils.FormatSubsection("🔧 "+match.Service, ""))
+		for _, opt := range match.Options {
+			if opt.Description != "" {
+				_, _ = fmt.Fprintf(out, "  %s — %s\n", opt.Name, opt.Description)
+			} else {
+				_, _ = fmt.Fprintf(out, "  %s\n", opt.Name)
+			}
+		}
+		if match.More > 0 {
+			_, _ = fmt.Fprintf(out, "  … and %d more\n", match.More)
+		}
+		_, _ = fmt.Fprintln(out)
+	}
+	_, _ = fmt.Fprintln(out, utils.FormatTip("Explain an option with: nixai explain-option "+matches[0].Options[0].Name))
+}
+
+// runServiceSearch lists the service options matching query, described by describe when set.
+// indexed is false when the option index could not be reached.
+func runServiceSearch(out io.Writer, query string, options []string, indexed bool, describe optionDescriber) {
+	if !indexed {
+		_, _ = fmt.Fprintln(out, utils.FormatWarning(noOptionIndexWarning))
+		return
+	}
+
+	printServiceOptions(out, query, searchServiceOptions(options, query, describe))
+}
+
+// searchServiceOptions returns the described service option trees matching query, at most
+// maxServiceMatches of them
+func searchServiceOptions(options []string, query string, describe optionDescriber) []ServiceMatch {
+	matches := findServiceOptions(options, query)
+	if len(matches) > maxServiceMatches {
+		matches = matches[:maxServiceMatches]
+	}
+	describeServiceOptions(matches, describe)
+	return matches
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/mcp"
+)
+
+// stubServiceIndex is an option index with a few service option trees
+func stubServiceIndex() optionIndex {
+	return func(prefix, contains string) ([]string, error) {
+		return []string{
+			"services.nginx.enable",
+			"services.nginx.package",
+			"services.nginx.virtualHosts.<name>.root",
+			"services.nginx.virtualHosts.<name>.forceSSL",
+			"services.nginx.recommendedTlsSettings",
+			"services.prometheus.exporters.nginx.enable",
+			"services.nginxAuth.enable",
+			"services.openssh.enable",
+			"networking.firewall.enable",
+		}, nil
+	}
+}
+
+func TestFindServiceOptions(t *testing.T) {
+	options, _ := loadOptionNames(filepath.Join(t.TempDir(), "options.json"), func(source, release string) optionIndex { return stubServiceIndex() },
+		optionIndexQuery{Source: mcp.OptionSourceNixOS, Prefix: "services."})
+	matches := findServiceOptions(options, "nginx")
+
+	if len(matches) != 2 {
+		t.Fatalf("expected services.nginx and services.nginxAuth, got %+v", matches)
+	}
+	if matches[0].Service != "services.nginx" || matches[1].Service != "services.nginxAuth" {
+		t.Errorf("exact match should come first, got %s, %s", matches[0].Service, matches[1].Service)
+	}
+
+	var names []string
+	for _, opt := range matches[0].Options {
+		names = append(names, opt.Name)
+	}
+	want := "services.nginx.enable,services.nginx.package,services.nginx.recommendedTlsSettings,services.nginx.virtualHosts"
+	if strings.Join(names, ",") != want {
+		t.Errorf("top-level options = %v, want %s", names, want)
+	}
+}
+
+func TestFindServiceOptions_NoMatch(t *testing.T) {
+	options, _ := stubServiceIndex()("", "")
+	if matches := findServiceOptions(options, "postgres"); len(matches) != 0 {
+		t.Errorf("expected no matches, got %+v", matches)
+	}
+}
+
+func TestRunServiceSearch(t *testing.T) {
+	options, _ := stubServiceIndex()("", "")
+	descriptions := map[string]string{
+		"services.nginx.enable": "Whether to enable Nginx Web Server. Defaults to false.",
+	}
+
+	var out bytes.Buffer
+	runServiceSearch(&out, "nginx", options, true, func(option string) string { return descriptions[option] })
+	result := out.String()
+
+	for _, want := range []string{
+		"services.nginx.enable — Whether to enable Nginx Web Server.",
+		"services.nginx.virtualHosts",
+		"services.nginxAuth",
+		"nixai explain-option services.nginx.enable",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("output missing %q:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "Defaults to false") {
+		t.Error("only the first sentence of a description should be shown")
+	}
+}
+
+func TestRunServiceSearch_NoIndex(t *testing.T) {
+	var out bytes.Buffer
+	runServiceSearch(&out, "nginx", nil, false, nil)
+	if !strings.Contains(out.String(), "No option index available") {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+
+	// An index without matches is not an unavailable index
+	out.Reset()
+	runServiceSearch(&out, "postgres", nil, true, nil)
+	if !strings.Contains(out.String(), "No service options found for: postgres") {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+}
+
+func TestServiceOptionQuery(t *testing.T) {
+	got := serviceOptionQuery("Home Assistant", "24.05")
+	want := optionIndexQuery{Source: mcp.OptionSourceNixOS, Release: "24.05", Prefix: "services.", Contains: "home"}
+	if got != want {
+		t.Errorf("serviceOptionQuery = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"nix-ai-help/pkg/utils"
+)
+
+// snippetInsertMarker marks where snippets apply inserts into a config; the marker is kept so
+// that later snippets land after the earlier ones
+const snippetInsertMarker = "# nixai:insert"
+
+// moduleHeaderPattern matches the argument set of a NixOS module function and its opening brace
+var moduleHeaderPattern = regexp.MustCompile(`^\{[^{}]*\}\s*:\s*\{`)
+
+// snippetBody returns the attributes of a snippet without their common indentation: the body
+// of a module function saved as a snippet, or the snippet itself when it is a plain list of
+// assignments
+func snippetBody(content string) string {
+	if module := strings.TrimSpace(content); strings.HasSuffix(module, "}") {
+		if loc := moduleHeaderPattern.FindStringIndex(module); loc != nil {
+			content = module[loc[1] : len(module)-1]
+		}
+	}
+
+	lines := strings.Split(strings.Trim(content, "\n"), "\n")
+	common := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if indent := len(line) - len(strings.TrimLeft(line, " \t")); common < 0 || indent < common {
+			common = indent
+		}
+	}
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ""
+		} else if common > 0 {
+			lines[i] = line[common:]
+		}
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// indentSnippet indents every non-empty line of a snippet
+func indentSnippet(body, indent string) string {
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// insertSnippet inserts a snippet into a NixOS configuration, before the `# nixai:insert` marker
+// when the configuration has one and otherwise before the closing brace of its attribute set
+func insertSnippet(configText, snippet, name string) (string, error) {
+	body := snippetBody(snippet)
+	if body == "" {
+		return "", fmt.Errorf("snippet %s is empty", name)
+	}
+
+	if idx := strings.Index(configText, snippetInsertMarker); idx >= 0 {
+		lineStart := strings.LastIndex(configText[:idx], "\n") + 1
+		prefix := configText[lineStart:idx]
+		indent := prefix[:len(prefix)-len(strings.TrimLeft(prefix, " \t"))]
+		block := indentSnippet("# Added from snippet: "+name+"\n"+body, indent) + "\n"
+		return configText[:lineStart] + block + configText[lineStart:], nil
+	}
+
+	closing := strings.LastIndex(configText, "}")
+	if closing < 0 {
+		return "", fmt.Errorf("no closing brace found to insert before; add a '%s' marker where the snippet should go", snippetInsertMarker)
+	}
+	lineStart := strings.LastIndex(configText[:closing], "\n") + 1
+	before := configText[:lineStart]
+	if strings.TrimSpace(configText[lineStart:closing]) != "" {
+		// Code shares the line with the closing brace, so break the line first
+		before = configText[:closing] + "\n"
+		lineStart = closing
+	}
+	block := "\n" + indentSnippet("# Added from snippet: "+name+"\n"+body, "  ") + "\n"
+	return before + block + configText[lineStart:], nil
+}
+
+// backupFile copies a file next to itself with a timestamp suffix and returns the copy's path
+func backupFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	backup := fmt.Sprintf("%s.%s.bak", path, time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(backup, data, info.Mode().Perm()); err != nil {
+		return "", fmt.Errorf("failed to write backup %s: %w", backup, err)
+	}
+	return backup, nil
+}
+
+// applySnippetToFile inserts a snippet into the configuration at target after backing it up and
+// returns the backup path. It refuses to write when the snippet sets options the configuration
+// already sets, unless force is set, and asks before writing as confirm allows.
+func applySnippetToFile(out io.Writer, snippet *Snippet, target string, force bool, confirm utils.WriteConfirm) (string, error) {
+	data, err := os.ReadFile(target)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", target, err)
+	}
+	existing := string(data)
+	updated, err := insertSnippet(existing, snippet.Content, snippet.Name)
+	if err != nil {
+		return "", err
+	}
+	if err := checkDuplicateOptions(out, existing, snippet.Content, force); err != nil {
+		return "", err
+	}
+
+	backup, err := backupFile(target)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return "", err
+	}
+	if err := utils.WriteFileWithConfirm(target, []byte(updated), info.Mode().Perm(), confirm); err != nil {
+		// Nothing was changed, so the backup is not needed
+		_ = os.Remove(backup)
+		return "", err
+	}
+	return backup, nil
+}
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nix-ai-help/pkg/utils"
+)
+
+const snippetTestConfig = `{ config, pkgs, ... }:
+
+{
+  imports = [ ./hardware-configuration.nix ];
+
+  networking.hostName = "laptop";
+  services.openssh.enable = true;
+}
+`
+
+func TestInsertSnippetBeforeClosingBrace(t *testing.T) {
+	got, err := insertSnippet(snippetTestConfig, "services.nginx.enable = true;\n", "nginx-basic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{ config, pkgs, ... }:
+
+{
+  imports = [ ./hardware-configuration.nix ];
+
+  networking.hostName = "laptop";
+  services.openssh.enable = true;
+
+  # Added from snippet: nginx-basic
+  services.nginx.enable = true;
+}
+`
+	if got != want {
+		t.Errorf("unexpected result:\n%s", got)
+	}
+}
+
+func TestInsertSnippetAtMarker(t *testing.T) {
+	config := `{ config, pkgs, ... }:
+
+{
+  services.openssh.enable = true;
+    # nixai:insert
+
+  users.users.alice.isNormalUser = true;
+}
+`
+	module := `{ config, pkgs, ... }:
+{
+  services.nginx = {
+    enable = true;
+  };
+}`
+	got, err := insertSnippet(config, module, "nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{ config, pkgs, ... }:
+
+{
+  services.openssh.enable = true;
+    # Added from snippet: nginx
+    services.nginx = {
+      enable = true;
+    };
+    # nixai:insert
+
+  users.users.alice.isNormalUser = true;
+}
+`
+	if got != want {
+		t.Errorf("unexpected result:\n%s", got)
+	}
+}
+
+func TestInsertSnippetErrors(t *testing.T) {
+	if _, err := insertSnippet("# no attribute set here\n", "a.b = 1;", "x"); err == nil || !strings.Contains(err.Error(), snippetInsertMarker) {
+		t.Errorf("expected an error pointing to the marker, got %v", err)
+	}
+	if _, err := insertSnippet(snippetTestConfig, "  \n", "empty"); err == nil {
+		t.Error("expected an error for an empty snippet")
+	}
+}
+
+func TestApplySnippetToFileWritesBackup(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "configuration.nix")
+	if err := os.WriteFile(target, []byte(snippetTestConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	snippet := &Snippet{Name: "nginx-basic", Content: "services.nginx.enable = true;"}
+	backup, err := applySnippetToFile(&out, snippet, target, false, utils.WriteConfirm{AssumeYes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(backup); string(data) != snippetTestConfig {
+		t.Errorf("backup %s does not hold the original configuration", backup)
+	}
+	if data, _ := os.ReadFile(target); !strings.Contains(string(data), "# Added from snippet: nginx-basic") {
+		t.Errorf("snippet not inserted:\n%s", data)
+	}
+}
+
+func TestApplySnippetToFileRefusesDuplicates(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "configuration.nix")
+	if err := os.WriteFile(target, []byte(snippetTestConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	snippet := &Snippet{Name: "ssh-off", Content: "services.openssh.enable = false;"}
+	if _, err := applySnippetToFile(&out, snippet, target, false, utils.WriteConfirm{AssumeYes: true}); !errors.Is(err, errDuplicateOptions) {
+		t.Fatalf("expected errDuplicateOptions, got %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != snippetTestConfig {
+		t.Error("the configuration was modified despite the duplicate")
+	}
+
+	if _, err := applySnippetToFile(&out, snippet, target, true, utils.WriteConfirm{AssumeYes: true}); err != nil {
+		t.Fatalf("--force should insert the snippet, got %v", err)
+	}
+	if data, _ := os.ReadFile(target); !strings.Contains(string(data), "services.openssh.enable = false;") {
+		t.Errorf("snippet not inserted with --force:\n%s", data)
+	}
+}
+
+func TestApplySnippetToFileDeclined(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "configuration.nix")
+	if err := os.WriteFile(target, []byte(snippetTestConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	snippet := &Snippet{Name: "nginx-basic", Content: "services.nginx.enable = true;"}
+	confirm := utils.WriteConfirm{In: strings.NewReader("n\n"), Out: &out}
+	if _, err := applySnippetToFile(&out, snippet, target, false, confirm); !errors.Is(err, utils.ErrOverwriteDeclined) {
+		t.Fatalf("expected ErrOverwriteDeclined, got %v", err)
+	}
+	if !strings.Contains(out.String(), "+   # Added from snippet: nginx-basic") {
+		t.Errorf("expected a preview of the insertion:\n%s", out.String())
+	}
+	if data, _ := os.ReadFile(target); string(data) != snippetTestConfig {
+		t.Error("the configuration was modified although the user declined")
+	}
+	if backups, _ := filepath.Glob(filepath.Join(dir, "*.bak")); len(backups) != 0 {
+		t.Errorf("expected no backup to be left behind, found %q", backups)
+	}
+}
@@ -1,7 +1,9 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"os"
 
 	nixoscontext "nix-ai-help/internal/ai/context"
 	"nix-ai-help/internal/config"
@@ -116,16 +118,68 @@ Examples:
 var storePerformanceCmd = &cobra.Command{
 	Use:   "performance",
 	Short: "Analyze Nix store performance and usage",
-	Long: `Analyze the performance and usage of your Nix store.
+	Long: `Analyze the performance and usage of your Nix store: its size on disk, number of paths,
+the space saved by hard-linking identical files, the average closure size of recent system
+generations and whether nix.settings.auto-optimise-store is on. The AI then suggests how to
+improve them (skipped with --no-ai).
+
+Measuring the store reads every file in it and can take a while on large stores.
 
 Examples:
   nixai store performance
+  nixai store performance --json
+  nixai store performance --no-ai
 `,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println(utils.FormatHeader("⚡ Nix Store Performance Analysis"))
-		fmt.Println(utils.FormatProgress("Analyzing store performance..."))
-		// TODO: Implement performance analysis logic
-		fmt.Println(utils.FormatSuccess("Store performance analysis completed."))
+		cfg, err := config.LoadUserConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, utils.FormatError("Failed to load config: "+err.Error()))
+			os.Exit(1)
+		}
+		outputFormat, err := resolveOutputFormat(cmd.Flags(), cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+			os.Exit(1)
+		}
+		utils.DisableSpinners(outputFormat == outputJSON)
+
+		if outputFormat != outputJSON {
+			fmt.Println(utils.FormatHeader("⚡ Nix Store Performance Analysis"))
+		}
+		spinner := utils.StartSpinner("Measuring the Nix store...")
+		// Only the generations that are averaged need their closure measured
+		generations, _ := listGenerations()
+		recent := newestGenerations(generations, storePerformanceGenerations)
+		addGenerationDetails(recent)
+		perf := collectStorePerformance(runDoctorCheckCommand, recent)
+		spinner.Stop()
+
+		if outputFormat == outputJSON {
+			_ = writeJSONOutput(os.Stdout, perf)
+			return
+		}
+		printStorePerformance(os.Stdout, perf)
+		if recommendAutoOptimise(perf) {
+			printAutoOptimiseRecommendation(os.Stdout, perf)
+		}
+
+		aiProvider, err := optionalAIProvider(cfg, logger.NewLogger())
+		if err != nil {
+			if !errors.Is(err, errAIDisabled) {
+				fmt.Println(utils.FormatWarning("Failed to initialize AI provider: " + err.Error()))
+			}
+			return
+		}
+		spinner = utils.StartSpinner("Asking the AI for improvements...")
+		tips, err := aiProvider.Query(withLanguageInstruction(storePerformancePrompt(perf), cfg))
+		spinner.Stop()
+		if err != nil {
+			fmt.Println(utils.FormatWarning("AI error: " + err.Error()))
+			return
+		}
+		fmt.Println(utils.FormatSubsection("💡 Recommendations", ""))
+		fmt.Println(renderAnswer(outputFormat, tips))
 	},
 }
 
@@ -140,7 +194,13 @@ Available subcommands:
   restore       - Restore the Nix store and config from a backup
   integrity     - Check store and config integrity
   performance   - Analyze store performance and usage
+  optimize      - Deduplicate the store by hard-linking identical files
 `,
+	Example: `  # Back up the store and configuration
+  nixai store backup --output ~/nixos-backup.tar.gz
+
+  # Check store integrity
+  nixai store integrity`,
 }
 
 func init() {
@@ -148,5 +208,8 @@ func init() {
 	storeCmd.AddCommand(storeRestoreCmd)
 	storeCmd.AddCommand(storeIntegrityCmd)
 	storeCmd.AddCommand(storePerformanceCmd)
+	storeCmd.AddCommand(newStoreOptimizeCmd())
 	storeBackupCmd.Flags().StringP("output", "o", "", "Output file for backup archive")
+	addOutputFormatFlags(storePerformanceCmd)
+	addRawFlag(storePerformanceCmd)
 }
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"nix-ai-help/pkg/utils"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// storeOptimiseMinSize is the store size from which an unoptimised store is worth deduplicating
+	storeOptimiseMinSize = 5 << 30
+	// storeOptimiseMaxRatio is the dedup ratio below which the store counts as not deduplicated
+	storeOptimiseMaxRatio = 1.05
+	// autoOptimiseSetting is the NixOS option that deduplicates new store paths as they are added
+	autoOptimiseSetting = "nix.settings.auto-optimise-store = true;"
+)
+
+// optimiseFreedRegex matches the summary nix-store --optimise ends with, e.g.
+// "1234.56 MiB freed by hard-linking 5678 files"
+var optimiseFreedRegex = regexp.MustCompile(`([\d.]+) (B|KiB|MiB|GiB|TiB) freed by hard-linking (\d+) files`)
+
+// runStoreOptimise runs sudo -v and nix-store --optimise and returns their combined output,
+// where the summary is printed on stderr; replaced in tests
+var runStoreOptimise commandRunner = func(name string, args ...string) ([]byte, error) {
+	// #nosec G204 -- the command is built by storeOptimiseCommand
+	return utils.TraceCombinedOutput(exec.Command(name, args...))
+}
+
+// recommendAutoOptimise reports whether store performance should recommend
+// auto-optimise-store: it is known to be off and a large store is hardly deduplicated
+func recommendAutoOptimise(perf StorePerformance) bool {
+	if perf.AutoOptimise == nil || *perf.AutoOptimise || perf.DiskSize < storeOptimiseMinSize {
+		return false
+	}
+	return perf.DedupRatio < storeOptimiseMaxRatio
+}
+
+// printAutoOptimiseRecommendation explains how to deduplicate the store once and for good
+func printAutoOptimiseRecommendation(out io.Writer, perf StorePerformance) {
+	_, _ = fmt.Fprintln(out, utils.FormatWarning(fmt.Sprintf(
+		"auto-optimise-store is off and the %s store is hardly deduplicated; identical files in it take space more than once",
+		formatBytes(perf.DiskSize))))
+	_, _ = fmt.Fprintln(out, utils.FormatTip("Run 'nixai store optimize' to hard-link identical files now, then add this to your configuration to keep new paths deduplicated:"))
+	_, _ = fmt.Fprintln(out, "  "+autoOptimiseSetting)
+	_, _ = fmt.Fprintln(out)
+}
+
+// storeOptimiseCommand returns the command deduplicating the store, through sudo unless
+// running as root
+func storeOptimiseCommand(euid int) []string {
+	command := []string{"nix-store", "--optimise"}
+	if euid != 0 {
+		command = append([]string{"sudo"}, command...)
+	}
+	return command
+}
+
+// parseOptimiseFreed returns the bytes freed and files hard-linked reported by nix-store --optimise
+func parseOptimiseFreed(output string) (freed int64, files int, ok bool) {
+	matches := optimiseFreedRegex.FindStringSubmatch(output)
+	if matches == nil {
+		return 0, 0, false
+	}
+	amount, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	units := map[string]float64{"B": 1, "KiB": 1 << 10, "MiB": 1 << 20, "GiB": 1 << 30, "TiB": 1 << 40}
+	files, _ = strconv.Atoi(matches[3])
+	return int64(amount * units[matches[2]]), files, true
+}
+
+// optimizeStore asks for confirmation, unless assumeYes, runs command and reports the space
+// freed. A command run through sudo authenticates first. autoOptimise is the current auto-optimise-store setting, nil when unknown; unless it
+// is on, the option that makes deduplication permanent is suggested.
+func optimizeStore(in *bufio.Reader, out io.Writer, run commandRunner, command []string, autoOptimise *bool, assumeYes bool) error {
+	_, _ = fmt.Fprintln(out, utils.FormatKeyValue("Command", strings.Join(command, " ")))
+	if !assumeYes {
+		_, _ = fmt.Fprintln(out, utils.FormatNote("This hard-links identical files in /nix/store and can take a long time on large stores"))
+		_, _ = fmt.Fprint(out, "Optimise the store now? (y/N): ")
+		answer, _ := in.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "y" && answer != "yes" {
+			_, _ = fmt.Fprintln(out, utils.FormatInfo("Store optimisation cancelled"))
+			return nil
+		}
+	}
+
+	// Ask for the sudo password before the spinner starts, which would otherwise draw over the
+	// prompt and leave the command waiting for input the user cannot see being asked for
+	if command[0] == "sudo" {
+		if output, err := run("sudo", "-v"); err != nil {
+			if text := strings.TrimSpace(string(output)); text != "" {
+				_, _ = fmt.Fprintln(out, text)
+			}
+			return fmt.Errorf("sudo authentication failed: %w", err)
+		}
+	}
+
+	spinner := utils.StartSpinner("Optimising the Nix store...")
+	output, err := run(command[0], command[1:]...)
+	spinner.Stop()
+	if err != nil {
+		if text := strings.TrimSpace(string(output)); text != "" {
+			_, _ = fmt.Fprintln(out, text)
+		}
+		return fmt.Errorf("store optimisation failed: %w", err)
+	}
+
+	if freed, files, ok := parseOptimiseFreed(string(output)); ok {
+		_, _ = fmt.Fprintln(out, utils.FormatSuccess(fmt.Sprintf("Freed %s by hard-linking %d files", formatBytes(freed), files)))
+	} else {
+		_, _ = fmt.Fprintln(out, utils.FormatSuccess("Store optimised"))
+	}
+	if autoOptimise == nil || !*autoOptimise {
+		_, _ = fmt.Fprintln(out, utils.FormatTip("Keep new store paths deduplicated by adding this to your configuration and rebuilding:"))
+		_, _ = fmt.Fprintln(out, "  "+autoOptimiseSetting)
+	}
+	return nil
+}
+
+// newStoreOptimizeCmd creates the store optimize command
+func newStoreOptimizeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "optimize",
+		Aliases: []string{"optimise"},
+		Short:   "Deduplicate the Nix store by hard-linking identical files",
+		Long: `Run nix-store --optimise, which replaces identical files in the Nix store with hard links,
+and report the space freed. Asks for confirmation first unless --yes is given; runs through sudo
+when not root. Afterwards suggests nix.settings.auto-optimise-store to deduplicate new store
+paths automatically.
+
+Examples:
+  nixai store optimize
+  nixai store optimize --yes
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			assumeYes, _ := cmd.Flags().GetBool("yes")
+			if !assumeYes && !isInteractiveSession() {
+				fmt.Fprintln(os.Stderr, utils.FormatError("store optimize needs a terminal to confirm; pass --yes to run it without asking"))
+				os.Exit(1)
+			}
+			fmt.Println(utils.FormatHeader("🗜️ Nix Store Optimisation"))
+			autoOptimise := readAutoOptimise(runDoctorCheckCommand)
+			if err := optimizeStore(bufio.NewReader(os.Stdin), os.Stdout, runStoreOptimise, storeOptimiseCommand(os.Geteuid()), autoOptimise, assumeYes); err != nil {
+				fmt.Fprintln(os.Stderr, utils.FormatError(err.Error()))
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().BoolP("yes", "y", false, "Optimise without asking for confirmation")
+	return cmd
+}
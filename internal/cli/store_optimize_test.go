@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecommendAutoOptimise(t *testing.T) {
+	off, on := false, true
+	cases := []struct {
+		name string
+		perf StorePerformance
+		want bool
+	}{
+		{"large unoptimised store", StorePerformance{AutoOptimise: &off, DiskSize: 40 << 30, DedupRatio: 1.01}, true},
+		{"dedup ratio unknown", StorePerformance{AutoOptimise: &off, DiskSize: 40 << 30}, true},
+		{"already enabled", StorePerformance{AutoOptimise: &on, DiskSize: 40 << 30, DedupRatio: 1.01}, false},
+		{"setting unknown", StorePerformance{DiskSize: 40 << 30, DedupRatio: 1.01}, false},
+		{"small store", StorePerformance{AutoOptimise: &off, DiskSize: 1 << 30, DedupRatio: 1.0}, false},
+		{"optimised by hand", StorePerformance{AutoOptimise: &off, DiskSize: 40 << 30, DedupRatio: 1.4}, false},
+	}
+	for _, c := range cases {
+		if got := recommendAutoOptimise(c.perf); got != c.want {
+			t.Errorf("%s: recommendAutoOptimise = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestStoreOptimiseCommand(t *testing.T) {
+	if got := storeOptimiseCommand(0); !reflect.DeepEqual(got, []string{"nix-store", "--optimise"}) {
+		t.Errorf("as root got %q", got)
+	}
+	if got := storeOptimiseCommand(1000); !reflect.DeepEqual(got, []string{"sudo", "nix-store", "--optimise"}) {
+		t.Errorf("as a user got %q", got)
+	}
+}
+
+func TestParseOptimiseFreed(t *testing.T) {
+	freed, files, ok := parseOptimiseFreed("hashing files...\n1536.00 MiB freed by hard-linking 4321 files\n")
+	if !ok || freed != 1536<<20 || files != 4321 {
+		t.Errorf("got %d bytes, %d files, %v", freed, files, ok)
+	}
+	if _, _, ok := parseOptimiseFreed("error: cannot open connection to remote store 'daemon'"); ok {
+		t.Error("expected no summary in an error message")
+	}
+}
+
+func TestOptimizeStore(t *testing.T) {
+	var calls [][]string
+	run := func(name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+		return []byte("2.50 GiB freed by hard-linking 1200 files\n"), nil
+	}
+	command := storeOptimiseCommand(1000)
+
+	// Declining runs nothing
+	var out bytes.Buffer
+	if err := optimizeStore(bufio.NewReader(strings.NewReader("\n")), &out, run, command, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 0 || !strings.Contains(out.String(), "cancelled") {
+		t.Fatalf("expected the default answer to cancel, ran %q:\n%s", calls, out.String())
+	}
+
+	out.Reset()
+	off := false
+	if err := optimizeStore(bufio.NewReader(strings.NewReader("y\n")), &out, run, command, &off, false); err != nil {
+		t.Fatal(err)
+	}
+	// sudo asks for the password before the spinner starts
+	if len(calls) != 2 || !reflect.DeepEqual(calls[0], []string{"sudo", "-v"}) || !reflect.DeepEqual(calls[1], command) {
+		t.Fatalf("expected sudo -v and then %q, got %q", command, calls)
+	}
+	for _, want := range []string{"sudo nix-store --optimise", "Freed 2.5 GB by hard-linking 1200 files", autoOptimiseSetting} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q:\n%s", want, out.String())
+		}
+	}
+
+	// With --yes and auto-optimise-store on there is no question and no suggestion
+	out.Reset()
+	on := true
+	if err := optimizeStore(bufio.NewReader(strings.NewReader("")), &out, run, command, &on, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 4 || strings.Contains(out.String(), "(y/N)") || strings.Contains(out.String(), autoOptimiseSetting) {
+		t.Errorf("unexpected output with --yes:\n%s", out.String())
+	}
+}
+
+func TestOptimizeStoreSudoFailure(t *testing.T) {
+	var calls [][]string
+	run := func(name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+		return []byte("sudo: 3 incorrect password attempts\n"), errors.New("exit status 1")
+	}
+	var out bytes.Buffer
+	err := optimizeStore(bufio.NewReader(strings.NewReader("")), &out, run, storeOptimiseCommand(1000), nil, true)
+	if err == nil || len(calls) != 1 || !strings.Contains(out.String(), "incorrect password") {
+		t.Errorf("expected only sudo -v to run and fail, got %v after %q:\n%s", err, calls, out.String())
+	}
+}
+
+func TestOptimizeStoreFailure(t *testing.T) {
+	run := func(name string, args ...string) ([]byte, error) {
+		return []byte("error: getting status of '/nix/store/.links': Permission denied\n"), errors.New("exit status 1")
+	}
+	var out bytes.Buffer
+	err := optimizeStore(bufio.NewReader(strings.NewReader("")), &out, run, storeOptimiseCommand(0), nil, true)
+	if err == nil || !strings.Contains(out.String(), "Permission denied") {
+		t.Errorf("expected the failure and its output, got %v:\n%s", err, out.String())
+	}
+}
@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"nix-ai-help/pkg/utils"
+)
+
+const (
+	// nixStoreDir is the Nix store measured by store performance
+	nixStoreDir = "/nix/store"
+	// storePerformanceGenerations is the number of recent generations whose closure sizes are averaged
+	storePerformanceGenerations = 5
+)
+
+// StorePerformance holds the metrics reported by store performance. Sizes are 0 when they
+// could not be measured.
+type StorePerformance struct {
+	StoreURL   string `json:"store_url,omitempty"`
+	NixVersion string `json:"nix_version,omitempty"`
+	Paths      int    `json:"paths"`
+	// DiskSize is the space the store takes on disk, counting hard-linked files once
+	DiskSize int64 `json:"disk_size"`
+	// LogicalSize is the size of the store when every hard link is counted, i.e. without optimisation
+	LogicalSize int64 `json:"logical_size"`
+	// HardLinkSavings is the space saved by files deduplicated with nix-store --optimise
+	HardLinkSavings int64 `json:"hard_link_savings"`
+	// DedupRatio is LogicalSize / DiskSize; 1 means nothing is deduplicated
+	DedupRatio float64 `json:"dedup_ratio"`
+	// AverageClosureSize is the average closure size of the RecentGenerations newest generations
+	AverageClosureSize int64 `json:"average_closure_size"`
+	RecentGenerations  int   `json:"recent_generations"`
+	// AutoOptimise is nix.settings.auto-optimise-store; nil when it could not be read
+	AutoOptimise *bool `json:"auto_optimise_store"`
+}
+
+// parseStoreInfo parses `nix store info` output ("Store URL: daemon", "Version: 2.18.1")
+func parseStoreInfo(output string) (storeURL, version string) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Store URL", "URL":
+			storeURL = strings.TrimSpace(value)
+		case "Version":
+			version = strings.TrimSpace(value)
+		}
+	}
+	return storeURL, version
+}
+
+// countStorePaths counts the store paths listed by `nix path-info --all`
+func countStorePaths(output string) int {
+	count := 0
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		if strings.HasPrefix(strings.TrimSpace(scanner.Text()), nixStoreDir+"/") {
+			count++
+		}
+	}
+	return count
+}
+
+// parseDuTotal returns the size in the first line of `du -sb <dir>` output
+func parseDuTotal(output string) (int64, error) {
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty du output")
+	}
+	return strconv.ParseInt(fields[0], 10, 64)
+}
+
+// parseNixConfigBool reads a boolean setting from `nix config show <name>` output, which is
+// just the value, or from `nix show-config` output with one "name = value" line per setting.
+// ok is false when the setting is missing.
+func parseNixConfigBool(output, name string) (value, ok bool) {
+	trimmed := strings.TrimSpace(output)
+	if trimmed == "true" || trimmed == "false" {
+		return trimmed == "true", true
+	}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, setting, found := strings.Cut(scanner.Text(), "=")
+		if found && strings.TrimSpace(key) == name {
+			setting = strings.TrimSpace(setting)
+			return setting == "true", setting == "true" || setting == "false"
+		}
+	}
+	return false, false
+}
+
+// averageClosureSize averages the known closure sizes of the n newest generations
+func averageClosureSize(generations []Generation, n int) (int64, int) {
+	var total int64
+	count := 0
+	for _, generation := range newestGenerations(generations, n) {
+		if generation.Size > 0 {
+			total += generation.Size
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	return total / int64(count), count
+}
+
+// newestGenerations returns the n generations with the highest numbers, in any input order
+func newestGenerations(generations []Generation, n int) []Generation {
+	sorted := append([]Generation(nil), generations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Number > sorted[j].Number })
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// collectStorePerformance measures the store with run. The store size and deduplication come
+// from du, which counts hard-linked files once, or, with -l, once per link; the .links
+// directory holding the shared copies is left out of both. A measurement that fails is left
+// at its zero value.
+func collectStorePerformance(run commandRunner, generations []Generation) StorePerformance {
+	var perf StorePerformance
+	if output, err := run("nix", "store", "info"); err == nil {
+		perf.StoreURL, perf.NixVersion = parseStoreInfo(string(output))
+	}
+	if output, err := run("nix", "path-info", "--all"); err == nil {
+		perf.Paths = countStorePaths(string(output))
+	}
+	// du exits non-zero on paths it cannot read but still prints the total
+	output, _ := run("du", "-sb", "--exclude=.links", nixStoreDir)
+	perf.DiskSize, _ = parseDuTotal(string(output))
+	output, _ = run("du", "-sbl", "--exclude=.links", nixStoreDir)
+	perf.LogicalSize, _ = parseDuTotal(string(output))
+	if perf.DiskSize > 0 && perf.LogicalSize >= perf.DiskSize {
+		perf.HardLinkSavings = perf.LogicalSize - perf.DiskSize
+		perf.DedupRatio = float64(perf.LogicalSize) / float64(perf.DiskSize)
+	}
+
+	perf.AutoOptimise = readAutoOptimise(run)
+	perf.AverageClosureSize, perf.RecentGenerations = averageClosureSize(generations, storePerformanceGenerations)
+	return perf
+}
+
+// readAutoOptimise reads nix.settings.auto-optimise-store with `nix config show`, or with
+// `nix show-config` on Nix before 2.19. It returns nil when the setting cannot be read.
+func readAutoOptimise(run commandRunner) *bool {
+	output, err := run("nix", "config", "show", "auto-optimise-store")
+	if err != nil {
+		output, err = run("nix", "show-config")
+	}
+	if err != nil {
+		return nil
+	}
+	value, ok := parseNixConfigBool(string(output), "auto-optimise-store")
+	if !ok {
+		return nil
+	}
+	return &value
+}
+
+// storePerformanceRows returns the metrics as name/value rows
+func storePerformanceRows(perf StorePerformance) [][]string {
+	unknown := func(known bool, value string) string {
+		if !known {
+			return "unknown"
+		}
+		return value
+	}
+	autoOptimise := "unknown"
+	if perf.AutoOptimise != nil {
+		autoOptimise = map[bool]string{true: "on", false: "off"}[*perf.AutoOptimise]
+	}
+
+	return [][]string{
+		{"Store", unknown(perf.StoreURL != "", perf.StoreURL)},
+		{"Nix version", unknown(perf.NixVersion != "", perf.NixVersion)},
+		{"Store paths", unknown(perf.Paths > 0, strconv.Itoa(perf.Paths))},
+		{"Size on disk", unknown(perf.DiskSize > 0, formatBytes(perf.DiskSize))},
+		{"Saved by hard links", unknown(perf.DedupRatio > 0, formatBytes(perf.HardLinkSavings))},
+		{"Dedup ratio", unknown(perf.DedupRatio > 0, fmt.Sprintf("%.2fx", perf.DedupRatio))},
+		{"Average closure size", unknown(perf.RecentGenerations > 0,
+			fmt.Sprintf("%s (last %d generations)", formatBytes(perf.AverageClosureSize), perf.RecentGenerations))},
+		{"auto-optimise-store", autoOptimise},
+	}
+}
+
+// printStorePerformance prints the metrics as a table
+func printStorePerformance(out io.Writer, perf StorePerformance) {
+	_, _ = fmt.Fprintln(out, utils.FormatTable([]string{"Metric", "Value"}, storePerformanceRows(perf)))
+}
+
+// storePerformancePrompt asks the AI for tips based on the measured metrics
+func storePerformancePrompt(perf StorePerformance) string {
+	var b strings.Builder
+	b.WriteString("You are a NixOS expert. Based on these Nix store metrics, give a short list of concrete, " +
+		"prioritized actions to reduce store size and speed up store operations, with the NixOS options or " +
+		"commands to use (e.g. nix.settings.auto-optimise-store, nix.gc.automatic, nix.optimise.automatic, " +
+		"removing old generations). Skip advice that the metrics show is already applied.\n\nMETRICS:\n")
+	for _, row := range storePerformanceRows(perf) {
+		b.WriteString("- " + row[0] + ": " + row[1] + "\n")
+	}
+	return b.String()
+}
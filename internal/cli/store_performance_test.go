@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const storeInfoOutput = `Store URL: daemon
+Version: 2.18.1
+Trusted: 1
+`
+
+const pathInfoAllOutput = `/nix/store/0a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p-glibc-2.38-44
+/nix/store/1a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p-bash-5.2p26
+/nix/store/2a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p-nixos-system-host-24.05
+`
+
+const showConfigOutput = `allowed-users = *
+auto-optimise-store = false
+cores = 0
+`
+
+func TestParseStoreInfo(t *testing.T) {
+	url, version := parseStoreInfo(storeInfoOutput)
+	if url != "daemon" || version != "2.18.1" {
+		t.Errorf("got %q, %q", url, version)
+	}
+	if url, version := parseStoreInfo("error: unknown command\n"); url != "" || version != "" {
+		t.Errorf("expected nothing from unrelated output, got %q, %q", url, version)
+	}
+}
+
+func TestParseNixConfigBool(t *testing.T) {
+	cases := []struct {
+		output    string
+		value, ok bool
+	}{
+		{"true\n", true, true},
+		{"false\n", false, true},
+		{showConfigOutput, false, true},
+		{"auto-optimise-store = true\n", true, true},
+		{"cores = 0\n", false, false},
+	}
+	for _, c := range cases {
+		value, ok := parseNixConfigBool(c.output, "auto-optimise-store")
+		if value != c.value || ok != c.ok {
+			t.Errorf("parseNixConfigBool(%q) = %v, %v; want %v, %v", c.output, value, ok, c.value, c.ok)
+		}
+	}
+}
+
+func TestAverageClosureSize(t *testing.T) {
+	generations := []Generation{
+		{Number: 40, Size: 100}, {Number: 44, Size: 3000}, {Number: 43, Size: 2000},
+		{Number: 42, Size: 0}, {Number: 41, Size: 1000},
+	}
+	average, count := averageClosureSize(generations, 4)
+	// Generations 44, 43 and 41; 42 has no known size and 40 is too old
+	if average != 2000 || count != 3 {
+		t.Errorf("got %d over %d generations", average, count)
+	}
+	if average, count := averageClosureSize(nil, 4); average != 0 || count != 0 {
+		t.Errorf("expected nothing without generations, got %d, %d", average, count)
+	}
+}
+
+func TestCollectStorePerformance(t *testing.T) {
+	run := fakeRunner(map[string]string{
+		"nix store info":                      storeInfoOutput,
+		"nix path-info --all":                 pathInfoAllOutput,
+		"du -sb --exclude=.links /nix/store":  "8000000000\t/nix/store\n",
+		"du -sbl --exclude=.links /nix/store": "10000000000\t/nix/store\n",
+		"nix show-config":                     showConfigOutput,
+	}, map[string]error{
+		// Nix before 2.19 has no `nix config show`
+		"nix config show auto-optimise-store": errors.New("exit status 1"),
+	})
+
+	perf := collectStorePerformance(run, []Generation{{Number: 7, Size: 4000}, {Number: 6, Size: 2000}})
+	if perf.StoreURL != "daemon" || perf.NixVersion != "2.18.1" || perf.Paths != 3 {
+		t.Errorf("unexpected store info: %+v", perf)
+	}
+	if perf.DiskSize != 8000000000 || perf.HardLinkSavings != 2000000000 || perf.DedupRatio != 1.25 {
+		t.Errorf("unexpected sizes: %+v", perf)
+	}
+	if perf.AverageClosureSize != 3000 || perf.RecentGenerations != 2 {
+		t.Errorf("unexpected closure sizes: %+v", perf)
+	}
+	if perf.AutoOptimise == nil || *perf.AutoOptimise {
+		t.Errorf("expected auto-optimise-store to be read as off, got %v", perf.AutoOptimise)
+	}
+
+	prompt := storePerformancePrompt(perf)
+	for _, want := range []string{"Dedup ratio: 1.25x", "auto-optimise-store: off", "Store paths: 3"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("expected prompt to contain %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestCollectStorePerformanceWithoutNix(t *testing.T) {
+	failing := errors.New("executable file not found")
+	run := func(name string, args ...string) ([]byte, error) { return nil, failing }
+
+	perf := collectStorePerformance(run, nil)
+	if perf.Paths != 0 || perf.DiskSize != 0 || perf.DedupRatio != 0 || perf.AutoOptimise != nil {
+		t.Errorf("expected no metrics, got %+v", perf)
+	}
+	for _, row := range storePerformanceRows(perf) {
+		if row[1] != "unknown" {
+			t.Errorf("expected %s to be unknown, got %q", row[0], row[1])
+		}
+	}
+}
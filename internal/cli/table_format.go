@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"strings"
+
+	"nix-ai-help/internal/nixos"
+	"nix-ai-help/pkg/utils"
+)
+
+// formatPackageTable renders package search results for `search --format table`
+func formatPackageTable(pkgs []nixos.NixPackage) string {
+	rows := make([][]string, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		rows = append(rows, []string{pkg.DisplayName(), pkg.Version, pkg.AttrPath, pkg.Description})
+	}
+	return utils.FormatTable([]string{"Name", "Version", "Attribute", "Description"}, rows)
+}
+
+// formatOptionTable renders the documented attributes of an option for `explain-option --format table`
+func formatOptionTable(opt mcpOptionDoc) string {
+	rows := [][]string{
+		{"Option", opt.Name},
+		{"Type", opt.Type},
+		{"Default", string(opt.Default)},
+		{"Example", string(opt.Example)},
+		{"Description", opt.Description},
+	}
+	if opt.Source != "" {
+		rows = append(rows, []string{"Source", opt.Source})
+	}
+	if opt.Version != "" {
+		rows = append(rows, []string{"NixOS Version", opt.Version})
+	}
+	if len(opt.Related) > 0 {
+		rows = append(rows, []string{"Related", strings.Join(opt.Related, ", ")})
+	}
+	return utils.FormatTable([]string{"Attribute", "Value"}, rows)
+}
@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/nixos"
+)
+
+func TestFormatPackageTable(t *testing.T) {
+	table := formatPackageTable([]nixos.NixPackage{
+		{AttrPath: "legacyPackages.x86_64-linux.firefox", Pname: "firefox", Version: "128.0", Description: "Web browser"},
+		{AttrPath: "legacyPackages.x86_64-linux.hello", Name: "hello-2.12", Description: "Program that produces a familiar greeting"},
+	})
+
+	lines := strings.Split(table, "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header, separator and two rows, got:\n%s", table)
+	}
+	for _, want := range []string{"Name", "Version", "firefox", "128.0", "hello-2.12"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("expected table to contain %q:\n%s", want, table)
+		}
+	}
+}
+
+func TestFormatOptionTable(t *testing.T) {
+	table := formatOptionTable(mcpOptionDoc{Name: "services.nginx.enable", Type: "boolean", Default: "false", Related: []string{"services.nginx.virtualHosts"}})
+	for _, want := range []string{"services.nginx.enable", "boolean", "Related", "services.nginx.virtualHosts"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("expected table to contain %q:\n%s", want, table)
+		}
+	}
+}
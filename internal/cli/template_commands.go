@@ -176,20 +176,26 @@ func (tm *TemplateManager) LoadCustomTemplates() ([]Template, error) {
 	return templates, nil
 }
 
-// ApplyTemplate applies a template to the configuration
-func (tm *TemplateManager) ApplyTemplate(template *Template, outputPath string, merge bool) error {
-	content := template.Content
+// templateOutputPath returns the file a template is applied to, /etc/nixos/configuration.nix or
+// ./configuration.nix when no output path is given
+func templateOutputPath(outputPath string) string {
+	if outputPath != "" {
+		return outputPath
+	}
+	// Check if we have permission to write to /etc/nixos
+	if _, err := os.Stat("/etc/nixos"); os.IsNotExist(err) {
+		// Fallback to current directory
+		return "./configuration.nix"
+	}
+	return "/etc/nixos/configuration.nix"
+}
 
-	// If no output path specified, use default
-	if outputPath == "" {
-		outputPath = "/etc/nixos/configuration.nix"
+// ApplyTemplate applies a template to the configuration, overwriting an existing file only as
+// confirm allows
+func (tm *TemplateManager) ApplyTemplate(template *Template, outputPath string, merge bool, confirm utils.WriteConfirm) error {
+	content := template.Content
 
-		// Check if we have permission to write to /etc/nixos
-		if _, err := os.Stat("/etc/nixos"); os.IsNotExist(err) {
-			// Fallback to current directory
-			outputPath = "./configuration.nix"
-		}
-	}
+	outputPath = templateOutputPath(outputPath)
 
 	if merge {
 		// Merge with existing configuration
@@ -199,18 +205,8 @@ func (tm *TemplateManager) ApplyTemplate(template *Template, outputPath string,
 		}
 	}
 
-	// Create directory if needed
-	dir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory %s: %v", dir, err)
-	}
-
 	// Write configuration
-	if err := os.WriteFile(outputPath, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to write configuration: %v", err)
-	}
-
-	return nil
+	return utils.WriteFileWithConfirm(outputPath, []byte(content), 0644, confirm)
 }
 
 // SaveTemplate saves a new template from a source
@@ -294,7 +290,7 @@ func (tm *TemplateManager) fetchGitHubContent(url string) (content, repo, path s
 			rawURL := fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s/%s", user, repoName, branch, filePath)
 
 			// Fetch content
-			resp, err := http.Get(rawURL)
+			resp, err := utils.NewHTTPClient(30 * time.Second).Get(rawURL)
 			if err != nil {
 				return "", "", "", err
 			}
@@ -317,35 +313,53 @@ func (tm *TemplateManager) fetchGitHubContent(url string) (content, repo, path s
 	return "", "", "", fmt.Errorf("unsupported URL format")
 }
 
-// GetCategories returns template categories with counts
-func (tm *TemplateManager) GetCategories() map[string]int {
-	categories := make(map[string]int)
+// templateCategory returns the category of a template, General when none is set
+func templateCategory(template Template) string {
+	if template.Category == "" {
+		return "General"
+	}
+	return template.Category
+}
 
-	// Count builtin templates
-	builtinTemplates := tm.LoadBuiltinTemplates()
-	for _, template := range builtinTemplates {
-		category := template.Category
-		if category == "" {
-			category = "General"
-		}
-		categories[category]++
+// ListTemplates returns the builtin and custom templates. When category is not empty only
+// templates of that category (matched case-insensitively) are returned.
+func (tm *TemplateManager) ListTemplates(category string) []Template {
+	templates := tm.LoadBuiltinTemplates()
+	if customTemplates, err := tm.LoadCustomTemplates(); err == nil {
+		templates = append(templates, customTemplates...)
+	}
+	if category == "" {
+		return templates
 	}
 
-	// Count custom templates
-	customTemplates, err := tm.LoadCustomTemplates()
-	if err == nil {
-		for _, template := range customTemplates {
-			category := template.Category
-			if category == "" {
-				category = "General"
-			}
-			categories[category]++
+	var matches []Template
+	for _, template := range templates {
+		if strings.EqualFold(templateCategory(template), category) {
+			matches = append(matches, template)
 		}
 	}
+	return matches
+}
 
+// GetCategories returns template categories with counts
+func (tm *TemplateManager) GetCategories() map[string]int {
+	categories := make(map[string]int)
+	for _, template := range tm.ListTemplates("") {
+		categories[templateCategory(template)]++
+	}
 	return categories
 }
 
+// sortedCategoryNames returns the category names of a GetCategories result in alphabetical order
+func sortedCategoryNames(categories map[string]int) []string {
+	names := make([]string, 0, len(categories))
+	for category := range categories {
+		names = append(names, category)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // LoadSnippets loads all saved snippets
 func (tm *TemplateManager) LoadSnippets() ([]Snippet, error) {
 	snippetsDir := filepath.Join(tm.configDir, "snippets")
@@ -483,8 +497,9 @@ func (tm *TemplateManager) GetSnippet(name string) (*Snippet, error) {
 	return nil, fmt.Errorf("snippet not found: %s", name)
 }
 
-// ApplySnippet applies a snippet to configuration
-func (tm *TemplateManager) ApplySnippet(name, outputPath string) error {
+// ApplySnippet applies a snippet to configuration, overwriting an existing file only as confirm
+// allows
+func (tm *TemplateManager) ApplySnippet(name, outputPath string, confirm utils.WriteConfirm) error {
 	snippet, err := tm.GetSnippet(name)
 	if err != nil {
 		return err
@@ -496,18 +511,8 @@ func (tm *TemplateManager) ApplySnippet(name, outputPath string) error {
 		return nil
 	}
 
-	// Create directory if needed
-	dir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory %s: %v", dir, err)
-	}
-
 	// Write content
-	if err := os.WriteFile(outputPath, []byte(snippet.Content), 0644); err != nil {
-		return fmt.Errorf("failed to write file: %v", err)
-	}
-
-	return nil
+	return utils.WriteFileWithConfirm(outputPath, []byte(snippet.Content), 0644, confirm)
 }
 
 // RemoveSnippet removes a snippet
@@ -566,6 +571,8 @@ Commands:
 
 Examples:
   nixai templates list
+  nixai templates list --category Gaming
+  nixai templates categories
   nixai templates search gaming
   nixai templates search desktop kde  
   nixai templates github "gaming nixos configuration"
@@ -580,7 +587,11 @@ Examples:
 var templatesListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List available NixOS configuration templates",
-	Long:  "Browse all available curated NixOS configuration templates organized by category.",
+	Long: `Browse all available curated NixOS configuration templates organized by category.
+
+Examples:
+  nixai templates list
+  nixai templates list --category Server`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println(utils.FormatHeader("📚 Available NixOS Configuration Templates"))
 		fmt.Println()
@@ -612,9 +623,15 @@ var templatesListCmd = &cobra.Command{
 		log := logger.NewLoggerWithLevel(cfg.LogLevel)
 		tm := NewTemplateManager("", log)
 
-		// Load builtin templates
-		templates := tm.LoadBuiltinTemplates()
+		// Load builtin and custom templates, optionally of a single category
+		category, _ := cmd.Flags().GetString("category")
+		templates := tm.ListTemplates(category)
 		if len(templates) == 0 {
+			if category != "" {
+				fmt.Println(utils.FormatWarning("No templates in category: " + category))
+				fmt.Println(utils.FormatTip("Use 'nixai templates categories' to see available categories"))
+				return
+			}
 			fmt.Println(utils.FormatWarning("No templates available"))
 			return
 		}
@@ -622,10 +639,7 @@ var templatesListCmd = &cobra.Command{
 		// Group templates by category
 		categories := make(map[string][]Template)
 		for _, template := range templates {
-			category := template.Category
-			if category == "" {
-				category = "General"
-			}
+			category := templateCategory(template)
 			categories[category] = append(categories[category], template)
 		}
 
@@ -670,6 +684,9 @@ var templatesSearchCmd = &cobra.Command{
 	Short: "Search NixOS templates by keyword or category",
 	Long: `Search available NixOS configuration templates by keyword, tag, or category.
 
+Builtin and saved templates are matched against their names, descriptions, tags and
+categories and listed by relevance, best match first.
+
 Examples:
   nixai templates search gaming
   nixai templates search desktop kde
@@ -881,6 +898,7 @@ Examples:
 		templateName := args[0]
 		merge, _ := cmd.Flags().GetBool("merge")
 		output, _ := cmd.Flags().GetString("output")
+		force, _ := cmd.Flags().GetBool("force")
 
 		// Load configuration
 		cfg, err := config.LoadUserConfig()
@@ -903,8 +921,18 @@ Examples:
 		fmt.Println(utils.FormatHeader("🔧 Applying Template: " + template.Name))
 		fmt.Println()
 
+		// Merging appends the template, so options both set would be defined twice
+		if merge {
+			if existing, err := os.ReadFile(templateOutputPath(output)); err == nil {
+				if err := checkDuplicateOptions(os.Stdout, string(existing), template.Content, force); err != nil {
+					fmt.Println(utils.FormatError("Error applying template: " + err.Error()))
+					os.Exit(1)
+				}
+			}
+		}
+
 		// Apply template
-		err = tm.ApplyTemplate(template, output, merge)
+		err = tm.ApplyTemplate(template, output, merge, writeConfirm(cmd))
 		if err != nil {
 			fmt.Println(utils.FormatError("Error applying template: " + err.Error()))
 			os.Exit(1)
@@ -916,7 +944,7 @@ Examples:
 		}
 		fmt.Println()
 		fmt.Println(utils.FormatTip("Review the generated configuration before rebuilding"))
-		fmt.Println(utils.FormatTip("Use 'sudo nixos-rebuild switch' to apply changes"))
+		fmt.Println(utils.FormatTip("Use '" + nixos.RebuildCommandLine(nixos.ContextForConfigPath(nixosPath), "switch") + "' to apply changes"))
 	},
 }
 
@@ -1001,7 +1029,8 @@ var templatesCategoriesCmd = &cobra.Command{
 			return
 		}
 
-		for category, count := range categories {
+		for _, category := range sortedCategoryNames(categories) {
+			count := categories[category]
 			description := getCategoryDescription(category)
 			fmt.Printf("  %s (%d template%s)\n",
 				utils.FormatKeyValue(category, description),
@@ -1016,7 +1045,7 @@ var templatesCategoriesCmd = &cobra.Command{
 		}
 
 		fmt.Println()
-		fmt.Println(utils.FormatTip("Use 'nixai templates search <category>' to find templates in a specific category"))
+		fmt.Println(utils.FormatTip("Use 'nixai templates list --category <category>' to list the templates in a category"))
 	},
 }
 
@@ -1038,7 +1067,8 @@ Examples:
   nixai snippets list
   nixai snippets search nvidia
   nixai snippets add my-nvidia-config
-  nixai snippets apply gaming-setup`,
+  nixai snippets apply gaming-setup
+  nixai snippets apply nginx-basic --to /etc/nixos/configuration.nix`,
 	Run: func(cmd *cobra.Command, args []string) {
 		_ = cmd.Help()
 	},
@@ -1193,11 +1223,22 @@ Examples:
 var snippetsApplyCmd = &cobra.Command{
 	Use:   "apply <name>",
 	Short: "Apply snippet to configuration",
-	Long:  "Apply a saved snippet to the current configuration or output it to a file.",
-	Args:  conditionalExactArgsValidator(1),
+	Long: `Apply a saved snippet to the current configuration or output it to a file.
+
+With --to the snippet is inserted into an existing configuration: at a '# nixai:insert'
+marker line when the file has one, otherwise before its closing brace. A backup of the
+file is written first. Options the file already sets are reported and nothing is written
+unless --force is given.
+
+Examples:
+  nixai snippets apply nginx-basic --to /etc/nixos/configuration.nix
+  nixai snippets apply gaming-setup --output ./gaming.nix`,
+	Args: conditionalExactArgsValidator(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		snippetName := args[0]
 		output, _ := cmd.Flags().GetString("output")
+		target, _ := cmd.Flags().GetString("to")
+		force, _ := cmd.Flags().GetBool("force")
 
 		// Load configuration
 		cfg, err := config.LoadUserConfig()
@@ -1210,8 +1251,24 @@ var snippetsApplyCmd = &cobra.Command{
 		log := logger.NewLoggerWithLevel(cfg.LogLevel)
 		tm := NewTemplateManager("", log)
 
+		if target != "" {
+			snippet, err := tm.GetSnippet(snippetName)
+			if err != nil {
+				fmt.Println(utils.FormatError("Snippet not found: " + snippetName))
+				os.Exit(1)
+			}
+			backup, err := applySnippetToFile(os.Stdout, snippet, target, force, writeConfirm(cmd))
+			if err != nil {
+				fmt.Println(utils.FormatError("Error applying snippet: " + err.Error()))
+				os.Exit(1)
+			}
+			fmt.Println(utils.FormatSuccess("✅ Snippet inserted into " + target))
+			fmt.Println(utils.FormatKeyValue("Backup", backup))
+			return
+		}
+
 		// Apply snippet
-		err = tm.ApplySnippet(snippetName, output)
+		err = tm.ApplySnippet(snippetName, output, writeConfirm(cmd))
 		if err != nil {
 			fmt.Println(utils.FormatError("Error applying snippet: " + err.Error()))
 			os.Exit(1)
@@ -1339,32 +1396,86 @@ func (tm *TemplateManager) LoadBuiltinTemplates() []Template {
 	}
 }
 
-// Search templates by query
+// Search templates by query. Builtin and custom templates are ranked by relevance: each
+// word of the query scores by where it matches, name matches weighing most, then tags,
+// category and description. Names that only contain the word as a fuzzy subsequence
+// score lowest. Templates matching more words rank higher.
 func (tm *TemplateManager) SearchTemplates(query string) []Template {
-	templates := tm.LoadBuiltinTemplates()
-	var matches []Template
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil
+	}
 
-	query = strings.ToLower(query)
+	type templateMatch struct {
+		template Template
+		score    int
+	}
+	var matches []templateMatch
+	for _, template := range tm.ListTemplates("") {
+		if score := templateSearchScore(template, terms); score > 0 {
+			matches = append(matches, templateMatch{template: template, score: score})
+		}
+	}
 
-	for _, template := range templates {
-		// Search in name, description, category, and tags
-		if strings.Contains(strings.ToLower(template.Name), query) ||
-			strings.Contains(strings.ToLower(template.Description), query) ||
-			strings.Contains(strings.ToLower(template.Category), query) {
-			matches = append(matches, template)
-			continue
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
 		}
+		return matches[i].template.Name < matches[j].template.Name
+	})
 
-		// Search in tags
+	results := make([]Template, len(matches))
+	for i, match := range matches {
+		results[i] = match.template
+	}
+	return results
+}
+
+// templateSearchScore returns the relevance of a template for the lower-case query terms,
+// 0 when no term matches
+func templateSearchScore(template Template, terms []string) int {
+	name := strings.ToLower(template.Name)
+	description := strings.ToLower(template.Description)
+	category := strings.ToLower(templateCategory(template))
+
+	total := 0
+	for _, term := range terms {
+		score := 0
+		switch {
+		case name == term:
+			score += 100
+		case strings.HasPrefix(name, term):
+			score += 70
+		case strings.Contains(name, term):
+			score += 50
+		}
 		for _, tag := range template.Tags {
-			if strings.Contains(strings.ToLower(tag), query) {
-				matches = append(matches, template)
+			tag = strings.ToLower(tag)
+			if tag == term {
+				score += 40
+				break
+			}
+			if strings.Contains(tag, term) {
+				score += 20
 				break
 			}
 		}
+		if category == term {
+			score += 30
+		} else if strings.Contains(category, term) {
+			score += 15
+		}
+		if strings.Contains(description, term) {
+			score += 10
+		}
+		if score == 0 {
+			if _, _, ok := fuzzyScore(term, template.Name); ok {
+				score = 5
+			}
+		}
+		total += score
 	}
-
-	return matches
+	return total
 }
 
 // Search GitHub for NixOS configurations
@@ -1379,7 +1490,7 @@ func (tm *TemplateManager) SearchGitHub(query string) (*GitHubSearchResponse, er
 	apiURL := fmt.Sprintf("https://api.github.com/search/code?q=%s&sort=stars&order=desc&per_page=20", encodedQuery)
 
 	// Create HTTP client with timeout
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := utils.NewHTTPClient(10 * time.Second)
 
 	// Create request
 	req, err := http.NewRequest("GET", apiURL, nil)
@@ -1645,6 +1756,9 @@ func init() {
 	snippetsCmd.AddCommand(snippetsShowCmd)
 	snippetsCmd.AddCommand(snippetsRemoveCmd)
 
+	// Add flags to list command
+	templatesListCmd.Flags().StringP("category", "c", "", "Only list templates of this category")
+
 	// Add flags to GitHub search command
 	templatesGithubCmd.Flags().IntP("limit", "l", 10, "Maximum number of results to show")
 	templatesGithubCmd.Flags().StringP("language", "", "nix", "Programming language to filter by")
@@ -1653,6 +1767,8 @@ func init() {
 	// Add flags to apply command
 	templatesApplyCmd.Flags().BoolP("merge", "m", false, "Merge template with existing configuration")
 	templatesApplyCmd.Flags().StringP("output", "o", "", "Output file for applied template")
+	templatesApplyCmd.Flags().Bool("force", false, "Merge even if the template sets options the configuration already sets")
+	addYesFlag(templatesApplyCmd)
 
 	// Add flags to save command
 	templatesSaveCmd.Flags().StringP("category", "c", "", "Category for the template")
@@ -1666,4 +1782,7 @@ func init() {
 
 	// Add flags to apply snippet command
 	snippetsApplyCmd.Flags().StringP("output", "o", "", "Output file for applied snippet")
+	snippetsApplyCmd.Flags().String("to", "", "Insert the snippet into this configuration file")
+	snippetsApplyCmd.Flags().Bool("force", false, "Insert the snippet even if it sets options the file already sets")
+	addYesFlag(snippetsApplyCmd)
 }
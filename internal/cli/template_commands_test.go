@@ -1,6 +1,9 @@
 package cli
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"nix-ai-help/pkg/logger"
@@ -101,6 +104,91 @@ func TestSearchTemplates(t *testing.T) {
 	}
 }
 
+// writeCustomTemplate saves a custom template file in the templates directory of configDir
+func writeCustomTemplate(t *testing.T, configDir, name, category string) {
+	t.Helper()
+	content := "name: " + name + "\ncategory: " + category + "\nsource: custom\ncontent: \"{ }\"\n"
+	if category == "" {
+		content = "name: " + name + "\nsource: custom\ncontent: \"{ }\"\n"
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "templates", name+".yaml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestTemplateCategories tests category counts and filtering over builtin and custom templates
+func TestTemplateCategories(t *testing.T) {
+	configDir := t.TempDir()
+	tm := NewTemplateManager(configDir, logger.NewLoggerWithLevel("info"))
+	writeCustomTemplate(t, configDir, "home-server", "Server")
+	writeCustomTemplate(t, configDir, "hardened", "Security")
+	writeCustomTemplate(t, configDir, "uncategorized", "")
+
+	categories := tm.GetCategories()
+	expected := map[string]int{"Desktop": 1, "Development": 1, "Gaming": 1, "General": 1, "Security": 1, "Server": 2}
+	if !reflect.DeepEqual(categories, expected) {
+		t.Errorf("Expected categories %v, got %v", expected, categories)
+	}
+	names := sortedCategoryNames(categories)
+	if !reflect.DeepEqual(names, []string{"Desktop", "Development", "Gaming", "General", "Security", "Server"}) {
+		t.Errorf("Expected sorted category names, got %v", names)
+	}
+
+	var servers []string
+	for _, template := range tm.ListTemplates("server") {
+		servers = append(servers, template.Name)
+	}
+	if !reflect.DeepEqual(servers, []string{"server-basic", "home-server"}) {
+		t.Errorf("Expected builtin and custom Server templates, got %v", servers)
+	}
+	if general := tm.ListTemplates("General"); len(general) != 1 || general[0].Name != "uncategorized" {
+		t.Errorf("Expected the uncategorized template in General, got %v", general)
+	}
+	if missing := tm.ListTemplates("Hardware"); len(missing) != 0 {
+		t.Errorf("Expected no Hardware templates, got %d", len(missing))
+	}
+	if all := tm.ListTemplates(""); len(all) != 7 {
+		t.Errorf("Expected all 7 templates without a category filter, got %d", len(all))
+	}
+}
+
+// TestSearchTemplatesRanking tests that search results are ranked by relevance
+func TestSearchTemplatesRanking(t *testing.T) {
+	configDir := t.TempDir()
+	tm := NewTemplateManager(configDir, logger.NewLoggerWithLevel("info"))
+	seed := map[string]string{
+		"nginx-proxy": "name: nginx-proxy\ndescription: Reverse proxy in front of local services\ncategory: Server\ntags: [nginx, proxy]\n",
+		"web-server":  "name: web-server\ndescription: Static website served by nginx\ncategory: Server\ntags: [web]\n",
+	}
+	for name, content := range seed {
+		if err := os.WriteFile(filepath.Join(configDir, "templates", name+".yaml"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"nginx", []string{"nginx-proxy", "web-server"}},
+		{"server", []string{"server-basic", "web-server", "nginx-proxy"}},
+		{"web nginx", []string{"web-server", "nginx-proxy"}},
+		{"STEAM", []string{"gaming-config"}},
+		{"dvlp", []string{"development-env"}},
+		{"kubernetes", nil},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		var names []string
+		for _, template := range tm.SearchTemplates(tt.query) {
+			names = append(names, template.Name)
+		}
+		if !reflect.DeepEqual(names, tt.expected) {
+			t.Errorf("SearchTemplates(%q) = %v, expected %v", tt.query, names, tt.expected)
+		}
+	}
+}
+
 // Helper function to check if a slice contains a string
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
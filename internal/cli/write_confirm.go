@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"os"
+
+	"nix-ai-help/pkg/utils"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// addYesFlag registers --yes (-y) on a command that writes files; --confirm, as on context
+// reset, is accepted for it too
+func addYesFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolP("yes", "y", false, "Overwrite existing files without asking (also --confirm)")
+	cmd.Flags().SetNormalizeFunc(func(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "confirm" {
+			name = "yes"
+		}
+		return pflag.NormalizedName(name)
+	})
+}
+
+// writeConfirm returns how cmd asks before overwriting a file: on the terminal, or not at all
+// with --yes. Outside a terminal existing files are kept unless --yes is given.
+func writeConfirm(cmd *cobra.Command) utils.WriteConfirm {
+	assumeYes, _ := cmd.Flags().GetBool("yes")
+	confirm := utils.WriteConfirm{Out: cmd.OutOrStdout(), AssumeYes: assumeYes}
+	if isInteractiveSession() {
+		confirm.In = os.Stdin
+	}
+	return confirm
+}
@@ -62,6 +62,12 @@ func (cm *CacheManager) getCacheFilePath(key string) string {
 
 // Get retrieves data from cache if it exists and is not expired
 func (cm *CacheManager) Get(key string, result interface{}) (bool, error) {
+	return cm.GetWithMaxAge(key, result, cm.maxAge)
+}
+
+// GetWithMaxAge retrieves data from cache if it exists and is younger than maxAge, for data
+// that stays fresh longer or shorter than the cache default
+func (cm *CacheManager) GetWithMaxAge(key string, result interface{}, maxAge time.Duration) (bool, error) {
 	filePath := cm.getCacheFilePath(key)
 
 	// Check if file exists
@@ -84,7 +90,7 @@ func (cm *CacheManager) Get(key string, result interface{}) (bool, error) {
 	}
 
 	// Check if expired
-	if time.Since(entry.Timestamp) > cm.maxAge {
+	if time.Since(entry.Timestamp) > maxAge {
 		// Clean up expired entry
 		_ = os.Remove(filePath)
 		return false, nil
@@ -49,24 +49,16 @@ type BestPractice struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
-// TrendData represents trending packages and configurations
+// TrendData represents the configurations and topics trending over a timeframe
 type TrendData struct {
-	PopularPackages     []PackageStats  `json:"popular_packages"`
+	Timeframe           string          `json:"timeframe"`
+	Category            string          `json:"category,omitempty"`
 	TrendingConfigs     []Configuration `json:"trending_configs"`
+	TrendingTopics      []TopicStats    `json:"trending_topics"`
 	TotalConfigurations int             `json:"total_configurations"`
-	ActiveContributors  int             `json:"active_contributors"`
-	PackagesTracked     int             `json:"packages_tracked"`
 	LastUpdated         time.Time       `json:"last_updated"`
 }
 
-// PackageStats represents statistics for a package
-type PackageStats struct {
-	Name        string  `json:"name"`
-	Downloads   int     `json:"downloads"`
-	Rating      float64 `json:"rating"`
-	Description string  `json:"description"`
-}
-
 // ValidationResult represents the result of configuration validation
 type ValidationResult struct {
 	IsValid       bool           `json:"is_valid"`
@@ -90,7 +82,7 @@ func NewManager(cfg *config.UserConfig) *Manager {
 	cacheDir := filepath.Join(os.Getenv("HOME"), ".cache", "nixai", "community")
 	cache := NewCacheManager(cacheDir)
 
-	githubClient := NewGitHubClient("")
+	githubClient := NewGitHubClient(os.Getenv("GITHUB_TOKEN"))
 
 	// Initialize Discourse client with environment variables or config values
 	discourseAPIKey := os.Getenv("DISCOURSE_API_KEY")
@@ -277,44 +269,9 @@ func (m *Manager) ValidateConfiguration(filePath string) (*ValidationResult, err
 	return result, nil
 }
 
-// GetTrends returns trending packages and configurations
+// GetTrends returns the configurations and topics trending this week
 func (m *Manager) GetTrends() (*TrendData, error) {
-	m.logger.Info("Fetching community trends")
-
-	// Check cache first
-	cacheKey := GetCacheKey("trends", "current")
-	var cachedTrends TrendData
-	if found, err := m.cache.Get(cacheKey, &cachedTrends); err == nil && found {
-		return &cachedTrends, nil
-	}
-
-	// Generate mock trend data
-	trends := &TrendData{
-		PopularPackages: []PackageStats{
-			{Name: "home-manager", Downloads: 15420, Rating: 4.8, Description: "Declarative home configuration"},
-			{Name: "nixpkgs", Downloads: 12890, Rating: 4.9, Description: "Nix packages collection"},
-			{Name: "flake-utils", Downloads: 8765, Rating: 4.7, Description: "Pure Nix flake utility functions"},
-			{Name: "nixos-hardware", Downloads: 7543, Rating: 4.6, Description: "Hardware-specific NixOS modules"},
-			{Name: "nix-direnv", Downloads: 6234, Rating: 4.5, Description: "Fast direnv integration"},
-		},
-		TrendingConfigs:     m.generateTrendingConfigurations(),
-		TotalConfigurations: 2847,
-		ActiveContributors:  456,
-		PackagesTracked:     89123,
-		LastUpdated:         time.Now(),
-	}
-
-	// Enhance with Discourse trending topics if available
-	if m.discourseClient != nil && m.config.Discourse.Enabled {
-		if err := m.enhanceTrendsWithDiscourse(trends); err != nil {
-			m.logger.Warn("Failed to fetch Discourse trends: " + err.Error())
-		}
-	}
-
-	// Cache trends
-	_ = m.cache.Set(cacheKey, trends, "trends")
-
-	return trends, nil
+	return m.GetTrendsFor("weekly", "")
 }
 
 // RateConfiguration submits a rating for a configuration
@@ -440,26 +397,6 @@ func (m *Manager) generateMockCategoryResults(category string, query string, lim
 	return mockConfigs
 }
 
-func (m *Manager) generateTrendingConfigurations() []Configuration {
-	return []Configuration{
-		{
-			ID: "trend1", Name: "Modern Development Workstation", Author: "dev_expert",
-			Description: "Complete development environment with modern tools",
-			Rating:      4.9, Views: 3200,
-		},
-		{
-			ID: "trend2", Name: "Media Server Setup", Author: "media_guru",
-			Description: "Plex, Sonarr, Radarr, and more for media management",
-			Rating:      4.7, Views: 2850,
-		},
-		{
-			ID: "trend3", Name: "Kubernetes Node Configuration", Author: "k8s_admin",
-			Description: "Production-ready Kubernetes worker node setup",
-			Rating:      4.8, Views: 2100,
-		},
-	}
-}
-
 func (m *Manager) getBestPractices() []BestPractice {
 	return []BestPractice{
 		{
@@ -510,8 +447,8 @@ func (m *Manager) validateMaintainability(content string, result *ValidationResu
 func (m *Manager) enhanceTrendsWithDiscourse(trends *TrendData) error {
 	ctx := context.Background()
 
-	// Get top topics from Discourse (weekly period)
-	topTopicsResp, err := m.discourseClient.GetTopTopics(ctx, "weekly", 10)
+	// Get top topics from Discourse over the timeframe of the trends
+	topTopicsResp, err := m.discourseClient.GetTopTopics(ctx, trends.Timeframe, 10)
 	if err != nil {
 		return fmt.Errorf("failed to get top topics: %w", err)
 	}
@@ -71,6 +71,7 @@ func TestManager_ValidateConfiguration(t *testing.T) {
 
 func TestManager_GetTrends(t *testing.T) {
 	mgr, _ := setupTestManager(t)
+	stubTrendingGitHub(t, mgr)
 	trends, err := mgr.GetTrends()
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
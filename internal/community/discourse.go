@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"nix-ai-help/pkg/logger"
+	"nix-ai-help/pkg/utils"
 )
 
 // DiscourseClient handles integration with NixOS Discourse forum
@@ -266,13 +267,11 @@ func NewDiscourseClient(baseURL string, apiKey string, username string) *Discour
 	}
 
 	return &DiscourseClient{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		baseURL:  baseURL,
-		apiKey:   apiKey,
-		username: username,
-		logger:   logger.NewLoggerWithLevel("info"),
+		httpClient: utils.NewHTTPClient(30 * time.Second),
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		username:   username,
+		logger:     logger.NewLoggerWithLevel("info"),
 	}
 }
 
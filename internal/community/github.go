@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"nix-ai-help/pkg/logger"
+	"nix-ai-help/pkg/utils"
 )
 
 // GitHubClient handles integration with GitHub for community configurations
@@ -20,6 +21,10 @@ type GitHubClient struct {
 	baseURL    string
 	apiToken   string // Optional, for authenticated requests
 	logger     *logger.Logger
+
+	// Transient 5xx responses are retried with exponential backoff
+	maxRetries   int
+	retryBackoff time.Duration
 }
 
 // GitHubRepository represents a GitHub repository
@@ -57,12 +62,29 @@ type GitHubSearchResponse struct {
 // NewGitHubClient creates a new GitHub client
 func NewGitHubClient(apiToken string) *GitHubClient {
 	return &GitHubClient{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		baseURL:  "https://api.github.com",
-		apiToken: apiToken,
-		logger:   logger.NewLoggerWithLevel("info"),
+		httpClient:   utils.NewHTTPClient(30 * time.Second),
+		baseURL:      "https://api.github.com",
+		apiToken:     apiToken,
+		logger:       logger.NewLoggerWithLevel("info"),
+		maxRetries:   2,
+		retryBackoff: 500 * time.Millisecond,
+	}
+}
+
+// do executes a request, retrying server errors (5xx) with exponential backoff.
+// The response of the last attempt is returned when all retries fail.
+func (gc *GitHubClient) do(req *http.Request) (*http.Response, error) {
+	backoff := gc.retryBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := gc.httpClient.Do(req.Clone(req.Context()))
+		if err != nil || resp.StatusCode < 500 || attempt >= gc.maxRetries {
+			return resp, err
+		}
+
+		gc.logger.Debug(fmt.Sprintf("GitHub API returned %d, retrying in %s", resp.StatusCode, backoff))
+		_ = resp.Body.Close()
+		time.Sleep(backoff)
+		backoff *= 2
 	}
 }
 
@@ -75,6 +97,15 @@ func (gc *GitHubClient) SearchRepositories(query string, maxResults int) ([]GitH
 	// Build search query
 	searchQuery := fmt.Sprintf("nixos %s in:name,description,readme", query)
 
+	searchResponse, err := gc.searchRepositories(searchQuery, maxResults)
+	if err != nil {
+		return nil, err
+	}
+	return searchResponse.Items, nil
+}
+
+// searchRepositories runs a GitHub repository search, most-starred first
+func (gc *GitHubClient) searchRepositories(searchQuery string, maxResults int) (*GitHubSearchResponse, error) {
 	params := url.Values{}
 	params.Set("q", searchQuery)
 	params.Set("sort", "stars")
@@ -98,7 +129,7 @@ func (gc *GitHubClient) SearchRepositories(query string, maxResults int) ([]GitH
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
 	req.Header.Set("User-Agent", "nixai-community-client")
 
-	resp, err := gc.httpClient.Do(req)
+	resp, err := gc.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -116,7 +147,7 @@ func (gc *GitHubClient) SearchRepositories(query string, maxResults int) ([]GitH
 
 	gc.logger.Info(fmt.Sprintf("GitHub search completed: found %d total, returned %d", searchResponse.TotalCount, len(searchResponse.Items)))
 
-	return searchResponse.Items, nil
+	return &searchResponse, nil
 }
 
 // GetRepository fetches detailed information about a specific repository
@@ -135,7 +166,7 @@ func (gc *GitHubClient) GetRepository(owner, repo string) (*GitHubRepository, er
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
 	req.Header.Set("User-Agent", "nixai-community-client")
 
-	resp, err := gc.httpClient.Do(req)
+	resp, err := gc.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -170,7 +201,7 @@ func (gc *GitHubClient) GetFileContent(owner, repo, path string) (string, error)
 	req.Header.Set("Accept", "application/vnd.github.v3.raw")
 	req.Header.Set("User-Agent", "nixai-community-client")
 
-	resp, err := gc.httpClient.Do(req)
+	resp, err := gc.do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -204,27 +235,30 @@ func (gc *GitHubClient) SearchNixOSConfigurations(topic string) ([]Configuration
 			continue
 		}
 
-		config := Configuration{
-			ID:          fmt.Sprintf("github_%d", repo.ID),
-			Name:        repo.Name,
-			Author:      repo.Owner.Login,
-			Description: repo.Description,
-			Tags:        repo.Topics,
-			Rating:      gc.calculateRating(repo),
-			Downloads:   repo.Forks, // Use forks as download metric
-			Views:       repo.Stars,
-			URL:         repo.URL,
-			CreatedAt:   repo.CreatedAt,
-			UpdatedAt:   repo.UpdatedAt,
-			Language:    "nix",
-		}
-
-		configs = append(configs, config)
+		configs = append(configs, gc.repoConfiguration(repo))
 	}
 
 	return configs, nil
 }
 
+// repoConfiguration describes a repository as a community configuration
+func (gc *GitHubClient) repoConfiguration(repo GitHubRepository) Configuration {
+	return Configuration{
+		ID:          fmt.Sprintf("github_%d", repo.ID),
+		Name:        repo.Name,
+		Author:      repo.Owner.Login,
+		Description: repo.Description,
+		Tags:        repo.Topics,
+		Rating:      gc.calculateRating(repo),
+		Downloads:   repo.Forks, // Use forks as download metric
+		Views:       repo.Stars,
+		URL:         repo.URL,
+		CreatedAt:   repo.CreatedAt,
+		UpdatedAt:   repo.UpdatedAt,
+		Language:    "nix",
+	}
+}
+
 // Helper methods
 
 func (gc *GitHubClient) isNixOSRelated(repo GitHubRepository) bool {
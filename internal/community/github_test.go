@@ -0,0 +1,97 @@
+package community
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nix-ai-help/pkg/utils"
+)
+
+func newTestGitHubClient(serverURL string) *GitHubClient {
+	gc := NewGitHubClient("")
+	gc.baseURL = serverURL
+	gc.retryBackoff = time.Millisecond
+	return gc
+}
+
+func TestGitHubClient_SearchRetriesTransientFailure(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"total_count":1,"items":[{"id":1,"name":"nixos-config","description":"My NixOS flake","language":"Nix","topics":["nixos"],"owner":{"login":"alice"}}]}`)
+	}))
+	defer server.Close()
+
+	configs, err := newTestGitHubClient(server.URL).SearchNixOSConfigurations("nginx")
+	if err != nil {
+		t.Fatalf("expected search to succeed after retry, got %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+	if len(configs) != 1 || configs[0].Name != "nixos-config" {
+		t.Errorf("unexpected configurations: %+v", configs)
+	}
+}
+
+func TestGitHubClient_SearchGivesUpAfterRetries(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	gc := newTestGitHubClient(server.URL)
+	if _, err := gc.SearchNixOSConfigurations("nginx"); err == nil {
+		t.Fatal("expected an error when GitHub keeps failing")
+	}
+	if attempts != gc.maxRetries+1 {
+		t.Errorf("expected %d attempts, got %d", gc.maxRetries+1, attempts)
+	}
+}
+
+func TestGitHubClient_SearchDoesNotRetryClientErrors(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "rate limited", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	if _, err := newTestGitHubClient(server.URL).SearchNixOSConfigurations("nginx"); err == nil {
+		t.Fatal("expected an error for a 403 response")
+	}
+	if attempts != 1 {
+		t.Errorf("expected a single attempt, got %d", attempts)
+	}
+}
+
+func TestGitHubClient_UsesConfiguredProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.Host)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"total_count":0,"items":[]}`)
+	}))
+	defer proxy.Close()
+	if err := utils.SetHTTPProxy(proxy.URL); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = utils.SetHTTPProxy("") }()
+
+	if _, err := newTestGitHubClient("http://api.github.test").SearchNixOSConfigurations("nginx"); err != nil {
+		t.Fatal(err)
+	}
+	if len(proxied) != 1 || proxied[0] != "api.github.test" {
+		t.Errorf("expected the search to go through the proxy, proxied hosts: %v", proxied)
+	}
+}
@@ -0,0 +1,141 @@
+package community
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// trendsCacheMaxAge keeps trends for a day; the cache key also carries the date so that the
+// trends refresh when the day changes
+const trendsCacheMaxAge = 24 * time.Hour
+
+// maxTrendingRepositories is how many repositories a trends search fetches
+const maxTrendingRepositories = 30
+
+// maxTrendingTopics is how many topics the trends list
+const maxTrendingTopics = 10
+
+// trendTimeframes maps the timeframes of `community trends` to how far back a repository must
+// have been pushed to count
+var trendTimeframes = map[string]time.Duration{
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+}
+
+// genericTopics are topics nearly every NixOS configuration repository carries; they say
+// nothing about what is trending
+var genericTopics = map[string]bool{
+	"nix": true, "nixos": true, "nixos-config": true, "nixos-configuration": true,
+	"nixos-dotfiles": true, "nix-config": true, "nix-configuration": true, "nix-dotfiles": true,
+	"dotfiles": true, "flake": true, "nix-flake": true, "nix-flakes": true,
+}
+
+// TopicStats counts the trending repositories that carry a GitHub topic
+type TopicStats struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// TrendTimeframe returns how far back a timeframe reaches; an empty timeframe is weekly
+func TrendTimeframe(timeframe string) (time.Duration, error) {
+	if timeframe == "" {
+		timeframe = "weekly"
+	}
+	window, ok := trendTimeframes[timeframe]
+	if !ok {
+		return 0, fmt.Errorf("unknown timeframe %q, use daily, weekly or monthly", timeframe)
+	}
+	return window, nil
+}
+
+// TrendingRepositories returns the most-starred NixOS configuration repositories pushed since
+// a time, optionally about a category such as "desktop", with the total number that matched
+func (gc *GitHubClient) TrendingRepositories(since time.Time, category string, maxResults int) ([]GitHubRepository, int, error) {
+	searchQuery := "nixos in:name,description,topics language:Nix pushed:>=" + since.UTC().Format("2006-01-02")
+	if category = strings.TrimSpace(category); category != "" {
+		searchQuery = category + " " + searchQuery
+	}
+	searchResponse, err := gc.searchRepositories(searchQuery, maxResults)
+	if err != nil {
+		return nil, 0, err
+	}
+	return searchResponse.Items, searchResponse.TotalCount, nil
+}
+
+// trendingTopics counts the specific topics of repositories, most common first
+func trendingTopics(repos []GitHubRepository) []TopicStats {
+	counts := make(map[string]int)
+	for _, repo := range repos {
+		for _, topic := range repo.Topics {
+			topic = strings.ToLower(topic)
+			if !genericTopics[topic] {
+				counts[topic]++
+			}
+		}
+	}
+
+	topics := make([]TopicStats, 0, len(counts))
+	for name, count := range counts {
+		topics = append(topics, TopicStats{Name: name, Count: count})
+	}
+	sort.Slice(topics, func(i, j int) bool {
+		if topics[i].Count != topics[j].Count {
+			return topics[i].Count > topics[j].Count
+		}
+		return topics[i].Name < topics[j].Name
+	})
+	if len(topics) > maxTrendingTopics {
+		topics = topics[:maxTrendingTopics]
+	}
+	return topics
+}
+
+// GetTrendsFor returns the NixOS configuration repositories and topics trending on GitHub
+// over a timeframe (daily, weekly or monthly), optionally about a category. Results are
+// cached for the day.
+func (m *Manager) GetTrendsFor(timeframe, category string) (*TrendData, error) {
+	window, err := TrendTimeframe(timeframe)
+	if err != nil {
+		return nil, err
+	}
+	if timeframe == "" {
+		timeframe = "weekly"
+	}
+	m.logger.Info("Fetching community trends")
+
+	now := time.Now()
+	cacheKey := GetCacheKey("trends", timeframe, category, now.Format("2006-01-02"))
+	var cachedTrends TrendData
+	if found, err := m.cache.GetWithMaxAge(cacheKey, &cachedTrends, trendsCacheMaxAge); err == nil && found {
+		return &cachedTrends, nil
+	}
+
+	repos, total, err := m.githubClient.TrendingRepositories(now.Add(-window), category, maxTrendingRepositories)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch trending repositories from GitHub: %w", err)
+	}
+
+	trends := &TrendData{
+		Timeframe:           timeframe,
+		Category:            category,
+		TrendingTopics:      trendingTopics(repos),
+		TotalConfigurations: total,
+		LastUpdated:         now,
+	}
+	for _, repo := range repos {
+		trends.TrendingConfigs = append(trends.TrendingConfigs, m.githubClient.repoConfiguration(repo))
+	}
+
+	// Enhance with Discourse trending topics if available
+	if m.discourseClient != nil && m.config.Discourse.Enabled {
+		if err := m.enhanceTrendsWithDiscourse(trends); err != nil {
+			m.logger.Warn("Failed to fetch Discourse trends: " + err.Error())
+		}
+	}
+
+	_ = m.cache.Set(cacheKey, trends, "trends")
+	return trends, nil
+}
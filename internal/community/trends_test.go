@@ -0,0 +1,77 @@
+package community
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// trendingReposJSON is a GitHub search response with three trending configuration repositories
+const trendingReposJSON = `{"total_count": 42, "items": [
+  {"id": 1, "name": "dotfiles", "description": "Hyprland on NixOS", "stargazers_count": 900, "topics": ["nixos", "hyprland", "home-manager"], "owner": {"login": "alice"}, "html_url": "https://github.com/alice/dotfiles"},
+  {"id": 2, "name": "nix-config", "description": "Servers with disko", "stargazers_count": 500, "topics": ["NixOS", "disko", "home-manager"], "owner": {"login": "bob"}},
+  {"id": 3, "name": "flake", "description": "Laptop flake", "stargazers_count": 120, "topics": ["hyprland", "dotfiles", "home-manager"], "owner": {"login": "carol"}}
+]}`
+
+// stubTrendingGitHub serves trendingReposJSON and records the search queries
+func stubTrendingGitHub(t *testing.T, mgr *Manager) *[]string {
+	t.Helper()
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query().Get("q"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, trendingReposJSON)
+	}))
+	t.Cleanup(server.Close)
+	mgr.githubClient = newTestGitHubClient(server.URL)
+	return &queries
+}
+
+func TestManager_GetTrendsFor(t *testing.T) {
+	mgr, _ := setupTestManager(t)
+	queries := stubTrendingGitHub(t, mgr)
+
+	trends, err := mgr.GetTrendsFor("monthly", "desktop")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	since := time.Now().Add(-30 * 24 * time.Hour).UTC().Format("2006-01-02")
+	if len(*queries) != 1 || !strings.HasPrefix((*queries)[0], "desktop nixos ") || !strings.Contains((*queries)[0], "pushed:>="+since) {
+		t.Errorf("unexpected GitHub queries: %v", *queries)
+	}
+	if trends.Timeframe != "monthly" || trends.TotalConfigurations != 42 {
+		t.Errorf("unexpected trends: %+v", trends)
+	}
+	if len(trends.TrendingConfigs) != 3 || trends.TrendingConfigs[0].Name != "dotfiles" || trends.TrendingConfigs[0].Views != 900 {
+		t.Errorf("unexpected trending configurations: %+v", trends.TrendingConfigs)
+	}
+	wantTopics := []TopicStats{{Name: "home-manager", Count: 3}, {Name: "hyprland", Count: 2}, {Name: "disko", Count: 1}}
+	if !reflect.DeepEqual(trends.TrendingTopics, wantTopics) {
+		t.Errorf("topics = %+v, want %+v", trends.TrendingTopics, wantTopics)
+	}
+
+	// The day's trends come from the cache
+	if _, err := mgr.GetTrendsFor("monthly", "desktop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*queries) != 1 {
+		t.Errorf("expected the cached trends to be used, GitHub was queried %d times", len(*queries))
+	}
+}
+
+func TestManager_GetTrendsForUnknownTimeframe(t *testing.T) {
+	mgr, _ := setupTestManager(t)
+	queries := stubTrendingGitHub(t, mgr)
+
+	if _, err := mgr.GetTrendsFor("hourly", ""); err == nil {
+		t.Error("expected an error for an unknown timeframe")
+	}
+	if len(*queries) != 0 {
+		t.Errorf("expected no GitHub query, got %v", *queries)
+	}
+}
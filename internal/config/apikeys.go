@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// defaultAPIKeyEnvVars are the environment variables holding the API keys of the cloud providers,
+// used for configurations without provider definitions
+var defaultAPIKeyEnvVars = map[string]string{
+	"openai":  "OPENAI_API_KEY",
+	"gemini":  "GEMINI_API_KEY",
+	"claude":  "CLAUDE_API_KEY",
+	"groq":    "GROQ_API_KEY",
+	"copilot": "GITHUB_TOKEN",
+}
+
+// warningOutput receives the warnings printed while loading the user config
+var warningOutput io.Writer = os.Stderr
+
+// warnedProviders records the providers already warned about, so each warning is printed once
+var warnedProviders sync.Map
+
+// APIKeyEnvVar returns the environment variable that must hold the API key of a provider, or ""
+// if the provider needs no key
+func (c *UserConfig) APIKeyEnvVar(provider string) string {
+	if p, ok := c.AIModels.Providers[provider]; ok {
+		if !p.RequiresAPIKey {
+			return ""
+		}
+		if p.EnvVar != "" {
+			return p.EnvVar
+		}
+	}
+	return defaultAPIKeyEnvVars[provider]
+}
+
+// MissingAPIKey returns the environment variable to set when the selected AI provider needs an
+// API key that is not in the environment
+func (c *UserConfig) MissingAPIKey() (string, bool) {
+	envVar := c.APIKeyEnvVar(c.AIProvider)
+	if envVar == "" || os.Getenv(envVar) != "" {
+		return "", false
+	}
+	return envVar, true
+}
+
+// MissingAPIKeyWarning describes a selected provider whose API key is not set, or returns ""
+func (c *UserConfig) MissingAPIKeyWarning() string {
+	envVar, missing := c.MissingAPIKey()
+	if !missing {
+		return ""
+	}
+	return fmt.Sprintf("ai_provider is %q but %s is not set; AI commands will fail until you run 'export %s=<your key>'",
+		c.AIProvider, envVar, envVar)
+}
+
+// warnMissingAPIKey prints MissingAPIKeyWarning once per provider. It only warns: the key may
+// still be exported before an AI command runs.
+func warnMissingAPIKey(cfg *UserConfig) {
+	warning := cfg.MissingAPIKeyWarning()
+	if warning == "" {
+		return
+	}
+	if _, warned := warnedProviders.LoadOrStore(cfg.AIProvider, true); warned {
+		return
+	}
+	_, _ = fmt.Fprintln(warningOutput, "Warning: "+warning)
+}
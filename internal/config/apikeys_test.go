@@ -0,0 +1,81 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMissingAPIKeyCloudProviders(t *testing.T) {
+	for provider, envVar := range map[string]string{
+		"openai":  "OPENAI_API_KEY",
+		"gemini":  "GEMINI_API_KEY",
+		"claude":  "CLAUDE_API_KEY",
+		"groq":    "GROQ_API_KEY",
+		"copilot": "GITHUB_TOKEN",
+	} {
+		t.Run(provider, func(t *testing.T) {
+			// Without provider definitions, as in configs written by older versions
+			cfg := &UserConfig{AIProvider: provider}
+
+			t.Setenv(envVar, "")
+			got, missing := cfg.MissingAPIKey()
+			if !missing || got != envVar {
+				t.Errorf("MissingAPIKey() = %q, %v; want %q, true", got, missing, envVar)
+			}
+			if warning := cfg.MissingAPIKeyWarning(); !strings.Contains(warning, "export "+envVar+"=") {
+				t.Errorf("warning does not name %s: %q", envVar, warning)
+			}
+
+			t.Setenv(envVar, "secret")
+			if got, missing := cfg.MissingAPIKey(); missing {
+				t.Errorf("MissingAPIKey() = %q, true with %s set", got, envVar)
+			}
+		})
+	}
+}
+
+func TestMissingAPIKeyUsesProviderDefinitions(t *testing.T) {
+	cfg := DefaultUserConfig()
+	for _, provider := range []string{"ollama", "llamacpp", "custom"} {
+		cfg.AIProvider = provider
+		if envVar, missing := cfg.MissingAPIKey(); missing {
+			t.Errorf("%s needs no API key, got %q", provider, envVar)
+		}
+	}
+
+	cfg.AIModels.Providers["openai"] = AIProviderConfig{RequiresAPIKey: true, EnvVar: "MY_OPENAI_KEY"}
+	cfg.AIProvider = "openai"
+	t.Setenv("OPENAI_API_KEY", "secret")
+	t.Setenv("MY_OPENAI_KEY", "")
+	if envVar, missing := cfg.MissingAPIKey(); !missing || envVar != "MY_OPENAI_KEY" {
+		t.Errorf("MissingAPIKey() = %q, %v; want the configured MY_OPENAI_KEY", envVar, missing)
+	}
+}
+
+func TestWarnMissingAPIKeyOnce(t *testing.T) {
+	var out bytes.Buffer
+	origOutput := warningOutput
+	warningOutput = &out
+	warnedProviders = sync.Map{}
+	defer func() {
+		warningOutput = origOutput
+		warnedProviders = sync.Map{}
+	}()
+
+	t.Setenv("GROQ_API_KEY", "")
+	cfg := &UserConfig{AIProvider: "groq"}
+	warnMissingAPIKey(cfg)
+	warnMissingAPIKey(cfg)
+	if got := strings.Count(out.String(), "GROQ_API_KEY is not set"); got != 1 {
+		t.Errorf("expected one warning, got %d:\n%s", got, out.String())
+	}
+
+	out.Reset()
+	t.Setenv("GEMINI_API_KEY", "secret")
+	warnMissingAPIKey(&UserConfig{AIProvider: "gemini"})
+	if out.Len() != 0 {
+		t.Errorf("unexpected warning with the key set: %s", out.String())
+	}
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// MaxConfigBackups is the number of previous config versions kept by SaveUserConfig
+const MaxConfigBackups = 10
+
+// configBackupLayout is the timestamp format of config backups, also used to name them in
+// `nixai config restore <timestamp>`
+const configBackupLayout = "20060102-150405"
+
+// ConfigBackup is a previous version of the user config
+type ConfigBackup struct {
+	Timestamp string
+	Time      time.Time
+	Path      string
+}
+
+// configBackupDir returns the backup directory of the config file at path
+func configBackupDir(path string) string {
+	return filepath.Join(filepath.Dir(path), "backups")
+}
+
+// writeConfigWithBackup writes data to the config file at path. An existing file with other
+// content is first copied to a timestamped backup, and only the newest keep backups are kept.
+func writeConfigWithBackup(path string, data []byte, now time.Time, keep int) error {
+	// #nosec G304 -- Config file paths are validated and not user-supplied
+	previous, err := os.ReadFile(path)
+	if err == nil && !bytes.Equal(previous, data) {
+		if err := backupConfig(path, previous, now, keep); err != nil {
+			return fmt.Errorf("failed to back up config: %w", err)
+		}
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// backupConfig stores previous as the backup taken at now and removes the oldest backups
+// beyond keep. A backup from the same second is kept, so the oldest version of a burst of
+// changes survives.
+func backupConfig(path string, previous []byte, now time.Time, keep int) error {
+	dir := configBackupDir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	backupPath := filepath.Join(dir, "config-"+now.Format(configBackupLayout)+".yaml")
+	if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+		if err := os.WriteFile(backupPath, previous, 0600); err != nil {
+			return err
+		}
+	}
+
+	backups, err := listConfigBackups(path)
+	if err != nil {
+		return err
+	}
+	for _, backup := range backups[min(keep, len(backups)):] {
+		if err := os.Remove(backup.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listConfigBackups returns the backups of the config file at path, newest first
+func listConfigBackups(path string) ([]ConfigBackup, error) {
+	files, err := filepath.Glob(filepath.Join(configBackupDir(path), "config-*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []ConfigBackup
+	for _, file := range files {
+		timestamp := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "config-"), ".yaml")
+		t, err := time.ParseInLocation(configBackupLayout, timestamp, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, ConfigBackup{Timestamp: timestamp, Time: t, Path: file})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// restoreConfigBackup replaces the config file at path with the backup of the given timestamp.
+// The current config is backed up first, so a restore can be undone as well.
+func restoreConfigBackup(path, timestamp string, now time.Time) error {
+	backups, err := listConfigBackups(path)
+	if err != nil {
+		return err
+	}
+	for _, backup := range backups {
+		if backup.Timestamp != timestamp {
+			continue
+		}
+		// #nosec G304 -- Backup paths come from the backup directory listing
+		data, err := os.ReadFile(backup.Path)
+		if err != nil {
+			return err
+		}
+		var cfg UserConfig
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return fmt.Errorf("backup %s is not a valid config: %w", timestamp, err)
+		}
+		return writeConfigWithBackup(path, data, now, MaxConfigBackups)
+	}
+	return fmt.Errorf("no config backup from %s (see 'nixai config history')", timestamp)
+}
+
+// ListConfigBackups returns the backups of the user config, newest first
+func ListConfigBackups() ([]ConfigBackup, error) {
+	path, err := ConfigFilePath()
+	if err != nil {
+		return nil, err
+	}
+	return listConfigBackups(path)
+}
+
+// RestoreConfigBackup restores the user config from the backup with the given timestamp
+func RestoreConfigBackup(timestamp string) error {
+	path, err := ConfigFilePath()
+	if err != nil {
+		return err
+	}
+	return restoreConfigBackup(path, timestamp, time.Now())
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteConfigWithBackup_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	start := time.Date(2025, 6, 14, 9, 30, 0, 0, time.Local)
+
+	// The first write has nothing to back up
+	if err := writeConfigWithBackup(path, []byte("ai_provider: v0\n"), start, 3); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 5; i++ {
+		data := []byte("ai_provider: v" + string(rune('0'+i)) + "\n")
+		if err := writeConfigWithBackup(path, data, start.Add(time.Duration(i)*time.Minute), 3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Writing unchanged content does not add a backup
+	if err := writeConfigWithBackup(path, []byte("ai_provider: v5\n"), start.Add(time.Hour), 3); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := listConfigBackups(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"20250614-093500", "20250614-093400", "20250614-093300"}
+	if len(backups) != len(expected) {
+		t.Fatalf("expected %d backups, got %+v", len(expected), backups)
+	}
+	for i, backup := range backups {
+		if backup.Timestamp != expected[i] {
+			t.Errorf("backup %d: expected %s, got %s", i, expected[i], backup.Timestamp)
+		}
+	}
+	// The newest backup holds the version before the last change
+	if data, _ := os.ReadFile(backups[0].Path); string(data) != "ai_provider: v4\n" {
+		t.Errorf("expected the newest backup to hold v4, got %q", data)
+	}
+}
+
+func TestWriteConfigWithBackup_SameSecondKeepsOldest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	now := time.Date(2025, 6, 14, 9, 30, 0, 0, time.Local)
+
+	for _, version := range []string{"v0", "v1", "v2"} {
+		if err := writeConfigWithBackup(path, []byte("ai_provider: "+version+"\n"), now, MaxConfigBackups); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups, _ := listConfigBackups(path)
+	if len(backups) != 1 {
+		t.Fatalf("expected one backup, got %+v", backups)
+	}
+	if data, _ := os.ReadFile(backups[0].Path); string(data) != "ai_provider: v0\n" {
+		t.Errorf("expected the oldest version to be kept, got %q", data)
+	}
+}
+
+func TestRestoreConfigBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	first := time.Date(2025, 6, 14, 9, 30, 0, 0, time.Local)
+
+	if err := writeConfigWithBackup(path, []byte("ai_provider: ollama\nai_model: llama3\n"), first, MaxConfigBackups); err != nil {
+		t.Fatal(err)
+	}
+	// A fat-fingered reset
+	if err := writeConfigWithBackup(path, []byte("ai_provider: openai\n"), first.Add(time.Minute), MaxConfigBackups); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := restoreConfigBackup(path, "20250614-093100", first.Add(2*time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "ai_provider: ollama\nai_model: llama3\n" {
+		t.Errorf("expected the backup to be restored, got %q", data)
+	}
+	// The replaced config can be restored in turn
+	backups, _ := listConfigBackups(path)
+	if len(backups) != 2 || backups[0].Timestamp != "20250614-093200" {
+		t.Fatalf("expected the replaced config to be backed up, got %+v", backups)
+	}
+	if data, _ := os.ReadFile(backups[0].Path); string(data) != "ai_provider: openai\n" {
+		t.Errorf("expected the replaced config in the backup, got %q", data)
+	}
+
+	if err := restoreConfigBackup(path, "20990101-000000", first); err == nil {
+		t.Error("expected an error for an unknown timestamp")
+	}
+	if err := os.WriteFile(filepath.Join(configBackupDir(path), "config-20250101-000000.yaml"), []byte("ai_provider: [\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := restoreConfigBackup(path, "20250101-000000", first); err == nil {
+		t.Error("expected an error for a corrupt backup")
+	}
+}
@@ -46,6 +46,7 @@ const EmbeddedDefaultConfig = `default:
                 supports_streaming: true
                 supports_tools: true
                 requires_api_key: false
+                max_concurrency: 1
                 models:
                     llama3:
                         name: "Llama 3"
@@ -73,7 +74,13 @@ const EmbeddedDefaultConfig = `default:
         host: localhost
         port: 8081
         socket_path: /tmp/nixai-mcp.sock
+        # Start the server in the background when a command needs documentation and it is down
         auto_start: false
+        # Each source is a URL, or url/type to choose how it is queried: options (option
+        # JSON lookups), wiki (MediaWiki search API) or manual (the passage of the page that
+        # mentions the query). Without a type it is inferred.
+        #   - url: https://example.org/options
+        #     type: options
         documentation_sources:
             - https://wiki.nixos.org/wiki/NixOS_Wiki
             - https://nix.dev/manual/nix
@@ -136,11 +143,11 @@ type Config struct {
 }
 
 type MCPServerConfig struct {
-	Host                 string   `yaml:"host" json:"host"`
-	Port                 int      `yaml:"port" json:"port"`
-	SocketPath           string   `yaml:"socket_path" json:"socket_path"`
-	AutoStart            bool     `yaml:"auto_start" json:"auto_start"`
-	DocumentationSources []string `yaml:"documentation_sources" json:"documentation_sources"`
+	Host                 string                `yaml:"host" json:"host"`
+	Port                 int                   `yaml:"port" json:"port"`
+	SocketPath           string                `yaml:"socket_path" json:"socket_path"`
+	AutoStart            bool                  `yaml:"auto_start" json:"auto_start"`
+	DocumentationSources []DocumentationSource `yaml:"documentation_sources" json:"documentation_sources"`
 }
 
 type NixosConfig struct {
@@ -155,6 +162,10 @@ type NixOSContext struct {
 	UsesChannels    bool   `yaml:"uses_channels" json:"uses_channels"`
 	NixOSConfigPath string `yaml:"nixos_config_path" json:"nixos_config_path"`
 	SystemType      string `yaml:"system_type" json:"system_type"` // "nixos", "nix-darwin", "home-manager-only", "unknown"
+	// Environment is where Nix runs: "standard", "container", "wsl" or "darwin"
+	Environment string `yaml:"environment,omitempty" json:"environment,omitempty"`
+	// ContainerRuntime names the container manager when Environment is "container", e.g. "systemd-nspawn"
+	ContainerRuntime string `yaml:"container_runtime,omitempty" json:"container_runtime,omitempty"`
 
 	// Home Manager
 	HasHomeManager        bool   `yaml:"has_home_manager" json:"has_home_manager"`
@@ -164,6 +175,8 @@ type NixOSContext struct {
 	// Version Information
 	NixOSVersion string `yaml:"nixos_version" json:"nixos_version"`
 	NixVersion   string `yaml:"nix_version" json:"nix_version"`
+	// NixpkgsRelease is the nixpkgs release the configuration builds from, such as "24.05" or "unstable"
+	NixpkgsRelease string `yaml:"nixpkgs_release,omitempty" json:"nixpkgs_release,omitempty"`
 
 	// Configuration Analysis
 	ConfigurationFiles []string `yaml:"configuration_files" json:"configuration_files"`
@@ -245,6 +258,36 @@ type DiscourseConfig struct {
 	Enabled  bool   `yaml:"enabled" json:"enabled"`
 }
 
+// NetworkConfig holds the network settings of the HTTP clients
+type NetworkConfig struct {
+	Proxy string `yaml:"proxy,omitempty" json:"proxy,omitempty"` // overrides HTTP_PROXY and HTTPS_PROXY
+}
+
+// PackageRepoConfig holds the settings of package-repo
+type PackageRepoConfig struct {
+	Timeout int `yaml:"timeout,omitempty" json:"timeout,omitempty"` // seconds for cloning, analysis and generation, 0 = default
+}
+
+// ContextConfig holds the settings of the NixOS context detection
+type ContextConfig struct {
+	// Enabled turns off detecting the NixOS context to enrich prompts when false; unset is true
+	Enabled *bool `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+}
+
+// AISamplingConfig sets the temperature and top_p of AI queries; unset values keep the
+// provider's default
+type AISamplingConfig struct {
+	Temperature *float64 `yaml:"temperature,omitempty" json:"temperature,omitempty"`
+	TopP        *float64 `yaml:"top_p,omitempty" json:"top_p,omitempty"`
+	// Commands overrides the sampling of single commands, keyed by command name
+	Commands map[string]AISamplingConfig `yaml:"commands,omitempty" json:"commands,omitempty"`
+}
+
+// OutputConfig holds the output preferences shared by commands
+type OutputConfig struct {
+	DefaultFormat string `yaml:"default_format,omitempty" json:"default_format,omitempty"` // markdown, plain or json
+}
+
 // AI Models Configuration Structures
 
 // AIModelConfig represents a single AI model configuration
@@ -272,6 +315,7 @@ type AIProviderConfig struct {
 	SupportsTools     bool                     `yaml:"supports_tools" json:"supports_tools"`
 	RequiresAPIKey    bool                     `yaml:"requires_api_key" json:"requires_api_key"`
 	EnvVar            string                   `yaml:"env_var,omitempty" json:"env_var,omitempty"`
+	MaxConcurrency    int                      `yaml:"max_concurrency,omitempty" json:"max_concurrency,omitempty"` // queries in flight to an Ollama server at once, 0 = 1; other providers ignore it
 	Models            map[string]AIModelConfig `yaml:"models" json:"models"`
 }
 
@@ -301,6 +345,18 @@ type AIModelsConfig struct {
 	Providers            map[string]AIProviderConfig `yaml:"providers" json:"providers"`
 	SelectionPreferences AISelectionPreferences      `yaml:"selection_preferences" json:"selection_preferences"`
 	Discovery            AIDiscoveryConfig           `yaml:"discovery" json:"discovery"`
+	ForbiddenPatterns    []string                    `yaml:"forbidden_patterns,omitempty" json:"forbidden_patterns,omitempty"`
+	Tiers                AIModelTiers                `yaml:"tiers,omitempty" json:"tiers,omitempty"`
+}
+
+// AIModelTiers names the models picked by task: a fast one for simple lookups and a strong one
+// for complex diagnosis. Both are models of the selected provider; an unset tier keeps the
+// provider's default model.
+type AIModelTiers struct {
+	Fast   string `yaml:"fast,omitempty" json:"fast,omitempty"`
+	Strong string `yaml:"strong,omitempty" json:"strong,omitempty"`
+	// Commands pins the tier of single commands (fast, strong or default), keyed by command name
+	Commands map[string]string `yaml:"commands,omitempty" json:"commands,omitempty"`
 }
 
 type YAMLConfig struct {
@@ -322,6 +378,7 @@ type UserConfig struct {
 	AIModel      string            `yaml:"ai_model" json:"ai_model"`
 	NixosFolder  string            `yaml:"nixos_folder" json:"nixos_folder"`
 	LogLevel     string            `yaml:"log_level" json:"log_level"`
+	Language     string            `yaml:"language,omitempty" json:"language,omitempty"` // Language for AI responses, e.g. "de"
 	AIModels     AIModelsConfig    `yaml:"ai_models" json:"ai_models"`
 	MCPServer    MCPServerConfig   `yaml:"mcp_server" json:"mcp_server"`
 	Nixos        NixosConfig       `yaml:"nixos" json:"nixos"`
@@ -332,6 +389,41 @@ type UserConfig struct {
 	CustomAI     CustomAIConfig    `yaml:"custom_ai" json:"custom_ai"`
 	Discourse    DiscourseConfig   `yaml:"discourse" json:"discourse"`
 	NixOSContext NixOSContext      `yaml:"nixos_context" json:"nixos_context"`
+	Output       OutputConfig      `yaml:"output,omitempty" json:"output,omitempty"`
+	Network      NetworkConfig     `yaml:"network,omitempty" json:"network,omitempty"`
+	AISampling   AISamplingConfig  `yaml:"ai_sampling,omitempty" json:"ai_sampling,omitempty"`
+	PackageRepo  PackageRepoConfig `yaml:"package_repo,omitempty" json:"package_repo,omitempty"`
+	Context      ContextConfig     `yaml:"context,omitempty" json:"context,omitempty"`
+}
+
+// contextDetectionDisabled is set by --no-context; see SetContextDetectionDisabled
+var contextDetectionDisabled bool
+
+// SetContextDetectionDisabled turns off NixOS context detection for the rest of the process,
+// whatever context.enabled says
+func SetContextDetectionDisabled(disabled bool) {
+	contextDetectionDisabled = disabled
+}
+
+// ContextDetectionEnabled reports whether commands may detect the NixOS context to enrich their
+// prompts: not with --no-context, nor with context.enabled set to false
+func (c *UserConfig) ContextDetectionEnabled() bool {
+	if contextDetectionDisabled {
+		return false
+	}
+	return c.Context.Enabled == nil || *c.Context.Enabled
+}
+
+// defaultPackageRepoTimeout bounds package-repo when package_repo.timeout is unset
+const defaultPackageRepoTimeout = 10 * time.Minute
+
+// PackageRepoTimeout returns how long package-repo may take to clone, analyze and package a
+// repository
+func (c *UserConfig) PackageRepoTimeout() time.Duration {
+	if c.PackageRepo.Timeout <= 0 {
+		return defaultPackageRepoTimeout
+	}
+	return time.Duration(c.PackageRepo.Timeout) * time.Second
 }
 
 // GetAITimeout returns the timeout for a specific AI provider
@@ -411,6 +503,7 @@ func DefaultUserConfig() *UserConfig {
 					SupportsStreaming: true,
 					SupportsTools:     true,
 					RequiresAPIKey:    false,
+					MaxConcurrency:    1,
 					Models: map[string]AIModelConfig{
 						"llama3": {
 							Name:             "Llama 3",
@@ -543,14 +636,14 @@ func DefaultUserConfig() *UserConfig {
 			Port:       8081,
 			SocketPath: "/tmp/nixai-mcp.sock",
 			AutoStart:  false,
-			DocumentationSources: []string{
+			DocumentationSources: DocumentationSourcesFromURLs(
 				"https://wiki.nixos.org/wiki/NixOS_Wiki",
 				"https://nix.dev/manual/nix",
 				"https://nix.dev/",
 				"https://nixos.org/manual/nixpkgs/stable/",
 				"https://nix.dev/manual/nix/2.28/language/",
 				"https://nix-community.github.io/home-manager/",
-			},
+			),
 		},
 		Nixos: NixosConfig{
 			ConfigPath: "~/nixos-config/configuration.nix",
@@ -631,7 +724,29 @@ func DefaultUserConfig() *UserConfig {
 	}
 }
 
+// configFilePathOverride replaces the default config file location; see SetConfigFilePath
+var configFilePathOverride string
+
+// SetConfigFilePath makes every config load and save of the process use path instead of
+// ~/.config/nixai/config.yaml, e.g. for the --config flag. An empty path restores the default.
+func SetConfigFilePath(path string) error {
+	if path == "" {
+		configFilePathOverride = ""
+		return nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	configFilePathOverride = abs
+	return nil
+}
+
+// ConfigFilePath returns the path of the user config file
 func ConfigFilePath() (string, error) {
+	if configFilePathOverride != "" {
+		return configFilePathOverride, nil
+	}
 	usr, err := user.Current()
 	if err != nil {
 		return "", err
@@ -664,6 +779,8 @@ func EnsureConfigFile() (string, error) {
 	return path, nil
 }
 
+// LoadUserConfig reads the user config, creating it from the defaults on first use. A selected
+// cloud provider without its API key in the environment is reported on stderr.
 func LoadUserConfig() (*UserConfig, error) {
 	path, err := EnsureConfigFile()
 	if err != nil {
@@ -678,10 +795,27 @@ func LoadUserConfig() (*UserConfig, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, err
 	}
+	warnMissingAPIKey(&cfg)
 	return &cfg, nil
 }
 
+// SaveUserConfig writes the user config, keeping the previous version as a timestamped backup
+// (see MaxConfigBackups) that `nixai config restore` can bring back
 func SaveUserConfig(cfg *UserConfig) error {
+	path, err := ConfigFilePath()
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	return writeConfigWithBackup(path, data, time.Now(), MaxConfigBackups)
+}
+
+// SaveUserConfigWithoutBackup writes the user config without a backup, for automatic updates
+// such as the cached NixOS context that would otherwise rotate user changes out of the backups
+func SaveUserConfigWithoutBackup(cfg *UserConfig) error {
 	path, err := ConfigFilePath()
 	if err != nil {
 		return err
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPackageRepoTimeout(t *testing.T) {
+	cfg := &UserConfig{}
+	if got := cfg.PackageRepoTimeout(); got != defaultPackageRepoTimeout {
+		t.Errorf("unset timeout: got %s, want %s", got, defaultPackageRepoTimeout)
+	}
+	cfg.PackageRepo.Timeout = 90
+	if got := cfg.PackageRepoTimeout(); got != 90*time.Second {
+		t.Errorf("got %s, want 1m30s", got)
+	}
+}
+
+func TestSetConfigFilePath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "team.yaml")
+	if err := os.WriteFile(path, []byte("ai_provider: gemini\nai_model: gemini-1.5-pro\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetConfigFilePath(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = SetConfigFilePath("") })
+
+	if got, _ := ConfigFilePath(); got != path {
+		t.Errorf("ConfigFilePath() = %s, want %s", got, path)
+	}
+	cfg, err := LoadUserConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AIModel != "gemini-1.5-pro" {
+		t.Errorf("expected the alternate config to be loaded, got model %q", cfg.AIModel)
+	}
+
+	cfg.AIModel = "gemini-2.0-flash"
+	if err := SaveUserConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "gemini-2.0-flash") {
+		t.Errorf("expected the alternate config to be saved, got:\n%s", data)
+	}
+	if backups, _ := ListConfigBackups(); len(backups) != 1 {
+		t.Errorf("expected the backup next to the alternate config, got %+v", backups)
+	}
+}
+
+func TestSetConfigFilePathCreatesMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "setups", "work.yaml")
+	if err := SetConfigFilePath(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = SetConfigFilePath("") })
+
+	if _, err := LoadUserConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected the defaults to be written to the alternate path: %v", err)
+	}
+}
+
+func TestContextDetectionEnabled(t *testing.T) {
+	disabled, enabled := false, true
+	t.Cleanup(func() { SetContextDetectionDisabled(false) })
+
+	for _, tc := range []struct {
+		name      string
+		setting   *bool
+		noContext bool
+		want      bool
+	}{
+		{"unset", nil, false, true},
+		{"enabled", &enabled, false, true},
+		{"context.enabled false", &disabled, false, false},
+		{"--no-context", &enabled, true, false},
+	} {
+		SetContextDetectionDisabled(tc.noContext)
+		cfg := &UserConfig{Context: ContextConfig{Enabled: tc.setting}}
+		if got := cfg.ContextDetectionEnabled(); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
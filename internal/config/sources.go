@@ -0,0 +1,128 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Documentation source types, deciding how the MCP server queries a source
+const (
+	// SourceTypeWiki is a wiki searched by full text, e.g. wiki.nixos.org
+	SourceTypeWiki = "wiki"
+	// SourceTypeManual is a manual or documentation site searched by full text, e.g. nix.dev
+	SourceTypeManual = "manual"
+	// SourceTypeOptions is an option index queried for option JSON, e.g. search.nixos.org options
+	SourceTypeOptions = "options"
+)
+
+// DocumentationSource is a documentation source of the MCP server. In YAML it is either a
+// plain URL, whose type is inferred from the URL, or a mapping with url and type:
+//
+//	documentation_sources:
+//	  - https://wiki.nixos.org/wiki/NixOS_Wiki
+//	  - url: https://example.org/options
+//	    type: options
+type DocumentationSource struct {
+	URL  string `yaml:"url" json:"url"`
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+}
+
+// SourceType returns the declared type of the source, or the type inferred from its URL
+func (s DocumentationSource) SourceType() string {
+	if s.Type != "" {
+		return s.Type
+	}
+	return InferSourceType(s.URL)
+}
+
+// UnmarshalYAML accepts both a plain URL and a url/type mapping
+func (s *DocumentationSource) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*s = DocumentationSource{URL: value.Value}
+		return nil
+	}
+	type plain DocumentationSource
+	var source plain
+	if err := value.Decode(&source); err != nil {
+		return err
+	}
+	*s = DocumentationSource(source)
+	return s.validate()
+}
+
+// MarshalYAML writes sources without a declared type as plain URLs
+func (s DocumentationSource) MarshalYAML() (interface{}, error) {
+	if s.Type == "" {
+		return s.URL, nil
+	}
+	type plain DocumentationSource
+	return plain(s), nil
+}
+
+// UnmarshalJSON accepts both a plain URL and a url/type object
+func (s *DocumentationSource) UnmarshalJSON(data []byte) error {
+	var url string
+	if err := json.Unmarshal(data, &url); err == nil {
+		*s = DocumentationSource{URL: url}
+		return nil
+	}
+	type plain DocumentationSource
+	var source plain
+	if err := json.Unmarshal(data, &source); err != nil {
+		return err
+	}
+	*s = DocumentationSource(source)
+	return s.validate()
+}
+
+// validate checks the declared source type
+func (s DocumentationSource) validate() error {
+	switch s.Type {
+	case "", SourceTypeWiki, SourceTypeManual, SourceTypeOptions:
+		return nil
+	}
+	return fmt.Errorf("documentation source %s: unknown type %q (use wiki, manual or options)", s.URL, s.Type)
+}
+
+// InferSourceType guesses the type of a source without a declared type from its URL
+func InferSourceType(url string) string {
+	switch {
+	case strings.HasPrefix(url, "nixos-options-es://"), strings.HasSuffix(url, "/options"),
+		strings.HasSuffix(url, "/options.json"), strings.Contains(url, "search.nixos.org/options"):
+		return SourceTypeOptions
+	case strings.Contains(url, "wiki"):
+		return SourceTypeWiki
+	default:
+		return SourceTypeManual
+	}
+}
+
+// SourceURLs returns the URLs of the sources
+func SourceURLs(sources []DocumentationSource) []string {
+	urls := make([]string, len(sources))
+	for i, source := range sources {
+		urls[i] = source.URL
+	}
+	return urls
+}
+
+// SourceTypes maps the URL of each source to its type
+func SourceTypes(sources []DocumentationSource) map[string]string {
+	types := make(map[string]string, len(sources))
+	for _, source := range sources {
+		types[source.URL] = source.SourceType()
+	}
+	return types
+}
+
+// DocumentationSourcesFromURLs returns sources of the given URLs with inferred types
+func DocumentationSourcesFromURLs(urls ...string) []DocumentationSource {
+	sources := make([]DocumentationSource, len(urls))
+	for i, url := range urls {
+		sources[i] = DocumentationSource{URL: url}
+	}
+	return sources
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestDocumentationSourcesYAML(t *testing.T) {
+	data := `
+documentation_sources:
+  - https://wiki.nixos.org/wiki/NixOS_Wiki
+  - nixos-options-es://options
+  - url: https://example.org/docs
+    type: options
+  - url: https://example.org/wiki-like-manual
+    type: manual
+`
+	var cfg MCPServerConfig
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ url, sourceType string }{
+		{"https://wiki.nixos.org/wiki/NixOS_Wiki", SourceTypeWiki},
+		{"nixos-options-es://options", SourceTypeOptions},
+		{"https://example.org/docs", SourceTypeOptions},
+		{"https://example.org/wiki-like-manual", SourceTypeManual},
+	}
+	if len(cfg.DocumentationSources) != len(want) {
+		t.Fatalf("got %d sources, want %d", len(cfg.DocumentationSources), len(want))
+	}
+	for i, w := range want {
+		source := cfg.DocumentationSources[i]
+		if source.URL != w.url || source.SourceType() != w.sourceType {
+			t.Errorf("source %d = %s (%s), want %s (%s)", i, source.URL, source.SourceType(), w.url, w.sourceType)
+		}
+	}
+
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "- https://wiki.nixos.org/wiki/NixOS_Wiki") || !strings.Contains(string(out), "type: options") {
+		t.Errorf("sources not written back in their short and typed forms:\n%s", out)
+	}
+}
+
+func TestDocumentationSourceInvalidType(t *testing.T) {
+	var cfg MCPServerConfig
+	err := yaml.Unmarshal([]byte("documentation_sources:\n  - url: https://example.org\n    type: forum\n"), &cfg)
+	if err == nil || !strings.Contains(err.Error(), "unknown type") {
+		t.Errorf("expected an unknown type error, got %v", err)
+	}
+}
+
+func TestDocumentationSourcesJSON(t *testing.T) {
+	var cfg MCPServerConfig
+	if err := json.Unmarshal([]byte(`{"documentation_sources": ["https://nix.dev/manual/nix", {"url": "https://example.org/o", "type": "options"}]}`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	types := SourceTypes(cfg.DocumentationSources)
+	if types["https://nix.dev/manual/nix"] != SourceTypeManual || types["https://example.org/o"] != SourceTypeOptions {
+		t.Errorf("unexpected source types: %v", types)
+	}
+}
+
+func TestInferSourceType(t *testing.T) {
+	tests := map[string]string{
+		"nixos-options-es://options":                             SourceTypeOptions,
+		"https://search.nixos.org/options":                       SourceTypeOptions,
+		"https://home-manager-options.extranix.com/options.json": SourceTypeOptions,
+		"https://wiki.nixos.org/wiki/NixOS_Wiki":                 SourceTypeWiki,
+		"https://nix.dev/manual/nix":                             SourceTypeManual,
+		"https://nixos.org/manual/nixpkgs/stable/":               SourceTypeManual,
+	}
+	for url, want := range tests {
+		if got := InferSourceType(url); got != want {
+			t.Errorf("InferSourceType(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
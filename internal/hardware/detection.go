@@ -6,6 +6,8 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+
+	"nix-ai-help/pkg/utils"
 )
 
 // HardwareInfo represents detected hardware information
@@ -138,7 +140,7 @@ func DetectHardwareComponents() (*HardwareInfo, error) {
 // runCommand executes a shell command and returns its output
 func runCommand(command string) (string, error) {
 	cmd := exec.Command("sh", "-c", command)
-	output, err := cmd.Output()
+	output, err := utils.TraceOutput(cmd)
 	if err != nil {
 		return "", err
 	}
@@ -42,6 +42,10 @@ type Question struct {
 type Progress struct {
 	CompletedModules map[string]bool
 	QuizScores       map[string]int
+	// StudiedModules are the modules opened with learn <topic>
+	StudiedModules map[string]bool
+	// Reviews is the spaced-repetition schedule of each reviewed question
+	Reviews map[string]ReviewState
 }
 
 // LoadModules loads available learning modules (stub).
@@ -77,7 +81,8 @@ func LoadProgress() (Progress, error) {
 	data, err := os.ReadFile(progressPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return Progress{CompletedModules: map[string]bool{}, QuizScores: map[string]int{}}, nil
+			return Progress{CompletedModules: map[string]bool{}, QuizScores: map[string]int{},
+				StudiedModules: map[string]bool{}, Reviews: map[string]ReviewState{}}, nil
 		}
 		return Progress{}, err
 	}
@@ -91,6 +96,12 @@ func LoadProgress() (Progress, error) {
 	if progress.QuizScores == nil {
 		progress.QuizScores = map[string]int{}
 	}
+	if progress.StudiedModules == nil {
+		progress.StudiedModules = map[string]bool{}
+	}
+	if progress.Reviews == nil {
+		progress.Reviews = map[string]ReviewState{}
+	}
 	return progress, nil
 }
 
@@ -0,0 +1,137 @@
+package learning
+
+import (
+	"fmt"
+	"strings"
+
+	"nix-ai-help/internal/config"
+)
+
+// Topics are the learning modules nixai offers, in the order a newcomer takes them
+var Topics = []Module{
+	{ID: "basics", Title: "NixOS fundamentals", Level: "basics",
+		Description: "The Nix store, generations, rebuilding and rolling back"},
+	{ID: "configuration", Title: "Configuration management", Level: "basics",
+		Description: "configuration.nix, the module system and splitting configuration with imports"},
+	{ID: "packages", Title: "Package management", Level: "basics",
+		Description: "Finding, installing and pinning packages declaratively"},
+	{ID: "services", Title: "System services", Level: "intermediate",
+		Description: "Enabling and configuring services through NixOS options"},
+	{ID: "flakes", Title: "Nix flakes", Level: "intermediate",
+		Description: "Flake inputs and outputs, flake.lock and building systems from a flake"},
+	{ID: "home-manager", Title: "Home Manager", Level: "intermediate",
+		Description: "Managing user programs and dotfiles declaratively"},
+	{ID: "advanced", Title: "Advanced topics", Level: "advanced",
+		Description: "Overlays, custom modules and writing derivations"},
+}
+
+// maxListedServices limits how many services a recommendation names
+const maxListedServices = 3
+
+// Recommendation is one module of a learning path with the reason it was chosen
+type Recommendation struct {
+	Module    Module
+	Reason    string
+	Completed bool
+}
+
+// RecommendPath returns an ordered learning path for the detected NixOS context. Modules
+// matching what the system uses (flakes, Home Manager, services) are included with the reason
+// they were picked, flakes early for systems built from a flake. Modules completed according
+// to progress, including by their quiz results, are marked. A nil context yields the general path.
+func RecommendPath(ctx *config.NixOSContext, progress Progress) []Recommendation {
+	if ctx == nil {
+		ctx = &config.NixOSContext{}
+	}
+	usesFlakes := ctx.UsesFlakes || ctx.FlakeFile != ""
+
+	var path []Recommendation
+	add := func(id, reason string) {
+		module, ok := Topic(id)
+		if !ok {
+			return
+		}
+		path = append(path, Recommendation{Module: module, Reason: reason, Completed: Completed(progress, id)})
+	}
+
+	add("basics", "Start here: everything else builds on how Nix stores and switches systems")
+	if usesFlakes {
+		add("flakes", "Your system is built from a flake, so learn how its inputs and outputs describe it early on")
+	}
+	add("configuration", configurationReason(ctx))
+	add("packages", packagesReason(ctx))
+	if len(ctx.EnabledServices) > 0 {
+		add("services", servicesReason(ctx.EnabledServices))
+	} else if ctx.SystemType != "home-manager-only" {
+		add("services", "Most NixOS setups run services; learn how to enable and configure them")
+	}
+	if ctx.HasHomeManager || ctx.SystemType == "home-manager-only" {
+		add("home-manager", homeManagerReason(ctx))
+	}
+	if !usesFlakes {
+		if ctx.UsesChannels {
+			add("flakes", "You use channels; flakes pin every input in flake.lock for reproducible rebuilds")
+		} else {
+			add("flakes", "Flakes are the modern way to pin inputs and share configurations")
+		}
+	}
+	add("advanced", "Once the above feel familiar: overlays, custom modules and derivations")
+	return path
+}
+
+// Topic returns the learning module with the given ID
+func Topic(id string) (Module, bool) {
+	for _, module := range Topics {
+		if module.ID == id {
+			return module, true
+		}
+	}
+	return Module{}, false
+}
+
+// configurationReason explains the configuration module for the detected configuration layout
+func configurationReason(ctx *config.NixOSContext) string {
+	switch {
+	case len(ctx.ConfigurationFiles) > 1:
+		return fmt.Sprintf("Your configuration is split over %d files; learn how imports and modules combine them",
+			len(ctx.ConfigurationFiles))
+	case ctx.ConfigurationNix != "":
+		return "Learn how " + ctx.ConfigurationNix + " describes your whole system"
+	default:
+		return "Learn how configuration.nix describes your whole system"
+	}
+}
+
+// packagesReason explains the packages module for the detected packages
+func packagesReason(ctx *config.NixOSContext) string {
+	if len(ctx.InstalledPackages) > 0 {
+		return fmt.Sprintf("You install %d packages system-wide; learn to find, add and pin them declaratively",
+			len(ctx.InstalledPackages))
+	}
+	return "Learn to find and install packages declaratively instead of imperatively"
+}
+
+// servicesReason names the services the system runs
+func servicesReason(services []string) string {
+	listed := services
+	if len(listed) > maxListedServices {
+		listed = listed[:maxListedServices]
+	}
+	names := strings.Join(listed, ", ")
+	if len(services) > maxListedServices {
+		names += fmt.Sprintf(" and %d more", len(services)-maxListedServices)
+	}
+	return "You run services such as " + names + "; learn how their options fit together"
+}
+
+// homeManagerReason explains the Home Manager module for the detected Home Manager setup
+func homeManagerReason(ctx *config.NixOSContext) string {
+	switch ctx.HomeManagerType {
+	case "module":
+		return "You use Home Manager as a NixOS module; learn how user configuration fits into the system"
+	case "standalone":
+		return "You use standalone Home Manager; learn how home.nix manages your programs and dotfiles"
+	default:
+		return "You use Home Manager; learn how it manages your programs and dotfiles"
+	}
+}
@@ -0,0 +1,81 @@
+package learning
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/config"
+)
+
+// pathIDs returns the module IDs of a learning path
+func pathIDs(path []Recommendation) []string {
+	ids := make([]string, len(path))
+	for i, step := range path {
+		ids[i] = step.Module.ID
+	}
+	return ids
+}
+
+func TestRecommendPath(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  *config.NixOSContext
+		want []string
+	}{
+		{"no context", nil,
+			[]string{"basics", "configuration", "packages", "services", "flakes", "advanced"}},
+		{"flakes", &config.NixOSContext{UsesFlakes: true, FlakeFile: "/etc/nixos/flake.nix"},
+			[]string{"basics", "flakes", "configuration", "packages", "services", "advanced"}},
+		{"channels with home manager", &config.NixOSContext{UsesChannels: true, HasHomeManager: true, HomeManagerType: "module"},
+			[]string{"basics", "configuration", "packages", "services", "home-manager", "flakes", "advanced"}},
+		{"flakes with home manager and services", &config.NixOSContext{UsesFlakes: true, HasHomeManager: true,
+			EnabledServices: []string{"nginx", "openssh"}},
+			[]string{"basics", "flakes", "configuration", "packages", "services", "home-manager", "advanced"}},
+		{"home manager only", &config.NixOSContext{SystemType: "home-manager-only", HomeManagerType: "standalone"},
+			[]string{"basics", "configuration", "packages", "home-manager", "flakes", "advanced"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pathIDs(RecommendPath(tt.ctx, Progress{})); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RecommendPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecommendPathReasons(t *testing.T) {
+	ctx := &config.NixOSContext{
+		UsesFlakes:         true,
+		ConfigurationFiles: []string{"flake.nix", "configuration.nix", "hardware-configuration.nix"},
+		EnabledServices:    []string{"nginx", "openssh", "postgresql", "tailscale"},
+	}
+	reasons := map[string]string{}
+	for _, step := range RecommendPath(ctx, Progress{}) {
+		reasons[step.Module.ID] = step.Reason
+	}
+
+	if !strings.Contains(reasons["flakes"], "built from a flake") {
+		t.Errorf("flakes reason = %q", reasons["flakes"])
+	}
+	if !strings.Contains(reasons["configuration"], "split over 3 files") {
+		t.Errorf("configuration reason = %q", reasons["configuration"])
+	}
+	if !strings.Contains(reasons["services"], "nginx, openssh, postgresql and 1 more") {
+		t.Errorf("services reason = %q", reasons["services"])
+	}
+}
+
+func TestRecommendPathMarksCompleted(t *testing.T) {
+	progress := Progress{CompletedModules: map[string]bool{"basics": true}, Reviews: map[string]ReviewState{}}
+	// flakes is completed by its quiz results alone
+	for i := range quizzes["flakes"].Questions {
+		progress.Reviews[reviewKey("flakes", i)] = ReviewState{Repetitions: 1}
+	}
+	for _, step := range RecommendPath(&config.NixOSContext{UsesFlakes: true}, progress) {
+		want := step.Module.ID == "basics" || step.Module.ID == "flakes"
+		if step.Completed != want {
+			t.Errorf("%s completed = %v, want %v", step.Module.ID, step.Completed, want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package learning
+
+// quizzes are the review questions of each learning topic
+var quizzes = map[string]Quiz{
+	"basics": {Questions: []Question{
+		{Prompt: "What happens to the previous system when you run nixos-rebuild switch?",
+			Choices:  []string{"It is deleted", "It stays available as an older generation you can roll back to", "It is moved to /etc/nixos/backup"},
+			Answer:   1,
+			Feedback: "Every rebuild creates a new generation; older ones stay in the boot menu and 'nixos-rebuild switch --rollback' returns to them."},
+		{Prompt: "Where does Nix keep built packages?",
+			Choices:  []string{"/usr/lib", "/nix/store", "/opt/nix"},
+			Answer:   1,
+			Feedback: "Everything Nix builds lives in /nix/store under a path containing the hash of its inputs."},
+	}},
+	"configuration": {Questions: []Question{
+		{Prompt: "How do you include another .nix file in configuration.nix?",
+			Choices:  []string{"imports = [ ./other.nix ];", "include ./other.nix;", "source ./other.nix"},
+			Answer:   0,
+			Feedback: "The module system merges every file listed in imports into one configuration."},
+		{Prompt: "Which command applies configuration changes and makes them the boot default?",
+			Choices:  []string{"nixos-rebuild test", "nixos-rebuild switch", "nix-env -i"},
+			Answer:   1,
+			Feedback: "switch activates the new generation and adds it to the boot menu; test only activates it."},
+	}},
+	"packages": {Questions: []Question{
+		{Prompt: "Where do you declare packages installed for all users?",
+			Choices:  []string{"environment.systemPackages", "users.packages", "nix-env -iA"},
+			Answer:   0,
+			Feedback: "environment.systemPackages is the declarative list of system-wide packages."},
+		{Prompt: "Which command searches nixpkgs for a package?",
+			Choices:  []string{"nix search nixpkgs <name>", "apt search <name>", "nix-store --query <name>"},
+			Answer:   0,
+			Feedback: "nix search queries the package set; search.nixos.org offers the same online."},
+	}},
+	"services": {Questions: []Question{
+		{Prompt: "How do you enable the OpenSSH server?",
+			Choices:  []string{"systemctl enable sshd", "services.openssh.enable = true;", "environment.ssh = true;"},
+			Answer:   1,
+			Feedback: "Services are enabled through their NixOS options; systemctl changes are lost on the next rebuild."},
+		{Prompt: "How do you read the logs of a failed service?",
+			Choices:  []string{"journalctl -u <service>", "cat /var/log/nixos/<service>.log", "nix log <service>"},
+			Answer:   0,
+			Feedback: "NixOS services are systemd units, so their logs are in the journal."},
+	}},
+	"flakes": {Questions: []Question{
+		{Prompt: "Which file pins the exact revisions of a flake's inputs?",
+			Choices:  []string{"flake.nix", "flake.lock", "default.nix"},
+			Answer:   1,
+			Feedback: "flake.lock records the revision and hash of every input; commit it with the flake."},
+		{Prompt: "How do you update all inputs of a flake?",
+			Choices:  []string{"nix flake update", "nix-channel --update", "nixos-rebuild upgrade"},
+			Answer:   0,
+			Feedback: "nix flake update rewrites flake.lock with the latest revisions of the inputs."},
+	}},
+	"home-manager": {Questions: []Question{
+		{Prompt: "What does Home Manager manage?",
+			Choices:  []string{"Kernel modules", "User programs and dotfiles", "Boot loaders"},
+			Answer:   1,
+			Feedback: "Home Manager configures a user's environment: programs, dotfiles and user services."},
+		{Prompt: "Which command applies a standalone Home Manager configuration?",
+			Choices:  []string{"home-manager switch", "nixos-rebuild switch --home", "nix-env --home"},
+			Answer:   0,
+			Feedback: "Standalone Home Manager has its own switch; as a NixOS module it is applied by nixos-rebuild."},
+	}},
+	"advanced": {Questions: []Question{
+		{Prompt: "What is an overlay used for?",
+			Choices:  []string{"Changing or adding packages in nixpkgs", "Mounting file systems", "Layering container images"},
+			Answer:   0,
+			Feedback: "Overlays are functions that override or extend the package set."},
+		{Prompt: "Which function builds a package from source?",
+			Choices:  []string{"stdenv.mkDerivation", "lib.mkOption", "lib.mkIf"},
+			Answer:   0,
+			Feedback: "stdenv.mkDerivation runs the standard build phases; mkOption and mkIf belong to the module system."},
+	}},
+}
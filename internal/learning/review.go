@@ -0,0 +1,162 @@
+package learning
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Spaced-repetition parameters of the SM-2 algorithm
+const (
+	// InitialEase is the ease factor of a question reviewed for the first time
+	InitialEase = 2.5
+	// minEase keeps hard questions from being scheduled ever more often
+	minEase = 1.3
+	// passingQuality is the lowest answer quality that counts as remembered
+	passingQuality = 3
+)
+
+// Answer qualities used by learn review: SM-2 grades answers from 0 to 5
+const (
+	QualityCorrect = 4
+	QualityWrong   = 1
+)
+
+// ReviewState is the spaced-repetition schedule of one quiz question
+type ReviewState struct {
+	Ease         float64
+	IntervalDays int
+	Repetitions  int
+	Due          time.Time
+}
+
+// ReviewQuestion is a quiz question due for review
+type ReviewQuestion struct {
+	Key      string
+	ModuleID string
+	Question Question
+	// New is set for questions that were never reviewed
+	New bool
+}
+
+// reviewKey identifies a question of a module in the review schedule
+func reviewKey(moduleID string, index int) string {
+	return fmt.Sprintf("%s#%d", moduleID, index)
+}
+
+// Schedule returns the next review state of a question answered with the given quality (0-5)
+// at now, following SM-2: remembered answers grow the interval (1 day, 6 days, then interval
+// times ease), forgotten ones restart at one day, and the ease follows the answer quality.
+func Schedule(state ReviewState, quality int, now time.Time) ReviewState {
+	if state.Ease == 0 {
+		state.Ease = InitialEase
+	}
+
+	if quality < passingQuality {
+		state.Repetitions = 0
+		state.IntervalDays = 1
+	} else {
+		switch state.Repetitions {
+		case 0:
+			state.IntervalDays = 1
+		case 1:
+			state.IntervalDays = 6
+		default:
+			state.IntervalDays = int(math.Round(float64(state.IntervalDays) * state.Ease))
+		}
+		state.Repetitions++
+	}
+
+	missed := float64(5 - quality)
+	state.Ease = math.Max(minEase, state.Ease+0.1-missed*(0.08+missed*0.02))
+	state.Due = now.AddDate(0, 0, state.IntervalDays)
+	return state
+}
+
+// MarkStudied records that a module was studied, so that its questions come up in reviews
+func MarkStudied(progress *Progress, moduleID string) {
+	if progress.StudiedModules == nil {
+		progress.StudiedModules = map[string]bool{}
+	}
+	progress.StudiedModules[moduleID] = true
+}
+
+// studied reports whether a module was studied, completed or quizzed
+func studied(progress Progress, moduleID string) bool {
+	_, quizzed := progress.QuizScores[moduleID]
+	return progress.StudiedModules[moduleID] || progress.CompletedModules[moduleID] || quizzed
+}
+
+// DueReviews returns the questions of studied modules that are due at now: scheduled questions
+// whose due time has passed, most overdue first, followed by questions never reviewed
+func DueReviews(progress Progress, now time.Time) []ReviewQuestion {
+	var due, fresh []ReviewQuestion
+	for _, module := range Topics {
+		if !studied(progress, module.ID) {
+			continue
+		}
+		for i, question := range quizzes[module.ID].Questions {
+			key := reviewKey(module.ID, i)
+			state, reviewed := progress.Reviews[key]
+			switch {
+			case !reviewed:
+				fresh = append(fresh, ReviewQuestion{Key: key, ModuleID: module.ID, Question: question, New: true})
+			case !state.Due.After(now):
+				due = append(due, ReviewQuestion{Key: key, ModuleID: module.ID, Question: question})
+			}
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return progress.Reviews[due[i].Key].Due.Before(progress.Reviews[due[j].Key].Due)
+	})
+	return append(due, fresh...)
+}
+
+// NextReview returns when the next scheduled question becomes due, or false if none is scheduled
+func NextReview(progress Progress) (time.Time, bool) {
+	var next time.Time
+	for _, state := range progress.Reviews {
+		if next.IsZero() || state.Due.Before(next) {
+			next = state.Due
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// RecordReview schedules a reviewed question in progress, and records its module as completed
+// once every question of the module's quiz was answered correctly
+func RecordReview(progress *Progress, key string, quality int, now time.Time) ReviewState {
+	if progress.Reviews == nil {
+		progress.Reviews = map[string]ReviewState{}
+	}
+	state := Schedule(progress.Reviews[key], quality, now)
+	progress.Reviews[key] = state
+
+	if moduleID, _, ok := strings.Cut(key, "#"); ok && Completed(*progress, moduleID) {
+		if progress.CompletedModules == nil {
+			progress.CompletedModules = map[string]bool{}
+		}
+		progress.CompletedModules[moduleID] = true
+	}
+	return state
+}
+
+// Completed reports whether a module is completed: recorded as such, or with every question of
+// its quiz answered correctly at its last review
+func Completed(progress Progress, moduleID string) bool {
+	if progress.CompletedModules[moduleID] {
+		return true
+	}
+	questions := quizzes[moduleID].Questions
+	if len(questions) == 0 {
+		return false
+	}
+	for i := range questions {
+		if progress.Reviews[reviewKey(moduleID, i)].Repetitions == 0 {
+			return false
+		}
+	}
+	return true
+}
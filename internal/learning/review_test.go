@@ -0,0 +1,131 @@
+package learning
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var reviewNow = time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+func TestScheduleIntervals(t *testing.T) {
+	// Remembered answers follow 1 day, 6 days, then interval times ease
+	state := ReviewState{}
+	wantIntervals := []int{1, 6, 16, 45}
+	for i, want := range wantIntervals {
+		state = Schedule(state, 5, reviewNow)
+		if state.IntervalDays != want {
+			t.Fatalf("review %d: interval = %d, want %d", i+1, state.IntervalDays, want)
+		}
+		if state.Repetitions != i+1 {
+			t.Errorf("review %d: repetitions = %d", i+1, state.Repetitions)
+		}
+	}
+	if !state.Due.Equal(reviewNow.AddDate(0, 0, 45)) {
+		t.Errorf("due = %v", state.Due)
+	}
+}
+
+func TestScheduleEase(t *testing.T) {
+	tests := []struct {
+		quality int
+		want    float64
+	}{
+		{5, 2.6},
+		{4, 2.5},
+		{3, 2.36},
+		{QualityWrong, 1.96},
+	}
+	for _, tt := range tests {
+		got := Schedule(ReviewState{}, tt.quality, reviewNow).Ease
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("quality %d: ease = %v, want %v", tt.quality, got, tt.want)
+		}
+	}
+
+	// Ease never drops below the minimum however often a question is missed
+	state := ReviewState{}
+	for i := 0; i < 10; i++ {
+		state = Schedule(state, 0, reviewNow)
+	}
+	if state.Ease != minEase {
+		t.Errorf("ease after repeated misses = %v, want %v", state.Ease, minEase)
+	}
+}
+
+func TestScheduleForgottenRestarts(t *testing.T) {
+	state := ReviewState{Ease: 2.5, IntervalDays: 15, Repetitions: 3}
+	state = Schedule(state, QualityWrong, reviewNow)
+	if state.IntervalDays != 1 || state.Repetitions != 0 {
+		t.Errorf("forgotten question: interval %d, repetitions %d", state.IntervalDays, state.Repetitions)
+	}
+	if !state.Due.Equal(reviewNow.AddDate(0, 0, 1)) {
+		t.Errorf("due = %v", state.Due)
+	}
+}
+
+func TestDueReviews(t *testing.T) {
+	progress := Progress{
+		StudiedModules: map[string]bool{"flakes": true},
+		QuizScores:     map[string]int{"basics": 50},
+		Reviews: map[string]ReviewState{
+			"basics#0": {Ease: 2.5, IntervalDays: 6, Due: reviewNow.Add(-time.Hour)},
+			"basics#1": {Ease: 2.5, IntervalDays: 6, Due: reviewNow.Add(24 * time.Hour)},
+			"flakes#0": {Ease: 2.5, IntervalDays: 1, Due: reviewNow.Add(-48 * time.Hour)},
+			// Modules that were never studied are not reviewed even when scheduled
+			"advanced#0": {Ease: 2.5, IntervalDays: 1, Due: reviewNow.Add(-time.Hour)},
+		},
+	}
+
+	var keys []string
+	var fresh []bool
+	for _, item := range DueReviews(progress, reviewNow) {
+		keys = append(keys, item.Key)
+		fresh = append(fresh, item.New)
+	}
+	if want := []string{"flakes#0", "basics#0", "flakes#1"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("due keys = %v, want %v", keys, want)
+	}
+	if want := []bool{false, false, true}; !reflect.DeepEqual(fresh, want) {
+		t.Errorf("new flags = %v, want %v", fresh, want)
+	}
+}
+
+func TestNextReview(t *testing.T) {
+	if _, ok := NextReview(Progress{}); ok {
+		t.Error("next review without a schedule")
+	}
+	progress := Progress{}
+	RecordReview(&progress, "basics#0", QualityCorrect, reviewNow)
+	RecordReview(&progress, "basics#1", QualityCorrect, reviewNow.AddDate(0, 0, -1))
+	next, ok := NextReview(progress)
+	if !ok || !next.Equal(reviewNow) {
+		t.Errorf("next review = %v, %v; want %v", next, ok, reviewNow)
+	}
+}
+
+func TestRecordReviewCompletesModule(t *testing.T) {
+	progress := Progress{}
+	questions := len(quizzes["flakes"].Questions)
+	RecordReview(&progress, "flakes#0", QualityWrong, reviewNow)
+	for i := 1; i < questions; i++ {
+		RecordReview(&progress, reviewKey("flakes", i), QualityCorrect, reviewNow)
+	}
+	if Completed(progress, "flakes") || progress.CompletedModules["flakes"] {
+		t.Fatal("completed with a question answered wrongly")
+	}
+
+	RecordReview(&progress, "flakes#0", QualityCorrect, reviewNow)
+	if !progress.CompletedModules["flakes"] {
+		t.Fatal("all questions answered correctly without recording the module as completed")
+	}
+	// A completed module stays completed when a later review is missed
+	RecordReview(&progress, "flakes#1", QualityWrong, reviewNow)
+	if !Completed(progress, "flakes") {
+		t.Error("completion lost after a missed review")
+	}
+	if Completed(progress, "basics") {
+		t.Error("module without reviews completed")
+	}
+}
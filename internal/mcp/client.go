@@ -3,36 +3,150 @@ package mcp
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
+
+	"nix-ai-help/pkg/utils"
+	"nix-ai-help/pkg/version"
 )
 
+// ErrIncompatibleServer is returned when the running MCP server's version does not
+// match the client's major version.
+var ErrIncompatibleServer = errors.New("incompatible MCP server version")
+
+// ErrServerInfoUnavailable is returned when the server does not expose /info,
+// which means it predates version reporting.
+var ErrServerInfoUnavailable = errors.New("MCP server does not report its version")
+
+// strictVersionCheck is the default strictness for new clients, set from the
+// --strict-mcp-version flag.
+var strictVersionCheck bool
+
+// versionWarningHandler receives the version mismatches of lenient clients; nil drops them.
+var versionWarningHandler func(error)
+
+// SetStrictVersionCheck controls whether new clients refuse incompatible servers
+// instead of only warning about them.
+func SetStrictVersionCheck(strict bool) {
+	strictVersionCheck = strict
+}
+
+// SetVersionWarningHandler sets the function new clients pass a version mismatch to when
+// they are not strict and so keep using the server, e.g. to print it as a warning.
+func SetVersionWarningHandler(handler func(error)) {
+	versionWarningHandler = handler
+}
+
 type MCPClient struct {
-	baseURL    string
-	httpClient *http.Client
+	baseURL       string
+	httpClient    *http.Client
+	clientVersion string
+	strict        bool
+	warn          func(error)
+	release       string
+	top           int
+	versionOnce   sync.Once
+	versionErr    error
 }
 
 func NewMCPClient(baseURL string) *MCPClient {
 	return &MCPClient{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		baseURL:       baseURL,
+		httpClient:    utils.NewHTTPClient(10 * time.Second),
+		clientVersion: version.Version,
+		strict:        strictVersionCheck,
+		warn:          versionWarningHandler,
+	}
+}
+
+// SetStrict overrides whether this client refuses incompatible servers.
+func (c *MCPClient) SetStrict(strict bool) {
+	c.strict = strict
+}
+
+// SetRelease makes documentation queries use the options of a nixpkgs release such as "24.05"
+// or "unstable"; an empty release leaves the choice to the server.
+func (c *MCPClient) SetRelease(release string) {
+	c.release = release
+}
+
+// SetTop limits documentation queries to the top n full-text results, in the order the server
+// ranks its sources; 0 returns them all.
+func (c *MCPClient) SetTop(n int) {
+	c.top = n
+}
+
+// IsCompatibleVersion reports whether a server version can be used by a client version.
+// Versions are compatible when they share the same major version number. A version that is
+// not a release number, such as "dev" or a commit hash from a local build, cannot be compared
+// and is not held against the server.
+func IsCompatibleVersion(clientVersion, serverVersion string) bool {
+	clientMajor, clientOK := majorVersion(clientVersion)
+	serverMajor, serverOK := majorVersion(serverVersion)
+	if !clientOK || !serverOK {
+		return true
+	}
+	return clientMajor == serverMajor
+}
+
+// majorVersion extracts the major component from a version such as "v1.2.3".
+func majorVersion(v string) (string, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	major := strings.SplitN(v, ".", 2)[0]
+	if major == "" {
+		return "", false
+	}
+	for _, r := range major {
+		if r < '0' || r > '9' {
+			return "", false
+		}
 	}
+	return major, true
+}
+
+// CheckServerVersion verifies the server version against the client once per client.
+// Unreachable servers are not reported here; the subsequent request will surface that error.
+// A lenient client passes the mismatch to its version warning handler once.
+func (c *MCPClient) CheckServerVersion() error {
+	c.versionOnce.Do(func() {
+		info, err := c.ServerInfo()
+		switch {
+		case errors.Is(err, ErrServerInfoUnavailable):
+			c.versionErr = fmt.Errorf("%w: server predates version reporting (client %s); run 'nixai mcp-server restart'",
+				ErrIncompatibleServer, c.clientVersion)
+		case err != nil:
+			return
+		case !IsCompatibleVersion(c.clientVersion, info.Version):
+			c.versionErr = fmt.Errorf("%w: server %s, client %s; run 'nixai mcp-server restart'",
+				ErrIncompatibleServer, info.Version, c.clientVersion)
+		}
+		if c.versionErr != nil && !c.strict && c.warn != nil {
+			c.warn(c.versionErr)
+		}
+	})
+	return c.versionErr
 }
 
 func (c *MCPClient) QueryDocumentation(query string, sources ...string) (string, error) {
-	var requestBody interface{}
+	if err := c.CheckServerVersion(); err != nil && c.strict {
+		return "", err
+	}
 
+	requestBody := map[string]interface{}{"query": query}
 	if len(sources) > 0 {
-		requestBody = map[string]interface{}{
-			"query":   query,
-			"sources": sources,
-		}
-	} else {
-		requestBody = map[string]string{"query": query}
+		requestBody["sources"] = sources
+	}
+	if c.release != "" {
+		requestBody["release"] = c.release
+	}
+	if c.top > 0 {
+		requestBody["top"] = c.top
 	}
 
 	jsonData, err := json.Marshal(requestBody)
@@ -62,37 +176,113 @@ func (c *MCPClient) QueryDocumentation(query string, sources ...string) (string,
 	return responseBody.Result, nil
 }
 
-// OptionCompletion queries the MCP server for option completions given a prefix.
+// SetTimeout changes the HTTP timeout used for requests to the server.
+func (c *MCPClient) SetTimeout(timeout time.Duration) {
+	c.httpClient.Timeout = timeout
+}
+
+// OptionCompletion queries the MCP server for NixOS option completions given a prefix.
 func (c *MCPClient) OptionCompletion(prefix string) ([]string, error) {
-	requestBody, err := json.Marshal(map[string]interface{}{
-		"method": "tools/call",
-		"params": map[string]interface{}{
-			"name":      "complete_nixos_option",
-			"arguments": map[string]interface{}{"prefix": prefix},
-		},
-	})
+	return c.OptionNames(OptionSourceNixOS, prefix, "")
+}
+
+// OptionNames lists the names of the options of a set (OptionSourceNixOS or
+// OptionSourceHomeManager) that start with prefix and contain contains after it. NixOS options
+// come from the release set with SetRelease.
+func (c *MCPClient) OptionNames(source, prefix, contains string) ([]string, error) {
+	query := url.Values{"source": {source}, "prefix": {prefix}}
+	if contains != "" {
+		query.Set("contains", contains)
+	}
+	if c.release != "" {
+		query.Set("release", c.release)
+	}
+	resp, err := c.httpClient.Get(strings.TrimRight(c.baseURL, "/") + "/options?" + query.Encode())
 	if err != nil {
 		return nil, err
 	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var response struct {
+		Options []string `json:"options"`
+		Error   string   `json:"error"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(body, &response) == nil && response.Error != "" {
+			return nil, fmt.Errorf("HTTP status %d: %s", resp.StatusCode, response.Error)
+		}
+		return nil, fmt.Errorf("HTTP status %d: %s", resp.StatusCode, string(body))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	return response.Options, nil
+}
 
-	resp, err := c.httpClient.Post(c.baseURL, "application/json", bytes.NewBuffer(requestBody))
+// ErrOptionLookupUnavailable is returned by QueryOption when the server has no /option
+// endpoint, which means it predates structured option lookups.
+var ErrOptionLookupUnavailable = errors.New("MCP server does not support option lookups")
+
+// QueryOption looks an option up by its exact name in the release set with SetRelease. It
+// returns nil when the release does not document the option.
+func (c *MCPClient) QueryOption(name string) (*NixOSOption, error) {
+	query := url.Values{"name": {name}}
+	if c.release != "" {
+		query.Set("release", c.release)
+	}
+	resp, err := c.httpClient.Get(strings.TrimRight(c.baseURL, "/") + "/option?" + query.Encode())
 	if err != nil {
 		return nil, err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w; run 'nixai mcp-server restart'", ErrOptionLookupUnavailable)
+	}
+	var response OptionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("HTTP status %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP status %d: %s", resp.StatusCode, response.Error)
+	}
+	return response.Option, nil
+}
+
+// Healthy checks the server's /healthz endpoint, which servers that predate /info also serve.
+func (c *MCPClient) Healthy() error {
+	resp, err := c.httpClient.Get(c.baseURL + "/healthz")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ServerInfo fetches version and runtime information from the server's /info endpoint.
+func (c *MCPClient) ServerInfo() (*ServerInfo, error) {
+	resp, err := c.httpClient.Get(c.baseURL + "/info")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrServerInfoUnavailable
+	}
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("HTTP status %d: %s", resp.StatusCode, string(body))
 	}
 
-	var response struct {
-		Result struct {
-			Options []string `json:"options"`
-		} `json:"result"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+	var info ServerInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
 		return nil, err
 	}
-	return response.Result.Options, nil
+	return &info, nil
 }
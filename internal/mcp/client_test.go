@@ -0,0 +1,281 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nix-ai-help/pkg/utils"
+)
+
+func TestIsCompatibleVersion(t *testing.T) {
+	tests := []struct {
+		client string
+		server string
+		want   bool
+	}{
+		{"1.0.7", "1.0.7", true},
+		{"1.0.7", "1.4.0", true},
+		{"v1.2.0", "1.0.1", true},
+		{"1.0.7", "2.0.0", false},
+		{"2.1.0", "1.9.9", false},
+		// Development builds cannot be compared and are not refused
+		{"1.0.7", "", true},
+		{"1.0.7", "dev", true},
+		{"dev", "2.0.0", true},
+		{"1.0.7", "3f2c1ab", true},
+	}
+
+	for _, tt := range tests {
+		if got := IsCompatibleVersion(tt.client, tt.server); got != tt.want {
+			t.Errorf("IsCompatibleVersion(%q, %q) = %v, want %v", tt.client, tt.server, got, tt.want)
+		}
+	}
+}
+
+func newVersionedTestServer(t *testing.T, serverVersion string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/info":
+			if serverVersion == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(ServerInfo{Status: "ok", Version: serverVersion})
+		case "/query":
+			_ = json.NewEncoder(w).Encode(map[string]string{"result": "docs"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestMCPClient_CompatibleServer(t *testing.T) {
+	ts := newVersionedTestServer(t, "1.3.0")
+	defer ts.Close()
+
+	c := NewMCPClient(ts.URL)
+	c.clientVersion = "1.0.7"
+	c.SetStrict(true)
+
+	if err := c.CheckServerVersion(); err != nil {
+		t.Fatalf("expected compatible server, got %v", err)
+	}
+	result, err := c.QueryDocumentation("nginx")
+	if err != nil {
+		t.Fatalf("expected query to succeed, got %v", err)
+	}
+	if result != "docs" {
+		t.Errorf("expected result 'docs', got %q", result)
+	}
+}
+
+func TestMCPClient_IncompatibleServer(t *testing.T) {
+	ts := newVersionedTestServer(t, "2.0.0")
+	defer ts.Close()
+
+	// Non-strict clients pass the mismatch to the warning handler once and still query.
+	var warnings []error
+	SetVersionWarningHandler(func(err error) { warnings = append(warnings, err) })
+	t.Cleanup(func() { SetVersionWarningHandler(nil) })
+	lenient := NewMCPClient(ts.URL)
+	lenient.clientVersion = "1.0.7"
+	lenient.SetStrict(false)
+	for i := 0; i < 2; i++ {
+		if _, err := lenient.QueryDocumentation("nginx"); err != nil {
+			t.Fatalf("expected non-strict query to succeed, got %v", err)
+		}
+	}
+	if len(warnings) != 1 || !errors.Is(warnings[0], ErrIncompatibleServer) {
+		t.Errorf("warnings = %v, want the version mismatch once", warnings)
+	}
+	if err := lenient.CheckServerVersion(); !errors.Is(err, ErrIncompatibleServer) {
+		t.Errorf("expected ErrIncompatibleServer, got %v", err)
+	}
+
+	// Strict clients refuse.
+	strict := NewMCPClient(ts.URL)
+	strict.clientVersion = "1.0.7"
+	strict.SetStrict(true)
+	if _, err := strict.QueryDocumentation("nginx"); !errors.Is(err, ErrIncompatibleServer) {
+		t.Errorf("expected strict query to fail with ErrIncompatibleServer, got %v", err)
+	}
+}
+
+func TestMCPClient_ServerWithoutInfo(t *testing.T) {
+	ts := newVersionedTestServer(t, "")
+	defer ts.Close()
+
+	c := NewMCPClient(ts.URL)
+	c.SetStrict(true)
+	if err := c.CheckServerVersion(); !errors.Is(err, ErrIncompatibleServer) {
+		t.Errorf("expected ErrIncompatibleServer for server without /info, got %v", err)
+	}
+}
+
+func TestOptionCompletion_UsesOptionsEndpoint(t *testing.T) {
+	original := listOptionNames
+	t.Cleanup(func() { listOptionNames = original })
+	index := map[string][]string{
+		OptionSourceNixOS:       {"networking.firewall.enable", "services.nginx.enable", "services.nginx.package", "services.prometheus.exporters.nginx.enable"},
+		OptionSourceHomeManager: {"programs.git.enable", "programs.zsh.enable"},
+	}
+	listOptionNames = func(source, release, prefix, contains string) ([]string, error) {
+		if release == "20.03" {
+			return nil, errors.New("no option index for release 20.03")
+		}
+		return filterOptionNames(index[source], prefix, contains), nil
+	}
+	s := NewServer("", nil)
+	server := httptest.NewServer(http.HandlerFunc(s.handleOptions))
+	defer server.Close()
+
+	client := NewMCPClient(server.URL)
+	options, err := client.OptionCompletion("services.")
+	if err != nil || strings.Join(options, ",") != "services.nginx.enable,services.nginx.package,services.prometheus.exporters.nginx.enable" {
+		t.Errorf("OptionCompletion(services.) = %v, %v", options, err)
+	}
+	if options, err := client.OptionNames(OptionSourceHomeManager, "programs.", ""); err != nil || len(options) != 2 {
+		t.Errorf("Home Manager options = %v, %v; want the programs.* options", options, err)
+	}
+	if options, err := client.OptionNames(OptionSourceNixOS, "services.", "NGINX"); err != nil || len(options) != 3 {
+		t.Errorf("options containing nginx = %v, %v", options, err)
+	}
+	client.SetRelease("20.03")
+	if _, err := client.OptionCompletion("services."); err == nil || !strings.Contains(err.Error(), "no option index for release 20.03") {
+		t.Errorf("expected the index error, got %v", err)
+	}
+}
+
+func TestFilterOptionNames(t *testing.T) {
+	names := []string{"services.nginx.enable", "services.nginxAuth.enable", "services.openssh.enable", "services.xserver.displayManager.sddm.enable"}
+	got := filterOptionNames(names, "services.", "nginx")
+	if strings.Join(got, ",") != "services.nginx.enable,services.nginxAuth.enable" {
+		t.Errorf("contains nginx: got %v", got)
+	}
+	if got := filterOptionNames(names, "services.xserver.displaymanager", ""); len(got) != 1 {
+		t.Errorf("prefixes match regardless of case, got %v", got)
+	}
+}
+
+func TestMCPClientUsesConfiguredProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.Host)
+		_ = json.NewEncoder(w).Encode(ServerInfo{Status: "ok", Version: "1.0.7"})
+	}))
+	defer proxy.Close()
+	if err := utils.SetHTTPProxy(proxy.URL); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = utils.SetHTTPProxy("") }()
+
+	info, err := NewMCPClient("http://mcp.example.test:8081").ServerInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != "1.0.7" || len(proxied) != 1 || proxied[0] != "mcp.example.test:8081" {
+		t.Errorf("expected the request to go through the proxy, got %+v via %v", info, proxied)
+	}
+}
+
+func TestMCPClient_SendsRelease(t *testing.T) {
+	var bodies []map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/query" {
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			bodies = append(bodies, body)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"result": "docs"})
+	}))
+	defer ts.Close()
+
+	c := NewMCPClient(ts.URL)
+	if _, err := c.QueryDocumentation("services.nginx.enable"); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	c.SetRelease("24.05")
+	if _, err := c.QueryDocumentation("services.nginx.enable", "nixos-options-es://"); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("expected 2 queries, got %d", len(bodies))
+	}
+	if _, ok := bodies[0]["release"]; ok {
+		t.Errorf("expected no release before SetRelease, got %v", bodies[0])
+	}
+	if bodies[1]["release"] != "24.05" || bodies[1]["sources"] == nil {
+		t.Errorf("expected release 24.05 and the sources, got %v", bodies[1])
+	}
+}
+
+func TestQueryOption_UsesOptionEndpoint(t *testing.T) {
+	original := fetchReleaseOption
+	t.Cleanup(func() { fetchReleaseOption = original })
+	fetchReleaseOption = func(name, release string) (*NixOSOption, error) {
+		switch {
+		case release == "20.03":
+			return nil, errors.New("no option index for release 20.03")
+		case name == "services.nginx.enable" && release == "24.05":
+			return &NixOSOption{Name: name, OptionType: "boolean", Default: "false"}, nil
+		}
+		return nil, nil
+	}
+	s := NewServer("", nil)
+	server := httptest.NewServer(http.HandlerFunc(s.handleOption))
+	defer server.Close()
+
+	client := NewMCPClient(server.URL)
+	client.SetRelease("24.05")
+	opt, err := client.QueryOption("services.nginx.enable")
+	if err != nil || opt == nil || opt.OptionType != "boolean" || opt.Default != "false" {
+		t.Fatalf("QueryOption = %+v, %v; want the documented option", opt, err)
+	}
+	if opt, err := client.QueryOption("services.missing.enable"); opt != nil || err != nil {
+		t.Errorf("undocumented option: got %+v, %v; want nil, nil", opt, err)
+	}
+
+	client.SetRelease("20.03")
+	if _, err := client.QueryOption("services.nginx.enable"); err == nil || !strings.Contains(err.Error(), "no option index for release 20.03") {
+		t.Errorf("expected the server's lookup error, got %v", err)
+	}
+}
+
+func TestQueryOption_OldServer(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	if _, err := NewMCPClient(server.URL).QueryOption("services.nginx.enable"); !errors.Is(err, ErrOptionLookupUnavailable) {
+		t.Errorf("expected ErrOptionLookupUnavailable, got %v", err)
+	}
+}
+
+func TestMCPClientHealthyWithoutInfo(t *testing.T) {
+	// A server from before /info only answers /healthz
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok", "uptime": "2024-03-01T09:00:00Z"})
+	}))
+	defer ts.Close()
+
+	client := NewMCPClient(ts.URL)
+	if _, err := client.ServerInfo(); !errors.Is(err, ErrServerInfoUnavailable) {
+		t.Fatalf("ServerInfo error = %v, want ErrServerInfoUnavailable", err)
+	}
+	if err := client.Healthy(); err != nil {
+		t.Errorf("Healthy() = %v, want the /healthz answer to count", err)
+	}
+
+	ts.Close()
+	if err := client.Healthy(); err == nil {
+		t.Error("Healthy() succeeded against a stopped server")
+	}
+}
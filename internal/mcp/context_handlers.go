@@ -20,7 +20,7 @@ func (m *MCPServer) handleGetContext(format string, detailed bool) string {
 	contextDetector := nixos.NewContextDetector(&m.logger)
 
 	// Get context (will use cache if valid)
-	nixosCtx, err := contextDetector.GetContext(cfg)
+	nixosCtx, err := contextDetector.GetContextEvenIfDisabled(cfg)
 	if err != nil {
 		return fmt.Sprintf("❌ Failed to get context: %v", err)
 	}
@@ -47,7 +47,7 @@ func (m *MCPServer) handleDetectContext(verbose bool) string {
 	}
 
 	// Get fresh context (will detect since cache was cleared)
-	nixosCtx, err := contextDetector.GetContext(cfg)
+	nixosCtx, err := contextDetector.GetContextEvenIfDisabled(cfg)
 	if err != nil {
 		return fmt.Sprintf("❌ Context detection failed: %v", err)
 	}
@@ -74,7 +74,7 @@ func (m *MCPServer) handleResetContext(confirm bool) string {
 	}
 
 	// Get fresh context
-	nixosCtx, err := contextDetector.GetContext(cfg)
+	nixosCtx, err := contextDetector.GetContextEvenIfDisabled(cfg)
 	if err != nil {
 		return fmt.Sprintf("❌ Context re-detection failed: %v", err)
 	}
@@ -106,7 +106,7 @@ func (m *MCPServer) handleContextStatus(includeMetrics bool) string {
 
 	// Try to get current context to check health
 	startTime := time.Now()
-	nixosCtx, err := contextDetector.GetContext(cfg)
+	nixosCtx, err := contextDetector.GetContextEvenIfDisabled(cfg)
 	detectionTime := time.Since(startTime)
 
 	// Check for issues
@@ -185,7 +185,7 @@ func (m *MCPServer) handleContextDiff() string {
 	contextDetector := nixos.NewContextDetector(&m.logger)
 
 	// Get current context
-	currentCtx, err := contextDetector.GetContext(cfg)
+	currentCtx, err := contextDetector.GetContextEvenIfDisabled(cfg)
 	if err != nil {
 		return fmt.Sprintf("❌ Failed to get current context: %v", err)
 	}
@@ -220,7 +220,7 @@ func (m *MCPServer) validateContextSystem(cfg *config.UserConfig) []string {
 	contextDetector := nixos.NewContextDetector(&m.logger)
 
 	// Try a quick context check
-	_, err := contextDetector.GetContext(cfg)
+	_, err := contextDetector.GetContextEvenIfDisabled(cfg)
 	if err != nil {
 		issues = append(issues, fmt.Sprintf("Context detection error: %v", err))
 	}
@@ -0,0 +1,132 @@
+package mcp
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// excerptSimilarityThreshold is the shingle overlap above which two paragraphs count as the
+// same text, e.g. a wiki section quoted by the manual with different formatting
+const excerptSimilarityThreshold = 0.8
+
+// shingleSize is the number of words in a shingle
+const shingleSize = 3
+
+// minDedupWords keeps short paragraphs such as headings, which repeat across sources without
+// duplicating their content
+const minDedupWords = 6
+
+// nonWordPattern matches the punctuation and markup ignored when comparing excerpts
+var nonWordPattern = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// paragraphSeparator separates the paragraphs of an excerpt
+var paragraphSeparator = regexp.MustCompile(`\n\s*\n`)
+
+// docExcerpt is the text a documentation source returned for a query
+type docExcerpt struct {
+	source string
+	text   string
+}
+
+// dedupeExcerpts drops the paragraphs that repeat one already kept from an earlier excerpt,
+// so that overlapping sources do not fill the context window with the same text. Excerpts
+// are in priority order, so the highest-priority source keeps its version. Excerpts left
+// without paragraphs are dropped.
+func dedupeExcerpts(excerpts []docExcerpt) []docExcerpt {
+	seen := make(map[string]bool)
+	var kept [][]string // shingles of the kept paragraphs
+	var result []docExcerpt
+	for _, excerpt := range excerpts {
+		var paragraphs []string
+		for _, paragraph := range paragraphSeparator.Split(excerpt.text, -1) {
+			if strings.TrimSpace(paragraph) == "" {
+				continue
+			}
+			words := strings.Fields(normalizeExcerpt(paragraph))
+			if len(words) < minDedupWords {
+				paragraphs = append(paragraphs, paragraph)
+				continue
+			}
+			key := strings.Join(words, " ")
+			if seen[key] {
+				continue
+			}
+			paragraphShingles := shingles(words)
+			if similarToAny(paragraphShingles, kept) {
+				continue
+			}
+			seen[key] = true
+			kept = append(kept, paragraphShingles)
+			paragraphs = append(paragraphs, paragraph)
+		}
+		if len(paragraphs) > 0 {
+			result = append(result, docExcerpt{source: excerpt.source, text: strings.Join(paragraphs, "\n\n")})
+		}
+	}
+	return result
+}
+
+// DedupeTexts is dedupeExcerpts for texts gathered by separate queries, such as the results of
+// several QueryDocumentation calls. The texts keep their order; a text that only repeats
+// earlier ones is returned as "".
+func DedupeTexts(texts []string) []string {
+	excerpts := make([]docExcerpt, len(texts))
+	for i, text := range texts {
+		excerpts[i] = docExcerpt{source: strconv.Itoa(i), text: text}
+	}
+	result := make([]string, len(texts))
+	for _, excerpt := range dedupeExcerpts(excerpts) {
+		i, _ := strconv.Atoi(excerpt.source)
+		result[i] = excerpt.text
+	}
+	return result
+}
+
+// normalizeExcerpt lowercases text and reduces it to its words
+func normalizeExcerpt(text string) string {
+	return strings.TrimSpace(nonWordPattern.ReplaceAllString(strings.ToLower(text), " "))
+}
+
+// shingles returns the distinct word n-grams of a paragraph, sorted by first appearance
+func shingles(words []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for i := 0; i+shingleSize <= len(words); i++ {
+		shingle := strings.Join(words[i:i+shingleSize], " ")
+		if !seen[shingle] {
+			seen[shingle] = true
+			result = append(result, shingle)
+		}
+	}
+	return result
+}
+
+// similarToAny reports whether a paragraph's shingles overlap those of a kept paragraph by at
+// least excerptSimilarityThreshold
+func similarToAny(paragraph []string, kept [][]string) bool {
+	for _, other := range kept {
+		if jaccard(paragraph, other) >= excerptSimilarityThreshold {
+			return true
+		}
+	}
+	return false
+}
+
+// jaccard returns the Jaccard similarity of two sets of distinct shingles
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	inA := make(map[string]bool, len(a))
+	for _, shingle := range a {
+		inA[shingle] = true
+	}
+	shared := 0
+	for _, shingle := range b {
+		if inA[shingle] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
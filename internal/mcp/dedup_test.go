@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+)
+
+const nginxParagraph = "To enable the nginx web server, set services.nginx.enable to true and add a virtual host for each site."
+
+func TestDedupeExcerptsKeepsHighestPrioritySource(t *testing.T) {
+	excerpts := []docExcerpt{
+		{source: "wiki", text: nginxParagraph + "\n\nThe wiki also covers ACME certificates for each virtual host you configure."},
+		{source: "manual", text: "**To enable the Nginx web server**, set `services.nginx.enable` to true and add a virtual host for each site!"},
+		{source: "nix.dev", text: "Use nix-shell -p nginx to try nginx without installing it system wide."},
+	}
+
+	got := dedupeExcerpts(excerpts)
+
+	if len(got) != 2 || got[0].source != "wiki" || got[1].source != "nix.dev" {
+		t.Fatalf("sources = %+v, want wiki and nix.dev", got)
+	}
+	if got[0].text != excerpts[0].text {
+		t.Errorf("the wiki excerpt changed: %q", got[0].text)
+	}
+}
+
+func TestDedupeExcerptsDropsNearDuplicateParagraphs(t *testing.T) {
+	excerpts := []docExcerpt{
+		{source: "wiki", text: nginxParagraph},
+		{source: "manual", text: "## Nginx\n\n" + strings.Replace(nginxParagraph, "each site", "each website", 1) +
+			"\n\nReload the service with systemctl reload nginx after changing the configuration."},
+	}
+
+	got := dedupeExcerpts(excerpts)
+
+	if len(got) != 2 {
+		t.Fatalf("got %d excerpts, want 2", len(got))
+	}
+	want := "## Nginx\n\nReload the service with systemctl reload nginx after changing the configuration."
+	if got[1].text != want {
+		t.Errorf("manual excerpt = %q, want %q", got[1].text, want)
+	}
+}
+
+func TestDedupeExcerptsKeepsDistinctContent(t *testing.T) {
+	excerpts := []docExcerpt{
+		{source: "wiki", text: nginxParagraph},
+		{source: "manual", text: "To enable the Caddy web server, set services.caddy.enable to true and point it at a Caddyfile for your sites."},
+		{source: "nix.dev", text: "Short heading"},
+		{source: "other", text: "Short heading"},
+	}
+
+	if got := dedupeExcerpts(excerpts); len(got) != len(excerpts) {
+		t.Errorf("got %d excerpts, want all %d: %+v", len(got), len(excerpts), got)
+	}
+}
+
+func TestDedupeTexts(t *testing.T) {
+	texts := []string{
+		nginxParagraph,
+		"**To enable the Nginx web server**, set `services.nginx.enable` to true and add a virtual host for each site!",
+		nginxParagraph + "\n\nReload the service with systemctl reload nginx after changing the configuration.",
+	}
+
+	got := DedupeTexts(texts)
+
+	want := []string{nginxParagraph, "", "Reload the service with systemctl reload nginx after changing the configuration."}
+	if len(got) != len(want) {
+		t.Fatalf("got %d texts, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("text %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestJaccard(t *testing.T) {
+	words := strings.Fields(normalizeExcerpt(nginxParagraph))
+	if got := jaccard(shingles(words), shingles(words)); got != 1 {
+		t.Errorf("jaccard of identical shingles = %v, want 1", got)
+	}
+	if got := jaccard(shingles(words), nil); got != 0 {
+		t.Errorf("jaccard with no shingles = %v, want 0", got)
+	}
+}
@@ -0,0 +1,139 @@
+package mcp
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/utils"
+)
+
+// maxManualPageSize bounds how much of a manual page is read; the nixpkgs manual is one page of
+// a few megabytes
+const maxManualPageSize = 16 << 20
+
+// docFetcher fetches the documentation for a query from a source of a type
+type docFetcher func(src, sourceType, query string) (string, error)
+
+// optionFetcher fetches the option documentation for a query from a source, for the nixpkgs
+// release the user runs ("" for unstable)
+type optionFetcher func(src, query, release string) (string, error)
+
+var (
+	// fetchOptionSource queries an option index for option JSON; replaced in tests
+	fetchOptionSource optionFetcher = fetchOptionDoc
+	// fetchFullTextSource searches a wiki or manual by full text; replaced in tests
+	fetchFullTextSource docFetcher = fetchFullTextDoc
+)
+
+// fetchFullTextDoc searches a source the way its type is searched: a wiki through the MediaWiki
+// search API of its host, a manual by the pages that mention the query
+func fetchFullTextDoc(src, sourceType, query string) (string, error) {
+	switch sourceType {
+	case config.SourceTypeWiki:
+		return fetchMediaWikiContent(src, query)
+	case config.SourceTypeManual:
+		// nix.dev is built with MyST, whose pages and search index are found by path
+		if strings.Contains(src, "nix.dev") {
+			return fetchMySTContent(src, query)
+		}
+		return fetchManualPage(src, query)
+	}
+	return fetchDocSource(src, query)
+}
+
+// scriptPattern matches the scripts and styles of an HTML page, which are not its text
+var scriptPattern = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+
+// fetchManualPage returns the passage of a manual page such as the nixpkgs manual, which is one
+// HTML page, that mentions the query. It is empty when the page does not mention it.
+func fetchManualPage(src, query string) (string, error) {
+	if query == "" {
+		return "", fmt.Errorf("query term required for manual search")
+	}
+	// #nosec G107 -- src is from trusted config/documentation sources only
+	resp, err := utils.NewHTTPClient(10 * time.Second).Get(src)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch %s: %s", src, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManualPageSize))
+	if err != nil {
+		return "", err
+	}
+
+	text := strings.Join(strings.Fields(html.UnescapeString(stripHTMLTags(scriptPattern.ReplaceAllString(string(body), " ")))), " ")
+	if !strings.Contains(strings.ToLower(text), strings.ToLower(query)) {
+		return "", nil
+	}
+	return extractRelevantSnippet(text, query), nil
+}
+
+// releasePattern matches the nixpkgs releases that have a NixOS option search index
+var releasePattern = regexp.MustCompile(`^(\d{2}\.\d{2}|unstable)$`)
+
+// nixosOptionsIndex returns the option search index of a nixpkgs release such as "24.05";
+// unknown releases use the unstable index
+func nixosOptionsIndex(release string) string {
+	index, err := releaseOptionsIndex(release)
+	if err != nil {
+		index, _ = releaseOptionsIndex("")
+	}
+	return index
+}
+
+// releaseOptionsIndex returns the option search index of a nixpkgs release such as "24.05", or
+// of unstable for "". Unlike nixosOptionsIndex it never substitutes another release.
+func releaseOptionsIndex(release string) (string, error) {
+	if release == "" {
+		release = "unstable"
+	}
+	if !releasePattern.MatchString(release) {
+		return "", fmt.Errorf("no option index for release %s", release)
+	}
+	return ElasticSearchIndexPrefix + "nixos-" + release, nil
+}
+
+// fetchOptionDoc queries the Home Manager option list or the NixOS option search. The NixOS
+// option search of a release falls back to unstable when the release has no index, which
+// suits documentation queries; lookups that compare releases use fetchNixOSOption instead.
+func fetchOptionDoc(src, query, release string) (string, error) {
+	if strings.HasSuffix(src, "/options.json") {
+		return fetchHomeManagerOptionsAPI(src, query)
+	}
+	result, err := fetchNixOSOptionsAPI(src, query, release)
+	if err != nil && nixosOptionsIndex(release) != nixosOptionsIndex("") {
+		return fetchNixOSOptionsAPI(src, query, "")
+	}
+	return result, err
+}
+
+// sourceTypeOf returns the type declared for a source in the config of the running server, or
+// the type inferred from its URL
+func sourceTypeOf(src string) string {
+	if server := globalServerInstance; server != nil {
+		if sourceType, ok := server.sourceTypes[src]; ok {
+			return sourceType
+		}
+	}
+	return config.InferSourceType(src)
+}
+
+// orderSourcesByType returns the sources with option indexes first, keeping the configured
+// order otherwise
+func orderSourcesByType(sources []string) []string {
+	ordered := append([]string(nil), sources...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return sourceTypeOf(ordered[i]) == config.SourceTypeOptions && sourceTypeOf(ordered[j]) != config.SourceTypeOptions
+	})
+	return ordered
+}
@@ -0,0 +1,200 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/config"
+)
+
+// stubDocFetchers records which fetcher is used for each source
+func stubDocFetchers(t *testing.T, optionResult string) *[]string {
+	t.Helper()
+	var calls []string
+	originalOption, originalFullText := fetchOptionSource, fetchFullTextSource
+	fetchOptionSource = func(src, query, release string) (string, error) {
+		calls = append(calls, "options:"+src)
+		return optionResult, nil
+	}
+	fetchFullTextSource = func(src, sourceType, query string) (string, error) {
+		calls = append(calls, sourceType+":"+src)
+		return "text about " + query, nil
+	}
+	t.Cleanup(func() { fetchOptionSource, fetchFullTextSource = originalOption, originalFullText })
+	return &calls
+}
+
+// stubServerSources installs a running server with declared source types
+func stubServerSources(t *testing.T, sources []config.DocumentationSource) {
+	t.Helper()
+	original := globalServerInstance
+	globalServerInstance = &Server{
+		documentationSources: config.SourceURLs(sources),
+		sourceTypes:          config.SourceTypes(sources),
+	}
+	t.Cleanup(func() { globalServerInstance = original })
+}
+
+func TestHandleDocQueryRoutesBySourceType(t *testing.T) {
+	stubServerSources(t, []config.DocumentationSource{
+		{URL: "https://wiki.nixos.org/wiki/NixOS_Wiki"},
+		{URL: "https://example.org/manual", Type: config.SourceTypeManual},
+		{URL: "https://example.org/option-search", Type: config.SourceTypeOptions},
+	})
+	calls := stubDocFetchers(t, "No documentation found")
+
+	var m *MCPServer
+	result := m.handleDocQuery("services.nginx.enable")
+
+	want := []string{
+		"options:https://example.org/option-search",
+		"wiki:https://wiki.nixos.org/wiki/NixOS_Wiki",
+		"manual:https://example.org/manual",
+	}
+	if !reflect.DeepEqual(*calls, want) {
+		t.Errorf("calls = %v, want %v", *calls, want)
+	}
+	if !strings.Contains(result, "https://example.org/manual: text about services.nginx.enable") {
+		t.Errorf("expected combined full-text results, got %q", result)
+	}
+}
+
+func TestHandleDocQueryReturnsOptionJSONFirst(t *testing.T) {
+	stubServerSources(t, nil)
+	calls := stubDocFetchers(t, `{"option_name": "services.nginx.enable", "option_type": "boolean"}`)
+
+	var m *MCPServer
+	result := m.handleDocQuery("services.nginx.enable", "https://nix.dev/manual/nix", "nixos-options-es://options")
+
+	if want := []string{"options:nixos-options-es://options"}; !reflect.DeepEqual(*calls, want) {
+		t.Errorf("calls = %v, want %v", *calls, want)
+	}
+	if !strings.Contains(result, `"option_type": "boolean"`) {
+		t.Errorf("expected the option JSON, got %q", result)
+	}
+}
+
+func TestHandleReleaseDocQueryUsesRelease(t *testing.T) {
+	stubServerSources(t, nil)
+	var releases []string
+	original := fetchOptionSource
+	fetchOptionSource = func(src, query, release string) (string, error) {
+		releases = append(releases, release)
+		return `{"option_name": "services.nginx.enable"}`, nil
+	}
+	t.Cleanup(func() { fetchOptionSource = original })
+
+	var m *MCPServer
+	m.handleReleaseDocQuery("services.nginx.enable", "24.05", "nixos-options-es://options")
+	m.handleDocQuery("services.nginx.enable", "nixos-options-es://options")
+
+	if want := []string{"24.05", ""}; !reflect.DeepEqual(releases, want) {
+		t.Errorf("releases = %v, want %v", releases, want)
+	}
+}
+
+func TestNixOSOptionsIndex(t *testing.T) {
+	tests := map[string]string{
+		"24.05":         ElasticSearchIndexPrefix + "nixos-24.05",
+		"unstable":      ElasticSearchIndexPrefix + "nixos-unstable",
+		"":              ElasticSearchIndexPrefix + "nixos-unstable",
+		"24.05/_search": ElasticSearchIndexPrefix + "nixos-unstable",
+	}
+	for release, want := range tests {
+		if got := nixosOptionsIndex(release); got != want {
+			t.Errorf("nixosOptionsIndex(%q) = %q, want %q", release, got, want)
+		}
+	}
+}
+
+func TestReleaseOptionsIndexIsStrict(t *testing.T) {
+	if index, err := releaseOptionsIndex("23.11"); err != nil || index != ElasticSearchIndexPrefix+"nixos-23.11" {
+		t.Errorf("releaseOptionsIndex(23.11) = %q, %v", index, err)
+	}
+	if index, err := releaseOptionsIndex(""); err != nil || index != ElasticSearchIndexPrefix+"nixos-unstable" {
+		t.Errorf("releaseOptionsIndex(\"\") = %q, %v", index, err)
+	}
+	// A release without an index fails instead of quietly using unstable
+	if _, err := fetchNixOSOption("services.nginx.enable", "nixos-23.11"); err == nil ||
+		!strings.Contains(err.Error(), "no option index for release nixos-23.11") {
+		t.Errorf("expected no option index error, got %v", err)
+	}
+}
+
+func TestHandleDocQueryDeduplicatesSources(t *testing.T) {
+	stubServerSources(t, nil)
+	stubDocFetchers(t, "No documentation found")
+	fetchFullTextSource = func(src, sourceType, query string) (string, error) {
+		if strings.Contains(src, "nix.dev") {
+			return "Set services.nginx.enable = true; to run the nginx web server with its default configuration.", nil
+		}
+		return "Set `services.nginx.enable = true;` to run the Nginx web server with its default configuration.", nil
+	}
+
+	var m *MCPServer
+	result := m.handleDocQuery("services.nginx.enable", "https://wiki.nixos.org/wiki/NixOS_Wiki", "https://nix.dev/manual/nix")
+
+	if !strings.Contains(result, "https://wiki.nixos.org/wiki/NixOS_Wiki: Set `services.nginx.enable") {
+		t.Errorf("expected the wiki excerpt, got %q", result)
+	}
+	if strings.Contains(result, "https://nix.dev/manual/nix:") {
+		t.Errorf("expected the duplicate nix.dev excerpt to be dropped, got %q", result)
+	}
+}
+
+func TestHandleTopDocQueryKeepsTheTopResults(t *testing.T) {
+	stubServerSources(t, nil)
+	stubDocFetchers(t, "No documentation found")
+	fetchFullTextSource = func(src, sourceType, query string) (string, error) {
+		return "The " + src + " page explains how flakes pin their inputs in a lock file.", nil
+	}
+	sources := []string{"https://wiki.nixos.org/wiki/NixOS_Wiki", "https://nix.dev/manual/nix", "https://example.org/manual"}
+
+	var m *MCPServer
+	result := m.handleTopDocQuery("flakes", "", 2, sources...)
+	for i, src := range sources {
+		if got, want := strings.Contains(result, src+": "), i < 2; got != want {
+			t.Errorf("result has %s: %v, want %v\n%s", src, got, want, result)
+		}
+	}
+	if all := m.handleTopDocQuery("flakes", "", 0, sources...); !strings.Contains(all, "https://example.org/manual: ") {
+		t.Errorf("top 0 should return every result, got %q", all)
+	}
+}
+
+func TestFetchFullTextDocByType(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/w/api.php":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"query": map[string]interface{}{"search": []map[string]interface{}{
+				{"title": "Nginx", "snippet": `Enable <span class="searchmatch">nginx</span> with services.nginx.enable`},
+			}}})
+		case "/manual/":
+			_, _ = w.Write([]byte(`<html><head><style>.nginx { color: red }</style></head><body>` +
+				`<h2>Web servers</h2><p>Set <code>services.nginx.enable</code> to run nginx.</p></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	// Any host declared as a wiki is searched through its MediaWiki API
+	wiki, err := fetchFullTextDoc(ts.URL+"/wiki/Main_Page", config.SourceTypeWiki, "nginx")
+	if err != nil || !strings.Contains(wiki, "Title: Nginx") || !strings.Contains(wiki, "Enable nginx with") {
+		t.Errorf("wiki search = %q, %v", wiki, err)
+	}
+
+	// A manual answers with the text around the query, without markup or styles
+	manual, err := fetchFullTextDoc(ts.URL+"/manual/", config.SourceTypeManual, "services.nginx.enable")
+	if err != nil || !strings.Contains(manual, "Set services.nginx.enable to run nginx.") || strings.Contains(manual, "<") ||
+		strings.Contains(manual, "color") {
+		t.Errorf("manual search = %q, %v", manual, err)
+	}
+	if manual, err := fetchFullTextDoc(ts.URL+"/manual/", config.SourceTypeManual, "services.caddy.enable"); err != nil || manual != "" {
+		t.Errorf("manual without the query = %q, %v; want no excerpt", manual, err)
+	}
+}
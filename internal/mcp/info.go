@@ -0,0 +1,51 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"nix-ai-help/pkg/version"
+)
+
+// ServerInfo describes a running MCP server so clients can verify compatibility.
+type ServerInfo struct {
+	Status               string `json:"status"`
+	Version              string `json:"version"`
+	GitCommit            string `json:"git_commit"`
+	StartedAt            string `json:"started_at,omitempty"`
+	UptimeSeconds        int64  `json:"uptime_seconds"`
+	DocumentationSources int    `json:"documentation_sources"`
+	CacheEntries         int    `json:"cache_entries"`
+	AuthEnabled          bool   `json:"auth_enabled"`
+}
+
+// Info returns the current server version and runtime information.
+func (s *Server) Info() ServerInfo {
+	info := ServerInfo{
+		Status:               "ok",
+		Version:              version.Version,
+		GitCommit:            version.GitCommit,
+		DocumentationSources: len(s.documentationSources),
+		// The HTTP endpoints do not support authentication yet.
+		AuthEnabled: false,
+	}
+
+	if !startTime.IsZero() {
+		info.StartedAt = startTime.Format(time.RFC3339)
+		info.UptimeSeconds = int64(time.Since(startTime).Seconds())
+	}
+
+	cacheMutex.RLock()
+	info.CacheEntries = len(cache)
+	cacheMutex.RUnlock()
+
+	return info
+}
+
+// handleInfo serves the server info payload for /healthz and /info.
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(s.Info())
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"nix-ai-help/pkg/logger"
+	"nix-ai-help/pkg/utils"
 )
 
 // LSPPosition represents a position in a text document
@@ -402,7 +403,7 @@ func (nlsp *NixLSPProvider) checkSyntax(filePath, content string) ([]LSPDiagnost
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, "nix-instantiate", "--parse", "--strict", tmpFile.Name())
-	output, err := cmd.CombinedOutput()
+	output, err := utils.TraceCombinedOutput(cmd)
 
 	var diagnostics []LSPDiagnostic
 	if err != nil {
@@ -0,0 +1,193 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nix-ai-help/pkg/utils"
+)
+
+// Option sets the /options endpoint lists
+const (
+	OptionSourceNixOS       = "nixos"
+	OptionSourceHomeManager = "home-manager"
+)
+
+const (
+	// maxOptionNames bounds the names /options returns, the most an ElasticSearch query yields
+	maxOptionNames = 10000
+	// homeManagerOptionsTTL is how long the downloaded Home Manager option list is used
+	homeManagerOptionsTTL = 24 * time.Hour
+	// defaultHomeManagerOptionsURL is the Home Manager option list used when no source lists one
+	defaultHomeManagerOptionsURL = "https://home-manager-options.extranix.com/options.json"
+)
+
+// listOptionNames returns the names of the options of a set that start with prefix and contain
+// contains after it, for the nixpkgs release of the NixOS options; replaced in tests
+var listOptionNames = func(source, release, prefix, contains string) ([]string, error) {
+	if source == OptionSourceHomeManager {
+		names, err := homeManagerOptions.names(homeManagerOptionsURL())
+		if err != nil {
+			return nil, err
+		}
+		return filterOptionNames(names, prefix, contains), nil
+	}
+	return nixosOptionNames(release, prefix, contains)
+}
+
+// filterOptionNames returns the sorted names starting with prefix that contain contains after
+// it, ignoring case, at most maxOptionNames of them
+func filterOptionNames(names []string, prefix, contains string) []string {
+	prefix, contains = strings.ToLower(prefix), strings.ToLower(contains)
+	var matches []string
+	for _, name := range names {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, prefix) && strings.Contains(lower[len(prefix):], contains) {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	if len(matches) > maxOptionNames {
+		matches = matches[:maxOptionNames]
+	}
+	return matches
+}
+
+// nixosOptionNames searches the NixOS option index of a release for option names by pattern
+func nixosOptionNames(release, prefix, contains string) ([]string, error) {
+	index, err := releaseOptionsIndex(release)
+	if err != nil {
+		return nil, err
+	}
+	// The pattern is matched against the whole name; wildcards typed by the user are dropped
+	literal := strings.NewReplacer("*", "", "?", "")
+	pattern := strings.ToLower(literal.Replace(prefix)) + "*"
+	if contains != "" {
+		pattern += strings.ToLower(literal.Replace(contains)) + "*"
+	}
+	opts, err := queryNixOSOptions(index, map[string]interface{}{
+		"size":    maxOptionNames,
+		"_source": []string{"option_name"},
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": []interface{}{
+					map[string]interface{}{"match": map[string]interface{}{"type": "option"}},
+					map[string]interface{}{"wildcard": map[string]interface{}{"option_name": map[string]interface{}{"value": pattern}}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(opts))
+	for _, opt := range opts {
+		names = append(names, opt.Name)
+	}
+	return filterOptionNames(names, prefix, contains), nil
+}
+
+// homeManagerOptionsURL returns the Home Manager option list among the documentation sources of
+// the running server, or the default one
+func homeManagerOptionsURL() string {
+	if server := findServerInstance(); server != nil {
+		for _, src := range server.documentationSources {
+			if strings.HasSuffix(src, "/options.json") {
+				return src
+			}
+		}
+	}
+	return defaultHomeManagerOptionsURL
+}
+
+// optionNameList is a downloaded option list, refreshed after homeManagerOptionsTTL
+type optionNameList struct {
+	mu       sync.Mutex
+	url      string
+	list     []string
+	loadedAt time.Time
+}
+
+// homeManagerOptions holds the Home Manager option names
+var homeManagerOptions optionNameList
+
+// names returns the option names listed at url, downloading them when missing or stale. A stale
+// list is still used when the download fails.
+func (l *optionNameList) names(url string) ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.url == url && len(l.list) > 0 && time.Since(l.loadedAt) < homeManagerOptionsTTL {
+		return l.list, nil
+	}
+
+	list, err := fetchOptionNameList(url)
+	if err != nil {
+		if l.url == url && len(l.list) > 0 {
+			return l.list, nil
+		}
+		return nil, err
+	}
+	l.url, l.list, l.loadedAt = url, list, time.Now()
+	return list, nil
+}
+
+// fetchOptionNameList downloads an option list such as the Home Manager options.json
+func fetchOptionNameList(url string) ([]string, error) {
+	resp, err := utils.NewHTTPClient(30 * time.Second).Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+
+	var result struct {
+		Options []struct {
+			Name string `json:"name"`
+		} `json:"options"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", url, err)
+	}
+	names := make([]string, 0, len(result.Options))
+	for _, opt := range result.Options {
+		if opt.Name != "" {
+			names = append(names, opt.Name)
+		}
+	}
+	return names, nil
+}
+
+// handleOptions serves the names of the options starting with the "prefix" query parameter,
+// optionally containing "contains" after it. "source" selects the NixOS (default) or Home
+// Manager options and "release" the nixpkgs release of the NixOS options.
+func (s *Server) handleOptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+	source := query.Get("source")
+	if source == "" {
+		source = OptionSourceNixOS
+	}
+	if source != OptionSourceNixOS && source != OptionSourceHomeManager {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "unknown option source " + source})
+		return
+	}
+
+	names, err := listOptionNames(source, query.Get("release"), query.Get("prefix"), query.Get("contains"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if names == nil {
+		names = []string{}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"options": names})
+}
@@ -0,0 +1,74 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// OptionResponse is the /option payload: the option looked up, nil when the release does not
+// document it, or the reason it could not be looked up
+type OptionResponse struct {
+	Option *NixOSOption `json:"option"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// errNoOptionIndex is returned by searchNixOSOptions when no index matches
+var errNoOptionIndex = errors.New("no such option index")
+
+// fetchReleaseOption looks an option up by its exact name in a release; replaced in tests
+var fetchReleaseOption = fetchNixOSOption
+
+// fetchNixOSOption returns the option called name from the NixOS option search of a nixpkgs
+// release, or nil when the release does not document it. Only an exact name match counts, and
+// only the index of that release is searched: a release without an index is an error, never a
+// lookup in unstable, so that releases can be compared.
+func fetchNixOSOption(name, release string) (*NixOSOption, error) {
+	index, err := releaseOptionsIndex(release)
+	if err != nil {
+		return nil, err
+	}
+	if release == "" {
+		release = "unstable"
+	}
+	opts, err := searchNixOSOptions(index, name, 3)
+	if errors.Is(err, errNoOptionIndex) {
+		return nil, fmt.Errorf("no option index for release %s", release)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to search the options of release %s: %w", release, err)
+	}
+	for _, opt := range opts {
+		if opt.Name == name {
+			opt.Description = stripHTMLTags(opt.Description)
+			if opt.Example == "null" {
+				opt.Example = ""
+			}
+			return &opt, nil
+		}
+	}
+	return nil, nil
+}
+
+// handleOption serves the documentation of the option given by the "name" query parameter in
+// the nixpkgs release of the "release" parameter, as structured JSON rather than the text of
+// /query
+func (s *Server) handleOption(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if name == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(OptionResponse{Error: "missing 'name' query parameter"})
+		return
+	}
+
+	opt, err := fetchReleaseOption(name, r.URL.Query().Get("release"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		_ = json.NewEncoder(w).Encode(OptionResponse{Error: err.Error()})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(OptionResponse{Option: opt})
+}
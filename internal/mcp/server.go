@@ -11,9 +11,12 @@ import (
 	"net/url"
 	"nix-ai-help/internal/config"
 	"nix-ai-help/pkg/logger"
+	"nix-ai-help/pkg/utils"
+	"nix-ai-help/pkg/version"
 	"os"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -104,7 +107,7 @@ func (m *MCPServer) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrp
 			},
 			"serverInfo": map[string]interface{}{
 				"name":    "nixai-mcp-server",
-				"version": "1.0.1",
+				"version": version.Version,
 			},
 		}
 		_ = conn.Reply(ctx, req.ID, result)
@@ -1311,11 +1314,26 @@ func (m *MCPServer) Stop() {
 
 // handleDocQuery processes documentation queries
 func (m *MCPServer) handleDocQuery(query string, sources ...string) string {
+	return m.handleReleaseDocQuery(query, "", sources...)
+}
+
+// handleReleaseDocQuery answers a documentation query with the options of a nixpkgs release
+// such as "24.05"; an empty release uses unstable
+func (m *MCPServer) handleReleaseDocQuery(query, release string, sources ...string) string {
+	return m.handleTopDocQuery(query, release, 0, sources...)
+}
+
+// handleTopDocQuery is handleReleaseDocQuery returning at most top full-text results, taken in
+// source priority order after deduplication; 0 returns them all
+func (m *MCPServer) handleTopDocQuery(query, release string, top int, sources ...string) string {
 	// Add debug header to identify this method is being called
 	var debugOutput strings.Builder
 	debugOutput.WriteString("==== USING MCP SERVER HANDLE_DOC_QUERY ====\n")
 	debugOutput.WriteString(fmt.Sprintf("Query: %s\n", query))
 	debugOutput.WriteString(fmt.Sprintf("Sources: %v\n", sources))
+	if release != "" {
+		debugOutput.WriteString(fmt.Sprintf("Release: %s\n", release))
+	}
 	debugOutput.WriteString("===================================\n\n")
 
 	// Create request to process internally
@@ -1347,68 +1365,54 @@ func (m *MCPServer) handleDocQuery(query string, sources ...string) string {
 		}
 	}
 
-	// Use a buffer to capture output that would normally go to the ResponseWriter
-	var buf bytes.Buffer
-
-	// Process each source manually
-	for _, src := range requestSources {
-		var body string
-		var err error
+	// Full-text results are collected in priority order and deduplicated before returning
+	var excerpts []docExcerpt
 
+	// Process each source by its type: option indexes answer with option JSON, everything
+	// else is searched by full text. Option sources go first so option lookups win.
+	for _, src := range orderSourcesByType(requestSources) {
+		sourceType := sourceTypeOf(src)
 		if m != nil {
-			m.logger.Debug(fmt.Sprintf("handleDocQuery: processing source: %s", src))
+			m.logger.Debug(fmt.Sprintf("handleDocQuery: processing %s source: %s", sourceType, src))
 		}
 
-		if strings.HasPrefix(src, "nixos-options-es://") {
-			body, err = fetchNixOSOptionsAPI(src, query)
+		if sourceType == config.SourceTypeOptions {
+			body, err := fetchOptionSource(src, query, release)
 			if err == nil && !strings.Contains(body, "No documentation found") {
 				if m != nil {
-					m.logger.Debug(fmt.Sprintf("handleDocQuery: found result in NixOS options API: %s", src))
+					m.logger.Debug(fmt.Sprintf("handleDocQuery: found result in options source: %s", src))
 				}
 				return debugOutput.String() + body // Return first good result with debug header
 			}
-		} else if strings.HasSuffix(src, "/options") {
-			body, err = fetchNixOSOptionsAPI(src, query)
-			if err == nil && !strings.Contains(body, "No documentation found") {
-				if m != nil {
-					m.logger.Debug(fmt.Sprintf("handleDocQuery: found result in NixOS options endpoint: %s", src))
-				}
-				return debugOutput.String() + body // Return first good result with debug header
-			}
-		} else if strings.HasSuffix(src, "/options.json") {
-			body, err = fetchHomeManagerOptionsAPI(src, query)
-			if err == nil && !strings.Contains(body, "No documentation found") {
-				if m != nil {
-					m.logger.Debug(fmt.Sprintf("handleDocQuery: found result in Home Manager options: %s", src))
-				}
-				return debugOutput.String() + body // Return first good result with debug header
-			}
-		} else if strings.Contains(src, "nix.dev") {
-			body, err = fetchMySTContent(src, query)
-			if err == nil && len(body) > 0 {
-				if m != nil {
-					m.logger.Debug(fmt.Sprintf("handleDocQuery: found result in nix.dev: %s", src))
-				}
-				return debugOutput.String() + body // Return first good result with debug header
-			}
-		} else {
-			body, err = fetchDocSource(src, query)
-			if err == nil && len(body) > 0 {
-				if m != nil {
-					m.logger.Debug(fmt.Sprintf("handleDocQuery: found partial result in: %s", src))
-				}
-				buf.WriteString(fmt.Sprintf("%s: %s\n", src, body))
+			if err != nil && m != nil {
+				m.logger.Debug(fmt.Sprintf("handleDocQuery: error processing source %s: %v", src, err))
 			}
+			continue
 		}
 
+		body, err := fetchFullTextSource(src, sourceType, query)
+		if err == nil && len(body) > 0 {
+			if m != nil {
+				m.logger.Debug(fmt.Sprintf("handleDocQuery: found partial result in: %s", src))
+			}
+			excerpts = append(excerpts, docExcerpt{source: src, text: body})
+		}
 		if err != nil && m != nil {
 			m.logger.Debug(fmt.Sprintf("handleDocQuery: error processing source %s: %v", src, err))
 		}
 	}
 
-	if buf.Len() > 0 {
+	if len(excerpts) > 0 {
+		deduped := dedupeExcerpts(excerpts)
+		if top > 0 && len(deduped) > top {
+			deduped = deduped[:top]
+		}
+		var buf strings.Builder
+		for _, excerpt := range deduped {
+			buf.WriteString(fmt.Sprintf("%s: %s\n", excerpt.source, excerpt.text))
+		}
 		if m != nil {
-			m.logger.Debug("handleDocQuery: returning combined results")
+			m.logger.Debug(fmt.Sprintf("handleDocQuery: returning combined results from %d of %d sources", len(deduped), len(excerpts)))
 		}
 		return debugOutput.String() + buf.String() // Return combined results with debug header
 	}
@@ -1435,7 +1439,7 @@ func findServerInstance() *Server {
 // handleOptionExplain processes NixOS option explanations
 func (m *MCPServer) handleOptionExplain(option string) string {
 	// Directly call fetchNixOSOptionsAPI instead of making a recursive HTTP call
-	result, err := fetchNixOSOptionsAPI("nixos-options-es://", option)
+	result, err := fetchNixOSOptionsAPI("nixos-options-es://", option, "")
 	if err != nil {
 		return fmt.Sprintf("Error explaining option %s: %v", option, err)
 	}
@@ -1457,20 +1461,17 @@ func (m *MCPServer) handlePackageSearch(query string) string {
 	return fmt.Sprintf("Package search for '%s' is not yet implemented in MCP protocol. Use the CLI interface: nixai search pkg %s", query, query)
 }
 
-// handleOptionCompletion processes option name completions for a given prefix
+// handleOptionCompletion processes option name completions for a given prefix from the NixOS
+// option index; no completions are offered when the index cannot be reached
 func (m *MCPServer) handleOptionCompletion(prefix string) []string {
-	// For demo: use a static list, but in real use, query ElasticSearch or in-memory index
-	allOptions := []string{
-		"services.nginx.enable", "networking.firewall.enable", "programs.zsh.enable", "users.users", "environment.systemPackages", "fonts.fonts", "hardware.opengl.enable", "services.openssh.enable",
-		// ... more options ...
-	}
-	var results []string
-	for _, opt := range allOptions {
-		if strings.HasPrefix(opt, prefix) {
-			results = append(results, opt)
+	names, err := listOptionNames(OptionSourceNixOS, "", prefix, "")
+	if err != nil {
+		if m != nil {
+			m.logger.Debug(fmt.Sprintf("handleOptionCompletion: %v", err))
 		}
+		return nil
 	}
-	return results
+	return names
 }
 
 // Server represents the combined HTTP and MCP server
@@ -1478,6 +1479,7 @@ type Server struct {
 	addr                 string
 	socketPath           string
 	documentationSources []string
+	sourceTypes          map[string]string // declared source types by URL; others are inferred
 	logger               *logger.Logger
 	debugLogging         bool
 	mcpServer            *MCPServer
@@ -1546,7 +1548,11 @@ func NewServerWithDebug(addr string, documentationSources []string) *Server {
 func NewServerFromConfig(configPath string) (*Server, error) {
 	// If configPath is empty, use default user config path
 	if configPath == "" {
-		configPath = os.ExpandEnv("$HOME/.config/nixai/config.yaml")
+		path, err := config.ConfigFilePath()
+		if err != nil {
+			return nil, err
+		}
+		configPath = path
 	}
 
 	// If config file does not exist, create it from embedded default config
@@ -1580,7 +1586,8 @@ func NewServerFromConfig(configPath string) (*Server, error) {
 	srv := &Server{
 		addr:                 addr,
 		socketPath:           socketPath,
-		documentationSources: userCfg.MCPServer.DocumentationSources,
+		documentationSources: config.SourceURLs(userCfg.MCPServer.DocumentationSources),
+		sourceTypes:          config.SourceTypes(userCfg.MCPServer.DocumentationSources),
 		logger:               log,
 		debugLogging:         strings.ToLower(userCfg.LogLevel) == "debug",
 		mcpServer:            &MCPServer{logger: *log, lspProvider: lspProvider},
@@ -1618,7 +1625,8 @@ func (s *Server) watchConfig() {
 				s.logger.Info("Config file changed, reloading...")
 				userCfg, err := config.LoadUserConfig()
 				if err == nil {
-					s.documentationSources = userCfg.MCPServer.DocumentationSources
+					s.documentationSources = config.SourceURLs(userCfg.MCPServer.DocumentationSources)
+					s.sourceTypes = config.SourceTypes(userCfg.MCPServer.DocumentationSources)
 					s.logger.Info("Reloaded documentation sources from config.")
 					// Optionally reload log level, etc.
 				} else {
@@ -1644,12 +1652,15 @@ func (s *Server) Start() error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/query", s.handleQuery)
 
-	// Improved /healthz endpoint
-	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok", "uptime": time.Now().Format(time.RFC3339)})
-	})
+	// /healthz and /info both report version, uptime and cache status
+	mux.HandleFunc("/healthz", s.handleInfo)
+	mux.HandleFunc("/info", s.handleInfo)
+
+	// /options?prefix=... lists option names for shell completion
+	mux.HandleFunc("/options", s.handleOptions)
+
+	// /option?name=...&release=... looks one option up in the index of a release
+	mux.HandleFunc("/option", s.handleOption)
 
 	// /metrics endpoint (simple Prometheus format)
 	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
@@ -1773,8 +1784,9 @@ func min(a, b, c int) int {
 
 // handleQuery processes incoming requests for NixOS documentation.
 func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
-	var query string
+	var query, release string
 	var sources []string
+	var top int
 
 	// Handle both GET requests with 'q' parameter and POST requests with JSON body
 	switch r.Method {
@@ -1785,12 +1797,24 @@ func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 			_, _ = fmt.Fprintln(w, "Missing 'q' query parameter.")
 			return
 		}
+		release = r.URL.Query().Get("release")
+		if value := r.URL.Query().Get("top"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = fmt.Fprintln(w, "Invalid 'top' query parameter.")
+				return
+			}
+			top = n
+		}
 		// Use default sources for GET requests
 		sources = s.documentationSources
 	case "POST":
 		var requestBody struct {
 			Query   string   `json:"query"`
 			Sources []string `json:"sources,omitempty"`
+			Release string   `json:"release,omitempty"`
+			Top     int      `json:"top,omitempty"`
 		}
 
 		// Read the raw request body for debugging
@@ -1806,11 +1830,18 @@ func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		query = requestBody.Query
+		release = requestBody.Release
+		top = requestBody.Top
 		if query == "" {
 			w.WriteHeader(http.StatusBadRequest)
 			_, _ = fmt.Fprintln(w, "Missing 'query' field in JSON body.")
 			return
 		}
+		if top < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintln(w, "Invalid 'top' field in JSON body.")
+			return
+		}
 
 		// Use sources from request if provided, otherwise use default sources
 		if len(requestBody.Sources) > 0 {
@@ -1836,8 +1867,8 @@ func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(map[string]string{"result": result})
 	}
 
-	// Create a cache key that includes both query and sources
-	cacheKey := fmt.Sprintf("%s|%s", query, strings.Join(sources, ","))
+	// Create a cache key that includes the query, sources, release and result limit
+	cacheKey := fmt.Sprintf("%s|%s|%s|%d", query, strings.Join(sources, ","), release, top)
 
 	// Check cache first
 	cacheMutex.RLock()
@@ -1861,7 +1892,7 @@ func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 
 	}
 
-	result := s.mcpServer.handleDocQuery(query, sources...)
+	result := s.mcpServer.handleTopDocQuery(query, release, top, sources...)
 
 	// Cache the result
 	cacheMutex.Lock()
@@ -1874,7 +1905,7 @@ func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 
 func fetchDocSource(urlStr string, queryTerm string) (string, error) {
 	if strings.HasSuffix(urlStr, "/options") {
-		return fetchNixOSOptionsAPI(urlStr, queryTerm)
+		return fetchNixOSOptionsAPI(urlStr, queryTerm, "")
 	}
 	if strings.HasSuffix(urlStr, "/options.json") {
 		return fetchHomeManagerOptionsAPI(urlStr, queryTerm)
@@ -1890,7 +1921,7 @@ func fetchDocSource(urlStr string, queryTerm string) (string, error) {
 		return fetchMySTContent(urlStr, queryTerm)
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := utils.NewHTTPClient(10 * time.Second)
 	// For regular URLs, if we have a query term, try to append it as a search parameter
 	if queryTerm != "" {
 		parsedURL, err := url.Parse(urlStr)
@@ -1948,7 +1979,7 @@ func fetchMediaWikiContent(wikiURL string, query string) (string, error) {
 	reqURL.RawQuery = q.Encode()
 
 	// Make the API request
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := utils.NewHTTPClient(10 * time.Second)
 	resp, err := client.Get(reqURL.String())
 	if err != nil {
 		return "", fmt.Errorf("failed to query MediaWiki API: %v", err)
@@ -2002,25 +2033,13 @@ func stripHTMLTags(s string) string {
 	return re.ReplaceAllString(s, "")
 }
 
-// fetchNixOSOptionsAPI fetches and parses option docs from the NixOS Elasticsearch backend
-func fetchNixOSOptionsAPI(_ string, option string) (string, error) {
-	if strings.TrimSpace(option) == "" {
-		return "", fmt.Errorf("option name required")
-	}
-
-	// Create retryable HTTP client
-	retryClient := retryablehttp.NewClient()
-	retryClient.RetryMax = 3
-	retryClient.Logger = nil
-
-	// Build ElasticSearch index URL
-	index := ElasticSearchIndexPrefix + "nixos-unstable"
-	esURL := fmt.Sprintf(ElasticSearchURLTemplate, index)
-
+// searchNixOSOptions returns up to size options of an ElasticSearch option index that match
+// option, best match first
+func searchNixOSOptions(index, option string, size int) ([]NixOSOption, error) {
 	// Build the query body for exact option match
-	body := map[string]interface{}{
+	return queryNixOSOptions(index, map[string]interface{}{
 		"from": 0,
-		"size": 3,
+		"size": size,
 		"query": map[string]interface{}{
 			"bool": map[string]interface{}{
 				"must": []interface{}{
@@ -2029,16 +2048,29 @@ func fetchNixOSOptionsAPI(_ string, option string) (string, error) {
 				},
 			},
 		},
-	}
+	})
+}
+
+// queryNixOSOptions runs an ElasticSearch query against an option index and returns the
+// options it hits
+func queryNixOSOptions(index string, body map[string]interface{}) ([]NixOSOption, error) {
+	// Create retryable HTTP client
+	retryClient := retryablehttp.NewClient()
+	retryClient.RetryMax = 3
+	retryClient.Logger = nil
+
+	// Build ElasticSearch index URL. A wildcard index matching no release is an error rather
+	// than an empty result.
+	esURL := fmt.Sprintf(ElasticSearchURLTemplate, index) + "?allow_no_indices=false"
 
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	req, err := http.NewRequest("POST", esURL, bytes.NewReader(jsonBody))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	req.SetBasicAuth(ElasticSearchUsername, ElasticSearchPassword)
@@ -2047,31 +2079,51 @@ func fetchNixOSOptionsAPI(_ string, option string) (string, error) {
 
 	resp, err := retryClient.StandardClient().Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to query ElasticSearch: %w", err)
+		return nil, fmt.Errorf("failed to query ElasticSearch: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errNoOptionIndex
+	}
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("ElasticSearch returned status %d", resp.StatusCode)
+		return nil, fmt.Errorf("ElasticSearch returned status %d", resp.StatusCode)
 	}
 
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Parse response
 	var esResp ESResponse
 	if err := json.Unmarshal(data, &esResp); err != nil {
-		return "", fmt.Errorf("failed to parse ElasticSearch response: %w", err)
+		return nil, fmt.Errorf("failed to parse ElasticSearch response: %w", err)
+	}
+
+	opts := make([]NixOSOption, 0, len(esResp.Hits.Hits))
+	for _, hit := range esResp.Hits.Hits {
+		opts = append(opts, hit.Source)
+	}
+	return opts, nil
+}
+
+// fetchNixOSOptionsAPI fetches and parses option docs from the NixOS Elasticsearch backend
+func fetchNixOSOptionsAPI(_ string, option, release string) (string, error) {
+	if strings.TrimSpace(option) == "" {
+		return "", fmt.Errorf("option name required")
 	}
 
-	if len(esResp.Hits.Hits) == 0 {
+	opts, err := searchNixOSOptions(nixosOptionsIndex(release), option, 3)
+	if err != nil {
+		return "", err
+	}
+	if len(opts) == 0 {
 		return "No documentation found for this option in the official NixOS options database.", nil
 	}
 
 	// Use the first (best) match
-	opt := esResp.Hits.Hits[0].Source
+	opt := opts[0]
 	var result strings.Builder
 
 	result.WriteString(fmt.Sprintf("Option: %s\n", opt.Name))
@@ -2109,7 +2161,7 @@ func fetchHomeManagerOptionsAPI(baseURL, option string) (string, error) {
 	// js-search performs both prefix and infix matching by default
 	apiURL := baseURL + "?query=" + url.QueryEscape(option)
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := utils.NewHTTPClient(10 * time.Second)
 	resp, err := client.Get(apiURL)
 	if err != nil {
 		return "", err
@@ -2280,7 +2332,7 @@ func fetchMySTContent(docURL string, query string) (string, error) {
 		Content string
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := utils.NewHTTPClient(10 * time.Second)
 
 	// Check each possible path
 	for _, path := range possiblePaths {
@@ -1,10 +1,13 @@
 package mcp
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"nix-ai-help/pkg/version"
 )
 
 func TestHandleQuery_FuzzySearch(t *testing.T) {
@@ -25,3 +28,48 @@ func TestHandleQuery_FuzzySearch(t *testing.T) {
 		t.Errorf("expected result to contain 'nixpkgs', got: %s", body)
 	}
 }
+
+func TestHandleInfo_Payload(t *testing.T) {
+	docs := []string{"https://wiki.nixos.org/wiki/NixOS_Wiki", "https://nix.dev/manual/nix"}
+	s := NewServer(":0", docs)
+	req := httptest.NewRequest("GET", "/info", nil)
+	w := httptest.NewRecorder()
+
+	s.handleInfo(w, req)
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 OK, got %d", resp.StatusCode)
+	}
+
+	var info ServerInfo
+	if err := json.NewDecoder(w.Body).Decode(&info); err != nil {
+		t.Fatalf("failed to decode info payload: %v", err)
+	}
+	if info.Status != "ok" {
+		t.Errorf("expected status ok, got %q", info.Status)
+	}
+	if info.Version != version.Version {
+		t.Errorf("expected version %q, got %q", version.Version, info.Version)
+	}
+	if info.DocumentationSources != len(docs) {
+		t.Errorf("expected %d documentation sources, got %d", len(docs), info.DocumentationSources)
+	}
+	if info.UptimeSeconds < 0 {
+		t.Errorf("expected non-negative uptime, got %d", info.UptimeSeconds)
+	}
+	if info.AuthEnabled {
+		t.Error("expected auth to be reported as disabled")
+	}
+
+	var raw map[string]interface{}
+	w = httptest.NewRecorder()
+	s.handleInfo(w, req)
+	if err := json.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+		t.Fatalf("failed to decode raw payload: %v", err)
+	}
+	for _, field := range []string{"version", "uptime_seconds", "documentation_sources", "cache_entries", "auth_enabled"} {
+		if _, ok := raw[field]; !ok {
+			t.Errorf("expected info payload to contain %q", field)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package nixos
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Kinds of errors recognized in nixos-rebuild and nix build output
+const (
+	// BuildErrorCollision is two packages of a profile or buildEnv providing the same file
+	BuildErrorCollision = "collision"
+	// BuildErrorHashMismatch is a fixed-output derivation whose content has another hash
+	BuildErrorHashMismatch = "hash_mismatch"
+	// BuildErrorEvaluation is an error while evaluating the configuration
+	BuildErrorEvaluation = "eval_error"
+	// BuildErrorBuilder is a derivation whose builder failed
+	BuildErrorBuilder = "builder_failed"
+)
+
+// BuildError is an error found in nixos-rebuild output
+type BuildError struct {
+	Kind    string
+	Summary string
+	// Location is the file:line:column of an evaluation error, if the output names one
+	Location string
+	// Derivation is the store path of the failing derivation, if the output names one
+	Derivation string
+}
+
+// buildOutputMarkers are lines only nix builds and nixos-rebuild print, telling build output
+// apart from journal and service logs
+var buildOutputMarkers = []*regexp.Regexp{
+	regexp.MustCompile(`(?m)^building the system configuration`),
+	regexp.MustCompile(`(?m)^these \d+ derivations? will be built`),
+	regexp.MustCompile(`(?m)^these \d+ paths? will be fetched`),
+	regexp.MustCompile(`(?m)^building '/nix/store/[^']+\.drv'`),
+	regexp.MustCompile(`(?m)^activating the configuration`),
+	regexp.MustCompile(`error: builder for '/nix/store/`),
+	regexp.MustCompile(`hash mismatch in fixed-output derivation`),
+	regexp.MustCompile(`error: collision between`),
+	regexp.MustCompile(`(?m)^\s*… while evaluating`),
+	regexp.MustCompile(`while evaluating the attribute '`),
+}
+
+var (
+	collisionRegex    = regexp.MustCompile("collision between [`']([^'`]+)' and [`']([^'`]+)'")
+	hashMismatchRegex = regexp.MustCompile(`hash mismatch in fixed-output derivation '([^']+)'`)
+	specifiedRegex    = regexp.MustCompile(`specified:\s*(\S+)`)
+	gotRegex          = regexp.MustCompile(`got:\s*(\S+)`)
+	builderRegex      = regexp.MustCompile(`builder for '([^']+)' failed(?: with exit code (\d+))?`)
+	errorLineRegex    = regexp.MustCompile(`^\s*error:\s*(.*)$`)
+	evalLocationRegex = regexp.MustCompile(`(?m)^\s*at (/[^:\s]+:\d+:\d+):?\s*$`)
+)
+
+// IsBuildOutput reports whether output comes from nixos-rebuild or nix build rather than a
+// generic log
+func IsBuildOutput(output string) bool {
+	for _, marker := range buildOutputMarkers {
+		if marker.MatchString(output) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseBuildErrors extracts collisions, hash mismatches, builder failures and evaluation errors
+// from nixos-rebuild output, in the order they appear
+func ParseBuildErrors(output string) []BuildError {
+	var errs []BuildError
+	lines := strings.Split(output, "\n")
+
+	for i, line := range lines {
+		switch {
+		case collisionRegex.MatchString(line):
+			m := collisionRegex.FindStringSubmatch(line)
+			errs = append(errs, BuildError{
+				Kind:    BuildErrorCollision,
+				Summary: "collision between " + m[1] + " and " + m[2],
+			})
+		case hashMismatchRegex.MatchString(line):
+			m := hashMismatchRegex.FindStringSubmatch(line)
+			summary := "hash mismatch in " + m[1]
+			following := strings.Join(lines[i+1:min(i+4, len(lines))], "\n")
+			if s, g := specifiedRegex.FindStringSubmatch(following), gotRegex.FindStringSubmatch(following); s != nil && g != nil {
+				summary += ": specified " + s[1] + ", got " + g[1]
+			}
+			errs = append(errs, BuildError{Kind: BuildErrorHashMismatch, Summary: summary, Derivation: m[1]})
+		case builderRegex.MatchString(line):
+			m := builderRegex.FindStringSubmatch(line)
+			summary := "builder for " + m[1] + " failed"
+			if m[2] != "" {
+				summary += " with exit code " + m[2]
+			}
+			errs = append(errs, BuildError{Kind: BuildErrorBuilder, Summary: summary, Derivation: m[1]})
+		}
+	}
+
+	if evalErr, ok := parseEvaluationError(lines); ok {
+		errs = append(errs, evalErr)
+	}
+	return errs
+}
+
+// parseEvaluationError finds an evaluation error in the lines of build output. Nix prints a
+// trace of "… while evaluating" frames followed by the innermost error message and its
+// location, which are the ones reported.
+func parseEvaluationError(lines []string) (BuildError, bool) {
+	output := strings.Join(lines, "\n")
+	if !strings.Contains(output, "while evaluating") && !strings.Contains(output, "undefined variable") &&
+		!strings.Contains(output, "infinite recursion encountered") {
+		return BuildError{}, false
+	}
+
+	messageLine := -1
+	var message string
+	for i, line := range lines {
+		m := errorLineRegex.FindStringSubmatch(line)
+		if m == nil || m[1] == "" {
+			continue
+		}
+		if collisionRegex.MatchString(line) || hashMismatchRegex.MatchString(line) || builderRegex.MatchString(line) {
+			continue
+		}
+		messageLine, message = i, strings.TrimSpace(m[1])
+	}
+	if message == "" {
+		return BuildError{}, false
+	}
+
+	evalErr := BuildError{Kind: BuildErrorEvaluation, Summary: message}
+	// Prefer the location printed right after the message over the trace frames before it
+	if m := evalLocationRegex.FindStringSubmatch(strings.Join(lines[messageLine+1:], "\n")); m != nil {
+		evalErr.Location = m[1]
+	} else if locations := evalLocationRegex.FindAllStringSubmatch(output, -1); len(locations) > 0 {
+		evalErr.Location = locations[len(locations)-1][1]
+	}
+	return evalErr, true
+}
@@ -0,0 +1,95 @@
+package nixos
+
+import (
+	"reflect"
+	"testing"
+)
+
+const collisionOutput = `building the system configuration...
+these 2 derivations will be built:
+  /nix/store/aaaa-system-path.drv
+  /nix/store/bbbb-nixos-system-host-24.05.drv
+building '/nix/store/aaaa-system-path.drv'...
+error: collision between ` + "`" + `/nix/store/cccc-vim-9.1/bin/vi' and ` + "`" + `/nix/store/dddd-neovim-0.10/bin/vi'
+error: builder for '/nix/store/aaaa-system-path.drv' failed with exit code 25;
+`
+
+const hashMismatchOutput = `these 3 derivations will be built:
+  /nix/store/eeee-source.drv
+building '/nix/store/eeee-source.drv'...
+error: hash mismatch in fixed-output derivation '/nix/store/eeee-source.drv':
+         specified: sha256-AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=
+            got:    sha256-BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB=
+`
+
+const evalErrorOutput = `building the system configuration...
+error:
+       … while evaluating the attribute 'config.system.build.toplevel'
+         at /nix/store/ffff-source/nixos/modules/system/activation/top-level.nix:71:12:
+       … while evaluating the attribute 'environment.systemPackages'
+         at /etc/nixos/configuration.nix:9:3:
+
+       error: undefined variable 'firefx'
+       at /etc/nixos/configuration.nix:10:5:
+            9|   environment.systemPackages = with pkgs; [
+           10|     firefx
+             |     ^
+`
+
+const journalOutput = `Jun 01 10:00:00 host systemd[1]: Starting nginx.service...
+Jun 01 10:00:01 host nginx[1234]: nginx: [emerg] bind() to 0.0.0.0:80 failed (98: Address already in use)
+Jun 01 10:00:01 host systemd[1]: nginx.service: Main process exited, code=exited, status=1/FAILURE
+Jun 01 10:00:01 host systemd[1]: Failed to start nginx.service.
+`
+
+func TestIsBuildOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   bool
+	}{
+		{"collision", collisionOutput, true},
+		{"hash mismatch", hashMismatchOutput, true},
+		{"evaluation error", evalErrorOutput, true},
+		{"journal", journalOutput, false},
+		{"generic error", "error: permission denied while opening /etc/shadow", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsBuildOutput(tt.output); got != tt.want {
+				t.Errorf("IsBuildOutput() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBuildErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []BuildError
+	}{
+		{"collision", collisionOutput, []BuildError{
+			{Kind: BuildErrorCollision, Summary: "collision between /nix/store/cccc-vim-9.1/bin/vi and /nix/store/dddd-neovim-0.10/bin/vi"},
+			{Kind: BuildErrorBuilder, Summary: "builder for /nix/store/aaaa-system-path.drv failed with exit code 25",
+				Derivation: "/nix/store/aaaa-system-path.drv"},
+		}},
+		{"hash mismatch", hashMismatchOutput, []BuildError{
+			{Kind: BuildErrorHashMismatch,
+				Summary: "hash mismatch in /nix/store/eeee-source.drv: specified sha256-AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=, " +
+					"got sha256-BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB=",
+				Derivation: "/nix/store/eeee-source.drv"},
+		}},
+		{"evaluation error", evalErrorOutput, []BuildError{
+			{Kind: BuildErrorEvaluation, Summary: "undefined variable 'firefx'", Location: "/etc/nixos/configuration.nix:10:5"},
+		}},
+		{"journal", journalOutput, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseBuildErrors(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBuildErrors() =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
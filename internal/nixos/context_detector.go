@@ -13,11 +13,13 @@ import (
 
 	"nix-ai-help/internal/config"
 	"nix-ai-help/pkg/logger"
+	"nix-ai-help/pkg/utils"
 )
 
 // ContextDetector handles NixOS configuration context detection
 type ContextDetector struct {
-	logger *logger.Logger
+	logger  *logger.Logger
+	imports *ImportTree // configuration files reached from configuration.nix during detection
 }
 
 // NewContextDetector creates a new context detector
@@ -39,11 +41,13 @@ func (cd *ContextDetector) DetectNixOSContext(userConfig *config.UserConfig) (*c
 
 	// Run detection methods
 	cd.detectSystemType(context)
+	cd.detectEnvironment(context)
 	cd.detectNixVersion(context)
 	cd.detectFlakesUsage(context, userConfig)
+	cd.detectNixpkgsRelease(context)
 	cd.detectChannelsUsage(context)
-	cd.detectHomeManager(context)
 	cd.detectConfigurationFiles(context, userConfig)
+	cd.detectHomeManager(context)
 	cd.detectEnabledServices(context)
 	cd.detectInstalledPackages(context)
 
@@ -75,7 +79,7 @@ func (cd *ContextDetector) detectSystemType(context *config.NixOSContext) {
 	}
 
 	// Check if nix is available but not system-wide (home-manager only)
-	if cmd := exec.Command("which", "nix"); cmd.Run() == nil {
+	if cmd := exec.Command("which", "nix"); utils.TraceRun(cmd) == nil {
 		context.SystemType = "home-manager-only"
 		cd.logger.Debug("Detected home-manager-only system")
 		return
@@ -91,14 +95,14 @@ func (cd *ContextDetector) detectNixVersion(context *config.NixOSContext) {
 	cd.logger.Debug("Detecting Nix version...")
 
 	// Get Nix version
-	if output, err := exec.Command("nix", "--version").Output(); err == nil {
+	if output, err := utils.TraceOutput(exec.Command("nix", "--version")); err == nil {
 		context.NixVersion = strings.TrimSpace(string(output))
 		cd.logger.Debug("Detected Nix version: " + context.NixVersion)
 	}
 
 	// Get NixOS version (only on NixOS systems)
 	if context.SystemType == "nixos" {
-		if output, err := exec.Command("nixos-version").Output(); err == nil {
+		if output, err := utils.TraceOutput(exec.Command("nixos-version")); err == nil {
 			context.NixOSVersion = strings.TrimSpace(string(output))
 			cd.logger.Debug("Detected NixOS version: " + context.NixOSVersion)
 		}
@@ -160,7 +164,7 @@ func (cd *ContextDetector) detectChannelsUsage(context *config.NixOSContext) {
 	cd.logger.Debug("Detecting channels usage...")
 
 	// Check for user channels
-	if output, err := exec.Command("nix-channel", "--list").Output(); err == nil && len(strings.TrimSpace(string(output))) > 0 {
+	if output, err := utils.TraceOutput(exec.Command("nix-channel", "--list")); err == nil && len(strings.TrimSpace(string(output))) > 0 {
 		context.UsesChannels = true
 		cd.logger.Debug("User channels detected")
 		return
@@ -168,7 +172,7 @@ func (cd *ContextDetector) detectChannelsUsage(context *config.NixOSContext) {
 
 	// Check for system channels (on NixOS)
 	if context.SystemType == "nixos" {
-		if output, err := exec.Command("sudo", "nix-channel", "--list").Output(); err == nil && len(strings.TrimSpace(string(output))) > 0 {
+		if output, err := utils.TraceOutput(exec.Command("sudo", "nix-channel", "--list")); err == nil && len(strings.TrimSpace(string(output))) > 0 {
 			context.UsesChannels = true
 			cd.logger.Debug("System channels detected")
 			return
@@ -190,6 +194,18 @@ func (cd *ContextDetector) detectHomeManager(context *config.NixOSContext) {
 			context.ConfigurationNix,
 		}
 
+		// Imported modules are checked too, since Home Manager is often imported from a sub-module
+		if cd.imports != nil {
+			for _, file := range cd.imports.Files {
+				if importsHomeManager(cd.imports.Contents[file]) {
+					context.HasHomeManager = true
+					context.HomeManagerType = "module"
+					cd.logger.Debug("Detected Home Manager as NixOS module in: " + file)
+					return
+				}
+			}
+		}
+
 		for _, configPath := range configPaths {
 			if configPath == "" {
 				continue
@@ -234,7 +250,7 @@ func (cd *ContextDetector) detectHomeManager(context *config.NixOSContext) {
 	}
 
 	// Check for standalone Home Manager (fallback)
-	if exec.Command("which", "home-manager").Run() == nil {
+	if utils.TraceRun(exec.Command("which", "home-manager")) == nil {
 		// Check if this is truly standalone by looking for standalone config files
 		hmConfigPaths := []string{
 			filepath.Join(os.Getenv("HOME"), ".config", "home-manager", "home.nix"),
@@ -311,21 +327,37 @@ func (cd *ContextDetector) detectConfigurationFiles(context *config.NixOSContext
 	}
 
 	// Look for additional .nix files in the config directory
+	var additionalFiles []string
 	if context.NixOSConfigPath != "" {
 		if files, err := filepath.Glob(filepath.Join(context.NixOSConfigPath, "*.nix")); err == nil {
-			for _, file := range files {
-				found := false
-				for _, existingFile := range context.ConfigurationFiles {
-					if existingFile == file {
-						found = true
-						break
-					}
-				}
-				if !found {
-					context.ConfigurationFiles = append(context.ConfigurationFiles, file)
-				}
+			additionalFiles = files
+		}
+	}
+
+	// Follow imports from configuration.nix so modular configs are fully covered
+	cd.imports = nil
+	if context.ConfigurationNix != "" {
+		cd.imports = FollowImports(context.ConfigurationNix, maxImportDepth)
+		for _, cycle := range cd.imports.Cycles {
+			cd.logger.Debug("Skipping import cycle: " + cycle)
+		}
+		if cd.imports.Truncated {
+			cd.logger.Debug(fmt.Sprintf("Imports nested deeper than %d levels were not followed", maxImportDepth))
+		}
+		additionalFiles = append(additionalFiles, cd.imports.Files...)
+	}
+
+	for _, file := range additionalFiles {
+		found := false
+		for _, existingFile := range context.ConfigurationFiles {
+			if existingFile == file {
+				found = true
+				break
 			}
 		}
+		if !found {
+			context.ConfigurationFiles = append(context.ConfigurationFiles, file)
+		}
 	}
 }
 
@@ -337,20 +369,31 @@ func (cd *ContextDetector) detectEnabledServices(context *config.NixOSContext) {
 		return
 	}
 
-	content, err := os.ReadFile(context.ConfigurationNix)
-	if err != nil {
-		context.DetectionErrors = append(context.DetectionErrors,
-			fmt.Sprintf("Failed to read configuration.nix: %v", err))
-		return
+	// Scan configuration.nix and every module it imports
+	contents := []string{}
+	if cd.imports != nil && len(cd.imports.Files) > 0 {
+		for _, file := range cd.imports.Files {
+			contents = append(contents, cd.imports.Contents[file])
+		}
+	} else {
+		content, err := os.ReadFile(context.ConfigurationNix)
+		if err != nil {
+			context.DetectionErrors = append(context.DetectionErrors,
+				fmt.Sprintf("Failed to read configuration.nix: %v", err))
+			return
+		}
+		contents = append(contents, string(content))
 	}
 
 	// Parse services from configuration
 	serviceRegex := regexp.MustCompile(`services\.([a-zA-Z0-9_-]+)\.enable\s*=\s*true`)
-	matches := serviceRegex.FindAllStringSubmatch(string(content), -1)
-
-	for _, match := range matches {
-		if len(match) > 1 {
-			context.EnabledServices = append(context.EnabledServices, match[1])
+	seen := make(map[string]bool)
+	for _, content := range contents {
+		for _, match := range serviceRegex.FindAllStringSubmatch(content, -1) {
+			if len(match) > 1 && !seen[match[1]] {
+				seen[match[1]] = true
+				context.EnabledServices = append(context.EnabledServices, match[1])
+			}
 		}
 	}
 
@@ -362,7 +405,7 @@ func (cd *ContextDetector) detectInstalledPackages(context *config.NixOSContext)
 	cd.logger.Debug("Detecting installed packages...")
 
 	// Try to get system packages (limited to avoid performance issues)
-	if output, err := exec.Command("nix-env", "--query", "--installed").Output(); err == nil {
+	if output, err := utils.TraceOutput(exec.Command("nix-env", "--query", "--installed")); err == nil {
 		scanner := bufio.NewScanner(strings.NewReader(string(output)))
 		count := 0
 		for scanner.Scan() && count < 50 { // Limit to first 50 packages
@@ -405,6 +448,15 @@ func (cd *ContextDetector) IsContextCacheValid(context *config.NixOSContext) boo
 		}
 	}
 
+	// Imported modules invalidate the cache as well
+	for _, file := range context.ConfigurationFiles {
+		if stat, err := os.Stat(file); err == nil {
+			if stat.ModTime().After(context.LastDetected) {
+				return false
+			}
+		}
+	}
+
 	return true
 }
 
@@ -420,7 +472,7 @@ func (cd *ContextDetector) RefreshContext(userConfig *config.UserConfig) error {
 	userConfig.NixOSContext = *newContext
 
 	// Save updated config
-	if err := config.SaveUserConfig(userConfig); err != nil {
+	if err := config.SaveUserConfigWithoutBackup(userConfig); err != nil {
 		return fmt.Errorf("failed to save updated context: %v", err)
 	}
 
@@ -428,8 +480,20 @@ func (cd *ContextDetector) RefreshContext(userConfig *config.UserConfig) error {
 	return nil
 }
 
-// GetContext returns the current context, detecting if necessary
+// GetContext returns the current context, detecting if necessary. When context detection is
+// disabled (see config.UserConfig.ContextDetectionEnabled) it returns nil without running any
+// detection, and callers build their prompts without context.
 func (cd *ContextDetector) GetContext(userConfig *config.UserConfig) (*config.NixOSContext, error) {
+	if !userConfig.ContextDetectionEnabled() {
+		cd.logger.Debug("NixOS context detection is disabled")
+		return nil, nil
+	}
+	return cd.GetContextEvenIfDisabled(userConfig)
+}
+
+// GetContextEvenIfDisabled returns the current context like GetContext, also when context
+// detection is disabled; for the context commands, which the user runs to see the context
+func (cd *ContextDetector) GetContextEvenIfDisabled(userConfig *config.UserConfig) (*config.NixOSContext, error) {
 	// Check if we have a valid cached context
 	if cd.IsContextCacheValid(&userConfig.NixOSContext) {
 		cd.logger.Debug("Using cached NixOS context")
@@ -445,7 +509,7 @@ func (cd *ContextDetector) GetContext(userConfig *config.UserConfig) (*config.Ni
 
 	// Update and save the config
 	userConfig.NixOSContext = *newContext
-	if err := config.SaveUserConfig(userConfig); err != nil {
+	if err := config.SaveUserConfigWithoutBackup(userConfig); err != nil {
 		cd.logger.Warn("Failed to save context to config: " + err.Error())
 	}
 
@@ -468,7 +532,7 @@ func (cd *ContextDetector) ClearCache() error {
 	userConfig.NixOSContext.DetectionErrors = []string{}
 
 	// Save the updated config
-	if err := config.SaveUserConfig(userConfig); err != nil {
+	if err := config.SaveUserConfigWithoutBackup(userConfig); err != nil {
 		return fmt.Errorf("failed to save updated config: %v", err)
 	}
 
@@ -0,0 +1,59 @@
+package nixos
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/logger"
+)
+
+func TestGetContextDisabledSkipsDetection(t *testing.T) {
+	// Detection saves the detected context, so a config file would appear here
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := config.SetConfigFilePath(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = config.SetConfigFilePath("") })
+
+	disabled := false
+	detector := NewContextDetector(logger.NewLoggerWithWriter(io.Discard))
+	cfg := &config.UserConfig{Context: config.ContextConfig{Enabled: &disabled}}
+	nixosCtx, err := detector.GetContext(cfg)
+	if err != nil || nixosCtx != nil {
+		t.Fatalf("expected no context without an error, got %+v, %v", nixosCtx, err)
+	}
+	if !cfg.NixOSContext.LastDetected.IsZero() {
+		t.Error("expected no detection to run")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected no context to be saved, got %v", err)
+	}
+
+	config.SetContextDetectionDisabled(true)
+	t.Cleanup(func() { config.SetContextDetectionDisabled(false) })
+	if nixosCtx, _ := detector.GetContext(&config.UserConfig{}); nixosCtx != nil {
+		t.Errorf("expected --no-context to skip detection, got %+v", nixosCtx)
+	}
+}
+
+func TestGetContextEvenIfDisabled(t *testing.T) {
+	config.SetContextDetectionDisabled(true)
+	t.Cleanup(func() { config.SetContextDetectionDisabled(false) })
+
+	cfg := &config.UserConfig{NixOSContext: config.NixOSContext{
+		SystemType:   "nixos",
+		CacheValid:   true,
+		LastDetected: time.Now(),
+	}}
+	nixosCtx, err := NewContextDetector(logger.NewLoggerWithWriter(io.Discard)).GetContextEvenIfDisabled(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nixosCtx == nil || nixosCtx.SystemType != "nixos" {
+		t.Errorf("expected the cached context, got %+v", nixosCtx)
+	}
+}
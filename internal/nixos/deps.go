@@ -38,7 +38,7 @@ func AnalyzeFlakeDependencies(flakeDir string, hostname string) (*DependencyGrap
 	buildCmd.Dir = flakeDir
 
 	fmt.Println(utils.FormatProgress(fmt.Sprintf("Executing: %s in %s", buildCmd.String(), flakeDir)))
-	buildOut, err := buildCmd.Output()
+	buildOut, err := utils.TraceOutput(buildCmd)
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			return nil, fmt.Errorf("nix build command failed to get system store path: %v\nStderr: %s", err, string(exitErr.Stderr))
@@ -97,7 +97,7 @@ func fetchAndParseDerivationRecursive(storePath string, graph *DependencyGraph,
 	if flakeDir != "" {
 		queryCmd.Dir = flakeDir // Set working directory for consistency
 	}
-	refsOut, err := queryCmd.Output()
+	refsOut, err := utils.TraceOutput(queryCmd)
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			return nil, fmt.Errorf("nix-store --query --references %s failed: %v\nStderr: %s", storePath, err, string(exitErr.Stderr))
@@ -463,7 +463,7 @@ func AnalyzeLegacyDependencies(configFilePath string) (*DependencyGraph, error)
 
 	cmd := exec.Command("nix-build", "<nixpkgs/nixos>", "-A", "system", "--no-link", "--print-out-paths", "-I", fmt.Sprintf("nixos-config=%s", absConfigPath))
 	fmt.Println(utils.FormatProgress(fmt.Sprintf("Executing: %s", cmd.String())))
-	buildOut, err := cmd.Output()
+	buildOut, err := utils.TraceOutput(cmd)
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			return nil, fmt.Errorf("nix-build command failed to get system store path for legacy config: %v\nStderr: %s", err, string(exitErr.Stderr))
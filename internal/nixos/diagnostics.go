@@ -61,6 +61,95 @@ func Diagnose(logOutput string, userInput string, aiProvider ai.AIProvider) []Di
 	var diagnostics []Diagnostic
 	log := logger.NewLogger()
 
+	errorPatterns := loadErrorPatterns(log)
+
+	// Check for each error pattern
+	for _, pattern := range errorPatterns {
+		if pattern.Pattern.MatchString(logOutput) {
+			diagnostic := Diagnostic{
+				Issue:     pattern.Description,
+				Details:   extractErrorContext(logOutput, pattern.Pattern),
+				ErrorType: pattern.ErrorType,
+				Severity:  pattern.Severity,
+				Steps:     generateFixSteps(pattern.ErrorType),
+				DocsLinks: getRelevantDocsLinks(pattern.ErrorType),
+			}
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+
+	// If no specific patterns matched but there's an error, create a generic diagnostic
+	if strings.Contains(logOutput, "error") && len(diagnostics) == 0 {
+		diagnostics = append(diagnostics, Diagnostic{
+			Issue:     "Unclassified error detected",
+			Details:   extractGenericError(logOutput),
+			ErrorType: "generic",
+			Severity:  "medium",
+			Steps:     []string{"Review the error message carefully", "Check your configuration syntax", "Consult the NixOS manual"},
+			DocsLinks: []string{"https://nixos.org/manual/nixos/stable/"},
+		})
+	}
+
+	// User input analysis: parse config snippets
+	if userInput != "" {
+		if strings.Contains(userInput, "=") {
+			// Looks like a config snippet, try parsing
+			_, err := ParseNixConfig(userInput)
+			if err != nil {
+				diagnostics = append(diagnostics, Diagnostic{
+					Issue:     "Invalid Nix config snippet",
+					Details:   err.Error(),
+					ErrorType: "syntax",
+					Severity:  "high",
+					Steps:     generateFixSteps("syntax"),
+					DocsLinks: getRelevantDocsLinks("syntax"),
+				})
+			} else {
+				diagnostics = append(diagnostics, Diagnostic{
+					Issue:     "User config snippet provided",
+					Details:   "Parsed config: OK",
+					ErrorType: "info",
+					Severity:  "low",
+					Steps:     []string{"Configuration appears valid"},
+					DocsLinks: []string{},
+				})
+			}
+		} else {
+			diagnostics = append(diagnostics, Diagnostic{
+				Issue:     "User input provided",
+				Details:   fmt.Sprintf("Received user input: %s", userInput),
+				ErrorType: "info",
+				Severity:  "low",
+				Steps:     []string{"Review the provided input for any issues"},
+				DocsLinks: []string{},
+			})
+		}
+	}
+
+	// Enhanced AI integration: ask LLM for structured diagnosis and fixes
+	if (len(logOutput) > 0 || len(userInput) > 0) && aiProvider != nil {
+		prompt := buildEnhancedDiagnosticPrompt(logOutput, userInput, diagnostics)
+		aiResp, err := aiProvider.Query(prompt)
+		if err == nil && aiResp != "" {
+			diagnostics = append(diagnostics, Diagnostic{
+				Issue:     "AI-Enhanced Analysis",
+				Details:   aiResp,
+				ErrorType: "ai_analysis",
+				Severity:  "medium",
+				Steps:     extractAISteps(aiResp),
+				DocsLinks: extractAILinks(aiResp),
+			})
+		} else if err != nil {
+			log.Warn("AI provider error: " + err.Error())
+		}
+	}
+
+	return diagnostics
+}
+
+// loadErrorPatterns returns the built-in error patterns merged with the user-defined ones
+// from config, keyed by name
+func loadErrorPatterns(log *logger.Logger) map[string]ErrorPattern {
 	// Enhanced error pattern recognition
 	errorPatterns := map[string]ErrorPattern{
 		"syntax_error": {
@@ -146,88 +235,7 @@ func Diagnose(logOutput string, userInput string, aiProvider ai.AIProvider) []Di
 		}
 	}
 
-	// Check for each error pattern
-	for _, pattern := range errorPatterns {
-		if pattern.Pattern.MatchString(logOutput) {
-			diagnostic := Diagnostic{
-				Issue:     pattern.Description,
-				Details:   extractErrorContext(logOutput, pattern.Pattern),
-				ErrorType: pattern.ErrorType,
-				Severity:  pattern.Severity,
-				Steps:     generateFixSteps(pattern.ErrorType),
-				DocsLinks: getRelevantDocsLinks(pattern.ErrorType),
-			}
-			diagnostics = append(diagnostics, diagnostic)
-		}
-	}
-
-	// If no specific patterns matched but there's an error, create a generic diagnostic
-	if strings.Contains(logOutput, "error") && len(diagnostics) == 0 {
-		diagnostics = append(diagnostics, Diagnostic{
-			Issue:     "Unclassified error detected",
-			Details:   extractGenericError(logOutput),
-			ErrorType: "generic",
-			Severity:  "medium",
-			Steps:     []string{"Review the error message carefully", "Check your configuration syntax", "Consult the NixOS manual"},
-			DocsLinks: []string{"https://nixos.org/manual/nixos/stable/"},
-		})
-	}
-
-	// User input analysis: parse config snippets
-	if userInput != "" {
-		if strings.Contains(userInput, "=") {
-			// Looks like a config snippet, try parsing
-			_, err := ParseNixConfig(userInput)
-			if err != nil {
-				diagnostics = append(diagnostics, Diagnostic{
-					Issue:     "Invalid Nix config snippet",
-					Details:   err.Error(),
-					ErrorType: "syntax",
-					Severity:  "high",
-					Steps:     generateFixSteps("syntax"),
-					DocsLinks: getRelevantDocsLinks("syntax"),
-				})
-			} else {
-				diagnostics = append(diagnostics, Diagnostic{
-					Issue:     "User config snippet provided",
-					Details:   "Parsed config: OK",
-					ErrorType: "info",
-					Severity:  "low",
-					Steps:     []string{"Configuration appears valid"},
-					DocsLinks: []string{},
-				})
-			}
-		} else {
-			diagnostics = append(diagnostics, Diagnostic{
-				Issue:     "User input provided",
-				Details:   fmt.Sprintf("Received user input: %s", userInput),
-				ErrorType: "info",
-				Severity:  "low",
-				Steps:     []string{"Review the provided input for any issues"},
-				DocsLinks: []string{},
-			})
-		}
-	}
-
-	// Enhanced AI integration: ask LLM for structured diagnosis and fixes
-	if (len(logOutput) > 0 || len(userInput) > 0) && aiProvider != nil {
-		prompt := buildEnhancedDiagnosticPrompt(logOutput, userInput, diagnostics)
-		aiResp, err := aiProvider.Query(prompt)
-		if err == nil && aiResp != "" {
-			diagnostics = append(diagnostics, Diagnostic{
-				Issue:     "AI-Enhanced Analysis",
-				Details:   aiResp,
-				ErrorType: "ai_analysis",
-				Severity:  "medium",
-				Steps:     extractAISteps(aiResp),
-				DocsLinks: extractAILinks(aiResp),
-			})
-		} else if err != nil {
-			log.Warn("AI provider error: " + err.Error())
-		}
-	}
-
-	return diagnostics
+	return errorPatterns
 }
 
 // Helper functions for enhanced error processing
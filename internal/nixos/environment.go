@@ -0,0 +1,94 @@
+package nixos
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"nix-ai-help/internal/config"
+)
+
+// Environments reported in NixOSContext.Environment
+const (
+	EnvironmentStandard  = "standard"
+	EnvironmentContainer = "container"
+	EnvironmentWSL       = "wsl"
+	EnvironmentDarwin    = "darwin"
+)
+
+// containerMarkers are files that only exist inside a container, with the runtime they reveal
+var containerMarkers = []struct {
+	path    string
+	runtime string
+}{
+	{".dockerenv", "docker"},
+	{"run/.containerenv", "podman"},
+}
+
+// IsWSL reports whether nixai is running under Windows Subsystem for Linux
+func IsWSL() bool {
+	return isWSLAt("/", os.Getenv)
+}
+
+// isWSLAt reports whether the system under root ("/" outside tests) is WSL, from the environment
+// variables, the WSL interop handler, the kernel version and the container systemd records
+func isWSLAt(root string, getenv func(string) string) bool {
+	// WSL sets these for every process and registers its interop handler with binfmt_misc
+	if getenv("WSL_DISTRO_NAME") != "" || getenv("WSL_INTEROP") != "" {
+		return true
+	}
+	if _, err := os.Stat(filepath.Join(root, "proc/sys/fs/binfmt_misc/WSLInterop")); err == nil {
+		return true
+	}
+	// #nosec G304 -- a fixed path under the root being inspected
+	if version, err := os.ReadFile(filepath.Join(root, "proc/version")); err == nil &&
+		strings.Contains(strings.ToLower(string(version)), "microsoft") {
+		return true
+	}
+	// systemd inside WSL records it as its container
+	// #nosec G304 -- a fixed path under the root being inspected
+	data, err := os.ReadFile(filepath.Join(root, "run/systemd/container"))
+	return err == nil && strings.TrimSpace(string(data)) == "wsl"
+}
+
+// detectEnvironmentAt classifies where Nix runs from the markers under root ("/" outside tests),
+// the environment variables and the operating system. The runtime names the container manager,
+// such as "systemd-nspawn" for nixos-container, when the environment is a container.
+func detectEnvironmentAt(root string, getenv func(string) string, goos string) (environment, runtime string) {
+	if goos == "darwin" {
+		return EnvironmentDarwin, ""
+	}
+
+	if isWSLAt(root, getenv) {
+		return EnvironmentWSL, ""
+	}
+
+	// systemd records the container it runs in, e.g. systemd-nspawn inside nixos-container
+	// #nosec G304 -- a fixed path under the root being inspected
+	if data, err := os.ReadFile(filepath.Join(root, "run/systemd/container")); err == nil {
+		if manager := strings.TrimSpace(string(data)); manager != "" {
+			return EnvironmentContainer, manager
+		}
+	}
+	if manager := getenv("container"); manager != "" {
+		return EnvironmentContainer, manager
+	}
+	for _, marker := range containerMarkers {
+		if _, err := os.Stat(filepath.Join(root, marker.path)); err == nil {
+			return EnvironmentContainer, marker.runtime
+		}
+	}
+	return EnvironmentStandard, ""
+}
+
+// detectEnvironment records whether Nix runs in a container, under WSL, on macOS or on a
+// standard machine, which decides what advice applies, e.g. no bootloader changes in a container
+func (cd *ContextDetector) detectEnvironment(context *config.NixOSContext) {
+	context.Environment, context.ContainerRuntime = detectEnvironmentAt("/", os.Getenv, runtime.GOOS)
+	if context.ContainerRuntime != "" {
+		cd.logger.Debug("Detected environment: " + context.Environment + " (" + context.ContainerRuntime + ")")
+		return
+	}
+	cd.logger.Debug("Detected environment: " + context.Environment)
+}
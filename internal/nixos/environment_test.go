@@ -0,0 +1,54 @@
+package nixos
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fixtureRoot creates a filesystem root holding the given files
+func fixtureRoot(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestDetectEnvironmentAt(t *testing.T) {
+	const linuxVersion = "Linux version 6.6.30 (nixbld@localhost) (gcc (GCC) 13.2.0) #1-NixOS SMP PREEMPT_DYNAMIC\n"
+	tests := []struct {
+		name        string
+		files       map[string]string
+		env         map[string]string
+		goos        string
+		environment string
+		runtime     string
+	}{
+		{"standard NixOS", map[string]string{"proc/version": linuxVersion, "etc/NIXOS": ""}, nil, "linux", EnvironmentStandard, ""},
+		{"nixos-container", map[string]string{"proc/version": linuxVersion, "run/systemd/container": "systemd-nspawn\n"}, nil, "linux", EnvironmentContainer, "systemd-nspawn"},
+		{"container from environment", nil, map[string]string{"container": "lxc"}, "linux", EnvironmentContainer, "lxc"},
+		{"docker", map[string]string{".dockerenv": ""}, nil, "linux", EnvironmentContainer, "docker"},
+		{"podman", map[string]string{"run/.containerenv": "engine=\"podman-4.9.3\"\n"}, nil, "linux", EnvironmentContainer, "podman"},
+		{"WSL from environment", nil, map[string]string{"WSL_DISTRO_NAME": "NixOS"}, "linux", EnvironmentWSL, ""},
+		{"WSL interop handler", map[string]string{"proc/sys/fs/binfmt_misc/WSLInterop": "enabled\n"}, nil, "linux", EnvironmentWSL, ""},
+		{"WSL kernel", map[string]string{"proc/version": "Linux version 5.15.153.1-microsoft-standard-WSL2 (root@941d701f84f1)\n"}, nil, "linux", EnvironmentWSL, ""},
+		{"WSL with systemd", map[string]string{"run/systemd/container": "wsl\n"}, nil, "linux", EnvironmentWSL, ""},
+		{"macOS", map[string]string{".dockerenv": ""}, nil, "darwin", EnvironmentDarwin, ""},
+	}
+	for _, tt := range tests {
+		root := fixtureRoot(t, tt.files)
+		getenv := func(name string) string { return tt.env[name] }
+		environment, runtime := detectEnvironmentAt(root, getenv, tt.goos)
+		if environment != tt.environment || runtime != tt.runtime {
+			t.Errorf("%s: got %q (%q), want %q (%q)", tt.name, environment, runtime, tt.environment, tt.runtime)
+		}
+	}
+}
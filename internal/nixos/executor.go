@@ -2,16 +2,25 @@ package nixos
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
+	"sort"
 	"strings"
+
+	"nix-ai-help/pkg/utils"
 )
 
 // Executor provides functionality to execute local commands related to NixOS configuration.
 type Executor struct {
 	ConfigPath string
+	// LookPath finds a command on PATH; nil uses exec.LookPath
+	LookPath func(file string) (string, error)
 }
 
+// ErrNixNotFound is returned by CheckNix when the nix command is not installed or not on PATH.
+var ErrNixNotFound = errors.New("nix not found on PATH")
+
 // NewExecutor creates a new instance of Executor with an optional config path.
 func NewExecutor(configPath string) *Executor {
 	return &Executor{ConfigPath: configPath}
@@ -23,18 +32,112 @@ func (e *Executor) ExecuteCommand(command string, args ...string) (string, error
 	if e.ConfigPath != "" {
 		cmd.Dir = e.ConfigPath
 	}
-	output, err := cmd.CombinedOutput()
+	output, err := utils.TraceCombinedOutput(cmd)
 	return string(output), err
 }
 
+// CheckNix returns ErrNixNotFound when the nix command cannot be found, as on a machine without
+// Nix or a CI runner that has not installed it yet.
+func (e *Executor) CheckNix() error {
+	lookPath := e.LookPath
+	if lookPath == nil {
+		lookPath = exec.LookPath
+	}
+	if _, err := lookPath("nix"); err != nil {
+		return ErrNixNotFound
+	}
+	return nil
+}
+
 // ExecuteNixCommand executes a NixOS specific command and returns the output.
 func (e *Executor) ExecuteNixCommand(command string) (string, error) {
 	return e.ExecuteCommand("nix", strings.Fields(command)...)
 }
 
+// NixPackage is a single package result from `nix search nixpkgs --json`.
+type NixPackage struct {
+	AttrPath    string   `json:"attrPath"`
+	Pname       string   `json:"pname"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Homepage    string   `json:"homepage"`
+	Version     string   `json:"version"`
+	Platforms   []string `json:"platforms"`
+}
+
+// DisplayName returns the package name, falling back to the full derivation name.
+func (p NixPackage) DisplayName() string {
+	if p.Pname != "" {
+		return p.Pname
+	}
+	return p.Name
+}
+
+// SearchPackages searches for Nix packages using `nix search nixpkgs <query> --json` and returns
+// the results sorted by attribute path. Multi-word queries fall back to fuzzy matching.
+func (e *Executor) SearchPackages(query string) ([]NixPackage, error) {
+	pkgs, _, err := e.searchPackageMap(query)
+	if err != nil {
+		return nil, err
+	}
+	return sortedPackages(pkgs), nil
+}
+
 // SearchNixPackages searches for Nix packages using `nix search nixpkgs <query> --json` and returns a parsed result.
 // Now supports fuzzy matching for multi-word queries.
 func (e *Executor) SearchNixPackages(query string) (string, error) {
+	pkgs, output, err := e.searchPackageMap(query)
+	if err != nil {
+		return output, err
+	}
+	return FormatPackageList(sortedPackages(pkgs)), nil
+}
+
+// sortedPackages returns the packages of a search sorted by attribute path
+func sortedPackages(pkgs map[string]NixPackage) []NixPackage {
+	results := make([]NixPackage, 0, len(pkgs))
+	for attr, pkg := range pkgs {
+		if pkg.AttrPath == "" {
+			pkg.AttrPath = attr
+		}
+		results = append(results, pkg)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].AttrPath < results[j].AttrPath })
+	return results
+}
+
+// FormatPackageList renders package search results as a colored list
+func FormatPackageList(pkgs []NixPackage) string {
+	// ANSI color codes
+	blue := "\033[1;34m"
+	reset := "\033[0m"
+	header := blue + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n"
+	header += "📦 Nixpkgs Package Results" + reset + "\n"
+	header += blue + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━" + reset + "\n"
+	var lines []string
+	lines = append(lines, header)
+	for _, pkg := range pkgs {
+		desc := pkg.Description
+		if desc == "" {
+			desc = pkg.Name
+		}
+		// Blue bullet for package
+		line := blue + "• " + pkg.DisplayName() + reset + " (" + pkg.AttrPath + ") - " + desc
+		if pkg.Version != "" {
+			line += " [v" + pkg.Version + "]"
+		}
+		if pkg.Homepage != "" {
+			line += "\n    " + blue + pkg.Homepage + reset
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, blue+"━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"+reset)
+	return strings.Join(lines, "\n")
+}
+
+// searchPackageMap runs the package search and returns the results keyed by attribute path
+// together with the raw command output.
+func (e *Executor) searchPackageMap(query string) (map[string]NixPackage, string, error) {
 	args := []string{"search", "nixpkgs", "--json"}
 	if strings.TrimSpace(query) != "" {
 		queryTerm := strings.TrimSpace(query)
@@ -42,29 +145,20 @@ func (e *Executor) SearchNixPackages(query string) (string, error) {
 	}
 	output, err := e.ExecuteCommand("nix", args...)
 	if err != nil {
-		return output, err
+		return nil, output, err
 	}
 	// Sanitize output: extract JSON object from first '{' to last '}'
 	start := strings.Index(output, "{")
 	end := strings.LastIndex(output, "}")
 	if start == -1 || end == -1 || end <= start {
-		return output, fmt.Errorf("could not find JSON object in output")
+		return nil, output, fmt.Errorf("could not find JSON object in output")
 	}
 	jsonStr := output[start : end+1]
 	// Parse JSON output
-	type NixPkg struct {
-		AttrPath    string   `json:"attrPath"`
-		Pname       string   `json:"pname"`
-		Name        string   `json:"name"`
-		Description string   `json:"description"`
-		Homepage    string   `json:"homepage"`
-		Version     string   `json:"version"`
-		Platforms   []string `json:"platforms"`
-	}
-	var pkgs map[string]NixPkg
+	var pkgs map[string]NixPackage
 	err = json.Unmarshal([]byte(jsonStr), &pkgs)
 	if err != nil {
-		return output, err
+		return nil, output, err
 	}
 
 	// Fuzzy match: if no results and query has multiple words, try to match any word in name/description
@@ -76,7 +170,7 @@ func (e *Executor) SearchNixPackages(query string) (string, error) {
 			end := strings.LastIndex(allPkgsOut, "}")
 			if start != -1 && end != -1 && end > start {
 				allPkgsJson := allPkgsOut[start : end+1]
-				var allPkgs map[string]NixPkg
+				var allPkgs map[string]NixPackage
 				if json.Unmarshal([]byte(allPkgsJson), &allPkgs) == nil {
 					words := strings.Fields(strings.ToLower(query))
 					for attr, pkg := range allPkgs {
@@ -98,35 +192,7 @@ func (e *Executor) SearchNixPackages(query string) (string, error) {
 		}
 	}
 
-	// ANSI color codes
-	blue := "\033[1;34m"
-	reset := "\033[0m"
-	header := blue + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n"
-	header += "📦 Nixpkgs Package Results" + reset + "\n"
-	header += blue + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━" + reset + "\n"
-	var lines []string
-	lines = append(lines, header)
-	for attr, pkg := range pkgs {
-		desc := pkg.Description
-		if desc == "" {
-			desc = pkg.Name
-		}
-		name := pkg.Pname
-		if name == "" {
-			name = pkg.Name
-		}
-		// Blue bullet for package
-		line := blue + "• " + name + reset + " (" + attr + ") - " + desc
-		if pkg.Version != "" {
-			line += " [v" + pkg.Version + "]"
-		}
-		if pkg.Homepage != "" {
-			line += "\n    " + blue + pkg.Homepage + reset
-		}
-		lines = append(lines, line)
-	}
-	lines = append(lines, blue+"━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"+reset)
-	return strings.Join(lines, "\n"), nil
+	return pkgs, output, nil
 }
 
 // SearchNixPackagesForAutocomplete searches for Nix packages using `nix search nixpkgs <query> --json` and returns a list of package names for autocomplete.
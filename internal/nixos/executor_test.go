@@ -1,6 +1,8 @@
 package nixos
 
 import (
+	"errors"
+	osexec "os/exec"
 	"strings"
 	"testing"
 )
@@ -33,3 +35,24 @@ func TestListServiceOptions_Command(t *testing.T) {
 		t.Logf("ListServiceOptions returned error (expected if nixos-option missing): %v", err)
 	}
 }
+
+func TestCheckNix(t *testing.T) {
+	missing := &Executor{LookPath: func(file string) (string, error) {
+		return "", &osexec.Error{Name: file, Err: osexec.ErrNotFound}
+	}}
+	if err := missing.CheckNix(); !errors.Is(err, ErrNixNotFound) {
+		t.Errorf("CheckNix without nix = %v, want ErrNixNotFound", err)
+	}
+
+	var looked string
+	found := &Executor{LookPath: func(file string) (string, error) {
+		looked = file
+		return "/run/current-system/sw/bin/" + file, nil
+	}}
+	if err := found.CheckNix(); err != nil {
+		t.Errorf("CheckNix with nix = %v, want nil", err)
+	}
+	if looked != "nix" {
+		t.Errorf("CheckNix looked up %q, want nix", looked)
+	}
+}
@@ -0,0 +1,97 @@
+package nixos
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"nix-ai-help/pkg/logger"
+)
+
+// LogFinding is an issue detected in a single log entry
+type LogFinding struct {
+	// Entry is the position of the log entry in the stream, starting at 1
+	Entry     int    `json:"entry"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Unit      string `json:"unit,omitempty"`
+	Level     string `json:"level,omitempty"`
+	ErrorType string `json:"error_type"`
+	Severity  string `json:"severity"`
+	Issue     string `json:"issue"`
+	Message   string `json:"message"`
+}
+
+// severityRank orders severities from most to least severe
+var severityRank = map[string]int{"critical": 0, "high": 1, "medium": 2, "low": 3}
+
+// errorLevelRegex matches the log levels of entries that report an error
+var errorLevelRegex = regexp.MustCompile(`(?i)^(err|error|crit|critical|alert|emerg|fatal)$`)
+
+// orderedErrorPatterns sorts error patterns most severe first, then by name, so that an entry
+// matching several patterns is always reported under the same one
+func orderedErrorPatterns(patterns map[string]ErrorPattern) []ErrorPattern {
+	names := make([]string, 0, len(patterns))
+	for name := range patterns {
+		names = append(names, name)
+	}
+	rank := func(name string) int {
+		if r, ok := severityRank[patterns[name].Severity]; ok {
+			return r
+		}
+		return len(severityRank)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if rank(names[i]) != rank(names[j]) {
+			return rank(names[i]) < rank(names[j])
+		}
+		return names[i] < names[j]
+	})
+
+	ordered := make([]ErrorPattern, 0, len(names))
+	for _, name := range names {
+		ordered = append(ordered, patterns[name])
+	}
+	return ordered
+}
+
+// findingForEntry classifies a log entry with the first matching pattern. Entries that match
+// no pattern but report an error are unclassified findings; other entries are no finding.
+func findingForEntry(patterns []ErrorPattern, entry LogEntry, index int) (LogFinding, bool) {
+	finding := LogFinding{
+		Entry:     index,
+		Timestamp: entry.Timestamp,
+		Unit:      strings.TrimSpace(entry.Unit),
+		Level:     entry.Level,
+		Message:   entry.Message,
+	}
+	for _, pattern := range patterns {
+		if pattern.Pattern.MatchString(entry.Message) {
+			finding.ErrorType, finding.Severity, finding.Issue = pattern.ErrorType, pattern.Severity, pattern.Description
+			return finding, true
+		}
+	}
+	if errorLevelRegex.MatchString(entry.Level) || strings.Contains(strings.ToLower(entry.Message), "error") {
+		finding.ErrorType, finding.Severity, finding.Issue = "generic", "medium", "Unclassified error detected"
+		return finding, true
+	}
+	return LogFinding{}, false
+}
+
+// StreamLogFindings classifies log entries as they arrive and emits a finding for each entry
+// that reports an issue, without waiting for the end of the log. The output channel is
+// closed once entries is closed.
+func StreamLogFindings(entries <-chan LogEntry) <-chan LogFinding {
+	patterns := orderedErrorPatterns(loadErrorPatterns(logger.NewLogger()))
+	output := make(chan LogFinding)
+	go func() {
+		defer close(output)
+		index := 0
+		for entry := range entries {
+			index++
+			if finding, ok := findingForEntry(patterns, entry, index); ok {
+				output <- finding
+			}
+		}
+	}()
+	return output
+}
@@ -0,0 +1,93 @@
+package nixos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// FlakeLock is a parsed flake.lock
+type FlakeLock struct {
+	Root  string                   `json:"root"`
+	Nodes map[string]FlakeLockNode `json:"nodes"`
+}
+
+// FlakeLockNode is an input of a flake.lock; its inputs map to a node name or to the input
+// path it follows
+type FlakeLockNode struct {
+	Inputs   map[string]json.RawMessage `json:"inputs"`
+	Locked   *FlakeLockInput            `json:"locked"`
+	Original struct {
+		Type string `json:"type"`
+		Ref  string `json:"ref"`
+		URL  string `json:"url"`
+	} `json:"original"`
+}
+
+// FlakeLockInput is the locked revision of one flake.lock node
+type FlakeLockInput struct {
+	Rev          string `json:"rev"`
+	NarHash      string `json:"narHash"`
+	LastModified int64  `json:"lastModified"`
+}
+
+// ParseFlakeLock parses the contents of a flake.lock
+func ParseFlakeLock(data []byte) (*FlakeLock, error) {
+	var lock FlakeLock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("invalid flake.lock: %w", err)
+	}
+	if lock.Root == "" {
+		lock.Root = "root"
+	}
+	return &lock, nil
+}
+
+// LockedInputs returns the locked inputs of the flake keyed by node name
+func (l *FlakeLock) LockedInputs() map[string]FlakeLockInput {
+	inputs := make(map[string]FlakeLockInput)
+	for name, node := range l.Nodes {
+		if name == l.Root || node.Locked == nil {
+			continue
+		}
+		inputs[name] = *node.Locked
+	}
+	return inputs
+}
+
+// Resolve follows an input path from the root of the flake.lock, such as ["nixpkgs"], to the
+// name of its node
+func (l *FlakeLock) Resolve(path []string) (string, error) {
+	return l.resolve(path, 0)
+}
+
+// resolve follows an input path; depth counts the follows already taken so that a malformed
+// lock cannot loop forever
+func (l *FlakeLock) resolve(path []string, depth int) (string, error) {
+	if depth > len(l.Nodes) {
+		return "", errors.New("flake.lock inputs follow each other in a cycle")
+	}
+	name := l.Root
+	for _, input := range path {
+		raw, ok := l.Nodes[name].Inputs[input]
+		if !ok {
+			return "", fmt.Errorf("flake.lock has no %s input", input)
+		}
+		var next string
+		if err := json.Unmarshal(raw, &next); err == nil {
+			name = next
+			continue
+		}
+		// A followed input names the path of the input it follows, starting from the root
+		var follows []string
+		if err := json.Unmarshal(raw, &follows); err != nil {
+			return "", fmt.Errorf("invalid %s input in flake.lock: %w", input, err)
+		}
+		resolved, err := l.resolve(follows, depth+1)
+		if err != nil {
+			return "", err
+		}
+		name = resolved
+	}
+	return name, nil
+}
@@ -0,0 +1,103 @@
+package nixos
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxImportDepth bounds how deep nested `imports = [ ... ]` lists are followed
+const maxImportDepth = 10
+
+var (
+	importsListRegex = regexp.MustCompile(`(?s)imports\s*=\s*\[(.*?)\]`)
+	commentRegex     = regexp.MustCompile(`(?m)#.*$`)
+)
+
+// ImportTree is the set of configuration files reachable through `imports` from a root file
+type ImportTree struct {
+	// Files lists every readable file in discovery order, starting with the root
+	Files []string
+	// Contents holds the content of each file, keyed by path
+	Contents map[string]string
+	// Cycles lists "from -> to" edges that import a file already being followed
+	Cycles []string
+	// Truncated is true when imports deeper than the depth limit were skipped
+	Truncated bool
+}
+
+// FollowImports reads root and follows its `imports = [ ... ]` path entries recursively,
+// up to maxDepth levels. Each file is read once, so import cycles terminate.
+func FollowImports(root string, maxDepth int) *ImportTree {
+	tree := &ImportTree{Contents: make(map[string]string)}
+	inProgress := make(map[string]bool)
+
+	var follow func(path string, depth int)
+	follow = func(path string, depth int) {
+		// #nosec G304 -- paths come from the user's own NixOS configuration
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return
+		}
+		tree.Files = append(tree.Files, path)
+		tree.Contents[path] = string(content)
+
+		if depth >= maxDepth {
+			if len(importPaths(path, string(content))) > 0 {
+				tree.Truncated = true
+			}
+			return
+		}
+
+		inProgress[path] = true
+		defer delete(inProgress, path)
+
+		for _, imported := range importPaths(path, string(content)) {
+			if inProgress[imported] {
+				tree.Cycles = append(tree.Cycles, path+" -> "+imported)
+				continue
+			}
+			if _, seen := tree.Contents[imported]; seen {
+				continue
+			}
+			follow(imported, depth+1)
+		}
+	}
+
+	follow(filepath.Clean(root), 0)
+	return tree
+}
+
+// importPaths extracts the local file paths from the imports lists of a Nix file, resolved
+// against the file's directory. Directory imports resolve to their default.nix.
+func importPaths(file, content string) []string {
+	content = commentRegex.ReplaceAllString(content, "")
+	dir := filepath.Dir(file)
+
+	var paths []string
+	for _, list := range importsListRegex.FindAllStringSubmatch(content, -1) {
+		for _, token := range strings.Fields(list[1]) {
+			path := strings.Trim(token, "()")
+			if !strings.HasPrefix(path, "./") && !strings.HasPrefix(path, "../") && !strings.HasPrefix(path, "/") {
+				continue
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			path = filepath.Clean(path)
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				path = filepath.Join(path, "default.nix")
+			}
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// importsHomeManager reports whether a Nix file imports Home Manager as a NixOS module
+func importsHomeManager(content string) bool {
+	return strings.Contains(content, "home-manager.nixosModules") ||
+		strings.Contains(content, "home-manager/nixos") ||
+		strings.Contains(content, "home-manager.users.")
+}
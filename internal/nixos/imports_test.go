@@ -0,0 +1,112 @@
+package nixos
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/logger"
+)
+
+// writeImportFixture creates a config tree with nested imports and a cycle back to configuration.nix
+func writeImportFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"configuration.nix": `{ config, pkgs, ... }:
+{
+  imports = [
+    ./hardware-configuration.nix
+    ./modules
+    <home-manager/nixos>
+    # ./disabled.nix
+  ];
+  services.openssh.enable = true;
+}`,
+		"hardware-configuration.nix": `{ ... }: { boot.loader.grub.enable = true; }`,
+		"modules/default.nix": `{ ... }:
+{
+  imports = [ ./services.nix ../configuration.nix ];
+}`,
+		"modules/services.nix": `{ ... }:
+{
+  services.nginx.enable = true;
+  services.openssh.enable = true;
+}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFollowImports_NestedWithCycle(t *testing.T) {
+	dir := writeImportFixture(t)
+	tree := FollowImports(filepath.Join(dir, "configuration.nix"), maxImportDepth)
+
+	want := []string{
+		filepath.Join(dir, "configuration.nix"),
+		filepath.Join(dir, "hardware-configuration.nix"),
+		filepath.Join(dir, "modules", "default.nix"),
+		filepath.Join(dir, "modules", "services.nix"),
+	}
+	if strings.Join(tree.Files, ",") != strings.Join(want, ",") {
+		t.Errorf("Files = %v, want %v", tree.Files, want)
+	}
+	if len(tree.Cycles) != 1 || !strings.HasSuffix(tree.Cycles[0], "-> "+want[0]) {
+		t.Errorf("expected one cycle back to configuration.nix, got %v", tree.Cycles)
+	}
+	if tree.Truncated {
+		t.Error("tree should not be truncated")
+	}
+	if !importsHomeManager(tree.Contents[want[0]]) {
+		t.Error("expected Home Manager module import to be detected")
+	}
+}
+
+func TestFollowImports_DepthLimit(t *testing.T) {
+	dir := writeImportFixture(t)
+	tree := FollowImports(filepath.Join(dir, "configuration.nix"), 1)
+
+	if !tree.Truncated {
+		t.Error("expected tree to be truncated at depth 1")
+	}
+	for _, file := range tree.Files {
+		if strings.HasSuffix(file, "services.nix") {
+			t.Errorf("services.nix is beyond the depth limit but was followed")
+		}
+	}
+}
+
+func TestContextDetector_ServicesFromImports(t *testing.T) {
+	dir := writeImportFixture(t)
+	cd := NewContextDetector(logger.NewTestLogger())
+	ctx := &config.NixOSContext{}
+
+	cd.detectConfigurationFiles(ctx, &config.UserConfig{NixosFolder: dir})
+	cd.detectEnabledServices(ctx)
+
+	if got := strings.Join(ctx.EnabledServices, ","); got != "openssh,nginx" {
+		t.Errorf("EnabledServices = %q, want openssh,nginx", got)
+	}
+	if !containsString(ctx.ConfigurationFiles, filepath.Join(dir, "modules", "services.nix")) {
+		t.Errorf("imported module missing from ConfigurationFiles: %v", ctx.ConfigurationFiles)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
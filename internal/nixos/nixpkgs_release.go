@@ -0,0 +1,85 @@
+package nixos
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"nix-ai-help/internal/config"
+)
+
+// UnstableRelease is the release name of nixos-unstable and nixpkgs-unstable
+const UnstableRelease = "unstable"
+
+// nixosVersionPattern matches the release at the start of nixos-version output, such as
+// "24.05.20240601.abcdef0 (Uakari)"; a "pre" suffix marks a system built from unstable
+var nixosVersionPattern = regexp.MustCompile(`^(\d{2}\.\d{2})(pre)?`)
+
+// nixpkgsBranchPattern matches a nixpkgs branch or channel name such as nixos-24.05,
+// nixpkgs-unstable or release-24.05
+var nixpkgsBranchPattern = regexp.MustCompile(`(?:nixos|nixpkgs|release)-(\d{2}\.\d{2}|unstable)(?:-small)?\b`)
+
+// ReleaseFromNixOSVersion returns the release ("24.05" or "unstable") of nixos-version output,
+// or "" when the output is not recognized
+func ReleaseFromNixOSVersion(output string) string {
+	match := nixosVersionPattern.FindStringSubmatch(output)
+	switch {
+	case match == nil:
+		return ""
+	case match[2] != "":
+		return UnstableRelease
+	default:
+		return match[1]
+	}
+}
+
+// ReleaseFromFlakeLock returns the release ("24.05" or "unstable") of the nixpkgs input of a
+// flake.lock. It returns "" when nixpkgs is pinned to something other than a release branch,
+// such as a commit or a fork's branch.
+func ReleaseFromFlakeLock(data []byte) (string, error) {
+	lock, err := ParseFlakeLock(data)
+	if err != nil {
+		return "", err
+	}
+	name, err := lock.Resolve([]string{"nixpkgs"})
+	if err != nil {
+		return "", err
+	}
+
+	original := lock.Nodes[name].Original
+	if (original.Ref == "" && original.URL == "") || original.Ref == "master" {
+		// An unpinned github:NixOS/nixpkgs or registry nixpkgs follows the unstable branch
+		return UnstableRelease, nil
+	}
+	for _, ref := range []string{original.Ref, original.URL} {
+		if match := nixpkgsBranchPattern.FindStringSubmatch(ref); match != nil {
+			return match[1], nil
+		}
+	}
+	return "", nil
+}
+
+// detectNixpkgsRelease records which nixpkgs release the configuration is built from: the
+// nixpkgs input of the flake.lock next to flake.nix, or the release of the running system
+func (cd *ContextDetector) detectNixpkgsRelease(context *config.NixOSContext) {
+	if context.FlakeFile != "" {
+		lockPath := filepath.Join(filepath.Dir(context.FlakeFile), "flake.lock")
+		// #nosec G304 -- lockPath is next to the flake found during detection
+		if data, err := os.ReadFile(lockPath); err == nil {
+			release, err := ReleaseFromFlakeLock(data)
+			if err != nil {
+				cd.logger.Debug("Could not read the nixpkgs release from " + lockPath + ": " + err.Error())
+			}
+			if release != "" {
+				context.NixpkgsRelease = release
+				cd.logger.Debug("Detected nixpkgs release from flake.lock: " + release)
+				return
+			}
+		}
+	}
+
+	if release := ReleaseFromNixOSVersion(context.NixOSVersion); release != "" {
+		context.NixpkgsRelease = release
+		cd.logger.Debug("Detected nixpkgs release from nixos-version: " + release)
+	}
+}
@@ -0,0 +1,131 @@
+package nixos
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nix-ai-help/internal/config"
+	"nix-ai-help/pkg/logger"
+)
+
+func TestReleaseFromNixOSVersion(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"24.05.20240601.abcdef0 (Uakari)\n", "24.05"},
+		{"23.11.7870.205fd4226592 (Tapir)", "23.11"},
+		{"24.11pre654321.abcdef0 (Vicuna)", "unstable"},
+		{"25.05pre-git (Warbler)", "unstable"},
+		{"", ""},
+		{"nix (Nix) 2.18.1", ""},
+	}
+	for _, tt := range tests {
+		if got := ReleaseFromNixOSVersion(tt.output); got != tt.want {
+			t.Errorf("ReleaseFromNixOSVersion(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
+
+// flakeLockWithNixpkgs returns a flake.lock whose nixpkgs input has the given original
+func flakeLockWithNixpkgs(original string) string {
+	return `{
+  "nodes": {
+    "nixpkgs": {
+      "locked": {"lastModified": 1717000000, "narHash": "sha256-x", "owner": "NixOS", "repo": "nixpkgs", "rev": "abcdef0", "type": "github"},
+      "original": ` + original + `
+    },
+    "root": {"inputs": {"nixpkgs": "nixpkgs"}}
+  },
+  "root": "root",
+  "version": 7
+}`
+}
+
+func TestReleaseFromFlakeLock(t *testing.T) {
+	tests := []struct {
+		name string
+		lock string
+		want string
+	}{
+		{"release branch", flakeLockWithNixpkgs(`{"owner": "NixOS", "ref": "nixos-24.05", "repo": "nixpkgs", "type": "github"}`), "24.05"},
+		{"small channel", flakeLockWithNixpkgs(`{"owner": "NixOS", "ref": "nixos-23.11-small", "repo": "nixpkgs", "type": "github"}`), "23.11"},
+		{"unstable branch", flakeLockWithNixpkgs(`{"owner": "NixOS", "ref": "nixos-unstable", "repo": "nixpkgs", "type": "github"}`), "unstable"},
+		{"default branch", flakeLockWithNixpkgs(`{"owner": "NixOS", "repo": "nixpkgs", "type": "github"}`), "unstable"},
+		{"channel tarball", flakeLockWithNixpkgs(`{"type": "tarball", "url": "https://channels.nixos.org/nixos-24.11/nixexprs.tar.xz"}`), "24.11"},
+		{"pinned commit", flakeLockWithNixpkgs(`{"owner": "me", "ref": "my-fixes", "repo": "nixpkgs", "type": "github"}`), ""},
+		{"followed input", `{
+  "nodes": {
+    "base": {"inputs": {"nixpkgs": "nixpkgs_2"}, "original": {"owner": "me", "repo": "base", "type": "github"}},
+    "nixpkgs_2": {"original": {"owner": "NixOS", "ref": "nixos-24.05", "repo": "nixpkgs", "type": "github"}},
+    "root": {"inputs": {"base": "base", "nixpkgs": ["base", "nixpkgs"]}}
+  },
+  "root": "root",
+  "version": 7
+}`, "24.05"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReleaseFromFlakeLock([]byte(tt.lock))
+			if err != nil {
+				t.Fatalf("ReleaseFromFlakeLock: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("release = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleaseFromFlakeLockErrors(t *testing.T) {
+	for name, lock := range map[string]string{
+		"invalid json":     `{"nodes": `,
+		"no nixpkgs input": `{"nodes": {"root": {"inputs": {}}}, "root": "root"}`,
+		"follows cycle":    `{"nodes": {"a": {"inputs": {"nixpkgs": ["nixpkgs"]}}, "root": {"inputs": {"a": "a", "nixpkgs": ["a", "nixpkgs"]}}}, "root": "root"}`,
+	} {
+		if _, err := ReleaseFromFlakeLock([]byte(lock)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestDetectNixpkgsRelease(t *testing.T) {
+	cd := NewContextDetector(logger.NewLoggerWithWriter(io.Discard))
+
+	t.Run("flake", func(t *testing.T) {
+		dir := t.TempDir()
+		flake := filepath.Join(dir, "flake.nix")
+		lock := flakeLockWithNixpkgs(`{"owner": "NixOS", "ref": "nixos-24.11", "repo": "nixpkgs", "type": "github"}`)
+		if err := os.WriteFile(flake, []byte("{ }"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "flake.lock"), []byte(lock), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		// The flake.lock wins over the release of the running system
+		ctx := &config.NixOSContext{UsesFlakes: true, FlakeFile: flake, NixOSVersion: "24.05.20240601.abcdef0 (Uakari)"}
+		cd.detectNixpkgsRelease(ctx)
+		if ctx.NixpkgsRelease != "24.11" {
+			t.Errorf("NixpkgsRelease = %q, want 24.11", ctx.NixpkgsRelease)
+		}
+	})
+
+	t.Run("channels", func(t *testing.T) {
+		ctx := &config.NixOSContext{UsesChannels: true, NixOSVersion: "24.05.20240601.abcdef0 (Uakari)"}
+		cd.detectNixpkgsRelease(ctx)
+		if ctx.NixpkgsRelease != "24.05" {
+			t.Errorf("NixpkgsRelease = %q, want 24.05", ctx.NixpkgsRelease)
+		}
+	})
+
+	t.Run("flake without lock", func(t *testing.T) {
+		ctx := &config.NixOSContext{UsesFlakes: true, FlakeFile: filepath.Join(t.TempDir(), "flake.nix"), NixOSVersion: "24.11pre654321.abcdef0 (Vicuna)"}
+		cd.detectNixpkgsRelease(ctx)
+		if ctx.NixpkgsRelease != "unstable" {
+			t.Errorf("NixpkgsRelease = %q, want unstable", ctx.NixpkgsRelease)
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package nixos
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"nix-ai-help/internal/config"
+)
+
+// defaultConfigurationNix is the configuration nixos-rebuild uses without -I nixos-config
+const defaultConfigurationNix = "/etc/nixos/configuration.nix"
+
+// defaultProfileName is the system profile nixos-rebuild uses without --profile-name
+const defaultProfileName = "system"
+
+// profileNameRegex matches the names nixos-rebuild accepts for --profile-name, which become
+// a link under /nix/var/nix/profiles/system-profiles
+var profileNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// rebuildHostname returns the host name used to select the flake output; replaced in tests
+var rebuildHostname = os.Hostname
+
+// privilegedRebuildActions are the nixos-rebuild actions that activate or register a
+// generation and therefore need root
+var privilegedRebuildActions = map[string]bool{
+	"switch": true,
+	"boot":   true,
+	"test":   true,
+}
+
+// RebuildOptions adjusts the nixos-rebuild invocation built from a context
+type RebuildOptions struct {
+	// Host is the machine to build and activate over SSH; empty means this machine
+	Host string
+	// Profile is the system profile to use instead of the default system profile
+	Profile string
+}
+
+// RebuildCommand returns the nixos-rebuild invocation for action (switch, boot, test,
+// dry-build, ...) matching the detected context: `--flake <dir>#<host>` for flake-based
+// systems and `-I nixos-config=<file>` for legacy systems using a non-default configuration.
+// A nil context yields the plain legacy command.
+func RebuildCommand(ctx *config.NixOSContext, action string) []string {
+	return RebuildCommandWithOptions(ctx, action, RebuildOptions{})
+}
+
+// RebuildCommandWithOptions is RebuildCommand with a target host and system profile. For
+// another host the flake output of that host is built and activated on it over SSH.
+func RebuildCommandWithOptions(ctx *config.NixOSContext, action string, opts RebuildOptions) []string {
+	args := []string{"nixos-rebuild", action}
+	if opts.Profile != "" && opts.Profile != defaultProfileName {
+		args = append(args, "--profile-name", opts.Profile)
+	}
+	if ctx != nil && (ctx.UsesFlakes || ctx.FlakeFile != "") {
+		args = append(args, "--flake", flakeReference(ctx, opts.Host))
+	} else if ctx != nil && ctx.ConfigurationNix != "" && ctx.ConfigurationNix != defaultConfigurationNix {
+		args = append(args, "-I", "nixos-config="+ctx.ConfigurationNix)
+	}
+	if opts.Host != "" {
+		args = append(args, "--target-host", opts.Host, "--use-remote-sudo")
+	}
+	return args
+}
+
+// RebuildCommandLine returns RebuildCommand as a shell command line, prefixed with sudo for
+// actions that need root
+func RebuildCommandLine(ctx *config.NixOSContext, action string) string {
+	return RebuildCommandLineWithOptions(ctx, action, RebuildOptions{})
+}
+
+// RebuildCommandLineWithOptions returns RebuildCommandWithOptions as a shell command line.
+// Remote hosts get --use-remote-sudo instead of a sudo prefix.
+func RebuildCommandLineWithOptions(ctx *config.NixOSContext, action string, opts RebuildOptions) string {
+	line := strings.Join(RebuildCommandWithOptions(ctx, action, opts), " ")
+	if privilegedRebuildActions[action] && opts.Host == "" {
+		return "sudo " + line
+	}
+	return line
+}
+
+// ValidateProfileName checks that name can be used as a nixos-rebuild profile name
+func ValidateProfileName(name string) error {
+	if !profileNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// ContextForConfigPath builds a minimal context for callers that only know where the
+// configuration lives: a directory is flake-based when it holds a flake.nix, a flake.nix file
+// selects its flake, and any other file is used as the legacy configuration
+func ContextForConfigPath(path string) *config.NixOSContext {
+	ctx := &config.NixOSContext{}
+	if path == "" {
+		return ctx
+	}
+
+	flake := filepath.Join(path, "flake.nix")
+	info, err := os.Stat(path)
+	if (err == nil && !info.IsDir()) || (err != nil && strings.HasSuffix(path, ".nix")) {
+		ctx.NixOSConfigPath = filepath.Dir(path)
+		if filepath.Base(path) != "flake.nix" {
+			ctx.ConfigurationNix = path
+			return ctx
+		}
+		flake = path
+	} else {
+		ctx.NixOSConfigPath = path
+	}
+
+	if _, err := os.Stat(flake); err == nil {
+		ctx.UsesFlakes = true
+		ctx.FlakeFile = flake
+	}
+	return ctx
+}
+
+// flakeReference returns the flake reference selecting the configuration of host, or of this
+// machine when host is empty, e.g. /etc/nixos#myhost
+func flakeReference(ctx *config.NixOSContext, host string) string {
+	dir := "."
+	switch {
+	case ctx.FlakeFile != "":
+		dir = filepath.Dir(ctx.FlakeFile)
+	case ctx.NixOSConfigPath != "":
+		dir = ctx.NixOSConfigPath
+	}
+	if host == "" {
+		host, _ = rebuildHostname()
+	}
+	if host == "" {
+		return dir
+	}
+	return dir + "#" + host
+}
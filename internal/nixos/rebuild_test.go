@@ -0,0 +1,126 @@
+package nixos
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"nix-ai-help/internal/config"
+)
+
+func stubRebuildHostname(t *testing.T, host string) {
+	t.Helper()
+	original := rebuildHostname
+	rebuildHostname = func() (string, error) { return host, nil }
+	t.Cleanup(func() { rebuildHostname = original })
+}
+
+func TestRebuildCommand(t *testing.T) {
+	stubRebuildHostname(t, "myhost")
+
+	tests := []struct {
+		name string
+		ctx  *config.NixOSContext
+		want []string
+	}{
+		{"no context", nil, []string{"nixos-rebuild", "switch"}},
+		{"legacy default configuration", &config.NixOSContext{ConfigurationNix: "/etc/nixos/configuration.nix"},
+			[]string{"nixos-rebuild", "switch"}},
+		{"legacy custom configuration", &config.NixOSContext{ConfigurationNix: "/home/me/nixos/configuration.nix"},
+			[]string{"nixos-rebuild", "switch", "-I", "nixos-config=/home/me/nixos/configuration.nix"}},
+		{"flake file", &config.NixOSContext{UsesFlakes: true, FlakeFile: "/etc/nixos/flake.nix"},
+			[]string{"nixos-rebuild", "switch", "--flake", "/etc/nixos#myhost"}},
+		{"flake without file", &config.NixOSContext{UsesFlakes: true, NixOSConfigPath: "/home/me/nixos"},
+			[]string{"nixos-rebuild", "switch", "--flake", "/home/me/nixos#myhost"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RebuildCommand(tt.ctx, "switch"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RebuildCommand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebuildCommandWithOptions(t *testing.T) {
+	stubRebuildHostname(t, "myhost")
+
+	flake := &config.NixOSContext{UsesFlakes: true, FlakeFile: "/etc/nixos/flake.nix"}
+	legacy := &config.NixOSContext{ConfigurationNix: "/home/me/nixos/configuration.nix"}
+	tests := []struct {
+		name string
+		ctx  *config.NixOSContext
+		opts RebuildOptions
+		want []string
+	}{
+		{"remote flake host", flake, RebuildOptions{Host: "server"},
+			[]string{"nixos-rebuild", "switch", "--flake", "/etc/nixos#server", "--target-host", "server", "--use-remote-sudo"}},
+		{"flake with profile", flake, RebuildOptions{Profile: "work"},
+			[]string{"nixos-rebuild", "switch", "--profile-name", "work", "--flake", "/etc/nixos#myhost"}},
+		{"legacy with profile", legacy, RebuildOptions{Profile: "work"},
+			[]string{"nixos-rebuild", "switch", "--profile-name", "work", "-I", "nixos-config=/home/me/nixos/configuration.nix"}},
+		{"default profile", nil, RebuildOptions{Profile: "system"}, []string{"nixos-rebuild", "switch"}},
+		{"remote host with profile", flake, RebuildOptions{Host: "server", Profile: "work"},
+			[]string{"nixos-rebuild", "switch", "--profile-name", "work", "--flake", "/etc/nixos#server", "--target-host", "server", "--use-remote-sudo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RebuildCommandWithOptions(tt.ctx, "switch", tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RebuildCommandWithOptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateProfileName(t *testing.T) {
+	for _, name := range []string{"work", "gaming-2", "test_profile.v1"} {
+		if err := ValidateProfileName(name); err != nil {
+			t.Errorf("ValidateProfileName(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "../system", "a/b", "-flag", "with space"} {
+		if err := ValidateProfileName(name); err == nil {
+			t.Errorf("ValidateProfileName(%q) accepted an invalid name", name)
+		}
+	}
+}
+
+func TestRebuildCommandLine(t *testing.T) {
+	stubRebuildHostname(t, "myhost")
+
+	flake := &config.NixOSContext{UsesFlakes: true, FlakeFile: "/etc/nixos/flake.nix"}
+	if got := RebuildCommandLine(flake, "boot"); got != "sudo nixos-rebuild boot --flake /etc/nixos#myhost" {
+		t.Errorf("boot = %q", got)
+	}
+	if got := RebuildCommandLine(nil, "dry-build"); got != "nixos-rebuild dry-build" {
+		t.Errorf("dry-build = %q", got)
+	}
+	if got := RebuildCommandLineWithOptions(flake, "switch", RebuildOptions{Host: "server", Profile: "work"}); got != "nixos-rebuild switch --profile-name work --flake /etc/nixos#server --target-host server --use-remote-sudo" {
+		t.Errorf("remote switch = %q", got)
+	}
+}
+
+func TestContextForConfigPath(t *testing.T) {
+	stubRebuildHostname(t, "myhost")
+
+	flakeDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(flakeDir, "flake.nix"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := RebuildCommand(ContextForConfigPath(flakeDir), "test"); !reflect.DeepEqual(got, []string{"nixos-rebuild", "test", "--flake", flakeDir + "#myhost"}) {
+		t.Errorf("flake directory: %v", got)
+	}
+
+	legacyDir := t.TempDir()
+	configuration := filepath.Join(legacyDir, "configuration.nix")
+	if err := os.WriteFile(configuration, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := RebuildCommand(ContextForConfigPath(configuration), "test"); !reflect.DeepEqual(got, []string{"nixos-rebuild", "test", "-I", "nixos-config=" + configuration}) {
+		t.Errorf("legacy configuration: %v", got)
+	}
+	if ctx := ContextForConfigPath(legacyDir); ctx.UsesFlakes {
+		t.Errorf("directory without flake.nix detected as flake")
+	}
+}
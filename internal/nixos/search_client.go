@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"nix-ai-help/pkg/logger"
+	"nix-ai-help/pkg/utils"
 )
 
 // SearchNixOSClient provides integration with search.nixos.org for package and option verification
@@ -59,11 +60,9 @@ type VerificationResult struct {
 // NewSearchNixOSClient creates a new client for search.nixos.org API
 func NewSearchNixOSClient() *SearchNixOSClient {
 	return &SearchNixOSClient{
-		baseURL: "https://search.nixos.org/",
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-		logger: logger.NewLogger(),
+		baseURL:    "https://search.nixos.org/",
+		httpClient: utils.NewHTTPClient(10 * time.Second),
+		logger:     logger.NewLogger(),
 	}
 }
 
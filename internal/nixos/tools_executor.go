@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"nix-ai-help/pkg/logger"
+	"nix-ai-help/pkg/utils"
 )
 
 // ToolsExecutor executes NixOS tools to validate answers in real-time
@@ -158,7 +159,7 @@ func (te *ToolsExecutor) checkPackageAvailability(ctx context.Context, packageNa
 
 	// Use nix search to check package availability
 	cmd := exec.CommandContext(ctx, "nix", "search", "nixpkgs", packageName, "--json")
-	output, err := cmd.Output()
+	output, err := utils.TraceOutput(cmd)
 	if err != nil {
 		result.Error = fmt.Sprintf("nix search failed: %v", err)
 		return result
@@ -182,7 +183,7 @@ func (te *ToolsExecutor) checkOptionValidity(ctx context.Context, optionName str
 
 	// Use nixos-option to check option validity
 	cmd := exec.CommandContext(ctx, "nixos-option", optionName)
-	output, err := cmd.Output()
+	output, err := utils.TraceOutput(cmd)
 	if err != nil {
 		result.Error = fmt.Sprintf("nixos-option failed: %v", err)
 		return result
@@ -207,7 +208,7 @@ func (te *ToolsExecutor) checkNixSyntax(ctx context.Context, expression string)
 	// Use nix-instantiate to check syntax
 	cmd := exec.CommandContext(ctx, "nix-instantiate", "--parse", "-")
 	cmd.Stdin = strings.NewReader(expression)
-	output, err := cmd.CombinedOutput()
+	output, err := utils.TraceCombinedOutput(cmd)
 
 	if err != nil {
 		result.Error = string(output)
@@ -239,7 +240,7 @@ func (te *ToolsExecutor) checkCommandAvailability(ctx context.Context, command s
 
 	// Check if command is available
 	cmd := exec.CommandContext(ctx, "which", binary)
-	err := cmd.Run()
+	err := utils.TraceRun(cmd)
 	if err == nil {
 		result.Available = true
 		result.Valid = true
@@ -422,7 +423,7 @@ func (te *ToolsExecutor) verifyPackageMetadata(ctx context.Context, packageName
 
 	// Method 1: nix-env -qaP (attribute path query)
 	cmd := exec.CommandContext(ctx, "nix-env", "-qaP", packageName)
-	if output, err := cmd.Output(); err == nil && len(output) > 0 {
+	if output, err := utils.TraceOutput(cmd); err == nil && len(output) > 0 {
 		metadata.Available = true
 		metadata.AttributePath = strings.TrimSpace(string(output))
 	}
@@ -431,14 +432,14 @@ func (te *ToolsExecutor) verifyPackageMetadata(ctx context.Context, packageName
 	if metadata.Available {
 		attrPath := fmt.Sprintf("nixpkgs#%s.meta.description", packageName)
 		cmd = exec.CommandContext(ctx, "nix", "eval", attrPath, "--raw")
-		if output, err := cmd.Output(); err == nil {
+		if output, err := utils.TraceOutput(cmd); err == nil {
 			metadata.Description = strings.TrimSpace(string(output))
 		}
 
 		// Get version information
 		versionPath := fmt.Sprintf("nixpkgs#%s.version", packageName)
 		cmd = exec.CommandContext(ctx, "nix", "eval", versionPath, "--raw")
-		if output, err := cmd.Output(); err == nil {
+		if output, err := utils.TraceOutput(cmd); err == nil {
 			metadata.Version = strings.TrimSpace(string(output))
 		}
 	}
@@ -455,7 +456,7 @@ func (te *ToolsExecutor) verifyOptionDetails(ctx context.Context, optionName str
 
 	// Method 1: nixos-option (current method)
 	cmd := exec.CommandContext(ctx, "nixos-option", optionName)
-	if output, err := cmd.Output(); err == nil {
+	if output, err := utils.TraceOutput(cmd); err == nil {
 		details.Valid = true
 		te.parseNixosOptionOutput(string(output), &details)
 	}
@@ -468,7 +469,7 @@ func (te *ToolsExecutor) verifyOptionDetails(ctx context.Context, optionName str
 	if details.Type == "" {
 		typeExpr := fmt.Sprintf("(import <nixpkgs/nixos> {}).options.%s.type", optionName)
 		cmd = exec.CommandContext(ctx, "nix-instantiate", "--eval", "-E", typeExpr)
-		if output, err := cmd.Output(); err == nil {
+		if output, err := utils.TraceOutput(cmd); err == nil {
 			details.Type = strings.Trim(strings.TrimSpace(string(output)), "\"")
 		}
 	}
@@ -477,7 +478,7 @@ func (te *ToolsExecutor) verifyOptionDetails(ctx context.Context, optionName str
 	if details.Default == "" {
 		defaultExpr := fmt.Sprintf("(import <nixpkgs/nixos> {}).options.%s.default or null", optionName)
 		cmd = exec.CommandContext(ctx, "nix-instantiate", "--eval", "-E", defaultExpr)
-		if output, err := cmd.Output(); err == nil {
+		if output, err := utils.TraceOutput(cmd); err == nil {
 			defaultVal := strings.TrimSpace(string(output))
 			if defaultVal != "null" {
 				details.Default = defaultVal
@@ -511,7 +512,7 @@ func (te *ToolsExecutor) validateConfigurationSyntax(ctx context.Context, config
 
 	// Method 1: Basic syntax check
 	cmd := exec.CommandContext(ctx, "nix-instantiate", "--parse", tmpFile.Name())
-	if output, err := cmd.CombinedOutput(); err != nil {
+	if output, err := utils.TraceCombinedOutput(cmd); err != nil {
 		result.Error = string(output)
 		return result
 	}
@@ -519,7 +520,7 @@ func (te *ToolsExecutor) validateConfigurationSyntax(ctx context.Context, config
 	// Method 2: Try to instantiate as NixOS configuration
 	nixosExpr := fmt.Sprintf("(import <nixpkgs/nixos> { configuration = %s; }).config.system.build.toplevel", tmpFile.Name())
 	cmd = exec.CommandContext(ctx, "nix-instantiate", "--eval", "-E", nixosExpr, "--show-trace")
-	if output, err := cmd.CombinedOutput(); err == nil {
+	if output, err := utils.TraceCombinedOutput(cmd); err == nil {
 		result.Valid = true
 		result.BuildPath = strings.TrimSpace(string(output))
 	} else {
@@ -554,7 +555,7 @@ func (te *ToolsExecutor) validateFlakeExpression(ctx context.Context, flakeConte
 
 	// Method 1: nix flake check (no build)
 	cmd := exec.CommandContext(ctx, "nix", "flake", "check", tmpDir, "--no-build")
-	if output, err := cmd.CombinedOutput(); err != nil {
+	if output, err := utils.TraceCombinedOutput(cmd); err != nil {
 		result.Error = string(output)
 		return result
 	}
@@ -563,13 +564,13 @@ func (te *ToolsExecutor) validateFlakeExpression(ctx context.Context, flakeConte
 
 	// Method 2: nix flake show (get outputs)
 	cmd = exec.CommandContext(ctx, "nix", "flake", "show", tmpDir, "--json")
-	if output, err := cmd.Output(); err == nil {
+	if output, err := utils.TraceOutput(cmd); err == nil {
 		result.Outputs = string(output)
 	}
 
 	// Method 3: nix flake metadata
 	cmd = exec.CommandContext(ctx, "nix", "flake", "metadata", tmpDir, "--json")
-	if output, err := cmd.Output(); err == nil {
+	if output, err := utils.TraceOutput(cmd); err == nil {
 		result.Metadata = string(output)
 	}
 
@@ -587,7 +588,7 @@ func (te *ToolsExecutor) verifyWithNixRepl(ctx context.Context, expression strin
 	cmd := exec.CommandContext(ctx, "nix", "repl", "<nixpkgs>")
 	cmd.Stdin = strings.NewReader(expression + "\n:q\n")
 
-	output, err := cmd.CombinedOutput()
+	output, err := utils.TraceCombinedOutput(cmd)
 	if err != nil {
 		result.Error = string(output)
 		return result
@@ -608,12 +609,11 @@ func (te *ToolsExecutor) performDryRunValidation(ctx context.Context, configPath
 
 	// Method 1: nixos-rebuild dry-build (if on NixOS)
 	if te.isNixOS() {
-		cmd := exec.CommandContext(ctx, "nixos-rebuild", "dry-build", "--fast", "--show-trace")
-		if configPath != "" {
-			cmd.Args = append(cmd.Args, "-I", fmt.Sprintf("nixos-config=%s", configPath))
-		}
+		args := append(RebuildCommand(ContextForConfigPath(configPath), "dry-build"), "--fast", "--show-trace")
+		// #nosec G204 -- Arguments are constructed internally from the configuration path
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
 
-		output, err := cmd.CombinedOutput()
+		output, err := utils.TraceCombinedOutput(cmd)
 		result.Output = string(output)
 
 		if err == nil {
@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"nix-ai-help/pkg/logger"
+	"nix-ai-help/pkg/utils"
 )
 
 // UpgradeInfo contains information about the current system and available upgrades
@@ -161,7 +162,7 @@ func (ua *UpgradeAdvisor) getCurrentVersion(ctx context.Context, info *UpgradeIn
 	// Get NixOS version
 	// #nosec G204 -- Arguments are constructed internally, not from user input
 	cmd := exec.CommandContext(ctx, "nixos-version")
-	output, err := cmd.Output()
+	output, err := utils.TraceOutput(cmd)
 	if err != nil {
 		return fmt.Errorf("failed to get nixos-version: %w", err)
 	}
@@ -184,7 +185,7 @@ func (ua *UpgradeAdvisor) getCurrentChannelInfo(ctx context.Context, info *Upgra
 	// Get current channel
 	// #nosec G204 -- Arguments are constructed internally, not from user input
 	cmd := exec.CommandContext(ctx, "nix-channel", "--list")
-	output, err := cmd.Output()
+	output, err := utils.TraceOutput(cmd)
 	if err != nil {
 		ua.logger.Warn("Failed to get nix channels: " + err.Error())
 		info.CurrentChannel = "unknown"
@@ -354,7 +355,7 @@ func (ua *UpgradeAdvisor) checkConfigurationFiles(ctx context.Context, info *Upg
 func (ua *UpgradeAdvisor) checkDiskSpace(ctx context.Context, info *UpgradeInfo) CheckResult {
 	// #nosec G204 -- Arguments are constructed internally, not from user input
 	cmd := exec.CommandContext(ctx, "df", "-h", "/nix")
-	output, err := cmd.Output()
+	output, err := utils.TraceOutput(cmd)
 	if err != nil {
 		return CheckResult{
 			Name:     "Disk Space Check",
@@ -449,18 +450,15 @@ func (ua *UpgradeAdvisor) checkDiskSpace(ctx context.Context, info *UpgradeInfo)
 
 // checkConfigValidity verifies NixOS configuration validity
 func (ua *UpgradeAdvisor) checkConfigValidity(ctx context.Context, info *UpgradeInfo) CheckResult {
+	args := append(RebuildCommand(ContextForConfigPath(ua.configPath), "dry-run"), "--fast")
+	// #nosec G204 -- Arguments are constructed internally, not from user input
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
 	// If we have a config path, run the check from that directory
-	var cmd *exec.Cmd
 	if ua.configPath != "" {
-		// #nosec G204 -- Arguments are constructed internally, not from user input
-		cmd = exec.CommandContext(ctx, "nixos-rebuild", "dry-run", "--fast")
 		cmd.Dir = ua.configPath
-	} else {
-		// #nosec G204 -- Arguments are constructed internally, not from user input
-		cmd = exec.CommandContext(ctx, "nixos-rebuild", "dry-run", "--fast")
 	}
 
-	output, err := cmd.CombinedOutput()
+	output, err := utils.TraceCombinedOutput(cmd)
 	if err != nil {
 		// Parse the error output to provide more specific suggestions
 		errorMsg := string(output)
@@ -496,7 +494,7 @@ func (ua *UpgradeAdvisor) checkConfigValidity(ctx context.Context, info *Upgrade
 func (ua *UpgradeAdvisor) checkRunningServices(ctx context.Context, info *UpgradeInfo) CheckResult {
 	// #nosec G204 -- Arguments are constructed internally, not from user input
 	cmd := exec.CommandContext(ctx, "systemctl", "list-units", "--failed", "--no-legend")
-	output, err := cmd.Output()
+	output, err := utils.TraceOutput(cmd)
 	if err != nil {
 		return CheckResult{
 			Name:     "Service Status Check",
@@ -529,7 +527,7 @@ func (ua *UpgradeAdvisor) checkRunningServices(ctx context.Context, info *Upgrad
 func (ua *UpgradeAdvisor) checkChannelUpdates(ctx context.Context, info *UpgradeInfo) CheckResult {
 	// #nosec G204 -- Arguments are constructed internally, not from user input
 	cmd := exec.CommandContext(ctx, "nix-channel", "--update", "--dry-run")
-	err := cmd.Run()
+	err := utils.TraceRun(cmd)
 	if err != nil {
 		return CheckResult{
 			Name:       "Channel Updates",
@@ -552,7 +550,7 @@ func (ua *UpgradeAdvisor) checkBootLoader(ctx context.Context, info *UpgradeInfo
 	// Check if systemd-boot or GRUB is configured
 	// #nosec G204 -- Arguments are constructed internally, not from user input
 	cmd := exec.CommandContext(ctx, "test", "-d", "/boot/loader")
-	err := cmd.Run()
+	err := utils.TraceRun(cmd)
 	if err == nil {
 		return CheckResult{
 			Name:    "Boot Loader Check",
@@ -563,7 +561,7 @@ func (ua *UpgradeAdvisor) checkBootLoader(ctx context.Context, info *UpgradeInfo
 
 	// #nosec G204 -- Arguments are constructed internally, not from user input
 	cmd = exec.CommandContext(ctx, "test", "-f", "/boot/grub/grub.cfg")
-	err = cmd.Run()
+	err = utils.TraceRun(cmd)
 	if err == nil {
 		return CheckResult{
 			Name:    "Boot Loader Check",
@@ -586,7 +584,7 @@ func (ua *UpgradeAdvisor) checkNetworkConnectivity(ctx context.Context, info *Up
 	// #nosec G204 -- Arguments are constructed internally, not from user input
 	cmd := exec.CommandContext(ctx, "curl", "-s", "--max-time", "10",
 		"https://cache.nixos.org/nix-cache-info")
-	err := cmd.Run()
+	err := utils.TraceRun(cmd)
 	if err != nil {
 		return CheckResult{
 			Name:       "Network Connectivity",
@@ -609,7 +607,7 @@ func (ua *UpgradeAdvisor) checkNixStoreIntegrity(ctx context.Context, info *Upgr
 	// #nosec G204 -- Arguments are constructed internally, not from user input
 	cmd := exec.CommandContext(ctx, "nix-store", "--verify", "--check-contents")
 	cmd.Env = append(cmd.Env, "NIX_STORE_CHECK_LIMIT=100") // Limit check for performance
-	err := cmd.Run()
+	err := utils.TraceRun(cmd)
 	if err != nil {
 		return CheckResult{
 			Name:       "Nix Store Integrity",
@@ -640,7 +638,7 @@ func (ua *UpgradeAdvisor) checkFlakeInputs(ctx context.Context, info *UpgradeInf
 	// Test if we can evaluate the flake without building
 	// #nosec G204 -- Arguments are constructed internally, not from user input
 	cmd := exec.CommandContext(ctx, "nix", "flake", "show", ua.configPath, "--no-build")
-	output, err := cmd.CombinedOutput()
+	output, err := utils.TraceCombinedOutput(cmd)
 	if err != nil {
 		errorMsg := string(output)
 		suggestion := "Check flake inputs and network connectivity"
@@ -737,7 +735,7 @@ func (ua *UpgradeAdvisor) getFlakeInputInfo(ctx context.Context, info *UpgradeIn
 	// Get flake inputs information
 	// #nosec G204 -- Arguments are constructed internally, not from user input
 	cmd := exec.CommandContext(ctx, "nix", "flake", "metadata", ua.configPath, "--json")
-	output, err := cmd.Output()
+	output, err := utils.TraceOutput(cmd)
 	if err != nil {
 		ua.logger.Warn("Failed to get flake metadata: " + err.Error())
 		return nil
@@ -774,6 +772,7 @@ func (ua *UpgradeAdvisor) generateBackupAdvice(info *UpgradeInfo) {
 // generateUpgradeSteps creates step-by-step upgrade instructions
 func (ua *UpgradeAdvisor) generateUpgradeSteps(info *UpgradeInfo) {
 	isFlake := ua.isFlakeBased()
+	rebuildCtx := ContextForConfigPath(ua.configPath)
 
 	var steps []UpgradeStep
 
@@ -790,7 +789,7 @@ func (ua *UpgradeAdvisor) generateUpgradeSteps(info *UpgradeInfo) {
 			},
 			{
 				Title:         "Test configuration",
-				Command:       "sudo nixos-rebuild test --flake .",
+				Command:       RebuildCommandLine(rebuildCtx, "test"),
 				Description:   "Test the new configuration without making it permanent",
 				Optional:      true,
 				Dangerous:     false,
@@ -798,7 +797,7 @@ func (ua *UpgradeAdvisor) generateUpgradeSteps(info *UpgradeInfo) {
 			},
 			{
 				Title:         "Switch to new generation",
-				Command:       "sudo nixos-rebuild switch --flake .",
+				Command:       RebuildCommandLine(rebuildCtx, "switch"),
 				Description:   "Apply the upgrade and switch to the new system generation",
 				Optional:      false,
 				Dangerous:     true,
@@ -834,7 +833,7 @@ func (ua *UpgradeAdvisor) generateUpgradeSteps(info *UpgradeInfo) {
 			},
 			{
 				Title:         "Test configuration",
-				Command:       "sudo nixos-rebuild test",
+				Command:       RebuildCommandLine(rebuildCtx, "test"),
 				Description:   "Test the new configuration without making it permanent",
 				Optional:      true,
 				Dangerous:     false,
@@ -842,7 +841,7 @@ func (ua *UpgradeAdvisor) generateUpgradeSteps(info *UpgradeInfo) {
 			},
 			{
 				Title:         "Switch to new generation",
-				Command:       "sudo nixos-rebuild switch",
+				Command:       RebuildCommandLine(rebuildCtx, "switch"),
 				Description:   "Apply the upgrade and switch to the new system generation",
 				Optional:      false,
 				Dangerous:     true,
@@ -867,13 +866,11 @@ func (ua *UpgradeAdvisor) generateUpgradeSteps(info *UpgradeInfo) {
 		}
 	}
 
-	// If we have a config path, adjust the commands to use it
+	// If we have a config path, update the flake inputs there
 	if ua.configPath != "" && isFlake {
 		for i := range steps {
 			if steps[i].Command == "nix flake update" {
 				steps[i].Command = fmt.Sprintf("cd %s && nix flake update", ua.configPath)
-			} else if strings.Contains(steps[i].Command, "--flake .") {
-				steps[i].Command = strings.Replace(steps[i].Command, "--flake .", fmt.Sprintf("--flake %s", ua.configPath), 1)
 			}
 		}
 	}
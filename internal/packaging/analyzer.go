@@ -49,6 +49,9 @@ type RepoAnalysis struct {
 	HasTests     bool         `json:"has_tests"`
 	License      string       `json:"license,omitempty"`
 	Description  string       `json:"description,omitempty"`
+	// PackageRoot is the packaged subdirectory of the repository, "" for the repository root;
+	// the result reports it as package_root
+	PackageRoot string `json:"-"`
 }
 
 // RepositoryAnalyzer analyzes Git repositories for packaging
@@ -65,8 +68,9 @@ func NewRepositoryAnalyzer(log *logger.Logger) *RepositoryAnalyzer {
 	}
 }
 
-// AnalyzeRepository analyzes a repository for packaging information
-func (ra *RepositoryAnalyzer) AnalyzeRepository(repoPath string) (*RepoAnalysis, error) {
+// AnalyzeRepository analyzes a repository for packaging information. The walks over the
+// repository stop when ctx is cancelled.
+func (ra *RepositoryAnalyzer) AnalyzeRepository(ctx context.Context, repoPath string) (*RepoAnalysis, error) {
 	analysis := &RepoAnalysis{
 		LocalPath:    repoPath,
 		Dependencies: []Dependency{},
@@ -77,7 +81,7 @@ func (ra *RepositoryAnalyzer) AnalyzeRepository(repoPath string) (*RepoAnalysis,
 	analysis.ProjectName = filepath.Base(repoPath)
 
 	// Detect build system and collect build files
-	buildSystem, buildFiles, err := ra.detectBuildSystem(repoPath)
+	buildSystem, buildFiles, err := ra.detectBuildSystem(ctx, repoPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect build system: %w", err)
 	}
@@ -85,7 +89,7 @@ func (ra *RepositoryAnalyzer) AnalyzeRepository(repoPath string) (*RepoAnalysis,
 	analysis.BuildFiles = buildFiles
 
 	// Detect primary language
-	language, err := ra.detectLanguage(repoPath)
+	language, err := ra.detectLanguage(ctx, repoPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect language: %w", err)
 	}
@@ -99,7 +103,10 @@ func (ra *RepositoryAnalyzer) AnalyzeRepository(repoPath string) (*RepoAnalysis,
 	analysis.Dependencies = dependencies
 
 	// Check for tests
-	analysis.HasTests = ra.hasTests(repoPath)
+	analysis.HasTests = ra.hasTests(ctx, repoPath)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// Try to find license
 	analysis.License = ra.findLicense(repoPath)
@@ -110,50 +117,53 @@ func (ra *RepositoryAnalyzer) AnalyzeRepository(repoPath string) (*RepoAnalysis,
 	return analysis, nil
 }
 
+// buildSystemFiles maps build files to the build system they belong to
+var buildSystemFiles = map[string]BuildSystem{
+	"CMakeLists.txt": BuildSystemCMake,
+	"meson.build":    BuildSystemMeson,
+	"configure.ac":   BuildSystemAutotools,
+	"configure.in":   BuildSystemAutotools,
+	"Makefile":       BuildSystemMake,
+	"makefile":       BuildSystemMake,
+	"Cargo.toml":     BuildSystemCargoRust,
+	"package.json":   BuildSystemNpm,
+	"yarn.lock":      BuildSystemYarn,
+	"setup.py":       BuildSystemPython,
+	"pyproject.toml": BuildSystemPython,
+	"go.mod":         BuildSystemGo,
+	"build.gradle":   BuildSystemGradle,
+	"pom.xml":        BuildSystemMaven,
+}
+
+// buildSystemPriorities orders build systems when several are found (higher number = higher priority)
+var buildSystemPriorities = map[BuildSystem]int{
+	BuildSystemCMake:     8,
+	BuildSystemMeson:     9,
+	BuildSystemAutotools: 7,
+	BuildSystemCargoRust: 10,
+	BuildSystemGo:        10,
+	BuildSystemNpm:       6,
+	BuildSystemYarn:      5,
+	BuildSystemPython:    4,
+	BuildSystemGradle:    3,
+	BuildSystemMaven:     2,
+	BuildSystemMake:      1,
+}
+
 // detectBuildSystem detects the build system used by the project
-func (ra *RepositoryAnalyzer) detectBuildSystem(repoPath string) (BuildSystem, []string, error) {
+func (ra *RepositoryAnalyzer) detectBuildSystem(ctx context.Context, repoPath string) (BuildSystem, []string, error) {
 	var buildFiles []string
 
-	// Check for various build system files
-	checks := map[string]BuildSystem{
-		"CMakeLists.txt": BuildSystemCMake,
-		"meson.build":    BuildSystemMeson,
-		"configure.ac":   BuildSystemAutotools,
-		"configure.in":   BuildSystemAutotools,
-		"Makefile":       BuildSystemMake,
-		"makefile":       BuildSystemMake,
-		"Cargo.toml":     BuildSystemCargoRust,
-		"package.json":   BuildSystemNpm,
-		"yarn.lock":      BuildSystemYarn,
-		"setup.py":       BuildSystemPython,
-		"pyproject.toml": BuildSystemPython,
-		"go.mod":         BuildSystemGo,
-		"build.gradle":   BuildSystemGradle,
-		"pom.xml":        BuildSystemMaven,
-	}
-
 	var detectedSystem = BuildSystemUnknown
 	priority := 0
 
-	// Priority order for build systems (higher number = higher priority)
-	priorities := map[BuildSystem]int{
-		BuildSystemCMake:     8,
-		BuildSystemMeson:     9,
-		BuildSystemAutotools: 7,
-		BuildSystemCargoRust: 10,
-		BuildSystemGo:        10,
-		BuildSystemNpm:       6,
-		BuildSystemYarn:      5,
-		BuildSystemPython:    4,
-		BuildSystemGradle:    3,
-		BuildSystemMaven:     2,
-		BuildSystemMake:      1,
-	}
-
 	err := filepath.Walk(repoPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 
 		// Skip hidden directories and common ignore patterns
 		if info.IsDir() {
@@ -165,9 +175,9 @@ func (ra *RepositoryAnalyzer) detectBuildSystem(repoPath string) (BuildSystem, [
 		}
 
 		fileName := info.Name()
-		if buildSystem, exists := checks[fileName]; exists {
+		if buildSystem, exists := buildSystemFiles[fileName]; exists {
 			buildFiles = append(buildFiles, path)
-			if systemPriority := priorities[buildSystem]; systemPriority > priority {
+			if systemPriority := buildSystemPriorities[buildSystem]; systemPriority > priority {
 				detectedSystem = buildSystem
 				priority = systemPriority
 			}
@@ -184,15 +194,17 @@ func (ra *RepositoryAnalyzer) detectBuildSystem(repoPath string) (BuildSystem, [
 }
 
 // detectLanguage attempts to detect the primary programming language
-func (ra *RepositoryAnalyzer) detectLanguage(repoPath string) (string, error) {
+func (ra *RepositoryAnalyzer) detectLanguage(ctx context.Context, repoPath string) (string, error) {
 	// Use enhanced detection system
-	ctx := context.Background()
 	opts := detection.DefaultAnalysisOptions()
 
 	results, err := ra.detector.DetectLanguages(ctx, repoPath, opts)
 	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 		ra.logger.Warn(fmt.Sprintf("Enhanced language detection failed, falling back to basic detection: %v", err))
-		return ra.detectLanguageBasic(repoPath)
+		return ra.detectLanguageBasic(ctx, repoPath)
 	}
 
 	// Return the highest confidence language
@@ -204,11 +216,11 @@ func (ra *RepositoryAnalyzer) detectLanguage(repoPath string) (string, error) {
 	}
 
 	// Fallback to basic detection if no languages detected
-	return ra.detectLanguageBasic(repoPath)
+	return ra.detectLanguageBasic(ctx, repoPath)
 }
 
 // detectLanguageBasic provides basic language detection as fallback
-func (ra *RepositoryAnalyzer) detectLanguageBasic(repoPath string) (string, error) {
+func (ra *RepositoryAnalyzer) detectLanguageBasic(ctx context.Context, repoPath string) (string, error) {
 	languageCount := make(map[string]int)
 
 	extensions := map[string]string{
@@ -240,6 +252,9 @@ func (ra *RepositoryAnalyzer) detectLanguageBasic(repoPath string) (string, erro
 		if err != nil {
 			return err
 		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 
 		if info.IsDir() {
 			name := info.Name()
@@ -326,7 +341,7 @@ func (ra *RepositoryAnalyzer) analyzeDependencies(repoPath string, buildSystem B
 }
 
 // hasTests checks if the project has test files
-func (ra *RepositoryAnalyzer) hasTests(repoPath string) bool {
+func (ra *RepositoryAnalyzer) hasTests(ctx context.Context, repoPath string) bool {
 	testIndicators := []string{
 		"test", "tests", "spec", "specs", "__tests__",
 		"*_test.go", "*_test.py", "test_*.py", "*.test.js",
@@ -338,6 +353,9 @@ func (ra *RepositoryAnalyzer) hasTests(repoPath string) bool {
 		if err != nil || hasTests {
 			return err
 		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 
 		name := strings.ToLower(info.Name())
 		for _, indicator := range testIndicators {
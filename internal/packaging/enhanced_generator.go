@@ -3,8 +3,6 @@ package packaging
 import (
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 
 	"nix-ai-help/internal/ai"
@@ -34,7 +32,6 @@ const (
 // GenerationOptions controls derivation generation behavior
 type GenerationOptions struct {
 	Mode        GenerationMode
-	OutputPath  string
 	Interactive bool
 	UseCache    bool
 }
@@ -53,7 +50,7 @@ func NewEnhancedDerivationGenerator(aiProvider ai.AIProvider, mcpClient *mcp.MCP
 // GenerateFromRepo generates a Nix derivation from a repository with the specified options
 func (edg *EnhancedDerivationGenerator) GenerateFromRepo(ctx context.Context, repoPath string, opts GenerationOptions) (string, error) {
 	// Analyze the repository
-	analysis, err := edg.analyzer.AnalyzeRepository(repoPath)
+	analysis, err := edg.analyzer.AnalyzeRepository(ctx, repoPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to analyze repository: %w", err)
 	}
@@ -78,15 +75,6 @@ func (edg *EnhancedDerivationGenerator) GenerateFromRepo(ctx context.Context, re
 		return "", fmt.Errorf("failed to generate derivation: %w", err)
 	}
 
-	// Write to file if output path specified
-	if opts.OutputPath != "" {
-		err = edg.writeDerivationToFile(derivation, opts.OutputPath)
-		if err != nil {
-			return "", fmt.Errorf("failed to write derivation to file: %w", err)
-		}
-		edg.logger.Info(fmt.Sprintf("Derivation written to: %s", opts.OutputPath))
-	}
-
 	return derivation, nil
 }
 
@@ -310,18 +298,6 @@ func (edg *EnhancedDerivationGenerator) fillMissingRequiredVariables(context *te
 	}
 }
 
-// writeDerivationToFile writes the derivation to a file
-func (edg *EnhancedDerivationGenerator) writeDerivationToFile(derivation, outputPath string) error {
-	// Ensure the output directory exists
-	dir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
-	}
-
-	// Write the derivation to file
-	return os.WriteFile(outputPath, []byte(derivation), 0644)
-}
-
 // getNixpkgsContext and other AI-related methods (delegate to existing implementation)
 func (edg *EnhancedDerivationGenerator) getNixpkgsContext(ctx context.Context, buildSystem BuildSystem, language string) (string, error) {
 	// Create legacy generator to reuse existing logic
@@ -3,6 +3,7 @@ package packaging
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"nix-ai-help/internal/ai"
@@ -43,7 +44,7 @@ func (dg *DerivationGenerator) GenerateDerivation(ctx context.Context, analysis
 	// Extract and clean the derivation from the response
 	derivation := dg.ExtractDerivation(response)
 
-	return derivation, nil
+	return withSourceRoot(derivation, analysis.PackageRoot), nil
 }
 
 // GetNixpkgsContext retrieves relevant nixpkgs documentation and examples
@@ -118,6 +119,10 @@ PROJECT ANALYSIS:
 		prompt.WriteString(fmt.Sprintf("- Description: %s\n", analysis.Description))
 	}
 	prompt.WriteString(fmt.Sprintf("- Has Tests: %t\n", analysis.HasTests))
+	if analysis.PackageRoot != "" {
+		prompt.WriteString(fmt.Sprintf("- Package Root: %s (a subdirectory of the repository; keep src pointing at the whole repository and set sourceRoot = \"${src.name}/%s\";)\n",
+			analysis.PackageRoot, filepath.ToSlash(analysis.PackageRoot)))
+	}
 
 	if len(analysis.BuildFiles) > 0 {
 		prompt.WriteString("\nBuild Files Found:\n")
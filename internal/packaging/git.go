@@ -1,11 +1,14 @@
 package packaging
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"nix-ai-help/pkg/utils"
 )
 
 // GitCloner handles cloning Git repositories for analysis
@@ -20,8 +23,9 @@ func NewGitCloner(tempDir string) *GitCloner {
 	}
 }
 
-// CloneRepository clones a Git repository to a temporary directory
-func (gc *GitCloner) CloneRepository(repoURL string) (string, error) {
+// CloneRepository clones a Git repository to a temporary directory. Cancelling ctx stops the
+// clone and removes what was cloned so far.
+func (gc *GitCloner) CloneRepository(ctx context.Context, repoURL string) (string, error) {
 	// Create temp directory if it doesn't exist
 	if err := os.MkdirAll(gc.tempDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create temp directory: %w", err)
@@ -45,19 +49,21 @@ func (gc *GitCloner) CloneRepository(repoURL string) (string, error) {
 
 	// Clone the repository
 	// #nosec G204 -- repoURL and targetDir are validated/trusted or controlled by CLI logic
-	cmd := exec.Command("git", "clone", "--depth", "1", repoURL, targetDir)
+	// A partial clone is removed when Ctrl-C ends nixai during the clone
+	defer utils.OnInterrupt(func() { _ = os.RemoveAll(targetDir) })()
+	cmd := exec.CommandContext(ctx, "git", "clone", "--depth", "1", repoURL, targetDir)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("failed to clone repository: %w", err)
+	if err := utils.TraceRun(cmd); err != nil {
+		return "", cloneError(ctx, targetDir, err)
 	}
 
 	return targetDir, nil
 }
 
 // CloneRepositoryQuiet clones a repository without output
-func (gc *GitCloner) CloneRepositoryQuiet(repoURL string) (string, error) {
+func (gc *GitCloner) CloneRepositoryQuiet(ctx context.Context, repoURL string) (string, error) {
 	// Create temp directory if it doesn't exist
 	if err := os.MkdirAll(gc.tempDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create temp directory: %w", err)
@@ -81,15 +87,26 @@ func (gc *GitCloner) CloneRepositoryQuiet(repoURL string) (string, error) {
 
 	// Clone the repository quietly
 	// #nosec G204 -- repoURL and targetDir are validated/trusted or controlled by CLI logic
-	cmd := exec.Command("git", "clone", "--depth", "1", "--quiet", repoURL, targetDir)
+	defer utils.OnInterrupt(func() { _ = os.RemoveAll(targetDir) })()
+	cmd := exec.CommandContext(ctx, "git", "clone", "--depth", "1", "--quiet", repoURL, targetDir)
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("failed to clone repository: %w", err)
+	if err := utils.TraceRun(cmd); err != nil {
+		return "", cloneError(ctx, targetDir, err)
 	}
 
 	return targetDir, nil
 }
 
+// cloneError removes a failed or aborted clone and reports why it failed; an aborted clone
+// reports the context error, so callers can tell a timeout from a git failure
+func cloneError(ctx context.Context, targetDir string, err error) error {
+	_ = os.RemoveAll(targetDir)
+	if ctx.Err() != nil {
+		return fmt.Errorf("clone aborted: %w", ctx.Err())
+	}
+	return fmt.Errorf("failed to clone repository: %w", err)
+}
+
 // Cleanup removes the temporary directory
 func (gc *GitCloner) Cleanup() error {
 	if gc.tempDir != "" {
@@ -134,25 +151,25 @@ func GetRepositoryInfo(repoPath string) (*RepositoryInfo, error) {
 
 	// Get remote origin URL
 	cmd := exec.Command("git", "-C", repoPath, "remote", "get-url", "origin")
-	if output, err := cmd.Output(); err == nil {
+	if output, err := utils.TraceOutput(cmd); err == nil {
 		info.RemoteURL = strings.TrimSpace(string(output))
 	}
 
 	// Get current branch
 	cmd = exec.Command("git", "-C", repoPath, "branch", "--show-current")
-	if output, err := cmd.Output(); err == nil {
+	if output, err := utils.TraceOutput(cmd); err == nil {
 		info.Branch = strings.TrimSpace(string(output))
 	}
 
 	// Get latest commit hash
 	cmd = exec.Command("git", "-C", repoPath, "rev-parse", "HEAD")
-	if output, err := cmd.Output(); err == nil {
+	if output, err := utils.TraceOutput(cmd); err == nil {
 		info.CommitHash = strings.TrimSpace(string(output))
 	}
 
 	// Get commit count
 	cmd = exec.Command("git", "-C", repoPath, "rev-list", "--count", "HEAD")
-	if output, err := cmd.Output(); err == nil {
+	if output, err := utils.TraceOutput(cmd); err == nil {
 		info.CommitCount = strings.TrimSpace(string(output))
 	}
 
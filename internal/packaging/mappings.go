@@ -0,0 +1,151 @@
+package packaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nix-ai-help/pkg/utils"
+)
+
+// MappingConfidence tells how sure a dependency-to-nixpkgs mapping is
+type MappingConfidence string
+
+const (
+	// MappingVerified is a mapping whose attribute was found by nix search
+	MappingVerified MappingConfidence = "verified"
+	// MappingHeuristic is a mapping guessed from known names or documentation, not checked
+	// because nix search is unavailable
+	MappingHeuristic MappingConfidence = "heuristic"
+	// MappingUnknown is a dependency without a nixpkgs attribute, or whose guessed attribute
+	// nix search did not find
+	MappingUnknown MappingConfidence = "unknown"
+)
+
+const (
+	// nixSearchTimeout bounds a single nix search when verifying a mapping
+	nixSearchTimeout = 30 * time.Second
+	// maxConcurrentNixSearches bounds how many nix searches verify mappings at once
+	maxConcurrentNixSearches = 4
+)
+
+// nixpkgsAttrExists reports whether nix search finds the nixpkgs attribute; replaced in tests
+var nixpkgsAttrExists = func(ctx context.Context, attr string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, nixSearchTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "nix", "search", "nixpkgs", "^"+regexp.QuoteMeta(attr)+"$", "--json")
+	output, err := utils.TraceOutput(cmd)
+	if err != nil {
+		return false, fmt.Errorf("nix search failed: %w", err)
+	}
+
+	// Results are keyed by attribute path, e.g. legacyPackages.x86_64-linux.openssl
+	var results map[string]json.RawMessage
+	if err := json.Unmarshal(output, &results); err != nil {
+		return false, fmt.Errorf("invalid nix search output: %w", err)
+	}
+	for path := range results {
+		if path == attr || strings.HasSuffix(path, "."+attr) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RateNixpkgsMappings returns the confidence of each mapping, keyed by dependency name.
+// System dependencies without a mapping are rated unknown; other dependencies without a
+// mapping are left out, since the language builder provides them. The first mapping is
+// searched alone, so that a missing nix costs a single search, and the others concurrently,
+// at most maxConcurrentNixSearches at a time. Once nix search fails, the mappings not yet
+// searched are rated heuristic without further searches.
+func RateNixpkgsMappings(ctx context.Context, dependencies []Dependency, mappings map[string]string) map[string]MappingConfidence {
+	confidence := make(map[string]MappingConfidence)
+	var pending []string
+	for _, dep := range dependencies {
+		if _, mapped := mappings[dep.Name]; !mapped {
+			if dep.System {
+				confidence[dep.Name] = MappingUnknown
+			}
+			continue
+		}
+		if _, rated := confidence[dep.Name]; rated {
+			continue
+		}
+		// Reserved here so that a repeated dependency is searched once
+		confidence[dep.Name] = MappingHeuristic
+		pending = append(pending, dep.Name)
+	}
+	if len(pending) == 0 {
+		return confidence
+	}
+
+	var mu sync.Mutex
+	searchAvailable := true
+	rate := func(dep string) {
+		mu.Lock()
+		available := searchAvailable
+		mu.Unlock()
+		if !available {
+			return
+		}
+
+		found, err := nixpkgsAttrExists(ctx, mappings[dep])
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case err != nil:
+			searchAvailable = false
+		case found:
+			confidence[dep] = MappingVerified
+		default:
+			confidence[dep] = MappingUnknown
+		}
+	}
+
+	rate(pending[0])
+	slots := make(chan struct{}, maxConcurrentNixSearches)
+	var wg sync.WaitGroup
+	for _, dep := range pending[1:] {
+		wg.Add(1)
+		go func(dep string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			rate(dep)
+		}(dep)
+	}
+	wg.Wait()
+
+	return confidence
+}
+
+// MappingIssues returns a validation issue for every mapping that is not verified, sorted
+// by dependency name
+func MappingIssues(confidence map[string]MappingConfidence, mappings map[string]string) []string {
+	dependencies := make([]string, 0, len(confidence))
+	for dep := range confidence {
+		dependencies = append(dependencies, dep)
+	}
+	sort.Strings(dependencies)
+
+	var issues []string
+	for _, dep := range dependencies {
+		attr, mapped := mappings[dep]
+		switch {
+		case confidence[dep] == MappingHeuristic:
+			issues = append(issues, fmt.Sprintf("Unverified nixpkgs mapping for %s: %s is a heuristic guess, double-check it", dep, attr))
+		case confidence[dep] == MappingUnknown && mapped:
+			issues = append(issues, fmt.Sprintf("nix search did not find %s, the guessed nixpkgs mapping for %s", attr, dep))
+		case confidence[dep] == MappingUnknown:
+			issues = append(issues, fmt.Sprintf("No nixpkgs package found for system dependency %s", dep))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,130 @@
+package packaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubNixSearch replaces nix search with a lookup in known; a nil known makes nix search fail
+func stubNixSearch(t *testing.T, known map[string]bool) *int32 {
+	t.Helper()
+	var searches int32
+	original := nixpkgsAttrExists
+	nixpkgsAttrExists = func(ctx context.Context, attr string) (bool, error) {
+		atomic.AddInt32(&searches, 1)
+		if known == nil {
+			return false, errors.New("nix: command not found")
+		}
+		return known[attr], nil
+	}
+	t.Cleanup(func() { nixpkgsAttrExists = original })
+	return &searches
+}
+
+func TestRateNixpkgsMappings(t *testing.T) {
+	stubNixSearch(t, map[string]bool{"openssl": true, "zlib": true})
+
+	dependencies := []Dependency{
+		{Name: "openssl", Type: "build", System: true},
+		{Name: "zlib", Type: "build", System: true},
+		{Name: "libfoo", Type: "build", System: true},
+		{Name: "make", Type: "build"},
+		{Name: "lodash", Type: "runtime"},
+	}
+	mappings := map[string]string{"openssl": "openssl", "zlib": "zlib", "make": "gnumaek"}
+
+	confidence := RateNixpkgsMappings(context.Background(), dependencies, mappings)
+	expected := map[string]MappingConfidence{
+		"openssl": MappingVerified,
+		"zlib":    MappingVerified,
+		"libfoo":  MappingUnknown,
+		"make":    MappingUnknown,
+	}
+	if !reflect.DeepEqual(confidence, expected) {
+		t.Errorf("expected %v, got %v", expected, confidence)
+	}
+
+	issues := MappingIssues(confidence, mappings)
+	if len(issues) != 2 {
+		t.Fatalf("expected issues for libfoo and make only, got %v", issues)
+	}
+	if !strings.Contains(issues[0], "system dependency libfoo") || !strings.Contains(issues[1], "gnumaek") {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+}
+
+func TestRateNixpkgsMappings_HeuristicWithoutNixSearch(t *testing.T) {
+	searches := stubNixSearch(t, nil)
+
+	dependencies := []Dependency{
+		{Name: "openssl", Type: "build", System: true},
+		{Name: "cmake", Type: "build"},
+		{Name: "pkgconfig", Type: "build"},
+	}
+	mappings := map[string]string{"openssl": "openssl", "cmake": "cmake", "pkgconfig": "pkg-config"}
+
+	confidence := RateNixpkgsMappings(context.Background(), dependencies, mappings)
+	for _, dep := range dependencies {
+		if confidence[dep.Name] != MappingHeuristic {
+			t.Errorf("expected %s to be heuristic, got %q", dep.Name, confidence[dep.Name])
+		}
+	}
+	if atomic.LoadInt32(searches) != 1 {
+		t.Errorf("expected nix search to be tried once, got %d searches", *searches)
+	}
+
+	issues := MappingIssues(confidence, mappings)
+	if len(issues) != 3 || !strings.Contains(issues[0], "cmake is a heuristic guess") {
+		t.Errorf("expected a heuristic issue per mapping, got %v", issues)
+	}
+}
+
+func TestRateNixpkgsMappings_BoundsSearches(t *testing.T) {
+	var inFlight, most int32
+	original := nixpkgsAttrExists
+	nixpkgsAttrExists = func(ctx context.Context, attr string) (bool, error) {
+		now := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(&most)
+			if now <= seen || atomic.CompareAndSwapInt32(&most, seen, now) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		// Every other dependency is in nixpkgs
+		return attr[len(attr)-1]%2 == 0, nil
+	}
+	t.Cleanup(func() { nixpkgsAttrExists = original })
+
+	var dependencies []Dependency
+	mappings := make(map[string]string)
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("lib%c", 'a'+i)
+		dependencies = append(dependencies, Dependency{Name: name, Type: "build", System: true})
+		mappings[name] = name
+	}
+
+	confidence := RateNixpkgsMappings(context.Background(), dependencies, mappings)
+	if most > maxConcurrentNixSearches {
+		t.Errorf("%d nix searches ran at once, want at most %d", most, maxConcurrentNixSearches)
+	}
+	if most < 2 {
+		t.Errorf("nix searches ran one at a time")
+	}
+	for _, dep := range dependencies {
+		want := MappingUnknown
+		if dep.Name[len(dep.Name)-1]%2 == 0 {
+			want = MappingVerified
+		}
+		if confidence[dep.Name] != want {
+			t.Errorf("%s rated %q, want %q", dep.Name, confidence[dep.Name], want)
+		}
+	}
+}
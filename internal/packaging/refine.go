@@ -0,0 +1,52 @@
+package packaging
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// RefineDerivation asks the AI provider to correct a derivation based on user feedback, such as
+// "build fails: missing openssl". Earlier feedback is sent along so that its fixes are kept.
+func (dg *DerivationGenerator) RefineDerivation(ctx context.Context, analysis *RepoAnalysis, derivation, feedback string, earlier []string) (string, error) {
+	var prompt strings.Builder
+
+	prompt.WriteString("You are an expert Nix package maintainer. Correct the following Nix derivation based on the user's feedback.\n\n")
+	prompt.WriteString(fmt.Sprintf("PROJECT: %s (build system: %s, language: %s)\n\n", analysis.ProjectName, analysis.BuildSystem, analysis.Language))
+	prompt.WriteString("CURRENT DERIVATION:\n```nix\n" + derivation + "\n```\n\n")
+	if len(earlier) > 0 {
+		prompt.WriteString("EARLIER FEEDBACK (already addressed, keep these fixes):\n")
+		for _, item := range earlier {
+			prompt.WriteString("- " + item + "\n")
+		}
+		prompt.WriteString("\n")
+	}
+	prompt.WriteString("USER FEEDBACK:\n" + feedback + "\n\n")
+	prompt.WriteString("Return the complete corrected derivation in a single ```nix code block. Change only what the feedback requires.")
+
+	response, err := dg.aiProvider.Query(prompt.String())
+	if err != nil {
+		return "", fmt.Errorf("failed to refine derivation: %w", err)
+	}
+
+	// ExtractDerivation falls back to the whole response, so check for a Nix expression
+	refined := dg.ExtractDerivation(response)
+	if !strings.Contains(refined, "{") {
+		return "", fmt.Errorf("the AI response did not contain a derivation")
+	}
+	return withSourceRoot(refined, analysis.PackageRoot), nil
+}
+
+// RefineResult applies user feedback to the derivation of a result and validates the refined
+// derivation again. The result is only changed when refinement succeeds.
+func (ps *PackagingService) RefineResult(ctx context.Context, result *PackageResult, feedback string, earlier []string) error {
+	refined, err := ps.generator.RefineDerivation(ctx, result.Analysis, result.Derivation, feedback, earlier)
+	if err != nil {
+		return err
+	}
+
+	result.Derivation = refined
+	result.ValidationIssues = append(ps.generator.ValidateDerivation(refined),
+		MappingIssues(result.MappingConfidence, result.NixpkgsMappings)...)
+	return nil
+}
@@ -0,0 +1,48 @@
+package packaging
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// scriptedProvider answers queries in order and records the prompts
+type scriptedProvider struct {
+	responses []string
+	prompts   []string
+}
+
+func (p *scriptedProvider) Query(prompt string) (string, error) {
+	p.prompts = append(p.prompts, prompt)
+	response := p.responses[0]
+	p.responses = p.responses[1:]
+	return response, nil
+}
+
+func TestRefineDerivation(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{
+		"Here is the fix:\n```nix\n{ stdenv, openssl }:\nstdenv.mkDerivation {\n  pname = \"hello\";\n  buildInputs = [ openssl ];\n}\n```",
+		"No derivation, sorry.",
+	}}
+	dg := NewDerivationGenerator(provider, nil)
+	analysis := &RepoAnalysis{ProjectName: "hello", BuildSystem: BuildSystemMake, Language: "c"}
+	current := "{ stdenv }:\nstdenv.mkDerivation {\n  pname = \"hello\";\n}"
+
+	refined, err := dg.RefineDerivation(context.Background(), analysis, current, "build fails: missing openssl", []string{"use version 1.2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(refined, "buildInputs = [ openssl ];") || strings.Contains(refined, "```") {
+		t.Errorf("expected the extracted derivation, got:\n%s", refined)
+	}
+	prompt := provider.prompts[0]
+	for _, want := range []string{current, "USER FEEDBACK:\nbuild fails: missing openssl", "- use version 1.2"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("expected the prompt to contain %q:\n%s", want, prompt)
+		}
+	}
+
+	if _, err := dg.RefineDerivation(context.Background(), analysis, current, "fix it", nil); err == nil {
+		t.Error("expected an error for a response without a derivation")
+	}
+}
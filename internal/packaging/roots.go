@@ -0,0 +1,175 @@
+package packaging
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxPackageRootDepth limits how deep FindPackageRoots looks for package roots
+const maxPackageRootDepth = 4
+
+// nonPackageDirs are never package roots: vendored code, build output and caches
+var nonPackageDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"third_party":  true,
+	"target":       true,
+	"build":        true,
+	"dist":         true,
+	"__pycache__":  true,
+}
+
+// auxiliaryDirs hold code that is rarely the package itself, such as examples and tests
+var auxiliaryDirs = map[string]bool{
+	"example":    true,
+	"examples":   true,
+	"test":       true,
+	"tests":      true,
+	"testdata":   true,
+	"docs":       true,
+	"benchmarks": true,
+	"fuzz":       true,
+}
+
+// PackageRoot is a directory of a repository that contains build files
+type PackageRoot struct {
+	Path        string      `json:"path"` // relative to the repository, "." for the root
+	BuildSystem BuildSystem `json:"build_system"`
+	BuildFiles  []string    `json:"build_files"`
+	score       int
+}
+
+// FindPackageRoots returns the directories of a repository that contain build files, most
+// likely package root first. The repository root wins when it has build files; otherwise
+// shallow directories, preferred build systems and directories named like the repository
+// rank higher, and examples, tests and docs rank last. Vendored directories are skipped.
+func FindPackageRoots(repoPath string) ([]PackageRoot, error) {
+	repoName := strings.ToLower(filepath.Base(repoPath))
+	roots := make(map[string]*PackageRoot)
+
+	err := filepath.WalkDir(repoPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(repoPath, path)
+		if entry.IsDir() {
+			name := entry.Name()
+			if rel != "." && (strings.HasPrefix(name, ".") || nonPackageDirs[name] || pathDepth(rel) > maxPackageRootDepth) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		buildSystem, ok := buildSystemFiles[entry.Name()]
+		if !ok {
+			return nil
+		}
+		dir := filepath.Dir(rel)
+		root := roots[dir]
+		if root == nil {
+			root = &PackageRoot{Path: dir, BuildSystem: BuildSystemUnknown}
+			roots[dir] = root
+		}
+		root.BuildFiles = append(root.BuildFiles, entry.Name())
+		if buildSystemPriorities[buildSystem] > buildSystemPriorities[root.BuildSystem] {
+			root.BuildSystem = buildSystem
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]PackageRoot, 0, len(roots))
+	for _, root := range roots {
+		root.score = packageRootScore(root, repoName)
+		candidates = append(candidates, *root)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].Path < candidates[j].Path
+	})
+	return candidates, nil
+}
+
+// packageRootScore rates how likely a directory is the package root of the repository
+func packageRootScore(root *PackageRoot, repoName string) int {
+	if root.Path == "." {
+		return 1000
+	}
+	score := 100 - 10*pathDepth(root.Path) + buildSystemPriorities[root.BuildSystem]
+	if strings.ToLower(filepath.Base(root.Path)) == repoName {
+		score += 15
+	}
+	for _, part := range strings.Split(root.Path, string(filepath.Separator)) {
+		if auxiliaryDirs[strings.ToLower(part)] {
+			score -= 50
+			break
+		}
+	}
+	return score
+}
+
+// pathDepth returns the number of path elements of a relative path
+func pathDepth(rel string) int {
+	if rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+// resolveSubdir returns the directory of a repository to package: subdir when given, which
+// must stay inside the repository
+func resolveSubdir(repoPath, subdir string) (string, error) {
+	clean := filepath.Clean(subdir)
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("subdirectory %s is outside the repository", subdir)
+	}
+	path := filepath.Join(repoPath, clean)
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return "", fmt.Errorf("subdirectory %s not found in the repository", subdir)
+	}
+	return path, nil
+}
+
+// withSourceRoot makes the derivation of a package in a subdirectory of its repository build
+// from that subdirectory, by setting sourceRoot after the src attribute. A derivation that sets
+// sourceRoot itself, or without a src attribute to place it after, is returned unchanged.
+func withSourceRoot(derivation, root string) string {
+	if root == "" || root == "." || strings.Contains(derivation, "sourceRoot") {
+		return derivation
+	}
+	lines := strings.Split(derivation, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "src =") && !strings.HasPrefix(trimmed, "src=") {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		end := i
+		if !strings.HasSuffix(trimmed, ";") {
+			// The attribute set of src ends at the closing brace indented like it
+			end = -1
+			for j := i + 1; j < len(lines); j++ {
+				if strings.HasPrefix(lines[j], indent+"}") {
+					end = j
+					break
+				}
+			}
+			if end < 0 {
+				return derivation
+			}
+		}
+		sourceRoot := indent + `sourceRoot = "${src.name}/` + filepath.ToSlash(root) + `";`
+		lines = append(lines[:end+1], append([]string{sourceRoot}, lines[end+1:]...)...)
+		return strings.Join(lines, "\n")
+	}
+	return derivation
+}
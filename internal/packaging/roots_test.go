@@ -0,0 +1,148 @@
+package packaging
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"nix-ai-help/pkg/logger"
+)
+
+// writeFixture creates the files under dir
+func writeFixture(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// rootPaths returns the paths of package roots
+func rootPaths(roots []PackageRoot) []string {
+	var paths []string
+	for _, root := range roots {
+		paths = append(paths, filepath.ToSlash(root.Path))
+	}
+	return paths
+}
+
+func TestFindPackageRoots_Monorepo(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "hello")
+	writeFixture(t, repo,
+		"README.md",
+		"cli/go.mod",
+		"web/package.json",
+		"web/node_modules/left-pad/package.json",
+		"libs/core/CMakeLists.txt",
+		"libs/core/Makefile",
+		"examples/demo/Cargo.toml",
+		"vendor/zlib/Makefile",
+		".github/actions/setup/package.json",
+		"packages/hello/pyproject.toml",
+	)
+
+	roots, err := FindPackageRoots(repo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"cli", "packages/hello", "web", "libs/core", "examples/demo"}
+	if paths := rootPaths(roots); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected roots %v, got %v", expected, paths)
+	}
+	for _, root := range roots {
+		if root.Path == filepath.FromSlash("libs/core") {
+			if root.BuildSystem != BuildSystemCMake || len(root.BuildFiles) != 2 {
+				t.Errorf("expected libs/core to be a CMake root with two build files, got %+v", root)
+			}
+		}
+	}
+}
+
+func TestFindPackageRoots_RootWins(t *testing.T) {
+	repo := t.TempDir()
+	writeFixture(t, repo, "go.mod", "examples/basic/go.mod", "tools/gen/go.mod")
+
+	roots, err := FindPackageRoots(repo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if paths := rootPaths(roots); !reflect.DeepEqual(paths, []string{".", "tools/gen", "examples/basic"}) {
+		t.Errorf("expected the repository root first, got %v", paths)
+	}
+}
+
+func TestSelectPackageRoot(t *testing.T) {
+	repo := t.TempDir()
+	writeFixture(t, repo, "README.md", "cli/go.mod", "web/package.json")
+	ps := NewPackagingService(nil, nil, t.TempDir(), logger.NewLoggerWithLevel("error"))
+
+	path, root, others, err := ps.selectPackageRoot(repo, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != filepath.Join(repo, "cli") || root != "cli" || !reflect.DeepEqual(others, []string{"web"}) {
+		t.Errorf("expected cli with web noted, got %s, %s, %v", path, root, others)
+	}
+
+	path, root, others, err = ps.selectPackageRoot(repo, "web/")
+	if err != nil || path != filepath.Join(repo, "web") || root != "web" || others != nil {
+		t.Errorf("expected the requested subdirectory, got %s, %s, %v, %v", path, root, others, err)
+	}
+
+	// A repository packaged from its root does not list its examples as alternatives
+	rooted := t.TempDir()
+	writeFixture(t, rooted, "go.mod", "examples/basic/go.mod")
+	if _, root, others, err := ps.selectPackageRoot(rooted, ""); err != nil || root != "." || others != nil {
+		t.Errorf("expected the repository root alone, got %s, %v, %v", root, others, err)
+	}
+
+	for _, subdir := range []string{"../outside", "/etc", "missing", "README.md"} {
+		if _, _, _, err := ps.selectPackageRoot(repo, subdir); err == nil {
+			t.Errorf("expected an error for subdirectory %q", subdir)
+		}
+	}
+}
+
+func TestWithSourceRoot(t *testing.T) {
+	derivation := `{ lib, buildGoModule, fetchFromGitHub }:
+
+buildGoModule rec {
+  pname = "tool";
+  version = "1.0.0";
+
+  src = fetchFromGitHub {
+    owner = "owner";
+    repo = "tool";
+    rev = "v${version}";
+    hash = lib.fakeHash;
+  };
+
+  vendorHash = lib.fakeHash;
+}`
+	got := withSourceRoot(derivation, "cli")
+	want := "    hash = lib.fakeHash;\n  };\n  sourceRoot = \"${src.name}/cli\";\n\n  vendorHash"
+	if !strings.Contains(got, want) {
+		t.Errorf("expected sourceRoot after src, got:\n%s", got)
+	}
+
+	if got := withSourceRoot("{ stdenv }:\nstdenv.mkDerivation {\n  src = ./.;\n}", "tools/gen"); !strings.Contains(got, "  src = ./.;\n  sourceRoot = \"${src.name}/tools/gen\";\n}") {
+		t.Errorf("expected sourceRoot after a one-line src, got:\n%s", got)
+	}
+	for _, unchanged := range []struct{ derivation, root string }{
+		{derivation, ""},
+		{derivation, "."},
+		{strings.Replace(derivation, "vendorHash", "sourceRoot = \"source/cli\";\n  vendorHash", 1), "cli"},
+		{"{ stdenv }: stdenv.mkDerivation { }", "cli"},
+	} {
+		if got := withSourceRoot(unchanged.derivation, unchanged.root); got != unchanged.derivation {
+			t.Errorf("withSourceRoot(%q) changed the derivation:\n%s", unchanged.root, got)
+		}
+	}
+}
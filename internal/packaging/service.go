@@ -9,6 +9,7 @@ import (
 	"nix-ai-help/internal/ai"
 	"nix-ai-help/internal/mcp"
 	"nix-ai-help/pkg/logger"
+	"nix-ai-help/pkg/utils"
 )
 
 // PackagingService coordinates repository analysis and derivation generation
@@ -23,9 +24,11 @@ type PackagingService struct {
 type PackageRequest struct {
 	RepoURL     string `json:"repo_url"`
 	LocalPath   string `json:"local_path,omitempty"`
-	OutputPath  string `json:"output_path,omitempty"`
 	PackageName string `json:"package_name,omitempty"`
+	Subdir      string `json:"subdir,omitempty"` // Package root within the repository
 	Quiet       bool   `json:"quiet,omitempty"`
+	KeepClone   bool   `json:"keep_clone,omitempty"`   // Leave the cloned repository for debugging
+	AnalyzeOnly bool   `json:"analyze_only,omitempty"` // Stop after the analysis, without generating a derivation
 }
 
 // PackageResult represents the result of packaging operation
@@ -34,7 +37,13 @@ type PackageResult struct {
 	Derivation       string            `json:"derivation"`
 	ValidationIssues []string          `json:"validation_issues,omitempty"`
 	NixpkgsMappings  map[string]string `json:"nixpkgs_mappings,omitempty"`
-	OutputFile       string            `json:"output_file,omitempty"`
+	// MappingConfidence rates each nixpkgs mapping by dependency name
+	MappingConfidence map[string]MappingConfidence `json:"mapping_confidence,omitempty"`
+	OutputFile        string                       `json:"output_file,omitempty"`
+	// PackageRoot is the packaged directory relative to the repository, "." for the root
+	PackageRoot string `json:"package_root,omitempty"`
+	// OtherPackageRoots are further directories with build files that were not packaged
+	OtherPackageRoots []string `json:"other_package_roots,omitempty"`
 }
 
 // NewPackagingService creates a new packaging service
@@ -47,7 +56,9 @@ func NewPackagingService(aiProvider ai.AIProvider, mcpClient *mcp.MCPClient, tem
 	}
 }
 
-// PackageRepository packages a Git repository into a Nix derivation
+// PackageRepository packages a Git repository into a Nix derivation. Cancelling ctx, or its
+// deadline passing, aborts the clone, analysis and generation; the returned error then wraps
+// the context error, and a cloned repository is removed.
 func (ps *PackagingService) PackageRepository(ctx context.Context, req *PackageRequest) (*PackageResult, error) {
 	var repoPath string
 	var err error
@@ -59,28 +70,39 @@ func (ps *PackagingService) PackageRepository(ctx context.Context, req *PackageR
 		ps.logger.Debug(fmt.Sprintf("Using local repository path: %s", repoPath))
 	} else if req.RepoURL != "" {
 		ps.logger.Info(fmt.Sprintf("Cloning repository: %s", req.RepoURL))
-		repoPath, err = ps.cloneRepository(req.RepoURL, req.Quiet)
+		repoPath, err = ps.cloneRepository(ctx, req.RepoURL, req.Quiet)
 		if err != nil {
 			return nil, fmt.Errorf("failed to clone repository: %w", err)
 		}
-		shouldCleanup = true
+		shouldCleanup = !req.KeepClone
 		ps.logger.Debug(fmt.Sprintf("Repository cloned to: %s", repoPath))
 	} else {
 		return nil, fmt.Errorf("either repo_url or local_path must be provided")
 	}
 
-	// Ensure cleanup if we cloned the repository
+	// Ensure cleanup if we cloned the repository, also when Ctrl-C ends nixai
 	if shouldCleanup {
+		removeOnInterrupt := utils.OnInterrupt(func() { _ = os.RemoveAll(repoPath) })
 		defer func() {
+			removeOnInterrupt()
 			if err := os.RemoveAll(repoPath); err != nil {
 				ps.logger.Warn(fmt.Sprintf("Failed to cleanup cloned repository %s: %v", repoPath, err))
 			}
 		}()
 	}
 
+	// Select the package root within the repository
+	packagePath, packageRoot, otherRoots, err := ps.selectPackageRoot(repoPath, req.Subdir)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("packaging aborted: %w", err)
+	}
+
 	// Analyze repository
-	ps.logger.Info(fmt.Sprintf("Analyzing repository: %s", repoPath))
-	analysis, err := ps.analyzer.AnalyzeRepository(repoPath)
+	ps.logger.Info(fmt.Sprintf("Analyzing repository: %s", packagePath))
+	analysis, err := ps.analyzer.AnalyzeRepository(ctx, packagePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze repository: %w", err)
 	}
@@ -90,6 +112,10 @@ func (ps *PackagingService) PackageRepository(ctx context.Context, req *PackageR
 		analysis.RepoURL = req.RepoURL
 	}
 
+	if packageRoot != "." {
+		analysis.PackageRoot = packageRoot
+	}
+
 	// Override package name if provided
 	if req.PackageName != "" {
 		analysis.ProjectName = req.PackageName
@@ -98,17 +124,22 @@ func (ps *PackagingService) PackageRepository(ctx context.Context, req *PackageR
 	ps.logger.Info(fmt.Sprintf("Repository analysis complete - project: %s, build_system: %s, language: %s, dependencies: %d",
 		analysis.ProjectName, analysis.BuildSystem, analysis.Language, len(analysis.Dependencies)))
 
-	// Generate derivation
-	ps.logger.Info("Generating Nix derivation")
-	derivation, err := ps.generator.GenerateDerivation(ctx, analysis)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate derivation: %w", err)
-	}
+	// Generate and validate the derivation, unless only the analysis was asked for
+	var derivation string
+	var validationIssues []string
+	if req.AnalyzeOnly {
+		ps.logger.Debug("Analysis only, not generating a derivation")
+	} else {
+		ps.logger.Info("Generating Nix derivation")
+		derivation, err = ps.generator.GenerateDerivation(ctx, analysis)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate derivation: %w", err)
+		}
 
-	// Validate derivation
-	validationIssues := ps.generator.ValidateDerivation(derivation)
-	if len(validationIssues) > 0 {
-		ps.logger.Warn(fmt.Sprintf("Derivation validation issues found: %v", validationIssues))
+		validationIssues = ps.generator.ValidateDerivation(derivation)
+		if len(validationIssues) > 0 {
+			ps.logger.Warn(fmt.Sprintf("Derivation validation issues found: %v", validationIssues))
+		}
 	}
 
 	// Get nixpkgs mappings for dependencies
@@ -118,60 +149,72 @@ func (ps *PackagingService) PackageRepository(ctx context.Context, req *PackageR
 		nixpkgsMappings = make(map[string]string)
 	}
 
-	// Save derivation to file if output path specified
-	var outputFile string
-	if req.OutputPath != "" {
-		outputFile, err = ps.saveDerivation(derivation, req.OutputPath, analysis.ProjectName)
-		if err != nil {
-			ps.logger.Warn(fmt.Sprintf("Failed to save derivation to file: %v", err))
-		} else {
-			ps.logger.Info(fmt.Sprintf("Derivation saved to: %s", outputFile))
-		}
-	}
+	// Rate the mappings and flag the ones to double-check
+	mappingConfidence := RateNixpkgsMappings(ctx, analysis.Dependencies, nixpkgsMappings)
+	validationIssues = append(validationIssues, MappingIssues(mappingConfidence, nixpkgsMappings)...)
 
 	result := &PackageResult{
-		Analysis:         analysis,
-		Derivation:       derivation,
-		ValidationIssues: validationIssues,
-		NixpkgsMappings:  nixpkgsMappings,
-		OutputFile:       outputFile,
+		Analysis:          analysis,
+		Derivation:        derivation,
+		ValidationIssues:  validationIssues,
+		NixpkgsMappings:   nixpkgsMappings,
+		MappingConfidence: mappingConfidence,
+		PackageRoot:       packageRoot,
+		OtherPackageRoots: otherRoots,
 	}
 
 	return result, nil
 }
 
-// cloneRepository clones a repository and returns the local path
-func (ps *PackagingService) cloneRepository(repoURL string, quiet bool) (string, error) {
-	if quiet {
-		return ps.cloner.CloneRepositoryQuiet(repoURL)
+// selectPackageRoot returns the directory to package and its path relative to the repository,
+// along with the other package roots found when the repository root is not one. Without a
+// requested subdirectory the most likely package root is chosen.
+func (ps *PackagingService) selectPackageRoot(repoPath, subdir string) (string, string, []string, error) {
+	if subdir != "" {
+		path, err := resolveSubdir(repoPath, subdir)
+		if err != nil {
+			return "", "", nil, err
+		}
+		return path, filepath.Clean(subdir), nil, nil
 	}
-	return ps.cloner.CloneRepository(repoURL)
-}
 
-// saveDerivation saves the derivation to a file
-func (ps *PackagingService) saveDerivation(derivation, outputPath, projectName string) (string, error) {
-	// #nosec G301 -- Output directory is user-controlled and not sensitive
-	if err := os.MkdirAll(outputPath, 0755); err != nil {
-		return "", fmt.Errorf("failed to create output directory: %w", err)
+	candidates, err := FindPackageRoots(repoPath)
+	if err != nil {
+		ps.logger.Warn(fmt.Sprintf("Failed to detect package roots, using the repository root: %v", err))
+		return repoPath, ".", nil, nil
+	}
+	if len(candidates) == 0 {
+		return repoPath, ".", nil, nil
+	}
+	// The other roots of a repository packaged from its root are its examples, tools and the
+	// like rather than alternatives to it
+	if candidates[0].Path == "." {
+		return repoPath, ".", nil, nil
 	}
 
-	// Generate filename
-	filename := fmt.Sprintf("%s.nix", projectName)
-	filepath := filepath.Join(outputPath, filename)
-
-	// #nosec G306 -- Derivation output is not sensitive, 0644 is intentional for Nix usage
-	if err := os.WriteFile(filepath, []byte(derivation), 0644); err != nil {
-		return "", fmt.Errorf("failed to write derivation file: %w", err)
+	var others []string
+	for _, candidate := range candidates[1:] {
+		others = append(others, candidate.Path)
 	}
+	if candidates[0].Path != "." {
+		ps.logger.Info(fmt.Sprintf("No build files at the repository root, packaging %s", candidates[0].Path))
+	}
+	return filepath.Join(repoPath, candidates[0].Path), candidates[0].Path, others, nil
+}
 
-	return filepath, nil
+// cloneRepository clones a repository and returns the local path
+func (ps *PackagingService) cloneRepository(ctx context.Context, repoURL string, quiet bool) (string, error) {
+	if quiet {
+		return ps.cloner.CloneRepositoryQuiet(ctx, repoURL)
+	}
+	return ps.cloner.CloneRepository(ctx, repoURL)
 }
 
 // AnalyzeLocalRepository analyzes a local repository without generating a derivation
-func (ps *PackagingService) AnalyzeLocalRepository(repoPath string) (*RepoAnalysis, error) {
+func (ps *PackagingService) AnalyzeLocalRepository(ctx context.Context, repoPath string) (*RepoAnalysis, error) {
 	ps.logger.Info(fmt.Sprintf("Analyzing local repository: %s", repoPath))
 
-	analysis, err := ps.analyzer.AnalyzeRepository(repoPath)
+	analysis, err := ps.analyzer.AnalyzeRepository(ctx, repoPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze repository: %w", err)
 	}
@@ -205,11 +248,16 @@ func (ps *PackagingService) GenerateDerivationFromAnalysis(ctx context.Context,
 		nixpkgsMappings = make(map[string]string)
 	}
 
+	// Rate the mappings and flag the ones to double-check
+	mappingConfidence := RateNixpkgsMappings(ctx, analysis.Dependencies, nixpkgsMappings)
+	validationIssues = append(validationIssues, MappingIssues(mappingConfidence, nixpkgsMappings)...)
+
 	result := &PackageResult{
-		Analysis:         analysis,
-		Derivation:       derivation,
-		ValidationIssues: validationIssues,
-		NixpkgsMappings:  nixpkgsMappings,
+		Analysis:          analysis,
+		Derivation:        derivation,
+		ValidationIssues:  validationIssues,
+		NixpkgsMappings:   nixpkgsMappings,
+		MappingConfidence: mappingConfidence,
 	}
 
 	return result, nil
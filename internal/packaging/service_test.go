@@ -0,0 +1,154 @@
+package packaging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"nix-ai-help/pkg/logger"
+)
+
+// cancelledOnceExists is a context that is cancelled as soon as path exists, e.g. once a
+// clone is done, without the clone itself seeing the cancellation
+type cancelledOnceExists struct {
+	context.Context
+	path string
+}
+
+func (c cancelledOnceExists) Err() error {
+	if _, err := os.Stat(c.path); err == nil {
+		return context.Canceled
+	}
+	return nil
+}
+
+// newTestPackagingService returns a service that clones into tempDir; it has no AI provider,
+// so it fails if packaging gets as far as generating the derivation
+func newTestPackagingService(tempDir string) *PackagingService {
+	return NewPackagingService(nil, nil, tempDir, logger.NewLoggerWithWriter(io.Discard))
+}
+
+// initGitRepo creates a Git repository with one commit of a Go module
+func initGitRepo(t *testing.T, dir string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	writeFixture(t, dir, "go.mod", "main.go")
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+}
+
+func TestPackageRepositoryCancelledBeforeClone(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "hello")
+	initGitRepo(t, repo)
+	tempDir := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := newTestPackagingService(tempDir).PackageRepository(ctx, &PackageRequest{RepoURL: repo, Quiet: true})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the clone to be aborted, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "hello")); !os.IsNotExist(err) {
+		t.Errorf("expected no clone to be left behind, got %v", err)
+	}
+}
+
+func TestPackageRepositoryCancelledAfterCloneCleansUp(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "hello")
+	initGitRepo(t, repo)
+	tempDir := t.TempDir()
+	clone := filepath.Join(tempDir, "hello")
+
+	ctx := cancelledOnceExists{Context: context.Background(), path: clone}
+	_, err := newTestPackagingService(tempDir).PackageRepository(ctx, &PackageRequest{RepoURL: repo, Quiet: true})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected packaging to be aborted after the clone, got %v", err)
+	}
+	if _, err := os.Stat(clone); !os.IsNotExist(err) {
+		t.Errorf("expected the clone to be removed, got %v", err)
+	}
+}
+
+func TestPackageRepositoryCancelledKeepsLocalPath(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "hello")
+	writeFixture(t, repo, "go.mod", "main.go")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := newTestPackagingService(t.TempDir()).PackageRepository(ctx, &PackageRequest{LocalPath: repo})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the analysis to be aborted, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "go.mod")); err != nil {
+		t.Errorf("a local repository must not be removed: %v", err)
+	}
+}
+
+func TestCloneErrorRemovesPartialClone(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "hello")
+	writeFixture(t, target, ".git/HEAD")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	err := cloneError(ctx, target, errors.New("signal: killed"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to be reported, got %v", err)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("expected the partial clone to be removed, got %v", err)
+	}
+
+	err = cloneError(context.Background(), target, errors.New("exit status 128"))
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("a git failure is not a cancellation: %v", err)
+	}
+}
+
+func TestPackageRepositoryKeepClone(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "hello")
+	initGitRepo(t, repo)
+	tempDir := t.TempDir()
+	clone := filepath.Join(tempDir, "hello")
+
+	// Abort after the clone so that no AI provider is needed
+	ctx := cancelledOnceExists{Context: context.Background(), path: clone}
+	_, err := newTestPackagingService(tempDir).PackageRepository(ctx, &PackageRequest{RepoURL: repo, Quiet: true, KeepClone: true})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected packaging to be aborted after the clone, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(clone, "go.mod")); err != nil {
+		t.Errorf("expected the clone to be kept: %v", err)
+	}
+}
+
+func TestPackageRepositoryAnalyzeOnly(t *testing.T) {
+	repo := t.TempDir()
+	writeFixture(t, repo, "go.mod", "main.go")
+
+	// The test service has no AI provider, so this only succeeds if generation is skipped
+	result, err := newTestPackagingService(t.TempDir()).PackageRepository(context.Background(), &PackageRequest{LocalPath: repo, AnalyzeOnly: true})
+	if err != nil {
+		t.Fatalf("PackageRepository: %v", err)
+	}
+	if result.Analysis == nil || result.Analysis.BuildSystem != BuildSystemGo {
+		t.Errorf("expected the Go module to be analyzed, got %+v", result.Analysis)
+	}
+	if result.Derivation != "" {
+		t.Errorf("expected no derivation, got %q", result.Derivation)
+	}
+}
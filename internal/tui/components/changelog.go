@@ -2,38 +2,16 @@ package components
 
 import (
 	"fmt"
-	"io/ioutil"
 	"strings"
 
+	"nix-ai-help/internal/changelog"
 	"nix-ai-help/internal/tui/styles"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"gopkg.in/yaml.v3"
 )
 
-// ChangelogEntry represents a single changelog entry
-type ChangelogEntry struct {
-	Version      string        `yaml:"version"`
-	Date         string        `yaml:"date"`
-	Highlights   []string      `yaml:"highlights"`
-	Features     []FeatureItem `yaml:"features"`
-	Improvements []string      `yaml:"improvements"`
-	Fixes        []string      `yaml:"fixes"`
-}
-
-// FeatureItem represents a feature with title and description
-type FeatureItem struct {
-	Title       string `yaml:"title"`
-	Description string `yaml:"description"`
-}
-
-// ChangelogData represents the entire changelog structure
-type ChangelogData struct {
-	Changelog []ChangelogEntry `yaml:"changelog"`
-}
-
 // ChangelogPopup represents the changelog popup component
 type ChangelogPopup struct {
 	width    int
@@ -169,80 +147,30 @@ func (c *ChangelogPopup) SetSize(width, height int) {
 	c.viewport.Height = height - 8 // Account for header, footer, border, padding
 }
 
-// loadChangelog loads the changelog from the YAML file
+// loadChangelog loads the release notes embedded in nixai, the ones the CLI TUI shows
 func (c *ChangelogPopup) loadChangelog() tea.Cmd {
 	return func() tea.Msg {
-		// Try to load the changelog file
-		data, err := ioutil.ReadFile("configs/changelog.yaml")
-		if err != nil {
-			return ChangelogLoadedMsg{
-				Content: c.generateErrorMessage(err),
-			}
-		}
-
-		var changelog ChangelogData
-		err = yaml.Unmarshal(data, &changelog)
-		if err != nil {
-			return ChangelogLoadedMsg{
-				Content: c.generateErrorMessage(err),
-			}
-		}
-
-		content := c.formatChangelog(changelog)
 		return ChangelogLoadedMsg{
-			Content: content,
+			Content: c.formatChangelog(changelog.Entries()),
 		}
 	}
 }
 
-// formatChangelog formats the changelog data into readable text
-func (c *ChangelogPopup) formatChangelog(changelog ChangelogData) string {
-	var content strings.Builder
+// formatChangelog formats the releases into readable text
+func (c *ChangelogPopup) formatChangelog(entries []changelog.Entry) string {
+	if len(entries) == 0 {
+		return "No release notes are available."
+	}
 
-	for i, entry := range changelog.Changelog {
+	var content strings.Builder
+	for i, entry := range entries {
 		// Version header
 		content.WriteString(fmt.Sprintf("Version %s (%s)\n", entry.Version, entry.Date))
 		content.WriteString(strings.Repeat("─", 50) + "\n\n")
-
-		// Highlights
-		if len(entry.Highlights) > 0 {
-			content.WriteString("HIGHLIGHTS:\n")
-			for _, highlight := range entry.Highlights {
-				content.WriteString(fmt.Sprintf("  * %s\n", highlight))
-			}
-			content.WriteString("\n")
-		}
-
-		// Features
-		if len(entry.Features) > 0 {
-			content.WriteString("NEW FEATURES:\n")
-			for _, feature := range entry.Features {
-				content.WriteString(fmt.Sprintf("  * %s\n", feature.Title))
-				content.WriteString(fmt.Sprintf("    %s\n", feature.Description))
-			}
-			content.WriteString("\n")
-		}
-
-		// Improvements
-		if len(entry.Improvements) > 0 {
-			content.WriteString("IMPROVEMENTS:\n")
-			for _, improvement := range entry.Improvements {
-				content.WriteString(fmt.Sprintf("  * %s\n", improvement))
-			}
-			content.WriteString("\n")
-		}
-
-		// Fixes
-		if len(entry.Fixes) > 0 {
-			content.WriteString("BUG FIXES:\n")
-			for _, fix := range entry.Fixes {
-				content.WriteString(fmt.Sprintf("  * %s\n", fix))
-			}
-			content.WriteString("\n")
-		}
+		content.WriteString(entry.Notes + "\n\n")
 
 		// Add separator between versions
-		if i < len(changelog.Changelog)-1 {
+		if i < len(entries)-1 {
 			content.WriteString(strings.Repeat("═", 60) + "\n\n")
 		}
 	}
@@ -250,19 +178,6 @@ func (c *ChangelogPopup) formatChangelog(changelog ChangelogData) string {
 	return content.String()
 }
 
-// generateErrorMessage generates an error message for display
-func (c *ChangelogPopup) generateErrorMessage(err error) string {
-	return fmt.Sprintf(`Error loading changelog: %s
-
-This might be because:
-  • The changelog.yaml file is missing
-  • The file format is invalid
-  • File permissions prevent access
-
-You can still use nixai normally. The changelog will be
-available when the configuration is properly set up.`, err.Error())
-}
-
 // ChangelogLoadedMsg represents a message when changelog is loaded
 type ChangelogLoadedMsg struct {
 	Content string
@@ -9,6 +9,7 @@ import (
 	"nix-ai-help/internal/cli"
 	"nix-ai-help/internal/tui/models"
 	"nix-ai-help/internal/tui/panels"
+	"nix-ai-help/pkg/utils"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -111,7 +112,7 @@ func (e *CommandExecutor) executeSystemCommand(cmdName string, args []string, st
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, cmdName, args...)
-	output, err := cmd.CombinedOutput()
+	output, err := utils.TraceCombinedOutput(cmd)
 
 	duration := time.Since(startTime)
 	exitCode := 0
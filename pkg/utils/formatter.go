@@ -2,6 +2,8 @@ package utils
 
 import (
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/glamour"
@@ -202,13 +204,103 @@ func FormatBox(title, content string) string {
 	return BoxStyle.Render(content)
 }
 
-// FormatTable creates a simple table
+// DefaultTableWidth is the table width used when the terminal width is unknown
+const DefaultTableWidth = 120
+
+// minColumnWidth is the narrowest a column is truncated to
+const minColumnWidth = 6
+
+// FormatTable creates an aligned table sized to the terminal width (from $COLUMNS)
 func FormatTable(headers []string, rows [][]string) string {
+	width := DefaultTableWidth
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		width = cols
+	}
+	return FormatTableWidth(headers, rows, width)
+}
+
+// FormatTableWidth creates an aligned table no wider than maxWidth. When the content
+// is too wide the widest columns are shrunk first and their cells truncated with "…".
+func FormatTableWidth(headers []string, rows [][]string, maxWidth int) string {
 	if len(headers) == 0 || len(rows) == 0 {
 		return ""
 	}
-	// Minimal stub to fix build
-	return ""
+
+	const gap = "  "
+	widths := make([]int, len(headers))
+	for i, h := range headers {
+		widths[i] = lipgloss.Width(h)
+	}
+	for _, row := range rows {
+		for i := range headers {
+			if i < len(row) && lipgloss.Width(row[i]) > widths[i] {
+				widths[i] = lipgloss.Width(row[i])
+			}
+		}
+	}
+
+	// Shrink the widest column until the table fits
+	for {
+		total := len(gap) * (len(widths) - 1)
+		widest := 0
+		for i, w := range widths {
+			total += w
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if total <= maxWidth || widths[widest] <= minColumnWidth {
+			break
+		}
+		widths[widest] -= min(total-maxWidth, widths[widest]-minColumnWidth)
+	}
+
+	formatRow := func(cells []string, style *lipgloss.Style) string {
+		parts := make([]string, len(widths))
+		for i, w := range widths {
+			cell := ""
+			if i < len(cells) {
+				cell = strings.ReplaceAll(cells[i], "\n", " ")
+			}
+			cell = truncateCell(cell, w)
+			padded := cell + strings.Repeat(" ", w-lipgloss.Width(cell))
+			if style != nil {
+				padded = style.Render(padded)
+			}
+			parts[i] = padded
+		}
+		return strings.TrimRight(strings.Join(parts, gap), " ")
+	}
+
+	var lines []string
+	lines = append(lines, formatRow(headers, &SubtitleStyle))
+	separators := make([]string, len(widths))
+	for i, w := range widths {
+		separators[i] = strings.Repeat("─", w)
+	}
+	lines = append(lines, MutedStyle.UnsetItalic().Render(strings.Join(separators, gap)))
+	for _, row := range rows {
+		lines = append(lines, formatRow(row, nil))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateCell shortens s to at most width display columns, marking the cut with "…"
+func truncateCell(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	var b strings.Builder
+	used := 0
+	for _, r := range s {
+		w := lipgloss.Width(string(r))
+		if used+w > width-1 {
+			break
+		}
+		b.WriteRune(r)
+		used += w
+	}
+	return b.String() + "…"
 }
 
 // FormatDivider creates a visual divider line
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestFormatTableWidth_Alignment(t *testing.T) {
+	table := FormatTableWidth(
+		[]string{"Name", "Version", "Description"},
+		[][]string{
+			{"firefox", "128.0", "Web browser"},
+			{"git", "2.45.2", "Distributed version control system"},
+		},
+		120,
+	)
+
+	lines := strings.Split(table, "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header, separator and two rows, got %d lines:\n%s", len(lines), table)
+	}
+
+	// Every column starts at the same offset on every line
+	versionCol := strings.Index(lines[0], "Version")
+	for _, line := range lines[2:] {
+		fields := strings.Fields(line)
+		if idx := strings.Index(line, fields[1]); idx != versionCol {
+			t.Errorf("expected version column at %d, got %d in %q", versionCol, idx, line)
+		}
+	}
+	if !strings.HasPrefix(lines[1], strings.Repeat("─", len("firefox"))+"  ") {
+		t.Errorf("expected separator sized to the name column, got %q", lines[1])
+	}
+}
+
+func TestFormatTableWidth_Truncation(t *testing.T) {
+	long := strings.Repeat("very long description ", 10)
+	table := FormatTableWidth(
+		[]string{"Name", "Description"},
+		[][]string{{"hello", long}},
+		40,
+	)
+
+	for _, line := range strings.Split(table, "\n") {
+		if w := lipgloss.Width(line); w > 40 {
+			t.Errorf("expected lines no wider than 40, got %d: %q", w, line)
+		}
+	}
+	if !strings.Contains(table, "…") {
+		t.Errorf("expected truncated cells to end with an ellipsis:\n%s", table)
+	}
+	if !strings.Contains(table, "hello") {
+		t.Errorf("expected narrow columns to be kept intact:\n%s", table)
+	}
+}
+
+func TestFormatTable_Empty(t *testing.T) {
+	if got := FormatTable([]string{"Name"}, nil); got != "" {
+		t.Errorf("expected empty output without rows, got %q", got)
+	}
+}
@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// proxyMu guards configuredProxy
+	proxyMu sync.RWMutex
+	// configuredProxy is the network.proxy override; nil uses the proxy environment variables
+	configuredProxy *url.URL
+)
+
+// ParseProxyURL validates a proxy setting; a bare host:port is taken as an http proxy, like
+// HTTP_PROXY does
+func ParseProxyURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	target := raw
+	if !strings.Contains(raw, "://") {
+		target = "http://" + raw
+	}
+	proxy, err := url.Parse(target)
+	if err != nil || proxy.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", raw)
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return proxy, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, use http, https or socks5", proxy.Scheme)
+	}
+}
+
+// SetHTTPProxy overrides HTTP_PROXY and HTTPS_PROXY for every client built with NewHTTPClient.
+// NO_PROXY is still honored. An empty value goes back to the environment variables.
+func SetHTTPProxy(raw string) error {
+	var proxy *url.URL
+	if strings.TrimSpace(raw) != "" {
+		var err error
+		if proxy, err = ParseProxyURL(raw); err != nil {
+			return err
+		}
+	}
+
+	proxyMu.Lock()
+	configuredProxy = proxy
+	proxyMu.Unlock()
+	return nil
+}
+
+// ProxyForRequest returns the proxy a request goes through: the network.proxy override when set,
+// otherwise the one from HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+func ProxyForRequest(req *http.Request) (*url.URL, error) {
+	proxyMu.RLock()
+	proxy := configuredProxy
+	proxyMu.RUnlock()
+	if proxy == nil {
+		return http.ProxyFromEnvironment(req)
+	}
+	if bypassProxy(req.URL.Hostname()) {
+		return nil, nil
+	}
+	return proxy, nil
+}
+
+// bypassProxy reports whether a host is reached directly: loopback hosts always are, like with
+// http.ProxyFromEnvironment, and so are the hosts listed in NO_PROXY
+func bypassProxy(host string) bool {
+	host = strings.ToLower(host)
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return true
+	}
+
+	noProxy := os.Getenv("NO_PROXY")
+	if noProxy == "" {
+		noProxy = os.Getenv("no_proxy")
+	}
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entry = h
+		}
+		// "example.com" and ".example.com" both match example.com and its subdomains
+		entry = strings.TrimPrefix(entry, ".")
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewHTTPTransport returns a transport with the default settings that picks its proxy with
+// ProxyForRequest
+func NewHTTPTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = ProxyForRequest
+	return transport
+}
+
+// NewHTTPClient returns an HTTP client that honors the proxy settings; a zero timeout means none
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: NewHTTPTransport()}
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestProxy starts a plain HTTP proxy stand-in that records the hosts requested through it
+func newTestProxy(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+	var hosts []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.URL.Host)
+		_, _ = io.WriteString(w, "via proxy")
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy, &hosts
+}
+
+func TestNewHTTPClientUsesConfiguredProxy(t *testing.T) {
+	proxy, hosts := newTestProxy(t)
+	if err := SetHTTPProxy(proxy.URL); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = SetHTTPProxy("") })
+
+	resp, err := NewHTTPClient(5 * time.Second).Get("http://api.example.test/search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "via proxy" || len(*hosts) != 1 || (*hosts)[0] != "api.example.test" {
+		t.Errorf("request did not go through the proxy: body %q, proxied hosts %v", body, *hosts)
+	}
+}
+
+func TestProxyForRequestHonorsNoProxy(t *testing.T) {
+	t.Setenv("NO_PROXY", "internal.example, .corp.test, 10.0.0.0/8, cache.lan:5000")
+	if err := SetHTTPProxy("proxy.corp.test:3128"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = SetHTTPProxy("") })
+
+	tests := []struct {
+		url     string
+		proxied bool
+	}{
+		{"https://api.github.com/search", true},
+		{"http://internal.example/", false},
+		{"http://git.internal.example/", false},
+		{"http://notinternal.example/", true},
+		{"http://builder.corp.test/", false},
+		{"http://10.1.2.3:8080/", false},
+		{"http://cache.lan:5000/", false},
+		{"http://localhost:8081/healthz", false},
+		{"http://127.0.0.1:8081/healthz", false},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+		proxy, err := ProxyForRequest(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.url, err)
+		}
+		if got := proxy != nil; got != tt.proxied {
+			t.Errorf("%s proxied = %v, want %v", tt.url, got, tt.proxied)
+		}
+		if proxy != nil && proxy.String() != "http://proxy.corp.test:3128" {
+			t.Errorf("%s went through %s", tt.url, proxy)
+		}
+	}
+}
+
+func TestProxyForRequestDefaultsToEnvironment(t *testing.T) {
+	if err := SetHTTPProxy(""); err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/", nil)
+	got, gotErr := ProxyForRequest(req)
+	want, wantErr := http.ProxyFromEnvironment(req)
+	if (got == nil) != (want == nil) || (got != nil && got.String() != want.String()) || (gotErr == nil) != (wantErr == nil) {
+		t.Errorf("ProxyForRequest = %v, %v; ProxyFromEnvironment = %v, %v", got, gotErr, want, wantErr)
+	}
+
+	transport := NewHTTPClient(0).Transport.(*http.Transport)
+	if transport.Proxy == nil {
+		t.Fatal("the client transport has no proxy function")
+	}
+}
+
+func TestParseProxyURL(t *testing.T) {
+	for raw, want := range map[string]string{
+		"http://proxy.corp:3128":   "http://proxy.corp:3128",
+		"proxy.corp:3128":          "http://proxy.corp:3128",
+		" socks5://127.0.0.1:1080": "socks5://127.0.0.1:1080",
+	} {
+		got, err := ParseProxyURL(raw)
+		if err != nil || got.String() != want {
+			t.Errorf("ParseProxyURL(%q) = %v, %v; want %s", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"", "ftp://proxy.corp:21", "http://"} {
+		if _, err := ParseProxyURL(raw); err == nil {
+			t.Errorf("ParseProxyURL(%q) should fail", raw)
+		}
+	}
+	if err := SetHTTPProxy("ftp://proxy.corp:21"); err == nil {
+		t.Error("SetHTTPProxy accepted an unsupported scheme")
+	}
+}
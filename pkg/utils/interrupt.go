@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// InterruptExitCode is the exit status of a command stopped with Ctrl-C (128 + SIGINT)
+const InterruptExitCode = 130
+
+// terminalReset resets text attributes and shows the cursor again
+const terminalReset = "\033[0m\033[?25h"
+
+// exitProcess ends the process after an interrupt; replaced in tests
+var exitProcess = os.Exit
+
+var (
+	// interruptMu guards interruptCleanups and savedTerminal
+	interruptMu       sync.Mutex
+	interruptCleanups []*interruptCleanup
+	// savedTerminal is the stdin terminal state from before the command ran
+	savedTerminal *term.State
+)
+
+// interruptCleanup is a cleanup registered with OnInterrupt; the pointer identifies it on removal
+type interruptCleanup struct {
+	fn func()
+}
+
+// SafeWriter buffers output until a newline or Flush, and serializes writes so that an
+// interrupt handler can flush a partially written answer without interleaving with it
+type SafeWriter struct {
+	mu  sync.Mutex
+	buf *bufio.Writer
+}
+
+// NewSafeWriter creates a SafeWriter writing to w
+func NewSafeWriter(w io.Writer) *SafeWriter {
+	return &SafeWriter{buf: bufio.NewWriter(w)}
+}
+
+// Write buffers p and writes out the buffer once it holds a complete line
+func (w *SafeWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n, err := w.buf.Write(p)
+	if err == nil && bytes.IndexByte(p, '\n') >= 0 {
+		err = w.buf.Flush()
+	}
+	return n, err
+}
+
+// Flush writes out any buffered output
+func (w *SafeWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Flush()
+}
+
+// OnInterrupt registers cleanup to run before the process exits on Ctrl-C, e.g. to flush a
+// partial answer. Cleanups run in reverse order of registration; the returned function
+// unregisters cleanup once the step it protects is done.
+func OnInterrupt(cleanup func()) (remove func()) {
+	entry := &interruptCleanup{fn: cleanup}
+	interruptMu.Lock()
+	interruptCleanups = append(interruptCleanups, entry)
+	interruptMu.Unlock()
+	return func() {
+		interruptMu.Lock()
+		defer interruptMu.Unlock()
+		for i, registered := range interruptCleanups {
+			if registered == entry {
+				interruptCleanups = append(interruptCleanups[:i], interruptCleanups[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// HandleInterrupts makes SIGINT exit through ExitInterrupted instead of killing the process
+// mid-write. It remembers the terminal state of stdin so that it can be restored. The
+// returned function stops handling the signal.
+func HandleInterrupts(out io.Writer) (stop func()) {
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		if state, err := term.GetState(fd); err == nil {
+			interruptMu.Lock()
+			savedTerminal = state
+			interruptMu.Unlock()
+		}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+			ExitInterrupted(out)
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
+
+// ExitInterrupted stops running spinners, runs the OnInterrupt cleanups, restores the
+// terminal and exits with InterruptExitCode
+func ExitInterrupted(out io.Writer) {
+	stopActiveSpinners()
+
+	interruptMu.Lock()
+	cleanups := interruptCleanups
+	interruptCleanups = nil
+	state := savedTerminal
+	interruptMu.Unlock()
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i].fn()
+	}
+
+	if state != nil {
+		_ = term.Restore(int(os.Stdin.Fd()), state)
+	}
+	if isTerminalWriter(out) {
+		// End the line the ^C was echoed on
+		_, _ = fmt.Fprintln(out, terminalReset)
+	}
+	exitProcess(InterruptExitCode)
+}
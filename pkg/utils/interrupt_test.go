@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+// stubExit replaces exitProcess for a test and returns the channel receiving the exit code
+func stubExit(t *testing.T) <-chan int {
+	t.Helper()
+	codes := make(chan int, 1)
+	original := exitProcess
+	exitProcess = func(code int) { codes <- code }
+	t.Cleanup(func() {
+		exitProcess = original
+		interruptMu.Lock()
+		interruptCleanups = nil
+		interruptMu.Unlock()
+	})
+	return codes
+}
+
+func TestSafeWriterBuffersPartialLines(t *testing.T) {
+	var out bytes.Buffer
+	w := NewSafeWriter(&out)
+
+	_, _ = w.Write([]byte("partial"))
+	if out.Len() != 0 {
+		t.Errorf("partial line was written before a flush: %q", out.String())
+	}
+	_, _ = w.Write([]byte(" line\nnext"))
+	if out.String() != "partial line\nnext" {
+		t.Errorf("complete line not written out, got %q", out.String())
+	}
+	_ = w.Flush()
+	if out.String() != "partial line\nnext" {
+		t.Errorf("unexpected output after flush %q", out.String())
+	}
+}
+
+func TestSIGINTFlushesOutputAndExits(t *testing.T) {
+	codes := stubExit(t)
+	var out bytes.Buffer
+	answer := NewSafeWriter(&out)
+	_, _ = answer.Write([]byte("services.openssh.enable = tr"))
+
+	var order []string
+	defer OnInterrupt(func() { order = append(order, "first") })()
+	defer OnInterrupt(func() {
+		order = append(order, "flush")
+		_ = answer.Flush()
+	})()
+	OnInterrupt(func() { t.Error("a removed cleanup ran") })()
+
+	stop := HandleInterrupts(&bytes.Buffer{})
+	defer stop()
+
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := self.Signal(os.Interrupt); err != nil {
+		t.Skipf("cannot send SIGINT on this platform: %v", err)
+	}
+
+	select {
+	case code := <-codes:
+		if code != InterruptExitCode {
+			t.Errorf("exit code = %d, want %d", code, InterruptExitCode)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGINT was not handled")
+	}
+	if out.String() != "services.openssh.enable = tr" {
+		t.Errorf("partial output was not flushed, got %q", out.String())
+	}
+	if len(order) != 2 || order[0] != "flush" || order[1] != "first" {
+		t.Errorf("cleanups ran in order %v, want the latest first", order)
+	}
+}
+
+func TestExitInterruptedStopsSpinners(t *testing.T) {
+	codes := stubExit(t)
+	// Register a running spinner directly; non-terminal spinners never start their goroutine
+	spinner := NewSpinner(&bytes.Buffer{}, "Querying...", 0)
+	spinner.started = true
+	go func() {
+		<-spinner.stop
+		close(spinner.done)
+	}()
+	activeSpinners.Store(spinner, struct{}{})
+
+	ExitInterrupted(&bytes.Buffer{})
+	if code := <-codes; code != InterruptExitCode {
+		t.Errorf("exit code = %d, want %d", code, InterruptExitCode)
+	}
+	if _, running := activeSpinners.Load(spinner); running {
+		t.Error("spinner still registered after the interrupt")
+	}
+}
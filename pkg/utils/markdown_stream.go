@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+// MarkdownStream renders streamed markdown block by block: each paragraph, list or heading is
+// rendered as soon as it is complete, so that a streamed answer ends up formatted without
+// waiting for the whole response. Code blocks, which can run long, are written raw line by line
+// as they arrive instead of being held back until their closing fence. It is safe to Close from
+// an interrupt handler while Write runs.
+type MarkdownStream struct {
+	mu     sync.Mutex
+	out    io.Writer
+	render func(string) string
+	line   string   // text after the last newline
+	block  []string // lines of the block being collected
+	fence  string   // marker of the open code block, "" outside code
+	closed bool
+}
+
+// NewMarkdownStream returns a MarkdownStream rendering with RenderMarkdown into out
+func NewMarkdownStream(out io.Writer) *MarkdownStream {
+	return newMarkdownStream(out, RenderMarkdown)
+}
+
+// newMarkdownStream returns a MarkdownStream rendering blocks with render
+func newMarkdownStream(out io.Writer, render func(string) string) *MarkdownStream {
+	return &MarkdownStream{out: out, render: render}
+}
+
+// Write adds streamed text and renders the blocks it completes
+func (s *MarkdownStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	s.line += string(p)
+	for {
+		i := strings.IndexByte(s.line, '\n')
+		if i < 0 {
+			break
+		}
+		line := s.line[:i]
+		s.line = s.line[i+1:]
+		if err := s.addLine(line); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// Close renders the last block, or writes the rest of a code block the stream ended in the
+// middle of
+func (s *MarkdownStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	line := s.line
+	s.line = ""
+	if s.fence != "" {
+		if line == "" {
+			return nil
+		}
+		_, err := io.WriteString(s.out, line+"\n")
+		return err
+	}
+	if line != "" {
+		s.block = append(s.block, line)
+	}
+	return s.flushBlock()
+}
+
+// addLine adds a complete line to the current block, rendering the block once the line ends it
+func (s *MarkdownStream) addLine(line string) error {
+	trimmed := strings.TrimSpace(line)
+	switch {
+	case s.fence != "":
+		// Blank lines belong to the code; only the closing fence ends the block, which is then
+		// spaced from the next block like a rendered one
+		if strings.HasPrefix(trimmed, s.fence) && strings.Trim(trimmed, s.fence[:1]) == "" {
+			s.fence = ""
+			line += "\n"
+		}
+		_, err := io.WriteString(s.out, line+"\n")
+		return err
+	case trimmed == "":
+		return s.flushBlock()
+	case codeFence(trimmed) != "":
+		if err := s.flushBlock(); err != nil {
+			return err
+		}
+		s.fence = codeFence(trimmed)
+		_, err := io.WriteString(s.out, line+"\n")
+		return err
+	case isHeading(trimmed):
+		// A heading is a block of its own
+		if err := s.flushBlock(); err != nil {
+			return err
+		}
+		s.block = []string{line}
+		return s.flushBlock()
+	default:
+		s.block = append(s.block, line)
+		return nil
+	}
+}
+
+// flushBlock renders and writes the collected block. Each rendered block is trimmed to one
+// trailing blank line, so that blocks rendered apart are spaced like a single rendering.
+func (s *MarkdownStream) flushBlock() error {
+	if len(s.block) == 0 {
+		return nil
+	}
+	rendered := strings.Trim(s.render(strings.Join(s.block, "\n")), "\n")
+	s.block = nil
+	_, err := io.WriteString(s.out, rendered+"\n\n")
+	return err
+}
+
+// codeFence returns the fence marker (``` or ~~~, possibly longer) that a line opens a code
+// block with, or ""
+func codeFence(line string) string {
+	for _, char := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, char))
+		if n >= 3 {
+			return strings.Repeat(char, n)
+		}
+	}
+	return ""
+}
+
+// isHeading reports whether a line is an ATX heading such as "## Steps"
+func isHeading(line string) bool {
+	level := len(line) - len(strings.TrimLeft(line, "#"))
+	return level >= 1 && level <= 6 && (len(line) == level || line[level] == ' ')
+}
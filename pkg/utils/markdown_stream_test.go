@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+// bracketRender marks rendered blocks so that tests can tell them from raw text
+func bracketRender(block string) string {
+	return "\n[" + block + "]\n\n"
+}
+
+func TestMarkdownStreamRendersCompletedBlocks(t *testing.T) {
+	var out strings.Builder
+	stream := newMarkdownStream(&out, bracketRender)
+
+	steps := []struct {
+		chunk string
+		want  string
+	}{
+		// A heading is complete at the end of its line
+		{"## Enable SSH\nTo enable ", "[## Enable SSH]\n\n"},
+		// A paragraph is held back until a blank line ends it
+		{"the daemon,\nadd this:\n", "[## Enable SSH]\n\n"},
+		// Code is streamed raw line by line instead of waiting for the closing fence
+		{"\n```nix\n{\n", "[## Enable SSH]\n\n[To enable the daemon,\nadd this:]\n\n```nix\n{\n"},
+		// Blank lines inside code do not end the block
+		{"\n  services.openssh.enable = true;\n}\n", "[## Enable SSH]\n\n[To enable the daemon,\nadd this:]\n\n" +
+			"```nix\n{\n\n  services.openssh.enable = true;\n}\n"},
+		{"```\nThen rebuild.", "[## Enable SSH]\n\n[To enable the daemon,\nadd this:]\n\n" +
+			"```nix\n{\n\n  services.openssh.enable = true;\n}\n```\n\n"},
+	}
+	for i, step := range steps {
+		if _, err := stream.Write([]byte(step.chunk)); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != step.want {
+			t.Fatalf("after chunk %d (%q):\ngot  %q\nwant %q", i, step.chunk, out.String(), step.want)
+		}
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out.String(), "```\n\n[Then rebuild.]\n\n") {
+		t.Errorf("expected Close to render the last paragraph, got %q", out.String())
+	}
+}
+
+func TestMarkdownStreamUnterminatedCodeBlockIsRaw(t *testing.T) {
+	var out strings.Builder
+	stream := newMarkdownStream(&out, bracketRender)
+	_, _ = stream.Write([]byte("Use this:\n\n~~~nix\nenvironment.systemPackages = [ pkgs.git ];"))
+	if out.String() != "[Use this:]\n\n~~~nix\n" {
+		t.Fatalf("expected the paragraph and the open fence, got %q", out.String())
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "[Use this:]\n\n~~~nix\nenvironment.systemPackages = [ pkgs.git ];\n"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+	if _, err := stream.Write([]byte("more")); err == nil {
+		t.Error("expected writes after Close to fail")
+	}
+}
+
+func TestCodeFenceAndHeading(t *testing.T) {
+	for line, want := range map[string]string{"```": "```", "````nix": "````", "~~~": "~~~", "``": "", "text": ""} {
+		if got := codeFence(line); got != want {
+			t.Errorf("codeFence(%q) = %q, want %q", line, got, want)
+		}
+	}
+	for line, want := range map[string]bool{"# Title": true, "###": true, "#include <x>": false, "####### Seven": false} {
+		if got := isHeading(line); got != want {
+			t.Errorf("isHeading(%q) = %v, want %v", line, got, want)
+		}
+	}
+}
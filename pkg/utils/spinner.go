@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// spinnerFrames are the animation frames of a Spinner
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// spinnerInterval is the time between two spinner frames
+const spinnerInterval = 100 * time.Millisecond
+
+// spinnersDisabled turns all spinners into no-ops, e.g. for --quiet or JSON output
+var spinnersDisabled atomic.Bool
+
+// activeSpinners holds the running spinners, which an interrupt stops to clear their line
+var activeSpinners sync.Map
+
+// DisableSpinners turns spinners off (or back on) for the rest of the process. Commands call
+// it when their output must stay machine-readable or quiet.
+func DisableSpinners(disabled bool) {
+	spinnersDisabled.Store(disabled)
+}
+
+// Spinner animates a single status line with the elapsed time while a slow step runs, and
+// clears the line when stopped. It does nothing unless it writes to a terminal.
+type Spinner struct {
+	out      io.Writer
+	message  string
+	estimate time.Duration
+	start    time.Time
+	started  bool
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// StartSpinner starts a spinner on stdout:
+//
+//	spinner := utils.StartSpinner("Querying AI provider...")
+//	defer spinner.Stop()
+func StartSpinner(message string) *Spinner {
+	return NewSpinner(os.Stdout, message, 0).Start()
+}
+
+// StartSpinnerWithETA starts a spinner on stdout that also shows the time left of an
+// expected duration
+func StartSpinnerWithETA(message string, estimate time.Duration) *Spinner {
+	return NewSpinner(os.Stdout, message, estimate).Start()
+}
+
+// NewSpinner creates a spinner writing to out; an estimate of 0 shows only the elapsed time
+func NewSpinner(out io.Writer, message string, estimate time.Duration) *Spinner {
+	return &Spinner{
+		out:      out,
+		message:  message,
+		estimate: estimate,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start begins the animation when out is a terminal and spinners are enabled
+func (s *Spinner) Start() *Spinner {
+	if s.started {
+		return s
+	}
+	s.started = true
+	s.start = time.Now()
+	if spinnersDisabled.Load() || !isTerminalWriter(s.out) {
+		close(s.done)
+		return s
+	}
+	activeSpinners.Store(s, struct{}{})
+	go s.run()
+	return s
+}
+
+// Stop ends the animation and clears the status line; it is safe to call more than once
+func (s *Spinner) Stop() {
+	if s == nil || !s.started {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+		activeSpinners.Delete(s)
+	})
+}
+
+// stopActiveSpinners stops every running spinner
+func stopActiveSpinners() {
+	activeSpinners.Range(func(key, _ interface{}) bool {
+		key.(*Spinner).Stop()
+		return true
+	})
+}
+
+// run draws frames until the spinner is stopped
+func (s *Spinner) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+	for frame := 0; ; frame++ {
+		_, _ = fmt.Fprint(s.out, "\r\033[K"+s.line(spinnerFrames[frame%len(spinnerFrames)], time.Since(s.start)))
+		select {
+		case <-s.stop:
+			_, _ = fmt.Fprint(s.out, "\r\033[K")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// line renders one frame of the status line
+func (s *Spinner) line(frame string, elapsed time.Duration) string {
+	status := formatSpinnerDuration(elapsed)
+	if s.estimate > 0 {
+		if left := s.estimate - elapsed; left > 0 {
+			status += ", ~" + formatSpinnerDuration(left) + " left"
+		} else {
+			status += ", taking longer than expected"
+		}
+	}
+	return InfoStyle.Render(frame+" "+s.message) + " " + MutedStyle.Render("("+status+")")
+}
+
+// formatSpinnerDuration formats a duration in whole seconds, e.g. 42s or 1m05s
+func formatSpinnerDuration(d time.Duration) string {
+	seconds := int(d.Round(time.Second) / time.Second)
+	if seconds < 60 {
+		return fmt.Sprintf("%ds", seconds)
+	}
+	return fmt.Sprintf("%dm%02ds", seconds/60, seconds%60)
+}
+
+// isTerminalWriter reports whether w is a file attached to a terminal; tests replace it to
+// pretend that a buffer is one
+var isTerminalWriter = func(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
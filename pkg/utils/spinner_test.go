@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpinnerNonTTYWritesNothing(t *testing.T) {
+	var buf bytes.Buffer
+	spinner := NewSpinner(&buf, "Querying...", time.Second).Start()
+	time.Sleep(3 * spinnerInterval)
+	spinner.Stop()
+	spinner.Stop()
+	if buf.Len() != 0 {
+		t.Errorf("spinner wrote to a non-terminal writer: %q", buf.String())
+	}
+}
+
+func TestSpinnerStopWithoutStart(t *testing.T) {
+	var buf bytes.Buffer
+	NewSpinner(&buf, "Querying...", 0).Stop()
+	var nilSpinner *Spinner
+	nilSpinner.Stop()
+}
+
+// pretendTerminal makes every writer count as a terminal until the test ends
+func pretendTerminal(t *testing.T) {
+	orig := isTerminalWriter
+	isTerminalWriter = func(io.Writer) bool { return true }
+	t.Cleanup(func() { isTerminalWriter = orig })
+}
+
+func TestSpinnerTerminalAnimates(t *testing.T) {
+	pretendTerminal(t)
+	var buf bytes.Buffer
+	spinner := NewSpinner(&buf, "Querying...", 0).Start()
+	time.Sleep(2 * spinnerInterval)
+	spinner.Stop()
+	if !strings.Contains(buf.String(), "Querying...") || !strings.HasSuffix(buf.String(), "\r\033[K") {
+		t.Errorf("expected frames followed by a cleared line, got %q", buf.String())
+	}
+}
+
+func TestSpinnerDisabled(t *testing.T) {
+	pretendTerminal(t)
+	DisableSpinners(true)
+	defer DisableSpinners(false)
+	var buf bytes.Buffer
+	spinner := NewSpinner(&buf, "Querying...", 0).Start()
+	time.Sleep(2 * spinnerInterval)
+	spinner.Stop()
+	if buf.Len() != 0 {
+		t.Errorf("a disabled spinner wrote to a terminal: %q", buf.String())
+	}
+}
+
+func TestSpinnerLine(t *testing.T) {
+	spinner := NewSpinner(&bytes.Buffer{}, "Building", 10*time.Second)
+	if line := spinner.line("⠋", 3*time.Second); !strings.Contains(line, "Building") || !strings.Contains(line, "3s, ~7s left") {
+		t.Errorf("unexpected line: %q", line)
+	}
+	if line := spinner.line("⠋", 12*time.Second); !strings.Contains(line, "taking longer than expected") {
+		t.Errorf("unexpected overdue line: %q", line)
+	}
+}
+
+func TestFormatSpinnerDuration(t *testing.T) {
+	if got := formatSpinnerDuration(42 * time.Second); got != "42s" {
+		t.Errorf("got %q", got)
+	}
+	if got := formatSpinnerDuration(65 * time.Second); got != "1m05s" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// traceMu guards traceOut and keeps trace lines of concurrent commands whole
+	traceMu sync.Mutex
+	// traceOut receives a line per external command; nil disables tracing
+	traceOut io.Writer
+)
+
+// SetCommandTrace makes every command run through TraceRun, TraceOutput or
+// TraceCombinedOutput print its arguments, exit code and duration to out; nil turns it off
+func SetCommandTrace(out io.Writer) {
+	traceMu.Lock()
+	traceOut = out
+	traceMu.Unlock()
+}
+
+// TraceRun runs a command like cmd.Run, tracing it when enabled
+func TraceRun(cmd *exec.Cmd) error {
+	start := time.Now()
+	err := cmd.Run()
+	traceCommand(cmd, time.Since(start), err)
+	return err
+}
+
+// TraceOutput runs a command like cmd.Output, tracing it when enabled
+func TraceOutput(cmd *exec.Cmd) ([]byte, error) {
+	start := time.Now()
+	output, err := cmd.Output()
+	traceCommand(cmd, time.Since(start), err)
+	return output, err
+}
+
+// TraceCombinedOutput runs a command like cmd.CombinedOutput, tracing it when enabled
+func TraceCombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+	traceCommand(cmd, time.Since(start), err)
+	return output, err
+}
+
+// traceCommand writes the trace line of a finished command
+func traceCommand(cmd *exec.Cmd, elapsed time.Duration, err error) {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	if traceOut == nil {
+		return
+	}
+	_, _ = fmt.Fprintf(traceOut, "[trace] %s (%s, %s)\n", quoteCommandArgs(cmd.Args), commandStatus(err), elapsed.Round(time.Millisecond))
+}
+
+// commandStatus describes how a command ended: its exit code, or why it did not run
+func commandStatus(err error) string {
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return "exit 0"
+	case errors.As(err, &exitErr):
+		return "exit " + strconv.Itoa(exitErr.ExitCode())
+	default:
+		return "error: " + err.Error()
+	}
+}
+
+// quoteCommandArgs joins command arguments, quoting those a shell would split
+func quoteCommandArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'$\\|&;<>()*?") {
+			arg = strconv.Quote(arg)
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestHelperProcess is the fake command run by the trace tests; it prints its arguments and
+// exits with the code given in FAKE_EXIT_CODE
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	fmt.Print(strings.Join(os.Args[3:], " "))
+	code, _ := strconv.Atoi(os.Getenv("FAKE_EXIT_CODE"))
+	os.Exit(code)
+}
+
+// fakeCommand builds a command running TestHelperProcess with the given arguments
+func fakeCommand(exitCode int, args ...string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=TestHelperProcess", "--"}, args...)...)
+	cmd.Env = append(os.Environ(), "GO_WANT_HELPER_PROCESS=1", "FAKE_EXIT_CODE="+strconv.Itoa(exitCode))
+	return cmd
+}
+
+func TestCommandTrace(t *testing.T) {
+	var trace bytes.Buffer
+	SetCommandTrace(&trace)
+	defer SetCommandTrace(nil)
+
+	output, err := TraceOutput(fakeCommand(0, "search", "nixpkgs", "hello world"))
+	if err != nil || string(output) != "search nixpkgs hello world" {
+		t.Fatalf("TraceOutput = %q, %v", output, err)
+	}
+	if _, err := TraceCombinedOutput(fakeCommand(3, "flake", "check")); err == nil {
+		t.Fatal("expected the exit status 3 error")
+	}
+	if err := TraceRun(exec.Command("nixai-test-missing-command", "--version")); err == nil {
+		t.Fatal("expected an error for a missing command")
+	}
+
+	lines := strings.Split(strings.TrimSpace(trace.String()), "\n")
+	want := []*regexp.Regexp{
+		regexp.MustCompile(`^\[trace\] \S+ -test\.run=TestHelperProcess -- search nixpkgs "hello world" \(exit 0, \d+(\.\d+)?m?s\)$`),
+		regexp.MustCompile(`^\[trace\] \S+ -test\.run=TestHelperProcess -- flake check \(exit 3, \d+(\.\d+)?m?s\)$`),
+		regexp.MustCompile(`^\[trace\] nixai-test-missing-command --version \(error: .*executable file not found.*, \d+(\.\d+)?m?s\)$`),
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d trace lines, want %d:\n%s", len(lines), len(want), trace.String())
+	}
+	for i, pattern := range want {
+		if !pattern.MatchString(lines[i]) {
+			t.Errorf("trace line %d = %q, want match for %s", i, lines[i], pattern)
+		}
+	}
+}
+
+func TestCommandTraceDisabled(t *testing.T) {
+	var trace bytes.Buffer
+	SetCommandTrace(&trace)
+	SetCommandTrace(nil)
+
+	if _, err := TraceOutput(fakeCommand(0, "ok")); err != nil {
+		t.Fatal(err)
+	}
+	if trace.Len() != 0 {
+		t.Errorf("tracing was turned off but wrote %q", trace.String())
+	}
+}
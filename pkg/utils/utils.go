@@ -172,7 +172,7 @@ func RunCommand(cmd string, args ...string) error {
 	c := exec.Command(cmd, args...)
 	c.Stdout = os.Stdout
 	c.Stderr = os.Stderr
-	return c.Run()
+	return TraceRun(c)
 }
 
 // FlakeHasDeployConfig checks if flake.nix contains a deploy-rs config.
@@ -467,7 +467,7 @@ func GetFlakeHosts(flakePath string, debug ...bool) ([]string, error) {
 	cmd.Stderr = &stderr
 
 	// Run the command
-	err = cmd.Run()
+	err = TraceRun(cmd)
 	if err != nil {
 		errMsg := fmt.Sprintf("nix eval failed: %v", err)
 		if isDebug {
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrOverwriteDeclined is returned by WriteFileWithConfirm when an existing file is kept
+var ErrOverwriteDeclined = errors.New("existing file kept")
+
+// writePreviewLines bounds how many changed lines the overwrite preview shows
+const writePreviewLines = 20
+
+// WriteConfirm says how WriteFileWithConfirm asks before overwriting a file
+type WriteConfirm struct {
+	// In supplies the answer; nil when there is no terminal to ask on
+	In io.Reader
+	// Out receives the preview and the question
+	Out io.Writer
+	// AssumeYes overwrites without asking, for --yes
+	AssumeYes bool
+}
+
+// WriteFileWithConfirm writes data to path, creating its directory. A new file, or one whose
+// content would not change, is written without asking. Before an existing file is overwritten a
+// preview of the change is shown and the user is asked, unless AssumeYes is set; without In to
+// ask on, the file is kept. ErrOverwriteDeclined is returned when the file is kept.
+func WriteFileWithConfirm(path string, data []byte, perm os.FileMode, confirm WriteConfirm) error {
+	// #nosec G304 -- path is the file the user asked to write
+	existing, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// A new file
+	case err != nil:
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	case bytes.Equal(existing, data):
+		return nil
+	case !confirm.AssumeYes:
+		if !confirmOverwrite(path, string(existing), string(data), confirm) {
+			return fmt.Errorf("%s: %w", path, ErrOverwriteDeclined)
+		}
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+	if err := os.WriteFile(path, data, perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// confirmOverwrite shows how path would change and asks whether to overwrite it
+func confirmOverwrite(path, existing, updated string, confirm WriteConfirm) bool {
+	out := confirm.Out
+	if out == nil {
+		out = io.Discard
+	}
+	_, _ = fmt.Fprintln(out, FormatWarning(path+" already exists"))
+	_, _ = fmt.Fprintln(out, OverwritePreview(existing, updated))
+	if confirm.In == nil {
+		_, _ = fmt.Fprintln(out, FormatTip("Pass --yes to overwrite it without asking"))
+		return false
+	}
+	_, _ = fmt.Fprintf(out, "Overwrite %s? (y/N): ", path)
+	answer, _ := bufio.NewReader(confirm.In).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// OverwritePreview describes how updated differs from existing: the lines between their common
+// beginning and end, removed lines prefixed with "-" and added lines with "+", up to
+// writePreviewLines of them
+func OverwritePreview(existing, updated string) string {
+	oldLines := strings.Split(strings.TrimSuffix(existing, "\n"), "\n")
+	newLines := strings.Split(strings.TrimSuffix(updated, "\n"), "\n")
+
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+	removed := oldLines[prefix : len(oldLines)-suffix]
+	added := newLines[prefix : len(newLines)-suffix]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Changes from line %d: %d removed, %d added\n", prefix+1, len(removed), len(added))
+	shown := 0
+	for _, change := range []struct {
+		sign  string
+		lines []string
+	}{{"-", removed}, {"+", added}} {
+		for _, line := range change.lines {
+			if shown == writePreviewLines {
+				break
+			}
+			b.WriteString(change.sign + " " + line + "\n")
+			shown++
+		}
+	}
+	if hidden := len(removed) + len(added) - shown; hidden > 0 {
+		fmt.Fprintf(&b, "... %d more changed lines\n", hidden)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
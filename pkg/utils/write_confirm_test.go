@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteFileWithConfirmNewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts", "laptop.nix")
+	var out bytes.Buffer
+	// No terminal to ask on: a new file is still written
+	if err := WriteFileWithConfirm(path, []byte("{ }\n"), 0o644, WriteConfirm{Out: &out}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{ }\n" {
+		t.Errorf("unexpected content %q", data)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no question for a new file, got %q", out.String())
+	}
+}
+
+func TestWriteFileWithConfirmOverwritePrompt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "configuration.nix")
+	original := "{\n  services.openssh.enable = true;\n}\n"
+	updated := "{\n  services.openssh.enable = true;\n  services.nginx.enable = true;\n}\n"
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Declining, or pressing Enter, keeps the file
+	for _, answer := range []string{"n\n", "\n"} {
+		var out bytes.Buffer
+		err := WriteFileWithConfirm(path, []byte(updated), 0o644, WriteConfirm{In: strings.NewReader(answer), Out: &out})
+		if !errors.Is(err, ErrOverwriteDeclined) {
+			t.Fatalf("answer %q: expected ErrOverwriteDeclined, got %v", answer, err)
+		}
+		for _, want := range []string{"already exists", "+ " + "  services.nginx.enable = true;", "Overwrite " + path + "? (y/N)"} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("answer %q: expected output to contain %q:\n%s", answer, want, out.String())
+			}
+		}
+		if data, _ := os.ReadFile(path); string(data) != original {
+			t.Fatalf("answer %q: the file was overwritten", answer)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := WriteFileWithConfirm(path, []byte(updated), 0o644, WriteConfirm{In: strings.NewReader("y\n"), Out: &out}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != updated {
+		t.Error("expected the file to be overwritten after yes")
+	}
+}
+
+func TestWriteFileWithConfirmWithoutTerminal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "configuration.nix")
+	if err := os.WriteFile(path, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err := WriteFileWithConfirm(path, []byte("new\n"), 0o644, WriteConfirm{Out: &out})
+	if !errors.Is(err, ErrOverwriteDeclined) || !strings.Contains(out.String(), "--yes") {
+		t.Fatalf("expected the file kept with a hint about --yes, got %v:\n%s", err, out.String())
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old\n" {
+		t.Error("a file must not be overwritten without a terminal to ask on")
+	}
+}
+
+func TestWriteFileWithConfirmAssumeYes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "configuration.nix")
+	if err := os.WriteFile(path, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := WriteFileWithConfirm(path, []byte("new\n"), 0o644, WriteConfirm{Out: &out, AssumeYes: true}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new\n" {
+		t.Error("expected --yes to overwrite the file")
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no preview or question with --yes, got %q", out.String())
+	}
+}
+
+func TestOverwritePreview(t *testing.T) {
+	preview := OverwritePreview("a\nb\nc\n", "a\nB\nc\n")
+	for _, want := range []string{"from line 2: 1 removed, 1 added", "- b", "+ B"} {
+		if !strings.Contains(preview, want) {
+			t.Errorf("expected preview to contain %q:\n%s", want, preview)
+		}
+	}
+
+	long := strings.Repeat("x\n", writePreviewLines+5)
+	if preview := OverwritePreview("", long); !strings.Contains(preview, "... 6 more changed lines") {
+		t.Errorf("expected the preview to be cut short:\n%s", preview)
+	}
+}